@@ -2,8 +2,14 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/samber/do-template-cli/pkg/config"
 	"github.com/samber/do-template-cli/pkg/jobs"
@@ -68,6 +74,8 @@ func (cli *CLI) setupCommands() {
 	cli.rootCommand.AddCommand(cli.newAggregateCommand())
 	cli.rootCommand.AddCommand(cli.newValidateCommand())
 	cli.rootCommand.AddCommand(cli.newTransformCommand())
+	cli.rootCommand.AddCommand(cli.newDedupeCommand())
+	cli.rootCommand.AddCommand(cli.newWindowCommand())
 }
 
 // newServeCommand creates the serve command.
@@ -134,6 +142,8 @@ func (cli *CLI) Execute() error {
 // newCSVToJSONCommand creates the CSV to JSON conversion command.
 func (cli *CLI) newCSVToJSONCommand() *cobra.Command {
 	var inputFile, outputFile string
+	var files fileFlags
+	var includeMetadata bool
 
 	cmd := &cobra.Command{
 		Use:   "csv-to-json",
@@ -145,12 +155,19 @@ func (cli *CLI) newCSVToJSONCommand() *cobra.Command {
 				os.Exit(1)
 			}
 
+			if err := cli.applyFileFlags(cmd, &files); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
 			// Get the CSV to JSON service from dependency injection container
 			service := do.MustInvoke[*jobs.CSVToJSONService](cli.injector)
 
-			result, err := service.ConvertFile(inputFile, outputFile)
+			result, err := service.ConvertFile(inputFile, outputFile, map[string]interface{}{
+				"include_metadata": includeMetadata,
+			})
 			if err != nil {
-				fmt.Printf("Error converting CSV to JSON: %v\n", err)
+				fmt.Printf("Error converting CSV to JSON: %s\n", formatJobError(err))
 				os.Exit(1)
 			}
 
@@ -161,6 +178,8 @@ func (cli *CLI) newCSVToJSONCommand() *cobra.Command {
 
 	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input CSV file (required)")
 	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON file (optional)")
+	files.register(cmd)
+	cmd.Flags().BoolVar(&includeMetadata, "include-metadata", false, "Include source line numbers in the output")
 
 	return cmd
 }
@@ -168,161 +187,715 @@ func (cli *CLI) newCSVToJSONCommand() *cobra.Command {
 // newFilterCommand creates the data filtering command.
 func (cli *CLI) newFilterCommand() *cobra.Command {
 	var inputFile, outputFile string
-	var rulesJSON string
+	var files fileFlags
+	var rulesJSON, rulesFile, outputFormat string
 	var inclusive bool
+	var flags filterFlags
+	var csvFlags csvOutputFlags
 
 	cmd := &cobra.Command{
 		Use:   "filter-data",
 		Short: "Filter data based on field conditions",
 		Long:  "Filter data based on field conditions using dependency injection",
 		Run: func(cmd *cobra.Command, args []string) {
-			if inputFile == "" || rulesJSON == "" {
-				fmt.Println("Error: input file and rules are required")
+			readRulesFile(&rulesJSON, rulesFile)
+			if inputFile == "" {
+				fmt.Println("Error: input file is required")
+				os.Exit(1)
+			}
+			if outputFormat != "text" && outputFormat != "json" {
+				fmt.Printf("Error: unknown output format %q, expected text or json\n", outputFormat)
+				os.Exit(1)
+			}
+			if rulesJSON == "" && flags.where == "" && !cmd.Flags().Changed("offset") && !cmd.Flags().Changed("limit") {
+				fmt.Println("Error: rules or a where expression are required unless --offset or --limit is set")
 				os.Exit(1)
 			}
 
-			// Parse filter rules from JSON
-			var rules []jobs.FilterRule
-			if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
-				fmt.Printf("Error parsing filter rules: %v\n", err)
+			// Check the where expression before any data is read
+			if flags.where != "" {
+				if _, err := jobs.ParseExpression(flags.where); err != nil {
+					fmt.Printf("Error parsing where expression: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			// Parse filter rules from JSON, as a flat array or a group of rules
+			rules := parseFilterFlag(rulesJSON, "filter rules")
+
+			options := csvFlags.options()
+			if err := flags.addOptions(options); err != nil {
+				fmt.Printf("Error parsing derive rules: %v\n", err)
+				os.Exit(1)
+			}
+
+			if err := cli.applyFileFlags(cmd, &files); err != nil {
+				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 
 			// Get the filter service from dependency injection container
 			service := do.MustInvoke[*jobs.FilterService](cli.injector)
 
-			result, err := service.FilterByFile(inputFile, outputFile, rules, inclusive)
+			result, err := service.FilterByFile(inputFile, outputFile, rules, inclusive, options)
 			if err != nil {
-				fmt.Printf("Error filtering data: %v\n", err)
+				fmt.Printf("Error filtering data: %s\n", formatJobError(err))
 				os.Exit(1)
 			}
 
-			fmt.Printf("Successfully filtered %d records from %s to %s\n",
-				result.Processed, inputFile, result.OutputPath)
+			if outputFormat == "json" {
+				encoded, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					fmt.Printf("Error encoding result: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(encoded))
+				return
+			}
+			printFilterResult(os.Stdout, result, inputFile, flags.rejectedFile)
 		},
 	}
 
 	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input CSV file (required)")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON file (optional)")
-	cmd.Flags().StringVar(&rulesJSON, "rules", "", "Filter rules in JSON format (required)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON or CSV file (optional)")
+	files.register(cmd)
+	cmd.Flags().StringVar(&rulesJSON, "rules", "", `Filter rules in JSON format, as an array or a group like {"logic":"or","rules":[...],"groups":[...]} (required unless --where, --offset or --limit is set)`)
+	cmd.Flags().StringVar(&rulesFile, "rules-file", "", "JSON or YAML file of the rules, which may include other rules files (exclusive with --rules)")
+	cmd.MarkFlagsMutuallyExclusive("rules", "rules-file")
+	flags.register(cmd)
+	cmd.MarkFlagsMutuallyExclusive("rules", "where")
+	cmd.MarkFlagsMutuallyExclusive("rules-file", "where")
 	cmd.Flags().BoolVar(&inclusive, "inclusive", true, "Include matching records (true) or exclude them (false)")
+	csvFlags.register(cmd)
+	csvFlags.registerMetadata(cmd)
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Format of the result summary: text or json (includes per-rule statistics)")
 
 	return cmd
 }
 
+// filterFlags holds the flags of filter-data turned into job options.
+type filterFlags struct {
+	rejectedFile       string
+	where              string
+	deriveJSON         string
+	keepDerived        bool
+	offset, limit      int
+	numberFormat       string
+	epsilon            float64
+	dateLayouts        []string
+	missingFieldPolicy string
+	stream             bool
+}
+
+// register adds the filter-data flags turned into job options to a command.
+func (f *filterFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.rejectedFile, "rejected-output", "", "Write records excluded by the rules to this JSON or CSV file in the same pass (optional)")
+	cmd.Flags().StringVar(&f.where, "where", "", `Filter expression, like 'amount > 100 && (country == "FR" || country == "DE") && email =~ "@corp\\.com$"'`)
+	cmd.Flags().StringVar(&f.deriveJSON, "derive", "", `Transformation rules computing extra fields before filtering, like [{"field":"email","operation":"extract","parameters":{"pattern":"@(.+)$","group":1},"target_field":"domain"}]`)
+	cmd.Flags().BoolVar(&f.keepDerived, "keep-derived", false, "Write derived fields to the output")
+	cmd.Flags().IntVar(&f.offset, "offset", 0, "Skip this many records before applying rules")
+	cmd.Flags().IntVar(&f.limit, "limit", 0, "Evaluate at most this many records after the offset (0 means no limit)")
+	cmd.Flags().StringVar(&f.numberFormat, "number-format", "plain", "How numbers are written in the input: plain, en (1,234.56), eu (1.234,56), auto or a locale like fr-FR")
+	cmd.Flags().Float64Var(&f.epsilon, "epsilon", 0, "Tolerance of numeric equality (0 means exact)")
+	cmd.Flags().StringSliceVar(&f.dateLayouts, "date-layouts", nil, "Layouts of date values compared by between rules, like 02/01/2006 or DD/MM/YYYY (default: ISO-8601 dates)")
+	cmd.Flags().StringVar(&f.missingFieldPolicy, "missing-field-policy", "exclude", "What a rule does on a record without its field: exclude (the rule fails), include (the rule matches) or error (abort)")
+	cmd.Flags().BoolVar(&f.stream, "stream", false, "Write records as they are read instead of loading the whole input, stops reading once --limit is reached")
+}
+
+// addOptions adds the flags to job options, parsing the derive rules, evaluated before the filter rules.
+func (f *filterFlags) addOptions(options map[string]interface{}) error {
+	var derive []jobs.TransformRule
+	if f.deriveJSON != "" {
+		if err := json.Unmarshal([]byte(f.deriveJSON), &derive); err != nil {
+			return err
+		}
+	}
+
+	options["offset"] = f.offset
+	options["limit"] = f.limit
+	options["stream"] = f.stream
+	options["where"] = f.where
+	options["rejected_output_file"] = f.rejectedFile
+	options["number_format"] = f.numberFormat
+	options["epsilon"] = f.epsilon
+	options["missing_field_policy"] = f.missingFieldPolicy
+	options["derive"] = derive
+	options["keep_derived"] = f.keepDerived
+	options["date_layouts"] = f.dateLayouts
+	return nil
+}
+
+// printFilterResult prints the text summary of a filter, with the statistics of its rules.
+func printFilterResult(w io.Writer, result *jobs.ProcessingResult, inputFile, rejectedFile string) {
+	fmt.Fprintf(w, "Successfully filtered %d records from %s to %s\n",
+		result.Processed, inputFile, result.OutputPath)
+	fmt.Fprintf(w, "Skipped %d records by position, excluded %d by rules\n", result.Skipped, result.Excluded)
+	if rejectedFile != "" {
+		fmt.Fprintf(w, "Excluded records saved to: %s\n", rejectedFile)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+	for _, stats := range result.Stats {
+		operator := stats.Operator
+		if stats.Negate {
+			operator = "not " + operator
+		}
+		fmt.Fprintf(w, "  %s %s %s: evaluated %d, matched %d, failed %d (missing %d, unparseable %d)\n",
+			stats.Rule, stats.Field, operator, stats.Evaluated, stats.Matched,
+			stats.Failed, stats.Missing, stats.Unparseable)
+	}
+}
+
 // newAggregateCommand creates the data aggregation command.
 func (cli *CLI) newAggregateCommand() *cobra.Command {
-	var inputFile, outputFile string
-	var rulesJSON, groupByJSON string
+	var inputFiles []string
+	var outputFile string
+	var files fileFlags
+	var rulesJSON, rulesFile, groupByJSON, havingJSON, stagesJSON string
+	var binField string
+	var binWidth float64
+	var binEdges []float64
+	var flags aggregateFlags
+	var csvFlags csvOutputFlags
 
 	cmd := &cobra.Command{
 		Use:   "aggregate-data",
 		Short: "Aggregate and summarize data with statistical operations",
 		Long:  "Aggregate and summarize data with statistical operations using dependency injection",
 		Run: func(cmd *cobra.Command, args []string) {
-			if inputFile == "" || rulesJSON == "" {
-				fmt.Println("Error: input file and rules are required")
+			readRulesFile(&rulesJSON, rulesFile)
+			if len(inputFiles) == 0 {
+				fmt.Println("Error: input file is required")
+				os.Exit(1)
+			}
+			if rulesJSON == "" && binField == "" {
+				fmt.Println("Error: rules are required unless --bin-field is set")
 				os.Exit(1)
 			}
 
 			// Parse aggregation rules from JSON
 			var rules []jobs.AggregateRule
-			if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
-				fmt.Printf("Error parsing aggregation rules: %v\n", err)
-				os.Exit(1)
-			}
+			unmarshalFlag(rulesJSON, &rules, "aggregation rules")
+
+			// Parse later stages from JSON, decoded generically like the options they hold
+			var stages []interface{}
+			unmarshalFlag(stagesJSON, &stages, "stages")
 
 			// Parse group by fields from JSON
-			var groupBy []string
-			if groupByJSON != "" {
-				if err := json.Unmarshal([]byte(groupByJSON), &groupBy); err != nil {
-					fmt.Printf("Error parsing group by fields: %v\n", err)
-					os.Exit(1)
-				}
+			var groupBy []jobs.GroupByField
+			unmarshalFlag(groupByJSON, &groupBy, "group by fields")
+
+			// Histogram of a numeric field, counts per bin come with every group
+			if binField != "" {
+				groupBy = append(groupBy, jobs.GroupByField{
+					Field: binField,
+					Bins:  &jobs.BinSpec{Width: binWidth, Edges: binEdges},
+				})
+			}
+
+			// Parse having rules from JSON, in the same shapes as filter-data rules
+			having := parseFilterFlag(havingJSON, "having rules")
+
+			if err := cli.applyFileFlags(cmd, &files); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
 			}
 
 			// Get the aggregate service from dependency injection container
 			service := do.MustInvoke[*jobs.AggregateService](cli.injector)
 
-			result, err := service.AggregateFile(inputFile, outputFile, rules, groupBy)
+			options := csvFlags.options()
+			flags.addOptions(options)
+			options["having"] = having
+			options["input_files"] = inputFiles[1:]
+			if stages != nil {
+				options["stages"] = stages
+			}
+
+			result, err := service.AggregateFile(inputFiles[0], outputFile, rules, groupBy, options)
 			if err != nil {
-				fmt.Printf("Error aggregating data: %v\n", err)
+				fmt.Printf("Error aggregating data: %s\n", formatJobError(err))
 				os.Exit(1)
 			}
 
 			fmt.Printf("Successfully aggregated %d records from %s to %s\n",
-				result.Processed, inputFile, result.OutputPath)
+				result.Processed, strings.Join(result.InputFiles, ", "), result.OutputPath)
+			if flags.offset > 0 || flags.limit > 0 {
+				fmt.Printf("Emitted %d of %d groups\n", result.Processed, result.TotalGroups)
+			}
+			for _, warning := range result.Warnings {
+				fmt.Printf("Warning: %s\n", warning)
+			}
 		},
 	}
 
-	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input CSV file (required)")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON file (optional)")
-	cmd.Flags().StringVar(&rulesJSON, "rules", "", "Aggregation rules in JSON format (required)")
-	cmd.Flags().StringVar(&groupByJSON, "group-by", "", "Group by fields in JSON format (optional)")
+	cmd.Flags().StringArrayVarP(&inputFiles, "input", "i", nil, `Input CSV file or glob like "exports/*.csv", repeat for several files sharing their columns, whose rows have the file in "_source_file" (required)`)
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON or CSV file (optional)")
+	files.register(cmd)
+	cmd.Flags().StringVar(&rulesJSON, "rules", "", `Aggregation rules in JSON format, like [{"field":"amount","operation":"sum"},{"field":"tag","operation":"collect","parameters":{"distinct":true,"order":"sorted"}}] (required)`)
+	cmd.Flags().StringVar(&rulesFile, "rules-file", "", "JSON or YAML file of the rules, which may include other rules files (exclusive with --rules)")
+	cmd.MarkFlagsMutuallyExclusive("rules", "rules-file")
+	cmd.Flags().StringVar(&groupByJSON, "group-by", "", `Group by fields in JSON format, names or date buckets like ["country",{"field":"created_at","bucket":"month"}] with buckets hour, day, week, month, quarter or year (optional)`)
+	cmd.Flags().StringVar(&havingJSON, "having", "", `Keep only the groups matching these rules, in the --rules format of filter-data, evaluated against "count", the group-by fields and the aggregate aliases (optional)`)
+	cmd.Flags().StringVar(&stagesJSON, "stages", "", `Further aggregations of the output rows, in order, as a JSON array of objects taking "rules", "group_by", "having", "sort_by", "limit" and the other options, like [{"rules":[{"field":"amount_sum","operation":"average"}]}] for the average of per-group sums (optional)`)
+	cmd.Flags().StringVar(&binField, "bin-field", "", "Build a histogram: group by the bins this numeric field falls in, after the --group-by fields (rules become optional)")
+	cmd.Flags().Float64Var(&binWidth, "bin-width", 0, "Width of the --bin-field bins, like 50 for [0, 50), [50, 100)...")
+	cmd.Flags().Float64SliceVar(&binEdges, "bin-edges", nil, "Edges of the --bin-field bins instead of a width, like 0,50,100, values outside go to underflow and overflow bins")
+	cmd.MarkFlagsMutuallyExclusive("bin-width", "bin-edges")
+	flags.register(cmd)
+	csvFlags.register(cmd)
 
 	return cmd
 }
 
+// aggregateFlags holds the flags of aggregate-data turned into job options as they are.
+type aggregateFlags struct {
+	dateLayouts   []string
+	sortBy        string
+	sortDesc      bool
+	numericPolicy string
+	rollup        bool
+	rollupMarker  string
+	rankBy        string
+	rankMethod    string
+	offset, limit int
+}
+
+// register adds the aggregate-data flags turned into job options as they are to a command.
+func (f *aggregateFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&f.dateLayouts, "date-layouts", nil, "Layouts of date values, like 02/01/2006 or DD/MM/YYYY (default: ISO-8601 dates)")
+	cmd.Flags().StringVar(&f.sortBy, "sort-by", "", `Sort groups by these comma-separated keys: "count", "group_key", group-by fields or aggregate aliases, prefixed with '-' or suffixed with ":desc" for descending, like "country,total:desc" (default: group key)`)
+	cmd.Flags().BoolVar(&f.sortDesc, "sort-desc", false, "Sort keys without a sign in descending order")
+	cmd.Flags().StringVar(&f.numericPolicy, "numeric-policy", "skip", "What numeric aggregates do with values that are not numbers: skip (reported in warnings), zero (count as 0) or error (abort)")
+	cmd.Flags().BoolVar(&f.rollup, "rollup", false, "Add a subtotal after the groups of each prefix of the --group-by fields, and a grand total last")
+	cmd.Flags().StringVar(&f.rollupMarker, "rollup-marker", jobs.DefaultRollupMarker, "Value of the group-by fields rolled up in subtotals and the grand total")
+	cmd.Flags().StringVar(&f.rankBy, "rank-by", "", `Add a "rank" column ranking groups by this key, highest first unless suffixed with ":asc", like "total" (default: no rank)`)
+	cmd.Flags().StringVar(&f.rankMethod, "rank-method", string(jobs.RankStandard), "How tied groups are ranked: standard (1, 2, 2, 4) or dense (1, 2, 2, 3)")
+	cmd.Flags().IntVar(&f.offset, "offset", 0, "Skip this many groups after sorting")
+	cmd.Flags().IntVar(&f.limit, "limit", 0, "Emit at most this many groups after sorting, like the top 50 with --sort-by=-total (0 means no limit)")
+}
+
+// addOptions adds the flags to job options.
+func (f *aggregateFlags) addOptions(options map[string]interface{}) {
+	options["sort_by"] = f.sortBy
+	options["sort_desc"] = f.sortDesc
+	options["offset"] = f.offset
+	options["limit"] = f.limit
+	options["date_layouts"] = f.dateLayouts
+	options["numeric_policy"] = f.numericPolicy
+	options["rollup"] = f.rollup
+	options["rollup_marker"] = f.rollupMarker
+	options["rank_by"] = f.rankBy
+	options["rank_method"] = f.rankMethod
+}
+
+// validateHelp is the long help of validate-data, describing its rule types, fixes and scoring.
+const validateHelp = `Validate data integrity and quality using dependency injection
+
+Every rule may set its severity, "error" or "warning": only errors make records invalid.
+Regex and length rules default to warnings, other rules to errors.
+
+Rules compare a field to another one of the same row with the compare_fields type,
+whose constraints give the other field, an operator (gt, gte, lt, lte, eq or ne) and
+optionally the type of the comparison (auto, number, date or string):
+
+  --rules '[{"field":"end_date","type":"compare_fields","constraints":{"field":"start_date","operator":"gt"}}]'
+  --rules '[{"field":"discount","type":"compare_fields","constraints":{"field":"price","operator":"lt","type":"number"}}]'
+
+Rules flag values found earlier with the unique type, whose constraints may be other key
+fields, or an object that also checks uniqueness against the records of other files, CSV
+or JSON Lines, the input file excepted, optionally keeping hashes of the keys to save memory:
+
+  --rules '[{"field":"id","type":"unique","constraints":{"files":["exports/*.csv"],"hash_values":true}}]'
+
+Rules flag rows repeating an earlier one with the duplicate_row type, on every column
+or on the key columns of its constraints, without a field:
+
+  --rules '[{"type":"duplicate_row","constraints":["customer_id","order_date"],"severity":"warning"}]'
+
+Rules flag numeric values far from the others of their field with the outlier type,
+by z-score or interquartile range, warning by default. Their distribution is computed
+from every record before validation, so the input is read in memory first:
+
+  --rules '[{"field":"price","type":"outlier","constraints":{"method":"iqr","threshold":1.5}}]'
+
+Rules check any condition over the fields of a record with the expression type, in the
+syntax of the --where flag of filter-data: comparisons, =~ regexes, && || !, arithmetic
+(+ - * /) and the len, lower, upper, trim and abs functions. Records whose condition cannot
+be evaluated, like arithmetic on a non-numeric value, fail with their own message:
+
+  --rules '[{"type":"expression","constraints":"quantity * unit_price == total"}]'
+  --rules '[{"type":"expression","constraints":"len(zip) == 5 || country != \"US\""}]'
+
+--auto-fix corrects trivially fixable values before judging records, keeping a fix only when
+the rule then passes: required and length rules trim whitespace, enum rules match values in
+any case, and numeric, integer, decimal and range rules drop whitespace and thousands
+separators and read commas as decimal separators. Every fix is listed in the report and
+counted per rule in the summary, and --write-fixed writes the corrected input:
+
+  --rules '[{"field":"status","type":"enum","constraints":["open","closed"]}]' --auto-fix --write-fixed fixed.csv
+
+The quality score is the percentage of valid records minus 5 points per warning per record.
+--scoring weighs it otherwise: an invalid record takes error_weight points off, times the
+weight of its heaviest failing rule, over the number of records, and each warning takes
+warning_weight points off, times the weight of its rule. Rule weights are given by rule type,
+or by rule type and field, and default to 1:
+
+  --scoring '{"error_weight":100,"warning_weight":2,"rule_weights":{"foreign_key":3,"email:contact":0.5}}'
+
+--min-quality-score checks the score of the active scoring.
+
+Exit codes, for CI pipelines:
+
+  0  validation completed, every threshold met
+  1  validation could not run: invalid flags, rules or input
+  2  quality score below --min-quality-score
+  3  invalid records above --max-invalid-rows
+
+When both thresholds trip, the exit code is 2.`
+
 // newValidateCommand creates the data validation command.
 func (cli *CLI) newValidateCommand() *cobra.Command {
 	var inputFile, outputFile string
-	var rulesJSON string
-	var failFast bool
+	var files fileFlags
+	var rulesJSON, rulesFile string
+	var failFast, verbose bool
+	var flags validateFlags
 
 	cmd := &cobra.Command{
 		Use:   "validate-data",
 		Short: "Validate data integrity and quality",
-		Long:  "Validate data integrity and quality using dependency injection",
+		Long:  validateHelp,
 		Run: func(cmd *cobra.Command, args []string) {
-			if inputFile == "" || rulesJSON == "" {
-				fmt.Println("Error: input file and rules are required")
+			readRulesFile(&rulesJSON, rulesFile)
+			if inputFile == "" || (rulesJSON == "" && flags.schemaFile == "") {
+				fmt.Println("Error: input file and rules or a schema are required")
 				os.Exit(1)
 			}
 
 			// Parse validation rules from JSON
 			var rules []jobs.ValidationRule
-			if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
-				fmt.Printf("Error parsing validation rules: %v\n", err)
+			unmarshalFlag(rulesJSON, &rules, "validation rules")
+
+			if err := cli.applyFileFlags(cmd, &files); err != nil {
+				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 
 			// Get the validate service from dependency injection container
 			service := do.MustInvoke[*jobs.ValidateService](cli.injector)
 
-			result, err := service.ValidateFile(inputFile, outputFile, rules, failFast)
+			options, err := flags.options(cmd)
+			if err != nil {
+				fmt.Printf("Error parsing scoring: %v\n", err)
+				os.Exit(1)
+			}
+
+			result, err := service.ValidateFile(inputFile, outputFile, rules, failFast, options)
 			if err != nil {
-				fmt.Printf("Error validating data: %v\n", err)
+				fmt.Printf("Error validating data: %s\n", formatJobError(err))
 				os.Exit(1)
 			}
 
-			fmt.Printf("Data validation completed:\n")
-			fmt.Printf("  Total records: %d\n", result.TotalRows)
-			fmt.Printf("  Valid records: %d\n", result.ValidRows)
-			fmt.Printf("  Invalid records: %d\n", result.InvalidRows)
-			fmt.Printf("  Quality score: %.2f%%\n", result.QualityScore)
-			fmt.Printf("  Output saved to: %s\n", outputFile)
+			printValidationSummary(os.Stdout, result, outputFile, verbose)
+			if code := validationExitCode(result); code != 0 {
+				os.Exit(code)
+			}
 		},
 	}
 
 	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input CSV file (required)")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON file (optional)")
-	cmd.Flags().StringVar(&rulesJSON, "rules", "", "Validation rules in JSON format (required)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output report file (optional)")
+	files.register(cmd)
+	cmd.Flags().StringVar(&rulesJSON, "rules", "", "Validation rules in JSON format (required without --schema)")
+	cmd.Flags().StringVar(&rulesFile, "rules-file", "", "JSON or YAML file of the rules, which may include other rules files (exclusive with --rules)")
+	cmd.MarkFlagsMutuallyExclusive("rules", "rules-file")
 	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop validation on first error")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print every detailed error and warning after the summary of the issues per rule")
+	flags.register(cmd)
 
 	return cmd
 }
 
+// validateFlags holds the flags of validate-data turned into job options.
+type validateFlags struct {
+	reportFormat     string
+	schemaFile       string
+	coerceNumbers    bool
+	requiredColumns  []string
+	forbiddenColumns []string
+	columnOrder      []string
+	autoFix          []string
+	fixedFile        string
+	exportValid      bool
+	exportInvalid    bool
+	invalidExport    string
+	failFastAfter    int
+	profile          bool
+	maxErrors        int
+	errorsPerRule    int
+	minQualityScore  float64
+	scoringJSON      string
+	maxInvalidRows   int
+}
+
+// register adds the validate-data flags turned into job options to a command.
+func (f *validateFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.reportFormat, "report-format", "", "Report format: json, csv or html (default: from the output extension, json otherwise)")
+	cmd.Flags().StringVar(&f.schemaFile, "schema", "", "JSON Schema file every record is validated against, combined with the rules")
+	cmd.Flags().BoolVar(&f.coerceNumbers, "coerce-numbers", false, "Validate numeric-looking values as numbers against the schema, and leave empty values out")
+	cmd.Flags().StringSliceVar(&f.requiredColumns, "required-columns", nil, "Columns the headers must have, besides the fields of the rules")
+	cmd.Flags().StringSliceVar(&f.forbiddenColumns, "forbidden-columns", nil, "Columns the headers must not have")
+	cmd.Flags().StringSliceVar(&f.columnOrder, "expected-column-order", nil, "Relative order of columns in the headers")
+	cmd.Flags().StringSliceVar(&f.autoFix, "auto-fix", nil, "Fix the failing values of these rule types before judging records, every fixable type without a value")
+	cmd.Flags().Lookup("auto-fix").NoOptDefVal = "all"
+	cmd.Flags().BoolVar(&f.exportValid, "export-valid", false, "Export the valid records to <output or input name>_valid.json")
+	cmd.Flags().BoolVar(&f.exportInvalid, "export-invalid", false, "Export the invalid records with their errors to <output or input name>_invalid.json")
+	cmd.Flags().StringVar(&f.invalidExport, "invalid-export", jobs.InvalidExportField, `Format of the invalid records export: field (an "_errors" field), object ({"row","errors"}) or plain`)
+	cmd.Flags().StringVar(&f.fixedFile, "write-fixed", "", "Write the input with its fixes applied to this CSV or JSON file")
+	cmd.Flags().IntVar(&f.failFastAfter, "fail-fast-after", 0, "Stop validation after this many errors, implying --fail-fast")
+	cmd.Flags().BoolVar(&f.profile, "profile", false, "Profile every field, printing its completeness, distinct values and lengths")
+	cmd.Flags().IntVar(&f.maxErrors, "max-errors", 10000, "Errors, and warnings, detailed at most while still counting all of them (0: no limit)")
+	cmd.Flags().IntVar(&f.errorsPerRule, "errors-per-rule", 0, "Errors, and warnings, detailed at most per rule and field (0: no limit)")
+	cmd.Flags().Float64Var(&f.minQualityScore, "min-quality-score", 0, "Exit with code 2 when the quality score, from 0 to 100, is below this one")
+	cmd.Flags().StringVar(&f.scoringJSON, "scoring", "", `Quality score weights in JSON format, like '{"error_weight":100,"warning_weight":5,"rule_weights":{"email":2,"regex:phone":0.5}}'`)
+	cmd.Flags().IntVar(&f.maxInvalidRows, "max-invalid-rows", 0, "Exit with code 3 when there are more invalid records than this (default: no limit)")
+}
+
+// options converts the flags to job options, parsing the scoring weights.
+// Thresholds are only set when their flag is.
+func (f *validateFlags) options(cmd *cobra.Command) (map[string]interface{}, error) {
+	options := map[string]interface{}{
+		"schema_file":           f.schemaFile,
+		"coerce_numbers":        f.coerceNumbers,
+		"required_columns":      f.requiredColumns,
+		"forbidden_columns":     f.forbiddenColumns,
+		"expected_column_order": f.columnOrder,
+		"report_format":         f.reportFormat,
+		"max_errors":            f.maxErrors,
+		"errors_per_rule":       f.errorsPerRule,
+		"min_quality_score":     f.minQualityScore,
+		"include_profile":       f.profile,
+		"export_valid":          f.exportValid,
+		"export_invalid":        f.exportInvalid,
+		"invalid_export":        f.invalidExport,
+	}
+	if cmd.Flags().Changed("max-invalid-rows") {
+		options["max_invalid_rows"] = f.maxInvalidRows
+	}
+	if cmd.Flags().Changed("fail-fast-after") {
+		options["fail_fast_after"] = f.failFastAfter
+	}
+	if slices.Contains(f.autoFix, "all") {
+		options["auto_fix"] = true
+	} else if len(f.autoFix) > 0 {
+		options["auto_fix"] = f.autoFix
+	}
+	if f.fixedFile != "" {
+		options["fixed_file"] = f.fixedFile
+	}
+	if f.scoringJSON != "" {
+		var scoring map[string]interface{}
+		if err := json.Unmarshal([]byte(f.scoringJSON), &scoring); err != nil {
+			return nil, err
+		}
+		options["scoring"] = scoring
+	}
+	return options, nil
+}
+
+// printScoreBreakdown prints the penalties a quality score is made of, largest first.
+func printScoreBreakdown(w io.Writer, breakdown *jobs.ScoreBreakdown) {
+	fmt.Fprintf(w, "  Score breakdown (%s scoring): 100 - %.2f for errors - %.2f for warnings, %.2f%% of records valid\n",
+		breakdown.Profile, breakdown.ErrorPenalty, breakdown.WarningPenalty, breakdown.ValidRatio*100)
+	for _, rule := range breakdown.Rules {
+		fmt.Fprintf(w, "    %s on %s: -%.2f (weight %s)\n", rule.Rule, rule.Field, rule.Penalty, strconv.FormatFloat(rule.Weight, 'g', -1, 64))
+	}
+}
+
+// printRuleSummary prints the issue counts of each rule, one line per rule and field.
+func printRuleSummary(w io.Writer, summary []jobs.RuleSummary) {
+	fmt.Fprintf(w, "  Issues per rule:\n")
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "    rule\tfield\terrors\twarnings\trows\texamples\n")
+	for _, rule := range summary {
+		examples := make([]string, len(rule.Examples))
+		for i, example := range rule.Examples {
+			examples[i] = strconv.Quote(example)
+		}
+		fmt.Fprintf(table, "    %s\t%s\t%d\t%d\t%d\t%s\n", rule.Rule, rule.Field, rule.Errors, rule.Warnings, rule.Rows, strings.Join(examples, ", "))
+	}
+	_ = table.Flush()
+}
+
+// printValidationIssues prints the detailed errors, then warnings, of a result, one per line.
+func printValidationIssues(w io.Writer, result *jobs.ValidationResult) {
+	if len(result.Errors) == 0 && len(result.Warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  Issues:\n")
+	for _, issues := range [][]jobs.ValidationError{result.Errors, result.Warnings} {
+		for _, issue := range issues {
+			fmt.Fprintf(w, "    %s: %s on field '%s': %s\n", issue.RowData.Location(), issue.Severity, issue.FieldName, issue.Message)
+		}
+	}
+}
+
+// printFieldProfiles prints the completeness table of field profiles, one line per field.
+func printFieldProfiles(w io.Writer, profiles []jobs.FieldProfile) {
+	fmt.Fprintf(w, "  Completeness:\n")
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "    field\tfilled\tempty\tdistinct\tlength\n")
+	for _, profile := range profiles {
+		distinct := strconv.Itoa(profile.Distinct)
+		if profile.DistinctCapped {
+			distinct += "+"
+		}
+		length := "-"
+		if profile.NonEmpty > 0 {
+			length = fmt.Sprintf("%d-%d", profile.MinLength, profile.MaxLength)
+		}
+		fmt.Fprintf(table, "    %s\t%.2f%%\t%d\t%s\t%s\n", profile.Field, profile.Completeness, profile.Empty, distinct, length)
+	}
+	_ = table.Flush()
+}
+
+// Exit codes of validate-data when a quality threshold is not met, see its help.
+const (
+	exitMinQualityScore = 2
+	exitMaxInvalidRows  = 3
+)
+
+// validationExitCode returns the exit code of a validation result, that of its first threshold failure, 0 without.
+func validationExitCode(result *jobs.ValidationResult) int {
+	if len(result.ThresholdFailures) == 0 {
+		return 0
+	}
+	switch result.ThresholdFailures[0].Threshold {
+	case jobs.ThresholdMinQualityScore:
+		return exitMinQualityScore
+	default:
+		return exitMaxInvalidRows
+	}
+}
+
+// readRulesFile replaces the rules JSON with the rules of the rules file, when one is given.
+func readRulesFile(rulesJSON *string, rulesFile string) {
+	if rulesFile == "" {
+		return
+	}
+
+	data, err := jobs.ReadRulesFile(rulesFile)
+	if err != nil {
+		fmt.Printf("Error reading rules file: %v\n", err)
+		os.Exit(1)
+	}
+	*rulesJSON = string(data)
+}
+
+// unmarshalFlag decodes a JSON flag into target when it is set, exiting on errors.
+func unmarshalFlag(value string, target interface{}, what string) {
+	if value == "" {
+		return
+	}
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		fmt.Printf("Error parsing %s: %v\n", what, err)
+		os.Exit(1)
+	}
+}
+
+// parseFilterFlag parses filter rules of a JSON flag when it is set, exiting on errors.
+func parseFilterFlag(value, what string) jobs.FilterGroup {
+	if value == "" {
+		return jobs.FilterGroup{}
+	}
+	rules, err := jobs.ParseFilterRules([]byte(value))
+	if err != nil {
+		fmt.Printf("Error parsing %s: %v\n", what, err)
+		os.Exit(1)
+	}
+	return rules
+}
+
+// printValidationSummary prints the totals of a validation result.
+func printValidationSummary(w io.Writer, result *jobs.ValidationResult, outputFile string, verbose bool) {
+	fmt.Fprintf(w, "Data validation completed:\n")
+	for _, structural := range result.Structural {
+		fmt.Fprintf(w, "  Structural error: %s\n", structural.Message)
+	}
+	fmt.Fprintf(w, "  Total records: %d\n", result.TotalRows)
+	printStoppedEarly(w, result)
+	fmt.Fprintf(w, "  Valid records: %d\n", result.ValidRows)
+	fmt.Fprintf(w, "  Invalid records: %d\n", result.InvalidRows)
+	fmt.Fprintf(w, "  Errors: %d\n", result.ErrorCount)
+	fmt.Fprintf(w, "  Warnings: %d\n", result.WarningCount)
+	printFixSummary(w, result)
+	if result.ErrorsTruncated || result.WarningsTruncated {
+		fmt.Fprintf(w, "  Warning: only %d errors and %d warnings are detailed, raise --max-errors or --errors-per-rule for more\n", len(result.Errors), len(result.Warnings))
+	}
+	for _, reference := range result.References {
+		fmt.Fprintf(w, "  Reference values of %s: %d (column '%s' of %s)\n", reference.Field, reference.Size, reference.Column, reference.File)
+	}
+	if result.DuplicateGroups > 0 {
+		fmt.Fprintf(w, "  Duplicate groups: %d\n", result.DuplicateGroups)
+	}
+	fmt.Fprintf(w, "  Quality score: %.2f%%\n", result.QualityScore)
+	if breakdown := result.ScoreBreakdown; breakdown != nil && (verbose || breakdown.Profile != jobs.ScoringDefault) {
+		printScoreBreakdown(w, breakdown)
+	}
+	if outputFile != "" {
+		fmt.Fprintf(w, "  Output saved to: %s\n", outputFile)
+	}
+	if len(result.RuleSummary) > 0 {
+		printRuleSummary(w, result.RuleSummary)
+	}
+	if verbose {
+		printValidationIssues(w, result)
+	}
+	if len(result.Profile) > 0 {
+		printFieldProfiles(w, result.Profile)
+	}
+	for _, failure := range result.ThresholdFailures {
+		fmt.Fprintf(w, "Threshold failed (%s): %s\n", failure.Threshold, failure.Message)
+	}
+}
+
+// printStoppedEarly prints how many records were left unvalidated when the validation stopped early.
+func printStoppedEarly(w io.Writer, result *jobs.ValidationResult) {
+	switch {
+	case result.RowsSkipped > 0 && result.RowsProcessed == 0:
+		fmt.Fprintf(w, "  Stopped early before the first row: %d records not validated\n", result.RowsSkipped)
+	case result.RowsSkipped > 0:
+		fmt.Fprintf(w, "  Stopped early at row %d: %d records not validated, left out of the quality score\n", result.RowsProcessed, result.RowsSkipped)
+	}
+}
+
+// printFixSummary prints the number of fixed values, by rule, if any.
+func printFixSummary(w io.Writer, result *jobs.ValidationResult) {
+	if len(result.Fixes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  Fixed values: %d\n", len(result.Fixes))
+	for _, summary := range result.FixSummary {
+		fmt.Fprintf(w, "    %s on %s: %d\n", summary.Rule, summary.Field, summary.Fixes)
+	}
+}
+
 // newTransformCommand creates the data transformation command.
 func (cli *CLI) newTransformCommand() *cobra.Command {
 	var inputFile, outputFile string
-	var rulesJSON string
-	var keepFields bool
+	var files fileFlags
+	var rulesJSON, rulesFile string
+	var keepFields, chained bool
+	var csvFlags csvOutputFlags
 
 	cmd := &cobra.Command{
 		Use:   "transform-data",
 		Short: "Transform data fields with various operations",
 		Long:  "Transform data fields with various operations using dependency injection",
 		Run: func(cmd *cobra.Command, args []string) {
+			readRulesFile(&rulesJSON, rulesFile)
 			if inputFile == "" || rulesJSON == "" {
 				fmt.Println("Error: input file and rules are required")
 				os.Exit(1)
@@ -335,24 +908,167 @@ func (cli *CLI) newTransformCommand() *cobra.Command {
 				os.Exit(1)
 			}
 
+			if err := cli.applyFileFlags(cmd, &files); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
 			// Get the transform service from dependency injection container
 			service := do.MustInvoke[*jobs.TransformService](cli.injector)
 
-			result, err := service.TransformFile(inputFile, outputFile, rules, keepFields)
+			options := csvFlags.options()
+			options["chained"] = chained
+			result, err := service.TransformFile(inputFile, outputFile, rules, keepFields, options)
 			if err != nil {
-				fmt.Printf("Error transforming data: %v\n", err)
+				fmt.Printf("Error transforming data: %s\n", formatJobError(err))
 				os.Exit(1)
 			}
 
 			fmt.Printf("Successfully transformed %d records from %s to %s\n",
 				result.Processed, inputFile, result.OutputPath)
+			if result.Excluded > 0 {
+				fmt.Printf("Excluded %d records\n", result.Excluded)
+			}
+			if result.Expanded > 0 {
+				fmt.Printf("Explode rules added %d records\n", result.Expanded)
+			}
+			for _, warning := range result.Warnings {
+				fmt.Printf("Warning: %s\n", warning)
+			}
 		},
 	}
 
 	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input CSV file (required)")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON file (optional)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON or CSV file (optional)")
+	files.register(cmd)
 	cmd.Flags().StringVar(&rulesJSON, "rules", "", "Transformation rules in JSON format (required)")
+	cmd.Flags().StringVar(&rulesFile, "rules-file", "", "JSON or YAML file of the rules, which may include other rules files (exclusive with --rules)")
+	cmd.MarkFlagsMutuallyExclusive("rules", "rules-file")
 	cmd.Flags().BoolVar(&keepFields, "keep-fields", true, "Keep non-transformed fields")
+	cmd.Flags().BoolVar(&chained, "chained", false, "Apply rules to the results of the rules before them, rather than to the original values (becomes the default in the next minor version)")
+	csvFlags.register(cmd)
+	csvFlags.registerMetadata(cmd)
+
+	return cmd
+}
+
+// newDedupeCommand creates the deduplication command.
+func (cli *CLI) newDedupeCommand() *cobra.Command {
+	var inputFile, outputFile, removedFile string
+	var files fileFlags
+	var keys []string
+	var keep string
+	var ignoreCase bool
+	var csvFlags csvOutputFlags
+
+	cmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Remove duplicate records by key fields",
+		Long:  "Remove duplicate records by key fields, or by entire record when no keys are given, using dependency injection",
+		Run: func(cmd *cobra.Command, args []string) {
+			if inputFile == "" {
+				fmt.Println("Error: input file is required")
+				os.Exit(1)
+			}
+
+			if err := cli.applyFileFlags(cmd, &files); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Get the dedupe service from dependency injection container
+			service := do.MustInvoke[*jobs.DedupeService](cli.injector)
+
+			options := csvFlags.options()
+			options["ignore_case"] = ignoreCase
+			options["removed_file"] = removedFile
+
+			result, err := service.DedupeFile(inputFile, outputFile, keys, keep, options)
+			if err != nil {
+				fmt.Printf("Error deduplicating data: %s\n", formatJobError(err))
+				os.Exit(1)
+			}
+
+			fmt.Printf("Successfully kept %d records from %s to %s, removed %d duplicates\n",
+				result.Processed, inputFile, result.OutputPath, result.Duplicates)
+			if removedFile != "" {
+				fmt.Printf("Removed records saved to: %s\n", removedFile)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input CSV file (required)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON or CSV file (optional)")
+	files.register(cmd)
+	cmd.Flags().StringSliceVar(&keys, "keys", nil, "Comma-separated key fields, the entire record is compared when empty")
+	cmd.Flags().StringVar(&keep, "keep", jobs.DedupeKeepFirst, "Which occurrence to keep: first or last")
+	cmd.Flags().BoolVar(&ignoreCase, "ignore-case", false, "Compare key values case-insensitively")
+	cmd.Flags().StringVar(&removedFile, "removed-output", "", "Write removed duplicate records to this JSON or CSV file (optional)")
+	csvFlags.register(cmd)
+	csvFlags.registerMetadata(cmd)
+
+	return cmd
+}
+
+// newWindowCommand creates the window command.
+func (cli *CLI) newWindowCommand() *cobra.Command {
+	var inputFile, outputFile, rulesJSON, orderBy string
+	var files fileFlags
+	var partitionBy, dateLayouts []string
+	var csvFlags csvOutputFlags
+
+	cmd := &cobra.Command{
+		Use:   "window",
+		Short: "Append running totals and moving averages to records",
+		Long:  "Append running totals, running counts and moving averages to records ordered by fields, optionally per partition, using dependency injection",
+		Run: func(cmd *cobra.Command, args []string) {
+			if inputFile == "" {
+				fmt.Println("Error: input file is required")
+				os.Exit(1)
+			}
+			if rulesJSON == "" {
+				fmt.Println("Error: rules are required")
+				os.Exit(1)
+			}
+
+			if err := cli.applyFileFlags(cmd, &files); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Parse window rules from JSON
+			var rules []jobs.WindowRule
+			if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+				fmt.Printf("Error parsing window rules: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Get the window service from dependency injection container
+			service := do.MustInvoke[*jobs.WindowService](cli.injector)
+
+			options := csvFlags.options()
+			options["date_layouts"] = dateLayouts
+
+			result, err := service.WindowFile(inputFile, outputFile, rules, orderBy, partitionBy, options)
+			if err != nil {
+				fmt.Printf("Error computing window aggregates: %s\n", formatJobError(err))
+				os.Exit(1)
+			}
+
+			fmt.Printf("Successfully computed window aggregates over %d records from %s to %s\n",
+				result.Processed, inputFile, result.OutputPath)
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input CSV file (required)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output JSON or CSV file (optional)")
+	files.register(cmd)
+	cmd.Flags().StringVar(&rulesJSON, "rules", "", `Window rules in JSON format, like [{"field":"amount","operation":"cumulative_sum"},{"field":"amount","operation":"moving_average","size":7,"alias":"weekly"}] with operations cumulative_sum, cumulative_count or moving_average (required)`)
+	cmd.Flags().StringVar(&orderBy, "order-by", "", `Order records by these comma-separated fields, prefixed with '-' for descending, like "date" (default: input order)`)
+	cmd.Flags().StringSliceVar(&partitionBy, "partition-by", nil, "Comma-separated fields whose values restart the running aggregates (optional)")
+	cmd.Flags().StringSliceVar(&dateLayouts, "date-layouts", nil, "Layouts of ordering dates, like 02/01/2006 or DD/MM/YYYY (default: ISO-8601 dates)")
+	csvFlags.register(cmd)
+	csvFlags.registerMetadata(cmd)
 
 	return cmd
 }
@@ -361,3 +1077,88 @@ func (cli *CLI) newTransformCommand() *cobra.Command {
 func (cli *CLI) AddCommand(command *cobra.Command) {
 	cli.rootCommand.AddCommand(command)
 }
+
+// fileFlags holds the per-command overrides of the files configuration.
+type fileFlags struct {
+	outputMode   string
+	maxInputSize string
+	maxInputRows int
+}
+
+// register adds the file handling flags to a command reading and writing files.
+func (f *fileFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.outputMode, "output-mode", "", "Permissions of created files, e.g. 0600 (overrides files.output_mode)")
+	cmd.Flags().StringVar(&f.maxInputSize, "max-input-size", "", "Largest input loaded in memory, e.g. 4GB, 0 for no limit (overrides files.max_size)")
+	cmd.Flags().IntVar(&f.maxInputRows, "max-input-rows", 0, "Largest number of input rows loaded in memory, 0 for no limit (overrides files.max_rows)")
+}
+
+// applyFileFlags overrides the file service configuration with the flags set on the command.
+func (cli *CLI) applyFileFlags(cmd *cobra.Command, f *fileFlags) error {
+	fileService := do.MustInvoke[*jobs.FileService](cli.injector)
+
+	if f.outputMode != "" {
+		mode, err := config.ParseFileMode(f.outputMode, config.DefaultOutputMode)
+		if err != nil {
+			return err
+		}
+		fileService.SetFileMode(mode)
+	}
+
+	if cmd.Flags().Changed("max-input-size") {
+		maxSize, err := config.ParseByteSize(f.maxInputSize, 0)
+		if err != nil {
+			return err
+		}
+		fileService.SetMaxInputSize(maxSize)
+	}
+
+	if cmd.Flags().Changed("max-input-rows") {
+		fileService.SetMaxInputRows(f.maxInputRows)
+	}
+
+	return nil
+}
+
+// formatJobError renders a job error, with remediation hints for known failures.
+func formatJobError(err error) string {
+	var limitErr *jobs.InputLimitError
+	if errors.As(err, &limitErr) {
+		if limitErr.Unit == "rows" {
+			return fmt.Sprintf("input exceeds limit of %d rows, raise files.max_rows or --max-input-rows, or use --stream where available (%v)", limitErr.Limit, err)
+		}
+		return fmt.Sprintf("input exceeds limit of %d bytes, raise files.max_size or --max-input-size, or use --stream where available (%v)", limitErr.Limit, err)
+	}
+	return err.Error()
+}
+
+// csvOutputFlags holds the output format flags shared by commands that can write CSV files.
+type csvOutputFlags struct {
+	crlf            bool
+	quoteAll        bool
+	includeMetadata bool
+}
+
+// register adds the output format flags to a command.
+func (f *csvOutputFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.crlf, "crlf", false, "Use CRLF line endings when writing CSV output")
+	cmd.Flags().BoolVar(&f.quoteAll, "quote-all", false, "Quote every field when writing CSV output")
+}
+
+// registerMetadata adds the --include-metadata flag to a command writing data rows.
+func (f *csvOutputFlags) registerMetadata(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.includeMetadata, "include-metadata", false, "Include source line numbers in the output")
+}
+
+// options converts the CSV dialect flags to job options.
+func (f *csvOutputFlags) options() map[string]interface{} {
+	quoting := jobs.CSVQuoteMinimal
+	if f.quoteAll {
+		quoting = jobs.CSVQuoteAll
+	}
+
+	return map[string]interface{}{
+		"use_crlf":         f.crlf,
+		"csv_quoting":      string(quoting),
+		"include_metadata": f.includeMetadata,
+	}
+}
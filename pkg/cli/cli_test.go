@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/do-template-cli/pkg/config"
+	"github.com/samber/do-template-cli/pkg/jobs"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintValidationSummary(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "people.csv")
+	is.NoError(os.WriteFile(input, []byte("name,email,age\n"+
+		"alice,alice@example.com,30\n"+
+		",bob@example.com,41\n"+
+		"carol,not-an-email,x\n"+
+		"dave,dave@example.com,27\n"), 0o600))
+
+	injector := do.New()
+	logger := zerolog.Nop()
+	do.ProvideValue(injector, &config.Config{})
+	do.ProvideValue(injector, &logger)
+	jobs.Package(injector)
+	service := do.MustInvoke[*jobs.ValidateService](injector)
+
+	output := filepath.Join(dir, "report.json")
+	result, err := service.ValidateFile(input, output, []jobs.ValidationRule{
+		{Field: "name", Type: "required"},
+		{Field: "email", Type: "email"},
+		{Field: "age", Type: "numeric"},
+		{Field: "name", Type: "max_length", Constraints: float64(4)},
+	}, false, nil)
+	is.NoError(err)
+
+	var summary strings.Builder
+	printValidationSummary(&summary, result, output, false)
+	is.Equal("Data validation completed:\n"+
+		"  Total records: 4\n"+
+		"  Valid records: 2\n"+
+		"  Invalid records: 2\n"+
+		"  Errors: 3\n"+
+		"  Warnings: 2\n"+
+		"  Quality score: 47.50%\n"+
+		"  Output saved to: "+output+"\n"+
+		"  Issues per rule:\n"+
+		"    rule        field  errors  warnings  rows  examples\n"+
+		"    required    name   1       0         1     \"\"\n"+
+		"    email       email  1       0         1     \"not-an-email\"\n"+
+		"    numeric     age    1       0         1     \"x\"\n"+
+		"    max_length  name   0       2         2     \"alice\", \"carol\"\n", summary.String())
+
+	// individual issues are only printed when verbose
+	summary.Reset()
+	printValidationSummary(&summary, result, output, true)
+	_, issues, _ := strings.Cut(summary.String(), "  Issues:\n")
+	is.Equal("    "+input+":3: error on field 'name': Field is required\n"+
+		"    "+input+":4: error on field 'email': Invalid email format\n"+
+		"    "+input+":4: error on field 'age': Value must be numeric\n"+
+		"    "+input+":2: warning on field 'name': Value must be at most 4 characters\n"+
+		"    "+input+":4: warning on field 'name': Value must be at most 4 characters\n", issues)
+
+	// fail-fast validations report the rows they did not validate
+	result, err = service.ValidateFile(input, "", []jobs.ValidationRule{{Field: "email", Type: "email"}}, true, nil)
+	is.NoError(err)
+	summary.Reset()
+	printValidationSummary(&summary, result, "", false)
+	is.Contains(summary.String(), "  Total records: 4\n  Stopped early at row 3: 1 records not validated, left out of the quality score\n")
+	is.Contains(summary.String(), "  Quality score: 66.67%\n")
+
+	// custom scorings explain the score
+	result, err = service.ValidateFile(input, "", []jobs.ValidationRule{
+		{Field: "email", Type: "email"},
+		{Field: "name", Type: "required"},
+	}, false, map[string]interface{}{"scoring": map[string]interface{}{"rule_weights": map[string]interface{}{"required": 2}}})
+	is.NoError(err)
+	summary.Reset()
+	printValidationSummary(&summary, result, "", false)
+	is.Contains(summary.String(), "  Quality score: 25.00%\n"+
+		"  Score breakdown (custom scoring): 100 - 75.00 for errors - 0.00 for warnings, 50.00% of records valid\n"+
+		"    required on name: -50.00 (weight 2)\n"+
+		"    email on email: -25.00 (weight 1)\n")
+}
+
+func TestValidationExitCode(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "people.csv")
+	is.NoError(os.WriteFile(input, []byte("name,age\nalice,30\n,41\nbob,27\n,35\n"), 0o600))
+
+	injector := do.New()
+	logger := zerolog.Nop()
+	do.ProvideValue(injector, &config.Config{})
+	do.ProvideValue(injector, &logger)
+	jobs.Package(injector)
+	service := do.MustInvoke[*jobs.ValidateService](injector)
+	rules := []jobs.ValidationRule{{Field: "name", Type: "required"}}
+
+	testCases := []struct {
+		name     string
+		options  map[string]interface{}
+		code     int
+		failures string
+	}{
+		{"no threshold", nil, 0, ""},
+		{"thresholds met", map[string]interface{}{"min_quality_score": 50.0, "max_invalid_rows": 2}, 0, ""},
+		{"quality score", map[string]interface{}{"min_quality_score": 95.0}, 2,
+			"Threshold failed (min_quality_score): quality score 50.00% is below the minimum of 95.00%\n"},
+		{"invalid rows", map[string]interface{}{"max_invalid_rows": 0}, 3,
+			"Threshold failed (max_invalid_rows): 2 invalid records exceed the maximum of 0\n"},
+		{"both", map[string]interface{}{"min_quality_score": 95.0, "max_invalid_rows": 1}, 2,
+			"Threshold failed (min_quality_score): quality score 50.00% is below the minimum of 95.00%\n" +
+				"Threshold failed (max_invalid_rows): 2 invalid records exceed the maximum of 1\n"},
+	}
+
+	for _, tc := range testCases {
+		result, err := service.ValidateFile(input, "", rules, false, tc.options)
+		is.NoError(err, tc.name)
+		is.Equal(tc.code, validationExitCode(result), tc.name)
+
+		var summary strings.Builder
+		printValidationSummary(&summary, result, "", false)
+		var failures strings.Builder
+		for _, line := range strings.SplitAfter(summary.String(), "\n") {
+			if strings.HasPrefix(line, "Threshold failed") {
+				failures.WriteString(line)
+			}
+		}
+		is.Equal(tc.failures, failures.String(), tc.name)
+	}
+
+	_, err := service.ValidateFile(input, "", rules, false, map[string]interface{}{"min_quality_score": 101.0})
+	is.Error(err)
+	_, err = service.ValidateFile(input, "", rules, false, map[string]interface{}{"max_invalid_rows": -1})
+	is.Error(err)
+}
+
+func TestPrintFieldProfiles(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	var table strings.Builder
+	printFieldProfiles(&table, []jobs.FieldProfile{
+		{Field: "customer_id", NonEmpty: 4, Empty: 0, Completeness: 100, Distinct: 10000, DistinctCapped: true, MinLength: 3, MaxLength: 12},
+		{Field: "email", NonEmpty: 0, Empty: 4, Completeness: 0},
+	})
+	is.Equal("  Completeness:\n"+
+		"    field        filled   empty  distinct  length\n"+
+		"    customer_id  100.00%  0      10000+    3-12\n"+
+		"    email        0.00%    4      0         -\n", table.String())
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/samber/do/v2"
@@ -14,6 +16,7 @@ import (
 type Config struct {
 	Logger LoggerConfig `mapstructure:"logger"`
 	App    AppConfig    `mapstructure:"app"`
+	Files  FilesConfig  `mapstructure:"files"`
 }
 
 // LoggerConfig holds logger configuration.
@@ -32,6 +35,79 @@ type AppConfig struct {
 	Debug       bool   `mapstructure:"debug"`
 }
 
+// FilesConfig holds configuration for files created by the data processing jobs.
+type FilesConfig struct {
+	OutputMode string `mapstructure:"output_mode"` // octal permissions of created files, e.g. "0600"
+	DirMode    string `mapstructure:"dir_mode"`    // octal permissions of created output directories
+	MaxSize    string `mapstructure:"max_size"`    // largest input file loaded in memory, e.g. "512MB", "0" for no limit
+	MaxRows    int    `mapstructure:"max_rows"`    // largest number of rows loaded in memory, 0 for no limit
+}
+
+const (
+	// DefaultOutputMode is the permission set used for created files when none is configured.
+	DefaultOutputMode os.FileMode = 0o644
+	// DefaultDirMode is the permission set used for created directories when none is configured.
+	DefaultDirMode os.FileMode = 0o755
+	// DefaultMaxSize is the largest input file loaded in memory when no limit is configured.
+	DefaultMaxSize int64 = 1 << 30
+)
+
+// byteSizeUnits maps size suffixes to their multiplier, longest suffixes first.
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size such as "1048576", "512MB" or "2G" into bytes,
+// returning fallback when the string is empty. Units are powers of 1024.
+func ParseByteSize(value string, fallback int64) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return fallback, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.multiplier
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q: expected bytes or a value like 512MB", value)
+	}
+
+	return size * multiplier, nil
+}
+
+// ParseFileMode parses an octal permission string such as "0600",
+// returning fallback when the string is empty.
+func ParseFileMode(value string, fallback os.FileMode) (os.FileMode, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q: expected octal permissions like 0644", value)
+	}
+
+	return os.FileMode(mode), nil
+}
+
 // NewConfig creates a new configuration instance using viper
 // This demonstrates configuration management with the samber/do library.
 func NewConfig(i do.Injector) (*Config, error) {
@@ -63,6 +139,12 @@ func (cs *Config) SetCobraFlags(cmd *cobra.Command) {
 	_ = cmd.PersistentFlags().String("app.environment", "development", "Application environment")
 	_ = cmd.PersistentFlags().Bool("app.debug", false, "Debug mode")
 
+	// Files flags
+	_ = cmd.PersistentFlags().String("files.output_mode", "0644", "Permissions of created output files (octal)")
+	_ = cmd.PersistentFlags().String("files.dir_mode", "0755", "Permissions of created output directories (octal)")
+	_ = cmd.PersistentFlags().String("files.max_size", "1GB", "Largest input file loaded in memory, 0 for no limit")
+	_ = cmd.PersistentFlags().Int("files.max_rows", 0, "Largest number of input rows loaded in memory, 0 for no limit")
+
 	// Bind all flags to viper for automatic configuration
 	cs.bindFlagsToViper(cmd)
 }
@@ -80,4 +162,10 @@ func (cs *Config) bindFlagsToViper(cmd *cobra.Command) {
 	_ = viper.BindPFlag("app.version", cmd.PersistentFlags().Lookup("app.version"))
 	_ = viper.BindPFlag("app.environment", cmd.PersistentFlags().Lookup("app.environment"))
 	_ = viper.BindPFlag("app.debug", cmd.PersistentFlags().Lookup("app.debug"))
+
+	// Files flags
+	_ = viper.BindPFlag("files.output_mode", cmd.PersistentFlags().Lookup("files.output_mode"))
+	_ = viper.BindPFlag("files.dir_mode", cmd.PersistentFlags().Lookup("files.dir_mode"))
+	_ = viper.BindPFlag("files.max_size", cmd.PersistentFlags().Lookup("files.max_size"))
+	_ = viper.BindPFlag("files.max_rows", cmd.PersistentFlags().Lookup("files.max_rows"))
 }
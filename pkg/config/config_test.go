@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileMode(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	mode, err := ParseFileMode("", DefaultOutputMode)
+	is.NoError(err)
+	is.Equal(DefaultOutputMode, mode)
+
+	mode, err = ParseFileMode("0600", DefaultOutputMode)
+	is.NoError(err)
+	is.Equal(os.FileMode(0o600), mode)
+
+	mode, err = ParseFileMode("750", DefaultDirMode)
+	is.NoError(err)
+	is.Equal(os.FileMode(0o750), mode)
+
+	_, err = ParseFileMode("0999", DefaultOutputMode)
+	is.Error(err)
+
+	_, err = ParseFileMode("1777", DefaultOutputMode)
+	is.Error(err)
+}
+
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	testCases := map[string]int64{
+		"":       DefaultMaxSize,
+		"0":      0,
+		"1024":   1024,
+		"512KB":  512 << 10,
+		"512 mb": 512 << 20,
+		"2G":     2 << 30,
+		"10B":    10,
+	}
+
+	for input, expected := range testCases {
+		size, err := ParseByteSize(input, DefaultMaxSize)
+		is.NoError(err, input)
+		is.Equal(expected, size, input)
+	}
+
+	_, err := ParseByteSize("-1", DefaultMaxSize)
+	is.Error(err)
+
+	_, err = ParseByteSize("lots", DefaultMaxSize)
+	is.Error(err)
+}
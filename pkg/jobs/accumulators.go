@@ -0,0 +1,514 @@
+package jobs
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// accumulator computes the result of an aggregation rule over the rows of a group, one row at a time,
+// so that groups never hold their rows.
+type accumulator interface {
+	// add folds a row into the running state, failing on a value the numeric policy rejects.
+	add(row DataRow) error
+	// result returns the aggregate and the number of rows the operation skipped.
+	result() (interface{}, int)
+}
+
+// newAccumulator returns the accumulator of a rule, or nil for an unknown operation.
+// Numeric operations treat values that are not numbers according to the numeric policy,
+// and every operation treats empty values according to the null policy of the rule.
+func (s *AggregateService) newAccumulator(rule AggregateRule, opts *AggregateOptions) accumulator {
+	policy := opts.NumericPolicy
+	includeNulls := rule.nulls == NullPolicyInclude
+	values := numericField{field: rule.Field, policy: policy, includeNulls: includeNulls}
+	//nolint:exhaustive
+	switch rule.Operation {
+	case Count, CountNonNull:
+		return &countAccumulator{field: rule.Field, includeNulls: includeNulls}
+	case Sum:
+		return &sumAccumulator{values: values}
+	case Average:
+		return &averageAccumulator{values: values}
+	case Min, Max:
+		return newExtremeAccumulator(rule, opts)
+	case Distinct:
+		if rule.approximate {
+			return &approximateDistinctAccumulator{field: rule.Field, includeNulls: includeNulls, sketch: newHyperLogLog()}
+		}
+		return &distinctAccumulator{field: rule.Field, includeNulls: includeNulls, values: make(map[string]struct{})}
+	case Collect:
+		return &collectAccumulator{field: rule.Field, includeNulls: includeNulls, opts: rule.collect, seen: make(map[string]struct{})}
+	case DistinctValues:
+		return &distinctValuesAccumulator{collectAccumulator: collectAccumulator{field: rule.Field, includeNulls: includeNulls, opts: rule.collect}}
+	case WeightedAverage:
+		return &weightedAverageAccumulator{
+			values:  values,
+			weights: numericField{field: rule.weight, policy: policy, includeNulls: includeNulls},
+			logger:  s.logger,
+		}
+	case Correlation:
+		return &correlationAccumulator{x: values, y: numericField{field: rule.with, policy: policy, includeNulls: includeNulls}}
+	case CountIf:
+		return &conditionalAccumulator{
+			matches: s.conditionMatcher(rule.condition),
+			inner:   &countAccumulator{field: rule.Field, includeNulls: includeNulls},
+		}
+	case SumIf:
+		return &conditionalAccumulator{matches: s.conditionMatcher(rule.condition), inner: &sumAccumulator{values: values}}
+	case Mode:
+		return &modeAccumulator{field: rule.Field, includeNulls: includeNulls, counts: make(map[string]int)}
+	default:
+		return nil
+	}
+}
+
+// conditionMatcher returns whether rows match the condition of a "count_if" or "sum_if" rule.
+func (s *AggregateService) conditionMatcher(condition FilterGroup) func(DataRow) bool {
+	return func(row DataRow) bool {
+		return s.filterService.matchConditions(row, condition)
+	}
+}
+
+// numericField reads the numbers of a field, applying a numeric policy to values that are not numbers.
+// Empty values are read as 0 when nulls are included, and ignored otherwise.
+type numericField struct {
+	field        string
+	policy       NumericPolicy
+	includeNulls bool
+	skipped      int // values that are not numbers, skipped under NumericPolicySkip
+}
+
+// value returns the number of a row, and whether there is one to use.
+func (f *numericField) value(row DataRow) (float64, bool, error) {
+	value := row.Fields[f.field]
+	if value == "" {
+		return 0, f.includeNulls, nil
+	}
+	if num, err := strconv.ParseFloat(value, 64); err == nil {
+		return num, true, nil
+	}
+
+	//nolint:exhaustive
+	switch f.policy {
+	case NumericPolicyZero:
+		return 0, true, nil
+	case NumericPolicyError:
+		return 0, false, &NumericValueError{Field: f.field, Value: value, Location: row.Location()}
+	default:
+		f.skipped++
+		return 0, false, nil
+	}
+}
+
+// countAccumulator counts rows, only those with a non-empty value when nulls are excluded.
+// Every row counts when the rule has no field.
+type countAccumulator struct {
+	field        string
+	includeNulls bool
+	count        int
+}
+
+func (a *countAccumulator) add(row DataRow) error {
+	if a.includeNulls || a.field == "" || row.Fields[a.field] != "" {
+		a.count++
+	}
+	return nil
+}
+
+func (a *countAccumulator) result() (interface{}, int) {
+	return a.count, 0
+}
+
+// sumAccumulator sums the numbers of a field.
+type sumAccumulator struct {
+	values numericField
+	sum    float64
+}
+
+func (a *sumAccumulator) add(row DataRow) error {
+	val, ok, err := a.values.value(row)
+	if ok {
+		a.sum += val
+	}
+	return err
+}
+
+func (a *sumAccumulator) result() (interface{}, int) {
+	return a.sum, a.values.skipped
+}
+
+// averageAccumulator averages the numbers of a field, ignoring the rows without one.
+// The average is nil when no value is a number.
+type averageAccumulator struct {
+	values numericField
+	sum    float64
+	count  int
+}
+
+func (a *averageAccumulator) add(row DataRow) error {
+	val, ok, err := a.values.value(row)
+	if ok {
+		a.sum += val
+		a.count++
+	}
+	return err
+}
+
+func (a *averageAccumulator) result() (interface{}, int) {
+	if a.count == 0 {
+		return nil, a.values.skipped
+	}
+	return a.sum / float64(a.count), a.values.skipped
+}
+
+// extremeAccumulator keeps the smallest value of a field when less is set, the largest otherwise.
+// Values compare as the value type of the rule, detected from the first non-empty value when auto:
+// numbers follow the numeric policy, while dates that do not parse are skipped.
+// Included empty values compare as 0 or as the empty string, and are skipped in date mode.
+type extremeAccumulator struct {
+	values     numericField
+	comparator valueComparator
+	less       bool
+	best       comparableValue
+	found      bool
+	nulls      int // included empty values, compared once the type is known
+	skipped    int // values that are not dates, in date mode
+}
+
+// newExtremeAccumulator returns the accumulator of a "min" or "max" rule.
+func newExtremeAccumulator(rule AggregateRule, opts *AggregateOptions) *extremeAccumulator {
+	valueType := rule.valueType
+	if valueType == "" {
+		valueType = ValueTypeAuto
+	}
+	return &extremeAccumulator{
+		values:     numericField{field: rule.Field, policy: opts.NumericPolicy, includeNulls: rule.nulls == NullPolicyInclude},
+		comparator: valueComparator{valueType: valueType, layouts: opts.DateLayouts},
+		less:       rule.Operation == Min,
+	}
+}
+
+func (a *extremeAccumulator) add(row DataRow) error {
+	value := row.Fields[a.values.field]
+	if value == "" {
+		if a.values.includeNulls {
+			a.nulls++
+		}
+		return nil
+	}
+
+	if a.comparator.valueType == ValueTypeAuto {
+		a.comparator.valueType = detectValueType(value, a.comparator.layouts)
+	}
+
+	if a.comparator.valueType == ValueTypeNumber {
+		num, ok, err := a.values.value(row)
+		if ok {
+			a.update(comparableValue{raw: value, num: num})
+		}
+		return err
+	}
+
+	parsed, ok := a.comparator.parse(value)
+	if !ok {
+		a.skipped++
+		return nil
+	}
+	a.update(parsed)
+	return nil
+}
+
+// update keeps a value if it is the new extreme.
+func (a *extremeAccumulator) update(value comparableValue) {
+	a.best, a.found = a.extremeOf(a.best, a.found, value)
+}
+
+// extreme returns the extreme value: a number, or the original date or string.
+// It is nil when no value is of the type.
+func (a *extremeAccumulator) extreme() interface{} {
+	best, found := a.best, a.found
+	if a.nulls > 0 {
+		//nolint:exhaustive
+		switch a.comparator.valueType {
+		case ValueTypeNumber, ValueTypeString, ValueTypeAuto:
+			// 0 or the empty string, which is the value of a field without any other
+			best, found = a.extremeOf(best, found, comparableValue{})
+		}
+	}
+
+	switch {
+	case !found:
+		return nil
+	case a.comparator.valueType == ValueTypeNumber:
+		return best.num
+	default:
+		return best.raw
+	}
+}
+
+// extremeOf returns the extreme of the current one, if found, and another value.
+func (a *extremeAccumulator) extremeOf(best comparableValue, found bool, value comparableValue) (comparableValue, bool) {
+	c := a.comparator.compare(value, best)
+	if !found || a.less && c < 0 || !a.less && c > 0 {
+		return value, true
+	}
+	return best, true
+}
+
+func (a *extremeAccumulator) result() (interface{}, int) {
+	skipped := a.values.skipped + a.skipped
+	if a.comparator.valueType == ValueTypeDate {
+		skipped += a.nulls
+	}
+	return a.extreme(), skipped
+}
+
+// distinctAccumulator counts the distinct values of a field exactly, holding each of them once.
+// The empty value is one of them when nulls are included.
+type distinctAccumulator struct {
+	field        string
+	includeNulls bool
+	values       map[string]struct{}
+}
+
+func (a *distinctAccumulator) add(row DataRow) error {
+	if value := row.Fields[a.field]; value != "" || a.includeNulls {
+		a.values[value] = struct{}{}
+	}
+	return nil
+}
+
+func (a *distinctAccumulator) result() (interface{}, int) {
+	return int64(len(a.values)), 0
+}
+
+// approximateDistinctAccumulator estimates the number of distinct values of a field in constant memory.
+type approximateDistinctAccumulator struct {
+	field        string
+	includeNulls bool
+	sketch       *hyperLogLog
+}
+
+func (a *approximateDistinctAccumulator) add(row DataRow) error {
+	if value := row.Fields[a.field]; value != "" || a.includeNulls {
+		a.sketch.add(value)
+	}
+	return nil
+}
+
+func (a *approximateDistinctAccumulator) result() (interface{}, int) {
+	return int64(math.Round(a.sketch.estimate())), 0
+}
+
+// collectAccumulator gathers the values of a field, as a joined string or a []string, empty values
+// only when nulls are included. At most limit values are ever held: in input order collecting stops
+// once the limit is reached, in sorted order only the smallest values are kept.
+type collectAccumulator struct {
+	field        string
+	includeNulls bool
+	opts         *collectOptions
+	values       []string
+	seen         map[string]struct{}
+}
+
+func (a *collectAccumulator) add(row DataRow) error {
+	value := row.Fields[a.field]
+	if value == "" && !a.includeNulls {
+		return nil
+	}
+
+	if a.opts.order == CollectOrderSorted {
+		a.values = insertSorted(a.values, value, a.opts.distinct, a.opts.limit)
+		return nil
+	}
+
+	if a.opts.limit > 0 && len(a.values) >= a.opts.limit {
+		return nil
+	}
+	if a.opts.distinct {
+		if _, ok := a.seen[value]; ok {
+			return nil
+		}
+		a.seen[value] = struct{}{}
+	}
+	a.values = append(a.values, value)
+	return nil
+}
+
+func (a *collectAccumulator) result() (interface{}, int) {
+	if a.opts.asArray {
+		if a.values == nil {
+			return []string{}, 0
+		}
+		return a.values, 0
+	}
+	return strings.Join(a.values, a.opts.separator), 0
+}
+
+// distinctValuesAccumulator gathers the smallest distinct values of a field in sorted order,
+// up to the limit, and reports whether other values were dropped.
+type distinctValuesAccumulator struct {
+	collectAccumulator
+	truncated bool
+}
+
+func (a *distinctValuesAccumulator) add(row DataRow) error {
+	value := row.Fields[a.field]
+	if value == "" && !a.includeNulls {
+		return nil
+	}
+
+	// a new value beyond the limit is either dropped or evicts the largest one
+	if i := sort.SearchStrings(a.values, value); len(a.values) >= a.opts.limit && (i == len(a.values) || a.values[i] != value) {
+		a.truncated = true
+	}
+	a.values = insertSorted(a.values, value, true, a.opts.limit)
+	return nil
+}
+
+// weightedAverageAccumulator computes sum(value*weight)/sum(weight) over the rows with both
+// a value and a weight, counting the skipped rows.
+// The average is nil when the total weight is zero.
+type weightedAverageAccumulator struct {
+	values      numericField
+	weights     numericField
+	logger      zerolog.Logger
+	weightedSum float64
+	totalWeight float64
+	skipped     int
+}
+
+func (a *weightedAverageAccumulator) add(row DataRow) error {
+	value, valueOK, err := a.values.value(row)
+	if err != nil {
+		return err
+	}
+	weight, weightOK, err := a.weights.value(row)
+	if err != nil {
+		return err
+	}
+	if !valueOK || !weightOK {
+		a.logger.Debug().Str("location", row.Location()).Str("field", a.values.field).Str("weight", a.weights.field).Msg("Row skipped by weighted average")
+		a.skipped++
+		return nil
+	}
+	a.weightedSum += value * weight
+	a.totalWeight += weight
+	return nil
+}
+
+func (a *weightedAverageAccumulator) result() (interface{}, int) {
+	if a.totalWeight == 0 {
+		return nil, a.skipped
+	}
+	return a.weightedSum / a.totalWeight, a.skipped
+}
+
+// correlationAccumulator computes Pearson's r between two fields over the rows with both numbers,
+// counting the skipped rows. Means and co-moments are updated in a single pass, which stays
+// numerically stable on large values. The correlation is nil with fewer than two pairs or
+// when either field does not vary.
+type correlationAccumulator struct {
+	x, y         numericField
+	n            int
+	meanX, meanY float64
+	m2X, m2Y     float64 // sums of squared deviations
+	coMoment     float64 // sum of products of deviations
+	skipped      int
+}
+
+func (a *correlationAccumulator) add(row DataRow) error {
+	x, xOK, err := a.x.value(row)
+	if err != nil {
+		return err
+	}
+	y, yOK, err := a.y.value(row)
+	if err != nil {
+		return err
+	}
+	if !xOK || !yOK {
+		a.skipped++
+		return nil
+	}
+
+	a.n++
+	dx := x - a.meanX
+	a.meanX += dx / float64(a.n)
+	dy := y - a.meanY
+	a.meanY += dy / float64(a.n)
+	a.m2X += dx * (x - a.meanX)
+	a.m2Y += dy * (y - a.meanY)
+	a.coMoment += dx * (y - a.meanY)
+	return nil
+}
+
+// correlation returns Pearson's r, and whether it is defined.
+func (a *correlationAccumulator) correlation() (float64, bool) {
+	if a.n < 2 || a.m2X == 0 || a.m2Y == 0 {
+		return 0, false
+	}
+	return a.coMoment / math.Sqrt(a.m2X*a.m2Y), true
+}
+
+func (a *correlationAccumulator) result() (interface{}, int) {
+	if r, ok := a.correlation(); ok {
+		return r, a.skipped
+	}
+	return nil, a.skipped
+}
+
+// conditionalAccumulator feeds only the rows matching a condition to another accumulator.
+type conditionalAccumulator struct {
+	matches func(DataRow) bool
+	inner   accumulator
+}
+
+func (a *conditionalAccumulator) add(row DataRow) error {
+	if !a.matches(row) {
+		return nil
+	}
+	return a.inner.add(row)
+}
+
+func (a *conditionalAccumulator) result() (interface{}, int) {
+	return a.inner.result()
+}
+
+// modeAccumulator counts the values of a field to find the most frequent one,
+// the empty value only when nulls are included.
+type modeAccumulator struct {
+	field        string
+	includeNulls bool
+	counts       map[string]int
+}
+
+func (a *modeAccumulator) add(row DataRow) error {
+	if value := row.Fields[a.field]; value != "" || a.includeNulls {
+		a.counts[value]++
+	}
+	return nil
+}
+
+// mode returns the most frequent value and its frequency, the lexicographically smallest value
+// on ties. The frequency is 0 when no value was counted.
+func (a *modeAccumulator) mode() (string, int) {
+	var mode string
+	best := 0
+	for value, count := range a.counts {
+		if count > best || count == best && value < mode {
+			mode, best = value, count
+		}
+	}
+	return mode, best
+}
+
+func (a *modeAccumulator) result() (interface{}, int) {
+	if value, count := a.mode(); count > 0 {
+		return value, 0
+	}
+	return nil, 0
+}
@@ -1,8 +1,11 @@
 package jobs
 
 import (
+	"container/heap"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,15 +17,17 @@ import (
 // AggregateService handles data aggregation operations
 // This service demonstrates data summarization and statistical analysis with dependency injection.
 type AggregateService struct {
-	fileService *FileService   `do:""`
-	logger      zerolog.Logger `do:""`
+	fileService   *FileService   `do:""`
+	filterService *FilterService `do:""`
+	logger        zerolog.Logger `do:""`
 }
 
 // NewAggregateService creates a new aggregate service with dependency injection.
 func NewAggregateService(i do.Injector) (*AggregateService, error) {
 	return &AggregateService{
-		fileService: do.MustInvoke[*FileService](i),
-		logger:      do.MustInvoke[zerolog.Logger](i),
+		fileService:   do.MustInvoke[*FileService](i),
+		filterService: do.MustInvoke[*FilterService](i),
+		logger:        *do.MustInvoke[*zerolog.Logger](i),
 	}, nil
 }
 
@@ -37,38 +42,281 @@ const (
 	Max      AggregateOperation = "max"
 	GroupBy  AggregateOperation = "group_by"
 	Distinct AggregateOperation = "distinct"
+	Collect  AggregateOperation = "collect"
+
+	WeightedAverage AggregateOperation = "weighted_average"
+	CountIf         AggregateOperation = "count_if"
+	SumIf           AggregateOperation = "sum_if"
+	Mode            AggregateOperation = "mode"
+	DistinctValues  AggregateOperation = "distinct_values"
+	Correlation     AggregateOperation = "correlation"
+	CountNonNull    AggregateOperation = "count_nonnull"
+)
+
+// DefaultDistinctValuesLimit is the number of values a "distinct_values" rule keeps unless its limit is set.
+const DefaultDistinctValuesLimit = 100
+
+// Collect orders.
+const (
+	CollectOrderInput  = "input"
+	CollectOrderSorted = "sorted"
 )
 
 // AggregateRule defines an aggregation rule.
 type AggregateRule struct {
-	Field     string             `json:"field"`
-	Operation AggregateOperation `json:"operation"`
-	Alias     string             `json:"alias,omitempty"`
+	Field      string                 `json:"field"`
+	Operation  AggregateOperation     `json:"operation"`
+	Alias      string                 `json:"alias,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"` // operation settings, like "weight" of weighted_average or "condition" of count_if
+
+	collect   *collectOptions // parsed parameters of a "collect" or "distinct_values" rule
+	weight    string          // weight field of a "weighted_average" rule
+	with      string          // second field of a "correlation" rule
+	valueType ValueType       // how a "min" or "max" rule compares values
+	condition FilterGroup     // rows counted by a "count_if" or "sum_if" rule
+	withCount bool            // whether a "mode" rule also reports the frequency, as "<alias>_count"
+
+	approximate bool // whether a "distinct" rule estimates the count in constant memory
+
+	nulls      NullPolicy // how the rule treats empty values, the default of its operation unless set
+	countNulls bool       // whether groups report the empty values of the field, when nulls is set explicitly
+}
+
+// alias returns the name of the rule result, "<field>_<operation>" unless Alias is set.
+func (r AggregateRule) alias() string {
+	if r.Alias != "" {
+		return r.Alias
+	}
+	return fmt.Sprintf("%s_%s", r.Field, r.Operation)
+}
+
+// collectOptions holds the parameters of a "collect" rule.
+type collectOptions struct {
+	distinct  bool   // drop repeated values
+	separator string // joins the values unless asArray, "," by default
+	limit     int    // maximum number of values, 0 for no limit
+	asArray   bool   // emit a JSON array instead of a joined string
+	order     string // "input" (default) or "sorted"
 }
 
 // AggregateOptions contains aggregation configuration.
 type AggregateOptions struct {
-	InputFile  string          `json:"input_file"`
+	InputFile  string          `json:"input_file"`            // path or glob of the input, like "exports/*.csv"
+	InputFiles []string        `json:"input_files,omitempty"` // further paths or globs, read after InputFile
 	OutputFile string          `json:"output_file"`
 	Rules      []AggregateRule `json:"rules"`
-	GroupBy    []string        `json:"group_by,omitempty"`
-	SortBy     string          `json:"sort_by,omitempty"`
-	SortDesc   bool            `json:"sort_desc,omitempty"`
+	GroupBy    []GroupByField  `json:"group_by,omitempty"`
+	Sort       []SortKey       `json:"sort,omitempty"`      // sort keys, in priority order, SortBy being used when empty
+	SortBy     string          `json:"sort_by,omitempty"`   // comma-separated sort keys, see ParseSortKeys
+	SortDesc   bool            `json:"sort_desc,omitempty"` // direction of the sort keys without a sign
+	CSV        CSVWriteOptions `json:"csv"`                 // dialect used when the output file is a CSV
+
+	// DateLayouts are the layouts of the dates grouped by bucket, see time.Parse, DefaultDateLayouts when empty.
+	DateLayouts []string `json:"date_layouts,omitempty"`
+
+	// Offset and Limit page through the sorted groups, Limit 0 meaning no limit.
+	Offset int `json:"offset,omitempty"`
+	Limit  int `json:"limit,omitempty"`
+
+	sortKeys []SortKey // Sort or parsed SortBy, ordered by group key when empty
+
+	// Having keeps only the groups matching these rules, evaluated against the count,
+	// the group values and the aggregates of each group, under their aliases.
+	Having FilterGroup `json:"having,omitempty"`
+
+	// NumericPolicy tells what numeric aggregates do with non-empty values that are not numbers.
+	NumericPolicy NumericPolicy `json:"numeric_policy,omitempty"`
+
+	// Rollup adds subtotal groups for each prefix of the group-by fields and a grand total, like SQL
+	// ROLLUP. Rolled-up fields have RollupMarker as value, DefaultRollupMarker when empty.
+	Rollup       bool   `json:"rollup,omitempty"`
+	RollupMarker string `json:"rollup_marker,omitempty"`
+
+	// RankBy adds a RankAggregate to each group, ranking groups by this sort key after having and
+	// before the limit, highest value first unless the key ascends, like "total:asc". Groups are
+	// ordered by rank when no sort key is set. RankMethod tells how ties are ranked.
+	RankBy     string     `json:"rank_by,omitempty"`
+	RankMethod RankMethod `json:"rank_method,omitempty"`
+
+	rankKey *SortKey // parsed RankBy, nil when groups are not ranked
+
+	// Stages aggregate the rows of the previous stage, in order, the options themselves being the first
+	// stage: grouping by customer then averaging "amount_sum" gives the average of per-customer totals.
+	// A stage takes the rules, group-by fields and group options, but no input or output file.
+	Stages []*AggregateOptions `json:"stages,omitempty"`
+}
+
+// RankAggregate is the aggregate holding the rank of a group when RankBy is set.
+const RankAggregate = "rank"
+
+// RankMethod tells how groups with the same value are ranked.
+type RankMethod string
+
+const (
+	// RankStandard gives tied groups the same rank and skips the ranks they take, like 1, 2, 2, 4.
+	// This is the default.
+	RankStandard RankMethod = "standard"
+	// RankDense gives tied groups the same rank without skipping any, like 1, 2, 2, 3.
+	RankDense RankMethod = "dense"
+)
+
+// ParseRankMethod validates a rank method name, the empty name being RankStandard.
+func ParseRankMethod(name string) (RankMethod, error) {
+	switch method := RankMethod(strings.ToLower(name)); method {
+	case "", RankStandard:
+		return RankStandard, nil
+	case RankDense:
+		return method, nil
+	default:
+		return "", fmt.Errorf("unknown rank method %q: expected standard or dense", name)
+	}
+}
+
+// DefaultRollupMarker is the value of the group-by fields rolled up in subtotal and grand total groups.
+const DefaultRollupMarker = "(all)"
+
+// NumericPolicy tells what numeric aggregates, like sum or average, do with a value that is not a number.
+// Empty values are missing rather than invalid, and always ignored.
+type NumericPolicy string
+
+const (
+	// NumericPolicySkip ignores the value and counts it as skipped. This is the default.
+	NumericPolicySkip NumericPolicy = "skip"
+	// NumericPolicyZero uses 0 instead of the value.
+	NumericPolicyZero NumericPolicy = "zero"
+	// NumericPolicyError aborts the run with a NumericValueError.
+	NumericPolicyError NumericPolicy = "error"
+)
+
+// ParseNumericPolicy validates a numeric policy name, the empty name being NumericPolicySkip.
+func ParseNumericPolicy(name string) (NumericPolicy, error) {
+	switch policy := NumericPolicy(strings.ToLower(name)); policy {
+	case "", NumericPolicySkip:
+		return NumericPolicySkip, nil
+	case NumericPolicyZero, NumericPolicyError:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown numeric policy %q: expected skip, zero or error", name)
+	}
+}
+
+// NullPolicy tells whether an aggregation rule takes empty values into account, set per rule
+// with the "null_policy" parameter.
+type NullPolicy string
+
+const (
+	// NullPolicyInclude takes empty values into account: counts count them, numeric aggregates
+	// read them as 0, and other aggregates as the empty string. This is the default of count and count_if.
+	NullPolicyInclude NullPolicy = "include"
+	// NullPolicyExclude ignores empty values. This is the default of every other operation.
+	NullPolicyExclude NullPolicy = "exclude"
+)
+
+// ParseNullPolicy validates a null policy name, the empty name being the default of the operation.
+func ParseNullPolicy(name string, operation AggregateOperation) (NullPolicy, error) {
+	switch policy := NullPolicy(strings.ToLower(name)); policy {
+	case "":
+		if operation == Count || operation == CountIf {
+			return NullPolicyInclude, nil
+		}
+		return NullPolicyExclude, nil
+	case NullPolicyInclude, NullPolicyExclude:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown null policy %q: expected include or exclude", name)
+	}
+}
+
+// NumericValueError is returned when a numeric aggregate meets a value that is not a number,
+// under the NumericPolicyError policy.
+type NumericValueError struct {
+	Field    string
+	Value    string
+	Location string // location of the row, see DataRow.Location
+}
+
+// Error implements error.
+func (e *NumericValueError) Error() string {
+	return fmt.Sprintf("value %q of field '%s' at %s is not a number", e.Value, e.Field, e.Location)
+}
+
+// numeric reports whether a rule aggregates numbers, and is subject to the numeric policy.
+// Min and max only count when their value type is forced to number, as they may otherwise
+// compare dates or strings.
+func (r AggregateRule) numeric() bool {
+	//nolint:exhaustive
+	switch r.Operation {
+	case Sum, Average, WeightedAverage, SumIf, Correlation:
+		return true
+	case Min, Max:
+		return r.valueType == ValueTypeNumber
+	default:
+		return false
+	}
+}
+
+// InvalidBucket is the group value of dates or numbers that do not parse when grouping by date bucket or bin.
+const InvalidBucket = "_invalid"
+
+// GroupByField is a field rows are grouped by, either its raw values, the date buckets or the numeric bins
+// they fall in. In JSON it is either a field name or an object like
+// {"field":"created_at","bucket":"month","format":"2006-01"} or {"field":"amount","bins":{"width":50}}.
+type GroupByField struct {
+	Field  string     `json:"field"`
+	Bucket DateBucket `json:"bucket,omitempty"` // group dates by this period instead of by value
+	Format string     `json:"format,omitempty"` // layout of the bucket labels, see DateBucket.Label
+	Bins   *BinSpec   `json:"bins,omitempty"`   // group numbers by bin instead of by value
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a bare field name.
+func (f *GroupByField) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*f = GroupByField{Field: name}
+		return nil
+	}
+
+	type plain GroupByField
+	return json.Unmarshal(data, (*plain)(f))
+}
+
+// groupByNames returns the names of the group-by fields.
+func groupByNames(groupBy []GroupByField) []string {
+	names := make([]string, 0, len(groupBy))
+	for _, field := range groupBy {
+		names = append(names, field.Field)
+	}
+	return names
 }
 
 // AggregateResult represents the result of an aggregation operation.
 type AggregateResult struct {
-	Groups    []GroupResult  `json:"groups,omitempty"`
-	Summary   *SummaryResult `json:"summary,omitempty"`
-	TotalRows int            `json:"total_rows"`
+	Groups      []GroupResult  `json:"groups,omitempty"`
+	Summary     *SummaryResult `json:"summary,omitempty"`
+	TotalRows   int            `json:"total_rows"`
+	TotalGroups int            `json:"total_groups,omitempty"` // groups before offset and limit
+
+	InvalidValues int `json:"invalid_values,omitempty"` // rows grouped in the InvalidBucket
+
+	// NonNumeric counts the values numeric aggregates skipped as not numbers, by field.
+	NonNumeric map[string]int `json:"non_numeric,omitempty"`
+
+	InputFiles []string `json:"input_files,omitempty"` // files the rows were read from, in order
 }
 
 // GroupResult represents aggregated data for a group.
+// GroupValues identify the group. GroupKey joins them with "|" for display, and may be the same
+// for two groups when values contain "|".
 type GroupResult struct {
 	GroupKey    string                 `json:"group_key"`
 	GroupValues map[string]string      `json:"group_values,omitempty"`
 	Aggregates  map[string]interface{} `json:"aggregates"`
 	Count       int                    `json:"count"`
+	Skipped     map[string]int         `json:"skipped,omitempty"`     // rows an aggregate could not use, by alias
+	NullCounts  map[string]int         `json:"null_counts,omitempty"` // empty values of the fields of rules setting a null policy
+
+	binStarts map[string]float64 // start of the bin of each binned group value, which orders bins
+	rolledUp  map[string]bool    // group-by fields rolled up in a subtotal or the grand total
 }
 
 // SummaryResult represents overall summary statistics.
@@ -79,32 +327,115 @@ type SummaryResult struct {
 
 // FieldStats contains statistical information for a field.
 type FieldStats struct {
-	Count     int64   `json:"count"`
-	Sum       float64 `json:"sum,omitempty"`
-	Average   float64 `json:"average,omitempty"`
-	Min       float64 `json:"min,omitempty"`
-	Max       float64 `json:"max,omitempty"`
-	Unique    int64   `json:"unique,omitempty"`
-	NullCount int64   `json:"null_count,omitempty"`
+	Count     int64    `json:"count"`
+	Sum       float64  `json:"sum,omitempty"`
+	Average   *float64 `json:"average,omitempty"` // nil when no value is a number, like Min and Max
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Unique    int64    `json:"unique,omitempty"`
+	NullCount int64    `json:"null_count,omitempty"`
+
+	NumericCount int64 `json:"numeric_count,omitempty"` // values used by the numeric statistics
+
+	// MinValue and MaxValue are the extremes of a "min" or "max" rule comparing dates or strings.
+	MinValue string `json:"min_value,omitempty"`
+	MaxValue string `json:"max_value,omitempty"`
+
+	WeightedAverage *float64 `json:"weighted_average,omitempty"` // nil when the total weight is zero
+	Skipped         int64    `json:"skipped,omitempty"`          // rows without a value or a weight
+	NonNumeric      int64    `json:"non_numeric,omitempty"`      // non-empty values that are not numbers
+
+	MostCommon      string `json:"most_common,omitempty"` // most frequent non-empty value, the smallest on ties
+	MostCommonCount int64  `json:"most_common_count,omitempty"`
+
+	// Correlation is Pearson's r with other fields, by field, nil when undefined.
+	Correlation map[string]*float64 `json:"correlation,omitempty"`
+
+	DistinctSample          []string `json:"distinct_sample,omitempty"`           // smallest distinct non-empty values, sorted
+	DistinctSampleTruncated bool     `json:"distinct_sample_truncated,omitempty"` // whether values beyond the limit were dropped
+
+	counted bool // whether Count is that of a count rule, which other rules keep
 }
 
 // ProcessData performs aggregation operations on data
 // This method demonstrates complex data aggregation logic.
 func (s *AggregateService) ProcessData(input []DataRow, options map[string]interface{}) ([]DataRow, error) {
+	_, rows, err := s.run(input, options)
+	return rows, err
+}
+
+// run aggregates the input, or the input file when input is empty, and writes the output file.
+// It returns the result along with its flattened rows.
+func (s *AggregateService) run(input []DataRow, options map[string]interface{}) (*AggregateResult, []DataRow, error) {
 	s.logger.Info().Msg("Performing data aggregation")
 
 	// Parse options
 	opts, err := s.parseAggregateOptions(options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse aggregate options: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse aggregate options: %w", err)
 	}
 
-	// If input data is empty, try to read from file
-	if len(input) == 0 && opts.InputFile != "" {
-		var err error
-		input, err = s.fileService.ReadCSV(opts.InputFile)
+	// Rows come from the input files when no input is given, checked to share their columns
+	var files []string
+	if len(input) == 0 && (opts.InputFile != "" || len(opts.InputFiles) > 0) {
+		if files, err = s.inputFiles(opts); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	result, err := s.aggregateInput(input, files, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Convert result back to DataRow format for consistency
+	rows := s.convertResultToDataRows(result, opts.Rules)
+
+	// Later stages aggregate the rows of the previous stage
+	result, rows, last, err := s.runStages(result, rows, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.InputFiles = files
+
+	// Write results to file if output file specified, flattened rows for CSV outputs
+	if opts.OutputFile != "" {
+		switch {
+		case !strings.EqualFold(filepath.Ext(opts.OutputFile), ".csv"):
+			err = s.fileService.WriteJSON(opts.OutputFile, result)
+		case len(rows) == 0:
+			// no group to take the columns from, the header still names them
+			err = s.fileService.WriteCSV(opts.OutputFile, last.groupColumns(), nil, opts.CSV)
+		default:
+			err = s.fileService.WriteDataCSV(opts.OutputFile, rows, opts.CSV)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to read input file: %w", err)
+			return nil, nil, fmt.Errorf("failed to write aggregated data: %w", err)
+		}
+	}
+
+	return result, rows, nil
+}
+
+// aggregateInput aggregates the input, or the rows of the input files when input is empty.
+// Groups only hold running aggregates, so input files are streamed rather than loaded when grouping.
+func (s *AggregateService) aggregateInput(input []DataRow, files []string, opts *AggregateOptions) (*AggregateResult, error) {
+	if len(input) == 0 && len(files) > 0 && len(opts.GroupBy) > 0 {
+		result, err := s.aggregateStream(files, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate data: %w", err)
+		}
+		return result, nil
+	}
+
+	// If input data is empty, read the input files
+	if len(input) == 0 {
+		for _, file := range files {
+			rows, err := s.fileService.ReadCSV(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input file: %w", err)
+			}
+			input = append(input, withSourceFileField(rows)...)
 		}
 	}
 
@@ -113,16 +444,30 @@ func (s *AggregateService) ProcessData(input []DataRow, options map[string]inter
 	if err != nil {
 		return nil, fmt.Errorf("failed to aggregate data: %w", err)
 	}
+	return result, nil
+}
 
-	// Write results to file if output file specified
-	if opts.OutputFile != "" {
-		if err := s.fileService.WriteJSON(opts.OutputFile, result); err != nil {
-			return nil, fmt.Errorf("failed to write aggregated data: %w", err)
+// runStages aggregates the rows of the first stage through the later stages, in order.
+// It returns the result and rows of the last stage, along with its options.
+func (s *AggregateService) runStages(result *AggregateResult, rows []DataRow, opts *AggregateOptions) (*AggregateResult, []DataRow, *AggregateOptions, error) {
+	last := opts
+	for i, stage := range opts.Stages {
+		available := collectHeaders(rows)
+		if len(rows) == 0 {
+			available = last.groupColumns()
+		}
+		if err := stage.checkFields(available); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid stage %d: %w", i+2, err)
 		}
-	}
 
-	// Convert result back to DataRow format for consistency
-	return s.convertResultToDataRows(result), nil
+		var err error
+		if result, err = s.aggregateData(rows, stage); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to aggregate stage %d: %w", i+2, err)
+		}
+		rows = s.convertResultToDataRows(result, stage.Rules)
+		last = stage
+	}
+	return result, rows, last, nil
 }
 
 // GetName returns the processor name.
@@ -138,17 +483,83 @@ func (s *AggregateService) GetDescription() string {
 // parseAggregateOptions parses aggregation options from map.
 func (s *AggregateService) parseAggregateOptions(options map[string]interface{}) (*AggregateOptions, error) {
 	opts := &AggregateOptions{}
+	opts.InputFile, opts.InputFiles = parseInputFileOptions(options)
+	if outputFile, ok := options["output_file"].(string); ok {
+		opts.OutputFile = outputFile
+	}
 
-	if inputFile, ok := options["input_file"].(string); ok {
-		opts.InputFile = inputFile
+	var err error
+	if opts.GroupBy, err = s.parseGroupBy(options["group_by"]); err != nil {
+		return nil, err
 	}
+	opts.DateLayouts = parseDateLayouts(options["date_layouts"])
 
-	if outputFile, ok := options["output_file"].(string); ok {
-		opts.OutputFile = outputFile
+	policy, _ := options["numeric_policy"].(string)
+	if opts.NumericPolicy, err = ParseNumericPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	if opts.Rules, err = s.parseRules(options["rules"], len(opts.GroupBy) > 0); err != nil {
+		return nil, err
+	}
+	if err := opts.parseSort(options); err != nil {
+		return nil, err
+	}
+	if err := opts.parseRank(options); err != nil {
+		return nil, err
+	}
+	if err := opts.resolveSortKeys(); err != nil {
+		return nil, err
+	}
+
+	if opts.CSV, err = parseCSVWriteOptions(options); err != nil {
+		return nil, err
+	}
+	if err := opts.parseRollup(options); err != nil {
+		return nil, err
+	}
+	if err := s.parseHaving(opts, options["having"]); err != nil {
+		return nil, err
 	}
 
-	// Parse aggregation rules
-	if rulesRaw, ok := options["rules"].([]interface{}); ok {
+	if opts.Stages, err = s.parseStages(options["stages"]); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// parseInputFileOptions parses the input files, a path or glob, or lists of them.
+func parseInputFileOptions(options map[string]interface{}) (string, []string) {
+	var inputFile string
+	var inputFiles []string
+	for _, key := range []string{"input_file", "input_files"} {
+		switch files := options[key].(type) {
+		case string:
+			if key == "input_file" {
+				inputFile = files
+			} else if files != "" {
+				inputFiles = append(inputFiles, files)
+			}
+		case []string:
+			inputFiles = append(inputFiles, files...)
+		case []interface{}:
+			for _, file := range files {
+				if file, ok := file.(string); ok {
+					inputFiles = append(inputFiles, file)
+				}
+			}
+		}
+	}
+	return inputFile, inputFiles
+}
+
+// parseRules parses aggregation rules, either typed or decoded from generic JSON, along with
+// their parameters. Some operations require grouped rows.
+func (s *AggregateService) parseRules(value interface{}, grouped bool) ([]AggregateRule, error) {
+	var rules []AggregateRule
+	if typed, ok := value.([]AggregateRule); ok {
+		rules = append(rules, typed...)
+	} else if rulesRaw, ok := value.([]interface{}); ok {
 		for _, ruleRaw := range rulesRaw {
 			if ruleMap, ok := ruleRaw.(map[string]interface{}); ok {
 				rule := AggregateRule{
@@ -156,26 +567,379 @@ func (s *AggregateService) parseAggregateOptions(options map[string]interface{})
 					Operation: AggregateOperation(s.getString(ruleMap, "operation")),
 					Alias:     s.getString(ruleMap, "alias"),
 				}
-				opts.Rules = append(opts.Rules, rule)
+				if params, ok := ruleMap["parameters"].(map[string]interface{}); ok {
+					rule.Parameters = params
+				}
+				rules = append(rules, rule)
 			}
 		}
 	}
 
-	// Parse group by fields
-	if groupByRaw, ok := options["group_by"].([]interface{}); ok {
-		for _, field := range groupByRaw {
-			if fieldStr, ok := field.(string); ok {
-				opts.GroupBy = append(opts.GroupBy, fieldStr)
+	for i, rule := range rules {
+		nullPolicy, _ := rule.Parameters["null_policy"].(string)
+		var err error
+		if rules[i].nulls, err = ParseNullPolicy(nullPolicy, rule.Operation); err != nil {
+			return nil, fmt.Errorf("invalid %s on field '%s': %w", rule.Operation, rule.Field, err)
+		}
+		rules[i].countNulls = nullPolicy != ""
+
+		if err := s.parseRuleParameters(&rules[i], grouped); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// groupedOperations are the operations that require grouped rows.
+var groupedOperations = map[AggregateOperation]bool{Collect: true, CountIf: true, SumIf: true}
+
+// collectParsers parse the parameters of the rules collecting values.
+var collectParsers = map[AggregateOperation]func(map[string]interface{}) (*collectOptions, error){
+	Collect:        parseCollectOptions,
+	DistinctValues: parseDistinctValuesOptions,
+}
+
+// parseRuleParameters parses the parameters of the operation of an aggregation rule.
+func (s *AggregateService) parseRuleParameters(rule *AggregateRule, grouped bool) error {
+	if groupedOperations[rule.Operation] && !grouped {
+		return fmt.Errorf("%s on field '%s' requires group_by", rule.Operation, rule.Field)
+	}
+
+	var err error
+	//nolint:exhaustive
+	switch rule.Operation {
+	case CountNonNull:
+		if rule.Field == "" {
+			return errors.New("count_nonnull requires a field")
+		}
+		if rule.nulls == NullPolicyInclude {
+			return fmt.Errorf("count_nonnull on field '%s' always excludes empty values", rule.Field)
+		}
+	case Collect, DistinctValues:
+		if rule.collect, err = collectParsers[rule.Operation](rule.Parameters); err != nil {
+			return fmt.Errorf("invalid %s parameters on field '%s': %w", rule.Operation, rule.Field, err)
+		}
+	case WeightedAverage:
+		rule.weight, err = rule.requiredParameter("weight")
+	case Correlation:
+		rule.with, err = rule.requiredParameter("with")
+	case CountIf, SumIf:
+		rule.condition, err = s.parseRuleCondition(*rule)
+	case Mode:
+		rule.withCount, _ = rule.Parameters["with_count"].(bool)
+	case Min, Max:
+		valueType, _ := rule.Parameters["type"].(string)
+		if rule.valueType, err = ParseValueType(valueType); err != nil {
+			return fmt.Errorf("invalid %s on field '%s': %w", rule.Operation, rule.Field, err)
+		}
+	case Distinct:
+		rule.approximate, _ = rule.Parameters["approximate"].(bool)
+	}
+	return err
+}
+
+// requiredParameter returns a field name parameter of a rule, failing when it is not set.
+func (r AggregateRule) requiredParameter(name string) (string, error) {
+	value, _ := r.Parameters[name].(string)
+	if value == "" {
+		return "", fmt.Errorf("%s on field '%s' requires a %s parameter", r.Operation, r.Field, name)
+	}
+	return value, nil
+}
+
+// parseRuleCondition parses the condition of a "count_if" or "sum_if" rule.
+func (s *AggregateService) parseRuleCondition(rule AggregateRule) (FilterGroup, error) {
+	condition, err := s.filterService.parseConditions(rule.Parameters["condition"])
+	if err != nil {
+		return FilterGroup{}, fmt.Errorf("invalid %s condition on field '%s': %w", rule.Operation, rule.Field, err)
+	}
+	if condition.isEmpty() {
+		return FilterGroup{}, fmt.Errorf("%s on field '%s' requires a condition parameter", rule.Operation, rule.Field)
+	}
+	return condition, nil
+}
+
+// parseGroupBy parses group by fields, either typed or decoded from generic JSON, as names or objects.
+func (s *AggregateService) parseGroupBy(value interface{}) ([]GroupByField, error) {
+	var fields []GroupByField
+	switch groupBy := value.(type) {
+	case []GroupByField:
+		fields = append(fields, groupBy...)
+	case []string:
+		for _, field := range groupBy {
+			fields = append(fields, GroupByField{Field: field})
+		}
+	case []interface{}:
+		for _, field := range groupBy {
+			switch field := field.(type) {
+			case string:
+				fields = append(fields, GroupByField{Field: field})
+			case map[string]interface{}:
+				groupByField := GroupByField{
+					Field:  s.getString(field, "field"),
+					Bucket: DateBucket(s.getString(field, "bucket")),
+					Format: s.getString(field, "format"),
+				}
+				if bins, ok := field["bins"].(map[string]interface{}); ok {
+					groupByField.Bins = parseBinSpec(bins)
+				}
+				fields = append(fields, groupByField)
 			}
 		}
 	}
 
-	if sortBy, ok := options["sort_by"].(string); ok {
-		opts.SortBy = sortBy
+	for i := range fields {
+		if err := fields[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// validate checks the bucket or bins of a group-by field, normalizing the bucket name.
+func (f *GroupByField) validate() error {
+	if f.Bins != nil {
+		if f.Bucket != "" {
+			return fmt.Errorf("group-by field '%s' takes either a bucket or bins, not both", f.Field)
+		}
+		if err := f.Bins.Validate(); err != nil {
+			return fmt.Errorf("invalid group-by field '%s': %w", f.Field, err)
+		}
 	}
+	if f.Bucket == "" {
+		if f.Format != "" {
+			return fmt.Errorf("format of group-by field '%s' requires a bucket", f.Field)
+		}
+		return nil
+	}
+	bucket, err := ParseDateBucket(string(f.Bucket))
+	if err != nil {
+		return fmt.Errorf("invalid group-by field '%s': %w", f.Field, err)
+	}
+	f.Bucket = bucket
+	return nil
+}
 
+// parseSort parses the sort keys of the groups, and the offset and limit paging through them.
+func (o *AggregateOptions) parseSort(options map[string]interface{}) error {
+	if sortBy, ok := options["sort_by"].(string); ok {
+		o.SortBy = sortBy
+	}
 	if sortDesc, ok := options["sort_desc"].(bool); ok {
-		opts.SortDesc = sortDesc
+		o.SortDesc = sortDesc
+	}
+
+	sortKeys, err := parseSortKeyList(options["sort"])
+	if err != nil {
+		return fmt.Errorf("invalid sort: %w", err)
+	}
+	o.Sort = sortKeys
+
+	if offset, ok := toInt(options["offset"]); ok {
+		o.Offset = offset
+	}
+	if limit, ok := toInt(options["limit"]); ok {
+		o.Limit = limit
+	}
+	if o.Offset < 0 || o.Limit < 0 {
+		return fmt.Errorf("offset and limit must not be negative, got %d and %d", o.Offset, o.Limit)
+	}
+	if (o.Offset > 0 || o.Limit > 0) && len(o.GroupBy) == 0 {
+		return errors.New("offset and limit require group_by")
+	}
+	return nil
+}
+
+// parseRank parses the sort key and the method the groups are ranked by.
+func (o *AggregateOptions) parseRank(options map[string]interface{}) error {
+	if rankBy, ok := options["rank_by"].(string); ok {
+		o.RankBy = rankBy
+	}
+	rankMethod, _ := options["rank_method"].(string)
+	var err error
+	if o.RankMethod, err = ParseRankMethod(rankMethod); err != nil {
+		return err
+	}
+	if o.RankBy == "" {
+		return nil
+	}
+
+	keys := ParseSortKeys(o.RankBy, true)
+	if len(keys) != 1 {
+		return fmt.Errorf("rank_by must name a single sort key, got %q", o.RankBy)
+	}
+	if len(o.GroupBy) == 0 {
+		return errors.New("rank_by requires group_by")
+	}
+	o.rankKey = &keys[0]
+	return nil
+}
+
+// resolveSortKeys sets the sort keys the groups are ordered by: the sort keys, else the sort_by
+// ones, else the rank key.
+func (o *AggregateOptions) resolveSortKeys() error {
+	o.sortKeys = o.Sort
+	if len(o.sortKeys) == 0 {
+		o.sortKeys = ParseSortKeys(o.SortBy, o.SortDesc)
+	}
+	if len(o.sortKeys) == 0 && o.rankKey != nil {
+		o.sortKeys = []SortKey{*o.rankKey}
+	}
+	return o.validateSortKeys()
+}
+
+// parseRollup parses whether subtotal groups are added, and the marker of rolled-up fields.
+func (o *AggregateOptions) parseRollup(options map[string]interface{}) error {
+	if rollup, ok := options["rollup"].(bool); ok {
+		o.Rollup = rollup
+	}
+	o.RollupMarker = DefaultRollupMarker
+	if marker, ok := options["rollup_marker"].(string); ok && marker != "" {
+		o.RollupMarker = marker
+	}
+	if o.Rollup && len(o.GroupBy) == 0 {
+		return errors.New("rollup requires group_by")
+	}
+	return nil
+}
+
+// parseHaving parses the rules the groups are kept by.
+func (s *AggregateService) parseHaving(opts *AggregateOptions, value interface{}) error {
+	var err error
+	if opts.Having, err = s.filterService.parseConditions(value); err != nil {
+		return fmt.Errorf("invalid having rules: %w", err)
+	}
+	if !opts.Having.isEmpty() && len(opts.GroupBy) == 0 {
+		return errors.New("having requires group_by")
+	}
+	return nil
+}
+
+// parseStages parses the stages following the first one, either typed or decoded from generic JSON.
+// Stages are numbered from 2 in errors.
+func (s *AggregateService) parseStages(value interface{}) ([]*AggregateOptions, error) {
+	var stagesOptions []map[string]interface{}
+	switch stages := value.(type) {
+	case nil:
+		return nil, nil
+	case []map[string]interface{}:
+		stagesOptions = stages
+	case []interface{}:
+		for i, stage := range stages {
+			stageOptions, ok := stage.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("stage %d is not an object", i+2)
+			}
+			stagesOptions = append(stagesOptions, stageOptions)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected stages %v: expected a list", value)
+	}
+
+	stages := make([]*AggregateOptions, 0, len(stagesOptions))
+	for i, stageOptions := range stagesOptions {
+		for _, key := range []string{"input_file", "input_files", "output_file", "stages"} {
+			if _, ok := stageOptions[key]; ok {
+				return nil, fmt.Errorf("stage %d cannot set %s", i+2, key)
+			}
+		}
+		stage, err := s.parseAggregateOptions(stageOptions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stage %d: %w", i+2, err)
+		}
+		if len(stage.Rules) == 0 {
+			return nil, fmt.Errorf("stage %d requires rules", i+2)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// checkFields fails when a field the rules or group-by fields read is not among the available fields.
+func (o *AggregateOptions) checkFields(available []string) error {
+	known := make(map[string]bool, len(available))
+	for _, field := range available {
+		known[field] = true
+	}
+
+	fields := make([]string, 0, len(o.GroupBy)+3*len(o.Rules))
+	for _, field := range o.GroupBy {
+		fields = append(fields, field.Field)
+	}
+	for _, rule := range o.Rules {
+		fields = append(fields, rule.Field, rule.weight, rule.with)
+	}
+	for _, field := range fields {
+		if field != "" && !known[field] {
+			sorted := append([]string{}, available...)
+			sort.Strings(sorted)
+			return fmt.Errorf("unknown field '%s', available fields: %s", field, strings.Join(sorted, ", "))
+		}
+	}
+	return nil
+}
+
+// parseCollectOptions parses and validates the parameters of a "collect" rule.
+func parseCollectOptions(params map[string]interface{}) (*collectOptions, error) {
+	opts := &collectOptions{
+		separator: ",",
+		order:     CollectOrderInput,
+	}
+
+	if distinct, ok := params["distinct"].(bool); ok {
+		opts.distinct = distinct
+	}
+
+	if separator, ok := params["separator"].(string); ok {
+		opts.separator = separator
+	}
+
+	if asArray, ok := params["as_array"].(bool); ok {
+		opts.asArray = asArray
+	}
+
+	if raw, exists := params["limit"]; exists {
+		limit, ok := toInt(raw)
+		if !ok || limit < 0 {
+			return nil, fmt.Errorf("limit must be a non-negative integer, got %v", raw)
+		}
+		opts.limit = limit
+	}
+
+	if order, ok := params["order"].(string); ok && order != "" {
+		opts.order = strings.ToLower(order)
+	}
+	if opts.order != CollectOrderInput && opts.order != CollectOrderSorted {
+		return nil, fmt.Errorf("unknown order %q: expected %q or %q", opts.order, CollectOrderInput, CollectOrderSorted)
+	}
+
+	return opts, nil
+}
+
+// parseDistinctValuesOptions parses and validates the parameters of a "distinct_values" rule,
+// which collects distinct values in sorted order up to a positive limit.
+func parseDistinctValuesOptions(params map[string]interface{}) (*collectOptions, error) {
+	opts := &collectOptions{
+		distinct:  true,
+		separator: ",",
+		limit:     DefaultDistinctValuesLimit,
+		order:     CollectOrderSorted,
+	}
+
+	if separator, ok := params["separator"].(string); ok {
+		opts.separator = separator
+	}
+
+	if asArray, ok := params["as_array"].(bool); ok {
+		opts.asArray = asArray
+	}
+
+	if raw, exists := params["limit"]; exists {
+		limit, ok := toInt(raw)
+		if !ok || limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer, got %v", raw)
+		}
+		opts.limit = limit
 	}
 
 	return opts, nil
@@ -195,82 +959,478 @@ func (s *AggregateService) aggregateData(data []DataRow, opts *AggregateOptions)
 		TotalRows: len(data),
 	}
 
-	if len(opts.GroupBy) > 0 {
-		// Group by aggregation
-		groups := s.groupData(data, opts.GroupBy)
-		result.Groups = s.processGroups(groups, opts)
-	} else {
-		// Overall aggregation
-		summary := s.processOverallAggregation(data, opts)
-		result.Summary = summary
+	if len(opts.GroupBy) > 0 {
+		// Group by aggregation
+		groups := s.newGroupSet(opts)
+		for _, row := range data {
+			if err := groups.add(row); err != nil {
+				return nil, err
+			}
+		}
+		s.completeGroups(result, groups, opts)
+	} else {
+		// Overall aggregation
+		summary, err := s.processOverallAggregation(data, opts)
+		if err != nil {
+			return nil, err
+		}
+		result.Summary = summary
+
+		if opts.NumericPolicy == NumericPolicySkip {
+			for _, rule := range opts.Rules {
+				if stats := summary.FieldStats[rule.Field]; rule.numeric() && stats.NonNumeric > 0 {
+					if result.NonNumeric == nil {
+						result.NonNumeric = make(map[string]int)
+					}
+					result.NonNumeric[rule.Field] = int(stats.NonNumeric)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// inputFiles resolves the input files of the options, and checks that they share their columns.
+func (s *AggregateService) inputFiles(opts *AggregateOptions) ([]string, error) {
+	patterns := opts.InputFiles
+	if opts.InputFile != "" {
+		patterns = append([]string{opts.InputFile}, patterns...)
+	}
+
+	files, err := s.fileService.ResolveInputFiles(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve input files: %w", err)
+	}
+	if len(files) > 1 {
+		if err := s.fileService.CheckHeaders(files); err != nil {
+			return nil, fmt.Errorf("incompatible input files: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// withSourceFileField sets the SourceFileField of rows read from a file, so that rows can be grouped by file.
+func withSourceFileField(rows []DataRow) []DataRow {
+	for _, row := range rows {
+		row.Fields[SourceFileField] = row.SourceFile
+	}
+	return rows
+}
+
+// aggregateStream performs a grouped aggregation of the input files row by row,
+// so that memory grows with the number of groups and not with the number of rows.
+func (s *AggregateService) aggregateStream(files []string, opts *AggregateOptions) (*AggregateResult, error) {
+	result := &AggregateResult{}
+	groups := s.newGroupSet(opts)
+
+	for _, file := range files {
+		err := s.fileService.StreamCSV(file, func(row DataRow) error {
+			result.TotalRows++
+			row.Fields[SourceFileField] = row.SourceFile
+			return groups.add(row)
+		})
+		if valueErr := (*NumericValueError)(nil); errors.As(err, &valueErr) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+	}
+
+	s.completeGroups(result, groups, opts)
+	return result, nil
+}
+
+// completeGroups sets the groups of a result, filtered by having, sorted and paged.
+func (s *AggregateService) completeGroups(result *AggregateResult, groups *groupSet, opts *AggregateOptions) {
+	if groups.invalidValues > 0 {
+		s.logger.Warn().Int("rows", groups.invalidValues).Strs("group_by", groupByNames(opts.GroupBy)).Msg("Rows grouped as invalid values")
+	}
+	result.InvalidValues = groups.invalidValues
+	if len(groups.nonNumeric) > 0 {
+		result.NonNumeric = groups.nonNumeric
+	}
+
+	groupResults := s.processGroups(groups, opts)
+	result.TotalGroups = len(groupResults)
+	result.Groups = pageGroups(groupResults, opts)
+}
+
+// groupSet holds the running state of every group: its values, its count and an accumulator per rule.
+type groupSet struct {
+	service       *AggregateService
+	opts          *AggregateOptions
+	groups        map[string]*groupState
+	invalidValues int // rows with a date or number that does not parse, grouped in the InvalidBucket
+
+	numericFields []string       // fields of the numeric aggregates, whose skipped values are counted
+	nonNumeric    map[string]int // values of the numeric fields skipped as not numbers
+	nullFields    []string       // fields of the rules setting a null policy, whose empty values are counted per group
+}
+
+// groupState is the running state of a group.
+type groupState struct {
+	result       GroupResult
+	accumulators []accumulator
+}
+
+// newGroupSet returns an empty group set.
+func (s *AggregateService) newGroupSet(opts *AggregateOptions) *groupSet {
+	groups := &groupSet{
+		service:    s,
+		opts:       opts,
+		groups:     make(map[string]*groupState),
+		nonNumeric: make(map[string]int),
+	}
+
+	seenNulls := make(map[string]bool)
+	for _, rule := range opts.Rules {
+		if rule.countNulls && rule.Field != "" && !seenNulls[rule.Field] {
+			seenNulls[rule.Field] = true
+			groups.nullFields = append(groups.nullFields, rule.Field)
+		}
+	}
+
+	if opts.NumericPolicy == NumericPolicySkip {
+		seen := make(map[string]bool)
+		for _, rule := range opts.Rules {
+			if !rule.numeric() {
+				continue
+			}
+			for _, field := range []string{rule.Field, rule.weight, rule.with} {
+				if field != "" && !seen[field] {
+					seen[field] = true
+					groups.numericFields = append(groups.numericFields, field)
+				}
+			}
+		}
+	}
+
+	return groups
+}
+
+// add folds a row into its group, creating the group on its first row, and into the subtotal
+// groups and the grand total with rollup.
+// It fails when a value is not a number under NumericPolicyError.
+func (g *groupSet) add(row DataRow) error {
+	values := make([]string, len(g.opts.GroupBy))
+	valid := true
+	for i, field := range g.opts.GroupBy {
+		value, _, ok := g.service.groupValue(row, field, g.opts.DateLayouts)
+		values[i] = value
+		valid = valid && ok
+	}
+	if !valid {
+		g.service.logger.Debug().Str("location", row.Location()).Msg("Value does not parse, row grouped as invalid")
+		g.invalidValues++
+	}
+
+	levels := 1
+	if g.opts.Rollup {
+		levels += len(values)
+	}
+	for rolledUp := range levels {
+		if err := g.fold(row, createGroupKey(values, rolledUp), rolledUp); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range g.numericFields {
+		if value := row.Fields[field]; value != "" {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				g.nonNumeric[field]++
+			}
+		}
+	}
+
+	return nil
+}
+
+// fold folds a row into the group of a key, with the last rolledUp group-by fields rolled up.
+func (g *groupSet) fold(row DataRow, key string, rolledUp int) error {
+	group, ok := g.groups[key]
+	if !ok {
+		group = g.newGroup(row, rolledUp)
+		g.groups[key] = group
+	}
+
+	group.result.Count++
+	for _, field := range g.nullFields {
+		if row.Fields[field] == "" {
+			group.result.NullCounts[field]++
+		}
+	}
+	for _, acc := range group.accumulators {
+		if acc == nil {
+			continue
+		}
+		if err := acc.add(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newGroup creates the state of a group, taking its values from its first row.
+// The last rolledUp group-by fields have the rollup marker as value.
+func (g *groupSet) newGroup(row DataRow, rolledUp int) *groupState {
+	group := &groupState{
+		result: GroupResult{
+			GroupValues: make(map[string]string),
+			Aggregates:  make(map[string]interface{}),
+		},
+		accumulators: make([]accumulator, len(g.opts.Rules)),
+	}
+	if len(g.nullFields) > 0 {
+		group.result.NullCounts = make(map[string]int, len(g.nullFields))
+		for _, field := range g.nullFields {
+			group.result.NullCounts[field] = 0
+		}
+	}
+
+	displayParts := make([]string, 0, len(g.opts.GroupBy))
+	for i, field := range g.opts.GroupBy {
+		if i >= len(g.opts.GroupBy)-rolledUp {
+			displayParts = append(displayParts, g.opts.RollupMarker)
+			group.result.GroupValues[field.Field] = g.opts.RollupMarker
+			if group.result.rolledUp == nil {
+				group.result.rolledUp = make(map[string]bool)
+			}
+			group.result.rolledUp[field.Field] = true
+			continue
+		}
+
+		value, start, ok := g.service.groupValue(row, field, g.opts.DateLayouts)
+		displayParts = append(displayParts, value)
+		group.result.GroupValues[field.Field] = value
+		if field.Bins != nil && ok {
+			if group.result.binStarts == nil {
+				group.result.binStarts = make(map[string]float64)
+			}
+			group.result.binStarts[field.Field] = start
+		}
+	}
+
+	group.result.GroupKey = strings.Join(displayParts, "|")
+
+	for i, rule := range g.opts.Rules {
+		group.accumulators[i] = g.service.newAccumulator(rule, g.opts)
+	}
+
+	return group
+}
+
+// createGroupKey creates a unique key for grouping rows with these group values, the last rolledUp
+// of them being rolled up. Values are length-prefixed so that no combination of values can
+// collide with another.
+func createGroupKey(values []string, rolledUp int) string {
+	var key strings.Builder
+	key.WriteString(strconv.Itoa(rolledUp))
+	key.WriteByte(';')
+	for _, value := range values[:len(values)-rolledUp] {
+		writeKeyPart(&key, value)
+	}
+	return key.String()
+}
+
+// groupValue returns the value of a row for a group-by field, the label of its date bucket or bin if any,
+// along with the start of the bin. Values that do not parse have the InvalidBucket value and are
+// reported as not ok.
+func (s *AggregateService) groupValue(row DataRow, field GroupByField, layouts []string) (string, float64, bool) {
+	value := row.Fields[field.Field]
+	switch {
+	case field.Bins != nil:
+		num, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return InvalidBucket, 0, false
+		}
+		label, start := field.Bins.bin(num)
+		return label, start, true
+	case field.Bucket != "":
+		date, ok := parseDate(value, layouts)
+		if !ok {
+			return InvalidBucket, 0, false
+		}
+		return field.Bucket.Label(date, field.Format), 0, true
+	default:
+		return value, 0, true
+	}
+}
+
+// processGroups computes the aggregates of each group and keeps the groups matching having,
+// ordered when rolled up.
+func (s *AggregateService) processGroups(groups *groupSet, opts *AggregateOptions) []GroupResult {
+	groupResults := []GroupResult{}
+
+	for _, group := range groups.groups {
+		groupResult := group.result
+
+		// Apply aggregation rules
+		for i, rule := range opts.Rules {
+			acc := group.accumulators[i]
+			if acc == nil {
+				groupResult.Aggregates[rule.alias()] = nil
+				continue
+			}
+
+			result, skipped := acc.result()
+			groupResult.Aggregates[rule.alias()] = result
+			if mode, ok := acc.(*modeAccumulator); ok && rule.withCount {
+				_, count := mode.mode()
+				groupResult.Aggregates[rule.alias()+"_count"] = count
+			}
+			if values, ok := acc.(*distinctValuesAccumulator); ok {
+				groupResult.Aggregates[rule.alias()+"_truncated"] = values.truncated
+			}
+			if skipped > 0 {
+				if groupResult.Skipped == nil {
+					groupResult.Skipped = make(map[string]int)
+				}
+				groupResult.Skipped[rule.alias()] = skipped
+			}
+		}
+
+		groupResults = append(groupResults, groupResult)
+	}
+
+	// Rollups are ordered before having, other groups being sorted as they are paged
+	if opts.Rollup {
+		sortGroupResults(groupResults, opts.groupSortKeys(), opts.DateLayouts)
+		groupResults = orderRollup(groupResults, opts.GroupBy)
+	}
+
+	// Filter after ordering, so that detail groups stay in place when having removes their subtotal
+	if !opts.Having.isEmpty() {
+		kept := groupResults[:0]
+		for _, groupResult := range groupResults {
+			if !s.filterService.matchConditions(s.groupRow(groupResult), opts.Having) {
+				s.logger.Debug().Str("group_key", groupResult.GroupKey).Msg("Group removed by having")
+				continue
+			}
+			kept = append(kept, groupResult)
+		}
+		groupResults = kept
+	}
+
+	// Rank the groups left by having, before they are paged
+	if opts.rankKey != nil {
+		rankGroups(groupResults, *opts.rankKey, opts.RankMethod, opts.DateLayouts)
 	}
-
-	return result, nil
+	return groupResults
 }
 
-// groupData groups data by specified fields.
-func (s *AggregateService) groupData(data []DataRow, groupBy []string) map[string][]DataRow {
-	groups := make(map[string][]DataRow)
-
-	for _, row := range data {
-		key := s.createGroupKey(row, groupBy)
-		groups[key] = append(groups[key], row)
+// rankGroups sets the RankAggregate of every group by a sort key, 1 being the group that sorts first.
+// Tied groups share a rank. Subtotals and groups lacking the value have a nil rank.
+func rankGroups(groups []GroupResult, key SortKey, method RankMethod, layouts []string) {
+	values := make([]interface{}, len(groups))
+	var ranked []int
+	for i, group := range groups {
+		group.Aggregates[RankAggregate] = nil
+		if len(group.rolledUp) > 0 {
+			continue
+		}
+		if values[i] = group.sortValue(key.Field); values[i] != nil {
+			ranked = append(ranked, i)
+		}
 	}
 
-	return groups
-}
+	comparator := valueComparator{valueType: sortValueType(values, layouts), layouts: layouts}
+	sortKeys := make([]*comparableValue, len(groups))
+	for _, i := range ranked {
+		sortKeys[i] = comparator.sortKey(values[i])
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return compareSortValues(sortKeys[ranked[a]], sortKeys[ranked[b]], comparator, key.Desc) < 0
+	})
 
-// createGroupKey creates a unique key for grouping.
-func (s *AggregateService) createGroupKey(row DataRow, groupBy []string) string {
-	keyParts := []string{}
-	for _, field := range groupBy {
-		keyParts = append(keyParts, row.Fields[field])
+	rank := 0
+	for n, i := range ranked {
+		if n == 0 || compareSortValues(sortKeys[ranked[n-1]], sortKeys[i], comparator, key.Desc) != 0 {
+			if method == RankDense {
+				rank++
+			} else {
+				rank = n + 1
+			}
+		}
+		groups[i].Aggregates[RankAggregate] = rank
 	}
-	return strings.Join(keyParts, "|")
 }
 
-// processGroups processes each group with aggregation rules.
-func (s *AggregateService) processGroups(groups map[string][]DataRow, opts *AggregateOptions) []GroupResult {
-	groupResults := []GroupResult{}
-
-	for key, groupData := range groups {
-		groupResult := GroupResult{
-			GroupKey:   key,
-			Count:      len(groupData),
-			Aggregates: make(map[string]interface{}),
+// orderRollup places every subtotal group right after the groups it totals, and the grand total last.
+// Groups of a same subtotal keep their sorted order.
+func orderRollup(groups []GroupResult, groupBy []GroupByField) []GroupResult {
+	// prefix key of the group values set in a group, up to a number of fields
+	prefix := func(group GroupResult, fields int) string {
+		var key strings.Builder
+		for _, field := range groupBy[:fields] {
+			writeKeyPart(&key, group.GroupValues[field.Field])
 		}
+		return key.String()
+	}
 
-		// Set group values
-		if len(groupData) > 0 {
-			groupResult.GroupValues = make(map[string]string)
-			for _, field := range opts.GroupBy {
-				groupResult.GroupValues[field] = groupData[0].Fields[field]
-			}
+	// groups by the prefix of the subtotal they belong to
+	children := make(map[string][]GroupResult)
+	for _, group := range groups {
+		if set := len(groupBy) - len(group.rolledUp); set > 0 {
+			parent := prefix(group, set-1)
+			children[parent] = append(children[parent], group)
 		}
+	}
 
-		// Apply aggregation rules
-		for _, rule := range opts.Rules {
-			result := s.applyAggregateRule(groupData, rule)
-			alias := rule.Alias
-			if alias == "" {
-				alias = fmt.Sprintf("%s_%s", rule.Field, rule.Operation)
+	ordered := make([]GroupResult, 0, len(groups))
+	var emit func(group GroupResult)
+	emit = func(group GroupResult) {
+		if set := len(groupBy) - len(group.rolledUp); set < len(groupBy) {
+			for _, child := range children[prefix(group, set)] {
+				emit(child)
 			}
-			groupResult.Aggregates[alias] = result
 		}
+		ordered = append(ordered, group)
+	}
+	for _, group := range groups {
+		if len(group.rolledUp) == len(groupBy) {
+			emit(group)
+		}
+	}
+	return ordered
+}
 
-		groupResults = append(groupResults, groupResult)
+// groupSortKeys returns the keys groups are sorted by: the sort keys, then the group values so
+// that bins and numbers come in numeric order, and output does not depend on map order.
+func (o *AggregateOptions) groupSortKeys() []SortKey {
+	keys := o.sortKeys[:len(o.sortKeys):len(o.sortKeys)]
+	for _, field := range o.GroupBy {
+		keys = append(keys, SortKey{Field: field.Field})
 	}
+	return keys
+}
 
-	// Sort results if specified
-	if opts.SortBy != "" {
-		s.sortGroupResults(groupResults, opts.SortBy, opts.SortDesc)
+// pageGroups returns the groups selected by offset and limit, limit 0 meaning no limit, in
+// order. Groups are sorted unless rolled up, the limit keeping the top groups by the sort keys
+// without sorting the others.
+func pageGroups(groups []GroupResult, opts *AggregateOptions) []GroupResult {
+	if opts.Offset >= len(groups) {
+		return []GroupResult{}
+	}
+	switch {
+	case opts.Rollup:
+	case opts.Limit > 0:
+		groups = topGroupResults(groups, opts.groupSortKeys(), opts.DateLayouts, opts.Offset+opts.Limit)
+	default:
+		sortGroupResults(groups, opts.groupSortKeys(), opts.DateLayouts)
 	}
 
-	return groupResults
+	groups = groups[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(groups) {
+		groups = groups[:opts.Limit]
+	}
+	return groups
 }
 
 // processOverallAggregation processes overall aggregation without grouping.
-func (s *AggregateService) processOverallAggregation(data []DataRow, opts *AggregateOptions) *SummaryResult {
+// It fails when a value is not a number under NumericPolicyError.
+func (s *AggregateService) processOverallAggregation(data []DataRow, opts *AggregateOptions) (*SummaryResult, error) {
 	summary := &SummaryResult{
 		TotalRecords: len(data),
 		FieldStats:   make(map[string]FieldStats),
@@ -278,245 +1438,802 @@ func (s *AggregateService) processOverallAggregation(data []DataRow, opts *Aggre
 
 	// Apply aggregation rules
 	for _, rule := range opts.Rules {
+		// the numeric policy only applies to numeric operations, other fields may well hold text
+		policy := NumericPolicySkip
+		if rule.numeric() {
+			policy = opts.NumericPolicy
+		}
+
+		var stats FieldStats
+		var err error
 		//nolint:exhaustive
 		switch rule.Operation {
-		case Count:
-			summary.FieldStats[rule.Field] = FieldStats{
-				Count: int64(len(data)),
-			}
+		case Count, CountNonNull:
+			// counts leave the statistics of earlier rules on the field as they are
+			stats = summary.FieldStats[rule.Field]
+			stats.Count, stats.NullCount = countField(data, rule)
+			stats.counted = true
+		case WeightedAverage:
+			stats, err = s.overallWeightedAverage(summary, data, rule, policy)
+		case Mode:
+			stats, err = s.overallMode(summary, data, rule, policy)
+		case Min, Max:
+			stats, err = s.overallExtreme(summary, data, rule, opts)
+		case Correlation:
+			stats, err = s.overallCorrelation(summary, data, rule, policy)
+		case DistinctValues:
+			stats, err = s.overallDistinctValues(summary, data, rule, policy)
 		default:
-			stats := s.calculateFieldStats(data, rule.Field)
-			summary.FieldStats[rule.Field] = stats
+			var described FieldStats
+			described, err = s.calculateFieldStats(data, rule.Field, policy, rule.nulls)
+			stats = summary.FieldStats[rule.Field]
+			stats.describe(described)
+		}
+		if err != nil {
+			return nil, err
 		}
+		summary.FieldStats[rule.Field] = stats
 	}
 
-	return summary
+	return summary, nil
 }
 
-// applyAggregateRule applies a single aggregation rule to a group.
-func (s *AggregateService) applyAggregateRule(groupData []DataRow, rule AggregateRule) interface{} {
-	//nolint:exhaustive
-	switch rule.Operation {
-	case Count:
-		return len(groupData)
-	case Sum:
-		return s.calculateSum(groupData, rule.Field)
-	case Average:
-		return s.calculateAverage(groupData, rule.Field)
-	case Min:
-		return s.calculateMin(groupData, rule.Field)
-	case Max:
-		return s.calculateMax(groupData, rule.Field)
-	case Distinct:
-		return s.calculateDistinct(groupData, rule.Field)
-	default:
-		return nil
+// ruleFieldStats returns the statistics of the field of a rule, keeping those of other rules on
+// the same field when there are some.
+func (s *AggregateService) ruleFieldStats(summary *SummaryResult, data []DataRow, rule AggregateRule, policy NumericPolicy) (FieldStats, error) {
+	if stats, ok := summary.FieldStats[rule.Field]; ok {
+		return stats, nil
 	}
+	return s.calculateFieldStats(data, rule.Field, policy, rule.nulls)
 }
 
-// calculateSum calculates the sum of numeric values in a field.
-func (s *AggregateService) calculateSum(data []DataRow, field string) float64 {
-	var sum float64
+// describe sets the descriptive statistics of a field, keeping the statistics only other rules
+// compute: the count of a count rule, the extremes of min and max rules and the rest.
+func (f *FieldStats) describe(described FieldStats) {
+	if !f.counted {
+		f.Count = described.Count
+	}
+	if f.Min == nil {
+		f.Min = described.Min
+	}
+	if f.Max == nil {
+		f.Max = described.Max
+	}
+	f.Sum, f.Average, f.Unique, f.NullCount = described.Sum, described.Average, described.Unique, described.NullCount
+	f.NumericCount, f.NonNumeric = described.NumericCount, described.NonNumeric
+}
+
+// countField returns the count of a "count" or "count_nonnull" rule over all rows, and the
+// number of empty values of its field.
+func countField(data []DataRow, rule AggregateRule) (int64, int64) {
+	count, nulls := int64(len(data)), int64(0)
+	if rule.Field == "" {
+		return count, nulls
+	}
 	for _, row := range data {
-		if val, err := strconv.ParseFloat(row.Fields[field], 64); err == nil {
-			sum += val
+		if row.Fields[rule.Field] == "" {
+			nulls++
 		}
 	}
-	return sum
+	if rule.nulls == NullPolicyExclude {
+		count -= nulls
+	}
+	return count, nulls
 }
 
-// calculateAverage calculates the average of numeric values in a field.
-func (s *AggregateService) calculateAverage(data []DataRow, field string) float64 {
-	if len(data) == 0 {
-		return 0
+// overallWeightedAverage returns the field statistics of a "weighted_average" rule over all rows.
+func (s *AggregateService) overallWeightedAverage(summary *SummaryResult, data []DataRow, rule AggregateRule, policy NumericPolicy) (FieldStats, error) {
+	stats, err := s.ruleFieldStats(summary, data, rule, policy)
+	if err != nil {
+		return stats, err
+	}
+	average, skipped, err := s.calculateWeightedAverage(data, rule, policy)
+	if err != nil {
+		return stats, err
+	}
+	if average, ok := average.(float64); ok {
+		stats.WeightedAverage = &average
 	}
-	return s.calculateSum(data, field) / float64(len(data))
+	stats.Skipped = int64(skipped)
+	return stats, nil
 }
 
-// calculateMin calculates the minimum value in a field.
-func (s *AggregateService) calculateMin(data []DataRow, field string) float64 {
-	if len(data) == 0 {
-		return 0
+// overallMode returns the field statistics of a "mode" rule over all rows.
+func (s *AggregateService) overallMode(summary *SummaryResult, data []DataRow, rule AggregateRule, policy NumericPolicy) (FieldStats, error) {
+	stats, err := s.ruleFieldStats(summary, data, rule, policy)
+	if err != nil {
+		return stats, err
 	}
-	mIn := math.MaxFloat64
+	if value, count := s.calculateMode(data, rule); count > 0 {
+		stats.MostCommon, stats.MostCommonCount = value, int64(count)
+	}
+	return stats, nil
+}
+
+// overallExtreme returns the field statistics of a "min" or "max" rule over all rows.
+func (s *AggregateService) overallExtreme(summary *SummaryResult, data []DataRow, rule AggregateRule, opts *AggregateOptions) (FieldStats, error) {
+	acc := newExtremeAccumulator(rule, opts)
 	for _, row := range data {
-		if val, err := strconv.ParseFloat(row.Fields[field], 64); err == nil && val < mIn {
-			mIn = val
+		if err := acc.add(row); err != nil {
+			return FieldStats{}, err
 		}
 	}
-	return mIn
+	// detected dates or strings are not numbers to apply the policy to
+	policy := opts.NumericPolicy
+	if !rule.numeric() || acc.comparator.valueType != ValueTypeNumber {
+		policy = NumericPolicySkip
+	}
+
+	stats, err := s.ruleFieldStats(summary, data, rule, policy)
+	if err != nil {
+		return stats, err
+	}
+	switch value := acc.extreme().(type) {
+	case string:
+		if rule.Operation == Min {
+			stats.MinValue = value
+		} else {
+			stats.MaxValue = value
+		}
+	case float64:
+		if rule.Operation == Min {
+			stats.Min = &value
+		} else {
+			stats.Max = &value
+		}
+	}
+	return stats, nil
 }
 
-// calculateMax calculates the maximum value in a field.
-func (s *AggregateService) calculateMax(data []DataRow, field string) float64 {
-	if len(data) == 0 {
-		return 0
+// overallCorrelation returns the field statistics of a "correlation" rule over all rows.
+func (s *AggregateService) overallCorrelation(summary *SummaryResult, data []DataRow, rule AggregateRule, policy NumericPolicy) (FieldStats, error) {
+	stats, err := s.ruleFieldStats(summary, data, rule, policy)
+	if err != nil {
+		return stats, err
+	}
+	includeNulls := rule.nulls == NullPolicyInclude
+	acc := &correlationAccumulator{
+		x: numericField{field: rule.Field, policy: policy, includeNulls: includeNulls},
+		y: numericField{field: rule.with, policy: policy, includeNulls: includeNulls},
 	}
-	mAx := -math.MaxFloat64
 	for _, row := range data {
-		if val, err := strconv.ParseFloat(row.Fields[field], 64); err == nil && val > mAx {
-			mAx = val
+		if err := acc.add(row); err != nil {
+			return stats, err
 		}
 	}
-	return mAx
+
+	if stats.Correlation == nil {
+		stats.Correlation = make(map[string]*float64)
+	}
+	stats.Correlation[rule.with] = nil
+	if r, ok := acc.correlation(); ok {
+		stats.Correlation[rule.with] = &r
+	}
+	return stats, nil
 }
 
-// calculateDistinct calculates the number of distinct values in a field.
-func (s *AggregateService) calculateDistinct(data []DataRow, field string) int64 {
-	unique := make(map[string]bool)
+// overallDistinctValues returns the field statistics of a "distinct_values" rule over all rows.
+func (s *AggregateService) overallDistinctValues(summary *SummaryResult, data []DataRow, rule AggregateRule, policy NumericPolicy) (FieldStats, error) {
+	stats, err := s.ruleFieldStats(summary, data, rule, policy)
+	if err != nil {
+		return stats, err
+	}
+	acc := &distinctValuesAccumulator{collectAccumulator: collectAccumulator{
+		field: rule.Field, includeNulls: rule.nulls == NullPolicyInclude, opts: rule.collect,
+	}}
+	for _, row := range data {
+		_ = acc.add(row)
+	}
+	stats.DistinctSample, stats.DistinctSampleTruncated = acc.values, acc.truncated
+	return stats, nil
+}
+
+// calculateWeightedAverage calculates sum(value*weight)/sum(weight) over the rows with both
+// a value and a weight, and returns how many rows were skipped.
+// The average is nil when the total weight is zero.
+func (s *AggregateService) calculateWeightedAverage(data []DataRow, rule AggregateRule, policy NumericPolicy) (interface{}, int, error) {
+	includeNulls := rule.nulls == NullPolicyInclude
+	acc := &weightedAverageAccumulator{
+		values:  numericField{field: rule.Field, policy: policy, includeNulls: includeNulls},
+		weights: numericField{field: rule.weight, policy: policy, includeNulls: includeNulls},
+		logger:  s.logger,
+	}
+	for _, row := range data {
+		if err := acc.add(row); err != nil {
+			return nil, 0, err
+		}
+	}
+	average, skipped := acc.result()
+	return average, skipped, nil
+}
+
+// calculateMode returns the most frequent value of the field of a rule and its frequency, the
+// lexicographically smallest value on ties. Empty values count only when the rule includes nulls,
+// and the frequency is 0 when no value counted.
+func (s *AggregateService) calculateMode(data []DataRow, rule AggregateRule) (string, int) {
+	acc := &modeAccumulator{field: rule.Field, includeNulls: rule.nulls == NullPolicyInclude, counts: make(map[string]int)}
 	for _, row := range data {
-		unique[row.Fields[field]] = true
+		_ = acc.add(row)
+	}
+	return acc.mode()
+}
+
+// insertSorted inserts a value into a sorted slice, keeping at most limit values when limit is positive.
+// With distinct, a value already present is not inserted again.
+func insertSorted(values []string, value string, distinct bool, limit int) []string {
+	i := sort.SearchStrings(values, value)
+	if distinct && i < len(values) && values[i] == value {
+		return values
+	}
+	if limit > 0 && i >= limit {
+		return values
+	}
+
+	if limit <= 0 || len(values) < limit {
+		values = append(values, "")
 	}
-	return int64(len(unique))
+	copy(values[i+1:], values[i:])
+	values[i] = value
+	return values
 }
 
-// calculateFieldStats calculates comprehensive statistics for a field.
-func (s *AggregateService) calculateFieldStats(data []DataRow, field string) FieldStats {
+// calculateFieldStats calculates comprehensive statistics for a field, the numeric ones
+// applying policy to values that are not numbers. Empty values are always counted as nulls,
+// and also taken as 0 and as a unique value when nulls are included.
+func (s *AggregateService) calculateFieldStats(data []DataRow, field string, policy NumericPolicy, nulls NullPolicy) (FieldStats, error) {
 	stats := FieldStats{
 		Count: int64(len(data)),
 	}
 
 	var sum float64
-	var numericValues []float64
+	var mIn, mAx float64
 	unique := make(map[string]bool)
 	nullCount := 0
+	numbers := numericField{field: field, policy: policy, includeNulls: nulls == NullPolicyInclude}
 
 	for _, row := range data {
 		value := row.Fields[field]
 		if value == "" {
 			nullCount++
-			continue
+			if nulls != NullPolicyInclude {
+				continue
+			}
 		}
 
 		unique[value] = true
 
-		if val, err := strconv.ParseFloat(value, 64); err == nil {
-			numericValues = append(numericValues, val)
-			sum += val
+		if _, err := strconv.ParseFloat(value, 64); err != nil && value != "" {
+			stats.NonNumeric++
+		}
+
+		val, ok, err := numbers.value(row)
+		if err != nil {
+			return FieldStats{}, err
+		}
+		if !ok {
+			continue
 		}
+		if stats.NumericCount == 0 || val < mIn {
+			mIn = val
+		}
+		if stats.NumericCount == 0 || val > mAx {
+			mAx = val
+		}
+		stats.NumericCount++
+		sum += val
 	}
 
 	stats.Unique = int64(len(unique))
 	stats.NullCount = int64(nullCount)
 
-	if len(numericValues) > 0 {
-		stats.Sum = sum
-		stats.Average = sum / float64(len(numericValues))
-		stats.Min = numericValues[0]
-		stats.Max = numericValues[0]
+	if stats.NumericCount > 0 {
+		average := sum / float64(stats.NumericCount)
+		stats.Sum, stats.Average = sum, &average
+		stats.Min, stats.Max = &mIn, &mAx
+	}
+
+	return stats, nil
+}
+
+// SortKey is a field group results are sorted by: "count", "group_key", a group-by field or an aggregate alias.
+type SortKey struct {
+	Field string `json:"key"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// ParseSortKeys parses comma-separated sort keys, like "country,-total" or "country,total:desc".
+// A key prefixed with '-' or suffixed with ":desc" sorts descending, one prefixed with '+' or
+// suffixed with ":asc" ascending, and one without a direction as desc says.
+func ParseSortKeys(spec string, desc bool) []SortKey {
+	var keys []SortKey
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		key := SortKey{Field: field, Desc: desc}
+		switch lower := strings.ToLower(field); {
+		case strings.HasPrefix(field, "-"):
+			key = SortKey{Field: strings.TrimSpace(field[1:]), Desc: true}
+		case strings.HasPrefix(field, "+"):
+			key = SortKey{Field: strings.TrimSpace(field[1:]), Desc: false}
+		case strings.HasSuffix(lower, ":desc"):
+			key = SortKey{Field: strings.TrimSpace(field[:len(field)-len(":desc")]), Desc: true}
+		case strings.HasSuffix(lower, ":asc"):
+			key = SortKey{Field: strings.TrimSpace(field[:len(field)-len(":asc")]), Desc: false}
+		}
+		if key.Field != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
 
-		for _, val := range numericValues {
-			if val < stats.Min {
-				stats.Min = val
+// parseSortKeyList parses a list of sort keys, either typed, or decoded from generic JSON
+// as objects like {"key": "total", "desc": true} or as strings in the ParseSortKeys format.
+func parseSortKeyList(value interface{}) ([]SortKey, error) {
+	switch list := value.(type) {
+	case nil:
+		return nil, nil
+	case []SortKey:
+		for _, key := range list {
+			if key.Field == "" {
+				return nil, errors.New("sort key without a name")
 			}
-			if val > stats.Max {
-				stats.Max = val
+		}
+		return list, nil
+	case []interface{}:
+		var keys []SortKey
+		for _, item := range list {
+			switch item := item.(type) {
+			case string:
+				keys = append(keys, ParseSortKeys(item, false)...)
+			case map[string]interface{}:
+				name, _ := item["key"].(string)
+				if name == "" {
+					return nil, fmt.Errorf("sort key without a name: %v", item)
+				}
+				desc, _ := item["desc"].(bool)
+				keys = append(keys, SortKey{Field: name, Desc: desc})
+			default:
+				return nil, fmt.Errorf("unexpected sort key %v: expected an object or a string", item)
 			}
 		}
+		return keys, nil
+	default:
+		return nil, fmt.Errorf("unexpected sort keys %v: expected a list", value)
+	}
+}
+
+// groupColumns returns the sorted columns of the rows of groups, as written by groupRow.
+func (o *AggregateOptions) groupColumns() []string {
+	seen := map[string]bool{"group_key": true, "count": true}
+	for _, field := range o.GroupBy {
+		seen[field.Field] = true
+	}
+	for _, rule := range o.Rules {
+		seen[rule.alias()] = true
+		if rule.withCount {
+			seen[rule.alias()+"_count"] = true
+		}
+		if rule.Operation == DistinctValues {
+			seen[rule.alias()+"_truncated"] = true
+		}
+		if rule.countNulls && rule.Field != "" {
+			seen[rule.Field+"_null_count"] = true
+		}
+	}
+	if o.rankKey != nil {
+		seen[RankAggregate] = true
 	}
 
-	return stats
+	columns := make([]string, 0, len(seen))
+	for column := range seen {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
 }
 
-// sortGroupResults sorts group results.
-func (s *AggregateService) sortGroupResults(groups []GroupResult, sortBy string, desc bool) {
-	sort.Slice(groups, func(i, j int) bool {
-		var iVal, jVal float64
-		var iOk, jOk bool
+// validateSortKeys checks that every sort key names a value that groups have.
+func (o *AggregateOptions) validateSortKeys() error {
+	known := map[string]bool{"count": true, "group_key": true}
+	for _, field := range o.GroupBy {
+		known[field.Field] = true
+	}
+	for _, rule := range o.Rules {
+		known[rule.alias()] = true
+		if rule.withCount {
+			known[rule.alias()+"_count"] = true
+		}
+		if rule.Operation == DistinctValues {
+			known[rule.alias()+"_truncated"] = true
+		}
+	}
 
-		if val, ok := groups[i].Aggregates[sortBy].(float64); ok {
-			iVal = val
-			iOk = true
-		} else if val, ok := groups[i].Aggregates[sortBy].(int); ok {
-			iVal = float64(val)
-			iOk = true
+	if o.rankKey != nil {
+		if !known[o.rankKey.Field] {
+			return fmt.Errorf("unknown rank key '%s': expected count, group_key, a group-by field or an aggregate alias", o.rankKey.Field)
+		}
+		if known[RankAggregate] {
+			return fmt.Errorf("rank_by adds a '%s' aggregate, which clashes with a group-by field or an aggregate alias", RankAggregate)
+		}
+	}
+	for _, key := range o.sortKeys {
+		if !known[key.Field] {
+			return fmt.Errorf("unknown sort key '%s': expected count, group_key, a group-by field or an aggregate alias", key.Field)
 		}
+	}
+	return nil
+}
+
+// sortGroupResults sorts group results by the given keys, then by group key so that the order
+// never depends on map iteration. A key compares numerically when all its values are numbers,
+// including group values written as numbers, as dates when they all parse with the date layouts,
+// and as strings otherwise. Groups lacking a value come last in both directions.
+func sortGroupResults(groups []GroupResult, keys []SortKey, layouts []string) {
+	order := newGroupOrder(groups, keys, layouts)
+	sort.Sort(order)
+	order.apply(groups)
+}
+
+// topGroupResults returns the first n groups in the order of sortGroupResults, selecting them
+// with a bounded heap rather than sorting every group.
+func topGroupResults(groups []GroupResult, keys []SortKey, layouts []string, n int) []GroupResult {
+	order := newGroupOrder(groups, keys, layouts)
+	if n >= len(groups) {
+		sort.Sort(order)
+		return order.apply(groups)
+	}
 
-		if val, ok := groups[j].Aggregates[sortBy].(float64); ok {
-			jVal = val
-			jOk = true
-		} else if val, ok := groups[j].Aggregates[sortBy].(int); ok {
-			jVal = float64(val)
-			jOk = true
+	// the heap holds the n first groups met so far, the last of them on top
+	top := &groupHeap{order: order}
+	for i := range groups {
+		heap.Push(top, i)
+		if top.Len() > n {
+			heap.Pop(top)
 		}
+	}
+	selected := make([]GroupResult, top.Len())
+	for i := len(selected) - 1; i >= 0; i-- {
+		index, _ := heap.Pop(top).(int)
+		selected[i] = groups[index]
+	}
+	return selected
+}
+
+// groupOrder orders groups by sort keys, holding the comparable values of each key by group.
+type groupOrder struct {
+	groups      []int // indexes of the groups, in order once sorted
+	keys        []SortKey
+	values      [][]*comparableValue // values of the keys, by group index
+	comparators []valueComparator
+	groupKeys   []string
+}
 
-		if !iOk || !jOk {
-			return false
+// newGroupOrder computes the comparable values of the keys of the groups, a key comparing as
+// the type all its values share.
+func newGroupOrder(groups []GroupResult, keys []SortKey, layouts []string) *groupOrder {
+	order := &groupOrder{
+		groups:      make([]int, len(groups)),
+		keys:        keys,
+		values:      make([][]*comparableValue, len(groups)),
+		comparators: make([]valueComparator, len(keys)),
+		groupKeys:   make([]string, len(groups)),
+	}
+
+	// values of each key, in group order
+	columns := make([][]interface{}, len(keys))
+	for k, key := range keys {
+		columns[k] = make([]interface{}, len(groups))
+		for i, group := range groups {
+			columns[k][i] = group.sortValue(key.Field)
 		}
+		order.comparators[k] = valueComparator{valueType: sortValueType(columns[k], layouts), layouts: layouts}
+	}
 
-		if desc {
-			return iVal > jVal
+	for i, group := range groups {
+		order.groups[i] = i
+		order.groupKeys[i] = group.GroupKey
+		order.values[i] = make([]*comparableValue, len(keys))
+		for k := range keys {
+			order.values[i][k] = order.comparators[k].sortKey(columns[k][i])
 		}
-		return iVal < jVal
-	})
+	}
+	return order
 }
 
-// convertResultToDataRows converts AggregateResult to DataRow format.
-func (s *AggregateService) convertResultToDataRows(result *AggregateResult) []DataRow {
-	var rows []DataRow
+// before reports whether the group at an index sorts before the group at another one.
+func (o *groupOrder) before(i, j int) bool {
+	for k, key := range o.keys {
+		if c := compareSortValues(o.values[i][k], o.values[j][k], o.comparators[k], key.Desc); c != 0 {
+			return c < 0
+		}
+	}
+	if o.groupKeys[i] != o.groupKeys[j] {
+		return o.groupKeys[i] < o.groupKeys[j]
+	}
+	return i < j
+}
 
-	if len(result.Groups) > 0 {
-		for _, group := range result.Groups {
-			row := DataRow{Fields: make(map[string]string)}
+// Len implements sort.Interface.
+func (o *groupOrder) Len() int { return len(o.groups) }
 
-			// Add group key and values
-			row.Fields["group_key"] = group.GroupKey
-			row.Fields["count"] = strconv.Itoa(group.Count)
+// Less implements sort.Interface.
+func (o *groupOrder) Less(a, b int) bool { return o.before(o.groups[a], o.groups[b]) }
 
-			// Add group values
-			for field, value := range group.GroupValues {
-				row.Fields[field] = value
-			}
+// Swap implements sort.Interface.
+func (o *groupOrder) Swap(a, b int) { o.groups[a], o.groups[b] = o.groups[b], o.groups[a] }
 
-			// Add aggregates
-			for alias, value := range group.Aggregates {
-				row.Fields[alias] = fmt.Sprintf("%v", value)
-			}
+// apply reorders the groups in the sorted order, and returns them.
+func (o *groupOrder) apply(groups []GroupResult) []GroupResult {
+	sorted := make([]GroupResult, len(o.groups))
+	for n, i := range o.groups {
+		sorted[n] = groups[i]
+	}
+	copy(groups, sorted)
+	return groups
+}
+
+// groupHeap is a heap of group indexes, the group sorting last on top.
+type groupHeap struct {
+	order   *groupOrder
+	indexes []int
+}
+
+// Len implements heap.Interface.
+func (h *groupHeap) Len() int { return len(h.indexes) }
 
-			rows = append(rows, row)
+// Less implements heap.Interface.
+func (h *groupHeap) Less(a, b int) bool { return h.order.before(h.indexes[b], h.indexes[a]) }
+
+// Swap implements heap.Interface.
+func (h *groupHeap) Swap(a, b int) { h.indexes[a], h.indexes[b] = h.indexes[b], h.indexes[a] }
+
+// Push implements heap.Interface.
+func (h *groupHeap) Push(x interface{}) {
+	index, _ := x.(int)
+	h.indexes = append(h.indexes, index)
+}
+
+// Pop implements heap.Interface.
+func (h *groupHeap) Pop() interface{} {
+	last := h.indexes[len(h.indexes)-1]
+	h.indexes = h.indexes[:len(h.indexes)-1]
+	return last
+}
+
+// sortValue returns the value of a sort key, and nil when the group lacks it.
+// Aggregates take precedence over the count and group values of the same name.
+// Bins are ordered by their start, and invalid dates or numbers come last like missing values.
+func (g GroupResult) sortValue(field string) interface{} {
+	if value, ok := g.Aggregates[field]; ok {
+		if values, ok := value.([]string); ok {
+			return strings.Join(values, ",")
 		}
-	} else if result.Summary.TotalRecords > 0 {
-		row := DataRow{Fields: make(map[string]string)}
-		row.Fields["total_records"] = strconv.Itoa(result.Summary.TotalRecords)
+		return value
+	}
+
+	switch field {
+	case "count":
+		return g.Count
+	case "group_key":
+		return g.GroupKey
+	}
+
+	if start, ok := g.binStarts[field]; ok {
+		return start
+	}
+	if value, ok := g.GroupValues[field]; ok && value != InvalidBucket && !g.rolledUp[field] {
+		return value
+	}
+	return nil
+}
 
-		// Add summary statistics
-		for field, stats := range result.Summary.FieldStats {
-			row.Fields[field+"_count"] = strconv.FormatInt(stats.Count, 10)
-			if stats.Sum != 0 {
-				row.Fields[field+"_sum"] = fmt.Sprintf("%.2f", stats.Sum)
-				row.Fields[field+"_average"] = fmt.Sprintf("%.2f", stats.Average)
-				row.Fields[field+"_min"] = fmt.Sprintf("%.2f", stats.Min)
-				row.Fields[field+"_max"] = fmt.Sprintf("%.2f", stats.Max)
+// sortValueType returns how the values of a sort key compare: as numbers when all of them are,
+// as dates when all of them parse as such, as strings otherwise. Missing values are ignored.
+func sortValueType(values []interface{}, layouts []string) ValueType {
+	for _, valueType := range []ValueType{ValueTypeNumber, ValueTypeDate} {
+		matches := true
+		for _, value := range values {
+			if value == nil {
+				continue
+			}
+			if valueType == ValueTypeNumber {
+				_, matches = sortNumber(value)
+			} else {
+				str, ok := value.(string)
+				matches = ok && detectValueType(str, layouts) == ValueTypeDate
 			}
-			if stats.Unique != 0 {
-				row.Fields[field+"_unique"] = strconv.FormatInt(stats.Unique, 10)
+			if !matches {
+				break
 			}
 		}
+		if matches {
+			return valueType
+		}
+	}
+	return ValueTypeString
+}
+
+// sortKey parses a sort value as the comparator type, nil staying nil for missing values.
+func (c valueComparator) sortKey(value interface{}) *comparableValue {
+	if value == nil {
+		return nil
+	}
+	if num, ok := sortNumber(value); ok && c.valueType == ValueTypeNumber {
+		return &comparableValue{num: num}
+	}
+	parsed, _ := c.parse(fmt.Sprintf("%v", value))
+	return &parsed
+}
+
+// compareSortValues compares two sort values, returning a negative number when a sorts first.
+func compareSortValues(a, b *comparableValue, comparator valueComparator, desc bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	c := comparator.compare(*a, *b)
+	if desc {
+		return -c
+	}
+	return c
+}
+
+// sortNumber returns a sort value as a number, parsing strings.
+func sortNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		num, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return num, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// convertResultToDataRows converts AggregateResult to DataRow format: a row per group, or a single
+// summary row. The summary row holds the total of records and the statistics each rule asks for,
+// zero values included, leaving out the numeric ones of fields without any number.
+func (s *AggregateService) convertResultToDataRows(result *AggregateResult, rules []AggregateRule) []DataRow {
+	var rows []DataRow
 
+	if len(result.Groups) > 0 {
+		for _, group := range result.Groups {
+			rows = append(rows, s.groupRow(group))
+		}
+	} else if result.Summary != nil {
+		row := DataRow{Fields: make(map[string]string)}
+		row.Fields["total_records"] = strconv.Itoa(result.Summary.TotalRecords)
+		for _, rule := range rules {
+			addRuleStats(row.Fields, rule, result.Summary.FieldStats[rule.Field])
+		}
 		rows = append(rows, row)
 	}
 
 	return rows
 }
 
+// addRuleStats adds the statistics the operation of a rule asks for to the fields of a summary row.
+// The weighted average and correlations are added even when they have no value.
+func addRuleStats(fields map[string]string, rule AggregateRule, stats FieldStats) {
+	//nolint:exhaustive
+	switch rule.Operation {
+	case Count, CountNonNull:
+		fields[rule.Field+"_count"] = strconv.FormatInt(stats.Count, 10)
+	case Sum:
+		if stats.NumericCount > 0 {
+			fields[rule.Field+"_sum"] = fmt.Sprintf("%.2f", stats.Sum)
+		}
+	case Average:
+		addExtremeStat(fields, rule.Field+"_average", stats.Average, "")
+	case Min:
+		addExtremeStat(fields, rule.Field+"_min", stats.Min, stats.MinValue)
+	case Max:
+		addExtremeStat(fields, rule.Field+"_max", stats.Max, stats.MaxValue)
+	case Distinct:
+		fields[rule.Field+"_unique"] = strconv.FormatInt(stats.Unique, 10)
+	case WeightedAverage:
+		fields[rule.Field+"_weighted_average"] = ""
+		if stats.WeightedAverage != nil {
+			fields[rule.Field+"_weighted_average"] = fmt.Sprintf("%.2f", *stats.WeightedAverage)
+		}
+	case Mode:
+		fields[rule.Field+"_most_common"] = stats.MostCommon
+		fields[rule.Field+"_most_common_count"] = strconv.FormatInt(stats.MostCommonCount, 10)
+	case DistinctValues:
+		addDistinctSample(fields, rule.Field, stats)
+	case Correlation:
+		fields[rule.Field+"_correlation_"+rule.with] = ""
+		if r := stats.Correlation[rule.with]; r != nil {
+			fields[rule.Field+"_correlation_"+rule.with] = strconv.FormatFloat(*r, 'f', 4, 64)
+		}
+	}
+}
+
+// addExtremeStat adds a statistic of a summary row, a date or a string as is, or a number
+// with 2 decimals, and nothing when it has neither.
+func addExtremeStat(fields map[string]string, key string, number *float64, value string) {
+	switch {
+	case value != "":
+		fields[key] = value
+	case number != nil:
+		fields[key] = fmt.Sprintf("%.2f", *number)
+	}
+}
+
+// addDistinctSample adds the distinct values of a field, as a JSON array, to the fields of a summary row.
+func addDistinctSample(fields map[string]string, field string, stats FieldStats) {
+	sample := stats.DistinctSample
+	if sample == nil {
+		sample = []string{}
+	}
+	encoded, _ := json.Marshal(sample)
+	fields[field+"_distinct_sample"] = string(encoded)
+	fields[field+"_distinct_sample_truncated"] = strconv.FormatBool(stats.DistinctSampleTruncated)
+}
+
+// groupRow flattens a group result into a row.
+func (s *AggregateService) groupRow(group GroupResult) DataRow {
+	row := DataRow{Fields: make(map[string]string)}
+
+	// Add group key and values
+	row.Fields["group_key"] = group.GroupKey
+	row.Fields["count"] = strconv.Itoa(group.Count)
+
+	// Add group values
+	for field, value := range group.GroupValues {
+		row.Fields[field] = value
+	}
+
+	for field, nulls := range group.NullCounts {
+		row.Fields[field+"_null_count"] = strconv.Itoa(nulls)
+	}
+
+	// Add aggregates, collected arrays as JSON and null values as empty
+	for alias, value := range group.Aggregates {
+		if value == nil {
+			row.Fields[alias] = ""
+			continue
+		}
+		if values, ok := value.([]string); ok {
+			encoded, _ := json.Marshal(values)
+			row.Fields[alias] = string(encoded)
+			continue
+		}
+		row.Fields[alias] = fmt.Sprintf("%v", value)
+	}
+
+	return row
+}
+
 // AggregateFile aggregates data from a file
 // This convenience method demonstrates file-based aggregation.
-func (s *AggregateService) AggregateFile(inputFile, outputFile string, rules []AggregateRule, groupBy []string) (*ProcessingResult, error) {
+// extraOptions may carry any additional ProcessData option and can be nil.
+func (s *AggregateService) AggregateFile(inputFile, outputFile string, rules []AggregateRule, groupBy []GroupByField, extraOptions map[string]interface{}) (*ProcessingResult, error) {
 	s.logger.Info().
 		Str("input", inputFile).
 		Str("output", outputFile).
 		Int("rules", len(rules)).
-		Strs("group_by", groupBy).
+		Strs("group_by", groupByNames(groupBy)).
 		Msg("Starting file aggregation")
 
-	options := map[string]interface{}{
+	options := mergeOptions(map[string]interface{}{
 		"input_file":  inputFile,
 		"output_file": outputFile,
 		"rules":       rules,
 		"group_by":    groupBy,
-	}
+	}, extraOptions)
 
-	resultData, err := s.ProcessData(nil, options)
+	result, resultData, err := s.run(nil, options)
 	if err != nil {
 		return &ProcessingResult{
 			Success:   false,
@@ -526,10 +2243,26 @@ func (s *AggregateService) AggregateFile(inputFile, outputFile string, rules []A
 		}, err
 	}
 
+	var warnings []string
+	if result.InvalidValues > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d rows have a date or number that does not parse, grouped as %q", result.InvalidValues, InvalidBucket))
+	}
+	fields := make([]string, 0, len(result.NonNumeric))
+	for field := range result.NonNumeric {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		warnings = append(warnings, fmt.Sprintf("field '%s' has %d non-numeric values, skipped by numeric aggregates", field, result.NonNumeric[field]))
+	}
+
 	return &ProcessingResult{
-		Success:    true,
-		Processed:  len(resultData),
-		OutputPath: outputFile,
-		Processor:  s.GetName(),
+		Success:     true,
+		Processed:   len(resultData),
+		TotalGroups: result.TotalGroups,
+		InputFiles:  result.InputFiles,
+		OutputPath:  outputFile,
+		Warnings:    warnings,
+		Processor:   s.GetName(),
 	}, nil
 }
@@ -0,0 +1,1530 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAggregateService(t *testing.T) *AggregateService {
+	t.Helper()
+
+	return &AggregateService{
+		fileService:   newTestFileService(t),
+		filterService: newTestFilterService(t),
+		logger:        zerolog.Nop(),
+	}
+}
+
+func TestAggregateService_collect(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"user", "tag"},
+		[]string{"ann", "go"}, []string{"ann", "sql"}, []string{"ann", "go"},
+		[]string{"ann", ""}, []string{"ann", "c"}, []string{"bob", "rust"},
+	)
+
+	testCases := []struct {
+		name     string
+		params   map[string]interface{}
+		expected interface{}
+	}{
+		{name: "all values in input order", expected: "go,sql,go,c"},
+		{name: "distinct", params: map[string]interface{}{"distinct": true, "separator": "|"}, expected: "go|sql|c"},
+		{name: "limit", params: map[string]interface{}{"limit": 2}, expected: "go,sql"},
+		{name: "distinct limit", params: map[string]interface{}{"distinct": true, "limit": float64(3)}, expected: "go,sql,c"},
+		{name: "sorted", params: map[string]interface{}{"order": "sorted"}, expected: "c,go,go,sql"},
+		{name: "sorted distinct limit", params: map[string]interface{}{"order": "sorted", "distinct": true, "limit": 2}, expected: "c,go"},
+		{name: "array", params: map[string]interface{}{"as_array": true, "distinct": true}, expected: []string{"go", "sql", "c"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestAggregateService(t)
+			opts, err := s.parseAggregateOptions(map[string]interface{}{
+				"rules":    []AggregateRule{{Field: "tag", Operation: Collect, Alias: "tags", Parameters: tc.params}},
+				"group_by": []string{"user"},
+			})
+			is.NoError(err)
+
+			result, err := s.aggregateData(rows, opts)
+			is.NoError(err)
+			for _, group := range result.Groups {
+				if group.GroupKey == "ann" {
+					is.Equal(tc.expected, group.Aggregates["tags"])
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateService_collectValidation(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+
+	_, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules":    []AggregateRule{{Field: "tag", Operation: Collect, Parameters: map[string]interface{}{"limit": -1}}},
+		"group_by": []string{"user"},
+	})
+	is.ErrorContains(err, "invalid collect parameters on field 'tag': limit must be a non-negative integer")
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules":    []AggregateRule{{Field: "tag", Operation: Collect, Parameters: map[string]interface{}{"order": "random"}}},
+		"group_by": []string{"user"},
+	})
+	is.ErrorContains(err, `unknown order "random"`)
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "tag", Operation: Collect}},
+	})
+	is.ErrorContains(err, "collect on field 'tag' requires group_by")
+}
+
+func TestAggregateService_collectFile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("user,tag\nann,go\nann,sql\n"), 0o600))
+
+	var rules []AggregateRule
+	is.NoError(json.Unmarshal([]byte(`[{"field":"tag","operation":"collect","alias":"tags","parameters":{"as_array":true}}]`), &rules))
+
+	output := filepath.Join(dir, "out.json")
+	result, err := s.AggregateFile(input, output, rules, []GroupByField{{Field: "user"}}, nil)
+	is.NoError(err)
+	is.Equal(1, result.Processed)
+
+	content, err := os.ReadFile(output)
+	is.NoError(err)
+	is.Contains(string(content), `"tags": [`)
+
+	rows, err := s.ProcessData(nil, map[string]interface{}{"input_file": input, "rules": rules, "group_by": []string{"user"}})
+	is.NoError(err)
+	is.Equal(`["go","sql"]`, rows[0].Fields["tags"])
+}
+
+func TestAggregateService_having(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	rows := testRows(t, []string{"customer", "amount"},
+		[]string{"acme", "9000"}, []string{"acme", "2000"}, []string{"globex", "500"},
+		[]string{"initech", "7000"}, []string{"initech", "7000"}, []string{"initech", "100"},
+	)
+	rules := []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}}
+
+	aggregate := func(having interface{}) []string {
+		t.Helper()
+
+		opts, err := s.parseAggregateOptions(map[string]interface{}{
+			"rules":    rules,
+			"group_by": []string{"customer"},
+			"having":   having,
+			"sort_by":  "total",
+		})
+		is.NoError(err)
+		result, err := s.aggregateData(rows, opts)
+		is.NoError(err)
+
+		keys := []string{}
+		for _, group := range result.Groups {
+			keys = append(keys, group.GroupKey)
+		}
+		return keys
+	}
+
+	is.Equal([]string{"acme", "initech"}, aggregate([]FilterRule{{Field: "total", Operator: "greater_than", Value: 10000}}))
+	is.Equal([]string{"initech"}, aggregate(FilterGroup{Rules: []FilterRule{
+		{Field: "total", Operator: "greater_than", Value: 10000},
+		{Field: "count", Operator: "greater_than", Value: 2},
+	}}))
+
+	// generic JSON, with group values visible too
+	var having interface{}
+	is.NoError(json.Unmarshal([]byte(`{"logic":"or","rules":[
+		{"field":"customer","operator":"equals","value":"globex"},
+		{"field":"total","operator":"between","value":{"min":11000,"max":11000}}
+	]}`), &having))
+	is.Equal([]string{"globex", "acme"}, aggregate(having))
+
+	_, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules":  rules,
+		"having": []FilterRule{{Field: "total", Operator: "greater_than", Value: 1}},
+	})
+	is.EqualError(err, "having requires group_by")
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules":    rules,
+		"group_by": []string{"customer"},
+		"having":   FilterGroup{Logic: "xor"},
+	})
+	is.ErrorContains(err, "invalid having rules")
+}
+
+func TestParseSortKeys(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	is.Equal([]SortKey{{Field: "country"}, {Field: "total", Desc: true}}, ParseSortKeys("country, -total", false))
+	is.Equal([]SortKey{{Field: "country", Desc: true}, {Field: "total"}}, ParseSortKeys("country,+total,", true))
+	is.Nil(ParseSortKeys("", false))
+	is.Equal([]SortKey{{Field: "country"}, {Field: "total", Desc: true}, {Field: "year"}}, ParseSortKeys("country:asc,total:DESC,year", false))
+}
+
+func TestAggregateService_sortList(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"country", "year", "amount"},
+		[]string{"FR", "2024", "10"}, []string{"DE", "2023", "30"}, []string{"US", "2023", "10"},
+		[]string{"FR", "2023", "5"}, []string{"DE", "2024", "1"}, []string{"US", "999", "20"},
+	)
+
+	testCases := []struct {
+		name     string
+		options  map[string]interface{}
+		expected []string
+	}{
+		{
+			name:     "typed keys",
+			options:  map[string]interface{}{"sort": []SortKey{{Field: "country"}, {Field: "total", Desc: true}}},
+			expected: []string{"DE|2023", "DE|2024", "FR|2024", "FR|2023", "US|999", "US|2023"},
+		},
+		{
+			name: "generic JSON keys",
+			options: map[string]interface{}{"sort": []interface{}{
+				map[string]interface{}{"key": "year", "desc": true},
+				map[string]interface{}{"key": "total"},
+			}},
+			expected: []string{"DE|2024", "FR|2024", "FR|2023", "US|2023", "DE|2023", "US|999"},
+		},
+		{
+			name:     "list takes precedence over sort_by",
+			options:  map[string]interface{}{"sort": []interface{}{"total:desc", "country"}, "sort_by": "country"},
+			expected: []string{"DE|2023", "US|999", "FR|2024", "US|2023", "FR|2023", "DE|2024"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			options := map[string]interface{}{
+				"rules":    []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}},
+				"group_by": []string{"country", "year"},
+			}
+			for name, value := range tc.options {
+				options[name] = value
+			}
+
+			s := newTestAggregateService(t)
+			opts, err := s.parseAggregateOptions(options)
+			is.NoError(err)
+
+			result, err := s.aggregateData(rows, opts)
+			is.NoError(err)
+			keys := []string{}
+			for _, group := range result.Groups {
+				keys = append(keys, group.GroupKey)
+			}
+			is.Equal(tc.expected, keys)
+		})
+	}
+
+	s := newTestAggregateService(t)
+	_, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "amount", Operation: Sum}},
+		"sort":  []interface{}{map[string]interface{}{"desc": true}},
+	})
+	assert.ErrorContains(t, err, "sort key without a name")
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules":    []AggregateRule{{Field: "amount", Operation: Sum}},
+		"group_by": []string{"country"},
+		"sort":     []SortKey{{Field: "revenue"}},
+	})
+	assert.ErrorContains(t, err, "unknown sort key 'revenue'")
+}
+
+func TestAggregateService_sort(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"country", "year", "amount"},
+		[]string{"FR", "2024", "10"}, []string{"DE", "2023", "30"}, []string{"US", "2023", "10"},
+		[]string{"FR", "2023", "5"}, []string{"DE", "2024", "1"}, []string{"US", "999", "20"},
+	)
+
+	testCases := []struct {
+		name     string
+		groupBy  []string
+		sortBy   string
+		sortDesc bool
+		expected []string
+	}{
+		{name: "group key by default", groupBy: []string{"country"}, expected: []string{"DE", "FR", "US"}},
+		{name: "group value descending", groupBy: []string{"country"}, sortBy: "country", sortDesc: true, expected: []string{"US", "FR", "DE"}},
+		{name: "aggregate with ties broken by group key", groupBy: []string{"country"}, sortBy: "total", expected: []string{"FR", "US", "DE"}},
+		{name: "count then aggregate", groupBy: []string{"year"}, sortBy: "-count,+total", expected: []string{"2023", "2024", "999"}},
+		{name: "numeric group values", groupBy: []string{"year"}, sortBy: "year", expected: []string{"999", "2023", "2024"}},
+		{name: "several keys", groupBy: []string{"year", "country"}, sortBy: "-year,country", expected: []string{"2024|DE", "2024|FR", "2023|DE", "2023|FR", "2023|US", "999|US"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestAggregateService(t)
+			opts, err := s.parseAggregateOptions(map[string]interface{}{
+				"rules":     []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}},
+				"group_by":  tc.groupBy,
+				"sort_by":   tc.sortBy,
+				"sort_desc": tc.sortDesc,
+			})
+			is.NoError(err)
+
+			result, err := s.aggregateData(rows, opts)
+			is.NoError(err)
+			keys := []string{}
+			for _, group := range result.Groups {
+				keys = append(keys, group.GroupKey)
+			}
+			is.Equal(tc.expected, keys)
+		})
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		_, err := newTestAggregateService(t).parseAggregateOptions(map[string]interface{}{
+			"rules":    []AggregateRule{{Field: "amount", Operation: Sum}},
+			"group_by": []string{"country"},
+			"sort_by":  "total",
+		})
+		is.ErrorContains(err, "unknown sort key 'total'")
+	})
+}
+
+func TestAggregateService_weightedAverage(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	rows := testRows(t, []string{"order", "unit_price", "quantity"},
+		[]string{"a", "10", "1"}, []string{"a", "20", "3"}, []string{"a", "n/a", "2"},
+		[]string{"b", "5", "0"}, []string{"b", "7", ""},
+	)
+	rules := []AggregateRule{{Field: "unit_price", Operation: WeightedAverage, Alias: "price", Parameters: map[string]interface{}{"weight": "quantity"}}}
+
+	opts, err := s.parseAggregateOptions(map[string]interface{}{"rules": rules, "group_by": []string{"order"}})
+	is.NoError(err)
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Len(result.Groups, 2)
+	is.Equal(17.5, result.Groups[0].Aggregates["price"])
+	is.Equal(map[string]int{"price": 1}, result.Groups[0].Skipped)
+	is.Nil(result.Groups[1].Aggregates["price"])
+	is.Equal(map[string]int{"price": 1}, result.Groups[1].Skipped)
+
+	encoded, err := json.Marshal(result.Groups[1])
+	is.NoError(err)
+	is.Contains(string(encoded), `"price":null`)
+	is.Equal("", s.groupRow(result.Groups[1]).Fields["price"])
+
+	// ungrouped, alongside the other statistics of the field
+	opts, err = s.parseAggregateOptions(map[string]interface{}{"rules": rules})
+	is.NoError(err)
+	result, err = s.aggregateData(rows, opts)
+	is.NoError(err)
+	stats := result.Summary.FieldStats["unit_price"]
+	is.NotNil(stats.WeightedAverage)
+	is.Equal(17.5, *stats.WeightedAverage)
+	is.Equal(int64(2), stats.Skipped)
+	is.Equal(42.0, stats.Sum)
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "unit_price", Operation: WeightedAverage}},
+	})
+	is.EqualError(err, "weighted_average on field 'unit_price' requires a weight parameter")
+}
+
+func TestAggregateService_conditionalAggregates(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	rows := testRows(t, []string{"customer", "status", "amount"},
+		[]string{"acme", "refunded", "100"}, []string{"acme", "paid", "50"}, []string{"acme", "REFUNDED", "20"},
+		[]string{"globex", "paid", "70"},
+	)
+
+	var rules []AggregateRule
+	is.NoError(json.Unmarshal([]byte(`[
+		{"field":"amount","operation":"sum","alias":"total"},
+		{"field":"status","operation":"count_if","alias":"refunds","parameters":{"condition":{"field":"status","operator":"equals","value":"refunded"}}},
+		{"field":"amount","operation":"sum_if","alias":"refunded_amount","parameters":{"condition":[
+			{"field":"status","operator":"equals","value":"refunded"},
+			{"field":"amount","operator":"greater_than","value":50}
+		]}}
+	]`), &rules))
+
+	opts, err := s.parseAggregateOptions(map[string]interface{}{"rules": rules, "group_by": []string{"customer"}})
+	is.NoError(err)
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal(map[string]interface{}{"total": 170.0, "refunds": 2, "refunded_amount": 100.0}, result.Groups[0].Aggregates)
+	is.Equal(map[string]interface{}{"total": 70.0, "refunds": 0, "refunded_amount": 0.0}, result.Groups[1].Aggregates)
+
+	// typed conditions
+	opts, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "amount", Operation: SumIf, Alias: "paid", Parameters: map[string]interface{}{
+			"condition": FilterRule{Field: "status", Operator: "equals", Value: "paid"},
+		}}},
+		"group_by": []string{"customer"},
+	})
+	is.NoError(err)
+	result, err = s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal(50.0, result.Groups[0].Aggregates["paid"])
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules":    []AggregateRule{{Field: "status", Operation: CountIf}},
+		"group_by": []string{"customer"},
+	})
+	is.EqualError(err, "count_if on field 'status' requires a condition parameter")
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "status", Operation: CountIf, Parameters: map[string]interface{}{
+			"condition": map[string]interface{}{"field": "status", "operator": "regex", "value": "("},
+		}}},
+		"group_by": []string{"customer"},
+	})
+	is.ErrorContains(err, "invalid count_if condition on field 'status'")
+}
+
+func TestAggregateService_mode(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	rows := testRows(t, []string{"country", "payment"},
+		[]string{"FR", "card"}, []string{"FR", "paypal"}, []string{"FR", "card"}, []string{"FR", ""},
+		[]string{"DE", "transfer"}, []string{"DE", "card"},
+		[]string{"US", ""}, []string{"US"},
+	)
+
+	opts, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules":    []AggregateRule{{Field: "payment", Operation: Mode, Alias: "method", Parameters: map[string]interface{}{"with_count": true}}},
+		"group_by": []string{"country"},
+		"sort_by":  "-method_count",
+	})
+	is.NoError(err)
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal([]GroupResult{
+		{GroupKey: "FR", GroupValues: map[string]string{"country": "FR"}, Aggregates: map[string]interface{}{"method": "card", "method_count": 2}, Count: 4},
+		{GroupKey: "DE", GroupValues: map[string]string{"country": "DE"}, Aggregates: map[string]interface{}{"method": "card", "method_count": 1}, Count: 2},
+		{GroupKey: "US", GroupValues: map[string]string{"country": "US"}, Aggregates: map[string]interface{}{"method": nil, "method_count": 0}, Count: 2},
+	}, result.Groups)
+
+	opts, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "payment", Operation: Mode}},
+	})
+	is.NoError(err)
+	result, err = s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal("card", result.Summary.FieldStats["payment"].MostCommon)
+	is.Equal(int64(3), result.Summary.FieldStats["payment"].MostCommonCount)
+	is.Equal("3", s.convertResultToDataRows(result, opts.Rules)[0].Fields["payment_most_common_count"])
+}
+
+func TestAggregateService_rulesOnSameField(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"country", "amount"},
+		[]string{"FR", "10"}, []string{"FR", "20"}, []string{"DE", "5"}, []string{"", "x"},
+	)
+	testCases := []struct {
+		name     string
+		rules    []AggregateRule
+		expected map[string]string
+	}{
+		{
+			name:     "mode and count",
+			rules:    []AggregateRule{{Field: "country", Operation: Mode}, {Field: "country", Operation: Count, Parameters: map[string]interface{}{"null_policy": "exclude"}}},
+			expected: map[string]string{"country_most_common": "FR", "country_most_common_count": "2", "country_count": "3"},
+		},
+		{
+			name:     "min and sum",
+			rules:    []AggregateRule{{Field: "amount", Operation: Min}, {Field: "amount", Operation: Sum}},
+			expected: map[string]string{"amount_min": "5.00", "amount_sum": "35.00"},
+		},
+		{
+			name:     "distinct values and average",
+			rules:    []AggregateRule{{Field: "country", Operation: DistinctValues}, {Field: "amount", Operation: Average}, {Field: "country", Operation: Distinct}},
+			expected: map[string]string{"country_distinct_sample": `["DE","FR"]`, "country_unique": "2", "amount_average": "11.67"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			reversed := make([]AggregateRule, 0, len(tc.rules))
+			for i := len(tc.rules) - 1; i >= 0; i-- {
+				reversed = append(reversed, tc.rules[i])
+			}
+
+			s := newTestAggregateService(t)
+			for _, rules := range [][]AggregateRule{tc.rules, reversed} {
+				out, err := s.ProcessData(rows, map[string]interface{}{"rules": rules})
+				is.NoError(err)
+				for key, value := range tc.expected {
+					is.Equal(value, out[0].Fields[key], key)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateService_distinctValues(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"country", "currency"},
+		[]string{"fr", "EUR"}, []string{"fr", "USD"}, []string{"fr", "EUR"}, []string{"fr", ""},
+		[]string{"us", "USD"}, []string{"us", "CAD"}, []string{"us", "MXN"}, []string{"us", "AUD"},
+	)
+
+	testCases := []struct {
+		name      string
+		params    map[string]interface{}
+		expected  map[string]interface{}
+		truncated map[string]bool
+	}{
+		{
+			name:      "joined",
+			expected:  map[string]interface{}{"fr": "EUR,USD", "us": "AUD,CAD,MXN,USD"},
+			truncated: map[string]bool{"fr": false, "us": false},
+		},
+		{
+			name:      "limit",
+			params:    map[string]interface{}{"limit": float64(2), "separator": "|"},
+			expected:  map[string]interface{}{"fr": "EUR|USD", "us": "AUD|CAD"},
+			truncated: map[string]bool{"fr": false, "us": true},
+		},
+		{
+			name:      "array",
+			params:    map[string]interface{}{"as_array": true, "limit": 3},
+			expected:  map[string]interface{}{"fr": []string{"EUR", "USD"}, "us": []string{"AUD", "CAD", "MXN"}},
+			truncated: map[string]bool{"fr": false, "us": true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestAggregateService(t)
+			opts, err := s.parseAggregateOptions(map[string]interface{}{
+				"rules":    []AggregateRule{{Field: "currency", Operation: DistinctValues, Alias: "currencies", Parameters: tc.params}},
+				"group_by": []string{"country"},
+			})
+			is.NoError(err)
+
+			result, err := s.aggregateData(rows, opts)
+			is.NoError(err)
+			for _, group := range result.Groups {
+				is.Equal(tc.expected[group.GroupKey], group.Aggregates["currencies"], group.GroupKey)
+				is.Equal(tc.truncated[group.GroupKey], group.Aggregates["currencies_truncated"], group.GroupKey)
+			}
+		})
+	}
+
+	t.Run("summary", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		s := newTestAggregateService(t)
+		out, err := s.ProcessData(rows, map[string]interface{}{
+			"rules": []AggregateRule{{Field: "currency", Operation: DistinctValues, Parameters: map[string]interface{}{"limit": 3}}},
+		})
+		is.NoError(err)
+		is.Equal(`["AUD","CAD","EUR"]`, out[0].Fields["currency_distinct_sample"])
+		is.Equal("true", out[0].Fields["currency_distinct_sample_truncated"])
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		_, err := newTestAggregateService(t).parseAggregateOptions(map[string]interface{}{
+			"rules": []AggregateRule{{Field: "currency", Operation: DistinctValues, Parameters: map[string]interface{}{"limit": 0}}},
+		})
+		is.EqualError(err, "invalid distinct_values parameters on field 'currency': limit must be a positive integer, got 0")
+	})
+}
+
+func TestAggregateService_correlation(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"shop", "price", "quantity"},
+		[]string{"up", "1", "10"}, []string{"up", "2", "20"}, []string{"up", "3", "30"}, []string{"up", "x", "40"},
+		[]string{"down", "1", "9"}, []string{"down", "2", "7"}, []string{"down", "4", "2"},
+		[]string{"flat", "1", "5"}, []string{"flat", "2", "5"},
+		[]string{"single", "1", "5"},
+	)
+	rules := []AggregateRule{{Field: "price", Operation: Correlation, Alias: "r", Parameters: map[string]interface{}{"with": "quantity"}}}
+
+	s := newTestAggregateService(t)
+	opts, err := s.parseAggregateOptions(map[string]interface{}{"rules": rules, "group_by": []string{"shop"}})
+	is.NoError(err)
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+
+	correlations := map[string]interface{}{}
+	for _, group := range result.Groups {
+		correlations[group.GroupKey] = group.Aggregates["r"]
+	}
+	is.InDelta(1.0, correlations["up"], 1e-9)
+	is.InDelta(-0.9986, correlations["down"], 1e-4)
+	is.Nil(correlations["flat"])
+	is.Nil(correlations["single"])
+	is.Equal(map[string]int{"r": 1}, result.Groups[3].Skipped)
+
+	out, err := s.ProcessData(rows, map[string]interface{}{"rules": rules})
+	is.NoError(err)
+	is.Equal("0.2020", out[0].Fields["price_correlation_quantity"])
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "price", Operation: Correlation}},
+	})
+	is.EqualError(err, "correlation on field 'price' requires a with parameter")
+}
+
+func TestAggregateService_typedMinMax(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"cohort", "signup_date", "name", "score"},
+		[]string{"a", "2024-03-01", "zoe", "9"}, []string{"a", "2024-01-15", "bob", "10"},
+		[]string{"a", "not a date", "", "n/a"}, []string{"b", "2023-12-31", "amy", "2"},
+	)
+
+	s := newTestAggregateService(t)
+	opts, err := s.parseAggregateOptions(map[string]interface{}{
+		"group_by": []string{"cohort"},
+		"rules": []AggregateRule{
+			{Field: "signup_date", Operation: Min, Alias: "first_signup"},
+			{Field: "signup_date", Operation: Max, Alias: "last_signup"},
+			{Field: "name", Operation: Min, Alias: "first_name"},
+			{Field: "score", Operation: Max, Alias: "best_score"},
+			{Field: "score", Operation: Max, Alias: "best_score_text", Parameters: map[string]interface{}{"type": "string"}},
+		},
+		"sort_by": "first_signup",
+	})
+	is.NoError(err)
+
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal("b", result.Groups[0].GroupKey)
+
+	group := result.Groups[1]
+	is.Equal("2024-01-15", group.Aggregates["first_signup"])
+	is.Equal("2024-03-01", group.Aggregates["last_signup"])
+	is.Equal("bob", group.Aggregates["first_name"])
+	is.Equal(10.0, group.Aggregates["best_score"])
+	is.Equal("n/a", group.Aggregates["best_score_text"])
+	is.Equal(map[string]int{"first_signup": 1, "last_signup": 1, "best_score": 1}, group.Skipped)
+
+	// sorting by a string aggregate compares strings
+	opts.sortKeys = ParseSortKeys("-first_name", false)
+	result, err = s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal("a", result.Groups[0].GroupKey)
+
+	out, err := s.ProcessData(rows, map[string]interface{}{
+		"rules": []AggregateRule{{Field: "signup_date", Operation: Min}, {Field: "name", Operation: Max}},
+	})
+	is.NoError(err)
+	is.Equal("2023-12-31", out[0].Fields["signup_date_min"])
+	is.Equal("zoe", out[0].Fields["name_max"])
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "name", Operation: Min, Parameters: map[string]interface{}{"type": "text"}}},
+	})
+	is.EqualError(err, `invalid min on field 'name': unknown value type "text": expected auto, number, date or string`)
+}
+
+func TestAggregateService_minMaxWithoutNumbers(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"group", "value"},
+		[]string{"text", "n/a"}, []string{"text", "unknown"},
+		[]string{"negative", "-5"}, []string{"negative", "n/a"},
+		[]string{"balanced", "-3"}, []string{"balanced", "3"},
+	)
+	rules := []AggregateRule{
+		{Field: "value", Operation: Min, Alias: "lowest", Parameters: map[string]interface{}{"type": "number"}},
+		{Field: "value", Operation: Max, Alias: "highest", Parameters: map[string]interface{}{"type": "number"}},
+		{Field: "value", Operation: Average, Alias: "mean"},
+	}
+
+	s := newTestAggregateService(t)
+	out, err := s.ProcessData(rows, map[string]interface{}{"rules": rules, "group_by": []string{"group"}})
+	is.NoError(err)
+	byGroup := map[string]DataRow{}
+	for _, row := range out {
+		byGroup[row.Fields["group"]] = row
+	}
+	is.Equal("", byGroup["text"].Fields["lowest"])
+	is.Equal("", byGroup["text"].Fields["highest"])
+	is.Equal("", byGroup["text"].Fields["mean"])
+	is.Equal("-5", byGroup["negative"].Fields["lowest"])
+	is.Equal("-5", byGroup["negative"].Fields["highest"])
+
+	summary := func(values ...string) DataRow {
+		t.Helper()
+
+		records := make([][]string, 0, len(values))
+		for _, value := range values {
+			records = append(records, []string{value})
+		}
+		out, err := s.ProcessData(testRows(t, []string{"value"}, records...), map[string]interface{}{
+			"rules": []AggregateRule{
+				{Field: "value", Operation: Sum},
+				{Field: "value", Operation: Average},
+				{Field: "value", Operation: Min, Parameters: map[string]interface{}{"type": "number"}},
+				{Field: "value", Operation: Max, Parameters: map[string]interface{}{"type": "number"}},
+			},
+		})
+		is.NoError(err)
+		return out[0]
+	}
+
+	row := summary("n/a", "unknown")
+	is.NotContains(row.Fields, "value_sum")
+	is.NotContains(row.Fields, "value_min")
+	is.NotContains(row.Fields, "value_average")
+
+	row = summary("-5", "n/a")
+	is.Equal("-5.00", row.Fields["value_min"])
+	is.Equal("-5.00", row.Fields["value_max"])
+
+	row = summary("-3", "3")
+	is.Equal("0.00", row.Fields["value_sum"])
+	is.Equal("-3.00", row.Fields["value_min"])
+	is.Equal("3.00", row.Fields["value_max"])
+}
+
+func TestSortGroupResults_dates(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	groups := []GroupResult{
+		{GroupKey: "a", Aggregates: map[string]interface{}{"last": "15/03/2024"}},
+		{GroupKey: "b", Aggregates: map[string]interface{}{"last": "02/11/2023"}},
+		{GroupKey: "c", Aggregates: map[string]interface{}{"last": "01/04/2024"}},
+	}
+	sortGroupResults(groups, []SortKey{{Field: "last"}}, []string{"02/01/2006"})
+	is.Equal([]string{"b", "a", "c"}, []string{groups[0].GroupKey, groups[1].GroupKey, groups[2].GroupKey})
+}
+
+func TestTopGroupResults(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	newGroups := func() []GroupResult {
+		groups := make([]GroupResult, 0, 50)
+		for i := range 50 {
+			// totals repeat, so that ties are broken by group key
+			groups = append(groups, GroupResult{GroupKey: fmt.Sprintf("g%02d", (i*37)%50), Aggregates: map[string]interface{}{"total": float64((i * 7) % 10)}})
+		}
+		return groups
+	}
+	keys := []SortKey{{Field: "total", Desc: true}}
+
+	sorted := newGroups()
+	sortGroupResults(sorted, keys, nil)
+	for _, n := range []int{1, 5, 13, 50, 80} {
+		top := topGroupResults(newGroups(), keys, nil, n)
+		is.Equal(sorted[:min(n, len(sorted))], top, n)
+	}
+}
+
+func TestAggregateService_groupValuesWithSeparator(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"first", "second", "amount"},
+		[]string{"a|b", "c", "1"}, []string{"a", "b|c", "10"}, []string{"a|b", "c", "100"},
+	)
+
+	s := newTestAggregateService(t)
+	opts, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules":    []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}},
+		"group_by": []string{"first", "second"},
+	})
+	is.NoError(err)
+
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal(2, result.TotalGroups)
+	is.Equal(map[string]string{"first": "a", "second": "b|c"}, result.Groups[0].GroupValues)
+	is.Equal(10.0, result.Groups[0].Aggregates["total"])
+	is.Equal(map[string]string{"first": "a|b", "second": "c"}, result.Groups[1].GroupValues)
+	is.Equal(101.0, result.Groups[1].Aggregates["total"])
+	is.Equal(result.Groups[0].GroupKey, result.Groups[1].GroupKey)
+}
+
+func TestAggregateService_rollup(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"region", "product", "amount"},
+		[]string{"north", "pen", "1"}, []string{"north", "ink", "2"}, []string{"south", "pen", "4"},
+		[]string{"north", "pen", "8"}, []string{"south", "ink", "16"},
+	)
+
+	s := newTestAggregateService(t)
+	aggregate := func(options map[string]interface{}) [][]interface{} {
+		t.Helper()
+
+		options["rules"] = []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}}
+		options["group_by"] = []string{"region", "product"}
+		options["rollup"] = true
+		opts, err := s.parseAggregateOptions(options)
+		is.NoError(err)
+		result, err := s.aggregateData(rows, opts)
+		is.NoError(err)
+
+		groups := [][]interface{}{}
+		for _, group := range result.Groups {
+			groups = append(groups, []interface{}{group.GroupValues["region"], group.GroupValues["product"], group.Aggregates["total"]})
+		}
+		return groups
+	}
+
+	is.Equal([][]interface{}{
+		{"north", "ink", 2.0}, {"north", "pen", 9.0}, {"north", "(all)", 11.0},
+		{"south", "ink", 16.0}, {"south", "pen", 4.0}, {"south", "(all)", 20.0},
+		{"(all)", "(all)", 31.0},
+	}, aggregate(map[string]interface{}{}))
+
+	// siblings follow the sort keys, subtotals stay after their groups
+	is.Equal([][]interface{}{
+		{"south", "ink", 16.0}, {"south", "pen", 4.0}, {"south", "*", 20.0},
+		{"north", "pen", 9.0}, {"north", "ink", 2.0}, {"north", "*", 11.0},
+		{"*", "*", 31.0},
+	}, aggregate(map[string]interface{}{"sort_by": "-total", "rollup_marker": "*"}))
+
+	// having removing a subtotal keeps its groups
+	is.Equal([][]interface{}{
+		{"north", "pen", 9.0}, {"south", "ink", 16.0}, {"south", "pen", 4.0}, {"south", "(all)", 20.0}, {"(all)", "(all)", 31.0},
+	}, aggregate(map[string]interface{}{"having": FilterGroup{Logic: "or", Rules: []FilterRule{
+		{Field: "product", Operator: "equals", Value: "pen"},
+		{Field: "total", Operator: "greater_than", Value: 15},
+	}}}))
+
+	_, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules":  []AggregateRule{{Field: "amount", Operation: Sum}},
+		"rollup": true,
+	})
+	is.EqualError(err, "rollup requires group_by")
+}
+
+func TestAggregateService_offsetAndLimit(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("user,amount\na,1\nb,5\nc,3\nd,4\nb,1\n"), 0o600))
+	rules := []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}}
+
+	result, err := s.AggregateFile(input, "", rules, []GroupByField{{Field: "user"}}, map[string]interface{}{"sort_by": "-total", "limit": 2})
+	is.NoError(err)
+	is.Equal(2, result.Processed)
+	is.Equal(4, result.TotalGroups)
+
+	rows, err := s.ProcessData(nil, map[string]interface{}{
+		"input_file": input, "rules": rules, "group_by": []string{"user"}, "sort_by": "-total", "offset": 1, "limit": float64(2),
+	})
+	is.NoError(err)
+	is.Equal([]string{"d", "c"}, []string{rows[0].Fields["user"], rows[1].Fields["user"]})
+
+	rows, err = s.ProcessData(nil, map[string]interface{}{
+		"input_file": input, "rules": rules, "group_by": []string{"user"}, "offset": 10,
+	})
+	is.NoError(err)
+	is.Empty(rows)
+
+	_, err = s.ProcessData(nil, map[string]interface{}{"input_file": input, "rules": rules, "limit": 1})
+	is.ErrorContains(err, "offset and limit require group_by")
+
+	_, err = s.ProcessData(nil, map[string]interface{}{"input_file": input, "rules": rules, "group_by": []string{"user"}, "limit": -1})
+	is.ErrorContains(err, "must not be negative")
+}
+
+func TestAggregateService_dateBuckets(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("created_at,country,amount\n"+
+		"2024-01-05T10:00:00Z,FR,10\n2024-01-20,FR,5\n2024-02-01 08:00,FR,1\n2024-01-07,DE,2\nyesterday,FR,3\n,DE,4\n"), 0o600))
+
+	var groupBy []GroupByField
+	is.NoError(json.Unmarshal([]byte(`[{"field":"created_at","bucket":"month"},"country"]`), &groupBy))
+	rules := []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}}
+
+	result, err := s.AggregateFile(input, "", rules, groupBy, nil)
+	is.NoError(err)
+	is.Equal([]string{`2 rows have a date or number that does not parse, grouped as "_invalid"`}, result.Warnings)
+
+	rows, err := s.ProcessData(nil, map[string]interface{}{"input_file": input, "rules": rules, "group_by": groupBy})
+	is.NoError(err)
+	groups := map[string]string{}
+	for _, row := range rows {
+		groups[row.Fields["group_key"]] = row.Fields["created_at"] + " " + row.Fields["total"]
+	}
+	is.Equal(map[string]string{
+		"2024-01|FR":  "2024-01 15",
+		"2024-02|FR":  "2024-02 1",
+		"2024-01|DE":  "2024-01 2",
+		"_invalid|FR": "_invalid 3",
+		"_invalid|DE": "_invalid 4",
+	}, groups)
+
+	// custom layouts and labels, from generic JSON
+	is.NoError(os.WriteFile(input, []byte("created_at,amount\n05/01/2024,1\n31/03/2024,2\n01/04/2024,4\n"), 0o600))
+	var options map[string]interface{}
+	is.NoError(json.Unmarshal([]byte(`{
+		"group_by": [{"field":"created_at","bucket":"quarter","format":"Jan 2006"}],
+		"date_layouts": ["02/01/2006"],
+		"rules": [{"field":"amount","operation":"sum","alias":"total"}]
+	}`), &options))
+	options["input_file"] = input
+	rows, err = s.ProcessData(nil, options)
+	is.NoError(err)
+	is.Len(rows, 2)
+	is.Equal("Apr 2024", rows[0].Fields["created_at"])
+	is.Equal("Jan 2024", rows[1].Fields["created_at"])
+
+	_, err = s.ProcessData(nil, map[string]interface{}{"rules": rules, "group_by": []GroupByField{{Field: "created_at", Bucket: "fortnight"}}})
+	is.ErrorContains(err, `invalid group-by field 'created_at': unknown date bucket "fortnight"`)
+
+	_, err = s.ProcessData(nil, map[string]interface{}{"rules": rules, "group_by": []GroupByField{{Field: "created_at", Format: "2006"}}})
+	is.ErrorContains(err, "format of group-by field 'created_at' requires a bucket")
+}
+
+func TestAggregateService_bins(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	rows := testRows(t, []string{"amount"},
+		[]string{"120"}, []string{"5"}, []string{"49.99"}, []string{"50"}, []string{"n/a"}, []string{"-3"}, []string{"1000"},
+	)
+
+	aggregate := func(groupBy interface{}) map[string]int {
+		t.Helper()
+
+		output, err := s.ProcessData(rows, map[string]interface{}{"group_by": groupBy})
+		is.NoError(err)
+		counts := map[string]int{}
+		labels := []string{}
+		for _, row := range output {
+			count, _ := strconv.Atoi(row.Fields["count"])
+			counts[row.Fields["amount"]] = count
+			labels = append(labels, row.Fields["amount"])
+		}
+		counts["order: "+strings.Join(labels, " ")] = 0
+		return counts
+	}
+
+	is.Equal(map[string]int{
+		"[-50, 0)": 1, "[0, 50)": 2, "[50, 100)": 1, "[100, 150)": 1, "[1000, 1050)": 1, "_invalid": 1,
+		"order: [-50, 0) [0, 50) [50, 100) [100, 150) [1000, 1050) _invalid": 0,
+	}, aggregate([]GroupByField{{Field: "amount", Bins: &BinSpec{Width: 50}}}))
+
+	var groupBy interface{}
+	is.NoError(json.Unmarshal([]byte(`[{"field":"amount","bins":{"edges":[0,50,100]}}]`), &groupBy))
+	is.Equal(map[string]int{
+		"(-inf, 0)": 1, "[0, 50)": 2, "[50, 100)": 1, "[100, +inf)": 2, "_invalid": 1,
+		"order: (-inf, 0) [0, 50) [50, 100) [100, +inf) _invalid": 0,
+	}, aggregate(groupBy))
+
+	_, err := s.ProcessData(rows, map[string]interface{}{"group_by": []GroupByField{{Field: "amount", Bins: &BinSpec{Width: -1}}}})
+	is.ErrorContains(err, "invalid group-by field 'amount': bin width must be positive, got -1")
+}
+
+func TestAggregateService_streamMatchesInMemory(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestAggregateService(t)
+	input := filepath.Join(t.TempDir(), "in.csv")
+	is.NoError(os.WriteFile(input, []byte("region,amount,qty,tag\n"+
+		"north,10,1,a\nsouth,5,2,b\nnorth,x,3,b\nnorth,30,1,a\nsouth,,4,\neast,7,1,c\n"), 0o600))
+
+	options := map[string]interface{}{
+		"input_file": input,
+		"group_by":   []string{"region"},
+		"rules": []AggregateRule{
+			{Field: "amount", Operation: Count},
+			{Field: "amount", Operation: Sum},
+			{Field: "amount", Operation: Average},
+			{Field: "amount", Operation: Min},
+			{Field: "amount", Operation: Max},
+			{Field: "tag", Operation: Distinct},
+			{Field: "tag", Operation: Mode, Parameters: map[string]interface{}{"with_count": true}},
+			{Field: "tag", Operation: Collect, Parameters: map[string]interface{}{"order": "sorted"}},
+			{Field: "amount", Operation: WeightedAverage, Parameters: map[string]interface{}{"weight": "qty"}},
+			{Field: "amount", Operation: SumIf, Parameters: map[string]interface{}{
+				"condition": FilterRule{Field: "tag", Operator: "equals", Value: "a"},
+			}},
+		},
+		"having":  FilterRule{Field: "count", Operator: "greater_than", Value: 1},
+		"sort_by": "-amount_sum",
+	}
+
+	streamed, _, err := s.run(nil, options)
+	is.NoError(err)
+
+	rows, err := s.fileService.ReadCSV(input)
+	is.NoError(err)
+	delete(options, "input_file")
+	inMemory, _, err := s.run(rows, options)
+	is.NoError(err)
+
+	is.Equal([]string{input}, streamed.InputFiles)
+	inMemory.InputFiles = streamed.InputFiles
+	is.Equal(inMemory, streamed)
+	is.Equal(6, streamed.TotalRows)
+	is.Equal(2, streamed.TotalGroups)
+	is.Equal("north", streamed.Groups[0].GroupKey)
+	is.Equal(40.0, streamed.Groups[0].Aggregates["amount_sum"])
+	is.Equal(20.0, streamed.Groups[0].Aggregates["amount_average"])
+	is.Equal(2, streamed.Groups[0].Aggregates["tag_mode_count"])
+	is.Equal("a,a,b", streamed.Groups[0].Aggregates["tag_collect"])
+	is.Equal(map[string]int{"amount_sum": 1, "amount_average": 1, "amount_min": 1, "amount_max": 1, "amount_weighted_average": 1}, streamed.Groups[0].Skipped)
+	is.Equal(map[string]int{"amount": 1}, streamed.NonNumeric)
+}
+
+func TestAggregateService_approximateDistinct(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	values := make([][]string, 0, 20000)
+	for i := range 20000 {
+		values = append(values, []string{"all", "user" + strconv.Itoa(i%10000)})
+	}
+
+	s := newTestAggregateService(t)
+	rows, err := s.ProcessData(testRows(t, []string{"group", "user"}, values...), map[string]interface{}{
+		"group_by": []string{"group"},
+		"rules": []AggregateRule{
+			{Field: "user", Operation: Distinct, Alias: "exact"},
+			{Field: "user", Operation: Distinct, Alias: "approximate", Parameters: map[string]interface{}{"approximate": true}},
+		},
+	})
+	is.NoError(err)
+	is.Len(rows, 1)
+	is.Equal("10000", rows[0].Fields["exact"])
+
+	estimate, err := strconv.ParseFloat(rows[0].Fields["approximate"], 64)
+	is.NoError(err)
+	is.InDelta(10000, estimate, 500)
+}
+
+func TestAggregateService_numericPolicy(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"region", "amount"},
+		[]string{"north", "10"}, []string{"north", "N/A"}, []string{"north", "20"}, []string{"north", ""},
+	)
+	rules := []AggregateRule{
+		{Field: "amount", Operation: Sum, Alias: "total"},
+		{Field: "amount", Operation: Average, Alias: "mean"},
+		{Field: "amount", Operation: Min, Alias: "lowest"},
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		s := newTestAggregateService(t)
+		opts, err := s.parseAggregateOptions(map[string]interface{}{"rules": rules, "group_by": []string{"region"}})
+		is.NoError(err)
+		result, err := s.aggregateData(rows, opts)
+		is.NoError(err)
+		is.Equal(30.0, result.Groups[0].Aggregates["total"])
+		is.Equal(15.0, result.Groups[0].Aggregates["mean"])
+		is.Equal(map[string]int{"total": 1, "mean": 1, "lowest": 1}, result.Groups[0].Skipped)
+		is.Equal(map[string]int{"amount": 1}, result.NonNumeric)
+
+		opts, err = s.parseAggregateOptions(map[string]interface{}{"rules": rules})
+		is.NoError(err)
+		result, err = s.aggregateData(rows, opts)
+		is.NoError(err)
+		is.Equal(15.0, *result.Summary.FieldStats["amount"].Average)
+		is.Equal(int64(1), result.Summary.FieldStats["amount"].NonNumeric)
+		is.Equal(map[string]int{"amount": 1}, result.NonNumeric)
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		s := newTestAggregateService(t)
+		opts, err := s.parseAggregateOptions(map[string]interface{}{"rules": rules, "group_by": []string{"region"}, "numeric_policy": "zero"})
+		is.NoError(err)
+		result, err := s.aggregateData(rows, opts)
+		is.NoError(err)
+		is.Equal(30.0, result.Groups[0].Aggregates["total"])
+		is.Equal(10.0, result.Groups[0].Aggregates["mean"])
+		is.Equal(0.0, result.Groups[0].Aggregates["lowest"])
+		is.Nil(result.Groups[0].Skipped)
+		is.Nil(result.NonNumeric)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		s := newTestAggregateService(t)
+		for _, groupBy := range [][]string{{"region"}, nil} {
+			_, err := s.ProcessData(rows, map[string]interface{}{"rules": rules, "group_by": groupBy, "numeric_policy": "error"})
+			var valueErr *NumericValueError
+			is.ErrorAs(err, &valueErr)
+			is.EqualError(valueErr, `value "N/A" of field 'amount' at line 3 is not a number`)
+		}
+
+		// non-numeric operations accept any value
+		_, err := s.ProcessData(rows, map[string]interface{}{
+			"rules":          []AggregateRule{{Field: "amount", Operation: Distinct}},
+			"numeric_policy": "error",
+		})
+		is.NoError(err)
+
+		_, err = s.parseAggregateOptions(map[string]interface{}{"rules": rules, "numeric_policy": "ignore"})
+		is.EqualError(err, `unknown numeric policy "ignore": expected skip, zero or error`)
+	})
+}
+
+func BenchmarkAggregateService_grouped(b *testing.B) {
+	input := filepath.Join(b.TempDir(), "in.csv")
+	var content strings.Builder
+	content.WriteString("region,amount\n")
+	for i := range 1_000_000 {
+		content.WriteString("region" + strconv.Itoa(i%100) + "," + strconv.Itoa(i%997) + "\n")
+	}
+	if err := os.WriteFile(input, []byte(content.String()), 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	s := &AggregateService{fileService: &FileService{logger: zerolog.Nop()}, logger: zerolog.Nop()}
+	opts, err := s.parseAggregateOptions(map[string]interface{}{
+		"input_file": input,
+		"group_by":   []string{"region"},
+		"rules": []AggregateRule{
+			{Field: "amount", Operation: Sum},
+			{Field: "amount", Operation: Average},
+			{Field: "amount", Operation: Max},
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			rows, err := s.fileService.ReadCSV(input)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := s.aggregateData(rows, opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if _, err := s.aggregateStream([]string{input}, opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestAggregateService_rank(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"seller", "revenue"},
+		[]string{"ann", "10"}, []string{"bob", "30"}, []string{"cid", "25"}, []string{"ann", "20"},
+		[]string{"dan", "5"}, []string{"eve", "25"}, []string{"fay", "40"},
+	)
+
+	s := newTestAggregateService(t)
+	aggregate := func(options map[string]interface{}) ([][]interface{}, int) {
+		t.Helper()
+
+		options["rules"] = []AggregateRule{{Field: "revenue", Operation: Sum, Alias: "total"}}
+		options["group_by"] = []string{"seller"}
+		opts, err := s.parseAggregateOptions(options)
+		is.NoError(err)
+		result, err := s.aggregateData(rows, opts)
+		is.NoError(err)
+
+		groups := [][]interface{}{}
+		for _, group := range result.Groups {
+			groups = append(groups, []interface{}{group.GroupKey, group.Aggregates[RankAggregate]})
+		}
+		return groups, result.TotalGroups
+	}
+
+	// groups are ordered by rank, ties share it
+	groups, _ := aggregate(map[string]interface{}{"rank_by": "total"})
+	is.Equal([][]interface{}{{"fay", 1}, {"ann", 2}, {"bob", 2}, {"cid", 4}, {"eve", 4}, {"dan", 6}}, groups)
+
+	groups, _ = aggregate(map[string]interface{}{"rank_by": "total", "rank_method": "dense"})
+	is.Equal([][]interface{}{{"fay", 1}, {"ann", 2}, {"bob", 2}, {"cid", 3}, {"eve", 3}, {"dan", 4}}, groups)
+
+	groups, _ = aggregate(map[string]interface{}{"rank_by": "total:asc"})
+	is.Equal([][]interface{}{{"dan", 1}, {"cid", 2}, {"eve", 2}, {"ann", 4}, {"bob", 4}, {"fay", 6}}, groups)
+
+	// ranks come after having and before the limit, whatever the sort keys
+	groups, total := aggregate(map[string]interface{}{
+		"rank_by": "total",
+		"having":  FilterGroup{Rules: []FilterRule{{Field: "total", Operator: "less_than", Value: 40}}},
+		"limit":   3,
+	})
+	is.Equal([][]interface{}{{"ann", 1}, {"bob", 1}, {"cid", 3}}, groups)
+	is.Equal(5, total)
+
+	groups, _ = aggregate(map[string]interface{}{"rank_by": "total", "sort_by": "seller", "limit": 2})
+	is.Equal([][]interface{}{{"ann", 2}, {"bob", 2}}, groups)
+
+	_, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "revenue", Operation: Sum}}, "group_by": []string{"seller"}, "rank_by": "revenue_avg",
+	})
+	is.ErrorContains(err, "unknown rank key 'revenue_avg'")
+
+	_, err = s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{{Field: "revenue", Operation: Sum}}, "group_by": []string{"seller"}, "rank_by": "count", "rank_method": "row",
+	})
+	is.ErrorContains(err, `unknown rank method "row"`)
+}
+
+func TestAggregateService_nullPolicy(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	// "full" is never empty, "part" partially and "none" entirely
+	rows := testRows(t, []string{"group", "full", "part", "none"},
+		[]string{"a", "1", "2", ""},
+		[]string{"a", "3", "", ""},
+		[]string{"b", "5", "", ""},
+	)
+	include := map[string]interface{}{"null_policy": "include"}
+	exclude := map[string]interface{}{"null_policy": "exclude"}
+
+	s := newTestAggregateService(t)
+	opts, err := s.parseAggregateOptions(map[string]interface{}{
+		"group_by": []string{"group"},
+		"rules": []AggregateRule{
+			{Field: "part", Operation: Count, Alias: "part_rows"},
+			{Field: "part", Operation: Count, Alias: "part_present", Parameters: exclude},
+			{Field: "part", Operation: CountNonNull},
+			{Field: "none", Operation: CountNonNull},
+			{Field: "full", Operation: CountNonNull},
+			{Field: "part", Operation: Average},
+			{Field: "part", Operation: Average, Alias: "part_average_all", Parameters: include},
+			{Field: "part", Operation: Distinct},
+			{Field: "part", Operation: Distinct, Alias: "part_distinct_all", Parameters: include},
+			{Field: "none", Operation: Distinct, Parameters: include},
+			{Field: "part", Operation: Min, Parameters: include},
+			{Field: "none", Operation: Mode},
+			{Field: "none", Operation: Mode, Alias: "none_mode_all", Parameters: include},
+		},
+	})
+	is.NoError(err)
+
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Len(result.Groups, 2)
+
+	a, b := result.Groups[0], result.Groups[1]
+	is.Equal(map[string]interface{}{
+		"part_rows": 2, "part_present": 1, "part_count_nonnull": 1, "none_count_nonnull": 0, "full_count_nonnull": 2,
+		"part_average": 2.0, "part_average_all": 1.0,
+		"part_distinct": int64(1), "part_distinct_all": int64(2), "none_distinct": int64(1),
+		"part_min": 0.0, "none_mode": nil, "none_mode_all": "",
+	}, a.Aggregates)
+	is.Equal(map[string]interface{}{
+		"part_rows": 1, "part_present": 0, "part_count_nonnull": 0, "none_count_nonnull": 0, "full_count_nonnull": 1,
+		"part_average": nil, "part_average_all": 0.0,
+		"part_distinct": int64(0), "part_distinct_all": int64(1), "none_distinct": int64(1),
+		"part_min": "", "none_mode": nil, "none_mode_all": "",
+	}, b.Aggregates)
+
+	// only the fields of rules setting a null policy are counted
+	is.Equal(map[string]int{"part": 1, "none": 2}, a.NullCounts)
+	is.Equal(map[string]int{"part": 1, "none": 1}, b.NullCounts)
+	is.Equal("2", s.groupRow(a).Fields["none_null_count"])
+}
+
+func TestAggregateService_nullPolicySummary(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"full", "part", "none"},
+		[]string{"1", "2", ""},
+		[]string{"3", "", ""},
+		[]string{"5", "4", ""},
+	)
+
+	s := newTestAggregateService(t)
+	opts, err := s.parseAggregateOptions(map[string]interface{}{
+		"rules": []AggregateRule{
+			{Field: "part", Operation: Count, Parameters: map[string]interface{}{"null_policy": "exclude"}},
+			{Field: "full", Operation: CountNonNull},
+			{Field: "none", Operation: Average, Parameters: map[string]interface{}{"null_policy": "include"}},
+		},
+	})
+	is.NoError(err)
+
+	result, err := s.aggregateData(rows, opts)
+	is.NoError(err)
+	is.Equal(int64(2), result.Summary.FieldStats["part"].Count)
+	is.Equal(int64(1), result.Summary.FieldStats["part"].NullCount)
+	is.Equal(int64(3), result.Summary.FieldStats["full"].Count)
+
+	none := result.Summary.FieldStats["none"]
+	is.Equal(int64(3), none.NullCount)
+	is.Equal(int64(3), none.NumericCount)
+	is.Equal(0.0, *none.Average)
+
+	testCases := []struct {
+		rule     AggregateRule
+		expected string
+	}{
+		{AggregateRule{Field: "part", Operation: Sum, Parameters: map[string]interface{}{"null_policy": "sometimes"}}, `unknown null policy "sometimes"`},
+		{AggregateRule{Field: "part", Operation: CountNonNull, Parameters: map[string]interface{}{"null_policy": "include"}}, "always excludes empty values"},
+		{AggregateRule{Operation: CountNonNull}, "count_nonnull requires a field"},
+	}
+	for _, tc := range testCases {
+		_, err := s.parseAggregateOptions(map[string]interface{}{"rules": []AggregateRule{tc.rule}})
+		is.ErrorContains(err, tc.expected)
+	}
+}
+
+func TestAggregateService_zeroStatistics(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	// credits and debits summing to zero keep all their statistics
+	rows := testRows(t, []string{"amount", "label"},
+		[]string{"10", ""}, []string{"-10", ""}, []string{"0", ""},
+	)
+	s := newTestAggregateService(t)
+	out, err := s.ProcessData(rows, map[string]interface{}{
+		"rules": []AggregateRule{
+			{Field: "amount", Operation: Count},
+			{Field: "amount", Operation: Sum},
+			{Field: "amount", Operation: Average},
+			{Field: "amount", Operation: Min},
+			{Field: "amount", Operation: Max},
+			{Field: "amount", Operation: Distinct},
+			{Field: "label", Operation: Distinct},
+			{Field: "label", Operation: Mode},
+		},
+	})
+	is.NoError(err)
+	is.Len(out, 1)
+	is.Equal(map[string]string{
+		"total_records":           "3",
+		"amount_count":            "3",
+		"amount_sum":              "0.00",
+		"amount_average":          "0.00",
+		"amount_min":              "-10.00",
+		"amount_max":              "10.00",
+		"amount_unique":           "3",
+		"label_unique":            "0",
+		"label_most_common":       "",
+		"label_most_common_count": "0",
+	}, out[0].Fields)
+
+	// a date min is not summed, and fields without rules are left out
+	out, err = s.ProcessData(testRows(t, []string{"date", "amount"}, []string{"2024-03-01", "1"}, []string{"2024-01-15", "2"}),
+		map[string]interface{}{"rules": []AggregateRule{{Field: "date", Operation: Min}}})
+	is.NoError(err)
+	is.Equal(map[string]string{"total_records": "2", "date_min": "2024-01-15"}, out[0].Fields)
+}
+
+func TestAggregateService_AggregateFile_emptyInput(t *testing.T) {
+	t.Parallel()
+
+	// neither groups nor summary
+	assert.Empty(t, newTestAggregateService(t).convertResultToDataRows(&AggregateResult{}, nil))
+
+	testCases := []struct {
+		name     string
+		groupBy  []GroupByField
+		expected string
+	}{
+		{"grouped", []GroupByField{{Field: "country"}}, "amount_sum,count,country,group_key\n"},
+		{"summary", nil, "total_records\n0\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.csv")
+			is.NoError(os.WriteFile(input, []byte("country,amount\n"), 0o600))
+
+			output := filepath.Join(dir, "out.csv")
+			result, err := newTestAggregateService(t).AggregateFile(input, output,
+				[]AggregateRule{{Field: "amount", Operation: Sum}}, tc.groupBy, nil)
+			is.NoError(err)
+			is.True(result.Success)
+
+			content, err := os.ReadFile(output)
+			is.NoError(err)
+			is.Equal(tc.expected, string(content))
+		})
+	}
+}
+
+func TestAggregateService_AggregateFile_multipleInputs(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	day1 := filepath.Join(dir, "day1.csv")
+	day2 := filepath.Join(dir, "day2.csv")
+	is.NoError(os.WriteFile(day1, []byte("shop,amount\na,1\nb,2\n"), 0o600))
+	is.NoError(os.WriteFile(day2, []byte("amount,shop\n4,a\n"), 0o600))
+	rules := []AggregateRule{{Field: "amount", Operation: Sum, Alias: "total"}}
+	s := newTestAggregateService(t)
+
+	// grouped by file, from a glob
+	output := filepath.Join(dir, "out.json")
+	result, err := s.AggregateFile(filepath.Join(dir, "day*.csv"), output, rules, []GroupByField{{Field: SourceFileField}}, nil)
+	is.NoError(err)
+	is.Equal([]string{day1, day2}, result.InputFiles)
+	is.Equal(2, result.Processed)
+
+	// ungrouped, from a list
+	aggregate, _, err := s.run(nil, map[string]interface{}{"input_file": day1, "input_files": []string{day2}, "rules": rules})
+	is.NoError(err)
+	is.Equal(3, aggregate.Summary.TotalRecords)
+	is.Equal(7.0, aggregate.Summary.FieldStats["amount"].Sum)
+
+	aggregate, _, err = s.run(nil, map[string]interface{}{"input_files": []interface{}{day1, day2}, "rules": rules, "group_by": []string{"_source_file", "shop"}})
+	is.NoError(err)
+	keys := []string{}
+	for _, group := range aggregate.Groups {
+		keys = append(keys, fmt.Sprintf("%s|%s=%v", filepath.Base(group.GroupValues[SourceFileField]), group.GroupValues["shop"], group.Aggregates["total"]))
+	}
+	is.Equal([]string{"day1.csv|a=1", "day1.csv|b=2", "day2.csv|a=4"}, keys)
+
+	// files must share their columns
+	other := filepath.Join(dir, "other.csv")
+	is.NoError(os.WriteFile(other, []byte("shop,price\n"), 0o600))
+	_, err = s.AggregateFile(day1, "", rules, nil, map[string]interface{}{"input_files": []string{other}})
+	is.ErrorContains(err, "columns of "+other+" do not match those of "+day1+": missing amount; unexpected price")
+}
+
+func TestAggregateService_stages(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"customer", "amount"},
+		[]string{"ann", "10"}, []string{"ann", "20"}, []string{"bob", "5"}, []string{"cid", "15"},
+	)
+	s := newTestAggregateService(t)
+	perCustomer := map[string]interface{}{
+		"rules":    []AggregateRule{{Field: "amount", Operation: Sum}},
+		"group_by": []string{"customer"},
+	}
+
+	// average of per-customer totals
+	result, out, err := s.run(rows, mergeOptions(perCustomer, map[string]interface{}{
+		"stages": []map[string]interface{}{
+			{"rules": []AggregateRule{{Field: "amount_sum", Operation: Average}}},
+		},
+	}))
+	is.NoError(err)
+	is.InDelta(50.0/3, *result.Summary.FieldStats["amount_sum"].Average, 1e-9)
+	is.Equal("16.67", out[0].Fields["amount_sum_average"])
+
+	// customers by number of orders, then the busiest order count, from generic JSON
+	result, _, err = s.run(rows, mergeOptions(perCustomer, map[string]interface{}{
+		"stages": []interface{}{
+			map[string]interface{}{
+				"rules":    []interface{}{map[string]interface{}{"field": "customer", "operation": "count", "alias": "customers"}},
+				"group_by": []interface{}{"count"},
+			},
+			map[string]interface{}{
+				"rules": []interface{}{map[string]interface{}{"field": "customers", "operation": "max"}},
+			},
+		},
+	}))
+	is.NoError(err)
+	is.Equal(2.0, *result.Summary.FieldStats["customers"].Max)
+
+	testCases := []struct {
+		name     string
+		stages   []interface{}
+		expected string
+	}{
+		{
+			"unknown alias",
+			[]interface{}{map[string]interface{}{"rules": []AggregateRule{{Field: "amount_total", Operation: Average}}}},
+			"invalid stage 2: unknown field 'amount_total', available fields: amount_sum, count, customer, group_key",
+		},
+		{
+			"input file",
+			[]interface{}{map[string]interface{}{"input_file": "other.csv", "rules": []AggregateRule{{Field: "count", Operation: Sum}}}},
+			"stage 2 cannot set input_file",
+		},
+		{"no rules", []interface{}{map[string]interface{}{}}, "stage 2 requires rules"},
+		{"not an object", []interface{}{"count"}, "stage 2 is not an object"},
+	}
+	for _, tc := range testCases {
+		_, _, err := s.run(rows, mergeOptions(perCustomer, map[string]interface{}{"stages": tc.stages}))
+		is.ErrorContains(err, tc.expected, tc.name)
+	}
+}
@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fix is a value auto_fix corrected before validating its row.
+type Fix struct {
+	RowNumber  int    `json:"row_number"`
+	LineNumber int    `json:"line_number,omitempty"`
+	SourceFile string `json:"source_file,omitempty"`
+	FieldName  string `json:"field_name"`
+	RuleType   string `json:"rule_type"` // rule the value failed before the fix
+	Before     string `json:"before"`
+	After      string `json:"after"`
+}
+
+// FixSummary counts the fixes of a rule on a field.
+type FixSummary struct {
+	Rule  string `json:"rule"`
+	Field string `json:"field"`
+	Fixes int    `json:"fixes"`
+}
+
+// autoFixes are the canned fixes of auto_fix, by rule type: required and length rules trim
+// whitespace, enum rules also match values in any case, and numeric rules drop whitespace and
+// thousands separators and read commas as decimal separators, like "1 234,50" for 1234.50.
+// They only hold stateless rules, which can be checked twice on a row.
+var autoFixes = map[string]func(ValidationRule, string) string{
+	"required":   trimFix,
+	"min_length": trimFix,
+	"max_length": trimFix,
+	"enum":       enumFix,
+	"numeric":    numberFix,
+	"integer":    numberFix,
+	"decimal":    numberFix,
+	"range":      numberFix,
+}
+
+// parseAutoFix parses the auto_fix option: true for every fixable rule type, or a list of them.
+func parseAutoFix(raw interface{}) ([]string, error) {
+	if enabled, ok := raw.(bool); ok {
+		if !enabled {
+			return nil, nil
+		}
+		ruleTypes := make([]string, 0, len(autoFixes))
+		for ruleType := range autoFixes {
+			ruleTypes = append(ruleTypes, ruleType)
+		}
+		sort.Strings(ruleTypes)
+		return ruleTypes, nil
+	}
+
+	ruleTypes := slices.Clone(parseColumnList(raw))
+	if ruleTypes == nil {
+		return nil, fmt.Errorf("invalid auto_fix %v: expected true or a list of rule types", raw)
+	}
+	for i, ruleType := range ruleTypes {
+		ruleTypes[i] = strings.TrimSpace(ruleType)
+		if autoFixes[ruleTypes[i]] == nil {
+			return nil, fmt.Errorf("rule type %q of auto_fix has no fix, expected one of enum, integer, decimal, max_length, min_length, numeric, range or required", ruleType)
+		}
+	}
+	return ruleTypes, nil
+}
+
+// trimFix removes leading and trailing whitespace.
+func trimFix(_ ValidationRule, value string) string {
+	return strings.TrimSpace(value)
+}
+
+// enumFix returns the value of an enum rule equal to a value in any case, once trimmed,
+// leaving the value as is when there is none or several.
+func enumFix(rule ValidationRule, value string) string {
+	value = strings.TrimSpace(value)
+	values, _ := rule.constraint.(enumConstraint)
+	match := ""
+	for _, allowed := range values {
+		if strings.EqualFold(allowed, value) {
+			if match != "" && match != allowed {
+				return value
+			}
+			match = allowed
+		}
+	}
+	if match == "" {
+		return value
+	}
+	return match
+}
+
+// numberFix rewrites a number with whitespace and guessed separators to Go float syntax.
+func numberFix(_ ValidationRule, value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value)
+	return normalizeSeparators(value)
+}
+
+// fixRow applies the fixes of the auto_fix rule types to the values of a row failing their rules,
+// keeping those the rule accepts. The row is copied before its first fix, leaving the input as is.
+func (s *ValidateService) fixRow(row DataRow, rules []ValidationRule, rowNumber int, ruleTypes []string) (DataRow, []Fix) {
+	var fixes []Fix
+	copied := false
+	for _, rule := range rules {
+		fix := autoFixes[rule.Type]
+		if fix == nil || !slices.Contains(ruleTypes, rule.Type) {
+			continue
+		}
+		before, ok := row.Fields[rule.Field]
+		if !ok || s.validateField(row, rule, rowNumber) == nil {
+			continue
+		}
+		after := fix(rule, before)
+		if after == before {
+			continue
+		}
+
+		if !copied {
+			row.Fields, copied = maps.Clone(row.Fields), true
+		}
+		row.Fields[rule.Field] = after
+		if s.validateField(row, rule, rowNumber) != nil {
+			row.Fields[rule.Field] = before
+			continue
+		}
+		fixes = append(fixes, Fix{
+			RowNumber:  rowNumber,
+			LineNumber: row.LineNumber,
+			SourceFile: row.SourceFile,
+			FieldName:  rule.Field,
+			RuleType:   rule.Type,
+			Before:     before,
+			After:      after,
+		})
+	}
+	return row, fixes
+}
+
+// applyFixes returns the rows with the fixes of a result applied, for writing the fixed dataset.
+func applyFixes(data []DataRow, fixes []Fix) []DataRow {
+	fixed := slices.Clone(data)
+	copied := make(map[int]bool)
+	for _, fix := range fixes {
+		row := &fixed[fix.RowNumber-1]
+		if !copied[fix.RowNumber] {
+			row.Fields, copied[fix.RowNumber] = maps.Clone(row.Fields), true
+		}
+		row.Fields[fix.FieldName] = fix.After
+	}
+	return fixed
+}
+
+// summarizeFixes counts the fixes per rule and field, in order of first fix.
+func summarizeFixes(fixes []Fix) []FixSummary {
+	var summary []FixSummary
+	index := make(map[[2]string]int)
+	for _, fix := range fixes {
+		key := [2]string{fix.RuleType, fix.FieldName}
+		i, ok := index[key]
+		if !ok {
+			i = len(summary)
+			index[key] = i
+			summary = append(summary, FixSummary{Rule: fix.RuleType, Field: fix.FieldName})
+		}
+		summary[i].Fixes++
+	}
+	return summary
+}
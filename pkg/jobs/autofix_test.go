@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateService_autoFix(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"code", "status", "amount"},
+		[]string{"AB12", "open", "12.5"},
+		[]string{" AB12 ", "Open", "1 234,50"},
+		[]string{"ABCDEFG", " CLOSED", "12,5"},
+		[]string{"AB", "pending", "n/a"},
+	)
+	rules := []ValidationRule{
+		{Field: "code", Type: "max_length", Constraints: float64(4), Severity: "error"},
+		{Field: "status", Type: "enum", Constraints: []interface{}{"open", "closed"}},
+		{Field: "amount", Type: "decimal"},
+	}
+
+	// without auto_fix, rows are judged as they are
+	opts, err := s.parseValidateOptions(map[string]interface{}{"rules": rules})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.Equal(3, result.InvalidRows)
+	is.Empty(result.Fixes)
+	is.Equal(`Value must be one of "open", "closed"`, result.Errors[1].Message)
+
+	opts, err = s.parseValidateOptions(map[string]interface{}{"rules": rules, "auto_fix": true})
+	is.NoError(err)
+	result, valid, invalid := s.validateData(rows, opts)
+	is.Equal(2, result.ValidRows)
+	is.Equal([]Fix{
+		{RowNumber: 2, LineNumber: 3, FieldName: "code", RuleType: "max_length", Before: " AB12 ", After: "AB12"},
+		{RowNumber: 2, LineNumber: 3, FieldName: "status", RuleType: "enum", Before: "Open", After: "open"},
+		{RowNumber: 2, LineNumber: 3, FieldName: "amount", RuleType: "decimal", Before: "1 234,50", After: "1234.50"},
+		{RowNumber: 3, LineNumber: 4, FieldName: "status", RuleType: "enum", Before: " CLOSED", After: "closed"},
+		{RowNumber: 3, LineNumber: 4, FieldName: "amount", RuleType: "decimal", Before: "12,5", After: "12.5"},
+	}, result.Fixes)
+	is.Equal([]FixSummary{
+		{Rule: "max_length", Field: "code", Fixes: 1},
+		{Rule: "enum", Field: "status", Fixes: 2},
+		{Rule: "decimal", Field: "amount", Fixes: 2},
+	}, result.FixSummary)
+
+	// fixed rows are returned fixed, unfixable values stay invalid, and the input is left as is
+	is.Equal(map[string]string{"code": "AB12", "status": "open", "amount": "1234.50"}, valid[1].Fields)
+	is.Equal(map[string]string{"code": "ABCDEFG", "status": "closed", "amount": "12.5"}, invalid[0].Fields)
+	is.Equal([]int{3, 4, 4}, validationRows(result.Errors))
+	is.Equal(" AB12 ", rows[1].Fields["code"])
+
+	// only the listed rule types are fixed
+	opts, err = s.parseValidateOptions(map[string]interface{}{"rules": rules, "auto_fix": []interface{}{"enum"}})
+	is.NoError(err)
+	result, _, _ = s.validateData(rows, opts)
+	is.Equal([]FixSummary{{Rule: "enum", Field: "status", Fixes: 2}}, result.FixSummary)
+
+	for _, autoFix := range []interface{}{"email", float64(1)} {
+		_, err = s.parseValidateOptions(map[string]interface{}{"rules": rules, "auto_fix": autoFix})
+		is.Error(err)
+	}
+	_, err = s.parseValidateOptions(map[string]interface{}{"rules": []ValidationRule{{Field: "status", Type: "enum"}}})
+	is.Error(err)
+}
+
+func TestValidateService_writeFixed(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "orders.csv")
+	is.NoError(os.WriteFile(input, []byte("id,status\n1,Open\n2,lost\n3,closed\n"), 0o600))
+	fixed := filepath.Join(dir, "fixed.csv")
+
+	s := newTestValidateService(t)
+	result, _, _, err := s.ValidateRows(nil, map[string]interface{}{
+		"input_file": input,
+		"fixed_file": fixed,
+		"auto_fix":   true,
+		"rules":      []ValidationRule{{Field: "status", Type: "enum", Constraints: "open,closed"}},
+	})
+	is.NoError(err)
+	is.Len(result.Fixes, 1)
+
+	content, err := os.ReadFile(fixed)
+	is.NoError(err)
+	is.Equal("id,status\n1,open\n2,lost\n3,closed\n", string(content))
+}
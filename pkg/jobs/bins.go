@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// BinSpec splits numbers into bins, either of a fixed width or between explicit edges.
+// Bins include their start and exclude their end, like [50, 100).
+type BinSpec struct {
+	Width  float64   `json:"width,omitempty"`
+	Origin float64   `json:"origin,omitempty"` // start of one of the bins of a fixed width, 0 by default
+	Edges  []float64 `json:"edges,omitempty"`  // increasing bin edges, values outside go to underflow and overflow bins
+}
+
+// Validate checks that the bins are either of a positive width or between increasing edges.
+func (b *BinSpec) Validate() error {
+	switch {
+	case b.Width != 0 && len(b.Edges) > 0:
+		return errors.New("bins take either a width or edges, not both")
+	case len(b.Edges) > 0:
+		if len(b.Edges) < 2 {
+			return errors.New("bin edges need at least two values")
+		}
+		if !sort.SliceIsSorted(b.Edges, func(i, j int) bool { return b.Edges[i] <= b.Edges[j] }) {
+			return fmt.Errorf("bin edges must be strictly increasing, got %v", b.Edges)
+		}
+		return nil
+	case b.Width <= 0:
+		return fmt.Errorf("bin width must be positive, got %v", b.Width)
+	default:
+		return nil
+	}
+}
+
+// bin returns the label of the bin containing a value, like "[50, 100)", and the start of the bin,
+// which orders bins numerically. The underflow bin starts at negative infinity.
+func (b *BinSpec) bin(value float64) (string, float64) {
+	if len(b.Edges) == 0 {
+		start := b.Origin + math.Floor((value-b.Origin)/b.Width)*b.Width
+		return binLabel(start, start+b.Width), start
+	}
+
+	i := sort.Search(len(b.Edges), func(i int) bool { return b.Edges[i] > value })
+	switch i {
+	case 0:
+		return binLabel(math.Inf(-1), b.Edges[0]), math.Inf(-1)
+	case len(b.Edges):
+		return binLabel(b.Edges[i-1], math.Inf(1)), b.Edges[i-1]
+	default:
+		return binLabel(b.Edges[i-1], b.Edges[i]), b.Edges[i-1]
+	}
+}
+
+// binLabel formats the range of a bin.
+func binLabel(start, end float64) string {
+	bound := func(value float64) string {
+		switch {
+		case math.IsInf(value, -1):
+			return "-inf"
+		case math.IsInf(value, 1):
+			return "+inf"
+		default:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+
+	if math.IsInf(start, -1) {
+		return fmt.Sprintf("(%s, %s)", bound(start), bound(end))
+	}
+	return fmt.Sprintf("[%s, %s)", bound(start), bound(end))
+}
+
+// parseBinSpec parses bins decoded from generic JSON, like {"width": 50} or {"edges": [0, 50, 100]}.
+func parseBinSpec(raw map[string]interface{}) *BinSpec {
+	spec := &BinSpec{}
+	spec.Width, _ = toFloat(raw["width"])
+	spec.Origin, _ = toFloat(raw["origin"])
+	if edges, ok := raw["edges"].([]interface{}); ok {
+		for _, edge := range edges {
+			if edge, ok := toFloat(edge); ok {
+				spec.Edges = append(spec.Edges, edge)
+			}
+		}
+	}
+	return spec
+}
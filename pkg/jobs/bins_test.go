@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinSpec_bin(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		spec          BinSpec
+		value         float64
+		expectedLabel string
+		expectedStart float64
+	}{
+		{name: "width", spec: BinSpec{Width: 50}, value: 75, expectedLabel: "[50, 100)", expectedStart: 50},
+		{name: "width on edge", spec: BinSpec{Width: 50}, value: 100, expectedLabel: "[100, 150)", expectedStart: 100},
+		{name: "width negative", spec: BinSpec{Width: 50}, value: -0.5, expectedLabel: "[-50, 0)", expectedStart: -50},
+		{name: "width with origin", spec: BinSpec{Width: 10, Origin: 5}, value: 12, expectedLabel: "[5, 15)", expectedStart: 5},
+		{name: "edges", spec: BinSpec{Edges: []float64{0, 10, 100}}, value: 10, expectedLabel: "[10, 100)", expectedStart: 10},
+		{name: "underflow", spec: BinSpec{Edges: []float64{0, 10, 100}}, value: -1, expectedLabel: "(-inf, 0)", expectedStart: math.Inf(-1)},
+		{name: "overflow", spec: BinSpec{Edges: []float64{0, 10, 100}}, value: 100, expectedLabel: "[100, +inf)", expectedStart: 100},
+		{name: "fractional edges", spec: BinSpec{Edges: []float64{0, 0.5, 1}}, value: 0.7, expectedLabel: "[0.5, 1)", expectedStart: 0.5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			is.NoError(tc.spec.Validate())
+			label, start := tc.spec.bin(tc.value)
+			is.Equal(tc.expectedLabel, label)
+			is.Equal(tc.expectedStart, start)
+		})
+	}
+}
+
+func TestBinSpec_Validate(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	is.EqualError((&BinSpec{}).Validate(), "bin width must be positive, got 0")
+	is.EqualError((&BinSpec{Width: 10, Edges: []float64{0, 1}}).Validate(), "bins take either a width or edges, not both")
+	is.EqualError((&BinSpec{Edges: []float64{0}}).Validate(), "bin edges need at least two values")
+	is.EqualError((&BinSpec{Edges: []float64{0, 10, 10}}).Validate(), "bin edges must be strictly increasing, got [0 10 10]")
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Policies of "calculate" rules for divisions and modulos by zero.
+const (
+	DivideByZeroEmpty = "empty" // empty the result, the default
+	DivideByZeroZero  = "zero"  // write 0
+	DivideByZeroKeep  = "keep"  // keep the value
+	DivideByZeroError = "error" // fail the transformation
+)
+
+// calculations are the operations of "calculate" rules, by name.
+var calculations = map[string]func(a, b float64) float64{
+	"add":      func(a, b float64) float64 { return a + b },
+	"subtract": func(a, b float64) float64 { return a - b },
+	"multiply": func(a, b float64) float64 { return a * b },
+	"divide":   func(a, b float64) float64 { return a / b },
+	"modulo":   math.Mod,
+	"power":    math.Pow,
+}
+
+// calculation computes the field of "calculate" rules from a second value. Its parameters are
+// "operation", one of calculations, and either a constant "operand" or an "operand_field" read
+// from the row, like {"operation": "multiply", "operand_field": "quantity"} on a price field.
+// "on_divide_by_zero" is one of the DivideByZero policies for zero divisors. Results have
+// 2 decimals unless a precision is given.
+type calculation struct {
+	name         string
+	apply        func(a, b float64) float64
+	operand      float64
+	operandField string
+	onZero       string
+	output       numberOutput
+}
+
+// parseCalculation parses the parameters of a "calculate" rule.
+func parseCalculation(rule TransformRule) (*calculation, error) {
+	name, _ := rule.Parameters["operation"].(string)
+	calc := &calculation{name: name, apply: calculations[name], onZero: DivideByZeroEmpty}
+	if calc.apply == nil {
+		return nil, fmt.Errorf("unknown operation %q of calculate rule on field '%s': expected add, subtract, multiply, divide, modulo or power", name, rule.Field)
+	}
+
+	operand, hasOperand := rule.Parameters["operand"]
+	calc.operandField, _ = rule.Parameters["operand_field"].(string)
+	switch {
+	case hasOperand && calc.operandField != "":
+		return nil, fmt.Errorf("calculate rule on field '%s' has both an operand and an operand_field", rule.Field)
+	case hasOperand:
+		var ok bool
+		if calc.operand, ok = toFloat(operand); !ok {
+			return nil, fmt.Errorf("invalid operand %v of calculate rule on field '%s': expected a number", operand, rule.Field)
+		}
+	case calc.operandField == "":
+		return nil, fmt.Errorf("calculate rule on field '%s' needs an operand or an operand_field", rule.Field)
+	}
+
+	switch onZero, _ := rule.Parameters["on_divide_by_zero"].(string); onZero {
+	case "":
+	case DivideByZeroEmpty, DivideByZeroZero, DivideByZeroKeep, DivideByZeroError:
+		calc.onZero = onZero
+	default:
+		return nil, fmt.Errorf("invalid on_divide_by_zero %q of calculate rule on field '%s': expected empty, zero, keep or error", onZero, rule.Field)
+	}
+
+	var err error
+	if calc.output, err = parseNumberOutput(rule, 2); err != nil {
+		return nil, err
+	}
+	return calc, nil
+}
+
+// applyCalculate computes a value with the operand of a rule. Empty values are kept.
+func (s *TransformService) applyCalculate(row DataRow, rule TransformRule) (string, error) {
+	value := row.Fields[rule.Field]
+	if value == "" {
+		return "", nil
+	}
+	numValue, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return "", untransformable("calculate rule on field '%s': value %q is not a number", rule.Field, value)
+	}
+
+	calc, _ := rule.parsed.(*calculation)
+	operand := calc.operand
+	if calc.operandField != "" {
+		raw := row.Fields[calc.operandField]
+		if operand, err = strconv.ParseFloat(strings.TrimSpace(raw), 64); err != nil {
+			return "", untransformable("calculate rule on field '%s': operand %q of field '%s' is not a number", rule.Field, raw, calc.operandField)
+		}
+	}
+
+	if operand == 0 && (calc.name == "divide" || calc.name == "modulo") {
+		return calc.byZero(value, rule.Field)
+	}
+
+	result := calc.apply(numValue, operand)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return "", untransformable("calculate rule on field '%s': %s of %s by %v has no finite result", rule.Field, calc.name, value, operand)
+	}
+	return calc.output.formatFloat(result), nil
+}
+
+// byZero applies the policy of a division or a modulo of a value by zero.
+func (c *calculation) byZero(value, field string) (string, error) {
+	switch c.onZero {
+	case DivideByZeroZero:
+		return "0", nil
+	case DivideByZeroKeep:
+		return value, nil
+	case DivideByZeroError:
+		return "", fmt.Errorf("calculate rule on field '%s': %s of %s by zero", field, c.name, value)
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_calculate(t *testing.T) {
+	t.Parallel()
+
+	input := testRows(t, []string{"price", "quantity"},
+		[]string{"2.50", "4"},
+		[]string{"10", "0"},
+		[]string{"7", "n/a"},
+	)
+
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		want   []string
+	}{
+		{name: "constant operand", params: map[string]interface{}{"operation": "add", "operand": float64(1)}, want: []string{"3.50", "11.00", "8.00"}},
+		{name: "operand field", params: map[string]interface{}{"operation": "multiply", "operand_field": "quantity"}, want: []string{"10.00", "0.00", "7"}},
+		{name: "modulo", params: map[string]interface{}{"operation": "modulo", "operand": 3}, want: []string{"2.50", "1.00", "1.00"}},
+		{name: "power", params: map[string]interface{}{"operation": "power", "operand": "2"}, want: []string{"6.25", "100.00", "49.00"}},
+		{name: "division by zero emptied", params: map[string]interface{}{"operation": "divide", "operand_field": "quantity"}, want: []string{"0.62", "", "7"}},
+		{name: "division by zero as zero", params: map[string]interface{}{"operation": "divide", "operand_field": "quantity", "on_divide_by_zero": "zero"}, want: []string{"0.62", "0", "7"}},
+		{name: "division by zero kept", params: map[string]interface{}{"operation": "modulo", "operand_field": "quantity", "on_divide_by_zero": "keep"}, want: []string{"2.50", "10", "7"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "price", Operation: Calculate, Parameters: tc.params, TargetField: "total"}},
+			})
+			is.NoError(err)
+			for i, row := range output {
+				is.Equal(tc.want[i], row.Fields["total"], i)
+			}
+		})
+	}
+}
+
+func TestTransformService_calculateErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"price", "quantity"}, []string{"10", "0"})
+	_, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "price", Operation: Calculate, Parameters: map[string]interface{}{
+			"operation": "divide", "operand_field": "quantity", "on_divide_by_zero": "error",
+		}}},
+	})
+	is.ErrorContains(err, "line 2: calculate rule on field 'price': divide of 10 by zero")
+
+	for _, params := range []map[string]interface{}{
+		{"operation": "sqrt", "operand": 2},
+		{"operation": "add"},
+		{"operation": "add", "operand": "two"},
+		{"operation": "add", "operand": 2, "operand_field": "quantity"},
+		{"operation": "divide", "operand": 2, "on_divide_by_zero": "infinity"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "price", Operation: Calculate, Parameters: params}},
+		})
+		is.ErrorContains(err, "calculate rule on field 'price'", params)
+	}
+}
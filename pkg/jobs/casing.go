@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Styles of case conversion rules.
+const (
+	CaseSnake = "snake"
+	CaseCamel = "camel"
+	CaseKebab = "kebab"
+	CaseSlug  = "slug"
+)
+
+// caseStyles are the case styles of case conversion operations.
+var caseStyles = map[TransformOperation]string{
+	SnakeCase: CaseSnake,
+	CamelCase: CaseCamel,
+	KebabCase: CaseKebab,
+	Slugify:   CaseSlug,
+}
+
+// transliterations are the ASCII spellings of the letters that decomposing does not strip down
+// to ASCII, lowercase ones as words are lowercased before transliterating.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+}
+
+// casing converts values to a case style for "snake_case", "camel_case", "kebab_case" and
+// "slugify" rules. Words are the runs of letters and digits, also split before an uppercase
+// letter following a lowercase one, like "homeGarden", or before the last of a run of uppercase
+// letters followed by a lowercase one, like "HTTPServer". Apostrophes inside words are dropped.
+// The parameters are "ampersand", writing "&" as the word "and", and "ascii", transliterating
+// diacritics, like "é" to "e", and dropping other non-ASCII letters. Slugs are always ASCII,
+// made of the lowercase words joined with "-".
+type casing struct {
+	style     string
+	ampersand bool
+	ascii     bool
+}
+
+// parseCasing parses the parameters of a case conversion rule.
+func parseCasing(rule TransformRule, style string) (*casing, error) {
+	c := &casing{style: style}
+	for name, flag := range map[string]*bool{"ampersand": &c.ampersand, "ascii": &c.ascii} {
+		value, ok := rule.Parameters[name]
+		if !ok {
+			continue
+		}
+		if *flag, ok = value.(bool); !ok {
+			return nil, fmt.Errorf("invalid %s of %s rule on field '%s': expected true or false", name, rule.Operation, rule.Field)
+		}
+	}
+	c.ascii = c.ascii || style == CaseSlug
+	return c, nil
+}
+
+// convert writes a value in the case style.
+func (c *casing) convert(value string) string {
+	words := c.words(value)
+	switch c.style {
+	case CaseCamel:
+		for i, word := range words {
+			word = strings.ToLower(word)
+			if i > 0 {
+				first, size := utf8.DecodeRuneInString(word)
+				word = string(unicode.ToTitle(first)) + word[size:]
+			}
+			words[i] = word
+		}
+		return strings.Join(words, "")
+	case CaseSnake:
+		return strings.ToLower(strings.Join(words, "_"))
+	default:
+		return strings.ToLower(strings.Join(words, "-"))
+	}
+}
+
+// words splits a value into words, transliterated when ASCII.
+func (c *casing) words(value string) []string {
+	runes := []rune(value)
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(word) > 0 && startsWord(word[len(word)-1], r, runes[i+1:]) {
+				flush()
+			}
+			word = c.appendLetter(word, r)
+		case len(word) > 0 && isInnerApostrophe(runes, i):
+			// apostrophes belong to their word, like "don't"
+		case unicode.Is(unicode.Mn, r) && len(word) > 0:
+			if !c.ascii {
+				word = append(word, r)
+			}
+		case r == '&' && c.ampersand:
+			flush()
+			words = append(words, "and")
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// appendLetter appends a letter or a digit to a word, transliterated when ASCII,
+// dropping letters without transliteration.
+func (c *casing) appendLetter(word []rune, r rune) []rune {
+	if !c.ascii {
+		return append(word, r)
+	}
+	return append(word, []rune(transliterate(r))...)
+}
+
+// isInnerApostrophe reports whether the rune at i is an apostrophe followed by a letter.
+func isInnerApostrophe(runes []rune, i int) bool {
+	return (runes[i] == '\'' || runes[i] == '’') && i+1 < len(runes) && unicode.IsLetter(runes[i+1])
+}
+
+// startsWord reports whether a letter starts a new word after the previous one, given the runes after it.
+func startsWord(previous, r rune, next []rune) bool {
+	if !unicode.IsUpper(r) {
+		return false
+	}
+	if unicode.IsLower(previous) || unicode.IsDigit(previous) {
+		return true
+	}
+	// the last uppercase letter of an acronym starts the next word, like the S of HTTPServer
+	return unicode.IsUpper(previous) && len(next) > 0 && unicode.IsLower(next[0])
+}
+
+// transliterate returns the ASCII spelling of a letter or digit, empty when it has none.
+func transliterate(r rune) string {
+	if r < utf8.RuneSelf {
+		return string(r)
+	}
+	lower := unicode.ToLower(r)
+	if ascii, ok := transliterations[lower]; ok {
+		if lower != r {
+			return strings.ToUpper(ascii[:1]) + ascii[1:]
+		}
+		return ascii
+	}
+
+	var b strings.Builder
+	for _, decomposed := range norm.NFD.String(string(r)) {
+		if decomposed < utf8.RuneSelf {
+			b.WriteRune(decomposed)
+		}
+	}
+	return b.String()
+}
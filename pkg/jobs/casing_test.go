@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCasing_convert(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value string
+		style string
+		and   bool
+		ascii bool
+		want  string
+	}{
+		{value: "Home & Garden", style: CaseSnake, and: true, want: "home_and_garden"},
+		{value: "Home & Garden", style: CaseSnake, want: "home_garden"},
+		{value: "Home & Garden", style: CaseSlug, want: "home-garden"},
+		{value: "Home & Garden", style: CaseSlug, and: true, want: "home-and-garden"},
+		{value: "Home & Garden", style: CaseCamel, and: true, want: "homeAndGarden"},
+		{value: "Home & Garden", style: CaseKebab, want: "home-garden"},
+		{value: "homeGarden", style: CaseSnake, want: "home_garden"},
+		{value: "HTTPServer2Go", style: CaseSnake, want: "http_server2_go"},
+		{value: "user_id", style: CaseCamel, want: "userId"},
+		{value: "user-ID", style: CaseCamel, want: "userId"},
+		{value: "  --Already  snake__case--  ", style: CaseSnake, want: "already_snake_case"},
+		{value: "Don't Stop", style: CaseKebab, want: "dont-stop"},
+		{value: "Crème Brûlée", style: CaseSnake, want: "crème_brûlée"},
+		{value: "Crème Brûlée", style: CaseSnake, ascii: true, want: "creme_brulee"},
+		{value: "Crème Brûlée", style: CaseSlug, want: "creme-brulee"},
+		{value: "Crème", style: CaseSlug, want: "creme"},
+		{value: "Straße Ærø Łódź", style: CaseSlug, want: "strasse-aero-lodz"},
+		{value: "Größe", style: CaseCamel, ascii: true, want: "grosse"},
+		{value: "东京 Tower", style: CaseSlug, want: "tower"},
+		{value: "东京 Tower", style: CaseKebab, want: "东京-tower"},
+		{value: "Ünïcödé WORDS", style: CaseCamel, want: "ünïcödéWords"},
+		{value: "Price €/kg (2024)", style: CaseSlug, want: "price-kg-2024"},
+		{value: "a\xffb", style: CaseSnake, want: "a_b"},
+		{value: "", style: CaseSlug, want: ""},
+		{value: "&&&", style: CaseSlug, want: ""},
+	}
+
+	for _, tc := range testCases {
+		c := &casing{style: tc.style, ampersand: tc.and, ascii: tc.ascii || tc.style == CaseSlug}
+		got := c.convert(tc.value)
+		assert.Equal(t, tc.want, got, "%q to %s", tc.value, tc.style)
+		assert.True(t, utf8.ValidString(got), tc.value)
+	}
+}
+
+func TestTransformService_caseConversions(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"category"}, []string{"Home & Garden"}, []string{"Café Équipement"})
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{
+			{Field: "category", Operation: SnakeCase, Parameters: map[string]interface{}{"ampersand": true}, TargetField: "key"},
+			{Field: "category", Operation: CamelCase, TargetField: "camel"},
+			{Field: "category", Operation: KebabCase, TargetField: "kebab"},
+			{Field: "category", Operation: Slugify, TargetField: "slug"},
+		},
+	})
+	is.NoError(err)
+	is.Equal(map[string]string{"category": "Home & Garden", "key": "home_and_garden", "camel": "homeGarden", "kebab": "home-garden", "slug": "home-garden"}, output[0].Fields)
+	is.Equal(map[string]string{"category": "Café Équipement", "key": "café_équipement", "camel": "caféÉquipement", "kebab": "café-équipement", "slug": "cafe-equipement"}, output[1].Fields)
+
+	_, err = newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"rules": []TransformRule{{Field: "category", Operation: Slugify, Parameters: map[string]interface{}{"ampersand": "yes"}}},
+	})
+	is.ErrorContains(err, "invalid ampersand of slugify rule on field 'category'")
+}
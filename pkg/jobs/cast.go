@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Types of "cast" rules.
+const (
+	CastInt    = "int"
+	CastFloat  = "float"
+	CastBool   = "bool"
+	CastString = "string"
+)
+
+// Default sets of "cast" rules to bool, compared in any case.
+var (
+	defaultTrueValues  = []string{"true", "t", "yes", "y", "1", "on"}
+	defaultFalseValues = []string{"false", "f", "no", "n", "0", "off"}
+)
+
+// typeCast converts values to a canonical representation for "cast" rules. Its parameters are
+// "to", one of the Cast types, values that do not convert following the on_error policy of the
+// rules. Numbers are read in the "number_format" of filters, plain by default, like "1,234.56" in
+// the en format, and written in Go syntax, like "1234.56", without going through float64 so that
+// long integers keep their digits. Integers fail on fractions. Bools are read with the "true_values" and "false_values" sets and
+// written as "true_output" and "false_output", true and false by default. Strings are left as is,
+// as are empty values and values already written canonically.
+type typeCast struct {
+	to           string
+	numberFormat NumberFormat
+	trueValues   []string
+	falseValues  []string
+	trueOutput   string
+	falseOutput  string
+}
+
+// parseTypeCast parses the parameters of a "cast" rule.
+func parseTypeCast(rule TransformRule) (*typeCast, error) {
+	cast := &typeCast{
+		trueValues:  defaultTrueValues,
+		falseValues: defaultFalseValues,
+		trueOutput:  "true",
+		falseOutput: "false",
+	}
+
+	switch to, _ := rule.Parameters["to"].(string); to {
+	case CastInt, CastFloat, CastBool, CastString:
+		cast.to = to
+	default:
+		return nil, fmt.Errorf("invalid to %q of cast rule on field '%s': expected int, float, bool or string", to, rule.Field)
+	}
+
+	format, _ := rule.Parameters["number_format"].(string)
+	var err error
+	if cast.numberFormat, err = ParseNumberFormat(format); err != nil {
+		return nil, fmt.Errorf("invalid number_format of cast rule on field '%s': %w", rule.Field, err)
+	}
+
+	for name, set := range map[string]*[]string{"true_values": &cast.trueValues, "false_values": &cast.falseValues} {
+		if raw, ok := rule.Parameters[name]; ok {
+			if *set = parseColumnList(raw); len(*set) == 0 {
+				return nil, fmt.Errorf("invalid %s of cast rule on field '%s': expected a list of values", name, rule.Field)
+			}
+		}
+	}
+	for _, value := range cast.trueValues {
+		if cast.isIn(value, cast.falseValues) {
+			return nil, fmt.Errorf("value %q of cast rule on field '%s' is both true and false", value, rule.Field)
+		}
+	}
+	for name, output := range map[string]*string{"true_output": &cast.trueOutput, "false_output": &cast.falseOutput} {
+		if raw, ok := rule.Parameters[name]; ok {
+			*output = jsonlValue(raw)
+		}
+	}
+	return cast, nil
+}
+
+// isIn reports whether a value is in a set, in any case and once trimmed.
+func (c *typeCast) isIn(value string, set []string) bool {
+	value = strings.TrimSpace(value)
+	for _, candidate := range set {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// cast converts a value, failing with an untransformableError when it does not convert.
+func (c *typeCast) cast(value string, field string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	converted, ok := c.convert(value)
+	if !ok {
+		return "", untransformable("cast rule on field '%s': value %q is not a valid %s", field, value, c.to)
+	}
+	return converted, nil
+}
+
+// convert converts a value, reporting whether it could.
+func (c *typeCast) convert(value string) (string, bool) {
+	switch c.to {
+	case CastBool:
+		switch {
+		case value == c.trueOutput || value == c.falseOutput:
+			return value, true
+		case c.isIn(value, c.trueValues):
+			return c.trueOutput, true
+		case c.isIn(value, c.falseValues):
+			return c.falseOutput, true
+		default:
+			return "", false
+		}
+	case CastInt, CastFloat:
+		normalized, ok := normalizeNumber(value, c.numberFormat)
+		if !ok {
+			return "", false
+		}
+		// values the format reads as written are canonical already
+		if _, ok := parseDecimal(value, false); ok && normalized == value && (c.to == CastFloat || !strings.Contains(value, ".")) {
+			return value, true
+		}
+		number, ok := parseDecimal(normalized, true)
+		if !ok {
+			return "", false
+		}
+		if c.to == CastFloat {
+			return numberOutput{precision: -1}.format(number), true
+		}
+		if !number.value.IsInt() {
+			return "", false
+		}
+		return number.value.Num().String(), true
+	default:
+		return value, true
+	}
+}
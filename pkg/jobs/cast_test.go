@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_castBool(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	values := []string{"true", "TRUE", "t", "Yes", "y", "1", "on", "false", "F", "no", "N", "0", "off", "", "maybe"}
+	rows := make([][]string, len(values))
+	for i, value := range values {
+		rows[i] = []string{value}
+	}
+	input := testRows(t, []string{"flag"}, rows...)
+
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "flag", Operation: Cast, Parameters: map[string]interface{}{"to": "bool"}, TargetField: "bool"}},
+	})
+	is.NoError(err)
+	want := []string{"true", "true", "true", "true", "true", "true", "true", "false", "false", "false", "false", "false", "false", "", "maybe"}
+	for i, row := range output {
+		is.Equal(want[i], row.Fields["bool"], values[i])
+	}
+
+	output, err = newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "flag", Operation: Cast, Parameters: map[string]interface{}{
+			"to": "bool", "true_values": []interface{}{"true", "oui"}, "false_values": "false,non",
+			"true_output": float64(1), "false_output": "0", "on_error": "empty",
+		}}},
+	})
+	is.NoError(err)
+	want = []string{"1", "1", "", "", "", "1", "", "0", "", "", "", "0", "", "", ""}
+	for i, row := range output {
+		is.Equal(want[i], row.Fields["flag"], values[i])
+	}
+}
+
+func TestTransformService_castNumbers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "english float", value: "1,234.56", params: map[string]interface{}{"to": "float", "number_format": "en"}, want: "1234.56"},
+		{name: "european float", value: "1.234,50", params: map[string]interface{}{"to": "float", "number_format": "eu"}, want: "1234.5"},
+		{name: "european float with spaces", value: "€ 1 234,5", params: map[string]interface{}{"to": "float", "number_format": "eu"}, want: "1234.5"},
+		{name: "european integer", value: "12.345.678.901.234.567.890", params: map[string]interface{}{"to": "int", "number_format": "eu"}, want: "12345678901234567890"},
+		{name: "integral float to int", value: "1,000.00", params: map[string]interface{}{"to": "int", "number_format": "auto"}, want: "1000"},
+		{name: "conforming float", value: "1234.560", params: map[string]interface{}{"to": "float", "number_format": "en"}, want: "1234.560"},
+		{name: "european thousands", value: "1.234", params: map[string]interface{}{"to": "float", "number_format": "eu"}, want: "1234"},
+		{name: "conforming int", value: "-042", params: map[string]interface{}{"to": "int"}, want: "-042"},
+		{name: "fraction to int", value: "1.5", params: map[string]interface{}{"to": "int"}, want: "1.5"},
+		{name: "not a number", value: "n/a", params: map[string]interface{}{"to": "float", "on_error": "empty"}, want: ""},
+		{name: "string", value: " As is ", params: map[string]interface{}{"to": "string"}, want: " As is "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: Cast, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_castErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"value"}, []string{"12"}, []string{"1.5"})
+	_, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "value", Operation: Cast, Parameters: map[string]interface{}{"to": "int", "on_error": "fail"}}},
+	})
+	is.ErrorContains(err, `line 3: cast rule on field 'value': value "1.5" is not a valid int`)
+
+	for _, params := range []map[string]interface{}{
+		{},
+		{"to": "date"},
+		{"to": "float", "number_format": "tlh"},
+		{"to": "bool", "true_values": []interface{}{}},
+		{"to": "bool", "true_values": "yes,1", "false_values": "no,1"},
+		{"to": "int", "on_error": "skip"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "value", Operation: Cast, Parameters: params}},
+		})
+		is.ErrorContains(err, "cast rule on field 'value'", params)
+	}
+}
@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Checksum algorithms of "checksum" rules.
+const (
+	ChecksumLuhn    = "luhn"     // card numbers and other Luhn (mod 10) identifiers
+	ChecksumIBAN    = "iban"     // international bank account numbers, ISO 13616 mod 97
+	ChecksumFRSiren = "fr_siren" // French company numbers, 9 digits with a Luhn key
+	ChecksumFRSiret = "fr_siret" // French establishment numbers, 14 digits with a Luhn key
+)
+
+// checksumConstraint checks the values of a "checksum" rule. Its constraints are an algorithm,
+// or {"algorithm": ..., "mask_value": true}, mask_value leaving all but the last 4 characters
+// of invalid values out of validation errors, for personal data like card numbers.
+type checksumConstraint struct {
+	algorithm string
+	mask      bool
+}
+
+// checksumAlgorithms validates the values of each checksum algorithm, once whitespace is removed.
+var checksumAlgorithms = map[string]func(string) bool{
+	ChecksumLuhn:    validateLuhn,
+	ChecksumIBAN:    validateIBAN,
+	ChecksumFRSiren: validateSIREN,
+	ChecksumFRSiret: validateSIRET,
+}
+
+// parseChecksumConstraint parses the constraints of a "checksum" rule.
+func parseChecksumConstraint(rule ValidationRule) (*checksumConstraint, error) {
+	constraint := &checksumConstraint{}
+	switch constraints := rule.Constraints.(type) {
+	case string:
+		constraint.algorithm = constraints
+	case map[string]interface{}:
+		constraint.algorithm, _ = constraints["algorithm"].(string)
+		if mask, ok := constraints["mask_value"]; ok {
+			if constraint.mask, ok = mask.(bool); !ok {
+				return nil, fmt.Errorf("invalid mask_value of checksum rule on field '%s': expected true or false", rule.Field)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid constraints of checksum rule on field '%s': expected an algorithm or an object", rule.Field)
+	}
+
+	constraint.algorithm = strings.ToLower(constraint.algorithm)
+	if checksumAlgorithms[constraint.algorithm] == nil {
+		return nil, fmt.Errorf("unknown checksum algorithm %q on field '%s': expected luhn, iban, fr_siren or fr_siret", constraint.algorithm, rule.Field)
+	}
+	return constraint, nil
+}
+
+// check validates a value, returning the failure message, if any.
+func (c *checksumConstraint) check(value string) string {
+	if checksumAlgorithms[c.algorithm](removeWhitespace(value)) {
+		return ""
+	}
+	return fmt.Sprintf("Invalid %s checksum", c.algorithm)
+}
+
+// removeWhitespace removes every whitespace character of a value, like the spaces grouping digits.
+func removeWhitespace(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// maskValue replaces all but the last 4 characters of a value with asterisks.
+func maskValue(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+// validateLuhn reports whether a value is digits whose Luhn (mod 10) checksum is valid.
+func validateLuhn(value string) bool {
+	if value == "" || !isDigits(value) {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < len(value); i++ {
+		digit := int(value[len(value)-1-i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// validateIBAN reports whether a value is an IBAN, in any case, whose mod 97 checksum is valid:
+// a country code, 2 check digits and up to 30 letters and digits.
+func validateIBAN(value string) bool {
+	value = strings.ToUpper(value)
+	if len(value) < 15 || len(value) > 34 || !isLetters(value[:2]) || !isDigits(value[2:4]) {
+		return false
+	}
+
+	// the first 4 characters move to the end, and letters count as 10 to 35
+	remainder := 0
+	for _, c := range value[4:] + value[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// validateSIREN reports whether a value is a French SIREN number, 9 digits with a Luhn key.
+func validateSIREN(value string) bool {
+	return len(value) == 9 && validateLuhn(value)
+}
+
+// validateSIRET reports whether a value is a French SIRET number, 14 digits with a Luhn key.
+// La Poste establishments, whose SIREN is 356000000, have digits summing to a multiple of 5 instead,
+// but for the head office, 35600000000048.
+func validateSIRET(value string) bool {
+	if len(value) != 14 || !isDigits(value) {
+		return false
+	}
+	if strings.HasPrefix(value, "356000000") && value != "35600000000048" {
+		sum := 0
+		for i := 0; i < len(value); i++ {
+			sum += int(value[i] - '0')
+		}
+		return sum%5 == 0
+	}
+	return validateLuhn(value)
+}
+
+// isLetters reports whether a value only has ASCII letters.
+func isLetters(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if (value[i] < 'A' || value[i] > 'Z') && (value[i] < 'a' || value[i] > 'z') {
+			return false
+		}
+	}
+	return true
+}
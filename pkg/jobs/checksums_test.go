@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksumAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		algorithm string
+		value     string
+		expected  bool
+	}{
+		{ChecksumLuhn, "4111111111111111", true},
+		{ChecksumLuhn, "4539 1488 0343 6467", true},
+		{ChecksumLuhn, "79927398713", true},
+		{ChecksumLuhn, "4111111111111112", false},
+		{ChecksumLuhn, "79927398731", false}, // transposed digits
+		{ChecksumLuhn, "4111-1111-1111-1111", false},
+		{ChecksumLuhn, "", false},
+		{ChecksumIBAN, "GB82 WEST 1234 5698 7654 32", true},
+		{ChecksumIBAN, "FR14 2004 1010 0505 0001 3M02 606", true},
+		{ChecksumIBAN, "de89370400440532013000", true},
+		{ChecksumIBAN, "GB82WEST12345698765423", false}, // transposed digits
+		{ChecksumIBAN, "GB82WEST1234569876543", false},
+		{ChecksumIBAN, "GB82-WEST-1234-5698-7654-32", false},
+		{ChecksumIBAN, "1282WEST12345698765432", false},
+		{ChecksumIBAN, "GB82", false},
+		{ChecksumFRSiren, "732 829 320", true},
+		{ChecksumFRSiren, "443061841", true},
+		{ChecksumFRSiren, "732829302", false},
+		{ChecksumFRSiren, "73282932000074", false},
+		{ChecksumFRSiret, "732 829 320 00074", true},
+		{ChecksumFRSiret, "44306184100047", true},
+		{ChecksumFRSiret, "44306184100074", false},
+		{ChecksumFRSiret, "732829320", false},
+		{ChecksumFRSiret, "35600000000048", true},  // La Poste head office, Luhn
+		{ChecksumFRSiret, "35600000049837", true},  // La Poste, digit sum
+		{ChecksumFRSiret, "35600000000055", false}, // La Poste, Luhn only
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.algorithm+" "+tc.value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			constraint := &checksumConstraint{algorithm: tc.algorithm}
+			is.Equal(tc.expected, constraint.check(tc.value) == "")
+		})
+	}
+}
+
+func TestMaskValue(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	is.Equal("************1111", maskValue("4111111111111111"))
+	is.Equal("****", maskValue("4111"))
+	is.Equal("**", maskValue("41"))
+	is.Equal("", maskValue(""))
+}
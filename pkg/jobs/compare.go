@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueType tells how values compare when looking for a minimum or maximum, or sorting.
+type ValueType string
+
+const (
+	// ValueTypeAuto detects the type from the first non-empty value. This is the default.
+	ValueTypeAuto ValueType = "auto"
+	// ValueTypeNumber compares values as numbers.
+	ValueTypeNumber ValueType = "number"
+	// ValueTypeDate compares values as dates, parsed with the date layouts.
+	ValueTypeDate ValueType = "date"
+	// ValueTypeString compares values lexicographically.
+	ValueTypeString ValueType = "string"
+)
+
+// ParseValueType validates a value type name, the empty name being ValueTypeAuto.
+func ParseValueType(name string) (ValueType, error) {
+	switch valueType := ValueType(strings.ToLower(name)); valueType {
+	case "", ValueTypeAuto:
+		return ValueTypeAuto, nil
+	case ValueTypeNumber, ValueTypeDate, ValueTypeString:
+		return valueType, nil
+	default:
+		return "", fmt.Errorf("unknown value type %q: expected auto, number, date or string", name)
+	}
+}
+
+// detectValueType returns the type of a value: a number if it parses as one, else a date, else a string.
+func detectValueType(value string, layouts []string) ValueType {
+	if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+		return ValueTypeNumber
+	}
+	if _, ok := parseDate(value, layouts); ok {
+		return ValueTypeDate
+	}
+	return ValueTypeString
+}
+
+// comparableValue is a value parsed as the type of a valueComparator.
+type comparableValue struct {
+	raw  string
+	num  float64
+	date time.Time
+}
+
+// valueComparator compares values of one type, which is never ValueTypeAuto.
+type valueComparator struct {
+	valueType ValueType
+	layouts   []string // date layouts, DefaultDateLayouts when empty
+}
+
+// parse parses a value as the comparator type, reporting whether it is one. Every value is a string.
+func (c valueComparator) parse(value string) (comparableValue, bool) {
+	parsed := comparableValue{raw: value}
+
+	//nolint:exhaustive
+	switch c.valueType {
+	case ValueTypeNumber:
+		num, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return parsed, false
+		}
+		parsed.num = num
+	case ValueTypeDate:
+		date, ok := parseDate(value, c.layouts)
+		if !ok {
+			return parsed, false
+		}
+		parsed.date = date
+	}
+
+	return parsed, true
+}
+
+// compare returns a negative number when a is smaller than b, a positive one when it is larger.
+func (c valueComparator) compare(a, b comparableValue) int {
+	//nolint:exhaustive
+	switch c.valueType {
+	case ValueTypeNumber:
+		switch {
+		case a.num < b.num:
+			return -1
+		case a.num > b.num:
+			return 1
+		}
+		return 0
+	case ValueTypeDate:
+		return a.date.Compare(b.date)
+	default:
+		return strings.Compare(a.raw, b.raw)
+	}
+}
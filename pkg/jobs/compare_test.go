@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectValueType(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	is.Equal(ValueTypeNumber, detectValueType(" 12.5", nil))
+	is.Equal(ValueTypeDate, detectValueType("2024-02-29", nil))
+	is.Equal(ValueTypeString, detectValueType("29/02/2024", nil))
+	is.Equal(ValueTypeDate, detectValueType("29/02/2024", []string{"02/01/2006"}))
+	is.Equal(ValueTypeString, detectValueType("n/a", nil))
+}
+
+func TestValueComparator(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	compare := func(valueType ValueType, a, b string) int {
+		c := valueComparator{valueType: valueType}
+		aValue, aOK := c.parse(a)
+		bValue, bOK := c.parse(b)
+		is.True(aOK && bOK)
+		return c.compare(aValue, bValue)
+	}
+
+	is.Negative(compare(ValueTypeNumber, "9", "10"))
+	is.Positive(compare(ValueTypeString, "9", "10"))
+	is.Negative(compare(ValueTypeDate, "2023-12-31", "2024-01-01"))
+	is.Zero(compare(ValueTypeString, "abc", "abc"))
+
+	_, ok := valueComparator{valueType: ValueTypeDate}.parse("soon")
+	is.False(ok)
+
+	_, err := ParseValueType("Date")
+	is.NoError(err)
+	_, err = ParseValueType("time")
+	is.Error(err)
+}
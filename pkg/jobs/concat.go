@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// concatFormat combines fields into the target field of a "concat" rule. Its parameters are either
+// "fields", the ordered fields joined with "separator", empty by default, and "skip_empty",
+// which leaves out blank values rather than emitting double separators, or "template", a text
+// with field references like "{first_name} {last_name}", "{{" and "}}" standing for braces.
+// Missing fields contribute empty values.
+type concatFormat struct {
+	fields    []string
+	separator string
+	skipEmpty bool
+	template  []templatePart
+}
+
+// templatePart is a literal text or a field reference of a concat template.
+type templatePart struct {
+	text  string
+	field string
+}
+
+// parseConcatFormat parses the parameters of a "concat" rule, which needs a target field.
+func parseConcatFormat(rule TransformRule) (*concatFormat, error) {
+	if rule.TargetField == "" {
+		return nil, fmt.Errorf("concat rule needs a target_field")
+	}
+
+	format := &concatFormat{fields: parseColumnList(rule.Parameters["fields"])}
+	for i, field := range format.fields {
+		format.fields[i] = strings.TrimSpace(field)
+	}
+	format.separator, _ = rule.Parameters["separator"].(string)
+	if skipEmpty, ok := rule.Parameters["skip_empty"]; ok {
+		if format.skipEmpty, ok = skipEmpty.(bool); !ok {
+			return nil, fmt.Errorf("invalid skip_empty of concat rule on field '%s': expected true or false", rule.TargetField)
+		}
+	}
+
+	template, hasTemplate := rule.Parameters["template"].(string)
+	switch {
+	case hasTemplate && len(format.fields) > 0:
+		return nil, fmt.Errorf("concat rule on field '%s' has both fields and a template", rule.TargetField)
+	case hasTemplate:
+		parts, err := parseConcatTemplate(template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of concat rule on field '%s': %w", rule.TargetField, err)
+		}
+		format.template = parts
+	case len(format.fields) == 0:
+		return nil, fmt.Errorf("concat rule on field '%s' needs fields or a template", rule.TargetField)
+	}
+	return format, nil
+}
+
+// parseConcatTemplate splits a template into literal texts and field references.
+func parseConcatTemplate(template string) ([]templatePart, error) {
+	var parts []templatePart
+	var text strings.Builder
+	for i := 0; i < len(template); i++ {
+		switch c := template[i]; {
+		case strings.HasPrefix(template[i:], "{{"), strings.HasPrefix(template[i:], "}}"):
+			text.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed field reference at position %d", i)
+			}
+			field := strings.TrimSpace(template[i+1 : i+end])
+			if field == "" {
+				return nil, fmt.Errorf("empty field reference at position %d", i)
+			}
+			if text.Len() > 0 {
+				parts = append(parts, templatePart{text: text.String()})
+				text.Reset()
+			}
+			parts = append(parts, templatePart{field: field})
+			i += end
+		case c == '}':
+			return nil, fmt.Errorf("unexpected '}' at position %d, write }} for a brace", i)
+		default:
+			text.WriteByte(c)
+		}
+	}
+	if text.Len() > 0 {
+		parts = append(parts, templatePart{text: text.String()})
+	}
+	return parts, nil
+}
+
+// concat combines the fields of a row.
+func (f *concatFormat) concat(row DataRow) string {
+	var b strings.Builder
+	if f.template != nil {
+		for _, part := range f.template {
+			if part.field != "" {
+				b.WriteString(row.Fields[part.field])
+			} else {
+				b.WriteString(part.text)
+			}
+		}
+		return b.String()
+	}
+
+	written := false
+	for _, field := range f.fields {
+		value := row.Fields[field]
+		if f.skipEmpty && strings.TrimSpace(value) == "" {
+			continue
+		}
+		if written {
+			b.WriteString(f.separator)
+		}
+		b.WriteString(value)
+		written = true
+	}
+	return b.String()
+}
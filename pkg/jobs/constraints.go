@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// valueConstraint checks the values of a rule on their own, returning why a value fails it,
+// or an empty string when it passes.
+type valueConstraint interface {
+	check(value string) string
+}
+
+// constraintParser parses the constraints of a rule into its valueConstraint.
+type constraintParser func(s *ValidateService, rule ValidationRule) (valueConstraint, error)
+
+// constraintParsers parse the constraints of the rule types checking values on their own, by type.
+// The other rule types read more than a value: unique and duplicate_row rules earlier rows,
+// compare_fields and expression rules other fields of the row.
+var constraintParsers = map[string]constraintParser{
+	"required": fixedConstraint(checkRequired),
+	"email":    fixedConstraint(checkEmail),
+	"numeric":  fixedConstraint(checkNumeric),
+	"ipv4":     fixedConstraint(checkIPv4),
+	"ipv6":     fixedConstraint(checkIPv6),
+	"hostname": fixedConstraint(checkHostname),
+	"uuid": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseUUIDConstraint(rule)
+	},
+	"enum": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseEnumConstraint(rule)
+	},
+	"regex": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseRegexConstraint(rule)
+	},
+	"min_length": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseLengthConstraint(rule, false), nil
+	},
+	"max_length": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseLengthConstraint(rule, true), nil
+	},
+	"range": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseRangeConstraint(rule), nil
+	},
+	"date": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseDateConstraint(rule)
+	},
+	"integer": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseNumberConstraint(rule)
+	},
+	"decimal": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseNumberConstraint(rule)
+	},
+	"checksum": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseChecksumConstraint(rule)
+	},
+	// the reference values are loaded before any row is read
+	"foreign_key": func(s *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return s.parseForeignKey(rule)
+	},
+	"outlier": func(_ *ValidateService, rule ValidationRule) (valueConstraint, error) {
+		return parseOutlierConstraint(rule)
+	},
+}
+
+// valueCheck is a constraint without parameters.
+type valueCheck func(value string) string
+
+func (c valueCheck) check(value string) string {
+	return c(value)
+}
+
+// fixedConstraint parses the rules of a type without constraints into the same check.
+func fixedConstraint(check valueCheck) constraintParser {
+	return func(*ValidateService, ValidationRule) (valueConstraint, error) {
+		return check, nil
+	}
+}
+
+// invalidConstraint fails every value with the same message, for rules whose constraints
+// are missing or of the wrong kind but which were accepted before constraints were parsed.
+type invalidConstraint string
+
+func (c invalidConstraint) check(string) string {
+	return string(c)
+}
+
+func checkRequired(value string) string {
+	if value == "" {
+		return "Field is required"
+	}
+	return ""
+}
+
+// emailRegex matches email addresses, compiled once for every validation.
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+func checkEmail(value string) string {
+	//bearer:disable go_lang_permissive_regex_validation
+	if !emailRegex.MatchString(value) {
+		return "Invalid email format"
+	}
+	return ""
+}
+
+func checkNumeric(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return "Value must be numeric"
+	}
+	return ""
+}
+
+func checkIPv4(value string) string {
+	if !validateIPv4(value) {
+		return "Value must be an IPv4 address, like 192.168.0.1"
+	}
+	return ""
+}
+
+func checkIPv6(value string) string {
+	if !validateIPv6(value) {
+		return "Value must be an IPv6 address, like 2001:db8::1"
+	}
+	return ""
+}
+
+func checkHostname(value string) string {
+	if !validateHostname(value) {
+		return "Value must be a hostname, like api.example.com"
+	}
+	return ""
+}
+
+// uuidConstraint is the version of a "uuid" rule, any when 0.
+type uuidConstraint int
+
+// parseUUIDConstraint parses the version of a "uuid" rule, from 1 to 8, if any.
+func parseUUIDConstraint(rule ValidationRule) (uuidConstraint, error) {
+	if rule.Constraints == nil {
+		return 0, nil
+	}
+	version, ok := toInt(rule.Constraints)
+	if !ok || version < 1 || version > 8 {
+		return 0, fmt.Errorf("invalid version %v of uuid rule on field '%s': expected 1 to 8", rule.Constraints, rule.Field)
+	}
+	return uuidConstraint(version), nil
+}
+
+func (c uuidConstraint) check(value string) string {
+	if validateUUID(value, int(c)) {
+		return ""
+	}
+	if c != 0 {
+		return fmt.Sprintf("Value must be a version %d UUID, like xxxxxxxx-xxxx-%dxxx-yxxx-xxxxxxxxxxxx", c, c)
+	}
+	return "Value must be a UUID, like 123e4567-e89b-12d3-a456-426614174000"
+}
+
+// enumConstraint is the allowed values of an "enum" rule.
+type enumConstraint []string
+
+// parseEnumConstraint resolves the allowed values of an "enum" rule, as a list or a comma-separated string.
+func parseEnumConstraint(rule ValidationRule) (enumConstraint, error) {
+	values := parseColumnList(rule.Constraints)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("invalid constraints %v of enum rule on field '%s': expected a list of values", rule.Constraints, rule.Field)
+	}
+	return values, nil
+}
+
+func (c enumConstraint) check(value string) string {
+	if slices.Contains(c, value) {
+		return ""
+	}
+	return "Value must be one of " + describeValues(c)
+}
+
+// regexConstraint is the pattern of a "regex" rule, compiled once instead of once per row.
+type regexConstraint struct {
+	regex   *regexp.Regexp
+	pattern string
+}
+
+// parseRegexConstraint compiles the pattern of a "regex" rule.
+func parseRegexConstraint(rule ValidationRule) (valueConstraint, error) {
+	pattern, ok := rule.Constraints.(string)
+	if !ok {
+		return invalidConstraint("Regex pattern not specified"), nil
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern %q on field '%s': %w", pattern, rule.Field, err)
+	}
+	return &regexConstraint{regex: regex, pattern: pattern}, nil
+}
+
+func (c *regexConstraint) check(value string) string {
+	if !c.regex.MatchString(value) {
+		return "Value does not match pattern: " + c.pattern
+	}
+	return ""
+}
+
+// lengthConstraint is the length bound, in bytes, of a "min_length" or "max_length" rule.
+type lengthConstraint struct {
+	limit int
+	max   bool
+}
+
+// parseLengthConstraint parses the length bound of a "min_length" or "max_length" rule.
+func parseLengthConstraint(rule ValidationRule, isMax bool) valueConstraint {
+	limit, ok := rule.Constraints.(float64)
+	switch {
+	case !ok && isMax:
+		return invalidConstraint("Max length not specified")
+	case !ok:
+		return invalidConstraint("Min length not specified")
+	}
+	return lengthConstraint{limit: int(limit), max: isMax}
+}
+
+func (c lengthConstraint) check(value string) string {
+	switch {
+	case c.max && len(value) > c.limit:
+		return fmt.Sprintf("Value must be at most %d characters", c.limit)
+	case !c.max && len(value) < c.limit:
+		return fmt.Sprintf("Value must be at least %d characters", c.limit)
+	}
+	return ""
+}
+
+// rangeConstraint is the bounds of a "range" rule, {"min": number, "max": number}.
+type rangeConstraint struct {
+	min, max float64
+}
+
+// parseRangeConstraint parses the bounds of a "range" rule.
+func parseRangeConstraint(rule ValidationRule) valueConstraint {
+	constraints, ok := rule.Constraints.(map[string]interface{})
+	if !ok {
+		return invalidConstraint("Range constraints not specified")
+	}
+	lower, ok := constraints["min"].(float64)
+	if !ok {
+		return invalidConstraint("Min value not specified for range")
+	}
+	upper, ok := constraints["max"].(float64)
+	if !ok {
+		return invalidConstraint("Max value not specified for range")
+	}
+	return rangeConstraint{min: lower, max: upper}
+}
+
+func (c rangeConstraint) check(value string) string {
+	num, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "Value must be numeric for range validation"
+	}
+	if num < c.min || num > c.max {
+		return fmt.Sprintf("Value must be between %.2f and %.2f", c.min, c.max)
+	}
+	return ""
+}
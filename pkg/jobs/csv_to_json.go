@@ -21,7 +21,7 @@ type CSVToJSONService struct {
 func NewCSVToJSONService(i do.Injector) (*CSVToJSONService, error) {
 	return &CSVToJSONService{
 		fileService: do.MustInvoke[*FileService](i),
-		logger:      do.MustInvoke[zerolog.Logger](i),
+		logger:      *do.MustInvoke[*zerolog.Logger](i),
 	}, nil
 }
 
@@ -49,8 +49,9 @@ func (s *CSVToJSONService) ProcessData(input []DataRow, options map[string]inter
 		outputFile = strings.TrimSuffix(inputFile, ext) + ".json"
 	}
 
-	// Write to JSON file
-	if err := s.fileService.WriteJSON(outputFile, dataRows); err != nil {
+	// Write to JSON file, with source line numbers when requested
+	includeMetadata, _ := options["include_metadata"].(bool)
+	if err := s.fileService.WriteJSONRows(outputFile, dataRows, includeMetadata); err != nil {
 		return nil, fmt.Errorf("failed to write JSON file: %w", err)
 	}
 
@@ -75,16 +76,17 @@ func (s *CSVToJSONService) GetDescription() string {
 
 // ConvertFile converts a single CSV file to JSON
 // This convenience method demonstrates file-level operations.
-func (s *CSVToJSONService) ConvertFile(inputPath, outputPath string) (*ProcessingResult, error) {
+// extraOptions may carry any additional ProcessData option and can be nil.
+func (s *CSVToJSONService) ConvertFile(inputPath, outputPath string, extraOptions map[string]interface{}) (*ProcessingResult, error) {
 	s.logger.Info().
 		Str("input", inputPath).
 		Str("output", outputPath).
 		Msg("Starting CSV to JSON conversion")
 
-	options := map[string]interface{}{
+	options := mergeOptions(map[string]interface{}{
 		"input_file":  inputPath,
 		"output_file": outputPath,
-	}
+	}, extraOptions)
 
 	dataRows, err := s.ProcessData(nil, options)
 	if err != nil {
@@ -120,7 +122,7 @@ func (s *CSVToJSONService) BatchConvert(inputPaths []string, outputDir string) (
 		outputFilename := strings.TrimSuffix(filename, ext) + ".json"
 		outputPath := filepath.Join(outputDir, outputFilename)
 
-		result, err := s.ConvertFile(inputPath, outputPath)
+		result, err := s.ConvertFile(inputPath, outputPath, nil)
 		if err != nil {
 			s.logger.Error().Err(err).Str("file", inputPath).Msg("Failed to convert file")
 		}
@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ambiguousCurrencySymbols are the symbols written for several currencies, like "$" for the
+// dollars of the United States, Canada or Australia.
+var ambiguousCurrencySymbols = map[string]bool{"$": true, "\u00a5": true}
+
+// currencyAmount reads amounts for "parse_currency" rules, like "$1,234.56", "1.234,56 EUR" or
+// "(99.90)", writing them in Go syntax as "parse_number" rules do, with the same parameters, and
+// writing the ISO 4217 code of their currency to "currency_target_field" when set. Currencies are
+// the codes and symbols of the currencies known to format_number rules, before or after amounts,
+// or any code of three uppercase letters. Amounts without currency, or with an ambiguous symbol,
+// are in "default_currency", none by default, ambiguous symbols being read as their most common
+// currency, like USD for "$", without it. Amounts in parentheses are negative.
+type currencyAmount struct {
+	number          *localizedNumber
+	codeField       string
+	defaultCurrency string
+}
+
+// parseCurrencyAmount parses the parameters of a "parse_currency" rule.
+func parseCurrencyAmount(rule TransformRule) (*currencyAmount, error) {
+	number, err := parseLocalizedNumber(rule)
+	if err != nil {
+		return nil, err
+	}
+	amount := &currencyAmount{number: number}
+
+	if raw, ok := rule.Parameters["currency_target_field"]; ok {
+		if amount.codeField, _ = raw.(string); strings.TrimSpace(amount.codeField) == "" {
+			return nil, fmt.Errorf("invalid currency_target_field of parse_currency rule on field '%s': expected a field name", rule.Field)
+		}
+	}
+
+	if raw, ok := rule.Parameters["default_currency"]; ok {
+		code, _ := raw.(string)
+		if amount.defaultCurrency = strings.ToUpper(code); !isCurrencyCode(amount.defaultCurrency) {
+			return nil, fmt.Errorf("invalid default_currency %v of parse_currency rule on field '%s': expected an ISO 4217 code like EUR", raw, rule.Field)
+		}
+	}
+	return amount, nil
+}
+
+// read returns an amount in Go syntax and the code of its currency, failing when it is not a
+// number.
+func (c *currencyAmount) read(value string) (string, string, bool) {
+	amount, negative := cutNegative(strings.TrimSpace(value))
+	code, amount := c.cutCurrency(amount)
+	amount, negativeAmount := cutNegative(amount)
+	if negative || negativeAmount {
+		amount = "-" + amount
+	}
+
+	number, ok := c.number.read(amount)
+	if !ok {
+		return "", "", false
+	}
+	return number, code, true
+}
+
+// cutCurrency removes the currency written before or after an amount, returning its code, the
+// default currency when there is none.
+func (c *currencyAmount) cutCurrency(amount string) (string, string) {
+	if code, rest, ok := cutCurrencyCode(amount); ok {
+		return code, rest
+	}
+	for _, currency := range currencies {
+		rest, ok := cutAffix(amount, currency.symbol)
+		if !ok {
+			continue
+		}
+		if ambiguousCurrencySymbols[currency.symbol] && c.defaultCurrency != "" {
+			return c.defaultCurrency, rest
+		}
+		return currency.code, rest
+	}
+	return c.defaultCurrency, amount
+}
+
+// cutCurrencyCode removes a code of three uppercase letters written before or after an amount,
+// like "EUR 12" or "12 SEK".
+func cutCurrencyCode(amount string) (string, string, bool) {
+	if len(amount) > 3 && isCurrencyCode(amount[:3]) {
+		if next, _ := utf8.DecodeRuneInString(amount[3:]); !unicode.IsLetter(next) {
+			return amount[:3], strings.TrimSpace(amount[3:]), true
+		}
+	}
+	if len(amount) > 3 && isCurrencyCode(amount[len(amount)-3:]) {
+		if previous, _ := utf8.DecodeLastRuneInString(amount[:len(amount)-3]); !unicode.IsLetter(previous) {
+			return amount[len(amount)-3:], strings.TrimSpace(amount[:len(amount)-3]), true
+		}
+	}
+	return "", amount, false
+}
+
+// isCurrencyCode reports whether a code is made of three uppercase ASCII letters, like "EUR".
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for i := range len(code) {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// cutAffix removes a text written before or after a value, and the whitespace around it.
+func cutAffix(value, affix string) (string, bool) {
+	if rest, ok := strings.CutPrefix(value, affix); ok {
+		return strings.TrimSpace(rest), true
+	}
+	if rest, ok := strings.CutSuffix(value, affix); ok {
+		return strings.TrimSpace(rest), true
+	}
+	return value, false
+}
+
+// cutNegative removes the parentheses or the sign of an amount, reporting whether it is negative.
+func cutNegative(amount string) (string, bool) {
+	if inner, ok := strings.CutPrefix(amount, "("); ok {
+		if inner, ok = strings.CutSuffix(inner, ")"); ok {
+			return strings.TrimSpace(inner), true
+		}
+	}
+	if rest, ok := strings.CutPrefix(amount, "-"); ok {
+		return strings.TrimSpace(rest), true
+	}
+	if rest, ok := strings.CutPrefix(amount, "+"); ok {
+		return strings.TrimSpace(rest), false
+	}
+	return amount, false
+}
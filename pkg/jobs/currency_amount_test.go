@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_parseCurrency(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		value    string
+		params   map[string]interface{}
+		want     string
+		wantCode string
+	}{
+		{name: "dollar", value: "$1,234.56", want: "1234.56", wantCode: "USD"},
+		{name: "trailing code", value: "1.234,56 EUR", want: "1234.56", wantCode: "EUR"},
+		{name: "pound", value: "£99", want: "99", wantCode: "GBP"},
+		{name: "euro symbol after", value: "12,50 €", want: "12.5", wantCode: "EUR"},
+		{name: "leading code", value: "CHF 1’234.50", params: map[string]interface{}{"locale": "de-CH"}, want: "1234.5", wantCode: "CHF"},
+		{name: "unknown code", value: "1 234,50 SEK", params: map[string]interface{}{"locale": "eu"}, want: "1234.5", wantCode: "SEK"},
+		{name: "lettered symbol", value: "R$ 10,00", want: "10", wantCode: "BRL"},
+		{name: "parentheses", value: "(123.45)", want: "-123.45"},
+		{name: "parentheses around amount", value: "($1,234.56)", want: "-1234.56", wantCode: "USD"},
+		{name: "parentheses after symbol", value: "€(5)", want: "-5", wantCode: "EUR"},
+		{name: "sign before symbol", value: "-$5.25", want: "-5.25", wantCode: "USD"},
+		{name: "sign after symbol", value: "$-5.25", want: "-5.25", wantCode: "USD"},
+		{name: "ambiguous symbol default", value: "$20", params: map[string]interface{}{"default_currency": "cad"}, want: "20", wantCode: "CAD"},
+		{name: "ambiguous yen default", value: "¥1,000", params: map[string]interface{}{"default_currency": "CNY"}, want: "1000", wantCode: "CNY"},
+		{name: "symbol-less default", value: "42.10", params: map[string]interface{}{"default_currency": "EUR"}, want: "42.1", wantCode: "EUR"},
+		{name: "symbol-less", value: "42.10", want: "42.1"},
+		{name: "explicit symbol over default", value: "£3", params: map[string]interface{}{"default_currency": "EUR"}, want: "3", wantCode: "GBP"},
+		{name: "precision", value: "$1,234.567", params: map[string]interface{}{"precision": 2}, want: "1234.57", wantCode: "USD"},
+		{name: "empty", value: "", want: ""},
+		{name: "not an amount kept", value: "free", want: "free"},
+		{name: "lowercase code kept", value: "12 eur", want: "12 eur"},
+		{name: "not an amount emptied", value: "$ n/a", params: map[string]interface{}{"on_error": "empty"}, want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			params := map[string]interface{}{"currency_target_field": "currency"}
+			for name, value := range tc.params {
+				params[name] = value
+			}
+			output, err := newTestTransformService(t).ProcessData(
+				[]DataRow{{Fields: map[string]string{"amount": tc.value}, Columns: []string{"amount"}}},
+				map[string]interface{}{"rules": []TransformRule{{Field: "amount", Operation: ParseCurrency, Parameters: params}}},
+			)
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["amount"])
+			is.Equal(tc.wantCode, output[0].Fields["currency"])
+			is.Equal([]string{"amount", "currency"}, output[0].Keys())
+		})
+	}
+}
+
+func TestTransformService_parseCurrencyTargets(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	output, err := newTestTransformService(t).ProcessData(
+		testRows(t, []string{"price"}, []string{"$1,234.56"}, []string{"1.234,56 EUR"}, []string{"oops"}),
+		map[string]interface{}{"chained": true, "rules": []TransformRule{
+			{Field: "price", Operation: ParseCurrency, TargetField: "amount", Parameters: map[string]interface{}{"currency_target_field": "currency"}},
+			{Field: "amount", Operation: Round, Parameters: map[string]interface{}{"precision": 1}},
+		}},
+	)
+	is.NoError(err)
+	is.Len(output, 3)
+	is.Equal([]string{"price", "amount", "currency"}, output[0].Keys())
+	is.Equal(map[string]string{"price": "$1,234.56", "amount": "1234.6", "currency": "USD"}, output[0].Fields)
+	is.Equal(map[string]string{"price": "1.234,56 EUR", "amount": "1234.6", "currency": "EUR"}, output[1].Fields)
+	is.Equal("", output[2].Fields["currency"])
+
+	output, err = newTestTransformService(t).ProcessData(
+		testRows(t, []string{"price"}, []string{"£5"}),
+		map[string]interface{}{"rules": []TransformRule{{Field: "price", Operation: ParseCurrency}}},
+	)
+	is.NoError(err)
+	is.Equal(map[string]string{"price": "5"}, output[0].Fields)
+}
+
+func TestTransformService_parseCurrencyErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for _, params := range []map[string]interface{}{
+		{"currency_target_field": ""},
+		{"currency_target_field": 1},
+		{"default_currency": "euro"},
+		{"default_currency": "$"},
+		{"locale": "tlh"},
+		{"precision": -2},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{{Field: "amount", Operation: ParseCurrency, Parameters: params}}})
+		is.ErrorContains(err, "parse_currency rule on field 'amount'", params)
+	}
+}
@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units of date arithmetic rules.
+var dateUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  0, // calendar months
+	"year":   0, // calendar years
+}
+
+// parseDateUnit parses the unit of a date arithmetic rule, singular or plural.
+func parseDateUnit(rule TransformRule, name string, defaultUnit string) (string, error) {
+	raw, _ := rule.Parameters[name].(string)
+	unit := strings.TrimSuffix(strings.ToLower(raw), "s")
+	if unit == "" {
+		return defaultUnit, nil
+	}
+	if _, ok := dateUnits[unit]; !ok {
+		return "", fmt.Errorf("invalid %s %q of %s rule on field '%s': expected seconds, minutes, hours, days, weeks, months or years", name, raw, rule.Operation, rule.Field)
+	}
+	return unit, nil
+}
+
+// dateShift adds an amount of time to dates for "date_add" rules. Its parameters are the
+// "amount", an integer, negative to subtract, and its "unit", days by default, plus the
+// parameters of format_date, dates being written in the layout they are read with unless an
+// "output_format" is given. Months and years are calendar ones, and a date past the end of the
+// target month is clamped to its last day, so January 31 plus a month is the last day of February.
+type dateShift struct {
+	format *dateFormat
+	amount int
+	unit   string
+}
+
+// parseDateShift parses the parameters of a "date_add" rule.
+func parseDateShift(rule TransformRule) (*dateShift, error) {
+	format, err := parseDateArithmeticFormat(rule)
+	if err != nil {
+		return nil, err
+	}
+	shift := &dateShift{format: format}
+
+	raw, ok := rule.Parameters["amount"]
+	if !ok {
+		return nil, fmt.Errorf("amount of date_add rule on field '%s' not specified", rule.Field)
+	}
+	if shift.amount, ok = toInt(raw); !ok {
+		return nil, fmt.Errorf("invalid amount %v of date_add rule on field '%s': expected an integer", raw, rule.Field)
+	}
+	if shift.unit, err = parseDateUnit(rule, "unit", "day"); err != nil {
+		return nil, err
+	}
+	return shift, nil
+}
+
+// parseDateArithmeticFormat parses the format_date parameters of a date arithmetic rule,
+// dates being written in the layout they are read with unless an output_format is given.
+func parseDateArithmeticFormat(rule TransformRule) (*dateFormat, error) {
+	format, err := parseDateFormat(rule)
+	if err != nil {
+		return nil, err
+	}
+	if output, _ := rule.Parameters["output_format"].(string); output == "" {
+		format.output = ""
+	}
+	return format, nil
+}
+
+// shift adds the amount of the rule to a date, reporting whether it parsed.
+func (d *dateShift) shift(value string) (string, bool) {
+	t, layout, ok := d.format.parse(value)
+	if !ok {
+		return "", false
+	}
+	return d.format.write(addDateUnits(t, d.amount, d.unit), layout), true
+}
+
+// addDateUnits adds an amount of a unit to a date, clamping calendar months to their last day.
+func addDateUnits(t time.Time, amount int, unit string) time.Time {
+	switch unit {
+	case "month":
+		return addMonths(t, amount)
+	case "year":
+		return addMonths(t, 12*amount)
+	case "day", "week":
+		// calendar days, which are not always 24 hours long across daylight saving changes
+		return t.AddDate(0, 0, amount*int(dateUnits[unit]/dateUnits["day"]))
+	default:
+		return t.Add(time.Duration(amount) * dateUnits[unit])
+	}
+}
+
+// addMonths adds months to a date, clamping its day to the last day of the target month.
+func addMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// dateDifference computes the time from dates to others for "date_diff" rules, as a whole
+// number of a "unit", days by default, truncated toward zero. The other date is read from an
+// "end_field" of the row, or is an "end" literal date or "now", the time of the TransformService
+// clock. Results are negative when the other date is before. Both dates are read with the
+// format_date parameters.
+type dateDifference struct {
+	format   *dateFormat
+	endField string
+	end      string
+	unit     string
+}
+
+// parseDateDifference parses the parameters of a "date_diff" rule.
+func parseDateDifference(rule TransformRule) (*dateDifference, error) {
+	format, err := parseDateFormat(rule)
+	if err != nil {
+		return nil, err
+	}
+	diff := &dateDifference{format: format}
+
+	diff.endField, _ = rule.Parameters["end_field"].(string)
+	diff.end, _ = rule.Parameters["end"].(string)
+	switch {
+	case diff.endField != "" && diff.end != "":
+		return nil, fmt.Errorf("date_diff rule on field '%s' has both an end and an end_field", rule.Field)
+	case diff.endField == "" && diff.end == "":
+		return nil, fmt.Errorf("date_diff rule on field '%s' needs an end date, \"now\", or an end_field", rule.Field)
+	case diff.end != "" && diff.end != "now":
+		if _, _, ok := format.parse(diff.end); !ok {
+			return nil, fmt.Errorf("invalid end %q of date_diff rule on field '%s': expected a date or \"now\"", diff.end, rule.Field)
+		}
+	}
+
+	if diff.unit, err = parseDateUnit(rule, "unit", "day"); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// diff returns the difference between a date and the end date, failing when either does not parse.
+func (d *dateDifference) diff(row DataRow, value string, now time.Time) (string, error) {
+	start, _, ok := d.format.parse(value)
+	if !ok {
+		return "", fmt.Errorf("value %q is not a date", value)
+	}
+
+	var end time.Time
+	switch {
+	case d.endField != "":
+		if end, _, ok = d.format.parse(row.Fields[d.endField]); !ok {
+			return "", fmt.Errorf("end value %q of field '%s' is not a date", row.Fields[d.endField], d.endField)
+		}
+	case d.end == "now":
+		end = now.In(d.format.location)
+	default:
+		if end, _, ok = d.format.parse(d.end); !ok {
+			return "", fmt.Errorf("end %q is not a date", d.end)
+		}
+	}
+	return strconv.Itoa(dateUnitsBetween(start, end, d.unit)), nil
+}
+
+// dateUnitsBetween returns the number of whole units from a date to another, negative when
+// the other is before.
+func dateUnitsBetween(start time.Time, end time.Time, unit string) int {
+	if end.Before(start) {
+		return -dateUnitsBetween(end, start, unit)
+	}
+
+	switch unit {
+	case "month", "year":
+		months := (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month())
+		if months > 0 && addMonths(start, months).After(end) {
+			months--
+		}
+		if unit == "year" {
+			return months / 12
+		}
+		return months
+	default:
+		return int(end.Sub(start) / dateUnits[unit])
+	}
+}
@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_dateAdd(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "days", value: "2024-01-15", params: map[string]interface{}{"amount": 30}, want: "2024-02-14"},
+		{name: "negative days", value: "2024-03-01", params: map[string]interface{}{"amount": -1, "unit": "day"}, want: "2024-02-29"},
+		{name: "hours", value: "2024-03-01T23:30:00Z", params: map[string]interface{}{"amount": 2, "unit": "hours"}, want: "2024-03-02T01:30:00Z"},
+		{name: "weeks", value: "2024-12-25", params: map[string]interface{}{"amount": 1, "unit": "weeks"}, want: "2025-01-01"},
+		{name: "end of month clamped", value: "2024-01-31", params: map[string]interface{}{"amount": 1, "unit": "months"}, want: "2024-02-29"},
+		{name: "end of month in common year", value: "2023-01-31", params: map[string]interface{}{"amount": 1, "unit": "month"}, want: "2023-02-28"},
+		{name: "months backwards", value: "2024-05-31", params: map[string]interface{}{"amount": -3, "unit": "months"}, want: "2024-02-29"},
+		{name: "leap day plus a year", value: "2024-02-29", params: map[string]interface{}{"amount": 1, "unit": "years"}, want: "2025-02-28"},
+		{name: "output format", value: "01/15/2024", params: map[string]interface{}{"amount": 30, "input_format": "MM/DD/YYYY", "output_format": "YYYY-MM-DD"}, want: "2024-02-14"},
+		{name: "epoch", value: "0", params: map[string]interface{}{"amount": 1, "input_format": "epoch"}, want: "86400"},
+		{name: "daylight saving", value: "2024-03-30 12:00", params: map[string]interface{}{"amount": 1, "location": "Europe/Paris"}, want: "2024-03-31 12:00"},
+		{name: "unparseable kept", value: "soon", params: map[string]interface{}{"amount": 1}, want: "soon"},
+		{name: "unparseable emptied", value: "soon", params: map[string]interface{}{"amount": 1, "on_error": "empty"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"date"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "date", Operation: DateAdd, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["date"])
+		})
+	}
+}
+
+func TestTransformService_dateDiff(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		start  string
+		end    string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "days", start: "2024-01-15", end: "2024-02-14", want: "30"},
+		{name: "negative days", start: "2024-02-14", end: "2024-01-15", want: "-30"},
+		{name: "partial day truncated", start: "2024-01-15T10:00:00Z", end: "2024-01-16T09:59:00Z", want: "0"},
+		{name: "hours", start: "2024-01-15T10:00:00Z", end: "2024-01-16T09:59:00Z", params: map[string]interface{}{"unit": "hours"}, want: "23"},
+		{name: "complete months", start: "2024-01-31", end: "2024-02-29", params: map[string]interface{}{"unit": "months"}, want: "1"},
+		{name: "incomplete month", start: "2024-01-15", end: "2024-02-14", params: map[string]interface{}{"unit": "months"}, want: "0"},
+		{name: "negative months", start: "2024-03-15", end: "2024-01-15", params: map[string]interface{}{"unit": "months"}, want: "-2"},
+		{name: "years", start: "1990-06-15", end: "2024-06-14", params: map[string]interface{}{"unit": "years"}, want: "33"},
+		{name: "missing end", start: "2024-01-15", end: "", want: "2024-01-15"},
+		{name: "unparseable start emptied", start: "n/a", end: "2024-01-15", params: map[string]interface{}{"on_error": "empty"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			params := map[string]interface{}{"end_field": "end"}
+			for name, value := range tc.params {
+				params[name] = value
+			}
+			input := testRows(t, []string{"start", "end"}, []string{tc.start, tc.end})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "start", Operation: DateDiff, Parameters: params, TargetField: "diff"}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["diff"])
+		})
+	}
+}
+
+func TestTransformService_dateDiffNow(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestTransformService(t)
+	service.SetClock(func() time.Time { return time.Date(2024, time.June, 1, 8, 0, 0, 0, time.UTC) })
+
+	input := testRows(t, []string{"signup_date"}, []string{"2024-05-01"}, []string{"2023-06-01"})
+	output, err := service.ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{
+			{Field: "signup_date", Operation: DateDiff, Parameters: map[string]interface{}{"end": "now"}, TargetField: "age_days"},
+			{Field: "signup_date", Operation: DateDiff, Parameters: map[string]interface{}{"end": "2025-01-01", "unit": "years"}, TargetField: "years"},
+		},
+	})
+	is.NoError(err)
+	is.Equal("31", output[0].Fields["age_days"])
+	is.Equal("366", output[1].Fields["age_days"])
+	is.Equal("0", output[0].Fields["years"])
+	is.Equal("1", output[1].Fields["years"])
+}
+
+func TestTransformService_dateArithmeticErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for _, rule := range []TransformRule{
+		{Operation: DateAdd},
+		{Operation: DateAdd, Parameters: map[string]interface{}{"amount": 1.5}},
+		{Operation: DateAdd, Parameters: map[string]interface{}{"amount": 1, "unit": "fortnights"}},
+		{Operation: DateAdd, Parameters: map[string]interface{}{"amount": 1, "location": "Mars/Olympus"}},
+		{Operation: DateDiff},
+		{Operation: DateDiff, Parameters: map[string]interface{}{"end": "now", "end_field": "end"}},
+		{Operation: DateDiff, Parameters: map[string]interface{}{"end": "tomorrow"}},
+		{Operation: DateDiff, Parameters: map[string]interface{}{"end": "now", "on_error": "drop"}},
+	} {
+		rule.Field = "date"
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		is.ErrorContains(err, string(rule.Operation)+" rule on field 'date'", rule.Parameters)
+	}
+}
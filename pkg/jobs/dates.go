@@ -0,0 +1,283 @@
+package jobs
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDateLayouts are the ISO-8601 style layouts tried when parsing dates,
+// shared by every operation that needs to understand date values.
+var DefaultDateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+// parseDate parses a date using the first matching layout.
+// DefaultDateLayouts are used when no layouts are given.
+func parseDate(value string, layouts []string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if len(layouts) == 0 {
+		layouts = DefaultDateLayouts
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// dateLayoutTokens translates friendly layout tokens, like YYYY-MM-DD, into Go layouts.
+// Go layouts never contain these tokens, so they can be translated unconditionally.
+var dateLayoutTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"DD", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+// normalizeDateLayout returns the Go layout of a layout, either a Go layout or friendly tokens.
+func normalizeDateLayout(layout string) string {
+	return dateLayoutTokens.Replace(layout)
+}
+
+// rawDateLayouts returns the layouts of a single layout or a list of them, as given.
+func rawDateLayouts(raw interface{}) []string {
+	switch layouts := raw.(type) {
+	case string:
+		if layouts != "" {
+			return []string{layouts}
+		}
+	case []string:
+		return layouts
+	case []interface{}:
+		var parsed []string
+		for _, layout := range layouts {
+			if layout, ok := layout.(string); ok && layout != "" {
+				parsed = append(parsed, layout)
+			}
+		}
+		return parsed
+	}
+	return nil
+}
+
+// parseDateLayouts parses a single date layout or a list of them, either typed or decoded
+// from generic JSON, as Go layouts or friendly tokens (YYYY, YY, MM, DD, HH, mm and ss).
+func parseDateLayouts(raw interface{}) []string {
+	layouts := rawDateLayouts(raw)
+	if len(layouts) == 0 {
+		return nil
+	}
+	parsed := make([]string, len(layouts))
+	for i, layout := range layouts {
+		parsed[i] = normalizeDateLayout(layout)
+	}
+	return parsed
+}
+
+// Layouts of dates written as Unix timestamps, which Go layouts cannot express.
+const (
+	epochLayout       = "epoch"    // seconds since 1970-01-01T00:00:00Z
+	epochMillisLayout = "epoch_ms" // milliseconds since 1970-01-01T00:00:00Z
+)
+
+// autoDateLayouts are the layouts format_date tries when it is given none: DefaultDateLayouts,
+// then US dates with slashes, month first, then dates with month names.
+var autoDateLayouts = append(slices.Clone(DefaultDateLayouts),
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/2006",
+	"2006/01/02",
+	"20060102",
+	time.RFC1123Z,
+	time.RFC1123,
+	"2 Jan 2006",
+	"02-Jan-2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+)
+
+// dateFormat converts dates from layouts to another one, for "format_date" transform rules,
+// and reads and writes the dates of date arithmetic rules.
+type dateFormat struct {
+	inputs   []string // Go layouts or epoch layouts, autoDateLayouts when empty
+	output   string   // Go layout or epoch layout, the layout a date parsed with when empty
+	location *time.Location
+}
+
+// parseDateFormat parses the parameters of a "format_date" rule: "input_format", one or more
+// Go layouts or friendly tokens like YYYY-MM-DD, "output_format", RFC 3339 by default,
+// and "location", the IANA time zone of dates without one and of outputs, UTC by default.
+// Formats may also be epoch or epoch_ms. Values that do not parse follow the on_error policy of the rules.
+func parseDateFormat(rule TransformRule) (*dateFormat, error) {
+	params, field := rule.Parameters, rule.Field
+	format := &dateFormat{output: time.RFC3339, location: time.UTC}
+	for _, layout := range rawDateLayouts(params["input_format"]) {
+		format.inputs = append(format.inputs, dateFormatLayout(layout))
+	}
+	if output, ok := params["output_format"].(string); ok && output != "" {
+		format.output = dateFormatLayout(output)
+	}
+
+	if name, ok := params["location"].(string); ok && name != "" {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid location %q of %s rule on field '%s': %w", name, rule.Operation, field, err)
+		}
+		format.location = location
+	}
+	return format, nil
+}
+
+// dateFormatLayout returns the Go layout of a format_date layout, epoch layouts as is.
+func dateFormatLayout(layout string) string {
+	if layout == epochLayout || layout == epochMillisLayout {
+		return layout
+	}
+	return normalizeDateLayout(layout)
+}
+
+// format converts a date to the output layout, reporting whether it parsed.
+func (f *dateFormat) format(value string) (string, bool) {
+	t, layout, ok := f.parse(value)
+	if !ok {
+		return "", false
+	}
+	return f.write(t, layout), true
+}
+
+// parse parses a date with the input layouts, returning it in the location of the format
+// with the layout it parsed with.
+func (f *dateFormat) parse(value string) (time.Time, string, bool) {
+	value = strings.TrimSpace(value)
+	layouts := f.inputs
+	if len(layouts) == 0 {
+		layouts = autoDateLayouts
+	}
+
+	for _, layout := range layouts {
+		var t time.Time
+		var err error
+		switch layout {
+		case epochLayout, epochMillisLayout:
+			var timestamp int64
+			if timestamp, err = strconv.ParseInt(value, 10, 64); err == nil {
+				t = time.Unix(timestamp, 0)
+				if layout == epochMillisLayout {
+					t = time.UnixMilli(timestamp)
+				}
+			}
+		default:
+			t, err = time.ParseInLocation(layout, value, f.location)
+		}
+		if err == nil {
+			return t.In(f.location), layout, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// write writes a date in the output layout, or in the layout it parsed with when the format has none.
+func (f *dateFormat) write(t time.Time, parsedLayout string) string {
+	output := f.output
+	if output == "" {
+		output = parsedLayout
+	}
+	t = t.In(f.location)
+	switch output {
+	case epochLayout:
+		return strconv.FormatInt(t.Unix(), 10)
+	case epochMillisLayout:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	default:
+		return t.Format(output)
+	}
+}
+
+// DateBucket is a period dates are truncated to, like a month.
+type DateBucket string
+
+const (
+	BucketHour    DateBucket = "hour"
+	BucketDay     DateBucket = "day"
+	BucketWeek    DateBucket = "week" // ISO week, starting on Monday
+	BucketMonth   DateBucket = "month"
+	BucketQuarter DateBucket = "quarter"
+	BucketYear    DateBucket = "year"
+)
+
+// ParseDateBucket validates a date bucket name.
+func ParseDateBucket(name string) (DateBucket, error) {
+	switch bucket := DateBucket(strings.ToLower(name)); bucket {
+	case BucketHour, BucketDay, BucketWeek, BucketMonth, BucketQuarter, BucketYear:
+		return bucket, nil
+	default:
+		return "", fmt.Errorf("unknown date bucket %q: expected hour, day, week, month, quarter or year", name)
+	}
+}
+
+// Truncate returns the start of the bucket containing t, in the location of t.
+func (b DateBucket) Truncate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	//nolint:exhaustive
+	switch b {
+	case BucketHour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+	case BucketWeek:
+		monday := day - (int(t.Weekday())+6)%7
+		return time.Date(year, month, monday, 0, 0, 0, 0, t.Location())
+	case BucketMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case BucketQuarter:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, t.Location())
+	case BucketYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Label returns the label of the bucket containing t. The start of the bucket is formatted
+// with layout when given, otherwise labels look like "2024-03-05T14", "2024-03-05", "2024-W10",
+// "2024-03", "2024-Q1" and "2024".
+func (b DateBucket) Label(t time.Time, layout string) string {
+	start := b.Truncate(t)
+	if layout != "" {
+		return start.Format(layout)
+	}
+
+	//nolint:exhaustive
+	switch b {
+	case BucketHour:
+		return start.Format("2006-01-02T15")
+	case BucketWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case BucketMonth:
+		return start.Format("2006-01")
+	case BucketQuarter:
+		return fmt.Sprintf("%04d-Q%d", start.Year(), (int(start.Month())+2)/3)
+	case BucketYear:
+		return start.Format("2006")
+	default:
+		return start.Format(time.DateOnly)
+	}
+}
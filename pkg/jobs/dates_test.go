@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateBucket_Label(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2024, time.December, 31, 14, 35, 0, 0, time.UTC) // a Tuesday of ISO week 2025-W01
+
+	testCases := []struct {
+		bucket   DateBucket
+		layout   string
+		expected string
+	}{
+		{bucket: BucketHour, expected: "2024-12-31T14"},
+		{bucket: BucketDay, expected: "2024-12-31"},
+		{bucket: BucketWeek, expected: "2025-W01"},
+		{bucket: BucketWeek, layout: time.DateOnly, expected: "2024-12-30"},
+		{bucket: BucketMonth, expected: "2024-12"},
+		{bucket: BucketMonth, layout: "Jan 2006", expected: "Dec 2024"},
+		{bucket: BucketQuarter, expected: "2024-Q4"},
+		{bucket: BucketQuarter, layout: time.DateOnly, expected: "2024-10-01"},
+		{bucket: BucketYear, expected: "2024"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.bucket)+tc.layout, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			is.Equal(tc.expected, tc.bucket.Label(date, tc.layout))
+		})
+	}
+}
+
+func TestParseDateBucket(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	bucket, err := ParseDateBucket("Month")
+	is.NoError(err)
+	is.Equal(BucketMonth, bucket)
+
+	_, err = ParseDateBucket("decade")
+	is.EqualError(err, `unknown date bucket "decade": expected hour, day, week, month, quarter or year`)
+}
+
+func TestParseDateLayouts(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	is.Equal([]string{"02/01/2006"}, parseDateLayouts("DD/MM/YYYY"))
+	is.Equal([]string{"2006-01-02 15:04:05", "02/01/06"}, parseDateLayouts([]interface{}{"YYYY-MM-DD HH:mm:ss", "02/01/06"}))
+	is.Equal([]string{"Jan 2, 2006"}, parseDateLayouts([]string{"Jan 2, 2006"}))
+	is.Nil(parseDateLayouts(""))
+	is.Nil(parseDateLayouts(nil))
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxDecimalExponent bounds the exponents of scientific notation, which would otherwise expand to huge numbers.
+const maxDecimalExponent = 1000
+
+// decimalNumber is a decimal number written as text, parsed exactly rather than as a float64,
+// so that long integers like 19-digit identifiers keep every digit.
+type decimalNumber struct {
+	value          *big.Rat
+	integerDigits  int // digits before the decimal point, leading zeros excluded
+	fractionDigits int // digits after the decimal point, trailing zeros included
+}
+
+// parseDecimal parses a plain decimal number, like -12.50 or .5, or one in scientific notation,
+// like 1.5e3, when scientific is set. Digits are counted once the exponent is applied.
+func parseDecimal(value string, scientific bool) (decimalNumber, bool) {
+	mantissa, exponent := value, 0
+	if i := strings.IndexAny(value, "eE"); i >= 0 {
+		exp, err := strconv.Atoi(value[i+1:])
+		if !scientific || err != nil || exp > maxDecimalExponent || exp < -maxDecimalExponent {
+			return decimalNumber{}, false
+		}
+		mantissa, exponent = value[:i], exp
+	}
+
+	digits := strings.TrimPrefix(strings.TrimPrefix(mantissa, "-"), "+")
+	if len(mantissa)-len(digits) > 1 {
+		return decimalNumber{}, false
+	}
+	integer, fraction, _ := strings.Cut(digits, ".")
+	if integer == "" && fraction == "" || !isDigits(integer) || !isDigits(fraction) {
+		return decimalNumber{}, false
+	}
+
+	number := decimalNumber{value: new(big.Rat)}
+	if _, ok := number.value.SetString(value); !ok {
+		return decimalNumber{}, false
+	}
+
+	number.fractionDigits = max(len(fraction)-exponent, 0)
+	if exponent != 0 {
+		// the exponent moves the decimal point
+		integer, _, _ = strings.Cut(number.value.FloatString(number.fractionDigits), ".")
+		integer = strings.TrimPrefix(integer, "-")
+	}
+	number.integerDigits = len(strings.TrimLeft(integer, "0"))
+	return number, true
+}
+
+// isDigits reports whether a value only has ASCII digits.
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value          string
+		scientific     bool
+		ok             bool
+		exact          string
+		integerDigits  int
+		fractionDigits int
+	}{
+		{"42", false, true, "42", 2, 0},
+		{"-12.50", false, true, "-12.50", 2, 2},
+		{"+0.5", false, true, "0.5", 0, 1},
+		{".5", false, true, "0.5", 0, 1},
+		{"5.", false, true, "5", 1, 0},
+		{"007", false, true, "7", 1, 0},
+		{"9223372036854775807", false, true, "9223372036854775807", 19, 0},
+		{"1234567890123456789", false, true, "1234567890123456789", 19, 0},
+		{"1.5e3", true, true, "1500", 4, 0},
+		{"-1.25E-1", true, true, "-0.125", 0, 3},
+		{"1.5e3", false, false, "", 0, 0},
+		{"1e99999", true, false, "", 0, 0},
+		{"1e", true, false, "", 0, 0},
+		{"", false, false, "", 0, 0},
+		{".", false, false, "", 0, 0},
+		{"-", false, false, "", 0, 0},
+		{"+-1", false, false, "", 0, 0},
+		{"1,5", false, false, "", 0, 0},
+		{" 1", false, false, "", 0, 0},
+		{"0x1F", false, false, "", 0, 0},
+		{"NaN", true, false, "", 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			number, ok := parseDecimal(tc.value, tc.scientific)
+			is.Equal(tc.ok, ok)
+			if !tc.ok {
+				return
+			}
+			is.Equal(tc.exact, number.value.FloatString(tc.fractionDigits))
+			is.Equal(tc.integerDigits, number.integerDigits)
+			is.Equal(tc.fractionDigits, number.fractionDigits)
+		})
+	}
+}
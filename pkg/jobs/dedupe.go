@@ -0,0 +1,259 @@
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/do/v2"
+)
+
+// Dedupe keep values.
+const (
+	DedupeKeepFirst = "first"
+	DedupeKeepLast  = "last"
+)
+
+// DedupeOptions contains deduplication configuration.
+type DedupeOptions struct {
+	InputFile   string        `json:"input_file"`
+	OutputFile  string        `json:"output_file"`
+	Keys        []string      `json:"keys,omitempty"`         // key fields, the entire row when empty
+	Keep        string        `json:"keep"`                   // "first" (default) or "last" occurrence
+	IgnoreCase  bool          `json:"ignore_case"`            // compare key values case-insensitively
+	RemovedFile string        `json:"removed_file,omitempty"` // where to write removed rows for audit
+	Output      OutputOptions `json:"output"`                 // format details of the written rows
+}
+
+// DedupeService handles duplicate removal operations
+// This service demonstrates row-level deduplication with dependency injection.
+type DedupeService struct {
+	fileService *FileService   `do:""`
+	logger      zerolog.Logger `do:""`
+}
+
+// NewDedupeService creates a new dedupe service with dependency injection.
+func NewDedupeService(i do.Injector) (*DedupeService, error) {
+	return &DedupeService{
+		fileService: do.MustInvoke[*FileService](i),
+		logger:      *do.MustInvoke[*zerolog.Logger](i),
+	}, nil
+}
+
+// ProcessData removes duplicate rows
+// This method demonstrates key-based deduplication logic.
+func (s *DedupeService) ProcessData(input []DataRow, options map[string]interface{}) ([]DataRow, error) {
+	kept, _, err := s.run(input, options)
+	return kept, err
+}
+
+// GetName returns the processor name.
+func (s *DedupeService) GetName() string {
+	return "dedupe"
+}
+
+// GetDescription returns the processor description.
+func (s *DedupeService) GetDescription() string {
+	return "Remove duplicate rows by key fields"
+}
+
+// run deduplicates the input, or the input file when input is empty, and writes the output files.
+// It returns the kept and the removed rows.
+func (s *DedupeService) run(input []DataRow, options map[string]interface{}) ([]DataRow, []DataRow, error) {
+	s.logger.Info().Msg("Removing duplicate rows")
+
+	// Parse options
+	opts, err := s.parseDedupeOptions(options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse dedupe options: %w", err)
+	}
+
+	// If input data is empty, try to read from file
+	if len(input) == 0 && opts.InputFile != "" {
+		var err error
+		input, err = s.fileService.ReadCSV(opts.InputFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+	}
+
+	kept, removed := s.dedupe(input, opts)
+
+	// Write results to file if output file specified
+	if opts.OutputFile != "" {
+		if err := s.fileService.WriteRows(opts.OutputFile, kept, opts.Output); err != nil {
+			return nil, nil, fmt.Errorf("failed to write deduplicated data: %w", err)
+		}
+	}
+
+	if opts.RemovedFile != "" {
+		if err := s.fileService.WriteRows(opts.RemovedFile, removed, opts.Output); err != nil {
+			return nil, nil, fmt.Errorf("failed to write removed rows: %w", err)
+		}
+	}
+
+	s.logger.Info().
+		Int("input_records", len(input)).
+		Int("output_records", len(kept)).
+		Int("duplicates", len(removed)).
+		Strs("keys", opts.Keys).
+		Msg("Deduplication completed")
+
+	return kept, removed, nil
+}
+
+// parseDedupeOptions parses dedupe options from map.
+func (s *DedupeService) parseDedupeOptions(options map[string]interface{}) (*DedupeOptions, error) {
+	opts := &DedupeOptions{
+		Keep: DedupeKeepFirst,
+	}
+
+	if inputFile, ok := options["input_file"].(string); ok {
+		opts.InputFile = inputFile
+	}
+
+	if outputFile, ok := options["output_file"].(string); ok {
+		opts.OutputFile = outputFile
+	}
+
+	if removedFile, ok := options["removed_file"].(string); ok {
+		opts.RemovedFile = removedFile
+	}
+
+	if keep, ok := options["keep"].(string); ok && keep != "" {
+		opts.Keep = strings.ToLower(keep)
+	}
+	if opts.Keep != DedupeKeepFirst && opts.Keep != DedupeKeepLast {
+		return nil, fmt.Errorf("unknown keep value %q: expected %q or %q", opts.Keep, DedupeKeepFirst, DedupeKeepLast)
+	}
+
+	if ignoreCase, ok := options["ignore_case"].(bool); ok {
+		opts.IgnoreCase = ignoreCase
+	}
+
+	// Parse key fields, either typed or decoded from generic JSON
+	switch keys := options["keys"].(type) {
+	case []string:
+		opts.Keys = keys
+	case []interface{}:
+		for _, key := range keys {
+			if name, ok := key.(string); ok {
+				opts.Keys = append(opts.Keys, name)
+			}
+		}
+	}
+
+	outputOpts, err := parseOutputOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	opts.Output = outputOpts
+
+	return opts, nil
+}
+
+// dedupe splits rows into kept and removed rows, both in input order.
+func (s *DedupeService) dedupe(rows []DataRow, opts *DedupeOptions) ([]DataRow, []DataRow) {
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		keys[i] = s.rowKey(row, opts)
+	}
+
+	// Index of the occurrence to keep for every key
+	keepIndex := make(map[string]int, len(rows))
+	for i, key := range keys {
+		if _, seen := keepIndex[key]; !seen || opts.Keep == DedupeKeepLast {
+			keepIndex[key] = i
+		}
+	}
+
+	var kept, removed []DataRow
+	for i, row := range rows {
+		if keepIndex[keys[i]] == i {
+			kept = append(kept, row)
+		} else {
+			s.logger.Debug().Str("location", row.Location()).Msg("Duplicate row removed")
+			removed = append(removed, row)
+		}
+	}
+
+	return kept, removed
+}
+
+// rowKey builds the deduplication key of a row. Values are length-prefixed so that
+// no combination of values can collide with another, and missing fields differ from empty ones.
+func (s *DedupeService) rowKey(row DataRow, opts *DedupeOptions) string {
+	fields := opts.Keys
+	if len(fields) == 0 {
+		fields = make([]string, 0, len(row.Fields))
+		for field := range row.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+
+	var key strings.Builder
+	for _, field := range fields {
+		if len(opts.Keys) == 0 {
+			writeKeyPart(&key, field)
+		}
+
+		value, exists := row.Fields[field]
+		if !exists {
+			key.WriteString("-;")
+			continue
+		}
+		if opts.IgnoreCase {
+			value = strings.ToLower(value)
+		}
+		writeKeyPart(&key, value)
+	}
+
+	return key.String()
+}
+
+// writeKeyPart appends a length-prefixed value to a key.
+func writeKeyPart(key *strings.Builder, value string) {
+	key.WriteString(strconv.Itoa(len(value)))
+	key.WriteByte(':')
+	key.WriteString(value)
+}
+
+// DedupeFile removes duplicate rows from a file
+// This convenience method demonstrates file-based deduplication.
+// extraOptions may carry any additional ProcessData option and can be nil.
+func (s *DedupeService) DedupeFile(inputFile, outputFile string, keys []string, keep string, extraOptions map[string]interface{}) (*ProcessingResult, error) {
+	s.logger.Info().
+		Str("input", inputFile).
+		Str("output", outputFile).
+		Strs("keys", keys).
+		Str("keep", keep).
+		Msg("Starting file deduplication")
+
+	options := mergeOptions(map[string]interface{}{
+		"input_file":  inputFile,
+		"output_file": outputFile,
+		"keys":        keys,
+		"keep":        keep,
+	}, extraOptions)
+
+	kept, removed, err := s.run(nil, options)
+	if err != nil {
+		return &ProcessingResult{
+			Success:   false,
+			Processed: 0,
+			Processor: s.GetName(),
+			Errors:    []string{err.Error()},
+		}, err
+	}
+
+	return &ProcessingResult{
+		Success:    true,
+		Processed:  len(kept),
+		Duplicates: len(removed),
+		OutputPath: outputFile,
+		Processor:  s.GetName(),
+	}, nil
+}
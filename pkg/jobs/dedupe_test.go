@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDedupeService(t *testing.T) *DedupeService {
+	t.Helper()
+
+	return &DedupeService{
+		fileService: newTestFileService(t),
+		logger:      zerolog.Nop(),
+	}
+}
+
+func TestDedupeService_ProcessData(t *testing.T) {
+	t.Parallel()
+
+	columns := []string{"name", "email", "city"}
+	rows := testRows(t, columns,
+		[]string{"ann", "ann@example.com", "Paris"},
+		[]string{"bob", "bob@example.com", "Lyon"},
+		[]string{"ann2", "ANN@example.com", "Paris"},
+		[]string{"cid", "cid@example.com", "Lyon"},
+		[]string{"bob", "bob@example.com", "Lyon"},
+	)
+
+	testCases := []struct {
+		name     string
+		options  map[string]interface{}
+		expected []string
+	}{
+		{"entire row", map[string]interface{}{}, []string{"ann", "bob", "ann2", "cid"}},
+		{"key keep first", map[string]interface{}{"keys": []string{"city"}}, []string{"ann", "bob"}},
+		{"key keep last", map[string]interface{}{"keys": []interface{}{"city"}, "keep": "last"}, []string{"ann2", "bob"}},
+		{"case-sensitive keys", map[string]interface{}{"keys": []string{"email"}}, []string{"ann", "bob", "ann2", "cid"}},
+		{"case-insensitive keys", map[string]interface{}{"keys": []string{"email"}, "ignore_case": true}, []string{"ann", "bob", "cid"}},
+		{"several keys", map[string]interface{}{"keys": []string{"name", "city"}}, []string{"ann", "bob", "ann2", "cid"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestDedupeService(t).ProcessData(rows, tc.options)
+			is.NoError(err)
+			is.Equal(tc.expected, filteredNames(output))
+		})
+	}
+}
+
+func TestDedupeService_keyParts(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	// values must not collide when concatenated, and missing differs from empty
+	rows := []DataRow{
+		{Fields: map[string]string{"name": "a", "x": "b", "y": "c"}},
+		{Fields: map[string]string{"name": "ab", "x": "", "y": "c"}},
+		{Fields: map[string]string{"name": "ab", "y": "c"}},
+	}
+	output, err := newTestDedupeService(t).ProcessData(rows, map[string]interface{}{"keys": []string{"name", "x"}})
+	is.NoError(err)
+	is.Len(output, 3)
+
+	_, err = newTestDedupeService(t).ProcessData(rows, map[string]interface{}{"keep": "middle"})
+	is.Error(err)
+}
+
+func TestDedupeService_DedupeFile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("id,name\n1,ann\n2,bob\n1,ann bis\n"), 0o600))
+
+	removed := filepath.Join(dir, "removed.csv")
+	result, err := newTestDedupeService(t).DedupeFile(input, filepath.Join(dir, "out.csv"), []string{"id"}, DedupeKeepFirst,
+		map[string]interface{}{"removed_file": removed})
+	is.NoError(err)
+	is.Equal(2, result.Processed)
+	is.Equal(1, result.Duplicates)
+
+	content, err := os.ReadFile(removed)
+	is.NoError(err)
+	is.Equal("id,name\n1,ann bis\n", string(content))
+}
@@ -0,0 +1,559 @@
+package jobs
+
+import "strings"
+
+// metaphoneCodes are the primary and alternate codes of a Double Metaphone encoding, up to their
+// maximum length.
+type metaphoneCodes struct {
+	primary   strings.Builder
+	alternate strings.Builder
+	maxLength int
+}
+
+// add appends to both codes.
+func (c *metaphoneCodes) add(code string) {
+	c.addBoth(code, code)
+}
+
+// addBoth appends to the primary and the alternate codes.
+func (c *metaphoneCodes) addBoth(primary, alternate string) {
+	c.addPrimary(primary)
+	c.addAlternate(alternate)
+}
+
+// addPrimary appends to the primary code only.
+func (c *metaphoneCodes) addPrimary(code string) {
+	if room := c.maxLength - c.primary.Len(); room > 0 {
+		c.primary.WriteString(code[:min(room, len(code))])
+	}
+}
+
+// addAlternate appends to the alternate code only.
+func (c *metaphoneCodes) addAlternate(code string) {
+	if room := c.maxLength - c.alternate.Len(); room > 0 {
+		c.alternate.WriteString(code[:min(room, len(code))])
+	}
+}
+
+// complete reports whether both codes reached their maximum length.
+func (c *metaphoneCodes) complete() bool {
+	return c.primary.Len() >= c.maxLength && c.alternate.Len() >= c.maxLength
+}
+
+// metaphoneWord is the uppercase text a Double Metaphone encoding reads.
+type metaphoneWord string
+
+// at returns the letter at an index, 0 out of the word.
+func (w metaphoneWord) at(index int) byte {
+	if index < 0 || index >= len(w) {
+		return 0
+	}
+	return w[index]
+}
+
+// has reports whether the word has one of the texts at an index, all of the same length.
+func (w metaphoneWord) has(index int, texts ...string) bool {
+	if index < 0 {
+		return false
+	}
+	for _, text := range texts {
+		if strings.HasPrefix(string(w[min(index, len(w)):]), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// vowel reports whether the letter at an index is a vowel.
+func (w metaphoneWord) vowel(index int) bool {
+	return strings.IndexByte("AEIOUY", w.at(index)) >= 0
+}
+
+// doubleMetaphone returns the primary and alternate Double Metaphone codes of uppercase letters,
+// words being separated by spaces, as described by Lawrence Philips in 2000.
+//
+// The letter cases and their conditions follow the order of Philips' reference implementation,
+// so that they can be checked against it, rather than being split to read shorter.
+//
+//nolint:gocyclo,funlen
+func doubleMetaphone(letters string, maxLength int) (string, string) {
+	w := metaphoneWord(letters)
+	codes := &metaphoneCodes{maxLength: maxLength}
+	slavoGermanic := strings.Contains(letters, "W") || strings.Contains(letters, "K") ||
+		strings.Contains(letters, "CZ") || strings.Contains(letters, "WITZ")
+	last := len(w) - 1
+
+	index := 0
+	if w.has(0, "GN", "KN", "PN", "WR", "PS") {
+		// silent first letter
+		index = 1
+	}
+
+	for !codes.complete() && index <= last {
+		switch w.at(index) {
+		case 'A', 'E', 'I', 'O', 'U', 'Y':
+			if index == 0 {
+				codes.add("A")
+			}
+			index++
+		case 'B':
+			codes.add("P")
+			index += skipDouble(w, index, "B")
+		case 'C':
+			index = metaphoneC(w, codes, index)
+		case 'D':
+			switch {
+			case w.has(index, "DG") && w.has(index+2, "I", "E", "Y"):
+				// "edge"
+				codes.add("J")
+				index += 3
+			case w.has(index, "DG"):
+				// "edgar"
+				codes.add("TK")
+				index += 2
+			case w.has(index, "DT", "DD"):
+				codes.add("T")
+				index += 2
+			default:
+				codes.add("T")
+				index++
+			}
+		case 'F':
+			codes.add("F")
+			index += skipDouble(w, index, "F")
+		case 'G':
+			index = metaphoneG(w, codes, index, slavoGermanic)
+		case 'H':
+			// kept first or between vowels, also skipping "HH"
+			if (index == 0 || w.vowel(index-1)) && w.vowel(index+1) {
+				codes.add("H")
+				index += 2
+			} else {
+				index++
+			}
+		case 'J':
+			index = metaphoneJ(w, codes, index, slavoGermanic)
+		case 'K':
+			codes.add("K")
+			index += skipDouble(w, index, "K")
+		case 'L':
+			if w.at(index+1) == 'L' {
+				if metaphoneSpanishLL(w, index) {
+					// "cabrillo", "gallegos"
+					codes.addPrimary("L")
+				} else {
+					codes.add("L")
+				}
+				index += 2
+			} else {
+				codes.add("L")
+				index++
+			}
+		case 'M':
+			codes.add("M")
+			if w.at(index+1) == 'M' || w.has(index-1, "UMB") && (index+1 == last || w.has(index+2, "ER")) {
+				// "dumb", "thumb"
+				index += 2
+			} else {
+				index++
+			}
+		case 'N':
+			codes.add("N")
+			index += skipDouble(w, index, "N")
+		case 'P':
+			if w.at(index+1) == 'H' {
+				codes.add("F")
+				index += 2
+			} else {
+				// "campbell", "raspberry"
+				codes.add("P")
+				index += skipDouble(w, index, "P", "B")
+			}
+		case 'Q':
+			codes.add("K")
+			index += skipDouble(w, index, "Q")
+		case 'R':
+			if index == last && !slavoGermanic && w.has(index-2, "IE") && !w.has(index-4, "ME", "MA") {
+				// French "rogier", but not "hochmeier"
+				codes.addAlternate("R")
+			} else {
+				codes.add("R")
+			}
+			index += skipDouble(w, index, "R")
+		case 'S':
+			index = metaphoneS(w, codes, index, slavoGermanic)
+		case 'T':
+			index = metaphoneT(w, codes, index)
+		case 'V':
+			codes.add("F")
+			index += skipDouble(w, index, "V")
+		case 'W':
+			index = metaphoneW(w, codes, index)
+		case 'X':
+			if index == 0 {
+				codes.add("S")
+				index++
+				break
+			}
+			if index != last || !w.has(index-3, "IAU", "EAU") && !w.has(index-2, "AU", "OU") {
+				// but French "breaux"
+				codes.add("KS")
+			}
+			index += skipDouble(w, index, "C", "X")
+		case 'Z':
+			if w.at(index+1) == 'H' {
+				// Chinese pinyin "zhao"
+				codes.add("J")
+				index += 2
+				break
+			}
+			if w.has(index+1, "ZO", "ZI", "ZA") || slavoGermanic && index > 0 && w.at(index-1) != 'T' {
+				codes.addBoth("S", "TS")
+			} else {
+				codes.add("S")
+			}
+			index += skipDouble(w, index, "Z")
+		default:
+			index++
+		}
+	}
+	return codes.primary.String(), codes.alternate.String()
+}
+
+// skipDouble returns how many letters to move past a letter, 2 when one of the letters follows it.
+func skipDouble(w metaphoneWord, index int, letters ...string) int {
+	if w.has(index+1, letters...) {
+		return 2
+	}
+	return 1
+}
+
+// metaphoneC encodes a C, returning the index of the next letter.
+// Its cases follow the reference implementation, see doubleMetaphone.
+//
+//nolint:gocyclo
+func metaphoneC(w metaphoneWord, codes *metaphoneCodes, index int) int {
+	switch {
+	case metaphoneGermanicACH(w, index):
+		// "bacher", "macher"
+		codes.add("K")
+		return index + 2
+	case index == 0 && w.has(index, "CAESAR"):
+		codes.add("S")
+		return index + 2
+	case w.has(index, "CH"):
+		return metaphoneCH(w, codes, index)
+	case w.has(index, "CZ") && !w.has(index-2, "WICZ"):
+		// "czerny"
+		codes.addBoth("S", "X")
+		return index + 2
+	case w.has(index+1, "CIA"):
+		// "focaccia"
+		codes.add("X")
+		return index + 3
+	case w.has(index, "CC") && !(index == 1 && w.at(0) == 'M'):
+		// double "cc", but not "mcclelland"
+		if w.has(index+2, "I", "E", "H") && !w.has(index+2, "HU") {
+			if index == 1 && w.at(0) == 'A' || w.has(index-1, "UCCEE", "UCCES") {
+				// "accident", "accede", "succeed"
+				codes.add("KS")
+			} else {
+				// "bacci", "bertucci"
+				codes.add("X")
+			}
+			return index + 3
+		}
+		// Pierce's rule
+		codes.add("K")
+		return index + 2
+	case w.has(index, "CK", "CG", "CQ"):
+		codes.add("K")
+		return index + 2
+	case w.has(index, "CI", "CE", "CY"):
+		// Italian against English
+		if w.has(index, "CIO", "CIE", "CIA") {
+			codes.addBoth("S", "X")
+		} else {
+			codes.add("S")
+		}
+		return index + 2
+	}
+
+	codes.add("K")
+	switch {
+	case w.has(index+1, " C", " Q", " G"):
+		// "mac caffrey", "mac gregor"
+		return index + 3
+	case w.has(index+1, "C", "K", "Q") && !w.has(index+1, "CE", "CI"):
+		return index + 2
+	default:
+		return index + 1
+	}
+}
+
+// metaphoneGermanicACH reports whether the C at an index sounds as a K in a Germanic "ach".
+func metaphoneGermanicACH(w metaphoneWord, index int) bool {
+	switch {
+	case w.has(index, "CHIA"):
+		return true
+	case index <= 1, w.vowel(index - 2), !w.has(index-1, "ACH"):
+		return false
+	}
+	next := w.at(index + 2)
+	return next != 'I' && next != 'E' || w.has(index-2, "BACHER", "MACHER")
+}
+
+// metaphoneCH encodes a "CH", returning the index of the next letter.
+// Its cases follow the reference implementation, see doubleMetaphone.
+//
+//nolint:gocyclo
+func metaphoneCH(w metaphoneWord, codes *metaphoneCodes, index int) int {
+	switch {
+	case index > 0 && w.has(index, "CHAE"):
+		// "michael"
+		codes.addBoth("K", "X")
+	case index == 0 && (w.has(index+1, "HARAC", "HARIS") || w.has(index+1, "HOR", "HYM", "HIA", "HEM")) && !w.has(0, "CHORE"):
+		// Greek roots, "chemistry", "chorus"
+		codes.add("K")
+	case w.has(0, "VAN ", "VON ") || w.has(0, "SCH") ||
+		w.has(index-2, "ORCHES", "ARCHIT", "ORCHID") ||
+		w.has(index+2, "T", "S") ||
+		(w.has(index-1, "A", "O", "U", "E") || index == 0) &&
+			(w.has(index+2, "L", "R", "N", "M", "B", "H", "F", "V", "W", " ") || index+1 == len(w)-1):
+		// Germanic, Greek or otherwise "kh" sound, "architect", "orchestra"
+		codes.add("K")
+	case index > 0 && w.has(0, "MC"):
+		// "mchugh"
+		codes.add("K")
+	case index > 0:
+		codes.addBoth("X", "K")
+	default:
+		codes.add("X")
+	}
+	return index + 2
+}
+
+// metaphoneG encodes a G, returning the index of the next letter.
+// Its cases follow the reference implementation, see doubleMetaphone.
+//
+//nolint:gocyclo
+func metaphoneG(w metaphoneWord, codes *metaphoneCodes, index int, slavoGermanic bool) int {
+	switch {
+	case w.at(index+1) == 'H':
+		return metaphoneGH(w, codes, index)
+	case w.at(index+1) == 'N':
+		switch {
+		case index == 1 && w.vowel(0) && !slavoGermanic:
+			codes.addBoth("KN", "N")
+		case !w.has(index+2, "EY") && w.at(index+1) != 'Y' && !slavoGermanic:
+			// not "cagney"
+			codes.addBoth("N", "KN")
+		default:
+			codes.add("KN")
+		}
+		return index + 2
+	case w.has(index+1, "LI") && !slavoGermanic:
+		// "tagliaro"
+		codes.addBoth("KL", "L")
+		return index + 2
+	case index == 0 && (w.at(index+1) == 'Y' || w.has(index+1, "ES", "EP", "EB", "EL", "EY", "IB", "IL", "IN", "IE", "EI", "ER")):
+		// "-ges-", "-gep-", "-gel-", "-gie-" at the beginning
+		codes.addBoth("K", "J")
+		return index + 2
+	case (w.has(index+1, "ER") || w.at(index+1) == 'Y') && !w.has(0, "DANGER", "RANGER", "MANGER") &&
+		!w.has(index-1, "E", "I") && !w.has(index-1, "RGY", "OGY"):
+		// "-ger-", "-gy-"
+		codes.addBoth("K", "J")
+		return index + 2
+	case w.has(index+1, "E", "I", "Y") || w.has(index-1, "AGGI", "OGGI"):
+		// Italian "biaggi"
+		switch {
+		case w.has(0, "VAN ", "VON ") || w.has(0, "SCH") || w.has(index+1, "ET"):
+			// obviously Germanic
+			codes.add("K")
+		case w.has(index+1, "IER"):
+			codes.add("J")
+		default:
+			codes.addBoth("J", "K")
+		}
+		return index + 2
+	case w.at(index+1) == 'G':
+		codes.add("K")
+		return index + 2
+	default:
+		codes.add("K")
+		return index + 1
+	}
+}
+
+// metaphoneGH encodes a "GH", returning the index of the next letter.
+func metaphoneGH(w metaphoneWord, codes *metaphoneCodes, index int) int {
+	switch {
+	case index > 0 && !w.vowel(index-1):
+		codes.add("K")
+	case index == 0:
+		// "ghislane", "ghiradelli"
+		if w.at(index+2) == 'I' {
+			codes.add("J")
+		} else {
+			codes.add("K")
+		}
+	case index > 1 && w.has(index-2, "B", "H", "D") ||
+		index > 2 && w.has(index-3, "B", "H", "D") ||
+		index > 3 && w.has(index-4, "B", "H"):
+		// Parker's rule, "hugh", "bough", "broughton"
+	case index > 2 && w.at(index-1) == 'U' && w.has(index-3, "C", "G", "L", "R", "T"):
+		// "laugh", "mclaughlin", "cough", "rough", "tough"
+		codes.add("F")
+	case w.at(index-1) != 'I':
+		codes.add("K")
+	}
+	return index + 2
+}
+
+// metaphoneJ encodes a J, returning the index of the next letter.
+func metaphoneJ(w metaphoneWord, codes *metaphoneCodes, index int, slavoGermanic bool) int {
+	if w.has(index, "JOSE") || w.has(0, "SAN ") {
+		// obviously Spanish, "jose", "san jacinto"
+		if index == 0 && w.at(index+4) == ' ' || len(w) == 4 || w.has(0, "SAN ") {
+			codes.add("H")
+		} else {
+			codes.addBoth("J", "H")
+		}
+		return index + 1
+	}
+
+	switch {
+	case index == 0:
+		// "yankelovich", "jankelowicz"
+		codes.addBoth("J", "A")
+	case w.vowel(index-1) && !slavoGermanic && (w.at(index+1) == 'A' || w.at(index+1) == 'O'):
+		// Spanish pronunciation of "bajador"
+		codes.addBoth("J", "H")
+	case index == len(w)-1:
+		codes.addPrimary("J")
+	case !w.has(index+1, "L", "T", "K", "S", "N", "M", "B", "Z") && !w.has(index-1, "S", "K", "L"):
+		codes.add("J")
+	}
+	return index + skipDouble(w, index, "J")
+}
+
+// metaphoneSpanishLL reports whether the "LL" at an index is Spanish, like in "cabrillo".
+func metaphoneSpanishLL(w metaphoneWord, index int) bool {
+	last := len(w) - 1
+	if index == last-2 && w.has(index-1, "ILLO", "ILLA", "ALLE") {
+		return true
+	}
+	return (w.has(last-1, "AS", "OS") || w.has(last, "A", "O")) && w.has(index-1, "ALLE")
+}
+
+// metaphoneS encodes an S, returning the index of the next letter.
+func metaphoneS(w metaphoneWord, codes *metaphoneCodes, index int, slavoGermanic bool) int {
+	switch {
+	case w.has(index-1, "ISL", "YSL"):
+		// "island", "isle", "carlisle", "carlysle"
+		return index + 1
+	case index == 0 && w.has(index, "SUGAR"):
+		codes.addBoth("X", "S")
+		return index + 1
+	case w.has(index, "SH"):
+		if w.has(index+1, "HEIM", "HOEK", "HOLM", "HOLZ") {
+			// Germanic
+			codes.add("S")
+		} else {
+			codes.add("X")
+		}
+		return index + 2
+	case w.has(index, "SIO", "SIA") || w.has(index, "SIAN"):
+		// Italian and Armenian
+		if slavoGermanic {
+			codes.add("S")
+		} else {
+			codes.addBoth("S", "X")
+		}
+		return index + 3
+	case index == 0 && w.has(index+1, "M", "N", "L", "W") || w.has(index+1, "Z"):
+		// Germanic and anglicisations, "smith" matching "schmidt", "snider" matching "schneider"
+		codes.addBoth("S", "X")
+		return index + skipDouble(w, index, "Z")
+	case w.has(index, "SC"):
+		return metaphoneSC(w, codes, index)
+	}
+
+	if index == len(w)-1 && w.has(index-2, "AI", "OI") {
+		// French "resnais", "artois"
+		codes.addAlternate("S")
+	} else {
+		codes.add("S")
+	}
+	return index + skipDouble(w, index, "S", "Z")
+}
+
+// metaphoneSC encodes an "SC", returning the index of the next letter.
+func metaphoneSC(w metaphoneWord, codes *metaphoneCodes, index int) int {
+	switch {
+	case w.at(index+2) == 'H':
+		// Schlesinger's rule
+		switch {
+		case w.has(index+3, "ER", "EN"):
+			// Dutch "schermerhorn", "schenker"
+			codes.addBoth("X", "SK")
+		case w.has(index+3, "OO", "UY", "ED", "EM"):
+			// Dutch "school", "schooner"
+			codes.add("SK")
+		case index == 0 && !w.vowel(3) && w.at(3) != 'W':
+			codes.addBoth("X", "S")
+		default:
+			codes.add("X")
+		}
+	case w.has(index+2, "I", "E", "Y"):
+		codes.add("S")
+	default:
+		codes.add("SK")
+	}
+	return index + 3
+}
+
+// metaphoneT encodes a T, returning the index of the next letter.
+func metaphoneT(w metaphoneWord, codes *metaphoneCodes, index int) int {
+	switch {
+	case w.has(index, "TION"), w.has(index, "TIA", "TCH"):
+		codes.add("X")
+		return index + 3
+	case w.has(index, "TH") || w.has(index, "TTH"):
+		if w.has(index+2, "OM", "AM") || w.has(0, "VAN ", "VON ") || w.has(0, "SCH") {
+			// "thomas", "thames" or Germanic
+			codes.add("T")
+		} else {
+			codes.addBoth("0", "T")
+		}
+		return index + 2
+	default:
+		codes.add("T")
+		return index + skipDouble(w, index, "T", "D")
+	}
+}
+
+// metaphoneW encodes a W, returning the index of the next letter.
+func metaphoneW(w metaphoneWord, codes *metaphoneCodes, index int) int {
+	switch {
+	case w.has(index, "WR"):
+		codes.add("R")
+		return index + 2
+	case index == 0 && w.vowel(index+1):
+		// "wasserman" matching "vasserman"
+		codes.addBoth("A", "F")
+	case index == 0 && w.has(index, "WH"):
+		// "uomo" matching "womo"
+		codes.add("A")
+	case index == len(w)-1 && w.vowel(index-1) ||
+		w.has(index-1, "EWSKI", "EWSKY", "OWSKI", "OWSKY") || w.has(0, "SCH"):
+		// "arnow" matching "arnoff"
+		codes.addAlternate("F")
+	case w.has(index, "WICZ", "WITZ"):
+		// Polish "filipowicz"
+		codes.addBoth("TS", "FX")
+		return index + 4
+	}
+	return index + 1
+}
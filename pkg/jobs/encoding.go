@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Policies of decoding rules for decoded bytes that are not valid UTF-8.
+const (
+	InvalidUTF8Replace = "replace" // replace invalid bytes with U+FFFD, the default
+	InvalidUTF8Hex     = "hex"     // write the bytes in hex instead
+	InvalidUTF8Empty   = "empty"   // empty the value
+)
+
+// byteCodec encodes and decodes values for "base64_encode", "base64_decode", "hex_encode" and
+// "hex_decode" rules. Base64 rules take "url_safe", for the URL alphabet, and "padding", true
+// by default; decoding also accepts values without padding. Decoding rules take "invalid_utf8",
+// one of the InvalidUTF8 policies, values that do not decode following their on_error policy.
+// Like other rules, the rules after them read the decoded values.
+type byteCodec struct {
+	operation   TransformOperation
+	urlSafe     bool
+	padding     bool
+	invalidUTF8 string
+}
+
+// parseByteCodec parses the parameters of an encoding or decoding rule.
+func parseByteCodec(rule TransformRule) (*byteCodec, error) {
+	codec := &byteCodec{operation: rule.Operation, padding: true, invalidUTF8: InvalidUTF8Replace}
+	for name, flag := range map[string]*bool{"url_safe": &codec.urlSafe, "padding": &codec.padding} {
+		value, ok := rule.Parameters[name]
+		if !ok {
+			continue
+		}
+		if *flag, ok = value.(bool); !ok {
+			return nil, fmt.Errorf("invalid %s of %s rule on field '%s': expected true or false", name, rule.Operation, rule.Field)
+		}
+	}
+
+	switch invalid, _ := rule.Parameters["invalid_utf8"].(string); invalid {
+	case "":
+	case InvalidUTF8Replace, InvalidUTF8Hex, InvalidUTF8Empty:
+		codec.invalidUTF8 = invalid
+	default:
+		return nil, fmt.Errorf("invalid invalid_utf8 %q of %s rule on field '%s': expected replace, hex or empty", invalid, rule.Operation, rule.Field)
+	}
+	return codec, nil
+}
+
+// encoding returns the base64 encoding of the codec.
+func (c *byteCodec) encoding() *base64.Encoding {
+	encoding := base64.StdEncoding
+	if c.urlSafe {
+		encoding = base64.URLEncoding
+	}
+	if !c.padding {
+		encoding = encoding.WithPadding(base64.NoPadding)
+	}
+	return encoding
+}
+
+// apply encodes or decodes a value, failing with an untransformableError when it does not decode.
+func (c *byteCodec) apply(value string, field string) (string, error) {
+	var decoded []byte
+	var err error
+	//nolint:exhaustive
+	switch c.operation {
+	case Base64Encode:
+		return c.encoding().EncodeToString([]byte(value)), nil
+	case HexEncode:
+		return hex.EncodeToString([]byte(value)), nil
+	case Base64Decode:
+		trimmed := strings.TrimRight(strings.TrimSpace(value), "=")
+		decoded, err = c.encoding().WithPadding(base64.NoPadding).DecodeString(trimmed)
+	default:
+		decoded, err = hex.DecodeString(strings.TrimSpace(value))
+	}
+
+	if err != nil {
+		return "", untransformable("%s rule on field '%s': %w", c.operation, field, err)
+	}
+
+	if utf8.Valid(decoded) {
+		return string(decoded), nil
+	}
+	switch c.invalidUTF8 {
+	case InvalidUTF8Hex:
+		return hex.EncodeToString(decoded), nil
+	case InvalidUTF8Empty:
+		return "", nil
+	default:
+		return strings.ToValidUTF8(string(decoded), "�"), nil
+	}
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_encoding(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		value     string
+		operation TransformOperation
+		params    map[string]interface{}
+		want      string
+	}{
+		{name: "base64", value: "hello?>", operation: Base64Encode, want: "aGVsbG8/Pg=="},
+		{name: "url-safe base64", value: "hello?>", operation: Base64Encode, params: map[string]interface{}{"url_safe": true}, want: "aGVsbG8_Pg=="},
+		{name: "unpadded base64", value: "hello?>", operation: Base64Encode, params: map[string]interface{}{"url_safe": true, "padding": false}, want: "aGVsbG8_Pg"},
+		{name: "hex", value: "héllo", operation: HexEncode, want: "68c3a96c6c6f"},
+		{name: "decode base64", value: "aGVsbG8/Pg==", operation: Base64Decode, want: "hello?>"},
+		{name: "decode unpadded base64", value: "aGVsbG8/Pg", operation: Base64Decode, want: "hello?>"},
+		{name: "decode url-safe base64", value: "aGVsbG8_Pg==", operation: Base64Decode, params: map[string]interface{}{"url_safe": true}, want: "hello?>"},
+		{name: "decode hex", value: "68C3A96C6C6F", operation: HexDecode, want: "héllo"},
+		{name: "undecodable kept", value: "not base64!", operation: Base64Decode, want: "not base64!"},
+		{name: "undecodable emptied", value: "6g", operation: HexDecode, params: map[string]interface{}{"on_error": "empty"}, want: ""},
+		{name: "invalid utf-8 replaced", value: "61ff62", operation: HexDecode, want: "a�b"},
+		{name: "invalid utf-8 in hex", value: "Yf9i", operation: Base64Decode, params: map[string]interface{}{"invalid_utf8": "hex"}, want: "61ff62"},
+		{name: "invalid utf-8 emptied", value: "61ff62", operation: HexDecode, params: map[string]interface{}{"invalid_utf8": "empty"}, want: ""},
+		{name: "empty", value: "", operation: Base64Decode, params: map[string]interface{}{"on_error": "fail"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: tc.operation, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_encodingErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"value"}, []string{"aGk="}, []string{"%%"})
+	_, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "value", Operation: Base64Decode, Parameters: map[string]interface{}{"on_error": "fail"}}},
+	})
+	is.ErrorContains(err, "line 3: base64_decode rule on field 'value'")
+
+	for _, params := range []map[string]interface{}{
+		{"url_safe": "yes"},
+		{"padding": 1},
+		{"on_error": "skip"},
+		{"invalid_utf8": "latin1"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "value", Operation: Base64Decode, Parameters: params}},
+		})
+		is.ErrorContains(err, "base64_decode rule on field 'value'", params)
+	}
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldExplosion splits the field of an "explode" rule into one row per part, the other fields
+// being copied onto every row and the rules after it applying to each row. The part is written
+// to the target field of the rule, the field itself by default. Its parameters are "separator",
+// "," by default, "trim", trimming whitespace around parts, "keep_empty", keeping empty parts,
+// and "index_field", a field numbering the parts of a row from 1. Values without parts, like
+// empty ones, are kept as one row with an empty part and index.
+type fieldExplosion struct {
+	separator  string
+	trim       bool
+	keepEmpty  bool
+	indexField string
+}
+
+// parseFieldExplosion parses the parameters of an "explode" rule.
+func parseFieldExplosion(rule TransformRule) (*fieldExplosion, error) {
+	explosion := &fieldExplosion{separator: ","}
+	if separator, ok := rule.Parameters["separator"].(string); ok {
+		if separator == "" {
+			return nil, fmt.Errorf("empty separator of explode rule on field '%s'", rule.Field)
+		}
+		explosion.separator = separator
+	}
+
+	for name, flag := range map[string]*bool{"trim": &explosion.trim, "keep_empty": &explosion.keepEmpty} {
+		if value, ok := rule.Parameters[name]; ok {
+			if *flag, ok = value.(bool); !ok {
+				return nil, fmt.Errorf("invalid %s of explode rule on field '%s': expected true or false", name, rule.Field)
+			}
+		}
+	}
+
+	if raw, ok := rule.Parameters["index_field"]; ok {
+		if explosion.indexField, _ = raw.(string); strings.TrimSpace(explosion.indexField) == "" {
+			return nil, fmt.Errorf("invalid index_field of explode rule on field '%s': expected a field name", rule.Field)
+		}
+	}
+	return explosion, nil
+}
+
+// parts splits a value into the parts of its rows, in order.
+func (e *fieldExplosion) parts(value string) []string {
+	parts := make([]string, 0, strings.Count(value, e.separator)+1)
+	for _, part := range strings.Split(value, e.separator) {
+		if e.trim {
+			part = strings.TrimSpace(part)
+		}
+		if part != "" || e.keepEmpty {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
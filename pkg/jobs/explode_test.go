@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func explodedFields(rows []DataRow, fields ...string) [][]string {
+	values := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		record := make([]string, 0, len(fields))
+		for _, field := range fields {
+			record = append(record, row.Fields[field])
+		}
+		values = append(values, record)
+	}
+	return values
+}
+
+func TestTransformService_explode(t *testing.T) {
+	t.Parallel()
+
+	input := []string{"red;blue;green", "red;;blue", "", " big ; small "}
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		target string
+		want   [][]string // id, tags, index
+	}{
+		{
+			name:   "parts",
+			params: map[string]interface{}{"separator": ";"},
+			want: [][]string{
+				{"1", "red", ""}, {"1", "blue", ""}, {"1", "green", ""},
+				{"2", "red", ""}, {"2", "blue", ""},
+				{"3", "", ""},
+				{"4", " big ", ""}, {"4", " small ", ""},
+			},
+		},
+		{
+			name:   "empty parts kept with index",
+			params: map[string]interface{}{"separator": ";", "keep_empty": true, "trim": true, "index_field": "tag_index"},
+			want: [][]string{
+				{"1", "red", "1"}, {"1", "blue", "2"}, {"1", "green", "3"},
+				{"2", "red", "1"}, {"2", "", "2"}, {"2", "blue", "3"},
+				{"3", "", "1"},
+				{"4", "big", "1"}, {"4", "small", "2"},
+			},
+		},
+		{
+			name:   "value without parts",
+			params: map[string]interface{}{"separator": ";", "trim": true, "index_field": "tag_index"},
+			want: [][]string{
+				{"1", "red", "1"}, {"1", "blue", "2"}, {"1", "green", "3"},
+				{"2", "red", "1"}, {"2", "blue", "2"},
+				{"3", "", ""},
+				{"4", "big", "1"}, {"4", "small", "2"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			rows := testRows(t, []string{"id", "tags"}, []string{"1", input[0]}, []string{"2", input[1]}, []string{"3", input[2]}, []string{"4", input[3]})
+			output, err := newTestTransformService(t).ProcessData(rows, map[string]interface{}{
+				"rules": []TransformRule{{Field: "tags", Operation: Explode, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, explodedFields(output, "id", "tags", "tag_index"))
+			is.Equal(3, output[3].LineNumber)
+			is.Equal("red;blue;green", rows[0].Fields["tags"])
+		})
+	}
+}
+
+func TestTransformService_explodeWithRules(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"order", "skus", "qty"}, []string{"A", "x-1,y-2", "3"}, []string{"B", "z-9", "n/a"})
+	output, err := newTestTransformService(t).ProcessData(rows, map[string]interface{}{
+		"keep_fields": false,
+		"chained":     true,
+		"rules": []TransformRule{
+			{Field: "order", Operation: LowerCase},
+			{Field: "skus", Operation: Explode, TargetField: "sku", Parameters: map[string]interface{}{"index_field": "line"}},
+			{Field: "sku", Operation: UpperCase},
+			{Field: "line", Operation: Concat, TargetField: "key", Parameters: map[string]interface{}{"fields": []interface{}{"order", "line"}, "separator": "-"}},
+			{Field: "qty", Operation: Round, Parameters: map[string]interface{}{"on_error": OnErrorSkipRow}},
+		},
+	})
+	is.NoError(err)
+	is.Equal([][]string{{"a", "X-1", "1", "a-1", "3"}, {"a", "Y-2", "2", "a-2", "3"}}, explodedFields(output, "order", "sku", "line", "key", "qty"))
+	is.Equal([]string{"order", "sku", "line", "key", "qty"}, output[1].Keys())
+}
+
+func TestTransformService_TransformFile_explode(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("id,tags\n1,red;blue;green\n2,\n3,blue\n"), 0o600))
+
+	result, err := newTestTransformService(t).TransformFile(input, filepath.Join(dir, "out.csv"), []TransformRule{
+		{Field: "tags", Operation: Explode, Parameters: map[string]interface{}{"separator": ";"}},
+	}, true, map[string]interface{}{"drop_nulls": true})
+	is.NoError(err)
+	is.Equal(4, result.Processed)
+	is.Equal(2, result.Expanded)
+	is.Equal(1, result.Excluded)
+
+	output, err := os.ReadFile(filepath.Join(dir, "out.csv"))
+	is.NoError(err)
+	is.Equal("id,tags\n1,red\n1,blue\n1,green\n3,blue\n", string(output))
+}
+
+func TestTransformService_explodeErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for _, params := range []map[string]interface{}{
+		{"separator": ""},
+		{"keep_empty": "yes"},
+		{"trim": 1},
+		{"index_field": " "},
+		{"index_field": 1},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "tags", Operation: Explode, Parameters: params}},
+		})
+		is.ErrorContains(err, "explode rule on field 'tags'", params)
+	}
+}
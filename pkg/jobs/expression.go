@@ -0,0 +1,707 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expression is a compiled predicate over the fields of a row, like
+// `amount > 100 && (country == "FR" || country == "DE") && email =~ "@corp\\.com$"`.
+//
+// Supported syntax:
+//   - literals: numbers, "double" or 'single' quoted strings, true and false
+//   - identifiers: field names made of letters, digits, '_', '-' and '.', not starting with '-',
+//     or any name between backticks
+//   - arithmetic: + - * / on numbers, "-" needing spaces around it since a-b is a field name
+//   - functions: len, lower, upper and trim of strings, abs of numbers, like len(zip) == 5
+//   - comparisons: == != < <= > >= and =~ !~ against a string literal regex
+//   - boolean logic: && || ! and parentheses
+//
+// Values of fields are compared as numbers when both sides parse as numbers, as strings otherwise,
+// numbers being equal within a relative 1e-9 so that computed amounts like 19.99 * 3 equal 59.97.
+// A missing field compares as not equal to everything. Values that cannot be computed, like
+// a non-numeric field in arithmetic, make the expression false, or fail Check.
+type Expression struct {
+	source string
+	root   exprNode
+	fields []string // fields read by the expression, in order of appearance
+}
+
+// ExpressionError reports a syntax error at a position of the expression source.
+type ExpressionError struct {
+	Source   string
+	Position int // byte offset of the error in Source
+	Message  string
+}
+
+// Error implements error, pointing at the position of the error with a caret.
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s at position %d\n  %s\n  %s^", e.Message, e.Position+1, e.Source, strings.Repeat(" ", e.Position))
+}
+
+// ParseExpression compiles an expression, regexes included.
+func ParseExpression(source string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{source: source, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "unexpected "+tok.describe())
+	}
+
+	return &Expression{source: source, root: root, fields: p.fields}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Matches reports whether the row satisfies the expression.
+func (e *Expression) Matches(row DataRow) bool {
+	return e.root.eval(row).truthy()
+}
+
+// Evaluate returns the value of the expression for the row, as a string.
+func (e *Expression) Evaluate(row DataRow) string {
+	return e.root.eval(row).String()
+}
+
+// Check reports whether the row satisfies the expression, failing when a value of
+// the expression cannot be computed, like a non-numeric field in arithmetic.
+func (e *Expression) Check(row DataRow) (bool, error) {
+	value := e.root.eval(row)
+	if value.kind == kindError {
+		return false, errors.New(value.str)
+	}
+	return value.truthy(), nil
+}
+
+// Fields returns the fields the expression reads, in order of appearance.
+func (e *Expression) Fields() []string {
+	return e.fields
+}
+
+// exprValue is the result of evaluating an expression node.
+type exprValue struct {
+	kind  exprKind
+	str   string
+	num   float64
+	bool  bool
+	isNum bool // str also parses as the number num
+}
+
+type exprKind int
+
+const (
+	kindMissing exprKind = iota
+	kindString
+	kindNumber
+	kindBool
+	kindError // a value that cannot be computed, str being the reason
+)
+
+func stringValue(s string) exprValue {
+	v := exprValue{kind: kindString, str: s}
+	if num, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+		v.num, v.isNum = num, true
+	}
+	return v
+}
+
+func numberValue(num float64) exprValue {
+	return exprValue{kind: kindNumber, num: num, isNum: true, str: strconv.FormatFloat(num, 'f', -1, 64)}
+}
+
+func boolValue(b bool) exprValue {
+	return exprValue{kind: kindBool, bool: b, str: strconv.FormatBool(b)}
+}
+
+func errorValue(format string, args ...interface{}) exprValue {
+	return exprValue{kind: kindError, str: fmt.Sprintf(format, args...)}
+}
+
+// describe returns the value as shown in error messages.
+func (v exprValue) describe() string {
+	//nolint:exhaustive
+	switch v.kind {
+	case kindMissing:
+		return "missing field"
+	case kindString:
+		return strconv.Quote(v.str)
+	default:
+		return v.str
+	}
+}
+
+// truthy reports whether the value counts as true on its own.
+func (v exprValue) truthy() bool {
+	//nolint:exhaustive
+	switch v.kind {
+	case kindBool:
+		return v.bool
+	case kindNumber:
+		return v.num != 0
+	case kindString:
+		return v.str != "" && v.str != "0" && !strings.EqualFold(v.str, "false")
+	default:
+		return false
+	}
+}
+
+// String returns the value as text, empty for a missing field.
+func (v exprValue) String() string {
+	return v.str
+}
+
+// compare returns -1, 0 or 1, numerically when both values are numbers and as strings otherwise.
+func (v exprValue) compare(other exprValue) int {
+	if v.isNum && other.isNum {
+		switch {
+		case math.Abs(v.num-other.num) <= 1e-9*math.Max(1, math.Max(math.Abs(v.num), math.Abs(other.num))):
+			return 0
+		case v.num < other.num:
+			return -1
+		case v.num > other.num:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(v.str, other.str)
+}
+
+// exprNode is a node of a compiled expression.
+type exprNode interface {
+	eval(row DataRow) exprValue
+}
+
+type literalNode struct{ value exprValue }
+
+func (n literalNode) eval(DataRow) exprValue { return n.value }
+
+type fieldNode struct{ name string }
+
+func (n fieldNode) eval(row DataRow) exprValue {
+	value, exists := row.Fields[n.name]
+	if !exists {
+		return exprValue{kind: kindMissing}
+	}
+	return stringValue(value)
+}
+
+type notNode struct{ operand exprNode }
+
+func (n notNode) eval(row DataRow) exprValue {
+	operand := n.operand.eval(row)
+	if operand.kind == kindError {
+		return operand
+	}
+	return boolValue(!operand.truthy())
+}
+
+type logicNode struct {
+	or          bool
+	left, right exprNode
+}
+
+func (n logicNode) eval(row DataRow) exprValue {
+	left := n.left.eval(row)
+	if left.kind == kindError {
+		return left
+	}
+	if left.truthy() == n.or {
+		return boolValue(n.or)
+	}
+	right := n.right.eval(row)
+	if right.kind == kindError {
+		return right
+	}
+	return boolValue(right.truthy())
+}
+
+type compareNode struct {
+	operator    string
+	left, right exprNode
+}
+
+func (n compareNode) eval(row DataRow) exprValue {
+	left, right := n.left.eval(row), n.right.eval(row)
+	if left.kind == kindError {
+		return left
+	}
+	if right.kind == kindError {
+		return right
+	}
+	if left.kind == kindMissing || right.kind == kindMissing {
+		return boolValue(n.operator == "!=")
+	}
+
+	cmp := left.compare(right)
+	switch n.operator {
+	case "==":
+		return boolValue(cmp == 0)
+	case "!=":
+		return boolValue(cmp != 0)
+	case "<":
+		return boolValue(cmp < 0)
+	case "<=":
+		return boolValue(cmp <= 0)
+	case ">":
+		return boolValue(cmp > 0)
+	default:
+		return boolValue(cmp >= 0)
+	}
+}
+
+type matchNode struct {
+	negate  bool
+	operand exprNode
+	regex   *regexp.Regexp
+}
+
+func (n matchNode) eval(row DataRow) exprValue {
+	value := n.operand.eval(row)
+	if value.kind == kindError {
+		return value
+	}
+	if value.kind == kindMissing {
+		return boolValue(false)
+	}
+	return boolValue(n.regex.MatchString(value.str) != n.negate)
+}
+
+type arithmeticNode struct {
+	operator    string
+	left, right exprNode
+}
+
+func (n arithmeticNode) eval(row DataRow) exprValue {
+	left, right := n.left.eval(row), n.right.eval(row)
+	for _, operand := range []exprValue{left, right} {
+		if operand.kind == kindError {
+			return operand
+		}
+		if !operand.isNum {
+			return errorValue("%s is not a number", operand.describe())
+		}
+	}
+
+	switch n.operator {
+	case "+":
+		return numberValue(left.num + right.num)
+	case "-":
+		return numberValue(left.num - right.num)
+	case "*":
+		return numberValue(left.num * right.num)
+	default:
+		if right.num == 0 {
+			return errorValue("division by zero")
+		}
+		return numberValue(left.num / right.num)
+	}
+}
+
+type callNode struct {
+	name     string
+	function func(exprValue) exprValue
+	argument exprNode
+}
+
+func (n callNode) eval(row DataRow) exprValue {
+	argument := n.argument.eval(row)
+	//nolint:exhaustive
+	switch argument.kind {
+	case kindError:
+		return argument
+	case kindMissing:
+		return errorValue("%s of a missing field", n.name)
+	}
+	return n.function(argument)
+}
+
+// expressionFunctions are the functions of expressions, which take one argument.
+var expressionFunctions = map[string]func(exprValue) exprValue{
+	"len":   func(v exprValue) exprValue { return numberValue(float64(utf8.RuneCountInString(v.str))) },
+	"lower": func(v exprValue) exprValue { return stringValue(strings.ToLower(v.str)) },
+	"upper": func(v exprValue) exprValue { return stringValue(strings.ToUpper(v.str)) },
+	"trim":  func(v exprValue) exprValue { return stringValue(strings.TrimSpace(v.str)) },
+	"abs": func(v exprValue) exprValue {
+		if !v.isNum {
+			return errorValue("abs of %s, which is not a number", v.describe())
+		}
+		return numberValue(math.Abs(v.num))
+	},
+}
+
+// exprParser is a recursive descent parser over expression tokens.
+type exprParser struct {
+	source string
+	tokens []exprToken
+	pos    int
+	fields []string // fields read, in order of appearance
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) errorAt(tok exprToken, message string) *ExpressionError {
+	return &ExpressionError{Source: p.source, Position: tok.pos, Message: message}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOperator("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek().isOperator("!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	if op.kind != tokenOperator {
+		return left, nil
+	}
+
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{operator: op.text, left: left, right: right}, nil
+	case "=~", "!~":
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, p.errorAt(pattern, "expected a string pattern after "+op.text+", got "+pattern.describe())
+		}
+		regex, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, p.errorAt(pattern, fmt.Sprintf("invalid regex pattern %q: %v", pattern.text, err))
+		}
+		return matchNode{negate: op.text == "!~", operand: left, regex: regex}, nil
+	default:
+		return left, nil
+	}
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOperator("+") || p.peek().isOperator("-") {
+		op := p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmeticNode{operator: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOperator("*") || p.peek().isOperator("/") {
+		op := p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmeticNode{operator: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+// field returns the node of a field, recording that the expression reads it.
+func (p *exprParser) field(name string) exprNode {
+	if !slices.Contains(p.fields, name) {
+		p.fields = append(p.fields, name)
+	}
+	return fieldNode{name: name}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+
+	//nolint:exhaustive
+	switch tok.kind {
+	case tokenNumber:
+		num, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorAt(tok, "invalid number "+tok.text)
+		}
+		return literalNode{value: numberValue(num)}, nil
+	case tokenString:
+		return literalNode{value: stringValue(tok.text)}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true", "false":
+			return literalNode{value: boolValue(tok.text == "true")}, nil
+		}
+		if p.peek().isOperator("(") {
+			return p.parseCall(tok)
+		}
+		return p.field(tok.text), nil
+	case tokenField:
+		return p.field(tok.text), nil
+	case tokenOperator:
+		if tok.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); !closing.isOperator(")") {
+				return nil, p.errorAt(closing, "expected ')', got "+closing.describe())
+			}
+			return inner, nil
+		}
+	}
+
+	return nil, p.errorAt(tok, "expected a value, got "+tok.describe())
+}
+
+// parseCall parses the call of the function named by tok, its opening parenthesis being next.
+func (p *exprParser) parseCall(tok exprToken) (exprNode, error) {
+	function, ok := expressionFunctions[tok.text]
+	if !ok {
+		return nil, p.errorAt(tok, "unknown function '"+tok.text+"'")
+	}
+	p.next()
+	argument, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if closing := p.next(); !closing.isOperator(")") {
+		return nil, p.errorAt(closing, "expected ')', got "+closing.describe())
+	}
+	return callNode{name: tok.text, function: function, argument: argument}, nil
+}
+
+// exprToken is a lexical token of an expression.
+type exprToken struct {
+	kind tokenKind
+	text string // operator, identifier, number or unquoted string
+	pos  int
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenField // backtick-quoted identifier
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+func (t exprToken) isOperator(text string) bool {
+	return t.kind == tokenOperator && t.text == text
+}
+
+// describe returns the token as shown in error messages.
+func (t exprToken) describe() string {
+	//nolint:exhaustive
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// expressionOperators lists the operators, two-character ones first so they win over their prefixes.
+var expressionOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")", "+", "-", "*", "/"}
+
+// tokenizeExpression splits an expression into tokens, ending with a tokenEOF.
+func tokenizeExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+
+	for i := 0; i < len(source); {
+		c, size := utf8.DecodeRuneInString(source[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '"' || c == '\'' || c == '`':
+			end, text, err := scanQuoted(source, i)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokenString
+			if c == '`' {
+				kind = tokenField
+			}
+			tokens = append(tokens, exprToken{kind: kind, text: text, pos: i})
+			i = end
+		case isNumberStart(source, i, tokens):
+			end := scanNumber(source, i)
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: source[i:end], pos: i})
+			i = end
+		case isIdentChar(c) && c != '-':
+			end := scanIdent(source, i)
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: source[i:end], pos: i})
+			i = end
+		default:
+			op := matchOperator(source[i:])
+			if op == "" {
+				return nil, &ExpressionError{Source: source, Position: i, Message: fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// scanNumber returns the offset after the number starting at start, its sign included.
+func scanNumber(source string, start int) int {
+	i := start + 1
+	for i < len(source) && (isDigit(source[i]) || source[i] == '.') {
+		i++
+	}
+	return i
+}
+
+// scanIdent returns the offset after the identifier starting at start.
+func scanIdent(source string, start int) int {
+	i := start
+	for i < len(source) {
+		c, size := utf8.DecodeRuneInString(source[i:])
+		if !isIdentChar(c) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// scanQuoted reads the quoted token starting at start, returning the offset after it and its content.
+// Double-quoted strings support Go escape sequences, other quotes are taken literally.
+func scanQuoted(source string, start int) (int, string, error) {
+	quote := source[start]
+	for i := start + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			raw := source[start : i+1]
+			if quote != '"' {
+				return i + 1, raw[1 : len(raw)-1], nil
+			}
+			text, err := strconv.Unquote(raw)
+			if err != nil {
+				return 0, "", &ExpressionError{Source: source, Position: start, Message: "invalid string " + raw}
+			}
+			return i + 1, text, nil
+		}
+	}
+	return 0, "", &ExpressionError{Source: source, Position: start, Message: "unterminated string"}
+}
+
+// isNumberStart reports whether a number starts at i. A '-' starts a negative number
+// only where a value is expected, so "a-b" stays an identifier.
+func isNumberStart(source string, i int, previous []exprToken) bool {
+	if isDigit(source[i]) {
+		return true
+	}
+	if source[i] != '-' || i+1 >= len(source) || !isDigit(source[i+1]) {
+		return false
+	}
+	if len(previous) == 0 {
+		return true
+	}
+	last := previous[len(previous)-1]
+	return last.kind == tokenOperator && last.text != ")"
+}
+
+func matchOperator(rest string) string {
+	for _, op := range expressionOperators {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c rune) bool {
+	return c == '_' || c == '.' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpression_Matches(t *testing.T) {
+	t.Parallel()
+
+	row := DataRow{Fields: map[string]string{
+		"amount":     "150",
+		"country":    "FR",
+		"email":      "ann@corp.com",
+		"first name": "Ann",
+		"active":     "true",
+		"zip":        "09100",
+	}}
+
+	testCases := []struct {
+		expression string
+		expected   bool
+	}{
+		{`amount > 100 && (country == "FR" || country == "DE") && email =~ "@corp\\.com$"`, true},
+		{`amount > 200 || country == 'DE'`, false},
+		{`amount >= 150 && amount <= 150.0`, true},
+		{`amount == "150.00"`, true},
+		{`country < "GB"`, true},
+		{`email !~ "@corp"`, false},
+		{`!(country == "FR")`, false},
+		{"`first name` == \"Ann\"", true},
+		{`active && !missing`, true},
+		{`missing == ""`, false},
+		{`missing != "x"`, true},
+		{`zip == 9100`, true},
+		{`amount > -5`, true},
+		{`true && false`, false},
+		{`amount * 2 - 100 == 200`, true},
+		{`amount / 3 == 50`, true},
+		{`0.1 + 0.2 == 0.3`, true},
+		{`len(zip) == 5 || country != 'FR'`, true},
+		{`lower(country) == "fr" && upper(email) =~ "CORP"`, true},
+		{`abs(-5 - amount) > 150`, true},
+		{`amount + country > 0`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			expression, err := ParseExpression(tc.expression)
+			is.NoError(err)
+			is.Equal(tc.expected, expression.Matches(row))
+		})
+	}
+}
+
+func TestParseExpression_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expression string
+		position   int
+		message    string
+	}{
+		{`amount > `, 9, "expected a value, got end of expression"},
+		{`amount > 1 &&& b`, 13, "unexpected character"},
+		{`(a == 1`, 7, "expected ')', got end of expression"},
+		{`a == "open`, 5, "unterminated string"},
+		{`a == 1 b`, 7, "unexpected 'b'"},
+		{`a =~ "("`, 5, "invalid regex pattern"},
+		{`a =~ b`, 5, "expected a string pattern"},
+		{`a # 1`, 2, "unexpected character"},
+		{`size(a) > 1`, 0, "unknown function 'size'"},
+		{`len(a > 1`, 9, "expected ')', got end of expression"},
+		{`a + `, 4, "expected a value, got end of expression"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			_, err := ParseExpression(tc.expression)
+			var exprErr *ExpressionError
+			is.ErrorAs(err, &exprErr)
+			is.Equal(tc.position, exprErr.Position)
+			is.Contains(exprErr.Message, tc.message)
+		})
+	}
+}
+
+func TestExpressionError_caret(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	_, err := ParseExpression(`a == 1 && )`)
+	is.EqualError(err, "expected a value, got ')' at position 11\n  a == 1 && )\n            ^")
+}
+
+func TestExpression_Check(t *testing.T) {
+	t.Parallel()
+
+	row := DataRow{Fields: map[string]string{"qty": "3", "price": "19.99", "total": "59.97", "note": "n/a", "zero": "0"}}
+
+	testCases := []struct {
+		expression string
+		expected   bool
+		err        string
+	}{
+		{`qty * price == total`, true, ""},
+		{`qty * price > total`, false, ""},
+		{`qty * note == total`, false, `"n/a" is not a number`},
+		{`qty + missing > 0`, false, "missing field is not a number"},
+		{`total / zero > 1`, false, "division by zero"},
+		{`len(missing) == 0`, false, "len of a missing field"},
+		{`abs(note) > 1`, false, `abs of "n/a", which is not a number`},
+		{`qty > 5 || note * 2 > 1`, false, `"n/a" is not a number`},
+		{`qty > 1 || note * 2 > 1`, true, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			expression, err := ParseExpression(tc.expression)
+			is.NoError(err)
+			ok, err := expression.Check(row)
+			is.Equal(tc.expected, ok)
+			if tc.err == "" {
+				is.NoError(err)
+			} else {
+				is.EqualError(err, tc.err)
+				is.False(expression.Matches(row))
+			}
+		})
+	}
+}
+
+func TestExpression_Fields(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	expression, err := ParseExpression("qty * price == total && len(`zip code`) == 5 && qty > 0")
+	is.NoError(err)
+	is.Equal([]string{"qty", "price", "total", "zip code"}, expression.Fields())
+}
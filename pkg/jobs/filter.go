@@ -1,10 +1,18 @@
 package jobs
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/rs/zerolog"
 	"github.com/samber/do/v2"
@@ -13,15 +21,17 @@ import (
 // FilterService handles data filtering operations
 // This service demonstrates conditional data processing with dependency injection.
 type FilterService struct {
-	fileService *FileService   `do:""`
-	logger      zerolog.Logger `do:""`
+	fileService      *FileService      `do:""`
+	transformService *TransformService `do:""`
+	logger           zerolog.Logger    `do:""`
 }
 
 // NewFilterService creates a new filter service with dependency injection.
 func NewFilterService(i do.Injector) (*FilterService, error) {
 	return &FilterService{
-		fileService: do.MustInvoke[*FileService](i),
-		logger:      do.MustInvoke[zerolog.Logger](i),
+		fileService:      do.MustInvoke[*FileService](i),
+		transformService: do.MustInvoke[*TransformService](i),
+		logger:           *do.MustInvoke[*zerolog.Logger](i),
 	}, nil
 }
 
@@ -30,19 +40,210 @@ type FilterRule struct {
 	Field    string      `json:"field"`
 	Operator string      `json:"operator"`
 	Value    interface{} `json:"value"`
+
+	// CaseSensitive controls string comparisons. When unset, operators keep their
+	// historical behavior: case-insensitive, except "regex" which is case-sensitive.
+	CaseSensitive *bool `json:"case_sensitive,omitempty"`
+
+	// Threshold is the minimum similarity, between 0 and 1, of a "fuzzy" rule.
+	// When unset DefaultFuzzyThreshold is used.
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// MissingAsEmpty makes length operators treat a missing field as an empty value
+	// instead of a non-matching one.
+	MissingAsEmpty bool `json:"missing_as_empty,omitempty"`
+
+	// Column is the CSV column holding the values of an "in_file" or "not_in_file" rule.
+	// When unset the file is read as plain text with one value per line.
+	Column string `json:"column,omitempty"`
+
+	// Negate inverts the result of the rule before it is combined with the other rules of its group.
+	Negate bool `json:"negate,omitempty"`
+
+	between       *betweenRange       // parsed bounds of a "between" rule
+	regex         *regexp.Regexp      // compiled pattern of a "regex" rule
+	fuzzy         []rune              // case-folded value of a "fuzzy" rule
+	valueSet      map[string]struct{} // values loaded by an "in_file" rule, case-folded unless case-sensitive
+	numbers       numberComparison    // how numbers are parsed and compared
+	missingPolicy MissingFieldPolicy  // what a missing field makes of the rule
+	index         int                 // position of the rule statistics
+}
+
+// isCaseSensitive reports whether string comparisons of the rule honor case.
+func (r FilterRule) isCaseSensitive() bool {
+	if r.CaseSensitive != nil {
+		return *r.CaseSensitive
+	}
+	return r.Operator == "regex"
+}
+
+// betweenRange holds the parsed bounds of a "between" rule, either numbers or dates.
+type betweenRange struct {
+	dates     bool
+	min, max  float64
+	minDate   time.Time
+	maxDate   time.Time
+	inclusive bool
+	layouts   []string // layouts of date values, DefaultDateLayouts when empty
+}
+
+// Filter group logic values.
+const (
+	FilterLogicAnd = "and"
+	FilterLogicOr  = "or"
+)
+
+// FilterGroup combines filter rules and nested groups with a boolean logic.
+// An empty "and" group matches every row, an empty "or" group matches none.
+type FilterGroup struct {
+	Logic  string        `json:"logic,omitempty"` // "and" (default) or "or"
+	Rules  []FilterRule  `json:"rules,omitempty"`
+	Groups []FilterGroup `json:"groups,omitempty"`
+}
+
+// Validate checks the logic of the group and its nested groups.
+func (g FilterGroup) Validate() error {
+	switch strings.ToLower(g.Logic) {
+	case "", FilterLogicAnd, FilterLogicOr:
+	default:
+		return fmt.Errorf("unknown filter logic %q: expected %q or %q", g.Logic, FilterLogicAnd, FilterLogicOr)
+	}
+
+	for _, group := range g.Groups {
+		if err := group.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isEmpty reports whether the group holds no rule at all.
+func (g FilterGroup) isEmpty() bool {
+	return len(g.Rules) == 0 && len(g.Groups) == 0
+}
+
+// isOr reports whether the group matches when any of its members match.
+func (g FilterGroup) isOr() bool {
+	return strings.EqualFold(g.Logic, FilterLogicOr)
+}
+
+// ParseFilterRules decodes filter rules in JSON, either as a flat array of rules
+// combined with AND, or as a group object like {"logic":"or","rules":[...],"groups":[...]}.
+func ParseFilterRules(data []byte) (FilterGroup, error) {
+	var group FilterGroup
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &group.Rules); err != nil {
+			return group, err
+		}
+	} else if err := json.Unmarshal(trimmed, &group); err != nil {
+		return group, err
+	}
+
+	return group, group.Validate()
 }
 
 // FilterOptions contains filtering configuration.
 type FilterOptions struct {
-	InputFile  string       `json:"input_file"`
-	OutputFile string       `json:"output_file"`
-	Rules      []FilterRule `json:"rules"`
-	Inclusive  bool         `json:"inclusive"` // true = keep matches, false = remove matches
+	InputFile  string        `json:"input_file"`
+	OutputFile string        `json:"output_file"`
+	Rules      []FilterRule  `json:"rules"`
+	Logic      string        `json:"logic,omitempty"`  // how rules and groups combine, "and" by default
+	Groups     []FilterGroup `json:"groups,omitempty"` // nested rule groups
+	Inclusive  bool          `json:"inclusive"`        // true = keep matches, false = remove matches
+	Output     OutputOptions `json:"output"`           // format details of the written rows
+	Window     RowWindow     `json:"window"`           // rows selected by position before rules apply
+	Stream     bool          `json:"stream"`           // write rows as they are read instead of loading the file
+	Where      string        `json:"where,omitempty"`  // expression used instead of rules, see Expression
+
+	NumberFormat NumberFormat `json:"number_format,omitempty"` // how numeric field values are written
+	Epsilon      float64      `json:"epsilon,omitempty"`       // tolerance of numeric equality, exact by default
+
+	// DateLayouts are the layouts of date values, see time.Parse, DefaultDateLayouts when empty.
+	DateLayouts []string `json:"date_layouts,omitempty"`
+
+	// MissingFieldPolicy tells what a rule does on a row without its field, it does not apply to Where.
+	MissingFieldPolicy MissingFieldPolicy `json:"missing_field_policy,omitempty"`
+
+	RejectedOutputFile string `json:"rejected_output_file,omitempty"` // where to write rows excluded by rules
+
+	// Derive computes extra fields on every row before it is evaluated, see TransformRule.
+	// Derived fields are written only when KeepDerived is set.
+	Derive      []TransformRule `json:"derive,omitempty"`
+	KeepDerived bool            `json:"keep_derived,omitempty"`
+
+	where *Expression // compiled Where expression
+	stats []RuleStats // statistics of every leaf rule, filled while filtering
+}
+
+// MissingFieldPolicy tells what a filter rule does on a row that lacks its field.
+type MissingFieldPolicy string
+
+const (
+	// MissingFieldPolicyExclude makes the rule fail before negation. This is the default.
+	MissingFieldPolicyExclude MissingFieldPolicy = "exclude"
+	// MissingFieldPolicyInclude makes the rule match before negation.
+	MissingFieldPolicyInclude MissingFieldPolicy = "include"
+	// MissingFieldPolicyError aborts the run with a MissingFieldError.
+	MissingFieldPolicyError MissingFieldPolicy = "error"
+)
+
+// ParseMissingFieldPolicy validates a missing field policy name, the empty name being MissingFieldPolicyExclude.
+func ParseMissingFieldPolicy(name string) (MissingFieldPolicy, error) {
+	switch policy := MissingFieldPolicy(strings.ToLower(name)); policy {
+	case "", MissingFieldPolicyExclude:
+		return MissingFieldPolicyExclude, nil
+	case MissingFieldPolicyInclude, MissingFieldPolicyError:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown missing field policy %q: expected exclude, include or error", name)
+	}
+}
+
+// MissingFieldError is returned when a rule references a field a row lacks,
+// under the MissingFieldPolicyError policy.
+type MissingFieldError struct {
+	Field    string
+	Location string   // location of the row, see DataRow.Location
+	Headers  []string // fields of the row
+}
+
+// Error implements error.
+func (e *MissingFieldError) Error() string {
+	return fmt.Sprintf("field '%s' is missing at %s, available fields: %s", e.Field, e.Location, strings.Join(e.Headers, ", "))
+}
+
+// rootGroup returns the top-level rules and groups as a single group.
+func (o *FilterOptions) rootGroup() FilterGroup {
+	return FilterGroup{Logic: o.Logic, Rules: o.Rules, Groups: o.Groups}
 }
 
 // ProcessData filters data based on rules
 // This method demonstrates complex data filtering logic.
+// In stream mode rows are written as they are read and nil is returned.
 func (s *FilterService) ProcessData(input []DataRow, options map[string]interface{}) ([]DataRow, error) {
+	outcome, err := s.run(input, options)
+	if err != nil {
+		return nil, err
+	}
+	return outcome.rows, nil
+}
+
+// filterOutcome describes the result of a filter run.
+type filterOutcome struct {
+	rows     []DataRow   // kept rows, nil when streaming
+	rejected []DataRow   // rows excluded by rules, only kept in memory for the rejected output file
+	kept     int         // rows written or returned
+	skipped  int         // rows skipped by position
+	excluded int         // rows excluded by rules
+	stats    []RuleStats // per leaf rule statistics
+	warnings []string    // problems found in the rules, like unknown fields
+}
+
+// run filters the input, or the input file when input is empty, and writes the output file.
+func (s *FilterService) run(input []DataRow, options map[string]interface{}) (*filterOutcome, error) {
 	s.logger.Info().Msg("Filtering data based on rules")
 
 	// Parse options
@@ -51,6 +252,10 @@ func (s *FilterService) ProcessData(input []DataRow, options map[string]interfac
 		return nil, fmt.Errorf("failed to parse filter options: %w", err)
 	}
 
+	if opts.Stream && len(input) == 0 && opts.InputFile != "" {
+		return s.runStream(opts)
+	}
+
 	// If input data is empty, try to read from file
 	if len(input) == 0 && opts.InputFile != "" {
 		var err error
@@ -60,32 +265,235 @@ func (s *FilterService) ProcessData(input []DataRow, options map[string]interfac
 		}
 	}
 
-	var filteredData []DataRow
+	outcome := &filterOutcome{stats: opts.stats}
+	selector := rowSelector{window: opts.Window}
+	outcome.warnings = s.checkRuleFields(opts, inputFields(input))
 
-	// Apply each filter rule to each row
-	for _, row := range input {
-		matches := s.matchesAllRules(row, opts.Rules)
+	// Select rows by position, then evaluate the rule tree against each of them
+	for i, row := range input {
+		if selected, _ := selector.next(); !selected {
+			continue
+		}
 
-		// Include row based on inclusive setting
-		if (opts.Inclusive && matches) || (!opts.Inclusive && !matches) {
-			filteredData = append(filteredData, row)
+		row, keep, err := s.keepRow(row, i, opts)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			outcome.rows = append(outcome.rows, row)
+		} else {
+			outcome.excluded++
+			if opts.RejectedOutputFile != "" {
+				outcome.rejected = append(outcome.rejected, row)
+			}
 		}
 	}
+	outcome.kept = len(outcome.rows)
+	outcome.skipped = selector.skipped
 
-	// Write results to file if output file specified
+	if err := s.writeOutcome(outcome, opts); err != nil {
+		return nil, err
+	}
+
+	s.logCompleted(len(input), outcome, opts)
+
+	return outcome, nil
+}
+
+// writeOutcome writes the kept rows to the output file and the rejected rows to the rejected
+// output file, when they are set.
+func (s *FilterService) writeOutcome(outcome *filterOutcome, opts *FilterOptions) error {
 	if opts.OutputFile != "" {
-		if err := s.fileService.WriteJSON(opts.OutputFile, filteredData); err != nil {
-			return nil, fmt.Errorf("failed to write filtered data: %w", err)
+		if err := s.fileService.WriteRows(opts.OutputFile, outcome.rows, opts.Output); err != nil {
+			return fmt.Errorf("failed to write filtered data: %w", err)
+		}
+	}
+
+	if opts.RejectedOutputFile != "" {
+		if err := s.fileService.WriteRows(opts.RejectedOutputFile, outcome.rejected, opts.Output); err != nil {
+			return fmt.Errorf("failed to write rejected data: %w", err)
+		}
+	}
+	return nil
+}
+
+// runStream filters the input file row by row, writing kept and rejected rows as they are read.
+// Reading stops as soon as no later row can be selected by position.
+func (s *FilterService) runStream(opts *FilterOptions) (*filterOutcome, error) {
+	if opts.OutputFile == "" {
+		return nil, errors.New("stream mode requires an output file")
+	}
+
+	writer, err := s.fileService.CreateRowWriter(opts.OutputFile, opts.Output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write filtered data: %w", err)
+	}
+	defer writer.Abort()
+
+	var rejectedWriter RowWriter
+	if opts.RejectedOutputFile != "" {
+		rejectedWriter, err = s.fileService.CreateRowWriter(opts.RejectedOutputFile, opts.Output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write rejected data: %w", err)
+		}
+		defer rejectedWriter.Abort()
+	}
+
+	outcome := &filterOutcome{stats: opts.stats}
+	selector := rowSelector{window: opts.Window}
+	read := 0
+
+	err = s.fileService.StreamCSV(opts.InputFile, func(row DataRow) error {
+		read++
+		if read == 1 {
+			outcome.warnings = s.checkRuleFields(opts, row.Keys())
+		}
+
+		selected, done := selector.next()
+		if selected {
+			if err := s.streamRow(row, read-1, opts, outcome, writer, rejectedWriter); err != nil {
+				return err
+			}
+		}
+		if done {
+			return ErrStopReading
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+	outcome.skipped = selector.skipped
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write filtered data: %w", err)
+	}
+	if rejectedWriter != nil {
+		if err := rejectedWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write rejected data: %w", err)
+		}
+	}
+
+	s.logCompleted(read, outcome, opts)
+
+	return outcome, nil
+}
+
+// streamRow filters the row at an index of the input in stream mode, writing it to the writer of
+// the kept rows or to the one of the rejected rows, if any.
+func (s *FilterService) streamRow(row DataRow, index int, opts *FilterOptions, outcome *filterOutcome, writer, rejectedWriter RowWriter) error {
+	row, keep, err := s.keepRow(row, index, opts)
+	if err != nil {
+		return err
+	}
+	if keep {
+		outcome.kept++
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write filtered data: %w", err)
+		}
+		return nil
+	}
+
+	outcome.excluded++
+	if rejectedWriter == nil {
+		return nil
+	}
+	if err := rejectedWriter.Write(row); err != nil {
+		return fmt.Errorf("failed to write rejected data: %w", err)
+	}
+	return nil
+}
+
+// inputFields returns every field name appearing in at least one row, in first-seen order.
+func inputFields(rows []DataRow) []string {
+	seen := make(map[string]struct{})
+	var fields []string
+	for _, row := range rows {
+		for _, field := range row.Keys() {
+			if _, ok := seen[field]; !ok {
+				seen[field] = struct{}{}
+				fields = append(fields, field)
+			}
 		}
 	}
+	return fields
+}
+
+// checkRuleFields warns about rule fields that are not among the fields of the input,
+// which usually means a typo in the rules. It returns the warnings.
+func (s *FilterService) checkRuleFields(opts *FilterOptions, fields []string) []string {
+	// derived fields exist on every evaluated row
+	for _, rule := range opts.Derive {
+		if rule.TargetField != "" && !slices.Contains(fields, rule.TargetField) {
+			fields = append(fields[:len(fields):len(fields)], rule.TargetField)
+		}
+	}
+
+	known := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		known[field] = struct{}{}
+	}
 
+	var warnings []string
+	reported := make(map[string]struct{})
+	for _, stats := range opts.stats {
+		if _, ok := known[stats.Field]; ok {
+			continue
+		}
+		if _, ok := reported[stats.Field]; ok {
+			continue
+		}
+		reported[stats.Field] = struct{}{}
+
+		s.logger.Warn().Str("field", stats.Field).Strs("available_fields", fields).Msg("Filter rule field appears in no row")
+		warnings = append(warnings, fmt.Sprintf("rule field '%s' appears in no row, available fields: %s", stats.Field, strings.Join(fields, ", ")))
+	}
+	return warnings
+}
+
+// keepRow reports whether the row at an index of the input is kept, given the rules or expression
+// and the inclusive setting.
+// It also returns the row to write, which carries the derived fields when they are kept.
+func (s *FilterService) keepRow(row DataRow, index int, opts *FilterOptions) (DataRow, bool, error) {
+	evaluated := row
+	if len(opts.Derive) > 0 {
+		derived, kept, err := s.transformService.deriveRow(row, index, opts.Derive)
+		if err != nil || !kept {
+			return row, false, err
+		}
+		evaluated = derived
+		if opts.KeepDerived {
+			row = evaluated
+		}
+	}
+
+	var matches bool
+	if opts.where != nil {
+		matches = opts.where.Matches(evaluated)
+	} else {
+		var err error
+		if matches, err = s.matchesGroup(evaluated, opts.rootGroup(), opts.stats); err != nil {
+			return row, false, err
+		}
+	}
+
+	if matches == opts.Inclusive {
+		return row, true, nil
+	}
+
+	s.logger.Debug().Str("location", row.Location()).Msg("Row filtered out")
+	return row, false, nil
+}
+
+// logCompleted logs the summary of a filter run.
+func (s *FilterService) logCompleted(read int, outcome *filterOutcome, opts *FilterOptions) {
 	s.logger.Info().
-		Int("input_records", len(input)).
-		Int("output_records", len(filteredData)).
+		Int("input_records", read).
+		Int("output_records", outcome.kept).
+		Int("skipped_by_position", outcome.skipped).
+		Int("excluded_by_rules", outcome.excluded).
 		Int("rules", len(opts.Rules)).
 		Msg("Data filtering completed")
-
-	return filteredData, nil
 }
 
 // GetName returns the processor name.
@@ -112,12 +520,237 @@ func (s *FilterService) parseFilterOptions(options map[string]interface{}) (*Fil
 		opts.OutputFile = outputFile
 	}
 
+	if rejectedOutputFile, ok := options["rejected_output_file"].(string); ok {
+		opts.RejectedOutputFile = rejectedOutputFile
+	}
+
 	if inclusive, ok := options["inclusive"].(bool); ok {
 		opts.Inclusive = inclusive
 	}
 
-	// Parse filter rules
-	if rulesRaw, ok := options["rules"].([]interface{}); ok {
+	outputOpts, err := parseOutputOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	opts.Output = outputOpts
+
+	if err := opts.parseComparisons(options); err != nil {
+		return nil, err
+	}
+
+	window, err := parseRowWindow(options)
+	if err != nil {
+		return nil, err
+	}
+	opts.Window = window
+
+	if stream, ok := options["stream"].(bool); ok {
+		opts.Stream = stream
+	}
+
+	// Parse filter rules and groups, either typed or decoded from generic JSON
+	if logic, ok := options["logic"].(string); ok {
+		opts.Logic = logic
+	}
+
+	opts.Rules = s.parseRules(options["rules"])
+	opts.Groups = s.parseGroups(options["groups"])
+
+	if err := s.parseDerive(opts, options); err != nil {
+		return nil, err
+	}
+
+	if err := opts.parseWhere(options); err != nil {
+		return nil, err
+	}
+
+	if err := opts.rootGroup().Validate(); err != nil {
+		return nil, err
+	}
+
+	// Validate rules and precompute what they need once, instead of once per row
+	root, err := s.prepareGroup(opts.rootGroup(), "", opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.Rules, opts.Groups = root.Rules, root.Groups
+
+	return opts, nil
+}
+
+// parseComparisons parses the options telling how values are compared: number format, date
+// layouts, epsilon and what rules do on missing fields.
+func (o *FilterOptions) parseComparisons(options map[string]interface{}) error {
+	var err error
+	format, _ := options["number_format"].(string)
+	if o.NumberFormat, err = ParseNumberFormat(format); err != nil {
+		return err
+	}
+
+	o.DateLayouts = parseDateLayouts(options["date_layouts"])
+
+	policy, _ := options["missing_field_policy"].(string)
+	if o.MissingFieldPolicy, err = ParseMissingFieldPolicy(policy); err != nil {
+		return err
+	}
+
+	if epsilon, ok := toFloat(options["epsilon"]); ok {
+		if epsilon < 0 {
+			return fmt.Errorf("epsilon must not be negative, got %v", epsilon)
+		}
+		o.Epsilon = epsilon
+	}
+	return nil
+}
+
+// parseDerive parses the derive rules, which must keep the rows filtered as they are.
+func (s *FilterService) parseDerive(opts *FilterOptions, options map[string]interface{}) error {
+	if keepDerived, ok := options["keep_derived"].(bool); ok {
+		opts.KeepDerived = keepDerived
+	}
+
+	var err error
+	if opts.Derive, err = s.transformService.parseTransformRules(options["derive"]); err != nil {
+		return fmt.Errorf("invalid derive rules: %w", err)
+	}
+	for _, rule := range opts.Derive {
+		if rule.Operation == Explode {
+			return fmt.Errorf("invalid derive rules: explode rule on field '%s' would change the rows filtered", rule.Field)
+		}
+	}
+	return nil
+}
+
+// parseWhere parses the where expression, exclusive with rules.
+func (o *FilterOptions) parseWhere(options map[string]interface{}) error {
+	where, ok := options["where"].(string)
+	if !ok || where == "" {
+		return nil
+	}
+	if len(o.Rules) > 0 || len(o.Groups) > 0 {
+		return errors.New("where and rules are mutually exclusive")
+	}
+	expression, err := ParseExpression(where)
+	if err != nil {
+		return fmt.Errorf("invalid where expression: %w", err)
+	}
+	o.Where, o.where = where, expression
+	return nil
+}
+
+// prepareGroup returns a copy of the group where every rule has been validated and prepared.
+// Each leaf rule also gets an entry in stats, named after its path in the tree.
+func (s *FilterService) prepareGroup(group FilterGroup, path string, opts *FilterOptions) (FilterGroup, error) {
+	prepared := FilterGroup{
+		Logic:  group.Logic,
+		Rules:  make([]FilterRule, 0, len(group.Rules)),
+		Groups: make([]FilterGroup, 0, len(group.Groups)),
+	}
+
+	for i, rule := range group.Rules {
+		rule, err := s.prepareRule(rule, opts)
+		if err != nil {
+			return prepared, err
+		}
+		rule.index = len(opts.stats)
+		opts.stats = append(opts.stats, RuleStats{
+			Rule:     fmt.Sprintf("%srules[%d]", path, i),
+			Field:    rule.Field,
+			Operator: rule.Operator,
+			Negate:   rule.Negate,
+		})
+		prepared.Rules = append(prepared.Rules, rule)
+	}
+
+	for i, nested := range group.Groups {
+		nested, err := s.prepareGroup(nested, fmt.Sprintf("%sgroups[%d].", path, i), opts)
+		if err != nil {
+			return prepared, err
+		}
+		prepared.Groups = append(prepared.Groups, nested)
+	}
+
+	return prepared, nil
+}
+
+// prepareRule validates a rule and parses its value when the operator needs it.
+// Filter-wide settings are copied to the rule, so that it can be evaluated on its own.
+func (s *FilterService) prepareRule(rule FilterRule, opts *FilterOptions) (FilterRule, error) {
+	rule.numbers = numberComparison{format: opts.NumberFormat, epsilon: opts.Epsilon}
+	rule.missingPolicy = opts.MissingFieldPolicy
+
+	var err error
+	switch rule.Operator {
+	case "between":
+		rule.between, err = parseBetweenRange(rule.Value, opts.DateLayouts)
+		if err != nil {
+			err = fmt.Errorf("invalid between rule on field '%s': %w", rule.Field, err)
+		}
+	case "regex":
+		err = rule.compileRegex()
+	case "fuzzy":
+		err = rule.prepareFuzzy()
+	case "in_file", "not_in_file":
+		err = s.loadRuleValues(&rule)
+	default:
+		if _, ok := parseLength(rule.Value); isLengthOperator(rule.Operator) && !ok {
+			err = fmt.Errorf("invalid %s rule on field '%s': value %v is not a non-negative integer", rule.Operator, rule.Field, rule.Value)
+		}
+	}
+	return rule, err
+}
+
+// compileRegex compiles the pattern of a regex rule, once instead of once per row.
+func (r *FilterRule) compileRegex() error {
+	pattern, ok := r.Value.(string)
+	if !ok {
+		return fmt.Errorf("invalid regex rule on field '%s': pattern must be a string", r.Field)
+	}
+	if !r.isCaseSensitive() {
+		pattern = "(?i)" + pattern
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regex pattern %q on field '%s': %w", r.Value, r.Field, err)
+	}
+	r.regex = regex
+	return nil
+}
+
+// prepareFuzzy checks the threshold of a fuzzy rule, DefaultFuzzyThreshold when 0, and folds its value.
+func (r *FilterRule) prepareFuzzy() error {
+	if r.Threshold == 0 {
+		r.Threshold = DefaultFuzzyThreshold
+	}
+	if r.Threshold < 0 || r.Threshold > 1 {
+		return fmt.Errorf("invalid fuzzy rule on field '%s': threshold %v is not between 0 and 1", r.Field, r.Threshold)
+	}
+	r.fuzzy = []rune(foldCase(fmt.Sprintf("%v", r.Value), r.isCaseSensitive()))
+	return nil
+}
+
+// loadRuleValues loads the values of the file of an "in_file" or "not_in_file" rule.
+func (s *FilterService) loadRuleValues(rule *FilterRule) error {
+	path, ok := rule.Value.(string)
+	if !ok || path == "" {
+		return fmt.Errorf("invalid %s rule on field '%s': value must be a file path", rule.Operator, rule.Field)
+	}
+	values, err := s.loadValueSet(path, rule.Column, rule.isCaseSensitive())
+	if err != nil {
+		return fmt.Errorf("invalid %s rule on field '%s': %w", rule.Operator, rule.Field, err)
+	}
+	rule.valueSet = values
+	return nil
+}
+
+// parseRules parses filter rules from a typed slice or generic JSON values.
+func (s *FilterService) parseRules(raw interface{}) []FilterRule {
+	if rules, ok := raw.([]FilterRule); ok {
+		return rules
+	}
+
+	var rules []FilterRule
+	if rulesRaw, ok := raw.([]interface{}); ok {
 		for _, ruleRaw := range rulesRaw {
 			if ruleMap, ok := ruleRaw.(map[string]interface{}); ok {
 				rule := FilterRule{
@@ -129,12 +762,89 @@ func (s *FilterService) parseFilterOptions(options map[string]interface{}) (*Fil
 					rule.Value = val
 				}
 
-				opts.Rules = append(opts.Rules, rule)
+				if caseSensitive, ok := ruleMap["case_sensitive"].(bool); ok {
+					rule.CaseSensitive = &caseSensitive
+				}
+
+				if threshold, ok := ruleMap["threshold"].(float64); ok {
+					rule.Threshold = threshold
+				}
+
+				if missingAsEmpty, ok := ruleMap["missing_as_empty"].(bool); ok {
+					rule.MissingAsEmpty = missingAsEmpty
+				}
+
+				rule.Column = s.getString(ruleMap, "column")
+
+				if negate, ok := ruleMap["negate"].(bool); ok {
+					rule.Negate = negate
+				}
+
+				rules = append(rules, rule)
 			}
 		}
 	}
 
-	return opts, nil
+	return rules
+}
+
+// parseGroups parses nested filter groups from a typed slice or generic JSON values.
+func (s *FilterService) parseGroups(raw interface{}) []FilterGroup {
+	if groups, ok := raw.([]FilterGroup); ok {
+		return groups
+	}
+
+	var groups []FilterGroup
+	if groupsRaw, ok := raw.([]interface{}); ok {
+		for _, groupRaw := range groupsRaw {
+			if groupMap, ok := groupRaw.(map[string]interface{}); ok {
+				groups = append(groups, FilterGroup{
+					Logic:  s.getString(groupMap, "logic"),
+					Rules:  s.parseRules(groupMap["rules"]),
+					Groups: s.parseGroups(groupMap["groups"]),
+				})
+			}
+		}
+	}
+
+	return groups
+}
+
+// parseConditions parses rules evaluated outside of a filter run, like the having clause of
+// an aggregation. They may be given as a FilterGroup, a flat list of rules combined with AND,
+// a single rule, or their generic JSON forms. The result is ready for matchConditions.
+func (s *FilterService) parseConditions(raw interface{}) (FilterGroup, error) {
+	var group FilterGroup
+	switch conditions := raw.(type) {
+	case FilterGroup:
+		group = conditions
+	case FilterRule:
+		group.Rules = []FilterRule{conditions}
+	case []FilterRule, []interface{}:
+		group.Rules = s.parseRules(conditions)
+	case map[string]interface{}:
+		if _, ok := conditions["operator"]; ok {
+			group.Rules = s.parseRules([]interface{}{conditions})
+			break
+		}
+		group = FilterGroup{
+			Logic:  s.getString(conditions, "logic"),
+			Rules:  s.parseRules(conditions["rules"]),
+			Groups: s.parseGroups(conditions["groups"]),
+		}
+	}
+
+	if err := group.Validate(); err != nil {
+		return group, err
+	}
+	return s.prepareGroup(group, "", &FilterOptions{MissingFieldPolicy: MissingFieldPolicyExclude})
+}
+
+// matchConditions reports whether a row matches rules returned by parseConditions.
+func (s *FilterService) matchConditions(row DataRow, conditions FilterGroup) bool {
+	// missing fields only fail rules under the exclude policy, so there is no error to handle
+	matches, _ := s.matchesGroup(row, conditions, nil)
+	return matches
 }
 
 // getString helper to safely get string from map.
@@ -145,97 +855,395 @@ func (s *FilterService) getString(m map[string]interface{}, key string) string {
 	return ""
 }
 
-// matchesAllRules checks if a row matches all filter rules.
-func (s *FilterService) matchesAllRules(row DataRow, rules []FilterRule) bool {
-	for _, rule := range rules {
-		if !s.matchesRule(row, rule) {
-			return false
+// matchesGroup evaluates a group of rules against a row, short-circuiting
+// as soon as the result of the group is known. Rule outcomes are counted in stats unless it is nil.
+func (s *FilterService) matchesGroup(row DataRow, group FilterGroup, stats []RuleStats) (bool, error) {
+	isOr := group.isOr()
+
+	for _, rule := range group.Rules {
+		matches, err := s.evaluateRule(row, rule)
+		if err != nil {
+			return false, err
+		}
+		if stats != nil {
+			stats[rule.index].record(row, rule, matches)
+		}
+		if matches == isOr {
+			return isOr, nil
 		}
 	}
-	return true
+
+	for _, nested := range group.Groups {
+		matches, err := s.matchesGroup(row, nested, stats)
+		if err != nil {
+			return false, err
+		}
+		if matches == isOr {
+			return isOr, nil
+		}
+	}
+
+	return !isOr, nil
+}
+
+// evaluateRule returns the result of a rule on a row, negation included.
+// When the field is missing the missing field policy of the rule decides the result before negation.
+func (s *FilterService) evaluateRule(row DataRow, rule FilterRule) (bool, error) {
+	if _, exists := row.Fields[rule.Field]; !exists && !(rule.MissingAsEmpty && isLengthOperator(rule.Operator)) {
+		//nolint:exhaustive
+		switch rule.missingPolicy {
+		case MissingFieldPolicyInclude:
+			return !rule.Negate, nil
+		case MissingFieldPolicyError:
+			return false, &MissingFieldError{Field: rule.Field, Location: row.Location(), Headers: row.Keys()}
+		default:
+			return rule.Negate, nil
+		}
+	}
+
+	return s.matchesRule(row, rule) != rule.Negate, nil
+}
+
+// negatedOperators are the operators matching the rows their positive operator does not,
+// but for rows without the field, which match neither.
+var negatedOperators = map[string]string{
+	"not_equals":   "equals",
+	"not_contains": "contains",
+	"not_in":       "in",
+	"not_in_file":  "in_file",
+}
+
+// textOperators match a value containing, starting or ending with the rule value.
+var textOperators = map[string]func(value, part string) bool{
+	"contains":    strings.Contains,
+	"starts_with": strings.HasPrefix,
+	"ends_with":   strings.HasSuffix,
 }
 
 // matchesRule checks if a row matches a single filter rule.
 func (s *FilterService) matchesRule(row DataRow, rule FilterRule) bool {
 	fieldValue, exists := row.Fields[rule.Field]
-	if !exists {
+	if !exists && !(rule.MissingAsEmpty && isLengthOperator(rule.Operator)) {
 		return false
 	}
 
+	if positive, ok := negatedOperators[rule.Operator]; ok {
+		rule.Operator = positive
+		return !s.matchesOperator(row, fieldValue, rule)
+	}
+	return s.matchesOperator(row, fieldValue, rule)
+}
+
+// matchesOperator checks if the value of a field matches the operator of a rule, negated ones excepted.
+func (s *FilterService) matchesOperator(row DataRow, fieldValue string, rule FilterRule) bool {
+	caseSensitive := rule.isCaseSensitive()
+	if matches, ok := textOperators[rule.Operator]; ok {
+		return matches(foldCase(fieldValue, caseSensitive), foldCase(fmt.Sprintf("%v", rule.Value), caseSensitive))
+	}
+
 	switch rule.Operator {
 	case "equals":
-		return s.compareValues(fieldValue, rule.Value)
-	case "not_equals":
-		return !s.compareValues(fieldValue, rule.Value)
-	case "contains":
-		return strings.Contains(strings.ToLower(fieldValue), strings.ToLower(fmt.Sprintf("%v", rule.Value)))
-	case "not_contains":
-		return !strings.Contains(strings.ToLower(fieldValue), strings.ToLower(fmt.Sprintf("%v", rule.Value)))
-	case "starts_with":
-		return strings.HasPrefix(strings.ToLower(fieldValue), strings.ToLower(fmt.Sprintf("%v", rule.Value)))
-	case "ends_with":
-		return strings.HasSuffix(strings.ToLower(fieldValue), strings.ToLower(fmt.Sprintf("%v", rule.Value)))
+		return s.compareValues(fieldValue, rule.Value, caseSensitive, rule.numbers)
 	case "regex":
-		if pattern, ok := rule.Value.(string); ok {
-			matched, err := regexp.MatchString(pattern, fieldValue)
-			return err == nil && matched
-		}
-		return false
+		return matchesRegex(fieldValue, rule, caseSensitive)
+	case "in":
+		return s.matchesAny(fieldValue, rule.Value, caseSensitive, rule.numbers)
+	case "between":
+		return s.matchesBetween(row, fieldValue, rule)
+	case "fuzzy":
+		return s.matchesFuzzy(fieldValue, rule)
+	case "length_gt", "length_lt", "length_eq":
+		return s.matchesLength(fieldValue, rule)
+	case "in_file":
+		_, found := rule.valueSet[foldCase(fieldValue, caseSensitive)]
+		return found
 	case "greater_than":
-		return s.numericCompare(fieldValue, rule.Value, true)
+		return s.numericCompare(fieldValue, rule.Value, true, rule.numbers)
 	case "less_than":
-		return s.numericCompare(fieldValue, rule.Value, false)
+		return s.numericCompare(fieldValue, rule.Value, false, rule.numbers)
 	default:
-		s.logger.Warn().Str("operator", rule.Operator).Msg("Unknown filter operator")
+		s.logger.Warn().Str("operator", rule.Operator).Str("location", row.Location()).Msg("Unknown filter operator")
+		return false
+	}
+}
+
+// matchesRegex checks a value against the compiled pattern of a regex rule, or compiles it
+// when the rule was not prepared. Invalid patterns match nothing.
+func matchesRegex(fieldValue string, rule FilterRule, caseSensitive bool) bool {
+	if rule.regex != nil {
+		return rule.regex.MatchString(fieldValue)
+	}
+	pattern, ok := rule.Value.(string)
+	if !ok {
 		return false
 	}
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	matched, err := regexp.MatchString(pattern, fieldValue)
+	return err == nil && matched
+}
+
+// foldCase lowercases a value unless the comparison is case-sensitive.
+func foldCase(value string, caseSensitive bool) string {
+	if caseSensitive {
+		return value
+	}
+	return strings.ToLower(value)
 }
 
 // compareValues compares two values with type conversion.
-func (s *FilterService) compareValues(a string, b interface{}) bool {
+// Strings are compared as numbers too once a number format or an epsilon is configured.
+func (s *FilterService) compareValues(a string, b interface{}, caseSensitive bool, numbers numberComparison) bool {
 	switch v := b.(type) {
 	case string:
-		return strings.EqualFold(a, v)
-	case int, int64, float64:
-		// Try to convert string to number
-		if num, err := strconv.ParseFloat(a, 64); err == nil { //nolint:nestif
-			if strVal, ok := v.(string); ok {
-				if strNum, err := strconv.ParseFloat(strVal, 64); err == nil {
-					return num == strNum
-				}
-			} else if numVal, ok := v.(float64); ok {
-				return num == numVal
-			} else if intVal, ok := v.(int); ok {
-				return num == float64(intVal)
-			}
+		if caseSensitive && a == v || !caseSensitive && strings.EqualFold(a, v) {
+			return true
 		}
-		return false
+		if !numbers.lenient() {
+			return false
+		}
+		aNum, aOk := numbers.parse(a)
+		bNum, bOk := numbers.parse(v)
+		return aOk && bOk && numbers.equal(aNum, bNum)
+	case int, int64, float64:
+		num, ok := numbers.parse(a)
+		bNum, _ := toFloat(v)
+		return ok && numbers.equal(num, bNum)
 	default:
 		return a == fmt.Sprintf("%v", v)
 	}
 }
 
-// numericCompare performs numeric comparison.
-func (s *FilterService) numericCompare(a string, b interface{}, greater bool) bool {
-	aNum, err1 := strconv.ParseFloat(a, 64)
-	var bNum float64
+// matchesAny checks if a value equals any member of a list, following the
+// comparison rules of "equals". An empty list matches nothing.
+func (s *FilterService) matchesAny(a string, list interface{}, caseSensitive bool, numbers numberComparison) bool {
+	for _, candidate := range s.listValues(list) {
+		if s.compareValues(a, candidate, caseSensitive, numbers) {
+			return true
+		}
+	}
+	return false
+}
 
-	switch v := b.(type) {
+// listValues converts a rule value to a list: JSON arrays are used as is and
+// strings are split on commas for convenience from the command line.
+func (s *FilterService) listValues(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, item)
+		}
+		return values
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		parts := strings.Split(v, ",")
+		values := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			values = append(values, strings.TrimSpace(part))
+		}
+		return values
+	case nil:
+		return nil
+	default:
+		return []interface{}{v}
+	}
+}
+
+// parseBetweenRange parses a value like {"min": 100, "max": 500, "inclusive": true}.
+// Bounds are numbers, or dates when both parse with the given layouts or as ISO-8601 dates.
+// Date values are then parsed with the given layouts.
+func parseBetweenRange(value interface{}, layouts []string) (*betweenRange, error) {
+	bounds, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`value must be an object like {"min": 1, "max": 10}`)
+	}
+
+	between := &betweenRange{inclusive: true, layouts: layouts}
+	if inclusive, ok := bounds["inclusive"].(bool); ok {
+		between.inclusive = inclusive
+	}
+
+	minNum, minIsNum := toFloat(bounds["min"])
+	maxNum, maxIsNum := toFloat(bounds["max"])
+	if minIsNum && maxIsNum {
+		if minNum > maxNum {
+			return nil, fmt.Errorf("min %v is greater than max %v", minNum, maxNum)
+		}
+		between.min, between.max = minNum, maxNum
+		return between, nil
+	}
+
+	minStr, _ := bounds["min"].(string)
+	maxStr, _ := bounds["max"].(string)
+	boundLayouts := append(layouts[:len(layouts):len(layouts)], DefaultDateLayouts...)
+	minDate, minIsDate := parseDate(minStr, boundLayouts)
+	maxDate, maxIsDate := parseDate(maxStr, boundLayouts)
+	if minIsDate && maxIsDate {
+		if minDate.After(maxDate) {
+			return nil, fmt.Errorf("min %s is after max %s", minStr, maxStr)
+		}
+		between.dates = true
+		between.minDate, between.maxDate = minDate, maxDate
+		return between, nil
+	}
+
+	return nil, errors.New("min and max must both be numbers or both be ISO-8601 dates")
+}
+
+// toFloat converts a JSON number or numeric string to a float.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
 	case float64:
-		bNum = v
+		return v, true
 	case int:
-		bNum = float64(v)
+		return float64(v), true
+	case int64:
+		return float64(v), true
 	case string:
-		var err2 error
-		bNum, err2 = strconv.ParseFloat(v, 64)
-		if err2 != nil {
+		num, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return num, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// matchesBetween checks if a value falls within the bounds of a "between" rule.
+func (s *FilterService) matchesBetween(row DataRow, value string, rule FilterRule) bool {
+	between := rule.between
+	if between == nil {
+		var err error
+		if between, err = parseBetweenRange(rule.Value, nil); err != nil {
+			s.logger.Warn().Err(err).Str("field", rule.Field).Msg("Invalid between rule")
 			return false
 		}
-	default:
+	}
+
+	if between.dates {
+		date, ok := parseDate(value, between.layouts)
+		if !ok {
+			s.logger.Warn().Str("field", rule.Field).Str("value", value).Str("location", row.Location()).Msg("Value is not a date for between comparison")
+			return false
+		}
+		if between.inclusive {
+			return !date.Before(between.minDate) && !date.After(between.maxDate)
+		}
+		return date.After(between.minDate) && date.Before(between.maxDate)
+	}
+
+	num, ok := rule.numbers.parse(value)
+	if !ok {
+		s.logger.Warn().Str("field", rule.Field).Str("value", value).Str("location", row.Location()).Msg("Value is not numeric for between comparison")
+		return false
+	}
+	if between.inclusive {
+		return num >= between.min && num <= between.max
+	}
+	return num > between.min && num < between.max
+}
+
+// matchesFuzzy checks if a value is similar enough to the value of a "fuzzy" rule.
+func (s *FilterService) matchesFuzzy(value string, rule FilterRule) bool {
+	target, threshold := rule.fuzzy, rule.Threshold
+	if target == nil {
+		target = []rune(foldCase(fmt.Sprintf("%v", rule.Value), rule.isCaseSensitive()))
+	}
+	if threshold == 0 {
+		threshold = DefaultFuzzyThreshold
+	}
+	return fuzzyMatches([]rune(foldCase(value, rule.isCaseSensitive())), target, threshold)
+}
+
+// loadValueSet loads the values of an "in_file" rule, one per line of a text file,
+// or from a column of a CSV file when column is set.
+func (s *FilterService) loadValueSet(path, column string, caseSensitive bool) (map[string]struct{}, error) {
+	values := make(map[string]struct{})
+
+	if column != "" {
+		err := s.fileService.StreamCSV(path, func(row DataRow) error {
+			value, exists := row.Fields[column]
+			if !exists {
+				return fmt.Errorf("column '%s' not found in %s", column, path)
+			}
+			values[foldCase(value, caseSensitive)] = struct{}{}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open values file: %w", err)
+		}
+		defer file.Close() //nolint:errcheck
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if value := strings.TrimSpace(scanner.Text()); value != "" {
+				values[foldCase(value, caseSensitive)] = struct{}{}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read values file: %w", err)
+		}
+	}
+
+	s.logger.Info().Str("file", path).Str("column", column).Int("values", len(values)).Msg("Loaded filter values")
+	return values, nil
+}
+
+// isLengthOperator reports whether an operator compares the length of values.
+func isLengthOperator(operator string) bool {
+	return operator == "length_gt" || operator == "length_lt" || operator == "length_eq"
+}
+
+// parseLength converts the value of a length rule to a number of characters.
+func parseLength(value interface{}) (int, bool) {
+	length, ok := toFloat(value)
+	if !ok || length < 0 || length != float64(int(length)) {
+		return 0, false
+	}
+	return int(length), true
+}
+
+// matchesLength compares the number of runes of a value to the value of a length rule.
+// Combining characters and each code point of composed emoji count separately.
+func (s *FilterService) matchesLength(value string, rule FilterRule) bool {
+	expected, ok := parseLength(rule.Value)
+	if !ok {
 		return false
 	}
 
-	if err1 != nil {
+	length := utf8.RuneCountInString(value)
+	switch rule.Operator {
+	case "length_gt":
+		return length > expected
+	case "length_lt":
+		return length < expected
+	default:
+		return length == expected
+	}
+}
+
+// numericCompare performs numeric comparison.
+func (s *FilterService) numericCompare(a string, b interface{}, greater bool, numbers numberComparison) bool {
+	aNum, aOk := numbers.parse(a)
+
+	var bNum float64
+	var bOk bool
+	if str, ok := b.(string); ok {
+		bNum, bOk = numbers.parse(str)
+	} else {
+		bNum, bOk = toFloat(b)
+	}
+
+	if !aOk || !bOk {
 		return false
 	}
 
@@ -245,24 +1253,28 @@ func (s *FilterService) numericCompare(a string, b interface{}, greater bool) bo
 	return aNum < bNum
 }
 
-// FilterByFile filters data from a file using filter rules
+// FilterByFile filters data from a file using a tree of filter rules
 // This convenience method demonstrates file-based filtering.
-func (s *FilterService) FilterByFile(inputFile, outputFile string, rules []FilterRule, inclusive bool) (*ProcessingResult, error) {
+// extraOptions may carry any additional ProcessData option and can be nil.
+func (s *FilterService) FilterByFile(inputFile, outputFile string, filter FilterGroup, inclusive bool, extraOptions map[string]interface{}) (*ProcessingResult, error) {
 	s.logger.Info().
 		Str("input", inputFile).
 		Str("output", outputFile).
-		Int("rules", len(rules)).
+		Int("rules", len(filter.Rules)).
+		Int("groups", len(filter.Groups)).
 		Bool("inclusive", inclusive).
 		Msg("Starting file filtering")
 
-	options := map[string]interface{}{
+	options := mergeOptions(map[string]interface{}{
 		"input_file":  inputFile,
 		"output_file": outputFile,
-		"rules":       rules,
+		"rules":       filter.Rules,
+		"logic":       filter.Logic,
+		"groups":      filter.Groups,
 		"inclusive":   inclusive,
-	}
+	}, extraOptions)
 
-	filteredData, err := s.ProcessData(nil, options)
+	outcome, err := s.run(nil, options)
 	if err != nil {
 		return &ProcessingResult{
 			Success:   false,
@@ -274,7 +1286,11 @@ func (s *FilterService) FilterByFile(inputFile, outputFile string, rules []Filte
 
 	return &ProcessingResult{
 		Success:    true,
-		Processed:  len(filteredData),
+		Processed:  outcome.kept,
+		Skipped:    outcome.skipped,
+		Excluded:   outcome.excluded,
+		Stats:      outcome.stats,
+		Warnings:   outcome.warnings,
 		OutputPath: outputFile,
 		Processor:  s.GetName(),
 	}, nil
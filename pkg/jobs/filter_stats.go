@@ -0,0 +1,56 @@
+package jobs
+
+// RuleStats counts how the rows evaluated by a single filter rule were decided.
+// Rules skipped because their group was already decided are not evaluated.
+// Matched and Failed count the result after negation. Missing rows are decided by
+// the missing field policy, Unparseable rows fail unless the rule is negated.
+type RuleStats struct {
+	Rule        string `json:"rule"` // position of the rule, like "rules[0]" or "groups[1].rules[0]"
+	Field       string `json:"field"`
+	Operator    string `json:"operator"`
+	Negate      bool   `json:"negate,omitempty"`
+	Evaluated   int    `json:"evaluated"`
+	Matched     int    `json:"matched"`
+	Failed      int    `json:"failed"`
+	Missing     int    `json:"missing"`     // the row has no such field
+	Unparseable int    `json:"unparseable"` // the value is not a number or date as the operator needs
+}
+
+// record counts the outcome of a rule on a row.
+func (rs *RuleStats) record(row DataRow, rule FilterRule, matched bool) {
+	rs.Evaluated++
+	if matched {
+		rs.Matched++
+	} else {
+		rs.Failed++
+	}
+
+	value, exists := row.Fields[rule.Field]
+	if !exists {
+		rs.Missing++
+		return
+	}
+
+	// the value parses whenever the rule itself matched
+	if matched == rule.Negate && !ruleCanParse(rule, value) {
+		rs.Unparseable++
+	}
+}
+
+// ruleCanParse reports whether a value can be parsed as the operator of the rule requires.
+func ruleCanParse(rule FilterRule, value string) bool {
+	switch rule.Operator {
+	case "greater_than", "less_than":
+		_, ok := rule.numbers.parse(value)
+		return ok
+	case "between":
+		if rule.between != nil && rule.between.dates {
+			_, ok := parseDate(value, rule.between.layouts)
+			return ok
+		}
+		_, ok := rule.numbers.parse(value)
+		return ok
+	default:
+		return true
+	}
+}
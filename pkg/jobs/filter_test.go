@@ -0,0 +1,901 @@
+package jobs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFilterService(t *testing.T) *FilterService {
+	t.Helper()
+
+	return &FilterService{
+		fileService:      newTestFileService(t),
+		transformService: newTestTransformService(t),
+		logger:           zerolog.Nop(),
+	}
+}
+
+func testRows(t testing.TB, columns []string, values ...[]string) []DataRow {
+	t.Helper()
+
+	rows := make([]DataRow, 0, len(values))
+	for i, record := range values {
+		row := DataRow{Fields: make(map[string]string), Columns: columns, LineNumber: i + 2}
+		for j, value := range record {
+			row.Fields[columns[j]] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func filteredNames(rows []DataRow) []string {
+	names := []string{}
+	for _, row := range rows {
+		names = append(names, row.Fields["name"])
+	}
+	return names
+}
+
+func TestParseFilterRules(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	group, err := ParseFilterRules([]byte(`[{"field":"status","operator":"equals","value":"active"}]`))
+	is.NoError(err)
+	is.Empty(group.Logic)
+	is.Len(group.Rules, 1)
+
+	group, err = ParseFilterRules([]byte(` {"logic":"or","rules":[{"field":"a","operator":"equals","value":"1"}],"groups":[{"logic":"and","rules":[]}]}`))
+	is.NoError(err)
+	is.Equal(FilterLogicOr, group.Logic)
+	is.Len(group.Rules, 1)
+	is.Len(group.Groups, 1)
+
+	_, err = ParseFilterRules([]byte(`{"logic":"xor"}`))
+	is.Error(err)
+
+	_, err = ParseFilterRules([]byte(`{"groups":[{"groups":[{"logic":"nand"}]}]}`))
+	is.Error(err)
+}
+
+func TestFilterService_nestedGroups(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestFilterService(t)
+	input := testRows(t, []string{"name", "status", "country", "amount"},
+		[]string{"alice", "active", "FR", "50"},
+		[]string{"bob", "trial", "DE", "500"},
+		[]string{"carol", "churned", "FR", "900"},
+		[]string{"dave", "trial", "US", "900"},
+		[]string{"erin", "active", "US", "10"},
+	)
+
+	// status in (active, trial) AND (country = FR OR (country = US AND (amount > 100 OR name = erin)))
+	filter := FilterGroup{
+		Groups: []FilterGroup{
+			{
+				Logic: FilterLogicOr,
+				Rules: []FilterRule{
+					{Field: "status", Operator: "equals", Value: "active"},
+					{Field: "status", Operator: "equals", Value: "trial"},
+				},
+			},
+			{
+				Logic: FilterLogicOr,
+				Rules: []FilterRule{{Field: "country", Operator: "equals", Value: "FR"}},
+				Groups: []FilterGroup{
+					{
+						Rules: []FilterRule{{Field: "country", Operator: "equals", Value: "US"}},
+						Groups: []FilterGroup{
+							{
+								Logic: FilterLogicOr,
+								Rules: []FilterRule{
+									{Field: "amount", Operator: "greater_than", Value: 100.0},
+									{Field: "name", Operator: "equals", Value: "erin"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	output, err := service.ProcessData(input, map[string]interface{}{
+		"rules":  filter.Rules,
+		"groups": filter.Groups,
+	})
+	is.NoError(err)
+	is.Equal([]string{"alice", "dave", "erin"}, filteredNames(output))
+
+	// the same tree decoded from JSON
+	group, err := ParseFilterRules([]byte(`{"groups":[
+		{"logic":"or","rules":[{"field":"status","operator":"equals","value":"active"},{"field":"status","operator":"equals","value":"trial"}]},
+		{"logic":"or","rules":[{"field":"country","operator":"equals","value":"FR"}],"groups":[
+			{"rules":[{"field":"country","operator":"equals","value":"US"}],"groups":[
+				{"logic":"or","rules":[{"field":"amount","operator":"greater_than","value":100},{"field":"name","operator":"equals","value":"erin"}]}
+			]}
+		]}
+	]}`))
+	is.NoError(err)
+
+	output, err = service.ProcessData(input, map[string]interface{}{
+		"logic":  group.Logic,
+		"rules":  group.Rules,
+		"groups": group.Groups,
+	})
+	is.NoError(err)
+	is.Equal([]string{"alice", "dave", "erin"}, filteredNames(output))
+
+	// top-level OR with the flat rules
+	output, err = service.ProcessData(input, map[string]interface{}{
+		"logic": FilterLogicOr,
+		"rules": []interface{}{
+			map[string]interface{}{"field": "status", "operator": "equals", "value": "churned"},
+			map[string]interface{}{"field": "name", "operator": "equals", "value": "bob"},
+		},
+	})
+	is.NoError(err)
+	is.Equal([]string{"bob", "carol"}, filteredNames(output))
+}
+
+func TestFilterService_flatRulesAreAnd(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestFilterService(t)
+	input := testRows(t, []string{"name", "status", "country"},
+		[]string{"alice", "active", "FR"},
+		[]string{"bob", "active", "DE"},
+	)
+
+	output, err := service.ProcessData(input, map[string]interface{}{
+		"rules": []FilterRule{
+			{Field: "status", Operator: "equals", Value: "active"},
+			{Field: "country", Operator: "equals", Value: "DE"},
+		},
+	})
+	is.NoError(err)
+	is.Equal([]string{"bob"}, filteredNames(output))
+
+	output, err = service.ProcessData(input, map[string]interface{}{
+		"inclusive": false,
+		"rules":     []FilterRule{{Field: "country", Operator: "equals", Value: "DE"}},
+	})
+	is.NoError(err)
+	is.Equal([]string{"alice"}, filteredNames(output))
+}
+
+func TestFilterService_inOperators(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestFilterService(t)
+	input := testRows(t, []string{"name", "country", "code"},
+		[]string{"alice", "FR", "1"},
+		[]string{"bob", "de", "2"},
+		[]string{"carol", "IT", "3.0"},
+		[]string{"dave", "US", "4"},
+	)
+
+	testCases := []struct {
+		name     string
+		rule     FilterRule
+		expected []string
+	}{
+		{"in strings case-insensitive", FilterRule{Field: "country", Operator: "in", Value: []interface{}{"FR", "DE", "it"}}, []string{"alice", "bob", "carol"}},
+		{"not_in strings", FilterRule{Field: "country", Operator: "not_in", Value: []interface{}{"FR", "DE"}}, []string{"carol", "dave"}},
+		{"in numbers", FilterRule{Field: "code", Operator: "in", Value: []interface{}{1.0, 3.0}}, []string{"alice", "carol"}},
+		{"in comma separated", FilterRule{Field: "country", Operator: "in", Value: "fr, US"}, []string{"alice", "dave"}},
+		{"in empty", FilterRule{Field: "country", Operator: "in", Value: []interface{}{}}, []string{}},
+		{"not_in empty", FilterRule{Field: "country", Operator: "not_in", Value: []interface{}{}}, []string{"alice", "bob", "carol", "dave"}},
+	}
+
+	for _, tc := range testCases {
+		output, err := service.ProcessData(input, map[string]interface{}{"rules": []FilterRule{tc.rule}})
+		is.NoError(err, tc.name)
+		is.Equal(tc.expected, filteredNames(output), tc.name)
+	}
+}
+
+func TestFilterService_between(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestFilterService(t)
+	input := testRows(t, []string{"name", "amount", "created_at"},
+		[]string{"alice", "100", "2024-01-01"},
+		[]string{"bob", "250.5", "2024-03-15T10:00:00Z"},
+		[]string{"carol", "500", "2024-06-30"},
+		[]string{"dave", "n/a", "not a date"},
+	)
+
+	testCases := []struct {
+		name     string
+		value    map[string]interface{}
+		field    string
+		expected []string
+	}{
+		{"numeric inclusive by default", map[string]interface{}{"min": 100.0, "max": 500.0}, "amount", []string{"alice", "bob", "carol"}},
+		{"numeric exclusive", map[string]interface{}{"min": 100.0, "max": 500.0, "inclusive": false}, "amount", []string{"bob"}},
+		{"numeric string bounds", map[string]interface{}{"min": "200", "max": "1000"}, "amount", []string{"bob", "carol"}},
+		{"dates", map[string]interface{}{"min": "2024-02-01", "max": "2024-06-30"}, "created_at", []string{"bob", "carol"}},
+		{"dates exclusive", map[string]interface{}{"min": "2024-01-01", "max": "2024-06-30", "inclusive": false}, "created_at", []string{"bob"}},
+	}
+
+	for _, tc := range testCases {
+		output, err := service.ProcessData(input, map[string]interface{}{
+			"rules": []FilterRule{{Field: tc.field, Operator: "between", Value: tc.value}},
+		})
+		is.NoError(err, tc.name)
+		is.Equal(tc.expected, filteredNames(output), tc.name)
+	}
+}
+
+func TestFilterService_betweenValidation(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestFilterService(t)
+
+	invalid := []interface{}{
+		map[string]interface{}{"min": 500.0, "max": 100.0},
+		map[string]interface{}{"min": "2024-12-31", "max": "2024-01-01"},
+		map[string]interface{}{"min": 1.0, "max": "2024-01-01"},
+		"100..500",
+	}
+
+	for _, value := range invalid {
+		_, err := service.ProcessData(nil, map[string]interface{}{
+			"groups": []FilterGroup{{Rules: []FilterRule{{Field: "amount", Operator: "between", Value: value}}}},
+		})
+		is.Error(err, value)
+	}
+}
+
+func TestFilterService_caseSensitivity(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestFilterService(t)
+	input := testRows(t, []string{"name", "code"},
+		[]string{"alice", "US"},
+		[]string{"bob", "us"},
+		[]string{"carol", "USD"},
+		[]string{"dave", "aGVsbG8="},
+	)
+
+	sensitive, insensitive := true, false
+
+	testCases := []struct {
+		name     string
+		rules    []FilterRule
+		expected []string
+	}{
+		{"equals default", []FilterRule{{Field: "code", Operator: "equals", Value: "us"}}, []string{"alice", "bob"}},
+		{"equals sensitive", []FilterRule{{Field: "code", Operator: "equals", Value: "us", CaseSensitive: &sensitive}}, []string{"bob"}},
+		{"contains sensitive", []FilterRule{{Field: "code", Operator: "contains", Value: "US", CaseSensitive: &sensitive}}, []string{"alice", "carol"}},
+		{"not_contains sensitive", []FilterRule{{Field: "code", Operator: "not_contains", Value: "US", CaseSensitive: &sensitive}}, []string{"bob", "dave"}},
+		{"starts_with sensitive", []FilterRule{{Field: "code", Operator: "starts_with", Value: "aG", CaseSensitive: &sensitive}}, []string{"dave"}},
+		{"ends_with sensitive", []FilterRule{{Field: "code", Operator: "ends_with", Value: "SD", CaseSensitive: &sensitive}}, []string{"carol"}},
+		{"in sensitive", []FilterRule{{Field: "code", Operator: "in", Value: []interface{}{"us", "USD"}, CaseSensitive: &sensitive}}, []string{"bob", "carol"}},
+		{"regex default sensitive", []FilterRule{{Field: "code", Operator: "regex", Value: "^us$"}}, []string{"bob"}},
+		{"regex insensitive", []FilterRule{{Field: "code", Operator: "regex", Value: "^us$", CaseSensitive: &insensitive}}, []string{"alice", "bob"}},
+		{
+			"mixed rules",
+			[]FilterRule{
+				{Field: "code", Operator: "starts_with", Value: "us"},
+				{Field: "code", Operator: "not_equals", Value: "US", CaseSensitive: &sensitive},
+			},
+			[]string{"bob", "carol"},
+		},
+	}
+
+	for _, tc := range testCases {
+		output, err := service.ProcessData(input, map[string]interface{}{"rules": tc.rules})
+		is.NoError(err, tc.name)
+		is.Equal(tc.expected, filteredNames(output), tc.name)
+	}
+
+	// case_sensitive from generic JSON
+	output, err := service.ProcessData(input, map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"field": "code", "operator": "equals", "value": "US", "case_sensitive": true},
+		},
+	})
+	is.NoError(err)
+	is.Equal([]string{"alice"}, filteredNames(output))
+}
+
+func TestFilterService_rowWindow(t *testing.T) {
+	t.Parallel()
+
+	columns := []string{"name", "age"}
+	rows := testRows(t, columns,
+		[]string{"r1", "10"}, []string{"r2", "20"}, []string{"r3", "30"}, []string{"r4", "40"},
+		[]string{"r5", "50"}, []string{"r6", "60"}, []string{"r7", "70"},
+	)
+	adults := []FilterRule{{Field: "age", Operator: "greater_than", Value: "35"}}
+
+	testCases := []struct {
+		name     string
+		options  map[string]interface{}
+		expected []string
+		skipped  int
+		excluded int
+	}{
+		{
+			name:     "offset and limit",
+			options:  map[string]interface{}{"offset": 1, "limit": 2},
+			expected: []string{"r2", "r3"},
+			skipped:  5,
+		},
+		{
+			name:     "row range",
+			options:  map[string]interface{}{"row_range": []interface{}{float64(3), float64(5)}},
+			expected: []string{"r3", "r4", "r5"},
+			skipped:  4,
+		},
+		{
+			name:     "range with offset",
+			options:  map[string]interface{}{"row_range": []int{2, 6}, "offset": 3},
+			expected: []string{"r5", "r6"},
+			skipped:  5,
+		},
+		{
+			name:     "window before rules",
+			options:  map[string]interface{}{"rules": adults, "limit": 5},
+			expected: []string{"r4", "r5"},
+			skipped:  2,
+			excluded: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			outcome, err := newTestFilterService(t).run(rows, tc.options)
+			is.NoError(err)
+			is.Equal(tc.expected, filteredNames(outcome.rows))
+			is.Equal(tc.skipped, outcome.skipped)
+			is.Equal(tc.excluded, outcome.excluded)
+		})
+	}
+}
+
+func TestFilterService_rowWindowValidation(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestFilterService(t)
+	for _, options := range []map[string]interface{}{
+		{"offset": -1},
+		{"limit": -2},
+		{"row_range": []int{5}},
+		{"row_range": []int{0, 3}},
+		{"row_range": []int{4, 2}},
+		{"row_range": []interface{}{"a", "b"}},
+	} {
+		_, err := s.ProcessData(testRows(t, []string{"name"}, []string{"x"}), options)
+		is.Error(err, "options %v", options)
+	}
+}
+
+func TestFilterService_streamStopsAtLimit(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	// the last line is malformed CSV, so reading it would fail
+	is.NoError(os.WriteFile(input, []byte("name,age\nr1,10\nr2,20\nr3,30\nr4,40\n\"broken\n"), 0o600))
+
+	s := newTestFilterService(t)
+	for _, output := range []string{"out.json", "out.csv"} {
+		result, err := s.FilterByFile(input, filepath.Join(dir, output), FilterGroup{}, true,
+			map[string]interface{}{"offset": 1, "limit": 2, "stream": true})
+		is.NoError(err)
+		is.Equal(2, result.Processed)
+		is.Equal(1, result.Skipped)
+	}
+
+	// streamed JSON is identical to the in-memory output
+	is.NoError(s.fileService.WriteRows(filepath.Join(dir, "expected.json"),
+		testRows(t, []string{"name", "age"}, []string{"r2", "20"}, []string{"r3", "30"}), OutputOptions{}))
+	expected, err := os.ReadFile(filepath.Join(dir, "expected.json"))
+	is.NoError(err)
+	actual, err := os.ReadFile(filepath.Join(dir, "out.json"))
+	is.NoError(err)
+	is.Equal(string(expected), string(actual))
+
+	actual, err = os.ReadFile(filepath.Join(dir, "out.csv"))
+	is.NoError(err)
+	is.Equal("name,age\nr2,20\nr3,30\n", string(actual))
+}
+
+func TestFilterService_ruleStats(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("name,age,status\nann,40,active\nbob,n/a,active\ncid,50,inactive\ndan,20,active\n"), 0o600))
+
+	filter := FilterGroup{
+		Rules: []FilterRule{{Field: "status", Operator: "equals", Value: "active"}},
+		Groups: []FilterGroup{{
+			Logic: FilterLogicOr,
+			Rules: []FilterRule{
+				{Field: "age", Operator: "greater_than", Value: 30},
+				{Field: "team", Operator: "equals", Value: "core"},
+			},
+		}},
+	}
+
+	result, err := newTestFilterService(t).FilterByFile(input, filepath.Join(dir, "out.json"), filter, true, nil)
+	is.NoError(err)
+	is.Equal(1, result.Processed)
+	is.Equal(3, result.Excluded)
+	is.Equal([]RuleStats{
+		{Rule: "rules[0]", Field: "status", Operator: "equals", Evaluated: 4, Matched: 3, Failed: 1},
+		{Rule: "groups[0].rules[0]", Field: "age", Operator: "greater_than", Evaluated: 3, Matched: 1, Failed: 2, Unparseable: 1},
+		{Rule: "groups[0].rules[1]", Field: "team", Operator: "equals", Evaluated: 2, Failed: 2, Missing: 2},
+	}, result.Stats)
+}
+
+func TestFilterService_invalidRegexFailsFast(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	_, err := newTestFilterService(t).ProcessData(testRows(t, []string{"name"}, []string{"ann"}), map[string]interface{}{
+		"rules": []FilterRule{{Field: "name", Operator: "regex", Value: "a(b"}},
+	})
+	is.ErrorContains(err, `"a(b"`)
+}
+
+func BenchmarkFilterService_regex(b *testing.B) {
+	values := make([][]string, 0, 1000)
+	for i := range 1000 {
+		values = append(values, []string{"user" + strconv.Itoa(i) + "@example.com"})
+	}
+	rows := testRows(b, []string{"email"}, values...)
+	pattern := `^user[0-9]*7@example\.(com|org)$`
+
+	b.Run("compiled once", func(b *testing.B) {
+		s := &FilterService{logger: zerolog.Nop()}
+		options := map[string]interface{}{
+			"rules": []FilterRule{{Field: "email", Operator: "regex", Value: pattern}},
+		}
+		for range b.N {
+			if _, err := s.ProcessData(rows, options); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("compiled per row", func(b *testing.B) {
+		for range b.N {
+			for _, row := range rows {
+				_, _ = regexp.MatchString(pattern, row.Fields["email"])
+			}
+		}
+	})
+}
+
+func TestFilterService_where(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestFilterService(t)
+	rows := testRows(t, []string{"name", "age", "city"},
+		[]string{"ann", "40", "Paris"}, []string{"bob", "20", "Lyon"}, []string{"cid", "50", "Nice"},
+	)
+
+	output, err := s.ProcessData(rows, map[string]interface{}{"where": `age > 30 && city != "Nice"`})
+	is.NoError(err)
+	is.Equal([]string{"ann"}, filteredNames(output))
+
+	_, err = s.ProcessData(rows, map[string]interface{}{
+		"where": "age > 30",
+		"rules": []FilterRule{{Field: "age", Operator: "equals", Value: "40"}},
+	})
+	is.ErrorContains(err, "mutually exclusive")
+
+	// parse errors are reported before the input file is read
+	_, err = s.ProcessData(nil, map[string]interface{}{"where": "age >", "input_file": "/does/not/exist.csv"})
+	is.ErrorContains(err, "at position 6")
+}
+
+func TestFilterService_lengthOperators(t *testing.T) {
+	t.Parallel()
+
+	rows := []DataRow{
+		{Fields: map[string]string{"name": "ascii", "text": "hello"}},
+		{Fields: map[string]string{"name": "accents", "text": "héllo"}},                 // 5 runes, 6 bytes
+		{Fields: map[string]string{"name": "combining", "text": "he\u0301llo"}},         // e + combining acute, 6 runes
+		{Fields: map[string]string{"name": "emoji", "text": "hi \U0001F44B\U0001F3FD"}}, // wave + skin tone, 5 runes
+		{Fields: map[string]string{"name": "missing"}},
+	}
+
+	testCases := []struct {
+		name     string
+		rule     FilterRule
+		expected []string
+	}{
+		{"equal", FilterRule{Field: "text", Operator: "length_eq", Value: 5}, []string{"ascii", "accents", "emoji"}},
+		{"greater", FilterRule{Field: "text", Operator: "length_gt", Value: "5"}, []string{"combining"}},
+		{"less", FilterRule{Field: "text", Operator: "length_lt", Value: float64(6)}, []string{"ascii", "accents", "emoji"}},
+		{"missing as empty", FilterRule{Field: "text", Operator: "length_lt", Value: 6, MissingAsEmpty: true}, []string{"ascii", "accents", "emoji", "missing"}},
+		{"missing zero", FilterRule{Field: "text", Operator: "length_eq", Value: 0, MissingAsEmpty: true}, []string{"missing"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestFilterService(t).ProcessData(rows, map[string]interface{}{"rules": []FilterRule{tc.rule}})
+			is.NoError(err)
+			is.Equal(tc.expected, filteredNames(output))
+		})
+	}
+}
+
+func TestFilterService_lengthValidation(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestFilterService(t)
+	for _, value := range []interface{}{"long", -1, 2.5, nil} {
+		_, err := s.ProcessData(testRows(t, []string{"name"}, []string{"x"}), map[string]interface{}{
+			"rules": []FilterRule{{Field: "name", Operator: "length_gt", Value: value}},
+		})
+		is.Error(err, "value %v", value)
+	}
+}
+
+func TestFilterService_rejectedOutput(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("name,age\nann,40\nbob,20\ncid,50\ndan,10\neve,60\n"), 0o600))
+	filter := FilterGroup{Rules: []FilterRule{{Field: "age", Operator: "greater_than", Value: 30}}}
+
+	for _, stream := range []bool{false, true} {
+		passed := filepath.Join(dir, "passed.csv")
+		rejected := filepath.Join(dir, "rejected.csv")
+
+		result, err := newTestFilterService(t).FilterByFile(input, passed, filter, true, map[string]interface{}{
+			"rejected_output_file": rejected,
+			"stream":               stream,
+			"limit":                4,
+		})
+		is.NoError(err)
+		is.Equal(2, result.Processed)
+		is.Equal(2, result.Excluded)
+
+		content, err := os.ReadFile(passed)
+		is.NoError(err)
+		is.Equal("name,age\nann,40\ncid,50\n", string(content), "stream %v", stream)
+
+		content, err = os.ReadFile(rejected)
+		is.NoError(err)
+		is.Equal("name,age\nbob,20\ndan,10\n", string(content), "stream %v", stream)
+	}
+}
+
+func TestFilterService_inFile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	textFile := filepath.Join(dir, "ids.txt")
+	is.NoError(os.WriteFile(textFile, []byte("C1\n c3 \n\nC9\n"), 0o600))
+	csvFile := filepath.Join(dir, "customers.csv")
+	is.NoError(os.WriteFile(csvFile, []byte("customer_id,region\nC2,eu\nC3,us\n"), 0o600))
+
+	s := newTestFilterService(t)
+	rows := testRows(t, []string{"name", "id"},
+		[]string{"ann", "c1"}, []string{"bob", "C2"}, []string{"cid", "C3"}, []string{"dan", "C4"},
+	)
+
+	output, err := s.ProcessData(rows, map[string]interface{}{
+		"rules": []FilterRule{{Field: "id", Operator: "in_file", Value: textFile}},
+	})
+	is.NoError(err)
+	is.Equal([]string{"ann", "cid"}, filteredNames(output))
+
+	output, err = s.ProcessData(rows, map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"field": "id", "operator": "not_in_file", "value": csvFile, "column": "customer_id"},
+		},
+	})
+	is.NoError(err)
+	is.Equal([]string{"ann", "dan"}, filteredNames(output))
+
+	// loading errors are reported before the input file is read
+	for _, rule := range []FilterRule{
+		{Field: "id", Operator: "in_file", Value: filepath.Join(dir, "missing.txt")},
+		{Field: "id", Operator: "in_file", Value: csvFile, Column: "nope"},
+		{Field: "id", Operator: "in_file", Value: 12},
+	} {
+		_, err := s.ProcessData(nil, map[string]interface{}{
+			"rules":      []FilterRule{rule},
+			"input_file": filepath.Join(dir, "does-not-exist.csv"),
+		})
+		is.ErrorContains(err, "in_file rule", "rule %v", rule)
+	}
+}
+
+func TestFilterService_numberFormatAndEpsilon(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"name", "price"},
+		[]string{"a", "€ 99,90"}, []string{"b", "1.234,56"}, []string{"c", "0.30000000000000004"}, []string{"d", "12"},
+	)
+
+	testCases := []struct {
+		name     string
+		options  map[string]interface{}
+		expected []string
+	}{
+		{
+			name:     "plain by default",
+			options:  map[string]interface{}{"rules": []FilterRule{{Field: "price", Operator: "greater_than", Value: 50}}},
+			expected: []string{},
+		},
+		{
+			name: "guessed greater than",
+			options: map[string]interface{}{
+				"number_format": "auto",
+				"rules":         []FilterRule{{Field: "price", Operator: "greater_than", Value: 50}},
+			},
+			expected: []string{"a", "b"},
+		},
+		{
+			name: "european equals string",
+			options: map[string]interface{}{
+				"number_format": "eu",
+				"rules":         []FilterRule{{Field: "price", Operator: "equals", Value: "99,9"}},
+			},
+			expected: []string{"a"},
+		},
+		{
+			name:     "exact equality",
+			options:  map[string]interface{}{"rules": []FilterRule{{Field: "price", Operator: "equals", Value: 0.3}}},
+			expected: []string{},
+		},
+		{
+			name: "epsilon equality",
+			options: map[string]interface{}{
+				"epsilon": 1e-9,
+				"rules":   []FilterRule{{Field: "price", Operator: "in", Value: []interface{}{0.3, "12.0000000001"}}},
+			},
+			expected: []string{"c", "d"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestFilterService(t).ProcessData(rows, tc.options)
+			is.NoError(err)
+			is.Equal(tc.expected, filteredNames(output))
+		})
+	}
+}
+
+func TestFilterService_negate(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestFilterService(t)
+	rows := testRows(t, []string{"name", "country", "status"},
+		[]string{"ann", "FR", "active"}, []string{"bob", "FR", "churned"}, []string{"cid", "DE", "active"},
+	)
+
+	rules, err := ParseFilterRules([]byte(`[
+		{"field":"country","operator":"equals","value":"FR"},
+		{"field":"status","operator":"equals","value":"churned","negate":true}
+	]`))
+	is.NoError(err)
+	output, err := s.ProcessData(rows, map[string]interface{}{"rules": rules.Rules})
+	is.NoError(err)
+	is.Equal([]string{"ann"}, filteredNames(output))
+
+	// negating not_equals is equals
+	output, err = s.ProcessData(rows, map[string]interface{}{
+		"rules": []FilterRule{{Field: "status", Operator: "not_equals", Value: "churned", Negate: true}},
+	})
+	is.NoError(err)
+	is.Equal([]string{"bob"}, filteredNames(output))
+
+	// negation applies before the logic of nested groups
+	output, err = s.ProcessData(rows, map[string]interface{}{
+		"logic": "or",
+		"groups": []FilterGroup{{
+			Rules: []FilterRule{
+				{Field: "country", Operator: "equals", Value: "FR", Negate: true},
+				{Field: "status", Operator: "equals", Value: "active"},
+			},
+		}},
+	})
+	is.NoError(err)
+	is.Equal([]string{"cid"}, filteredNames(output))
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("name,status\nann,active\nbob,churned\ncid\n"), 0o600))
+	result, err := s.FilterByFile(input, filepath.Join(dir, "out.json"), FilterGroup{
+		Rules: []FilterRule{{Field: "status", Operator: "equals", Value: "churned", Negate: true}},
+	}, true, nil)
+	is.NoError(err)
+	is.Equal([]RuleStats{
+		{Rule: "rules[0]", Field: "status", Operator: "equals", Negate: true, Evaluated: 2, Matched: 1, Failed: 1},
+	}, result.Stats)
+}
+
+func TestFilterService_missingFieldPolicy(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"name", "tier"},
+		[]string{"ann", "gold"}, []string{"bob"}, []string{"cid", "silver"},
+	)
+
+	testCases := []struct {
+		name     string
+		policy   string
+		negate   bool
+		expected []string
+	}{
+		{name: "exclude by default", expected: []string{"ann"}},
+		{name: "exclude negated", policy: "exclude", negate: true, expected: []string{"bob", "cid"}},
+		{name: "include", policy: "include", expected: []string{"ann", "bob"}},
+		{name: "include negated", policy: "INCLUDE", negate: true, expected: []string{"cid"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestFilterService(t).ProcessData(rows, map[string]interface{}{
+				"rules":                []FilterRule{{Field: "tier", Operator: "equals", Value: "gold", Negate: tc.negate}},
+				"missing_field_policy": tc.policy,
+			})
+			is.NoError(err)
+			is.Equal(tc.expected, filteredNames(output))
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		_, err := newTestFilterService(t).ProcessData(rows, map[string]interface{}{
+			"rules":                []FilterRule{{Field: "tier", Operator: "equals", Value: "gold"}},
+			"missing_field_policy": "error",
+		})
+		var missing *MissingFieldError
+		is.ErrorAs(err, &missing)
+		is.Equal("tier", missing.Field)
+		is.Equal("field 'tier' is missing at line 3, available fields: name", err.Error())
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		t.Parallel()
+		is := assert.New(t)
+
+		_, err := newTestFilterService(t).ProcessData(rows, map[string]interface{}{
+			"rules":                []FilterRule{{Field: "tier", Operator: "equals", Value: "gold"}},
+			"missing_field_policy": "ignore",
+		})
+		is.ErrorContains(err, `unknown missing field policy "ignore"`)
+	})
+}
+
+func TestFilterService_unknownRuleFieldWarning(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestFilterService(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("name,country\nann,FR\nbob,DE\n"), 0o600))
+
+	rules := FilterGroup{Rules: []FilterRule{
+		{Field: "country", Operator: "equals", Value: "FR"},
+		{Field: "contry", Operator: "equals", Value: "FR"},
+		{Field: "contry", Operator: "not_equals", Value: "DE"},
+	}}
+
+	for _, stream := range []bool{false, true} {
+		result, err := s.FilterByFile(input, filepath.Join(dir, "out.json"), rules, false, map[string]interface{}{"stream": stream})
+		is.NoError(err)
+		is.Equal([]string{"rule field 'contry' appears in no row, available fields: name, country"}, result.Warnings)
+		is.Equal(2, result.Processed)
+	}
+}
+
+func TestFilterService_derive(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestFilterService(t)
+	rows := testRows(t, []string{"name", "email"},
+		[]string{"ann", "ann@corp.com"}, []string{"bob", "bob@gmail.com"}, []string{"cid", "cid@CORP.COM"},
+	)
+	derive := []TransformRule{{
+		Field:       "email",
+		Operation:   Extract,
+		Parameters:  map[string]interface{}{"pattern": `@(.+)$`, "group": float64(1)},
+		TargetField: "domain",
+	}}
+	rules := []FilterRule{{Field: "domain", Operator: "equals", Value: "corp.com"}}
+
+	output, err := s.ProcessData(rows, map[string]interface{}{"rules": rules, "derive": derive})
+	is.NoError(err)
+	is.Equal([]string{"ann", "cid"}, filteredNames(output))
+	is.Equal([]string{"name", "email"}, output[0].Keys())
+	is.Nil(derive[0].parsed)
+
+	output, err = s.ProcessData(rows, map[string]interface{}{"rules": rules, "derive": derive, "keep_derived": true})
+	is.NoError(err)
+	is.Equal([]string{"name", "email", "domain"}, output[1].Keys())
+	is.Equal("CORP.COM", output[1].Fields["domain"])
+	is.Equal([]string{"name", "email"}, rows[2].Keys())
+
+	// derived fields are visible to where expressions too, decoded from generic JSON
+	var options map[string]interface{}
+	is.NoError(json.Unmarshal([]byte(`{
+		"where": "domain == \"gmail.com\"",
+		"derive": [{"field":"email","operation":"extract","parameters":{"pattern":"@(.+)$","group":1},"target_field":"domain"}]
+	}`), &options))
+	output, err = s.ProcessData(rows, options)
+	is.NoError(err)
+	is.Equal([]string{"bob"}, filteredNames(output))
+
+	_, err = s.ProcessData(rows, map[string]interface{}{
+		"rules":  rules,
+		"derive": []TransformRule{{Field: "email", Operation: Extract, Parameters: map[string]interface{}{"pattern": "(["}}},
+	})
+	is.ErrorContains(err, "invalid derive rules")
+
+	_, err = s.ProcessData(rows, map[string]interface{}{
+		"rules":  rules,
+		"derive": []TransformRule{{Field: "email", Operation: Explode, Parameters: map[string]interface{}{"separator": "@"}}},
+	})
+	is.ErrorContains(err, "explode rule on field 'email' would change the rows filtered")
+}
+
+func TestFilterService_dateLayouts(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"name", "joined"},
+		[]string{"ann", "05/01/2024"}, []string{"bob", "20/02/2024"}, []string{"cid", "2024-01-10"},
+	)
+	output, err := newTestFilterService(t).ProcessData(rows, map[string]interface{}{
+		"rules": []FilterRule{{Field: "joined", Operator: "between", Value: map[string]interface{}{
+			"min": "2024-01-01", "max": "31/01/2024",
+		}}},
+		"date_layouts": []interface{}{"02/01/2006"},
+	})
+	is.NoError(err)
+	is.Equal([]string{"ann"}, filteredNames(output))
+}
@@ -0,0 +1,115 @@
+package jobs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// foreignKey checks the values of a "foreign_key" rule against the values of a column of a reference
+// file. Its constraints are {"file": path, "column": name, "allow_empty": bool, "case_insensitive": bool},
+// the column defaulting to the rule field. Reference files are CSV files, or JSON Lines files when
+// their extension is .jsonl or .ndjson.
+type foreignKey struct {
+	file            string
+	column          string
+	allowEmpty      bool
+	caseInsensitive bool
+	values          map[string]struct{} // non-empty values of the reference column, lowercased when case-insensitive
+}
+
+// ReferenceSet describes the reference values a foreign_key rule loaded.
+type ReferenceSet struct {
+	Field  string `json:"field"`
+	File   string `json:"file"`
+	Column string `json:"column"`
+	Size   int    `json:"size"` // distinct non-empty values
+}
+
+// parseForeignKey parses the constraints of a "foreign_key" rule and loads its reference values.
+func (s *ValidateService) parseForeignKey(rule ValidationRule) (*foreignKey, error) {
+	constraints, ok := rule.Constraints.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid constraints of foreign_key rule on field '%s': expected an object with a file", rule.Field)
+	}
+
+	key := &foreignKey{column: rule.Field}
+	if key.file, _ = constraints["file"].(string); key.file == "" {
+		return nil, fmt.Errorf("reference file of foreign_key rule on field '%s' not specified", rule.Field)
+	}
+	if column, ok := constraints["column"].(string); ok && column != "" {
+		key.column = column
+	}
+	for name, flag := range map[string]*bool{"allow_empty": &key.allowEmpty, "case_insensitive": &key.caseInsensitive} {
+		value, ok := constraints[name]
+		if !ok {
+			continue
+		}
+		if *flag, ok = value.(bool); !ok {
+			return nil, fmt.Errorf("invalid %s of foreign_key rule on field '%s': expected true or false", name, rule.Field)
+		}
+	}
+
+	values, err := s.loadReferenceValues(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load references of foreign_key rule on field '%s': %w", rule.Field, err)
+	}
+	key.values = values
+	return key, nil
+}
+
+// loadReferenceValues reads the non-empty values of the reference column of a foreign key,
+// failing when the reference file does not have the column.
+func (s *ValidateService) loadReferenceValues(key *foreignKey) (map[string]struct{}, error) {
+	values := make(map[string]struct{})
+	found := false
+	add := func(row DataRow) error {
+		value, ok := row.Fields[key.column]
+		found = found || ok
+		if value != "" {
+			values[key.normalize(value)] = struct{}{}
+		}
+		return nil
+	}
+
+	if isJSONLFile(key.file) {
+		if err := s.fileService.StreamJSONL(key.file, add); err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("column '%s' is in no row of %s", key.column, key.file)
+		}
+		return values, nil
+	}
+
+	headers, err := s.fileService.readHeaders(key.file)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(headers, key.column) {
+		return nil, fmt.Errorf("column '%s' is missing from %s, available columns: %s", key.column, key.file, strings.Join(headers, ", "))
+	}
+	if err := s.fileService.StreamCSV(key.file, add); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// normalize returns the value a foreign key looks up.
+func (key *foreignKey) normalize(value string) string {
+	if key.caseInsensitive {
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// check validates a value, returning the failure message, if any.
+func (key *foreignKey) check(value string) string {
+	if value == "" && key.allowEmpty {
+		return ""
+	}
+	if _, ok := key.values[key.normalize(value)]; ok {
+		return ""
+	}
+	return fmt.Sprintf("Value not found in column '%s' of %s", key.column, key.file)
+}
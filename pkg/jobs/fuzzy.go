@@ -0,0 +1,55 @@
+package jobs
+
+// DefaultFuzzyThreshold is the similarity a "fuzzy" rule requires when it sets no threshold.
+const DefaultFuzzyThreshold = 0.8
+
+// fuzzyMatches reports whether the similarity of two strings reaches threshold, where similarity
+// is 1 - levenshtein(a, b) / max(len(a), len(b)) counted in runes. Identical strings, empty ones
+// included, have a similarity of 1.
+func fuzzyMatches(a, b []rune, threshold float64) bool {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return true
+	}
+
+	maxDistance := int((1 - threshold) * float64(longest))
+	return levenshteinWithin(a, b, maxDistance)
+}
+
+// levenshteinWithin reports whether the edit distance of a and b is at most maxDistance.
+// It gives up as soon as the distance is known to be larger, starting with the length difference.
+func levenshteinWithin(a, b []rune, maxDistance int) bool {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if len(a)-len(b) > maxDistance {
+		return false
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+
+		if rowMin > maxDistance {
+			return false
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)] <= maxDistance
+}
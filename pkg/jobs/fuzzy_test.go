@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatches(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a, b      string
+		threshold float64
+		expected  bool
+	}{
+		{"acme corp", "acme crop", 0.75, true},  // distance 2 over 9 runes
+		{"acme corp", "acme crop", 0.85, false}, // similarity 0.78
+		{"acme corp", "acme corporation", 0.85, false},
+		{"", "", 0.9, true},
+		{"abc", "", 0.5, false},
+		{"crème brûlée", "creme brulee", 0.7, true}, // runes, not bytes
+		{"crème brûlée", "creme brulee", 0.8, false},
+		{"kitten", "sitting", 0.5, true},
+		{"kitten", "sitting", 0.6, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+"/"+tc.b, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			is.Equal(tc.expected, fuzzyMatches([]rune(tc.a), []rune(tc.b), tc.threshold))
+			is.Equal(tc.expected, fuzzyMatches([]rune(tc.b), []rune(tc.a), tc.threshold))
+		})
+	}
+}
+
+func TestFilterService_fuzzy(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestFilterService(t)
+	rows := testRows(t, []string{"name"},
+		[]string{"Acme Crop"}, []string{"ACME CORP"}, []string{"Acme Corporation"}, []string{"Initech"},
+	)
+
+	output, err := s.ProcessData(rows, map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"field": "name", "operator": "fuzzy", "value": "Acme Corp", "threshold": 0.75},
+		},
+	})
+	is.NoError(err)
+	is.Equal([]string{"Acme Crop", "ACME CORP"}, filteredNames(output))
+
+	_, err = s.ProcessData(rows, map[string]interface{}{
+		"rules": []FilterRule{{Field: "name", Operator: "fuzzy", Value: "Acme", Threshold: 1.5}},
+	})
+	is.ErrorContains(err, "threshold")
+}
+
+func BenchmarkFuzzyMatches(b *testing.B) {
+	target := []rune("acme corporation limited")
+
+	b.Run("similar", func(b *testing.B) {
+		value := []rune("acme corporaton limted")
+		for range b.N {
+			fuzzyMatches(value, target, 0.85)
+		}
+	})
+
+	b.Run("different", func(b *testing.B) {
+		value := []rune("initech software services")
+		for range b.N {
+			fuzzyMatches(value, target, 0.85)
+		}
+	})
+
+	b.Run("length mismatch", func(b *testing.B) {
+		value := []rune(strings.Repeat("acme ", 20))
+		for range b.N {
+			fuzzyMatches(value, target, 0.85)
+		}
+	})
+}
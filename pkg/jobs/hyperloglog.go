@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hyperLogLogPrecision is the number of hash bits selecting a register: 4096 one-byte registers
+// per sketch, for a standard error of about 1.6%.
+const hyperLogLogPrecision = 12
+
+// hyperLogLog estimates the number of distinct values added to it in constant memory.
+type hyperLogLog struct {
+	registers []uint8
+}
+
+// newHyperLogLog returns an empty sketch.
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hyperLogLogPrecision)}
+}
+
+// add adds a value to the sketch.
+func (h *hyperLogLog) add(value string) {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(value))
+	hash := mix64(hasher.Sum64())
+
+	index := hash >> (64 - hyperLogLogPrecision)
+	// rank of the first set bit among the remaining bits, capped when they are all zero
+	rank := uint8(bits.LeadingZeros64(hash<<hyperLogLogPrecision|1<<(hyperLogLogPrecision-1)) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// estimate returns the estimated number of distinct values, using linear counting for small cardinalities.
+func (h *hyperLogLog) estimate() float64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, register := range h.registers {
+		sum += math.Ldexp(1, -int(register))
+		if register == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		return m * math.Log(m/float64(zeros))
+	}
+	return estimate
+}
+
+// mix64 spreads the bits of a hash, since FNV leaves its high bits poorly mixed for short values.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	t.Parallel()
+
+	for _, cardinality := range []int{0, 1, 100, 5000, 200000} {
+		t.Run(strconv.Itoa(cardinality), func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			sketch := newHyperLogLog()
+			for i := range cardinality {
+				sketch.add(strconv.Itoa(i))
+				sketch.add(strconv.Itoa(i)) // repeated values do not count
+			}
+			is.InEpsilon(float64(cardinality)+1, sketch.estimate()+1, 0.05)
+		})
+	}
+}
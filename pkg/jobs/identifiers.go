@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Namespaces of name-based UUIDs defined by RFC 4122.
+var uuidNamespaces = map[string]string{
+	"dns":  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	"url":  "6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+	"oid":  "6ba7b812-9dad-11d1-80b4-00c04fd430c8",
+	"x500": "6ba7b814-9dad-11d1-80b4-00c04fd430c8",
+}
+
+// validateUUID reports whether a value is a UUID in its canonical form,
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx, in any case.
+// A non-zero version also requires the version bits and the RFC 4122 variant bits.
+func validateUUID(value string, version int) bool {
+	if len(value) != 36 || value[8] != '-' || value[13] != '-' || value[18] != '-' || value[23] != '-' {
+		return false
+	}
+
+	var uuid [16]byte
+	if _, err := hex.Decode(uuid[:], []byte(strings.ReplaceAll(value, "-", ""))); err != nil {
+		return false
+	}
+
+	if version == 0 {
+		return true
+	}
+	return int(uuid[6]>>4) == version && uuid[8]&0xc0 == 0x80
+}
+
+// parseUUID parses a UUID in its canonical form, or the name of an RFC 4122 namespace like dns.
+func parseUUID(value string) ([16]byte, error) {
+	var uuid [16]byte
+	if namespace, ok := uuidNamespaces[strings.ToLower(value)]; ok {
+		value = namespace
+	}
+	if !validateUUID(value, 0) {
+		return uuid, fmt.Errorf("invalid UUID %q", value)
+	}
+	_, err := hex.Decode(uuid[:], []byte(strings.ReplaceAll(value, "-", "")))
+	return uuid, err
+}
+
+// newUUIDv4 returns a random UUID.
+func newUUIDv4() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	return formatUUID(uuid, 4)
+}
+
+// newUUIDv5 returns the name-based UUID of a name in a namespace, always the same for both.
+func newUUIDv5(namespace [16]byte, name string) string {
+	h := sha1.New() //nolint:gosec
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var uuid [16]byte
+	copy(uuid[:], h.Sum(nil))
+	return formatUUID(uuid, 5)
+}
+
+// formatUUID sets the version and RFC 4122 variant bits of a UUID and writes it in canonical form.
+func formatUUID(uuid [16]byte, version int) string {
+	uuid[6] = uuid[6]&0x0f | byte(version)<<4
+	uuid[8] = uuid[8]&0x3f | 0x80
+	encoded := hex.EncodeToString(uuid[:])
+	return encoded[:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:]
+}
+
+// validateIPv4 reports whether a value is an IPv4 address in dotted decimal form.
+func validateIPv4(value string) bool {
+	addr, err := netip.ParseAddr(value)
+	return err == nil && addr.Is4()
+}
+
+// validateIPv6 reports whether a value is an IPv6 address, IPv4-mapped ones and zones included.
+func validateIPv6(value string) bool {
+	addr, err := netip.ParseAddr(value)
+	return err == nil && addr.Is6()
+}
+
+// validateHostname reports whether a value is a hostname as of RFC 1123: dot-separated labels
+// of 1 to 63 letters, digits and hyphens, not starting nor ending with a hyphen,
+// 253 characters at most, with an optional trailing dot.
+func validateHostname(value string) bool {
+	value = strings.TrimSuffix(value, ".")
+	if value == "" || len(value) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(value, ".") {
+		if !validateHostnameLabel(label) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateHostnameLabel reports whether a label of a hostname has 1 to 63 letters, digits and hyphens,
+// not starting nor ending with a hyphen.
+func validateHostnameLabel(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateUUID(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		version  int
+		expected bool
+	}{
+		{"123e4567-e89b-12d3-a456-426614174000", 0, true},
+		{"00000000-0000-0000-0000-000000000000", 0, true},
+		{"F47AC10B-58CC-4372-A567-0E02B2C3D479", 4, true},
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d479", 4, true},
+		{"123e4567-e89b-12d3-a456-426614174000", 4, false}, // version 1
+		{"f47ac10b-58cc-4372-c567-0e02b2c3d479", 4, false}, // not the RFC 4122 variant
+		{"123e4567-e89b-12d3-a456-426614174000", 1, true},
+		{"f47ac10b58cc4372a5670e02b2c3d479", 0, false},
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d47", 0, false},
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d47z", 0, false},
+		{"{f47ac10b-58cc-4372-a567-0e02b2c3d479}", 0, false},
+		{"f47ac10b-58cc-4372-a5670-e02b2c3d479", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, validateUUID(tc.value, tc.version))
+		})
+	}
+}
+
+func TestValidateIPAddresses(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value string
+		ipv4  bool
+		ipv6  bool
+	}{
+		{"192.168.0.1", true, false},
+		{"0.0.0.0", true, false},
+		{"256.1.1.1", false, false},
+		{"192.168.01.1", false, false},
+		{"192.168.0", false, false},
+		{"2001:db8::1", false, true},
+		{"::1", false, true},
+		{"::ffff:192.168.0.1", false, true},
+		{"fe80::1%eth0", false, true},
+		{"2001:db8:::1", false, false},
+		{"localhost", false, false},
+		{"", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			is.Equal(tc.ipv4, validateIPv4(tc.value))
+			is.Equal(tc.ipv6, validateIPv6(tc.value))
+		})
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		expected bool
+	}{
+		{"localhost", true},
+		{"api.example.com", true},
+		{"api.example.com.", true},
+		{"xn--bcher-kva.example", true},
+		{"3com.net", true},
+		{"my-host-01", true},
+		{"-host.example.com", false},
+		{"host-.example.com", false},
+		{"host..example.com", false},
+		{"host_name.example.com", false},
+		{"host name", false},
+		{strings.Repeat("a", 63) + ".com", true},
+		{strings.Repeat("a", 64) + ".com", false},
+		{strings.Repeat("a.", 126) + "a", true},
+		{strings.Repeat("a.", 127) + "a", false},
+		{".", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, validateHostname(tc.value))
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a step of a json_extract path, an object key or an array index.
+type jsonPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// String returns the step as written in paths.
+func (step jsonPathStep) String() string {
+	if step.isIndex {
+		return "[" + strconv.Itoa(step.index) + "]"
+	}
+	return step.key
+}
+
+// jsonExtraction extracts values from a field holding JSON for "json_extract" rules. Its "path",
+// from the root of the JSON value, uses dots and brackets, like "items[0].sku" or
+// "$.items[0]['sku']", negative indexes counting from the end of arrays. Scalars are written as is,
+// null as an empty value, and objects and arrays as compact JSON. Invalid JSON and missing paths
+// follow the on_error policy of the rules, empty by default.
+type jsonExtraction struct {
+	path []jsonPathStep
+}
+
+// parsedJSON is the JSON value of a field, parsed once per row for all the json_extract rules reading it.
+type parsedJSON struct {
+	raw   string
+	value interface{}
+	err   error
+}
+
+// parseJSONExtraction parses the parameters of a "json_extract" rule.
+func parseJSONExtraction(rule TransformRule) (*jsonExtraction, error) {
+	extraction := &jsonExtraction{}
+
+	path, ok := rule.Parameters["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path of json_extract rule on field '%s' not specified", rule.Field)
+	}
+	var err error
+	if extraction.path, err = parseJSONPath(path); err != nil {
+		return nil, fmt.Errorf("invalid path %q of json_extract rule on field '%s': %w", path, rule.Field, err)
+	}
+	return extraction, nil
+}
+
+// parseJSONPath parses a path of dot-separated keys and bracketed indexes or quoted keys.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	steps := []jsonPathStep{}
+	for rest != "" {
+		if rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.New("unclosed bracket")
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			} else if index, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			} else {
+				return nil, fmt.Errorf("expected an index or a quoted key in brackets, got [%s]", inner)
+			}
+			rest = rest[end+1:]
+			continue
+		}
+
+		rest = strings.TrimPrefix(rest, ".")
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, errors.New("empty key")
+		}
+		steps = append(steps, jsonPathStep{key: rest[:end]})
+		rest = rest[end:]
+	}
+	return steps, nil
+}
+
+// parseFieldJSON returns the JSON value of a field, reusing the value parsed by an earlier rule of the row
+// when the field did not change since.
+func parseFieldJSON(cache map[string]*parsedJSON, field string, raw string) *parsedJSON {
+	if parsed, ok := cache[field]; ok && parsed.raw == raw {
+		return parsed
+	}
+
+	parsed := &parsedJSON{raw: raw}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	if parsed.err = decoder.Decode(&parsed.value); parsed.err == nil && decoder.More() {
+		parsed.err = errors.New("invalid character after top-level value")
+	}
+	cache[field] = parsed
+	return parsed
+}
+
+// extract returns the value at the path of a parsed JSON value, failing with an
+// untransformableError when the JSON is invalid or the path is missing.
+func (e *jsonExtraction) extract(parsed *parsedJSON, field string) (string, error) {
+	result, err := e.lookup(parsed)
+	if err != nil {
+		return "", untransformable("json_extract rule on field '%s': %w", field, err)
+	}
+	return result, nil
+}
+
+// lookup walks the path of a parsed JSON value and formats the value found.
+func (e *jsonExtraction) lookup(parsed *parsedJSON) (string, error) {
+	if parsed.err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", parsed.err)
+	}
+
+	value := parsed.value
+	for i, step := range e.path {
+		found := false
+		switch node := value.(type) {
+		case map[string]interface{}:
+			if !step.isIndex {
+				value, found = node[step.key]
+			}
+		case []interface{}:
+			index := step.index
+			if index < 0 {
+				index += len(node)
+			}
+			if step.isIndex && index >= 0 && index < len(node) {
+				value, found = node[index], true
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("path %s not found", formatJSONPath(e.path[:i+1]))
+		}
+	}
+
+	return formatJSONValue(value)
+}
+
+// formatJSONValue formats a JSON value: strings, numbers and booleans as is, null as empty,
+// and objects and arrays as compact JSON.
+func formatJSONValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	default:
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buffer.String(), "\n"), nil
+	}
+}
+
+// formatJSONPath writes path steps back in the dot and bracket notation.
+func formatJSONPath(steps []jsonPathStep) string {
+	var builder strings.Builder
+	for i, step := range steps {
+		if i > 0 && !step.isIndex {
+			builder.WriteByte('.')
+		}
+		builder.WriteString(step.String())
+	}
+	return builder.String()
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_jsonExtract(t *testing.T) {
+	t.Parallel()
+
+	payload := `{"user": {"id": 42, "name": "Ada", "admin": false, "team": null}, "items": [{"sku": "A-1", "price": 9.90}, {"sku": "B-2", "tags": ["x", "<y>"]}], "a.b": 1}`
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "string", value: payload, params: map[string]interface{}{"path": "user.name"}, want: "Ada"},
+		{name: "number kept as written", value: payload, params: map[string]interface{}{"path": "items[0].price"}, want: "9.90"},
+		{name: "bool", value: payload, params: map[string]interface{}{"path": "user.admin"}, want: "false"},
+		{name: "null", value: payload, params: map[string]interface{}{"path": "user.team", "on_error": "fail"}, want: ""},
+		{name: "jsonpath root", value: payload, params: map[string]interface{}{"path": "$.items[1].sku"}, want: "B-2"},
+		{name: "negative index", value: payload, params: map[string]interface{}{"path": "items[-1].sku"}, want: "B-2"},
+		{name: "quoted key", value: payload, params: map[string]interface{}{"path": `$['a.b']`}, want: "1"},
+		{name: "subtree", value: payload, params: map[string]interface{}{"path": "items[1]"}, want: `{"sku":"B-2","tags":["x","<y>"]}`},
+		{name: "missing path", value: payload, params: map[string]interface{}{"path": "items[2].sku"}, want: ""},
+		{name: "missing path kept", value: `{"a": 1}`, params: map[string]interface{}{"path": "b", "on_error": "keep"}, want: `{"a": 1}`},
+		{name: "invalid json", value: `{"a": `, params: map[string]interface{}{"path": "a"}, want: ""},
+		{name: "trailing data", value: `{"a": 1} {}`, params: map[string]interface{}{"path": "a"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"payload"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "payload", Operation: JSONExtract, Parameters: tc.params, TargetField: "value"}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_jsonExtractChained(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"payload"}, []string{"eyJldmVudCI6ICJjbGljayIsICJ1c2VyIjogeyJpZCI6IDd9fQ=="})
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"chained": true,
+		"rules": []TransformRule{
+			{Field: "payload", Operation: Base64Decode},
+			{Field: "payload", Operation: JSONExtract, Parameters: map[string]interface{}{"path": "event"}, TargetField: "event"},
+			{Field: "payload", Operation: JSONExtract, Parameters: map[string]interface{}{"path": "user.id"}, TargetField: "user_id"},
+		},
+	})
+	is.NoError(err)
+	is.Equal([]string{"payload", "event", "user_id"}, output[0].Keys())
+	is.Equal("click", output[0].Fields["event"])
+	is.Equal("7", output[0].Fields["user_id"])
+}
+
+func TestParseFieldJSON(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	cache := make(map[string]*parsedJSON)
+	parsed := parseFieldJSON(cache, "payload", `{"a": 1}`)
+	is.NoError(parsed.err)
+	is.Same(parsed, parseFieldJSON(cache, "payload", `{"a": 1}`))
+	is.NotSame(parsed, parseFieldJSON(cache, "payload", `{"a": 2}`))
+	is.NotSame(parsed, parseFieldJSON(cache, "other", `{"a": 1}`))
+}
+
+func TestTransformService_jsonExtractErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"payload"}, []string{`{"a": 1}`}, []string{`{"b": 1}`})
+	_, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "payload", Operation: JSONExtract, Parameters: map[string]interface{}{"path": "a", "on_error": "fail"}}},
+	})
+	is.ErrorContains(err, "line 3: json_extract rule on field 'payload': path a not found")
+
+	for _, params := range []map[string]interface{}{
+		{},
+		{"path": "items[0"},
+		{"path": "items[first]"},
+		{"path": "a..b"},
+		{"path": "a", "on_error": "skip"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "payload", Operation: JSONExtract, Parameters: params}},
+		})
+		is.ErrorContains(err, "json_extract rule on field 'payload'", params)
+	}
+}
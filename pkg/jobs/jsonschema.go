@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// compileSchema loads and compiles the JSON Schema document of a file.
+func compileSchema(path string) (*jsonschema.Schema, error) {
+	schema, err := jsonschema.NewCompiler().Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema file %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// schemaInstance returns the JSON object a row is validated as against a schema.
+// Every value is a string, unless coerceNumbers is set: numeric-looking values are then
+// numbers and empty values are left out, as CSV files have no other way to tell them apart.
+func schemaInstance(row DataRow, coerceNumbers bool) map[string]interface{} {
+	instance := make(map[string]interface{}, len(row.Fields))
+	for field, value := range row.Fields {
+		if !coerceNumbers {
+			instance[field] = value
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			instance[field] = json.Number(value)
+		} else {
+			instance[field] = value
+		}
+	}
+	return instance
+}
+
+// validateSchema validates a row against the schema of the options, one error per violation.
+// Messages locate violations with the JSON pointer of their instance, # being the whole row.
+func (s *ValidateService) validateSchema(row DataRow, opts *ValidateOptions, rowNumber int) []ValidationError {
+	err := opts.schema.Validate(schemaInstance(row, opts.CoerceNumbers))
+	if err == nil {
+		return nil
+	}
+
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return []ValidationError{s.schemaError(row, rowNumber, "", err.Error())}
+	}
+
+	var violations []ValidationError
+	for _, unit := range schemaErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		violations = append(violations, s.schemaError(row, rowNumber, unit.InstanceLocation, unit.Error.String()))
+	}
+	return violations
+}
+
+// schemaError returns the validation error of a schema violation at a JSON pointer of a row.
+func (s *ValidateService) schemaError(row DataRow, rowNumber int, pointer, message string) ValidationError {
+	// the first token of the pointer is the field, ~1 and ~0 escaping / and ~
+	field, _, _ := strings.Cut(strings.TrimPrefix(pointer, "/"), "/")
+	field = strings.NewReplacer("~1", "/", "~0", "~").Replace(field)
+
+	return ValidationError{
+		RowNumber:  rowNumber,
+		LineNumber: row.LineNumber,
+		SourceFile: row.SourceFile,
+		FieldName:  field,
+		FieldValue: row.Fields[field],
+		RuleType:   "schema",
+		Message:    fmt.Sprintf("Schema violation at #%s: %s", pointer, message),
+		Severity:   "error",
+		RowData:    row,
+	}
+}
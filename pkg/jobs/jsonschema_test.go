@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateService_schema(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	schemaFile := filepath.Join(dir, "schema.json")
+	is.NoError(os.WriteFile(schemaFile, []byte(`{
+		"type": "object",
+		"required": ["id", "email"],
+		"properties": {
+			"id": {"pattern": "^[0-9]+$"},
+			"age": {"type": "number", "minimum": 18},
+			"a/b": {"enum": ["x"]}
+		}
+	}`), 0o600))
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"id", "email", "age", "a/b"},
+		[]string{"1", "a@example.com", "42", "x"},
+		[]string{"x2", "b@example.com", "12", "y"},
+		[]string{"3", "", "", "x"},
+	)
+
+	// values are strings unless numbers are coerced, the ids then being numbers a pattern does not apply to
+	opts, err := s.parseValidateOptions(map[string]interface{}{"schema_file": schemaFile})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.Equal(0, result.ValidRows)
+
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"schema_file":    schemaFile,
+		"coerce_numbers": true,
+		"rules":          []ValidationRule{{Field: "email", Type: "required"}},
+	})
+	is.NoError(err)
+	result, _, _ = s.validateData(rows, opts)
+	is.Equal(1, result.ValidRows)
+	is.Equal([]int{2, 2, 2, 3, 3}, validationRows(result.Errors))
+
+	byField := make(map[string]ValidationError)
+	for _, validationError := range result.Errors {
+		byField[validationError.RuleType+":"+validationError.FieldName] = validationError
+	}
+	is.Equal("Schema violation at #/age: minimum: got 12, want 18", byField["schema:age"].Message)
+	is.Equal("12", byField["schema:age"].FieldValue)
+	is.Equal("Schema violation at #/a~1b: value must be 'x'", byField["schema:a/b"].Message)
+	is.Contains(byField["schema:id"].Message, "#/id")
+	// empty values are left out, so the required email is missing from the whole row
+	is.Equal("Schema violation at #: missing property 'email'", byField["schema:"].Message)
+	is.Equal("Field is required", byField["required:email"].Message)
+
+	_, err = s.parseValidateOptions(map[string]interface{}{"schema_file": filepath.Join(dir, "missing.json")})
+	is.Error(err)
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rowSequence numbers rows for "row_number" rules. Its parameters are "start" and "step", 1 by
+// default, and "width", the number of digits numbers are zero-padded to. Numbers follow from
+// the position of rows in the input, not from the order rows are transformed in.
+type rowSequence struct {
+	start int
+	step  int
+	width int
+}
+
+// parseRowSequence parses the parameters of a "row_number" rule, which needs a target field.
+func parseRowSequence(rule TransformRule) (*rowSequence, error) {
+	if rule.TargetField == "" {
+		return nil, fmt.Errorf("row_number rule needs a target_field")
+	}
+
+	sequence := &rowSequence{start: 1, step: 1}
+	for name, value := range map[string]*int{"start": &sequence.start, "step": &sequence.step, "width": &sequence.width} {
+		raw, ok := rule.Parameters[name]
+		if !ok {
+			continue
+		}
+		if *value, ok = toInt(raw); !ok {
+			return nil, fmt.Errorf("invalid %s %v of row_number rule on field '%s': expected an integer", name, raw, rule.TargetField)
+		}
+	}
+	if sequence.width < 0 {
+		return nil, fmt.Errorf("invalid width %d of row_number rule on field '%s': expected a positive number of digits", sequence.width, rule.TargetField)
+	}
+	return sequence, nil
+}
+
+// number returns the number of the row at an index of the input, from 0.
+func (s *rowSequence) number(index int) string {
+	number := s.start + index*s.step
+	digits := strconv.Itoa(number)
+	sign := ""
+	if number < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) < s.width {
+		digits = strings.Repeat("0", s.width-len(digits)) + digits
+	}
+	return sign + digits
+}
+
+// uuidGenerator generates identifiers for "uuid" rules. Its parameters are "version", 4 for
+// random UUIDs, the default, or 5 for UUIDs derived from the rule field and a "namespace",
+// a UUID or one of dns, url, oid and x500. Version 5 UUIDs are the same for the same value in
+// the same namespace, across runs, and empty for empty values.
+type uuidGenerator struct {
+	version   int
+	namespace [16]byte
+}
+
+// parseUUIDGenerator parses the parameters of a "uuid" rule, which needs a target field.
+func parseUUIDGenerator(rule TransformRule) (*uuidGenerator, error) {
+	if rule.TargetField == "" {
+		return nil, fmt.Errorf("uuid rule needs a target_field")
+	}
+
+	generator := &uuidGenerator{version: 4}
+	if raw, ok := rule.Parameters["version"]; ok {
+		if generator.version, ok = toInt(raw); !ok || generator.version != 4 && generator.version != 5 {
+			return nil, fmt.Errorf("invalid version %v of uuid rule on field '%s': expected 4 or 5", raw, rule.TargetField)
+		}
+	}
+	if generator.version == 4 {
+		return generator, nil
+	}
+
+	if rule.Field == "" {
+		return nil, fmt.Errorf("version 5 uuid rule on field '%s' needs a source field", rule.TargetField)
+	}
+	namespace, _ := rule.Parameters["namespace"].(string)
+	var err error
+	if generator.namespace, err = parseUUID(namespace); err != nil {
+		return nil, fmt.Errorf("invalid namespace of uuid rule on field '%s': %w", rule.TargetField, err)
+	}
+	return generator, nil
+}
+
+// generate returns the UUID of a row.
+func (g *uuidGenerator) generate(row DataRow, field string) string {
+	if g.version == 4 {
+		return newUUIDv4()
+	}
+	value := row.Fields[field]
+	if value == "" {
+		return ""
+	}
+	return newUUIDv5(g.namespace, value)
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_rowNumber(t *testing.T) {
+	t.Parallel()
+
+	input := testRows(t, []string{"name"}, []string{"a"}, []string{"b"}, []string{"c"})
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		want   []string
+	}{
+		{name: "default", want: []string{"1", "2", "3"}},
+		{name: "start and step", params: map[string]interface{}{"start": float64(100), "step": 10}, want: []string{"100", "110", "120"}},
+		{name: "zero padding", params: map[string]interface{}{"start": 0, "width": 4}, want: []string{"0000", "0001", "0002"}},
+		{name: "negative", params: map[string]interface{}{"start": 1, "step": -1, "width": 2}, want: []string{"01", "00", "-01"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Operation: RowNumber, Parameters: tc.params, TargetField: "id"}},
+			})
+			is.NoError(err)
+			for i, row := range output {
+				is.Equal(tc.want[i], row.Fields["id"])
+			}
+			is.Equal([]string{"name", "id"}, output[0].Keys())
+		})
+	}
+
+	for _, rule := range []TransformRule{
+		{Operation: RowNumber},
+		{Operation: RowNumber, TargetField: "id", Parameters: map[string]interface{}{"start": "one"}},
+		{Operation: RowNumber, TargetField: "id", Parameters: map[string]interface{}{"width": -1}},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		assert.ErrorContains(t, err, "row_number rule", rule.Parameters)
+	}
+}
+
+func TestTransformService_uuid(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"domain"}, []string{"python.org"}, []string{"example.com"}, []string{"python.org"}, []string{""})
+	v5 := map[string]interface{}{
+		"rules": []TransformRule{{Field: "domain", Operation: UUID, Parameters: map[string]interface{}{"version": 5, "namespace": "dns"}, TargetField: "id"}},
+	}
+
+	// version 5 UUIDs are the same for the same values, across runs
+	for range 2 {
+		output, err := newTestTransformService(t).ProcessData(input, v5)
+		is.NoError(err)
+		is.Equal("886313e1-3b8a-5372-9b90-0c9aee199e5d", output[0].Fields["id"])
+		is.Equal("cfbff0d1-9375-5685-968c-48ce8b15ae17", output[1].Fields["id"])
+		is.Equal(output[0].Fields["id"], output[2].Fields["id"])
+		is.Empty(output[3].Fields["id"])
+	}
+
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Operation: UUID, TargetField: "id"}},
+	})
+	is.NoError(err)
+	seen := make(map[string]bool)
+	for _, row := range output {
+		is.True(validateUUID(row.Fields["id"], 4), row.Fields["id"])
+		is.False(seen[row.Fields["id"]])
+		seen[row.Fields["id"]] = true
+	}
+
+	for _, rule := range []TransformRule{
+		{Field: "domain", Operation: UUID},
+		{Field: "domain", Operation: UUID, TargetField: "id", Parameters: map[string]interface{}{"version": 1}},
+		{Operation: UUID, TargetField: "id", Parameters: map[string]interface{}{"version": 5, "namespace": "dns"}},
+		{Field: "domain", Operation: UUID, TargetField: "id", Parameters: map[string]interface{}{"version": 5}},
+		{Field: "domain", Operation: UUID, TargetField: "id", Parameters: map[string]interface{}{"version": 5, "namespace": "web"}},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		is.ErrorContains(err, "uuid rule", rule.Parameters)
+	}
+}
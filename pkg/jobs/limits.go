@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"fmt"
+	"io"
+)
+
+// InputLimitError is returned when an input exceeds the configured size or row limit
+// of data loaded in memory.
+type InputLimitError struct {
+	Path   string `json:"path"`
+	Unit   string `json:"unit"` // "bytes" or "rows"
+	Limit  int64  `json:"limit"`
+	Actual int64  `json:"actual"` // size of the input, or amount read when the limit tripped
+}
+
+// Error implements the error interface.
+func (e *InputLimitError) Error() string {
+	return fmt.Sprintf("input %s exceeds limit: %d %s read, limit is %d %s", e.Path, e.Actual, e.Unit, e.Limit, e.Unit)
+}
+
+// limitedReader counts bytes read from an input and fails once maxSize is exceeded,
+// which catches inputs growing while being read or whose size is unknown upfront.
+type limitedReader struct {
+	reader  io.Reader
+	path    string
+	maxSize int64
+	read    int64
+}
+
+// Read implements io.Reader.
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.maxSize > 0 && r.read > r.maxSize {
+		return n, &InputLimitError{Path: r.path, Unit: "bytes", Limit: r.maxSize, Actual: r.read}
+	}
+	return n, err
+}
+
+// SetMaxInputSize overrides the largest input size in bytes loaded in memory, 0 disables the limit.
+func (fs *FileService) SetMaxInputSize(maxSize int64) {
+	fs.maxSize = maxSize
+}
+
+// SetMaxInputRows overrides the largest number of rows loaded in memory, 0 disables the limit.
+func (fs *FileService) SetMaxInputRows(maxRows int) {
+	fs.maxRows = maxRows
+}
+
+// checkInputSize fails early when a file is already larger than the configured limit.
+func (fs *FileService) checkInputSize(filepath string, size int64) error {
+	if fs.maxSize > 0 && size > fs.maxSize {
+		return &InputLimitError{Path: filepath, Unit: "bytes", Limit: fs.maxSize, Actual: size}
+	}
+	return nil
+}
+
+// checkInputRows fails once more rows than the configured limit have been loaded.
+func (fs *FileService) checkInputRows(filepath string, rows int) error {
+	if fs.maxRows > 0 && rows > fs.maxRows {
+		return &InputLimitError{Path: filepath, Unit: "rows", Limit: int64(fs.maxRows), Actual: int64(rows)}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileService_ReadCSV_inputLimits(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := filepath.Join(t.TempDir(), "in.csv")
+	is.NoError(os.WriteFile(input, []byte("a,b\n1,2\n3,4\n5,6\n"), 0o600))
+
+	fs := newTestFileService(t)
+	rows, err := fs.ReadCSV(input)
+	is.NoError(err)
+	is.Len(rows, 3)
+
+	fs.SetMaxInputSize(8)
+	_, err = fs.ReadCSV(input)
+	var limitErr *InputLimitError
+	is.True(errors.As(err, &limitErr))
+	is.Equal("bytes", limitErr.Unit)
+	is.Equal(int64(8), limitErr.Limit)
+	is.Equal(int64(16), limitErr.Actual)
+
+	fs.SetMaxInputSize(0)
+	fs.SetMaxInputRows(2)
+	_, err = fs.ReadCSV(input)
+	is.True(errors.As(err, &limitErr))
+	is.Equal("rows", limitErr.Unit)
+	is.Equal(int64(2), limitErr.Limit)
+}
+
+func TestLimitedReader(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	reader := &limitedReader{reader: strings.NewReader(strings.Repeat("x", 100)), path: "stdin", maxSize: 10}
+	buf := make([]byte, 64)
+
+	_, err := reader.Read(buf)
+	var limitErr *InputLimitError
+	is.True(errors.As(err, &limitErr))
+	is.Equal("stdin", limitErr.Path)
+}
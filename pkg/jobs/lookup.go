@@ -0,0 +1,204 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Behaviors of "lookup" rules for values missing from their mapping.
+const (
+	LookupKeep  = "keep"  // keep the value, the default
+	LookupEmpty = "empty" // empty the value
+	LookupValue = "value" // write the default_value
+	LookupError = "error" // fail the transformation
+)
+
+// lookupMapping translates values for "lookup" rules, like country codes to names. Its parameters
+// are an inline "map" object or a mapping "file", "default", what unmapped values become, one of
+// the Lookup behaviors, "default_value", and "case_insensitive". Mapping files are JSON objects
+// when their extension is .json, or CSV or JSON Lines files whose "key_column" maps to their
+// "value_column", the first two columns of CSV files and "key" and "value" in JSON Lines files by
+// default. Empty values are not looked up.
+type lookupMapping struct {
+	values          map[string]string // lowercased keys when case-insensitive
+	source          string            // mapping file, "inline map" for inline ones
+	onMissing       string
+	defaultValue    string
+	caseInsensitive bool
+}
+
+// parseLookupMapping parses the parameters of a "lookup" rule and loads its mapping.
+func (s *TransformService) parseLookupMapping(rule TransformRule) (*lookupMapping, error) {
+	mapping := &lookupMapping{onMissing: LookupKeep}
+	if caseInsensitive, ok := rule.Parameters["case_insensitive"]; ok {
+		if mapping.caseInsensitive, ok = caseInsensitive.(bool); !ok {
+			return nil, fmt.Errorf("invalid case_insensitive of lookup rule on field '%s': expected true or false", rule.Field)
+		}
+	}
+
+	if err := mapping.parseMissing(rule); err != nil {
+		return nil, err
+	}
+
+	inline, hasInline := rule.Parameters["map"]
+	file, _ := rule.Parameters["file"].(string)
+	var err error
+	switch {
+	case hasInline && file != "":
+		return nil, fmt.Errorf("lookup rule on field '%s' has both a map and a file", rule.Field)
+	case hasInline:
+		object, ok := inline.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid map of lookup rule on field '%s': expected an object", rule.Field)
+		}
+		mapping.source, mapping.values = "inline map", make(map[string]string, len(object))
+		for key, value := range object {
+			if err = mapping.add(key, jsonlValue(value)); err != nil {
+				break
+			}
+		}
+	case file != "":
+		mapping.source, mapping.values = file, make(map[string]string)
+		err = s.loadLookupFile(mapping, rule.Parameters)
+	default:
+		return nil, fmt.Errorf("lookup rule on field '%s' needs a map or a file", rule.Field)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping of lookup rule on field '%s': %w", rule.Field, err)
+	}
+
+	s.logger.Info().Str("field", rule.Field).Str("source", mapping.source).Int("entries", len(mapping.values)).Msg("Loaded lookup mapping")
+	return mapping, nil
+}
+
+// loadLookupFile reads the mapping file of a lookup rule.
+func (s *TransformService) loadLookupFile(mapping *lookupMapping, params map[string]interface{}) error {
+	file := mapping.source
+	keyColumn, _ := params["key_column"].(string)
+	valueColumn, _ := params["value_column"].(string)
+
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		return mapping.loadJSONObject()
+	}
+
+	add := func(row DataRow) error {
+		key, ok := row.Fields[keyColumn]
+		if !ok {
+			return fmt.Errorf("column '%s' is missing from %s", keyColumn, row.Location())
+		}
+		return mapping.add(key, row.Fields[valueColumn])
+	}
+
+	if isJSONLFile(file) {
+		if keyColumn == "" {
+			keyColumn = "key"
+		}
+		if valueColumn == "" {
+			valueColumn = "value"
+		}
+		return s.fileService.StreamJSONL(file, add)
+	}
+
+	headers, err := s.fileService.readHeaders(file)
+	if err != nil {
+		return err
+	}
+	if len(headers) < 2 {
+		return fmt.Errorf("%s has fewer than two columns", file)
+	}
+	if keyColumn == "" {
+		keyColumn = headers[0]
+	}
+	if valueColumn == "" {
+		valueColumn = headers[1]
+	}
+	for _, column := range []string{keyColumn, valueColumn} {
+		if !slices.Contains(headers, column) {
+			return fmt.Errorf("column '%s' is missing from %s, available columns: %s", column, file, strings.Join(headers, ", "))
+		}
+	}
+	return s.fileService.StreamCSV(file, add)
+}
+
+// parseMissing parses what the values missing from the mapping of a lookup rule become.
+func (m *lookupMapping) parseMissing(rule TransformRule) error {
+	defaultValue, hasDefaultValue := rule.Parameters["default_value"].(string)
+	switch onMissing, _ := rule.Parameters["default"].(string); {
+	case onMissing == "" && hasDefaultValue, onMissing == LookupValue:
+		if !hasDefaultValue {
+			return fmt.Errorf("lookup rule on field '%s' needs a default_value", rule.Field)
+		}
+		m.onMissing, m.defaultValue = LookupValue, defaultValue
+	case onMissing == "":
+	case onMissing == LookupKeep, onMissing == LookupEmpty, onMissing == LookupError:
+		m.onMissing = onMissing
+	default:
+		return fmt.Errorf("invalid default %q of lookup rule on field '%s': expected keep, empty, value or error", onMissing, rule.Field)
+	}
+	return nil
+}
+
+// loadJSONObject reads a JSON mapping file, an object of values by key.
+func (m *lookupMapping) loadJSONObject() error {
+	//bearer:disable go_gosec_filesystem_filereadtaint
+	data, err := os.ReadFile(m.source)
+	if err != nil {
+		return fmt.Errorf("failed to read mapping file: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return fmt.Errorf("%s: expected an object of values by key: %w", m.source, err)
+	}
+	for key, value := range object {
+		if err := m.add(key, jsonlValue(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add maps a key to a value, failing when the key is mapped to another value.
+func (m *lookupMapping) add(key, value string) error {
+	normalized := m.normalize(key)
+	if previous, ok := m.values[normalized]; ok && previous != value {
+		return fmt.Errorf("key %q is mapped to both %q and %q", key, previous, value)
+	}
+	m.values[normalized] = value
+	return nil
+}
+
+// normalize returns the key a value is looked up with.
+func (m *lookupMapping) normalize(value string) string {
+	if m.caseInsensitive {
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// lookup returns the value a value maps to, failing for unmapped values with the error default.
+func (m *lookupMapping) lookup(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if mapped, ok := m.values[m.normalize(value)]; ok {
+		return mapped, nil
+	}
+
+	switch m.onMissing {
+	case LookupEmpty:
+		return "", nil
+	case LookupValue:
+		return m.defaultValue, nil
+	case LookupError:
+		return "", fmt.Errorf("value %q is not in the %s", value, m.source)
+	default:
+		return value, nil
+	}
+}
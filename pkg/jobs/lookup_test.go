@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_lookup(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"countries.csv":   "code,name,continent\nFR,France,Europe\nUS,United States,America\n",
+		"countries.json":  `{"FR": "France", "US": "United States"}`,
+		"countries.jsonl": "{\"iso\": \"FR\", \"label\": \"France\"}\n{\"iso\": \"US\", \"label\": \"United States\"}\n",
+		"continents.csv":  "name,code,continent\nFrance,FR,Europe\nUnited States,US,America\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		want   []string
+	}{
+		{name: "inline map", params: map[string]interface{}{"map": map[string]interface{}{"FR": "France", "US": "United States"}}, want: []string{"France", "fr", "", "DE"}},
+		{name: "csv file", params: map[string]interface{}{"file": filepath.Join(dir, "countries.csv")}, want: []string{"France", "fr", "", "DE"}},
+		{name: "csv columns", params: map[string]interface{}{"file": filepath.Join(dir, "continents.csv"), "key_column": "code", "value_column": "continent"}, want: []string{"Europe", "fr", "", "DE"}},
+		{name: "json file", params: map[string]interface{}{"file": filepath.Join(dir, "countries.json"), "default": "empty"}, want: []string{"France", "", "", ""}},
+		{name: "jsonl file", params: map[string]interface{}{"file": filepath.Join(dir, "countries.jsonl"), "key_column": "iso", "value_column": "label", "default_value": "Other"}, want: []string{"France", "Other", "", "Other"}},
+		{name: "case insensitive", params: map[string]interface{}{"map": map[string]interface{}{"fr": "France"}, "case_insensitive": true, "default": "keep"}, want: []string{"France", "France", "", "DE"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"country"}, []string{"FR"}, []string{"fr"}, []string{""}, []string{"DE"})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "country", Operation: Lookup, Parameters: tc.params, TargetField: "country_name"}},
+			})
+			is.NoError(err)
+			for i, row := range output {
+				is.Equal(tc.want[i], row.Fields["country_name"], i)
+			}
+		})
+	}
+}
+
+func TestTransformService_lookupErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.csv")
+	conflicting := filepath.Join(dir, "conflicting.csv")
+	is.NoError(os.WriteFile(input, []byte("country\nFR\nDE\n"), 0o600))
+	is.NoError(os.WriteFile(conflicting, []byte("code,name\nfr,France\nFR,Francia\n"), 0o600))
+
+	// unmapped values fail the transformation with the error default
+	_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"input_file": input,
+		"rules": []TransformRule{{Field: "country", Operation: Lookup, Parameters: map[string]interface{}{
+			"map": map[string]interface{}{"FR": "France"}, "default": "error",
+		}}},
+	})
+	is.ErrorContains(err, `input.csv:3: lookup rule on field 'country': value "DE" is not in the inline map`)
+
+	for _, params := range []map[string]interface{}{
+		{},
+		{"file": filepath.Join(dir, "missing.csv")},
+		{"file": conflicting, "case_insensitive": true},
+		{"file": conflicting, "value_column": "label"},
+		{"map": map[string]interface{}{}, "file": conflicting},
+		{"map": []interface{}{"FR"}},
+		{"map": map[string]interface{}{}, "default": "value"},
+		{"map": map[string]interface{}{}, "default": "drop"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"input_file": input,
+			"rules":      []TransformRule{{Field: "country", Operation: Lookup, Parameters: params}},
+		})
+		is.ErrorContains(err, "lookup rule on field 'country'", params)
+	}
+}
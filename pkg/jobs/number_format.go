@@ -0,0 +1,181 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Positions of the currencies of "format_number" rules.
+const (
+	CurrencyBefore = "before" // like "$1,234.56"
+	CurrencyAfter  = "after"  // like "1 234,56 €"
+)
+
+// localizedNumber writes and reads numbers the way a locale does, for "format_number" and
+// "parse_number" rules. Their parameters are "locale", like "fr-FR", and "group_separator" and
+// "decimal_separator", overriding the ones of the locale, an empty group separator writing
+// digits ungrouped. "format_number" rules read plain numbers, like "1234.5", write them with
+// "precision" decimals, 2 by default, and "rounding", and may add a "currency", an ISO 4217 code
+// written as its symbol, like "EUR", or a symbol as is, before or after the amount as in the
+// locale unless "currency_position" says otherwise. "parse_number" rules write numbers in Go
+// syntax, with as many decimals as they need unless "precision" is set; their locale may also be
+// a number format of filters, auto by default, ignoring currency codes and symbols.
+type localizedNumber struct {
+	format        NumberFormat // how parse_number rules read numbers, unless they set separators
+	locale        numberLocale
+	output        numberOutput
+	currency      string // symbol of format_number rules
+	currencyAfter bool
+}
+
+// parseLocalizedNumber parses the parameters of a "format_number", "parse_number" or
+// "parse_currency" rule.
+func parseLocalizedNumber(rule TransformRule) (*localizedNumber, error) {
+	number := &localizedNumber{format: NumberFormatAuto, locale: numberLocales["en"]}
+
+	tag, _ := rule.Parameters["locale"].(string)
+	if rule.Operation == FormatNumber && tag == "" {
+		tag = "en-US"
+	}
+	if tag != "" {
+		var err error
+		if number.format, err = ParseNumberFormat(tag); err != nil {
+			return nil, fmt.Errorf("invalid locale of %s rule on field '%s': %w", rule.Operation, rule.Field, err)
+		}
+		locale, ok := lookupNumberLocale(tag)
+		if !ok && rule.Operation == FormatNumber {
+			return nil, fmt.Errorf("invalid locale %q of format_number rule on field '%s': expected a locale like fr-FR", tag, rule.Field)
+		}
+		if ok {
+			number.locale = locale
+		}
+	}
+
+	if err := number.parseSeparators(rule); err != nil {
+		return nil, err
+	}
+
+	precision := -1
+	if rule.Operation == FormatNumber {
+		precision = 2
+	}
+	var err error
+	if number.output, err = parseNumberOutput(rule, precision); err != nil {
+		return nil, err
+	}
+
+	if rule.Operation == FormatNumber {
+		if err := number.parseCurrency(rule); err != nil {
+			return nil, err
+		}
+	}
+	return number, nil
+}
+
+// parseSeparators parses the separators of a rule, which override those of its locale.
+func (n *localizedNumber) parseSeparators(rule TransformRule) error {
+	custom := false
+	for name, separator := range map[string]*string{"group_separator": &n.locale.group, "decimal_separator": &n.locale.decimal} {
+		if raw, ok := rule.Parameters[name]; ok {
+			if *separator, ok = raw.(string); !ok {
+				return fmt.Errorf("invalid %s of %s rule on field '%s': expected a string", name, rule.Operation, rule.Field)
+			}
+			custom = true
+		}
+	}
+	if n.locale.decimal == "" || n.locale.decimal == n.locale.group {
+		return fmt.Errorf("invalid separators of %s rule on field '%s': expected a decimal separator other than the group one", rule.Operation, rule.Field)
+	}
+	if custom {
+		n.format = ""
+	}
+	return nil
+}
+
+// parseCurrency parses the currency parameters of a "format_number" rule.
+func (n *localizedNumber) parseCurrency(rule TransformRule) error {
+	currency, _ := rule.Parameters["currency"].(string)
+	n.currency = currency
+	for _, known := range currencies {
+		if strings.EqualFold(currency, known.code) {
+			n.currency = known.symbol
+			break
+		}
+	}
+
+	n.currencyAfter = n.locale.currencyAfter
+	switch position, _ := rule.Parameters["currency_position"].(string); position {
+	case "":
+	case CurrencyBefore, CurrencyAfter:
+		n.currencyAfter = position == CurrencyAfter
+	default:
+		return fmt.Errorf("invalid currency_position %q of format_number rule on field '%s': expected before or after", position, rule.Field)
+	}
+	return nil
+}
+
+// write writes a number the way the locale does, failing when the value is not a plain number.
+func (n *localizedNumber) write(value string) (string, bool) {
+	number, ok := parseDecimal(strings.TrimSpace(value), true)
+	if !ok {
+		return "", false
+	}
+
+	digits := n.output.format(number)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	integer, fraction, _ := strings.Cut(digits, ".")
+
+	var b strings.Builder
+	b.WriteString(sign)
+	if n.currency != "" && !n.currencyAfter {
+		b.WriteString(n.currency + n.currencySpace())
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(n.locale.group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(n.locale.decimal + fraction)
+	}
+	if n.currency != "" && n.currencyAfter {
+		b.WriteString(n.currencySpace() + n.currency)
+	}
+	return b.String(), true
+}
+
+// currencySpace returns what sets the currency apart from the amount: the space of the locale,
+// or a no-break space for currencies written with letters, like "CHF 12.50".
+func (n *localizedNumber) currencySpace() string {
+	if n.locale.currencySpace != "" {
+		return n.locale.currencySpace
+	}
+	first, _ := utf8.DecodeRuneInString(n.currency)
+	last, _ := utf8.DecodeLastRuneInString(n.currency)
+	if n.currencyAfter && unicode.IsLetter(first) || !n.currencyAfter && unicode.IsLetter(last) {
+		return "\u00a0"
+	}
+	return ""
+}
+
+// read normalizes a number written the way the locale does to Go syntax, failing when it is
+// not a number.
+func (n *localizedNumber) read(value string) (string, bool) {
+	var normalized string
+	if n.format == "" {
+		normalized = n.locale.normalize(stripCurrency(strings.TrimSpace(value)))
+	} else {
+		normalized, _ = normalizeNumber(value, n.format)
+	}
+	number, ok := parseDecimal(normalized, true)
+	if !ok {
+		return "", false
+	}
+	return n.output.format(number), true
+}
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_formatNumber(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "en-US default", value: "1234.5", want: "1,234.50"},
+		{name: "en-US currency", value: "1234.56", params: map[string]interface{}{"currency": "USD"}, want: "$1,234.56"},
+		{name: "en-US negative currency", value: "-1234.56", params: map[string]interface{}{"currency": "USD"}, want: "-$1,234.56"},
+		{name: "fr-FR currency", value: "1234.56", params: map[string]interface{}{"locale": "fr-FR", "currency": "EUR"}, want: "1\u202f234,56\u00a0€"},
+		{name: "de-DE", value: "1234567.891", params: map[string]interface{}{"locale": "de-DE"}, want: "1.234.567,89"},
+		{name: "de-DE currency", value: "-0.5", params: map[string]interface{}{"locale": "de_DE", "currency": "eur"}, want: "-0,50\u00a0€"},
+		{name: "de-CH letters currency", value: "1234.5", params: map[string]interface{}{"locale": "de-CH", "currency": "CHF"}, want: "CHF\u00a01\u2019234.50"},
+		{name: "en letters currency", value: "12", params: map[string]interface{}{"currency": "CHF"}, want: "CHF\u00a012.00"},
+		{name: "currency after", value: "12", params: map[string]interface{}{"currency": "$", "currency_position": "after"}, want: "12.00$"},
+		{name: "explicit separators", value: "1234.5", params: map[string]interface{}{"group_separator": " ", "decimal_separator": ","}, want: "1 234,50"},
+		{name: "ungrouped", value: "1234.5", params: map[string]interface{}{"locale": "fr-FR", "group_separator": ""}, want: "1234,50"},
+		{name: "no decimals", value: "999.5", params: map[string]interface{}{"precision": 0, "rounding": "half_up"}, want: "1,000"},
+		{name: "language only", value: "1000", params: map[string]interface{}{"locale": "it", "precision": 0}, want: "1.000"},
+		{name: "empty", value: "", want: ""},
+		{name: "not a number kept", value: "n/a", want: "n/a"},
+		{name: "not a number emptied", value: "1,234", params: map[string]interface{}{"on_error": "empty"}, want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(
+				[]DataRow{{Fields: map[string]string{"amount": tc.value}}},
+				map[string]interface{}{"rules": []TransformRule{{Field: "amount", Operation: FormatNumber, Parameters: tc.params}}},
+			)
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["amount"])
+		})
+	}
+}
+
+func TestTransformService_parseNumber(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "auto english", value: "$1,234.56", want: "1234.56"},
+		{name: "auto european", value: "1.234,50 €", want: "1234.5"},
+		{name: "fr-FR", value: "1\u202f234,56\u00a0€", params: map[string]interface{}{"locale": "fr-FR"}, want: "1234.56"},
+		{name: "fr-FR plain spaces", value: "-1 234,5", params: map[string]interface{}{"locale": "fr-FR"}, want: "-1234.5"},
+		{name: "de-DE", value: "1.234.567,891", params: map[string]interface{}{"locale": "de-DE"}, want: "1234567.891"},
+		{name: "eu format", value: "12,5", params: map[string]interface{}{"locale": "eu"}, want: "12.5"},
+		{name: "currency code", value: "CHF 1\u2019234.50", params: map[string]interface{}{"locale": "de-CH"}, want: "1234.5"},
+		{name: "explicit separators", value: "1'234|5", params: map[string]interface{}{"group_separator": "'", "decimal_separator": "|"}, want: "1234.5"},
+		{name: "precision", value: "1,234.567", params: map[string]interface{}{"locale": "en-US", "precision": 2}, want: "1234.57"},
+		{name: "empty", value: "", want: ""},
+		{name: "not a number kept", value: "twelve", want: "twelve"},
+		{name: "not a number emptied", value: "12 apples", params: map[string]interface{}{"on_error": "empty"}, want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(
+				[]DataRow{{Fields: map[string]string{"amount": tc.value}}},
+				map[string]interface{}{"rules": []TransformRule{{Field: "amount", Operation: ParseLocaleNumber, Parameters: tc.params}}},
+			)
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["amount"])
+		})
+	}
+}
+
+func TestTransformService_localizedNumberRoundTrip(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	values := []string{"0.00", "7.10", "-42.00", "999.99", "1000.00", "-1234.56", "1234567.89", "100000000.01"}
+	records := make([][]string, 0, len(values))
+	for _, value := range values {
+		records = append(records, []string{value})
+	}
+	for _, locale := range []string{"en-US", "fr-FR", "de-DE"} {
+		for _, currency := range []string{"", "EUR", "USD"} {
+			output, err := newTestTransformService(t).ProcessData(
+				testRows(t, []string{"amount"}, records...),
+				map[string]interface{}{"chained": true, "rules": []TransformRule{
+					{Field: "amount", Operation: FormatNumber, TargetField: "formatted", Parameters: map[string]interface{}{"locale": locale, "currency": currency}},
+					{Field: "formatted", Operation: ParseLocaleNumber, TargetField: "parsed", Parameters: map[string]interface{}{"locale": locale, "precision": 2}},
+				}},
+			)
+			is.NoError(err)
+			for i, row := range output {
+				is.Equal(values[i], row.Fields["parsed"], "%s %s %q", locale, currency, row.Fields["formatted"])
+			}
+		}
+	}
+}
+
+func TestTransformService_localizedNumberErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for _, rule := range []TransformRule{
+		{Operation: FormatNumber, Parameters: map[string]interface{}{"locale": "tlh"}},
+		{Operation: FormatNumber, Parameters: map[string]interface{}{"locale": "auto"}},
+		{Operation: FormatNumber, Parameters: map[string]interface{}{"decimal_separator": ","}},
+		{Operation: FormatNumber, Parameters: map[string]interface{}{"decimal_separator": ""}},
+		{Operation: FormatNumber, Parameters: map[string]interface{}{"group_separator": 1}},
+		{Operation: FormatNumber, Parameters: map[string]interface{}{"currency_position": "middle"}},
+		{Operation: FormatNumber, Parameters: map[string]interface{}{"precision": -2}},
+		{Operation: ParseLocaleNumber, Parameters: map[string]interface{}{"locale": "tlh"}},
+		{Operation: ParseLocaleNumber, Parameters: map[string]interface{}{"rounding": "down"}},
+	} {
+		rule.Field = "amount"
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		is.ErrorContains(err, string(rule.Operation)+" rule on field 'amount'", rule.Parameters)
+	}
+}
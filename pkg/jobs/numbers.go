@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NumberFormat tells how numbers written as text are parsed.
+type NumberFormat string
+
+const (
+	// NumberFormatPlain accepts Go float syntax only, like "1234.56". This is the default.
+	NumberFormatPlain NumberFormat = "plain"
+	// NumberFormatEnglish accepts comma thousands separators, like "1,234.56".
+	NumberFormatEnglish NumberFormat = "en"
+	// NumberFormatEuropean accepts dot or space thousands separators and comma decimals,
+	// like "1.234,56" or "1 234,56".
+	NumberFormatEuropean NumberFormat = "eu"
+	// NumberFormatAuto guesses the separators of each value, taking the last of '.' and ','
+	// as the decimal separator when both appear.
+	NumberFormatAuto NumberFormat = "auto"
+)
+
+// Number formats may also be locales, like "fr-FR", reading numbers with the separators of
+// numberLocales.
+
+// numberLocale holds how numbers and amounts are written in a locale.
+type numberLocale struct {
+	group         string // thousands separator
+	decimal       string // decimal separator
+	currencyAfter bool   // whether currencies follow amounts, like "12,50 €"
+	currencySpace string // between amounts and currencies
+}
+
+// numberLocales are the locales of number formats, by lowercase language or language and region,
+// the language standing for its regions without an entry. Separators follow CLDR, French grouping
+// digits with narrow no-break spaces and currencies being set apart with no-break spaces.
+var numberLocales = map[string]numberLocale{
+	"en":    {group: ",", decimal: "."},
+	"ja":    {group: ",", decimal: "."},
+	"zh":    {group: ",", decimal: "."},
+	"fr":    {group: "\u202f", decimal: ",", currencyAfter: true, currencySpace: "\u00a0"},
+	"de":    {group: ".", decimal: ",", currencyAfter: true, currencySpace: "\u00a0"},
+	"de-ch": {group: "\u2019", decimal: ".", currencySpace: "\u00a0"},
+	"es":    {group: ".", decimal: ",", currencyAfter: true, currencySpace: "\u00a0"},
+	"it":    {group: ".", decimal: ",", currencyAfter: true, currencySpace: "\u00a0"},
+	"nl":    {group: ".", decimal: ",", currencySpace: "\u00a0"},
+	"pt":    {group: ".", decimal: ",", currencySpace: "\u00a0"},
+	"pt-pt": {group: "\u00a0", decimal: ",", currencyAfter: true, currencySpace: "\u00a0"},
+}
+
+// currencies are the ISO 4217 codes and symbols of the currencies amounts are written with,
+// the symbols with letters first so that they are not read as shorter ones.
+var currencies = []struct{ code, symbol string }{
+	{"BRL", "R$"}, {"CAD", "CA$"}, {"AUD", "A$"}, {"NZD", "NZ$"}, {"HKD", "HK$"}, {"MXN", "MX$"},
+	{"CHF", "CHF"}, {"PLN", "z\u0142"}, {"USD", "$"}, {"EUR", "\u20ac"}, {"GBP", "\u00a3"},
+	{"JPY", "\u00a5"}, {"CNY", "\u00a5"}, {"INR", "\u20b9"}, {"KRW", "\u20a9"}, {"RUB", "\u20bd"},
+	{"TRY", "\u20ba"}, {"ILS", "\u20aa"},
+}
+
+// lookupNumberLocale returns the locale of a tag like "fr-FR" or "pt_BR", falling back to
+// its language.
+func lookupNumberLocale(tag string) (numberLocale, bool) {
+	tag = strings.ReplaceAll(strings.ToLower(tag), "_", "-")
+	if locale, ok := numberLocales[tag]; ok {
+		return locale, true
+	}
+	language, _, _ := strings.Cut(tag, "-")
+	locale, ok := numberLocales[language]
+	return locale, ok
+}
+
+// normalize rewrites the separators of a number written in the locale to Go float syntax.
+func (l numberLocale) normalize(value string) string {
+	if l.group != "" {
+		value = strings.ReplaceAll(value, l.group, "")
+	}
+	return strings.ReplaceAll(value, l.decimal, ".")
+}
+
+// ParseNumberFormat validates a number format name, the empty name being NumberFormatPlain.
+func ParseNumberFormat(name string) (NumberFormat, error) {
+	switch format := NumberFormat(strings.ToLower(name)); format {
+	case "", NumberFormatPlain:
+		return NumberFormatPlain, nil
+	case NumberFormatEnglish, NumberFormatEuropean, NumberFormatAuto:
+		return format, nil
+	default:
+		if _, ok := lookupNumberLocale(name); ok {
+			return NumberFormat(strings.ReplaceAll(string(format), "_", "-")), nil
+		}
+		return "", fmt.Errorf("unknown number format %q: expected plain, en, eu, auto or a locale like fr-FR", name)
+	}
+}
+
+// ParseNumber parses a number written in the given format. Except in the plain format,
+// currency symbols and codes and whitespace anywhere in the value are ignored, so "€ 99,90"
+// is 99.9 in the eu format.
+func ParseNumber(value string, format NumberFormat) (float64, bool) {
+	normalized, ok := normalizeNumber(value, format)
+	if !ok {
+		return 0, false
+	}
+	num, err := strconv.ParseFloat(normalized, 64)
+	return num, err == nil
+}
+
+// normalizeNumber rewrites a number written in the given format to Go float syntax, as ParseNumber
+// reads it, without parsing it.
+func normalizeNumber(value string, format NumberFormat) (string, bool) {
+	value = strings.TrimSpace(value)
+	if format == "" || format == NumberFormatPlain {
+		return value, value != ""
+	}
+
+	value = stripCurrency(value)
+
+	//nolint:exhaustive
+	switch format {
+	case NumberFormatEnglish:
+		value = strings.ReplaceAll(value, ",", "")
+	case NumberFormatEuropean:
+		value = strings.ReplaceAll(strings.ReplaceAll(value, ".", ""), ",", ".")
+	case NumberFormatAuto:
+		value = normalizeSeparators(value)
+	default:
+		if locale, ok := lookupNumberLocale(string(format)); ok {
+			value = locale.normalize(value)
+		}
+	}
+	return value, value != ""
+}
+
+// stripCurrency removes the currency and the whitespace of an amount.
+func stripCurrency(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.Is(unicode.Sc, r) {
+			return -1
+		}
+		return r
+	}, trimCurrency(value))
+}
+
+// trimCurrency removes the currency code or symbol written before or after an amount, after
+// its sign, like "-R$ 12" or "12 CHF".
+func trimCurrency(value string) string {
+	sign := ""
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		sign, value = value[:1], value[1:]
+	}
+	for _, currency := range currencies {
+		for _, text := range []string{currency.code, currency.symbol} {
+			if trimmed, ok := strings.CutPrefix(value, text); ok {
+				return sign + strings.TrimSpace(trimmed)
+			}
+			if trimmed, ok := strings.CutSuffix(value, text); ok {
+				return sign + strings.TrimSpace(trimmed)
+			}
+		}
+	}
+	return sign + value
+}
+
+// normalizeSeparators rewrites a number with guessed separators to Go float syntax.
+// A lone separator is a decimal one unless it is a comma followed by exactly three digits.
+func normalizeSeparators(value string) string {
+	lastDot, lastComma := strings.LastIndex(value, "."), strings.LastIndex(value, ",")
+
+	decimal := byte('.')
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			decimal = ','
+		}
+	case lastComma >= 0:
+		if strings.Count(value, ",") == 1 && len(value)-lastComma-1 != 3 {
+			decimal = ','
+		} else {
+			decimal = 0
+		}
+	case lastDot >= 0 && strings.Count(value, ".") > 1:
+		decimal = 0
+	}
+
+	var normalized strings.Builder
+	for i := range len(value) {
+		switch c := value[i]; {
+		case c == decimal:
+			normalized.WriteByte('.')
+		case c == '.' || c == ',':
+			// thousands separator
+		default:
+			normalized.WriteByte(c)
+		}
+	}
+	return normalized.String()
+}
+
+// numberComparison holds how a filter rule parses and compares numbers.
+type numberComparison struct {
+	format  NumberFormat
+	epsilon float64 // largest difference of numbers still considered equal
+}
+
+// parse parses a field value as a number.
+func (c numberComparison) parse(value string) (float64, bool) {
+	return ParseNumber(value, c.format)
+}
+
+// lenient reports whether numbers written as strings need a numeric comparison,
+// because they may differ as text while being equal as numbers.
+func (c numberComparison) lenient() bool {
+	return c.format != "" && c.format != NumberFormatPlain || c.epsilon > 0
+}
+
+// equal reports whether two numbers are equal within epsilon.
+func (c numberComparison) equal(a, b float64) bool {
+	return a == b || math.Abs(a-b) <= c.epsilon
+}
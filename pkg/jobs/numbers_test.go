@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumber(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		format   NumberFormat
+		expected float64
+		ok       bool
+	}{
+		{" 1234.56 ", NumberFormatPlain, 1234.56, true},
+		{"1,234.56", NumberFormatPlain, 0, false},
+		{"1,234.56", NumberFormatEnglish, 1234.56, true},
+		{"$1,234,567", NumberFormatEnglish, 1234567, true},
+		{"1.234,56", NumberFormatEuropean, 1234.56, true},
+		{"1 234,56", NumberFormatEuropean, 1234.56, true},
+		{"1 234,56", NumberFormatEuropean, 1234.56, true},
+		{"€ 99,90", NumberFormatEuropean, 99.9, true},
+		{"-12,5 €", NumberFormatEuropean, -12.5, true},
+		{"1,234.56", NumberFormatAuto, 1234.56, true},
+		{"1.234,56", NumberFormatAuto, 1234.56, true},
+		{"€ 99,90", NumberFormatAuto, 99.9, true},
+		{"1\u202f234,56\u00a0€", "fr-fr", 1234.56, true},
+		{"1.234,56 €", "de", 1234.56, true},
+		{"CHF 1\u2019234.50", "de-ch", 1234.5, true},
+		{"-R$ 1.234,56", "pt-br", -1234.56, true},
+		{"12.50 CHF", NumberFormatEnglish, 12.5, true},
+		{"1,234", NumberFormatAuto, 1234, true},
+		{"1.5", NumberFormatAuto, 1.5, true},
+		{"1.234.567", NumberFormatAuto, 1234567, true},
+		{"€", NumberFormatAuto, 0, false},
+		{"n/a", NumberFormatEuropean, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.format)+" "+tc.value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			num, ok := ParseNumber(tc.value, tc.format)
+			is.Equal(tc.ok, ok)
+			if tc.ok {
+				is.InDelta(tc.expected, num, 1e-9)
+			}
+		})
+	}
+}
+
+func TestParseNumberFormat(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	format, err := ParseNumberFormat("")
+	is.NoError(err)
+	is.Equal(NumberFormatPlain, format)
+
+	format, err = ParseNumberFormat("EU")
+	is.NoError(err)
+	is.Equal(NumberFormatEuropean, format)
+
+	format, err = ParseNumberFormat("fr_FR")
+	is.NoError(err)
+	is.Equal(NumberFormat("fr-fr"), format)
+
+	_, err = ParseNumberFormat("tlh")
+	is.Error(err)
+}
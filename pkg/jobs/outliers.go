@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Outlier detection methods of "outlier" rules.
+const (
+	OutlierZScore = "zscore" // values more than threshold standard deviations from the mean
+	OutlierIQR    = "iqr"    // values more than threshold interquartile ranges beyond the quartiles
+)
+
+// defaultOutlierThresholds are the thresholds of outlier methods when rules set none.
+var defaultOutlierThresholds = map[string]float64{
+	OutlierZScore: 3,
+	OutlierIQR:    1.5,
+}
+
+// outlierConstraint checks the values of an "outlier" rule against the distribution of the numeric
+// values of its field. Its constraints are {"method": "zscore"|"iqr", "threshold": k}, zscore and
+// thresholds of 3 standard deviations or 1.5 interquartile ranges by default.
+// The distribution is computed by a first pass over every row, before rows are validated:
+// empty and non-numeric values are left out of it, and are never outliers.
+type outlierConstraint struct {
+	method    string
+	threshold float64
+	low, high float64 // bounds of the values that are not outliers, set by fit
+	fitted    bool    // whether the field had numeric values to set the bounds from
+}
+
+// parseOutlierConstraint parses the constraints of an "outlier" rule.
+func parseOutlierConstraint(rule ValidationRule) (*outlierConstraint, error) {
+	constraint := &outlierConstraint{method: OutlierZScore}
+	if rule.Constraints != nil {
+		constraints, ok := rule.Constraints.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid constraints of outlier rule on field '%s': expected an object", rule.Field)
+		}
+		if method, ok := constraints["method"].(string); ok && method != "" {
+			constraint.method = strings.ToLower(method)
+		}
+		if threshold, ok := constraints["threshold"]; ok {
+			if constraint.threshold, ok = toFloat(threshold); !ok || constraint.threshold <= 0 {
+				return nil, fmt.Errorf("invalid threshold %v of outlier rule on field '%s': expected a positive number", threshold, rule.Field)
+			}
+		}
+	}
+
+	defaultThreshold, ok := defaultOutlierThresholds[constraint.method]
+	if !ok {
+		return nil, fmt.Errorf("unknown outlier method %q on field '%s': expected zscore or iqr", constraint.method, rule.Field)
+	}
+	if constraint.threshold == 0 {
+		constraint.threshold = defaultThreshold
+	}
+	return constraint, nil
+}
+
+// fit computes the bounds of the values of a field that are not outliers, from every row.
+// The z-score method only keeps running sums, while the IQR one sorts the values.
+func (c *outlierConstraint) fit(data []DataRow, field string) {
+	var values []float64
+	var count int
+	var mean, squares float64
+	for _, row := range data {
+		value, err := strconv.ParseFloat(row.Fields[field], 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		count++
+		if c.method == OutlierIQR {
+			values = append(values, value)
+			continue
+		}
+		// population mean and variance, with Welford's algorithm
+		delta := value - mean
+		mean += delta / float64(count)
+		squares += delta * (value - mean)
+	}
+	c.fitted = count > 0
+	if !c.fitted {
+		return
+	}
+
+	if c.method == OutlierIQR {
+		sort.Float64s(values)
+		q1, q3 := quantile(values, 0.25), quantile(values, 0.75)
+		c.low, c.high = q1-c.threshold*(q3-q1), q3+c.threshold*(q3-q1)
+		return
+	}
+	deviation := math.Sqrt(squares / float64(count))
+	c.low, c.high = mean-c.threshold*deviation, mean+c.threshold*deviation
+}
+
+// quantile returns the q quantile of sorted values, interpolating linearly between ranks.
+func quantile(sorted []float64, q float64) float64 {
+	rank := q * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// check validates a value, returning the failure message, if any.
+func (c *outlierConstraint) check(value string) string {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || !c.fitted || (number >= c.low && number <= c.high) {
+		return ""
+	}
+	return fmt.Sprintf("Value %s is an outlier, expected between %s and %s (%s, threshold %s)",
+		value, formatBound(c.low), formatBound(c.high), c.method, strconv.FormatFloat(c.threshold, 'g', -1, 64))
+}
+
+// formatBound formats an outlier bound with at most 2 decimals.
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(math.Round(bound*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateService_outlierRule(t *testing.T) {
+	t.Parallel()
+
+	// 1 to 20, then 100: a mean of 14.76 and a standard deviation of 19.87, quartiles of 6 and 16
+	values := make([][]string, 0, 23)
+	for i := 1; i <= 20; i++ {
+		values = append(values, []string{strconv.Itoa(i)})
+	}
+	values = append(values, []string{"100"}, []string{""}, []string{"n/a"})
+	rows := testRows(t, []string{"price"}, values...)
+
+	testCases := []struct {
+		name        string
+		constraints interface{}
+		outliers    []int
+		message     string
+	}{
+		{"zscore by default", nil, []int{21}, "Value 100 is an outlier, expected between -44.86 and 74.38 (zscore, threshold 3)"},
+		{"zscore threshold", map[string]interface{}{"threshold": float64(1)}, []int{21}, "Value 100 is an outlier, expected between -5.11 and 34.64 (zscore, threshold 1)"},
+		{"iqr", map[string]interface{}{"method": "iqr"}, []int{21}, "Value 100 is an outlier, expected between -9 and 31 (iqr, threshold 1.5)"},
+		{"iqr threshold", map[string]interface{}{"method": "IQR", "threshold": 0.1}, []int{1, 2, 3, 4, 18, 19, 20, 21}, "Value 1 is an outlier, expected between 5 and 17 (iqr, threshold 0.1)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestValidateService(t)
+			opts, err := s.parseValidateOptions(map[string]interface{}{
+				"rules": []ValidationRule{{Field: "price", Type: "outlier", Constraints: tc.constraints}},
+			})
+			is.NoError(err)
+			result, valid, _ := s.validateData(rows, opts)
+			// outliers only warn by default
+			is.Empty(result.Errors)
+			is.Len(valid, len(rows))
+			is.Equal(tc.outliers, validationRows(result.Warnings))
+			is.Equal(tc.message, result.Warnings[0].Message)
+		})
+	}
+
+	is := assert.New(t)
+	s := newTestValidateService(t)
+	for _, constraints := range []interface{}{
+		"iqr",
+		map[string]interface{}{"method": "mad"},
+		map[string]interface{}{"threshold": float64(0)},
+		map[string]interface{}{"threshold": "high"},
+	} {
+		_, err := s.parseValidateOptions(map[string]interface{}{
+			"rules": []ValidationRule{{Field: "price", Type: "outlier", Constraints: constraints}},
+		})
+		is.Error(err)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	is.InDelta(2.5, quantile([]float64{1, 2, 3, 4}, 0.5), 1e-9)
+	is.InDelta(1.75, quantile([]float64{1, 2, 3, 4}, 0.25), 1e-9)
+	is.InDelta(4, quantile([]float64{1, 2, 3, 4}, 1), 1e-9)
+	is.InDelta(7, quantile([]float64{7}, 0.75), 1e-9)
+}
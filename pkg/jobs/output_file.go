@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// outputFile is an output written to a temporary file next to its target and
+// renamed into place on Commit, so readers never observe a partially written file.
+type outputFile struct {
+	*os.File
+	target    string
+	committed bool
+}
+
+// createOutputFile creates the parent directories of target if needed and opens
+// a temporary file in the same directory, using the configured permissions.
+func (fs *FileService) createOutputFile(target string) (*outputFile, error) {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, fs.dirMode); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	base := filepath.Base(target)
+	for attempt := 0; attempt < 10; attempt++ {
+		tmpPath := filepath.Join(dir, "."+base+".tmp-"+strconv.FormatUint(rand.Uint64(), 36)) //nolint:gosec
+
+		//bearer:disable go_gosec_file_permissions_file_perm
+		file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fs.fileMode)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+
+		return &outputFile{File: file, target: target}, nil
+	}
+
+	return nil, fmt.Errorf("failed to create temporary file for %s", target)
+}
+
+// Commit closes the temporary file and moves it to its final location.
+func (f *outputFile) Commit() error {
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), f.target); err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	f.committed = true
+	return nil
+}
+
+// Abort discards the temporary file unless it has already been committed.
+func (f *outputFile) Abort() {
+	if f.committed {
+		return
+	}
+
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// writeOutput writes an output file with a write function, moving it into place once it succeeds.
+func (fs *FileService) writeOutput(target string, write func(io.Writer) error) error {
+	fs.logger.Info().Str("filepath", target).Msg("Writing file")
+
+	file, err := fs.createOutputFile(target)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	if err := write(file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+
+	if err := file.Commit(); err != nil {
+		return err
+	}
+
+	fs.logger.Info().Str("filepath", target).Msg("Successfully wrote file")
+	return nil
+}
@@ -9,4 +9,6 @@ var Package = do.Package(
 	do.Lazy(NewAggregateService),
 	do.Lazy(NewValidateService),
 	do.Lazy(NewTransformService),
+	do.Lazy(NewDedupeService),
+	do.Lazy(NewWindowService),
 )
@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Algorithms of "phonetic" rules.
+const (
+	PhoneticSoundex         = "soundex"          // American Soundex, like "R163" for "Robert"
+	PhoneticDoubleMetaphone = "double_metaphone" // Double Metaphone, like "XMT" for "Schmidt"
+)
+
+// Codes of "phonetic" rules with the double_metaphone algorithm.
+const (
+	PhoneticPrimary   = "primary"   // the primary code, the default
+	PhoneticAlternate = "alternate" // the alternate code
+	PhoneticBoth      = "both"      // both codes joined with the separator
+)
+
+// phoneticEncoding writes the phonetic code of a value for "phonetic" rules, to match names that
+// sound alike. Its parameters are "algorithm", one of the Phonetic algorithms, soundex by default,
+// "max_length", the length of the codes, 4 by default, and for double_metaphone, "code", one of
+// the Phonetic codes, joined with "separator", "," by default. Values are written in ASCII first,
+// as remove_diacritics rules do, values without letters having an empty code.
+type phoneticEncoding struct {
+	algorithm string
+	maxLength int
+	code      string
+	separator string
+}
+
+// parsePhoneticEncoding parses the parameters of a "phonetic" rule.
+func parsePhoneticEncoding(rule TransformRule) (*phoneticEncoding, error) {
+	encoding := &phoneticEncoding{algorithm: PhoneticSoundex, maxLength: 4, code: PhoneticPrimary, separator: ","}
+
+	switch algorithm, _ := rule.Parameters["algorithm"].(string); strings.ReplaceAll(strings.ToLower(algorithm), "-", "_") {
+	case "":
+	case PhoneticSoundex:
+	case PhoneticDoubleMetaphone:
+		encoding.algorithm = PhoneticDoubleMetaphone
+	default:
+		return nil, fmt.Errorf("invalid algorithm %q of phonetic rule on field '%s': expected soundex or double_metaphone", algorithm, rule.Field)
+	}
+
+	if raw, ok := rule.Parameters["max_length"]; ok {
+		if encoding.maxLength, ok = toInt(raw); !ok || encoding.maxLength < 1 {
+			return nil, fmt.Errorf("invalid max_length %v of phonetic rule on field '%s': expected a positive integer", raw, rule.Field)
+		}
+	}
+
+	switch code, _ := rule.Parameters["code"].(string); code {
+	case "":
+	case PhoneticPrimary, PhoneticAlternate, PhoneticBoth:
+		if encoding.algorithm != PhoneticDoubleMetaphone {
+			return nil, fmt.Errorf("code of phonetic rule on field '%s' needs the double_metaphone algorithm", rule.Field)
+		}
+		encoding.code = code
+	default:
+		return nil, fmt.Errorf("invalid code %q of phonetic rule on field '%s': expected primary, alternate or both", code, rule.Field)
+	}
+	if separator, ok := rule.Parameters["separator"].(string); ok {
+		encoding.separator = separator
+	}
+	return encoding, nil
+}
+
+// encode returns the phonetic code of a value.
+func (e *phoneticEncoding) encode(value string) string {
+	letters := phoneticLetters(value)
+	if letters == "" {
+		return ""
+	}
+	if e.algorithm == PhoneticSoundex {
+		return soundex(letters, e.maxLength)
+	}
+
+	primary, alternate := doubleMetaphone(letters, e.maxLength)
+	switch e.code {
+	case PhoneticAlternate:
+		return alternate
+	case PhoneticBoth:
+		return primary + e.separator + alternate
+	default:
+		return primary
+	}
+}
+
+// phoneticLetters writes a value in uppercase ASCII letters, words being separated by single spaces.
+func phoneticLetters(value string) string {
+	ascii := (&diacriticsRemoval{dropUnmapped: true}).remove(value)
+	return strings.Join(strings.FieldsFunc(strings.ToUpper(ascii), func(r rune) bool {
+		return r < 'A' || r > 'Z'
+	}), " ")
+}
+
+// soundexCodes are the Soundex digits of consonants, vowels being '0' and H and W having none.
+var soundexCodes = [26]byte{
+	'0', '1', '2', '3', '0', '1', '2', 0, '0', '2', '2', '4', '5',
+	'5', '0', '1', '2', '6', '2', '3', '0', '1', 0, '2', '0', '2',
+}
+
+// soundex returns the American Soundex code of uppercase letters: the first letter followed by
+// the digits of the consonants after it, consonants with the same digit counting once unless a
+// vowel separates them, padded with zeros.
+func soundex(letters string, length int) string {
+	letters = strings.ReplaceAll(letters, " ", "")
+	code := make([]byte, 0, length)
+	code = append(code, letters[0])
+	last := soundexCodes[letters[0]-'A']
+	for i := 1; i < len(letters) && len(code) < length; i++ {
+		digit := soundexCodes[letters[i]-'A']
+		switch digit {
+		case 0:
+			// H and W do not separate consonants
+			continue
+		case '0':
+		default:
+			if digit != last {
+				code = append(code, digit)
+			}
+		}
+		last = digit
+	}
+	for len(code) < length {
+		code = append(code, '0')
+	}
+	return string(code)
+}
@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoundex(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for name, want := range map[string]string{
+		"Robert":     "R163",
+		"Rupert":     "R163",
+		"Rubin":      "R150",
+		"Ashcraft":   "A261",
+		"Ashcroft":   "A261",
+		"Tymczak":    "T522",
+		"Pfister":    "P236",
+		"Honeyman":   "H555",
+		"Lee":        "L000",
+		"Gutierrez":  "G362",
+		"Jackson":    "J250",
+		"Washington": "W252",
+	} {
+		is.Equal(want, soundex(phoneticLetters(name), 4), name)
+	}
+}
+
+func TestDoubleMetaphone(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for name, want := range map[string][2]string{
+		"Smith":     {"SM0", "XMT"},
+		"Schmidt":   {"XMT", "SMT"},
+		"Thomas":    {"TMS", "TMS"},
+		"Jose":      {"HS", "HS"},
+		"Arnow":     {"ARN", "ARNF"},
+		"Caesar":    {"SSR", "SSR"},
+		"Richard":   {"RXRT", "RKRT"},
+		"Katherine": {"K0RN", "KTRN"},
+		"Aubrey":    {"APR", "APR"},
+		"Dumb":      {"TM", "TM"},
+		"Angier":    {"ANJ", "ANJR"},
+		"Bachelor":  {"PXLR", "PKLR"},
+		"Ashby":     {"AXP", "AXP"},
+		"Knight":    {"NT", "NT"},
+		"Laugh":     {"LF", "LF"},
+		"Michael":   {"MKL", "MXL"},
+		"Czerny":    {"SRN", "XRN"},
+		"Sugar":     {"XKR", "SKR"},
+		"Campbell":  {"KMPL", "KMPL"},
+		"Xavier":    {"SF", "SFR"},
+	} {
+		primary, alternate := doubleMetaphone(phoneticLetters(name), 4)
+		is.Equal(want, [2]string{primary, alternate}, name)
+	}
+}
+
+func TestTransformService_phonetic(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "soundex default", value: "Robert", want: "R163"},
+		{name: "soundex lowercase", value: "ashcroft", params: map[string]interface{}{"algorithm": "soundex"}, want: "A261"},
+		{name: "soundex max length", value: "Washington", params: map[string]interface{}{"max_length": 6}, want: "W25235"},
+		{name: "transliterated", value: "Müller", want: "M460"},
+		{name: "punctuation ignored", value: " O'Brien-Smith ", want: "O165"},
+		{name: "double metaphone", value: "Schmidt", params: map[string]interface{}{"algorithm": "double-metaphone"}, want: "XMT"},
+		{name: "double metaphone alternate", value: "Smith", params: map[string]interface{}{"algorithm": "double_metaphone", "code": "alternate"}, want: "XMT"},
+		{name: "double metaphone both", value: "Richard", params: map[string]interface{}{"algorithm": "double_metaphone", "code": "both", "separator": "|"}, want: "RXRT|RKRT"},
+		{name: "double metaphone transliterated", value: "Zoë", params: map[string]interface{}{"algorithm": "double_metaphone"}, want: "S"},
+		{name: "empty", value: "", want: ""},
+		{name: "punctuation only", value: "--!?", params: map[string]interface{}{"algorithm": "double_metaphone"}, want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(
+				[]DataRow{{Fields: map[string]string{"name": tc.value}}},
+				map[string]interface{}{"rules": []TransformRule{{Field: "name", Operation: Phonetic, TargetField: "name_code", Parameters: tc.params}}},
+			)
+			is.NoError(err)
+			is.Equal(tc.value, output[0].Fields["name"])
+			is.Equal(tc.want, output[0].Fields["name_code"])
+		})
+	}
+}
+
+func TestTransformService_phoneticErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for _, params := range []map[string]interface{}{
+		{"algorithm": "nysiis"},
+		{"max_length": 0},
+		{"max_length": "four"},
+		{"code": "alternate"},
+		{"algorithm": "double_metaphone", "code": "secondary"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{{Field: "name", Operation: Phonetic, Parameters: params}}})
+		is.ErrorContains(err, "phonetic rule on field 'name'", params)
+	}
+}
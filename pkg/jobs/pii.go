@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"crypto/hmac"
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// hashAlgorithms are the algorithms of "hash" rules.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+	"md5":    md5.New,
+}
+
+// hashFormat pseudonymizes values for "hash" rules. Its parameters are "algorithm", sha256 by
+// default, sha1 or md5, a "salt" prepended to values or a "key" of an HMAC, "encoding", hex by
+// default or base64, and "length", the number of characters kept. Salts and keys are better read
+// from environment variables named by "salt_env" and "key_env", keeping them out of rule files
+// and shell history. Hashes only depend on the parameters, so that pseudonymized keys still join
+// across runs, and empty values stay empty.
+type hashFormat struct {
+	algorithm func() hash.Hash
+	salt      []byte
+	key       []byte // HMAC key, hashing without one when nil
+	base64    bool
+	length    int // 0 keeps the whole hash
+}
+
+// parseHashFormat parses the parameters of a "hash" rule.
+func parseHashFormat(params map[string]interface{}, field string) (*hashFormat, error) {
+	format := &hashFormat{algorithm: sha256.New}
+	if name, ok := params["algorithm"].(string); ok && name != "" {
+		if format.algorithm = hashAlgorithms[strings.ToLower(name)]; format.algorithm == nil {
+			return nil, fmt.Errorf("unknown algorithm %q of hash rule on field '%s': expected sha256, sha1 or md5", name, field)
+		}
+	}
+
+	var err error
+	if format.salt, err = hashSecret(params, "salt", field); err != nil {
+		return nil, err
+	}
+	if format.key, err = hashSecret(params, "key", field); err != nil {
+		return nil, err
+	}
+	if format.salt != nil && format.key != nil {
+		return nil, fmt.Errorf("hash rule on field '%s' has both a salt and a key", field)
+	}
+
+	switch encoding, _ := params["encoding"].(string); encoding {
+	case "", "hex":
+	case "base64":
+		format.base64 = true
+	default:
+		return nil, fmt.Errorf("unknown encoding %q of hash rule on field '%s': expected hex or base64", encoding, field)
+	}
+
+	if length, ok := params["length"]; ok {
+		if format.length, ok = toInt(length); !ok || format.length < 1 {
+			return nil, fmt.Errorf("invalid length %v of hash rule on field '%s': expected a positive integer", length, field)
+		}
+	}
+	return format, nil
+}
+
+// hashSecret returns the salt or key of a hash rule, inline or read from the environment
+// variable of its "_env" parameter, nil when there is none.
+func hashSecret(params map[string]interface{}, name, field string) ([]byte, error) {
+	inline, hasInline := params[name].(string)
+	variable, hasVariable := params[name+"_env"].(string)
+	switch {
+	case hasInline && hasVariable:
+		return nil, fmt.Errorf("hash rule on field '%s' has both %s and %s_env", field, name, name)
+	case hasVariable:
+		value, ok := os.LookupEnv(variable)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("environment variable %s of the %s of hash rule on field '%s' is not set", variable, name, field)
+		}
+		return []byte(value), nil
+	case hasInline && inline != "":
+		return []byte(inline), nil
+	default:
+		return nil, nil
+	}
+}
+
+// hash returns the encoded hash of a value.
+func (f *hashFormat) hash(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	var h hash.Hash
+	if f.key != nil {
+		h = hmac.New(f.algorithm, f.key)
+	} else {
+		h = f.algorithm()
+		h.Write(f.salt)
+	}
+	h.Write([]byte(value))
+
+	sum := h.Sum(nil)
+	encoded := hex.EncodeToString(sum)
+	if f.base64 {
+		encoded = base64.RawURLEncoding.EncodeToString(sum)
+	}
+	if f.length > 0 && f.length < len(encoded) {
+		encoded = encoded[:f.length]
+	}
+	return encoded
+}
+
+// maskFormat hides values for "mask" rules. Its parameters are "keep_first" and "keep_last",
+// the numbers of characters left visible at each end, 0 by default, and "mask_char", * by
+// default. Values too short to hide anything once the kept characters are left out are
+// masked whole, rather than shown.
+type maskFormat struct {
+	keepFirst int
+	keepLast  int
+	char      string
+}
+
+// parseMaskFormat parses the parameters of a "mask" rule.
+func parseMaskFormat(params map[string]interface{}, field string) (*maskFormat, error) {
+	format := &maskFormat{char: "*"}
+	for name, count := range map[string]*int{"keep_first": &format.keepFirst, "keep_last": &format.keepLast} {
+		value, ok := params[name]
+		if !ok {
+			continue
+		}
+		if *count, ok = toInt(value); !ok || *count < 0 {
+			return nil, fmt.Errorf("invalid %s %v of mask rule on field '%s': expected a non-negative integer", name, value, field)
+		}
+	}
+
+	if char, ok := params["mask_char"].(string); ok {
+		if utf8.RuneCountInString(char) != 1 {
+			return nil, fmt.Errorf("invalid mask_char %q of mask rule on field '%s': expected one character", char, field)
+		}
+		format.char = char
+	}
+	return format, nil
+}
+
+// mask replaces the characters of a value with the mask character, but the kept ones.
+func (f *maskFormat) mask(value string) string {
+	runes := []rune(value)
+	if f.keepFirst+f.keepLast >= len(runes) {
+		return strings.Repeat(f.char, len(runes))
+	}
+	return string(runes[:f.keepFirst]) +
+		strings.Repeat(f.char, len(runes)-f.keepFirst-f.keepLast) +
+		string(runes[len(runes)-f.keepLast:])
+}
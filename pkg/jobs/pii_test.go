@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_hash(t *testing.T) {
+	t.Setenv("TEST_HASH_KEY", "secret")
+	t.Setenv("TEST_HASH_SALT", "pepper")
+
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "sha256", want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{name: "sha1", params: map[string]interface{}{"algorithm": "sha1"}, want: "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{name: "md5", params: map[string]interface{}{"algorithm": "MD5"}, want: "900150983cd24fb0d6963f7d28e17f72"},
+		{name: "hmac key from environment", params: map[string]interface{}{"key_env": "TEST_HASH_KEY"}, want: "9946dad4e00e913fc8be8e5d3f7e110a4a9e832f83fb09c345285d78638d8a0e"},
+		{name: "inline hmac key", params: map[string]interface{}{"key": "secret"}, want: "9946dad4e00e913fc8be8e5d3f7e110a4a9e832f83fb09c345285d78638d8a0e"},
+		{name: "truncated salted hash", params: map[string]interface{}{"salt_env": "TEST_HASH_SALT", "length": float64(12)}, want: "fadf7b97406e"},
+		{name: "base64", params: map[string]interface{}{"encoding": "base64"}, want: "ungWv48Bz-pBQUDeXa4iI7ADYaOWF3qctBD_YfIAFa0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := assert.New(t)
+
+			input := []DataRow{
+				{Fields: map[string]string{"email": "abc"}, Columns: []string{"email"}},
+				{Fields: map[string]string{"email": ""}, Columns: []string{"email"}},
+				{Fields: map[string]string{"email": "abc"}, Columns: []string{"email"}},
+			}
+			options := map[string]interface{}{
+				"rules": []TransformRule{{Field: "email", Operation: Hash, Parameters: tc.params}},
+			}
+
+			// runs are independent, so that pseudonymized keys join across them
+			for range 2 {
+				output, err := newTestTransformService(t).ProcessData(input, options)
+				is.NoError(err)
+				is.Equal(tc.want, output[0].Fields["email"])
+				is.Empty(output[1].Fields["email"])
+				is.Equal(tc.want, output[2].Fields["email"])
+			}
+		})
+	}
+}
+
+func TestTransformService_invalidHashFailsFast(t *testing.T) {
+	t.Setenv("TEST_HASH_EMPTY", "")
+
+	for _, params := range []map[string]interface{}{
+		{"algorithm": "crc32"},
+		{"encoding": "base32"},
+		{"length": 0},
+		{"key_env": "TEST_HASH_UNSET_VARIABLE"},
+		{"key_env": "TEST_HASH_EMPTY"},
+		{"key": "a", "key_env": "TEST_HASH_KEY"},
+		{"key": "a", "salt": "b"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "email", Operation: Hash, Parameters: params}},
+		})
+		assert.ErrorContains(t, err, "hash rule on field 'email'", params)
+	}
+}
+
+func TestTransformService_mask(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "card number", value: "4111111111111111", params: map[string]interface{}{"keep_last": float64(4)}, want: "************1111"},
+		{name: "both ends", value: "ann@corp.com", params: map[string]interface{}{"keep_first": 1, "keep_last": 4, "mask_char": "#"}, want: "a#######.com"},
+		{name: "unicode", value: "Zoë Ørsted", params: map[string]interface{}{"keep_first": 2, "mask_char": "•"}, want: "Zo••••••••"},
+		{name: "too short to keep characters", value: "1234", params: map[string]interface{}{"keep_last": 4}, want: "****"},
+		{name: "empty", value: "", params: map[string]interface{}{"keep_last": 4}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := []DataRow{{Fields: map[string]string{"value": tc.value}, Columns: []string{"value"}}}
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: Mask, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"keep_last": -1},
+		{"keep_first": 1.5},
+		{"mask_char": "**"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "value", Operation: Mask, Parameters: params}},
+		})
+		assert.ErrorContains(t, err, "mask rule on field 'value'", params)
+	}
+}
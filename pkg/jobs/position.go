@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RowWindow selects rows by their position in the input, before any rule is evaluated.
+// Positions are 1-based and count data rows only, the header is not a position.
+type RowWindow struct {
+	Offset   int   `json:"offset,omitempty"`    // rows to skip once inside the range
+	Limit    int   `json:"limit,omitempty"`     // maximum rows to select, 0 means no limit
+	RowRange []int `json:"row_range,omitempty"` // optional [start, end], inclusive
+}
+
+// Validate checks that the window is well formed.
+func (w RowWindow) Validate() error {
+	if w.Offset < 0 {
+		return fmt.Errorf("offset must not be negative, got %d", w.Offset)
+	}
+	if w.Limit < 0 {
+		return fmt.Errorf("limit must not be negative, got %d", w.Limit)
+	}
+	if w.RowRange == nil {
+		return nil
+	}
+	if len(w.RowRange) != 2 {
+		return errors.New("row_range must have exactly two values [start, end]")
+	}
+	if w.RowRange[0] < 1 || w.RowRange[1] < w.RowRange[0] {
+		return fmt.Errorf("invalid row_range [%d, %d], expected 1 <= start <= end", w.RowRange[0], w.RowRange[1])
+	}
+	return nil
+}
+
+// IsZero reports whether the window selects every row.
+func (w RowWindow) IsZero() bool {
+	return w.Offset == 0 && w.Limit == 0 && w.RowRange == nil
+}
+
+// rowSelector applies a RowWindow to rows in input order.
+type rowSelector struct {
+	window   RowWindow
+	position int
+	skipped  int
+	selected int
+}
+
+// next reports whether the next row is selected, and whether no later row can be selected.
+func (s *rowSelector) next() (selected, done bool) {
+	s.position++
+	window := s.window
+
+	if window.RowRange != nil && s.position < window.RowRange[0] {
+		s.skipped++
+		return false, false
+	}
+	if s.exhausted(s.position - 1) {
+		s.skipped++
+		return false, true
+	}
+	if s.position-s.rangeStart() < window.Offset {
+		s.skipped++
+		return false, false
+	}
+
+	s.selected++
+	return true, s.exhausted(s.position)
+}
+
+// exhausted reports whether rows after the given position are all outside the window.
+func (s *rowSelector) exhausted(position int) bool {
+	if s.window.Limit > 0 && s.selected >= s.window.Limit {
+		return true
+	}
+	return s.window.RowRange != nil && position >= s.window.RowRange[1]
+}
+
+// rangeStart returns the position of the first row inside the range.
+func (s *rowSelector) rangeStart() int {
+	if s.window.RowRange != nil {
+		return s.window.RowRange[0]
+	}
+	return 1
+}
+
+// parseRowWindow parses offset, limit and row_range options from map.
+func parseRowWindow(options map[string]interface{}) (RowWindow, error) {
+	var window RowWindow
+
+	if offset, ok := toInt(options["offset"]); ok {
+		window.Offset = offset
+	}
+	if limit, ok := toInt(options["limit"]); ok {
+		window.Limit = limit
+	}
+
+	switch rowRange := options["row_range"].(type) {
+	case []int:
+		window.RowRange = rowRange
+	case []interface{}:
+		for _, value := range rowRange {
+			position, ok := toInt(value)
+			if !ok {
+				return window, fmt.Errorf("row_range values must be integers, got %v", value)
+			}
+			window.RowRange = append(window.RowRange, position)
+		}
+	}
+
+	return window, window.Validate()
+}
+
+// toInt converts an integer option, which may have been decoded from JSON as a float64.
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), v == float64(int(v))
+	default:
+		return 0, false
+	}
+}
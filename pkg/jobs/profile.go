@@ -0,0 +1,95 @@
+package jobs
+
+// maxProfileDistinct is the number of distinct values a field profile counts exactly,
+// bounding the memory of high-cardinality fields like identifiers.
+const maxProfileDistinct = 10000
+
+// profileSampleSize is the number of distinct values a field profile keeps as a sample.
+const profileSampleSize = 5
+
+// FieldProfile describes the values of a field across the validated rows.
+type FieldProfile struct {
+	Field          string   `json:"field"`
+	NonEmpty       int      `json:"non_empty"`
+	Empty          int      `json:"empty"`                     // empty values, rows without the field included
+	Completeness   float64  `json:"completeness"`              // percentage of non-empty values
+	Distinct       int      `json:"distinct"`                  // distinct non-empty values, up to maxProfileDistinct
+	DistinctCapped bool     `json:"distinct_capped,omitempty"` // whether Distinct stopped at maxProfileDistinct
+	MinLength      int      `json:"min_length"`                // of non-empty values, in bytes as length rules count them
+	MaxLength      int      `json:"max_length"`
+	Sample         []string `json:"sample,omitempty"` // first distinct non-empty values
+}
+
+// fieldProfiler builds the profiles of the fields of rows as they are validated.
+type fieldProfiler struct {
+	rows     int
+	profiles []FieldProfile
+	index    map[string]int        // index of each field in profiles
+	distinct []map[string]struct{} // distinct values of each field, up to maxProfileDistinct
+}
+
+// newFieldProfiler returns a profiler listing the given columns first, in order.
+func newFieldProfiler(columns []string) *fieldProfiler {
+	p := &fieldProfiler{index: make(map[string]int)}
+	for _, column := range columns {
+		p.profile(column)
+	}
+	return p
+}
+
+// profile returns the profile of a field, added after the others when new.
+func (p *fieldProfiler) profile(field string) *FieldProfile {
+	i, ok := p.index[field]
+	if !ok {
+		i = len(p.profiles)
+		p.index[field] = i
+		p.profiles = append(p.profiles, FieldProfile{Field: field})
+		p.distinct = append(p.distinct, make(map[string]struct{}))
+	}
+	return &p.profiles[i]
+}
+
+// add profiles the values of a row.
+func (p *fieldProfiler) add(row DataRow) {
+	p.rows++
+	for field, value := range row.Fields {
+		profile := p.profile(field)
+		if value == "" {
+			continue
+		}
+
+		if profile.NonEmpty == 0 || len(value) < profile.MinLength {
+			profile.MinLength = len(value)
+		}
+		if len(value) > profile.MaxLength {
+			profile.MaxLength = len(value)
+		}
+		profile.NonEmpty++
+
+		distinct := p.distinct[p.index[field]]
+		if _, seen := distinct[value]; seen {
+			continue
+		}
+		if len(distinct) >= maxProfileDistinct {
+			profile.DistinctCapped = true
+			continue
+		}
+		distinct[value] = struct{}{}
+		if len(profile.Sample) < profileSampleSize {
+			profile.Sample = append(profile.Sample, value)
+		}
+	}
+}
+
+// result returns the profiles of the fields, in order.
+func (p *fieldProfiler) result() []FieldProfile {
+	for i := range p.profiles {
+		profile := &p.profiles[i]
+		profile.Empty = p.rows - profile.NonEmpty
+		profile.Distinct = len(p.distinct[i])
+		if p.rows > 0 {
+			profile.Completeness = float64(profile.NonEmpty) / float64(p.rows) * 100
+		}
+	}
+	return p.profiles
+}
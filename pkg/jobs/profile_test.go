@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldProfiler(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	rows := testRows(t, []string{"name", "city"},
+		[]string{"alice", "Paris"},
+		[]string{"", "Lyon"},
+		[]string{"bob", "Paris"},
+		[]string{"carol", ""},
+	)
+	rows = append(rows, DataRow{Fields: map[string]string{"name": "dave", "note": "new"}})
+
+	profiler := newFieldProfiler([]string{"name", "city"})
+	for _, row := range rows {
+		profiler.add(row)
+	}
+	profiles := profiler.result()
+
+	is.Equal([]FieldProfile{
+		{Field: "name", NonEmpty: 4, Empty: 1, Completeness: 80, Distinct: 4, MinLength: 3, MaxLength: 5, Sample: []string{"alice", "bob", "carol", "dave"}},
+		{Field: "city", NonEmpty: 3, Empty: 2, Completeness: 60, Distinct: 2, MinLength: 4, MaxLength: 5, Sample: []string{"Paris", "Lyon"}},
+		{Field: "note", NonEmpty: 1, Empty: 4, Completeness: 20, Distinct: 1, MinLength: 3, MaxLength: 3, Sample: []string{"new"}},
+	}, profiles)
+}
+
+func TestFieldProfiler_distinctCap(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	profiler := newFieldProfiler([]string{"id"})
+	for i := 0; i < maxProfileDistinct+10; i++ {
+		profiler.add(DataRow{Fields: map[string]string{"id": strconv.Itoa(i)}})
+	}
+	profile := profiler.result()[0]
+	is.Equal(maxProfileDistinct, profile.Distinct)
+	is.True(profile.DistinctCapped)
+	is.Equal(maxProfileDistinct+10, profile.NonEmpty)
+	is.Len(profile.Sample, profileSampleSize)
+}
+
+func TestValidateService_profile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"id", "email"},
+		[]string{"1", "a@example.com"},
+		[]string{"2", ""},
+	)
+
+	// profiles cover fields without rules, and only with include_profile
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "id", Type: "required"}},
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.Nil(result.Profile)
+
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules":           []ValidationRule{{Field: "id", Type: "required"}},
+		"include_profile": true,
+	})
+	is.NoError(err)
+	result, _, _ = s.validateData(rows, opts)
+	is.Len(result.Profile, 2)
+	is.Equal("email", result.Profile[1].Field)
+	is.Equal(50.0, result.Profile[1].Completeness)
+}
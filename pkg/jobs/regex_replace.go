@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// regexRewrite rewrites values for "regex_replace" rules. Its parameters are "pattern",
+// "replacement", with $1 or ${1} for numbered groups and $name or ${name} for named ones, $$
+// standing for a dollar, "replace_all", true by default, and the "case_insensitive" and "multiline"
+// flags, also writable as (?i) and (?m) in the pattern. Like "(\d{3})\D+(\d{3})\D+(\d{4})" with
+// "$1-$2-$3" rewriting "(123) 456-7890" to "123-456-7890".
+type regexRewrite struct {
+	regex       *regexp.Regexp
+	replacement string
+	all         bool
+}
+
+// parseRegexRewrite parses the parameters of a "regex_replace" rule and compiles its pattern,
+// failing when the replacement refers to groups the pattern does not have.
+func parseRegexRewrite(rule TransformRule) (*regexRewrite, error) {
+	pattern, ok := rule.Parameters["pattern"].(string)
+	if !ok || pattern == "" {
+		return nil, fmt.Errorf("regex_replace rule on field '%s' needs a pattern", rule.Field)
+	}
+	rewrite := &regexRewrite{all: true}
+	if rewrite.replacement, ok = rule.Parameters["replacement"].(string); !ok {
+		return nil, fmt.Errorf("regex_replace rule on field '%s' needs a replacement", rule.Field)
+	}
+
+	var caseInsensitive, multiline bool
+	for name, flag := range map[string]*bool{"replace_all": &rewrite.all, "case_insensitive": &caseInsensitive, "multiline": &multiline} {
+		value, ok := rule.Parameters[name]
+		if !ok {
+			continue
+		}
+		if *flag, ok = value.(bool); !ok {
+			return nil, fmt.Errorf("invalid %s of regex_replace rule on field '%s': expected true or false", name, rule.Field)
+		}
+	}
+	switch {
+	case caseInsensitive && multiline:
+		pattern = "(?im)" + pattern
+	case caseInsensitive:
+		pattern = "(?i)" + pattern
+	case multiline:
+		pattern = "(?m)" + pattern
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern %q on field '%s': %w", pattern, rule.Field, err)
+	}
+	rewrite.regex = regex
+
+	if err := checkReplacementGroups(regex, rewrite.replacement, rule.Field); err != nil {
+		return nil, err
+	}
+	return rewrite, nil
+}
+
+// checkReplacementGroups fails when a replacement refers to groups a pattern does not have.
+func checkReplacementGroups(regex *regexp.Regexp, replacement, field string) error {
+	for _, group := range replacementGroups(replacement) {
+		if number, err := strconv.Atoi(group); err == nil {
+			if number > regex.NumSubexp() {
+				return fmt.Errorf("replacement of regex_replace rule on field '%s' refers to group %d, the pattern has %d", field, number, regex.NumSubexp())
+			}
+		} else if regex.SubexpIndex(group) < 0 {
+			return fmt.Errorf("replacement of regex_replace rule on field '%s' refers to group %q, the pattern has no such named group", field, group)
+		}
+	}
+	return nil
+}
+
+// replacementGroups returns the groups a replacement refers to, as regexp.Expand reads them:
+// the longest run of letters, digits and underscores after $, or the name within ${}.
+func replacementGroups(replacement string) []string {
+	var groups []string
+	for i := 0; i < len(replacement); i++ {
+		if replacement[i] != '$' || i+1 == len(replacement) {
+			continue
+		}
+		rest := replacement[i+1:]
+		switch {
+		case rest[0] == '$':
+			i++
+		case rest[0] == '{':
+			if end := strings.IndexByte(rest, '}'); end > 1 {
+				groups = append(groups, rest[1:end])
+				i += end + 1
+			}
+		default:
+			end := 0
+			for end < len(rest) && (rest[end] == '_' || isASCIIAlnum(rest[end])) {
+				end++
+			}
+			if end > 0 {
+				groups = append(groups, rest[:end])
+				i += end
+			}
+		}
+	}
+	return groups
+}
+
+// isASCIIAlnum reports whether a byte is an ASCII letter or digit.
+func isASCIIAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// rewrite replaces the matches of the pattern in a value, or the first one only.
+func (r *regexRewrite) rewrite(value string) string {
+	if r.all {
+		return r.regex.ReplaceAllString(value, r.replacement)
+	}
+	match := r.regex.FindStringSubmatchIndex(value)
+	if match == nil {
+		return value
+	}
+	replaced := r.regex.ExpandString(nil, r.replacement, value, match)
+	return value[:match[0]] + string(replaced) + value[match[1]:]
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_regexReplace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "numbered groups", value: "(123) 456-7890", params: map[string]interface{}{"pattern": `\((\d{3})\)\s*(\d{3})-(\d{4})`, "replacement": "$1-$2-$3"}, want: "123-456-7890"},
+		{name: "named groups", value: "2024-01-15", params: map[string]interface{}{"pattern": `(?P<y>\d+)-(?P<m>\d+)-(?P<d>\d+)`, "replacement": "${d}/${m}/$y"}, want: "15/01/2024"},
+		{name: "braces before text", value: "a1 b2", params: map[string]interface{}{"pattern": `([a-z])(\d)`, "replacement": "${2}x$$"}, want: "1x$ 2x$"},
+		{name: "first only", value: "a-b-c", params: map[string]interface{}{"pattern": "-", "replacement": "+", "replace_all": false}, want: "a+b-c"},
+		{name: "first only without match", value: "abc", params: map[string]interface{}{"pattern": "-", "replacement": "+", "replace_all": false}, want: "abc"},
+		{name: "case insensitive", value: "Foo foo FOO", params: map[string]interface{}{"pattern": "foo", "replacement": "bar", "case_insensitive": true}, want: "bar bar bar"},
+		{name: "multiline", value: "one\ntwo", params: map[string]interface{}{"pattern": "^", "replacement": "> ", "multiline": true}, want: "> one\n> two"},
+		{name: "inline flags", value: "one\nTWO", params: map[string]interface{}{"pattern": "(?im)^two$", "replacement": "2"}, want: "one\n2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: RegexReplace, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_invalidRegexReplaceFailsFast(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		params  map[string]interface{}
+		message string
+	}{
+		{params: map[string]interface{}{"replacement": "x"}, message: "needs a pattern"},
+		{params: map[string]interface{}{"pattern": "a"}, message: "needs a replacement"},
+		{params: map[string]interface{}{"pattern": "[a", "replacement": "x"}, message: `invalid regex pattern "[a"`},
+		{params: map[string]interface{}{"pattern": "(a)", "replacement": "$2"}, message: "refers to group 2, the pattern has 1"},
+		{params: map[string]interface{}{"pattern": "(a)", "replacement": "$1x"}, message: `refers to group "1x"`},
+		{params: map[string]interface{}{"pattern": "(?P<y>a)", "replacement": "${year}"}, message: `refers to group "year"`},
+		{params: map[string]interface{}{"pattern": "a", "replacement": "b", "multiline": 1}, message: "invalid multiline"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "phone", Operation: RegexReplace, Parameters: tc.params}},
+		})
+		assert.ErrorContains(t, err, tc.message, tc.params)
+		assert.ErrorContains(t, err, "on field 'phone'", tc.params)
+	}
+}
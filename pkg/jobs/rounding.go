@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Rounding modes of numeric transform outputs.
+const (
+	RoundHalfUp   = "half_up"   // halves away from zero, like 2.5 to 3 and -2.5 to -3
+	RoundHalfEven = "half_even" // halves to the even neighbour, like 2.5 to 2 and 3.5 to 4
+	RoundFloor    = "floor"     // towards negative infinity
+	RoundCeil     = "ceil"      // towards positive infinity
+)
+
+// numberOutput formats the numbers of numeric transform rules. Its parameters are "precision",
+// the number of decimals, -1 writing as many as the number needs, and "rounding", one of the
+// rounding modes, half_even by default. Numbers are rounded as the decimals they are written as,
+// so 2.675 rounds to 2.68 even though its float64 is slightly below it.
+type numberOutput struct {
+	precision int
+	rounding  string
+}
+
+// parseNumberOutput parses the precision and rounding parameters of a rule, the precision
+// defaulting to the given one.
+func parseNumberOutput(rule TransformRule, precision int) (numberOutput, error) {
+	output := numberOutput{precision: precision, rounding: RoundHalfEven}
+	if raw, ok := rule.Parameters["precision"]; ok {
+		if output.precision, ok = toInt(raw); !ok || output.precision < -1 {
+			return output, fmt.Errorf("invalid precision %v of %s rule on field '%s': expected a number of decimals, or -1", raw, rule.Operation, rule.Field)
+		}
+	}
+
+	switch rounding, _ := rule.Parameters["rounding"].(string); strings.ReplaceAll(rounding, "-", "_") {
+	case "":
+	case RoundHalfUp, RoundHalfEven, RoundFloor, RoundCeil:
+		output.rounding = strings.ReplaceAll(rounding, "-", "_")
+	default:
+		return output, fmt.Errorf("invalid rounding %q of %s rule on field '%s': expected half_up, half_even, floor or ceil", rounding, rule.Operation, rule.Field)
+	}
+	return output, nil
+}
+
+// formatFloat formats a computed number, as the shortest decimal reading back as it.
+func (o numberOutput) formatFloat(value float64) string {
+	text := strconv.FormatFloat(value, 'f', -1, 64)
+	number, _ := parseDecimal(text, false)
+	return o.format(number)
+}
+
+// format formats a decimal number.
+func (o numberOutput) format(number decimalNumber) string {
+	if o.precision < 0 {
+		text := number.value.FloatString(number.fractionDigits)
+		if strings.Contains(text, ".") {
+			text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+		}
+		return text
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(o.precision)), nil)
+	scaled := new(big.Rat).Mul(number.value, new(big.Rat).SetInt(scale))
+
+	// floor and remainder of the scaled number, the denominator being positive
+	quotient, remainder := new(big.Int).DivMod(scaled.Num(), scaled.Denom(), new(big.Int))
+	if remainder.Sign() != 0 {
+		// twice the remainder against the denominator tells halves apart
+		half := new(big.Int).Lsh(remainder, 1).Cmp(scaled.Denom())
+		up := false
+		switch o.rounding {
+		case RoundCeil:
+			up = true
+		case RoundHalfUp:
+			up = half > 0 || half == 0 && scaled.Sign() > 0
+		case RoundHalfEven:
+			up = half > 0 || half == 0 && quotient.Bit(0) == 1
+		}
+		if up {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return new(big.Rat).SetFrac(quotient, scale).FloatString(o.precision)
+}
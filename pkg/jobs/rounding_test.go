@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberOutput_format(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value     string
+		precision int
+		rounding  string
+		want      string
+	}{
+		// banker's rounding takes halves to the even neighbour
+		{value: "2.5", rounding: RoundHalfEven, want: "2"},
+		{value: "3.5", rounding: RoundHalfEven, want: "4"},
+		{value: "-2.5", rounding: RoundHalfEven, want: "-2"},
+		{value: "0.125", precision: 2, rounding: RoundHalfEven, want: "0.12"},
+		{value: "0.135", precision: 2, rounding: RoundHalfEven, want: "0.14"},
+		{value: "2.675", precision: 2, rounding: RoundHalfEven, want: "2.68"},
+		{value: "2.665", precision: 2, rounding: RoundHalfEven, want: "2.66"},
+		{value: "2.6651", precision: 2, rounding: RoundHalfEven, want: "2.67"},
+		{value: "2.5", rounding: RoundHalfUp, want: "3"},
+		{value: "-2.5", rounding: RoundHalfUp, want: "-3"},
+		{value: "2.665", precision: 2, rounding: RoundHalfUp, want: "2.67"},
+		{value: "-1.21", precision: 1, rounding: RoundFloor, want: "-1.3"},
+		{value: "1.29", precision: 1, rounding: RoundFloor, want: "1.2"},
+		{value: "-1.29", precision: 1, rounding: RoundCeil, want: "-1.2"},
+		{value: "1.21", precision: 1, rounding: RoundCeil, want: "1.3"},
+		{value: "42", precision: 2, rounding: RoundHalfEven, want: "42.00"},
+		{value: "12345678901234567890.5", rounding: RoundHalfUp, want: "12345678901234567891"},
+		{value: "2.50", precision: -1, want: "2.5"},
+		{value: "1.5e3", precision: -1, want: "1500"},
+	}
+
+	for _, tc := range testCases {
+		number, ok := parseDecimal(tc.value, true)
+		assert.True(t, ok, tc.value)
+		output := numberOutput{precision: tc.precision, rounding: tc.rounding}
+		assert.Equal(t, tc.want, output.format(number), "%s %d %s", tc.value, tc.precision, tc.rounding)
+	}
+}
+
+func TestTransformService_round(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"id", "rate", "amount"},
+		[]string{"42", "0.1234565", "2.345"},
+		[]string{"7", "n/a", "1e2"},
+	)
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{
+			{Field: "id", Operation: Calculate, Parameters: map[string]interface{}{"operation": "multiply", "operand": 3, "precision": -1}, TargetField: "triple"},
+			{Field: "rate", Operation: Calculate, Parameters: map[string]interface{}{"operation": "add", "operand": 0.1, "precision": 6, "rounding": "half-up"}, TargetField: "next_rate"},
+			{Field: "rate", Operation: Round, Parameters: map[string]interface{}{"precision": 6}},
+			{Field: "amount", Operation: Round, Parameters: map[string]interface{}{"precision": 2, "rounding": "ceil"}},
+		},
+	})
+	is.NoError(err)
+	is.Equal("126", output[0].Fields["triple"])
+	is.Equal("0.223457", output[0].Fields["next_rate"])
+	is.Equal("0.123456", output[0].Fields["rate"])
+	is.Equal("2.35", output[0].Fields["amount"])
+	is.Equal("21", output[1].Fields["triple"])
+	is.Equal("n/a", output[1].Fields["rate"])
+	is.Equal("100.00", output[1].Fields["amount"])
+
+	for _, rule := range []TransformRule{
+		{Field: "rate", Operation: Round, Parameters: map[string]interface{}{"precision": -2}},
+		{Field: "rate", Operation: Round, Parameters: map[string]interface{}{"rounding": "up"}},
+		{Field: "rate", Operation: Calculate, Parameters: map[string]interface{}{"operation": "add", "operand": 1, "precision": 1.5}},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		is.ErrorContains(err, string(rule.Operation)+" rule on field 'rate'", rule.Parameters)
+	}
+}
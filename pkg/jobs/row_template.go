@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the helpers of "template" rules, on top of those of text/template.
+var templateFuncs = template.FuncMap{
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"trim":   strings.TrimSpace,
+	"printf": fmt.Sprintf,
+	// default returns the value, or the default when it is empty, like {{.country | default "US"}}
+	"default": func(defaultValue, value string) string {
+		if value == "" {
+			return defaultValue
+		}
+		return value
+	},
+}
+
+// rowTemplate renders the target field of "template" rules. Its parameters are "template",
+// a text/template executed over the fields of the row, like "{{.first_name}} {{.last_name}}",
+// missing fields rendering empty and fields with other characters than letters, digits and
+// underscores being read with index, like {{index . "e-mail"}}. Rows the template fails on follow
+// the on_error policy of the rules, fail by default, keep writing the value of the target field.
+type rowTemplate struct {
+	template *template.Template
+}
+
+// parseRowTemplate parses the parameters of a "template" rule, which needs a target field.
+func parseRowTemplate(rule TransformRule) (*rowTemplate, error) {
+	if rule.TargetField == "" {
+		return nil, fmt.Errorf("template rule needs a target_field")
+	}
+
+	text, ok := rule.Parameters["template"].(string)
+	if !ok {
+		return nil, fmt.Errorf("template rule on field '%s' needs a template", rule.TargetField)
+	}
+	parsed, err := template.New(rule.TargetField).Option("missingkey=zero").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template of template rule on field '%s': %w", rule.TargetField, err)
+	}
+
+	return &rowTemplate{template: parsed}, nil
+}
+
+// render executes the template over a row, failing with an untransformableError when it fails.
+func (t *rowTemplate) render(row DataRow, targetField string) (string, error) {
+	var b strings.Builder
+	if err := t.template.Execute(&b, row.Fields); err != nil {
+		return "", untransformable("template rule on field '%s': %w", targetField, err)
+	}
+	return b.String(), nil
+}
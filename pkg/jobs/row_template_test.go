@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_template(t *testing.T) {
+	t.Parallel()
+
+	input := []DataRow{
+		{Fields: map[string]string{"first_name": "ada", "last_name": "Lovelace", "e-mail": " ada@example.com ", "country": ""}, Columns: []string{"first_name", "last_name", "e-mail", "country"}},
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "fields", template: "{{.first_name}} {{.last_name}}", want: "ada Lovelace"},
+		{name: "helpers", template: `{{upper .first_name}} <{{index . "e-mail" | trim}}> {{.country | default "US"}}`, want: "ADA <ada@example.com> US"},
+		{name: "printf", template: `{{printf "%s-%05d" (lower .last_name) 42}}`, want: "lovelace-00042"},
+		{name: "missing field", template: "{{.first_name}}{{.middle_name}}!", want: "ada!"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Operation: Template, Parameters: map[string]interface{}{"template": tc.template}, TargetField: "label"}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["label"])
+		})
+	}
+}
+
+func TestTransformService_templateErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"code", "label"}, []string{"abcdef", "old"}, []string{"ab", "old"})
+	rule := func(onError string) []TransformRule {
+		return []TransformRule{{Operation: Template, TargetField: "label", Parameters: map[string]interface{}{
+			"template": "{{index .code 3 | printf \"%c\"}}", "on_error": onError,
+		}}}
+	}
+
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{"rules": rule("keep")})
+	is.NoError(err)
+	is.Equal("d", output[0].Fields["label"])
+	is.Equal("old", output[1].Fields["label"])
+
+	output, err = newTestTransformService(t).ProcessData(input, map[string]interface{}{"rules": rule("empty")})
+	is.NoError(err)
+	is.Empty(output[1].Fields["label"])
+
+	_, err = newTestTransformService(t).ProcessData(input, map[string]interface{}{"rules": rule("")})
+	is.ErrorContains(err, "line 3: template rule on field 'label'")
+	is.ErrorContains(err, "index out of range")
+
+	for _, tr := range []TransformRule{
+		{Operation: Template, Parameters: map[string]interface{}{"template": "{{.a}}"}},
+		{Operation: Template, TargetField: "label"},
+		{Operation: Template, TargetField: "label", Parameters: map[string]interface{}{"template": "{{.a}}", "on_error": "skip"}},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{tr}})
+		is.ErrorContains(err, "template rule", tr.Parameters)
+	}
+
+	// parse errors tell where the template is wrong
+	_, err = newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{
+		{Operation: Template, TargetField: "label", Parameters: map[string]interface{}{"template": "{{.a}}\n{{shout .b}}"}},
+	}})
+	is.ErrorContains(err, `invalid template of template rule on field 'label': template: label:2: function "shout" not defined`)
+}
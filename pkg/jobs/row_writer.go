@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RowWriter writes data rows one at a time, so outputs can be produced
+// without holding every row in memory.
+type RowWriter interface {
+	// Write appends a row to the output.
+	Write(row DataRow) error
+	// Close finalizes the output and moves it into place.
+	Close() error
+	// Abort discards the output unless it has been closed successfully.
+	Abort()
+}
+
+// CreateRowWriter creates a streaming writer, picking CSV or JSON from the file extension.
+// The JSON output is identical to WriteRows. CSV headers are taken from the first row,
+// fields absent from it are not written.
+func (fs *FileService) CreateRowWriter(path string, opts OutputOptions) (RowWriter, error) {
+	fs.logger.Info().Str("filepath", path).Msg("Streaming rows to file")
+
+	file, err := fs.createOutputFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	base := rowWriterBase{file: file, buffered: bufio.NewWriter(file), includeMetadata: opts.IncludeMetadata}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return &csvRowWriter{rowWriterBase: base, opts: opts.CSV}, nil
+	}
+	return &jsonRowWriter{rowWriterBase: base}, nil
+}
+
+// rowWriterBase holds the file shared by the row writer implementations.
+type rowWriterBase struct {
+	file            *outputFile
+	buffered        *bufio.Writer
+	includeMetadata bool
+}
+
+// commit flushes buffered data and moves the file into place.
+func (w *rowWriterBase) commit() error {
+	if err := w.buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return w.file.Commit()
+}
+
+// Abort implements RowWriter.
+func (w *rowWriterBase) Abort() {
+	w.file.Abort()
+}
+
+// jsonRowWriter writes rows as an indented JSON array.
+type jsonRowWriter struct {
+	rowWriterBase
+	count int
+}
+
+// Write implements RowWriter.
+func (w *jsonRowWriter) Write(row DataRow) error {
+	var value interface{} = row
+	if w.includeMetadata {
+		value = rowWithMetadata(row)
+	}
+
+	encoded, err := json.MarshalIndent(value, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	prefix := ",\n  "
+	if w.count == 0 {
+		prefix = "[\n  "
+	}
+	w.count++
+
+	if _, err := w.buffered.WriteString(prefix); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if _, err := w.buffered.Write(encoded); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// Close implements RowWriter.
+func (w *jsonRowWriter) Close() error {
+	suffix := "\n]\n"
+	if w.count == 0 {
+		suffix = "[]\n"
+	}
+	if _, err := w.buffered.WriteString(suffix); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return w.commit()
+}
+
+// csvRowWriter writes rows as CSV records.
+type csvRowWriter struct {
+	rowWriterBase
+	opts    CSVWriteOptions
+	headers []string
+}
+
+// Write implements RowWriter.
+func (w *csvRowWriter) Write(row DataRow) error {
+	if w.includeMetadata {
+		row = withMetadataFields([]DataRow{row})[0]
+	}
+
+	if w.headers == nil {
+		w.headers = row.Keys()
+		if err := writeCSVRecords(w.buffered, [][]string{w.headers}, w.opts); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(w.headers))
+	for i, header := range w.headers {
+		record[i] = row.Fields[header]
+	}
+	return writeCSVRecords(w.buffered, [][]string{record}, w.opts)
+}
+
+// Close implements RowWriter.
+func (w *csvRowWriter) Close() error {
+	return w.commit()
+}
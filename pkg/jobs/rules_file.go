@@ -0,0 +1,216 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// RulesIncludeKey is the key of rules files that includes other rules files, relative to the including one.
+//
+// A rule list may contain {"include": "shared.yaml"} items, replaced by the rules of the included file,
+// and a rule object, like a filter group, may have an include key, a path or a list of paths, whose rules
+// come before its own "rules". Included files hold a rule list, or an object with "rules".
+const RulesIncludeKey = "include"
+
+// ReadRulesFile reads the rules of a JSON or YAML file, picked from its .yaml or .yml extension,
+// resolves its includes and returns them as JSON. YAML files may have comments.
+// Syntax errors report the file and line they are found at.
+func ReadRulesFile(path string) ([]byte, error) {
+	rules, err := readRulesDocument(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// LoadRulesFromFile decodes the rules of a JSON or YAML file into rules, like a *[]ValidationRule,
+// see ReadRulesFile.
+func LoadRulesFromFile(path string, rules interface{}) error {
+	data, err := ReadRulesFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return fmt.Errorf("%s: invalid rules: %w", path, err)
+	}
+	return nil
+}
+
+// LoadFilterRulesFromFile decodes the filter rules of a JSON or YAML file, as a flat list
+// or a group, see ReadRulesFile and ParseFilterRules.
+func LoadFilterRulesFromFile(path string) (FilterGroup, error) {
+	data, err := ReadRulesFile(path)
+	if err != nil {
+		return FilterGroup{}, err
+	}
+	group, err := ParseFilterRules(data)
+	if err != nil {
+		return group, fmt.Errorf("%s: invalid filter rules: %w", path, err)
+	}
+	return group, nil
+}
+
+// readRulesDocument decodes a rules file and resolves its includes, stack holding the files including it.
+func readRulesDocument(path string, stack []string) (interface{}, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve rules file %s: %w", path, err)
+	}
+	for i, including := range stack {
+		if including == absolute {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], absolute), " -> "))
+		}
+	}
+	stack = append(stack, absolute)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var document interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, fmt.Errorf("%s: %w", jsonErrorLocation(path, data, err), err)
+		}
+	}
+
+	return resolveIncludes(document, filepath.Dir(path), stack)
+}
+
+// resolveIncludes replaces the includes of a rules document by the rules of the included files.
+func resolveIncludes(document interface{}, dir string, stack []string) (interface{}, error) {
+	switch value := document.(type) {
+	case []interface{}:
+		rules := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			object, ok := item.(map[string]interface{})
+			if include, isInclude := object[RulesIncludeKey]; ok && isInclude && len(object) == 1 {
+				included, err := includedRules(include, dir, stack)
+				if err != nil {
+					return nil, err
+				}
+				rules = append(rules, included...)
+				continue
+			}
+			rules = append(rules, item)
+		}
+		return rules, nil
+
+	case map[string]interface{}:
+		include, ok := value[RulesIncludeKey]
+		if !ok {
+			return value, nil
+		}
+		included, err := includedRules(include, dir, stack)
+		if err != nil {
+			return nil, err
+		}
+		own, err := resolveIncludes(value["rules"], dir, stack)
+		if err != nil {
+			return nil, err
+		}
+		if own, ok := own.([]interface{}); ok {
+			included = append(included, own...)
+		} else if own != nil {
+			return nil, errors.New(`"rules" next to an include must be a list`)
+		}
+
+		resolved := make(map[string]interface{}, len(value))
+		for key, field := range value {
+			resolved[key] = field
+		}
+		delete(resolved, RulesIncludeKey)
+		resolved["rules"] = included
+		return resolved, nil
+
+	default:
+		return document, nil
+	}
+}
+
+// includedRules returns the rules of the files of an include, a path or a list of paths.
+func includedRules(include interface{}, dir string, stack []string) ([]interface{}, error) {
+	var paths []string
+	switch value := include.(type) {
+	case string:
+		paths = []string{value}
+	case []interface{}:
+		for _, path := range value {
+			name, ok := path.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid include %v: expected a path or a list of paths", include)
+			}
+			paths = append(paths, name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid include %v: expected a path or a list of paths", include)
+	}
+
+	var rules []interface{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		document, err := readRulesDocument(path, stack)
+		if err != nil {
+			return nil, err
+		}
+
+		switch included := document.(type) {
+		case []interface{}:
+			rules = append(rules, included...)
+		case map[string]interface{}:
+			list, ok := included["rules"].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: included rules must be a list or an object with a rules list", path)
+			}
+			rules = append(rules, list...)
+		default:
+			return nil, fmt.Errorf("%s: included rules must be a list or an object with a rules list", path)
+		}
+	}
+	return rules, nil
+}
+
+// jsonErrorLocation returns "path:line:column" for JSON errors with an offset, or path otherwise.
+func jsonErrorLocation(path string, data []byte, err error) string {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		offset = int64(len(data))
+	default:
+		return path
+	}
+
+	before := data[:min(int(offset), len(data))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Sprintf("%s:%d:%d", path, line, column)
+}
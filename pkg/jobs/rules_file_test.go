@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeRulesFiles writes files named after their keys in a temporary directory, returned.
+func writeRulesFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestLoadRulesFromFile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := writeRulesFiles(t, map[string]string{
+		"rules.yaml": `# rules of the people export
+- field: name
+  type: required
+- include: shared/contact.json
+- field: age
+  type: range
+  constraints: {min: 0, max: 120}
+`,
+		"shared/contact.json": `{"include": "email.yml", "rules": [{"field": "phone", "type": "required"}]}`,
+		"shared/email.yml":    `[{field: email, type: email, severity: warning}]`,
+	})
+
+	var rules []ValidationRule
+	is.NoError(LoadRulesFromFile(filepath.Join(dir, "rules.yaml"), &rules))
+	is.Equal([]ValidationRule{
+		{Field: "name", Type: "required"},
+		{Field: "email", Type: "email", Severity: "warning"},
+		{Field: "phone", Type: "required"},
+		{Field: "age", Type: "range", Constraints: map[string]interface{}{"min": float64(0), "max": float64(120)}},
+	}, rules)
+}
+
+func TestLoadFilterRulesFromFile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := writeRulesFiles(t, map[string]string{
+		"filter.yaml": `logic: or
+include: [active.yaml]
+rules:
+  - {field: role, operator: equals, value: admin}
+`,
+		"active.yaml": `- {field: status, operator: equals, value: active}`,
+	})
+
+	group, err := LoadFilterRulesFromFile(filepath.Join(dir, "filter.yaml"))
+	is.NoError(err)
+	is.Equal("or", group.Logic)
+	is.Equal([]FilterRule{
+		{Field: "status", Operator: "equals", Value: "active"},
+		{Field: "role", Operator: "equals", Value: "admin"},
+	}, group.Rules)
+}
+
+func TestReadRulesFile_errors(t *testing.T) {
+	t.Parallel()
+
+	dir := writeRulesFiles(t, map[string]string{
+		"syntax.json":    "[\n  {\"field\": \"name\",\n   \"type\": required}\n]",
+		"syntax.yaml":    "- field: name\n  type: [required\n",
+		"truncated.json": "[{\"field\": \"name\"",
+		"cycle.yaml":     "- include: loop.yaml\n",
+		"loop.yaml":      "- include: cycle.yaml\n",
+		"missing.yaml":   "- include: nowhere.yaml\n",
+		"scalar.yaml":    "- include: value.yaml\n",
+		"value.yaml":     "42\n",
+	})
+
+	testCases := map[string]string{
+		"syntax.json":    "syntax.json:3:13: invalid character 'r' looking for beginning of value",
+		"syntax.yaml":    "syntax.yaml: yaml: line 1: did not find expected ',' or ']'",
+		"truncated.json": "truncated.json:1:18: unexpected EOF",
+		"cycle.yaml":     "include cycle: " + filepath.Join(dir, "cycle.yaml") + " -> " + filepath.Join(dir, "loop.yaml") + " -> " + filepath.Join(dir, "cycle.yaml"),
+		"missing.yaml":   "failed to read rules file",
+		"scalar.yaml":    "value.yaml: included rules must be a list or an object with a rules list",
+	}
+
+	for name, expected := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ReadRulesFile(filepath.Join(dir, name))
+			assert.ErrorContains(t, err, expected)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Names of scoring profiles.
+const (
+	ScoringDefault = "default" // DefaultScoringProfile
+	ScoringCustom  = "custom"  // the scoring option
+)
+
+// ScoringProfile weighs the issues of rows into the quality score, from 0 to 100:
+//
+//	score = 100 - error_weight * Σ invalid rows * weight / rows - warning_weight * Σ warnings * weight / rows
+//
+// over the processed rows, weights being those of the rules: an invalid row counts with the weight
+// of its heaviest failing rule, and each warning with the weight of its rule. Weights are points
+// taken off when every row has the issue, so the default profile takes off 5 points per warning per row.
+type ScoringProfile struct {
+	ErrorWeight   float64 `json:"error_weight"`
+	WarningWeight float64 `json:"warning_weight"`
+
+	// RuleWeights weighs the issues of rules, by rule type like "email" or by rule type and field
+	// like "email:contact", the latter first. Rules without a weight weigh 1.
+	RuleWeights map[string]float64 `json:"rule_weights,omitempty"`
+}
+
+// DefaultScoringProfile is the valid row percentage, minus 5 points per warning per row.
+var DefaultScoringProfile = ScoringProfile{ErrorWeight: 100, WarningWeight: 5}
+
+// ScoreBreakdown explains a quality score: the score is 100 minus its penalties, down to 0.
+type ScoreBreakdown struct {
+	Profile        string             `json:"profile"`     // ScoringDefault or ScoringCustom
+	ValidRatio     float64            `json:"valid_ratio"` // valid rows over processed rows, from 0 to 1
+	ErrorPenalty   float64            `json:"error_penalty"`
+	WarningPenalty float64            `json:"warning_penalty"`
+	Rules          []RuleContribution `json:"rules,omitempty"` // largest penalties first
+}
+
+// RuleContribution is the part of the penalties of a quality score due to a rule on a field.
+type RuleContribution struct {
+	Rule    string  `json:"rule"`
+	Field   string  `json:"field"`
+	Weight  float64 `json:"weight"`
+	Penalty float64 `json:"penalty"`
+}
+
+// parseScoringProfile parses the scoring option, as an object or a ScoringProfile.
+// Weights it leaves out are those of the default profile.
+func parseScoringProfile(raw interface{}) (ScoringProfile, error) {
+	profile := DefaultScoringProfile
+	switch scoring := raw.(type) {
+	case ScoringProfile:
+		profile = scoring
+	case *ScoringProfile:
+		profile = *scoring
+	case map[string]interface{}:
+		for name, weight := range map[string]*float64{"error_weight": &profile.ErrorWeight, "warning_weight": &profile.WarningWeight} {
+			value, ok := scoring[name]
+			if !ok {
+				continue
+			}
+			if *weight, ok = toFloat(value); !ok {
+				return profile, fmt.Errorf("invalid %s %v of scoring: expected a number", name, value)
+			}
+		}
+		if ruleWeights, ok := scoring["rule_weights"]; ok {
+			weights, ok := ruleWeights.(map[string]interface{})
+			if !ok {
+				return profile, fmt.Errorf("invalid rule_weights of scoring: expected an object of weights by rule")
+			}
+			profile.RuleWeights = make(map[string]float64, len(weights))
+			for rule, value := range weights {
+				if profile.RuleWeights[rule], ok = toFloat(value); !ok {
+					return profile, fmt.Errorf("invalid weight %v of rule '%s' in scoring: expected a number", value, rule)
+				}
+			}
+		}
+	default:
+		return profile, fmt.Errorf("invalid scoring: expected an object with error_weight, warning_weight and rule_weights")
+	}
+
+	if profile.ErrorWeight < 0 || profile.WarningWeight < 0 {
+		return profile, fmt.Errorf("scoring weights must not be negative")
+	}
+	for rule, weight := range profile.RuleWeights {
+		if weight < 0 {
+			return profile, fmt.Errorf("weight of rule '%s' in scoring must not be negative, got %v", rule, weight)
+		}
+	}
+	return profile, nil
+}
+
+// weight returns the weight of the issues of a rule on a field.
+func (p ScoringProfile) weight(ruleType, field string) float64 {
+	if weight, ok := p.RuleWeights[ruleType+":"+field]; ok {
+		return weight
+	}
+	if weight, ok := p.RuleWeights[ruleType]; ok {
+		return weight
+	}
+	return 1
+}
+
+// qualityScorer adds up the weighted issues of rows as they are validated.
+type qualityScorer struct {
+	profile ScoringProfile
+	name    string
+	rules   map[[2]string]*RuleContribution // by rule type and field
+	order   []*RuleContribution
+	errors  float64 // weighted invalid rows
+	warns   float64 // weighted warnings
+}
+
+func newQualityScorer(opts *ValidateOptions) *qualityScorer {
+	scorer := &qualityScorer{profile: DefaultScoringProfile, name: ScoringDefault, rules: make(map[[2]string]*RuleContribution)}
+	if opts.Scoring != nil {
+		scorer.profile, scorer.name = *opts.Scoring, ScoringCustom
+	}
+	return scorer
+}
+
+// contribution returns the contribution of a rule on a field, added when new.
+func (sc *qualityScorer) contribution(ruleType, field string) *RuleContribution {
+	key := [2]string{ruleType, field}
+	contribution, ok := sc.rules[key]
+	if !ok {
+		contribution = &RuleContribution{Rule: ruleType, Field: field, Weight: sc.profile.weight(ruleType, field)}
+		sc.rules[key] = contribution
+		sc.order = append(sc.order, contribution)
+	}
+	return contribution
+}
+
+// addRow adds the errors and warnings of a row. Its error penalty goes to its heaviest failing
+// rule, the first one of them at equal weights.
+func (sc *qualityScorer) addRow(errors, warnings []ValidationError) {
+	var heaviest *RuleContribution
+	for _, issue := range errors {
+		contribution := sc.contribution(issue.RuleType, issue.FieldName)
+		if heaviest == nil || contribution.Weight > heaviest.Weight {
+			heaviest = contribution
+		}
+	}
+	if heaviest != nil {
+		sc.errors += heaviest.Weight
+		heaviest.Penalty += sc.profile.ErrorWeight * heaviest.Weight
+	}
+
+	for _, issue := range warnings {
+		contribution := sc.contribution(issue.RuleType, issue.FieldName)
+		sc.warns += contribution.Weight
+		contribution.Penalty += sc.profile.WarningWeight * contribution.Weight
+	}
+}
+
+// score returns the quality score of a result whose rows were added, with its breakdown.
+func (sc *qualityScorer) score(result *ValidationResult) (float64, *ScoreBreakdown) {
+	breakdown := &ScoreBreakdown{Profile: sc.name}
+	if result.RowsProcessed == 0 {
+		return 0, breakdown
+	}
+
+	rows := float64(result.RowsProcessed)
+	breakdown.ValidRatio = float64(result.ValidRows) / rows
+	breakdown.ErrorPenalty = sc.profile.ErrorWeight * sc.errors / rows
+	breakdown.WarningPenalty = sc.profile.WarningWeight * sc.warns / rows
+	for _, contribution := range sc.order {
+		if rule := *contribution; rule.Penalty > 0 {
+			rule.Penalty /= rows
+			breakdown.Rules = append(breakdown.Rules, rule)
+		}
+	}
+	sort.SliceStable(breakdown.Rules, func(i, j int) bool {
+		return breakdown.Rules[i].Penalty > breakdown.Rules[j].Penalty
+	})
+
+	score := 100 - breakdown.ErrorPenalty - breakdown.WarningPenalty
+	return math.Min(math.Max(score, 0), 100), breakdown
+}
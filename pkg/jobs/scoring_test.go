@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateService_scoring(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"email", "name"},
+		[]string{"ann@corp.com", "Ann"},
+		[]string{"bob", "Bob"},
+		[]string{"cid", ""},
+		[]string{"dan@corp.com", "Dan the third"},
+	)
+	rules := []ValidationRule{
+		{Field: "email", Type: "email"},
+		{Field: "name", Type: "required"},
+		{Field: "name", Type: "max_length", Constraints: float64(5)},
+	}
+
+	testCases := []struct {
+		name      string
+		scoring   interface{}
+		score     float64
+		breakdown ScoreBreakdown
+	}{
+		{
+			// 2 invalid rows of 4, and 1 warning
+			name:  "default",
+			score: 48.75,
+			breakdown: ScoreBreakdown{Profile: ScoringDefault, ValidRatio: 0.5, ErrorPenalty: 50, WarningPenalty: 1.25, Rules: []RuleContribution{
+				{Rule: "email", Field: "email", Weight: 1, Penalty: 50},
+				{Rule: "max_length", Field: "name", Weight: 1, Penalty: 1.25},
+			}},
+		},
+		{
+			// the invalid row failing both rules counts with the weight of required
+			name:    "rule weights",
+			scoring: map[string]interface{}{"warning_weight": 20, "rule_weights": map[string]interface{}{"email": 0.5, "required:name": 2}},
+			score:   32.5,
+			breakdown: ScoreBreakdown{Profile: ScoringCustom, ValidRatio: 0.5, ErrorPenalty: 62.5, WarningPenalty: 5, Rules: []RuleContribution{
+				{Rule: "required", Field: "name", Weight: 2, Penalty: 50},
+				{Rule: "email", Field: "email", Weight: 0.5, Penalty: 12.5},
+				{Rule: "max_length", Field: "name", Weight: 1, Penalty: 5},
+			}},
+		},
+		{
+			name:    "warnings only",
+			scoring: &ScoringProfile{WarningWeight: 10},
+			score:   97.5,
+			breakdown: ScoreBreakdown{Profile: ScoringCustom, ValidRatio: 0.5, WarningPenalty: 2.5, Rules: []RuleContribution{
+				{Rule: "max_length", Field: "name", Weight: 1, Penalty: 2.5},
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestValidateService(t)
+			opts, err := s.parseValidateOptions(map[string]interface{}{"rules": rules, "scoring": tc.scoring})
+			is.NoError(err)
+			result, _, _ := s.validateData(rows, opts)
+			is.InDelta(tc.score, result.QualityScore, 0.001)
+			is.Equal(tc.breakdown, *result.ScoreBreakdown)
+		})
+	}
+}
+
+func TestValidateService_scoringThreshold(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"email"}, []string{"ann@corp.com"}, []string{"bob"})
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules":             []ValidationRule{{Field: "email", Type: "email"}},
+		"scoring":           map[string]interface{}{"error_weight": 20},
+		"min_quality_score": 95,
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.InDelta(90, result.QualityScore, 0.001)
+	is.Equal([]ThresholdFailure{{
+		Threshold: ThresholdMinQualityScore,
+		Message:   "quality score 90.00% is below the minimum of 95.00% (custom scoring)",
+	}}, checkThresholds(result, opts))
+}
+
+func TestParseScoringProfile_errors(t *testing.T) {
+	t.Parallel()
+
+	for _, scoring := range []interface{}{
+		"strict",
+		map[string]interface{}{"error_weight": "high"},
+		map[string]interface{}{"warning_weight": -1},
+		map[string]interface{}{"rule_weights": []interface{}{"email"}},
+		map[string]interface{}{"rule_weights": map[string]interface{}{"email": -2}},
+	} {
+		_, err := parseScoringProfile(scoring)
+		assert.Error(t, err, scoring)
+	}
+}
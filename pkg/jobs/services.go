@@ -1,19 +1,210 @@
 package jobs
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/rs/zerolog"
+	"github.com/samber/do-template-cli/pkg/config"
 	"github.com/samber/do/v2"
 )
 
 // DataRow represents a single row of data with dynamic fields
 // This demonstrates flexible data handling in the do dependency injection system.
 type DataRow struct {
-	Fields map[string]string `json:"fields"`
+	Fields  map[string]string `json:"fields"`
+	Columns []string          `json:"-"` // field order, as read from the CSV headers
+
+	// Source metadata, never written to outputs unless include_metadata is set
+	LineNumber int    `json:"-"` // 1-based line of the record in its source file
+	SourceFile string `json:"-"` // path of the file the record was read from
+}
+
+// Location returns a human readable position of the row in its source file.
+func (r DataRow) Location() string {
+	switch {
+	case r.SourceFile != "" && r.LineNumber > 0:
+		return fmt.Sprintf("%s:%d", r.SourceFile, r.LineNumber)
+	case r.LineNumber > 0:
+		return fmt.Sprintf("line %d", r.LineNumber)
+	default:
+		return "unknown line"
+	}
+}
+
+// Keys returns the field names of the row, in column order when known.
+// Fields missing from Columns are appended in lexical order so output stays deterministic.
+func (r DataRow) Keys() []string {
+	keys := make([]string, 0, len(r.Fields))
+	seen := make(map[string]bool, len(r.Fields))
+
+	for _, column := range r.Columns {
+		if _, ok := r.Fields[column]; ok && !seen[column] {
+			seen[column] = true
+			keys = append(keys, column)
+		}
+	}
+
+	if len(keys) < len(r.Fields) {
+		extra := make([]string, 0, len(r.Fields)-len(keys))
+		for field := range r.Fields {
+			if !seen[field] {
+				extra = append(extra, field)
+			}
+		}
+		sort.Strings(extra)
+		keys = append(keys, extra...)
+	}
+
+	return keys
+}
+
+// SetField sets a field value, appending the field to the column order when it is new.
+func (r *DataRow) SetField(name, value string) {
+	if r.Fields == nil {
+		r.Fields = make(map[string]string)
+	}
+	if _, exists := r.Fields[name]; !exists {
+		// full slice expression so rows sharing a header slice never overwrite each other
+		r.Columns = append(r.Columns[:len(r.Columns):len(r.Columns)], name)
+	}
+	r.Fields[name] = value
+}
+
+// RemoveField removes a field and its column, reporting whether the row had it.
+func (r *DataRow) RemoveField(name string) bool {
+	if _, exists := r.Fields[name]; !exists {
+		return false
+	}
+	delete(r.Fields, name)
+	columns := make([]string, 0, len(r.Columns))
+	for _, column := range r.Columns {
+		if column != name {
+			columns = append(columns, column)
+		}
+	}
+	r.Columns = columns
+	return true
+}
+
+// RenameField moves the value of a field to another name, in the column of the field unless the
+// row already has the other name, reporting whether the row had the field.
+func (r *DataRow) RenameField(name, newName string) bool {
+	value, exists := r.Fields[name]
+	if !exists {
+		return false
+	}
+	if name == newName {
+		return true
+	}
+	if _, taken := r.Fields[newName]; taken {
+		r.RemoveField(name)
+		r.Fields[newName] = value
+		return true
+	}
+
+	delete(r.Fields, name)
+	r.Fields[newName] = value
+	columns := make([]string, 0, len(r.Columns)+1)
+	renamed := false
+	for _, column := range r.Columns {
+		if column == name {
+			column, renamed = newName, true
+		}
+		columns = append(columns, column)
+	}
+	if !renamed {
+		columns = append(columns, newName)
+	}
+	r.Columns = columns
+	return true
+}
+
+// MarshalJSON encodes the row with its fields in column order,
+// so that two runs over the same input produce byte-identical output.
+func (r DataRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"fields":`)
+	if err := writeOrderedFields(&buf, r.Keys(), r.Fields); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// rowWithMetadata is a DataRow encoded together with its source metadata.
+type rowWithMetadata DataRow
+
+// MarshalJSON encodes the row fields followed by line number and source file.
+func (r rowWithMetadata) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"fields":`)
+	if err := writeOrderedFields(&buf, DataRow(r).Keys(), r.Fields); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&buf, `,"line_number":%d`, r.LineNumber)
+	if r.SourceFile != "" {
+		sourceFile, err := json.Marshal(r.SourceFile)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"source_file":`)
+		buf.Write(sourceFile)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// withMetadataFields returns copies of rows where source metadata is exposed as
+// trailing "_line_number" and "_source_file" fields, for flat outputs like CSV.
+func withMetadataFields(rows []DataRow) []DataRow {
+	result := make([]DataRow, 0, len(rows))
+	for _, row := range rows {
+		copied := DataRow{Fields: make(map[string]string, len(row.Fields)+2)}
+		for _, key := range row.Keys() {
+			copied.SetField(key, row.Fields[key])
+		}
+		copied.SetField("_line_number", strconv.Itoa(row.LineNumber))
+		copied.SetField(SourceFileField, row.SourceFile)
+		result = append(result, copied)
+	}
+	return result
+}
+
+// writeOrderedFields writes fields as a JSON object with keys in the given order.
+func writeOrderedFields(buf *bytes.Buffer, keys []string, fields map[string]string) error {
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		encodedValue, err := json.Marshal(fields[key])
+		if err != nil {
+			return err
+		}
+
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encodedValue)
+	}
+	buf.WriteByte('}')
+	return nil
 }
 
 // DataProcessor defines the interface for data processing operations
@@ -26,68 +217,346 @@ type DataProcessor interface {
 
 // ProcessingResult represents the result of a data processing operation.
 type ProcessingResult struct {
-	Success    bool     `json:"success"`
-	Processed  int      `json:"processed"`
-	OutputPath string   `json:"output_path,omitempty"`
-	Errors     []string `json:"errors,omitempty"`
-	Warnings   []string `json:"warnings,omitempty"`
-	Processor  string   `json:"processor"`
+	Success         bool                  `json:"success"`
+	Processed       int                   `json:"processed"`
+	Skipped         int                   `json:"skipped,omitempty"`          // rows skipped by position, rows a stream stopped before are not counted
+	Excluded        int                   `json:"excluded,omitempty"`         // rows excluded by rules
+	Duplicates      int                   `json:"duplicates,omitempty"`       // duplicate rows removed
+	Expanded        int                   `json:"expanded,omitempty"`         // rows added by explode transform rules
+	TotalGroups     int                   `json:"total_groups,omitempty"`     // aggregation groups before offset and limit
+	InputFiles      []string              `json:"input_files,omitempty"`      // files read, when the input may span several
+	Stats           []RuleStats           `json:"stats,omitempty"`            // per rule match statistics of filters
+	TransformErrors []TransformErrorStats `json:"transform_errors,omitempty"` // per rule counts of the rows transform rules could not transform
+	OutputPath      string                `json:"output_path,omitempty"`
+	Errors          []string              `json:"errors,omitempty"`
+	Warnings        []string              `json:"warnings,omitempty"`
+	Processor       string                `json:"processor"`
 }
 
 // FileService handles file I/O operations
 // This service demonstrates how to create reusable components with dependency injection.
 type FileService struct {
-	logger zerolog.Logger `do:""`
+	logger   zerolog.Logger `do:""`
+	fileMode os.FileMode
+	dirMode  os.FileMode
+	maxSize  int64 // largest input loaded in memory, in bytes
+	maxRows  int   // largest number of rows loaded in memory
 }
 
 // NewFileService creates a new file service with dependency injection.
 func NewFileService(i do.Injector) (*FileService, error) {
+	cfg := do.MustInvoke[*config.Config](i)
+
+	fileMode, err := config.ParseFileMode(cfg.Files.OutputMode, config.DefaultOutputMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid files.output_mode: %w", err)
+	}
+
+	dirMode, err := config.ParseFileMode(cfg.Files.DirMode, config.DefaultDirMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid files.dir_mode: %w", err)
+	}
+
+	maxSize, err := config.ParseByteSize(cfg.Files.MaxSize, config.DefaultMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid files.max_size: %w", err)
+	}
+
 	return &FileService{
-		logger: do.MustInvoke[zerolog.Logger](i),
+		logger:   *do.MustInvoke[*zerolog.Logger](i),
+		fileMode: fileMode,
+		dirMode:  dirMode,
+		maxSize:  maxSize,
+		maxRows:  cfg.Files.MaxRows,
 	}, nil
 }
 
+// SetFileMode overrides the permissions of files created by the service.
+// The process umask still applies, as with any call to os.OpenFile.
+func (fs *FileService) SetFileMode(mode os.FileMode) {
+	fs.fileMode = mode
+}
+
+// SetDirMode overrides the permissions of output directories created by the service.
+func (fs *FileService) SetDirMode(mode os.FileMode) {
+	fs.dirMode = mode
+}
+
+// ErrStopReading can be returned by a StreamCSV callback to stop reading early without error.
+var ErrStopReading = errors.New("stop reading")
+
 // ReadCSV reads a CSV file and returns data rows
 // This method demonstrates file operations with proper error handling and logging.
 func (fs *FileService) ReadCSV(filepath string) ([]DataRow, error) {
 	fs.logger.Info().Str("filepath", filepath).Msg("Reading CSV file")
 
+	dataRows := []DataRow{}
+	err := fs.readCSV(filepath, true, func(row DataRow) error {
+		dataRows = append(dataRows, row)
+		return fs.checkInputRows(filepath, len(dataRows))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fs.logger.Info().Int("records", len(dataRows)).Msg("Successfully read CSV file")
+	return dataRows, nil
+}
+
+// SourceFileField is the implicit field holding the path of the file a row was read from,
+// set by services reading several input files, so that rows can be told apart by file.
+const SourceFileField = "_source_file"
+
+// ResolveInputFiles expands the glob patterns among paths, like "exports/*.csv", into the files they
+// match in lexical order, and returns every file once. A pattern matching no file is an error,
+// while plain paths are returned as is and fail when read.
+func (fs *FileService) ResolveInputFiles(paths []string) ([]string, error) {
+	files := []string{}
+	seen := make(map[string]bool)
+	for _, pattern := range paths {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(pattern); err != nil {
+				return nil, fmt.Errorf("invalid input pattern %q: %w", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("input pattern %q matches no file", pattern)
+			}
+		}
+		for _, file := range matches {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// CheckHeaders fails when a file does not have the columns of the first one, in any order,
+// naming both files and the columns that differ.
+func (fs *FileService) CheckHeaders(paths []string) error {
+	var first []string
+	for i, path := range paths {
+		headers, err := fs.readHeaders(path)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			first = headers
+			continue
+		}
+
+		missing, unexpected := diffColumns(first, headers)
+		if len(missing) == 0 && len(unexpected) == 0 {
+			continue
+		}
+		var details []string
+		if len(missing) > 0 {
+			details = append(details, "missing "+strings.Join(missing, ", "))
+		}
+		if len(unexpected) > 0 {
+			details = append(details, "unexpected "+strings.Join(unexpected, ", "))
+		}
+		return fmt.Errorf("columns of %s do not match those of %s: %s", path, paths[0], strings.Join(details, "; "))
+	}
+	return nil
+}
+
+// readHeaders returns the header record of a CSV file, nil when the file is empty.
+func (fs *FileService) readHeaders(path string) ([]string, error) {
 	//bearer:disable go_gosec_filesystem_filereadtaint
-	file, err := os.Open(filepath)
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close() //nolint:errcheck
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	headers, err := csv.NewReader(file).Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
+		return nil, fmt.Errorf("failed to read CSV header of %s: %w", path, err)
 	}
+	return headers, nil
+}
 
-	if len(records) == 0 {
-		return []DataRow{}, nil
+// diffColumns returns the expected columns that are missing from actual, and the unexpected ones.
+func diffColumns(expected, actual []string) ([]string, []string) {
+	inExpected := make(map[string]bool, len(expected))
+	for _, column := range expected {
+		inExpected[column] = true
+	}
+	inActual := make(map[string]bool, len(actual))
+	for _, column := range actual {
+		inActual[column] = true
 	}
 
-	// Get headers from first row
-	headers := records[0]
-	dataRows := []DataRow{}
+	var missing, unexpected []string
+	for _, column := range expected {
+		if !inActual[column] {
+			missing = append(missing, column)
+		}
+	}
+	for _, column := range actual {
+		if !inExpected[column] {
+			unexpected = append(unexpected, column)
+		}
+	}
+	return missing, unexpected
+}
+
+// StreamCSV reads a CSV file row by row, calling fn for each data row.
+// Rows are not retained, so memory use does not grow with the input and the
+// in-memory input limits do not apply. Returning ErrStopReading from fn stops early.
+func (fs *FileService) StreamCSV(filepath string, fn func(DataRow) error) error {
+	fs.logger.Info().Str("filepath", filepath).Msg("Streaming CSV file")
+
+	return fs.readCSV(filepath, false, fn)
+}
+
+// StreamJSONL reads a JSON Lines file, one JSON object per line, row by row, calling fn for each
+// data row. Values are strings as in CSV files: numbers as written, null as empty and nested values
+// as JSON. Blank lines are skipped, and returning ErrStopReading from fn stops early.
+func (fs *FileService) StreamJSONL(filepath string, fn func(DataRow) error) error {
+	fs.logger.Info().Str("filepath", filepath).Msg("Streaming JSONL file")
 
-	for i, record := range records[1:] {
+	//bearer:disable go_gosec_filesystem_filereadtaint
+	file, err := os.Open(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxJSONLLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return fmt.Errorf("failed to read JSONL line %d of %s: %w", line, filepath, err)
+		}
+
+		row := DataRow{Fields: make(map[string]string, len(object)), LineNumber: line, SourceFile: filepath}
+		for field, value := range object {
+			row.Fields[field] = jsonlValue(value)
+		}
+		if err := fn(row); errors.Is(err, ErrStopReading) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read JSONL: %w", err)
+	}
+	return nil
+}
+
+// maxJSONLLineSize is the longest line StreamJSONL reads.
+const maxJSONLLineSize = 64 << 20
+
+// jsonlValue returns the string of a value decoded from a JSONL row.
+func jsonlValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// isJSONLFile reports whether a path has the extension of a JSON Lines file, .jsonl or .ndjson.
+func isJSONLFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return true
+	default:
+		return false
+	}
+}
+
+// readCSV parses a CSV file and calls fn for each well-formed data row.
+// Input size limits are enforced when the rows are loaded in memory.
+func (fs *FileService) readCSV(filepath string, inMemory bool, fn func(DataRow) error) error {
+	//bearer:disable go_gosec_filesystem_filereadtaint
+	file, err := os.Open(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	var input io.Reader = file
+	if inMemory {
+		// Refuse inputs that cannot reasonably be loaded in memory before reading them
+		if info, err := file.Stat(); err == nil {
+			if err := fs.checkInputSize(filepath, info.Size()); err != nil {
+				return err
+			}
+		}
+		input = &limitedReader{reader: file, path: filepath, maxSize: fs.maxSize}
+	}
+
+	reader := csv.NewReader(input)
+	// Column count is checked below so malformed rows are skipped instead of aborting the read
+	reader.FieldsPerRecord = -1
+
+	var headers []string
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if limitErr := (*InputLimitError)(nil); errors.As(err, &limitErr) {
+			return limitErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		// Get headers from first row, capped so appending columns to a row always copies
+		if headers == nil {
+			headers = record[:len(record):len(record)]
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
 		if len(record) != len(headers) {
-			fs.logger.Warn().Int("row", i+2).Msg("Row column count mismatch")
+			fs.logger.Warn().Str("filepath", filepath).Int("line", line).Msg("Row column count mismatch")
 			continue
 		}
 
-		row := DataRow{Fields: make(map[string]string)}
+		row := DataRow{
+			Fields:     make(map[string]string, len(headers)),
+			Columns:    headers,
+			LineNumber: line,
+			SourceFile: filepath,
+		}
 		for j, value := range record {
 			row.Fields[headers[j]] = value
 		}
-		dataRows = append(dataRows, row)
-	}
 
-	fs.logger.Info().Int("records", len(dataRows)).Msg("Successfully read CSV file")
-	return dataRows, nil
+		if err := fn(row); errors.Is(err, ErrStopReading) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
 }
 
 // WriteJSON writes data rows to a JSON file
@@ -95,11 +564,11 @@ func (fs *FileService) ReadCSV(filepath string) ([]DataRow, error) {
 func (fs *FileService) WriteJSON(filepath string, data interface{}) error {
 	fs.logger.Info().Str("filepath", filepath).Msg("Writing JSON file")
 
-	file, err := os.Create(filepath)
+	file, err := fs.createOutputFile(filepath)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return err
 	}
-	defer file.Close() //nolint:errcheck
+	defer file.Abort()
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
@@ -107,37 +576,219 @@ func (fs *FileService) WriteJSON(filepath string, data interface{}) error {
 		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
 
+	if err := file.Commit(); err != nil {
+		return err
+	}
+
 	fs.logger.Info().Str("filepath", filepath).Msg("Successfully wrote JSON file")
 	return nil
 }
 
+// CSVQuoting defines the quoting policy used when writing CSV files.
+type CSVQuoting string
+
+const (
+	// CSVQuoteMinimal only quotes fields that contain separators, quotes or line breaks.
+	CSVQuoteMinimal CSVQuoting = "minimal"
+	// CSVQuoteAll quotes every field, as expected by some spreadsheet importers.
+	CSVQuoteAll CSVQuoting = "all"
+)
+
+// CSVWriteOptions controls the CSV dialect produced by WriteCSV.
+type CSVWriteOptions struct {
+	UseCRLF bool       `json:"use_crlf"`
+	Quoting CSVQuoting `json:"quoting,omitempty"`
+}
+
+// parseCSVWriteOptions parses CSV output options from an options map.
+func parseCSVWriteOptions(options map[string]interface{}) (CSVWriteOptions, error) {
+	opts := CSVWriteOptions{Quoting: CSVQuoteMinimal}
+
+	if useCRLF, ok := options["use_crlf"].(bool); ok {
+		opts.UseCRLF = useCRLF
+	}
+
+	if quoting, ok := options["csv_quoting"].(string); ok && quoting != "" {
+		opts.Quoting = CSVQuoting(quoting)
+	}
+
+	if opts.Quoting != CSVQuoteMinimal && opts.Quoting != CSVQuoteAll {
+		return opts, fmt.Errorf("unknown csv quoting policy: %s", opts.Quoting)
+	}
+
+	return opts, nil
+}
+
+// mergeOptions returns a copy of options with extra options layered on top.
+func mergeOptions(options, extra map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(options)+len(extra))
+	for key, value := range options {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	return merged
+}
+
 // WriteCSV writes data rows to a CSV file
-// This method demonstrates CSV writing with headers.
-func (fs *FileService) WriteCSV(filepath string, headers []string, data [][]string) error {
+// This method demonstrates CSV writing with headers and a configurable dialect.
+func (fs *FileService) WriteCSV(filepath string, headers []string, data [][]string, opts CSVWriteOptions) error {
 	fs.logger.Info().Str("filepath", filepath).Msg("Writing CSV file")
 
-	file, err := os.Create(filepath)
+	file, err := fs.createOutputFile(filepath)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return err
 	}
-	defer file.Close() //nolint:errcheck
+	defer file.Abort()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	if err := writeCSVRecords(file, append([][]string{headers}, data...), opts); err != nil {
+		return err
+	}
 
-	// Write headers
-	if err := writer.Write(headers); err != nil {
-		return fmt.Errorf("failed to write headers: %w", err)
+	if err := file.Commit(); err != nil {
+		return err
 	}
 
-	// Write data
-	for _, record := range data {
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("failed to write record: %w", err)
+	fs.logger.Info().Str("filepath", filepath).Msg("Successfully wrote CSV file")
+	return nil
+}
+
+// WriteDataCSV writes data rows to a CSV file, using the union of all fields as headers
+// in the order they first appear.
+func (fs *FileService) WriteDataCSV(filepath string, rows []DataRow, opts CSVWriteOptions) error {
+	headers := collectHeaders(rows)
+
+	data := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		record := make([]string, len(headers))
+		for i, header := range headers {
+			record[i] = row.Fields[header]
 		}
+		data = append(data, record)
 	}
 
-	fs.logger.Info().Str("filepath", filepath).Msg("Successfully wrote CSV file")
+	return fs.WriteCSV(filepath, headers, data, opts)
+}
+
+// OutputOptions controls how data rows are written by WriteRows.
+type OutputOptions struct {
+	CSV             CSVWriteOptions `json:"csv"`              // dialect used when the output file is a CSV
+	IncludeMetadata bool            `json:"include_metadata"` // also write source line numbers and files
+}
+
+// parseOutputOptions parses row output options from an options map.
+func parseOutputOptions(options map[string]interface{}) (OutputOptions, error) {
+	csvOpts, err := parseCSVWriteOptions(options)
+	if err != nil {
+		return OutputOptions{}, err
+	}
+
+	opts := OutputOptions{CSV: csvOpts}
+	if includeMetadata, ok := options["include_metadata"].(bool); ok {
+		opts.IncludeMetadata = includeMetadata
+	}
+
+	return opts, nil
+}
+
+// WriteRows writes data rows to a file, picking CSV or JSON from the file extension.
+// Source metadata is only written when opts.IncludeMetadata is set.
+func (fs *FileService) WriteRows(filepath string, rows []DataRow, opts OutputOptions) error {
+	if strings.EqualFold(path.Ext(filepath), ".csv") {
+		if opts.IncludeMetadata {
+			rows = withMetadataFields(rows)
+		}
+		return fs.WriteDataCSV(filepath, rows, opts.CSV)
+	}
+
+	return fs.WriteJSONRows(filepath, rows, opts.IncludeMetadata)
+}
+
+// WriteJSONRows writes data rows to a JSON file, optionally with their source metadata.
+func (fs *FileService) WriteJSONRows(filepath string, rows []DataRow, includeMetadata bool) error {
+	if !includeMetadata {
+		return fs.WriteJSON(filepath, rows)
+	}
+
+	withMetadata := make([]rowWithMetadata, 0, len(rows))
+	for _, row := range rows {
+		withMetadata = append(withMetadata, rowWithMetadata(row))
+	}
+	return fs.WriteJSON(filepath, withMetadata)
+}
+
+// collectHeaders returns the union of field names across rows, in first-seen column order.
+func collectHeaders(rows []DataRow) []string {
+	seen := make(map[string]bool)
+	headers := []string{}
+
+	for _, row := range rows {
+		for _, field := range row.Keys() {
+			if !seen[field] {
+				seen[field] = true
+				headers = append(headers, field)
+			}
+		}
+	}
+
+	return headers
+}
+
+// writeCSVRecords writes records using encoding/csv for minimal quoting,
+// or a quote-all encoder compatible with Excel otherwise.
+func writeCSVRecords(w io.Writer, records [][]string, opts CSVWriteOptions) error {
+	if opts.Quoting != CSVQuoteAll {
+		writer := csv.NewWriter(w)
+		writer.UseCRLF = opts.UseCRLF
+
+		for _, record := range records {
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
+		}
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("failed to flush CSV: %w", err)
+		}
+		return nil
+	}
+
+	// encoding/csv has no quote-all mode, so mirror its escaping rules here:
+	// quotes are doubled and line breaks follow the UseCRLF setting.
+	buffered := bufio.NewWriter(w)
+	lineEnding := "\n"
+	if opts.UseCRLF {
+		lineEnding = "\r\n"
+	}
+
+	for _, record := range records {
+		for i, field := range record {
+			if i > 0 {
+				_ = buffered.WriteByte(',')
+			}
+			_ = buffered.WriteByte('"')
+			for _, r := range field {
+				switch {
+				case r == '"':
+					_, _ = buffered.WriteString(`""`)
+				case r == '\r' && opts.UseCRLF:
+					// dropped, \n is expanded to \r\n below
+				case r == '\n':
+					_, _ = buffered.WriteString(lineEnding)
+				default:
+					_, _ = buffered.WriteRune(r)
+				}
+			}
+			_ = buffered.WriteByte('"')
+		}
+		_, _ = buffered.WriteString(lineEnding)
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
 	return nil
 }
 
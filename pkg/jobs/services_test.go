@@ -0,0 +1,309 @@
+package jobs
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/do-template-cli/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFileService(t *testing.T) *FileService {
+	t.Helper()
+
+	return &FileService{
+		logger:   zerolog.Nop(),
+		fileMode: config.DefaultOutputMode,
+		dirMode:  config.DefaultDirMode,
+	}
+}
+
+func TestFileService_WriteCSV_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	headers := []string{"name", "comment"}
+	data := [][]string{
+		{"Doe, John", `said "hi"`},
+		{"Jane", "multi\nline"},
+		{"", "plain"},
+	}
+
+	testCases := []struct {
+		name     string
+		opts     CSVWriteOptions
+		expected string
+	}{
+		{
+			name:     "minimal LF",
+			opts:     CSVWriteOptions{Quoting: CSVQuoteMinimal},
+			expected: "name,comment\n\"Doe, John\",\"said \"\"hi\"\"\"\nJane,\"multi\nline\"\n,plain\n",
+		},
+		{
+			name:     "minimal CRLF",
+			opts:     CSVWriteOptions{UseCRLF: true, Quoting: CSVQuoteMinimal},
+			expected: "name,comment\r\n\"Doe, John\",\"said \"\"hi\"\"\"\r\nJane,\"multi\r\nline\"\r\n,plain\r\n",
+		},
+		{
+			name:     "quote all CRLF",
+			opts:     CSVWriteOptions{UseCRLF: true, Quoting: CSVQuoteAll},
+			expected: "\"name\",\"comment\"\r\n\"Doe, John\",\"said \"\"hi\"\"\"\r\n\"Jane\",\"multi\r\nline\"\r\n\"\",\"plain\"\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			fs := newTestFileService(t)
+			path := filepath.Join(t.TempDir(), "out.csv")
+
+			is.NoError(fs.WriteCSV(path, headers, data, tc.opts))
+
+			content, err := os.ReadFile(path)
+			is.NoError(err)
+			is.Equal(tc.expected, string(content))
+
+			records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+			is.NoError(err)
+			is.Equal(append([][]string{headers}, data...), records)
+		})
+	}
+}
+
+func TestFileService_WriteRows_csvExtension(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	fs := newTestFileService(t)
+	path := filepath.Join(t.TempDir(), "out.csv")
+
+	rows := []DataRow{
+		{Fields: map[string]string{"b": "2", "a": "1"}},
+		{Fields: map[string]string{"a": "3", "c": "4"}},
+	}
+
+	is.NoError(fs.WriteRows(path, rows, OutputOptions{CSV: CSVWriteOptions{Quoting: CSVQuoteAll}}))
+
+	content, err := os.ReadFile(path)
+	is.NoError(err)
+	is.Equal("\"a\",\"b\",\"c\"\n\"1\",\"2\",\"\"\n\"3\",\"\",\"4\"\n", string(content))
+}
+
+func TestParseCSVWriteOptions(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	opts, err := parseCSVWriteOptions(map[string]interface{}{})
+	is.NoError(err)
+	is.Equal(CSVWriteOptions{Quoting: CSVQuoteMinimal}, opts)
+
+	opts, err = parseCSVWriteOptions(map[string]interface{}{"use_crlf": true, "csv_quoting": "all"})
+	is.NoError(err)
+	is.Equal(CSVWriteOptions{UseCRLF: true, Quoting: CSVQuoteAll}, opts)
+
+	_, err = parseCSVWriteOptions(map[string]interface{}{"csv_quoting": "some"})
+	is.Error(err)
+}
+
+func TestFileService_WriteJSON_permissions(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	fs := newTestFileService(t)
+	fs.SetFileMode(0o600)
+	fs.SetDirMode(0o700)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reports", "daily", "out.json")
+
+	is.NoError(fs.WriteJSON(path, []string{"a"}))
+
+	info, err := os.Stat(path)
+	is.NoError(err)
+	is.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	info, err = os.Stat(filepath.Join(dir, "reports"))
+	is.NoError(err)
+	is.True(info.IsDir())
+	is.Equal(os.FileMode(0o700), info.Mode().Perm())
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	is.NoError(err)
+	is.Len(entries, 1)
+}
+
+func TestFileService_ReadCSV_preservesColumnOrder(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	fs := newTestFileService(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("zeta,alpha,mid\n1,2,3\n4,5,6\n"), 0o600))
+
+	rows, err := fs.ReadCSV(input)
+	is.NoError(err)
+	is.Len(rows, 2)
+	is.Equal([]string{"zeta", "alpha", "mid"}, rows[0].Keys())
+
+	var outputs []string
+	for _, name := range []string{"a.json", "b.json"} {
+		rows, err := fs.ReadCSV(input)
+		is.NoError(err)
+		is.NoError(fs.WriteJSON(filepath.Join(dir, name), rows))
+
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		is.NoError(err)
+		outputs = append(outputs, string(content))
+	}
+
+	is.Equal(outputs[0], outputs[1])
+	is.Contains(outputs[0], "\"zeta\": \"1\",\n      \"alpha\": \"2\",\n      \"mid\": \"3\"")
+
+	is.NoError(fs.WriteRows(filepath.Join(dir, "out.csv"), rows, OutputOptions{}))
+	content, err := os.ReadFile(filepath.Join(dir, "out.csv"))
+	is.NoError(err)
+	is.Equal("zeta,alpha,mid\n1,2,3\n4,5,6\n", string(content))
+}
+
+func TestDataRow_SetField_doesNotShareColumns(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	headers := make([]string, 1, 4)
+	headers[0] = "a"
+
+	first := DataRow{Fields: map[string]string{"a": "1"}, Columns: headers}
+	second := DataRow{Fields: map[string]string{"a": "2"}, Columns: headers}
+
+	first.SetField("b", "x")
+	second.SetField("c", "y")
+
+	is.Equal([]string{"a", "b"}, first.Keys())
+	is.Equal([]string{"a", "c"}, second.Keys())
+}
+
+func TestDataRow_RenameField(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	headers := []string{"a", "b", "c"}
+	row := DataRow{Fields: map[string]string{"a": "1", "b": "2", "c": "3"}, Columns: headers}
+
+	is.True(row.RenameField("b", "z"))
+	is.Equal([]string{"a", "z", "c"}, row.Keys())
+	is.Equal("2", row.Fields["z"])
+	is.Equal([]string{"a", "b", "c"}, headers)
+
+	is.True(row.RenameField("a", "c"))
+	is.Equal([]string{"z", "c"}, row.Keys())
+	is.Equal("1", row.Fields["c"])
+
+	is.False(row.RenameField("missing", "y"))
+	is.True(row.RemoveField("z"))
+	is.False(row.RemoveField("z"))
+	is.Equal([]string{"c"}, row.Keys())
+}
+
+func TestFileService_ReadCSV_lineNumbers(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	fs := newTestFileService(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	// line 3 is malformed and a quoted field spans lines 4-5
+	is.NoError(os.WriteFile(input, []byte("a,b\n1,2\nbroken\n\"x\ny\",3\n4,5\n"), 0o600))
+
+	rows, err := fs.ReadCSV(input)
+	is.NoError(err)
+	is.Len(rows, 3)
+	is.Equal(2, rows[0].LineNumber)
+	is.Equal(4, rows[1].LineNumber)
+	is.Equal(6, rows[2].LineNumber)
+	is.Equal(input, rows[2].SourceFile)
+
+	// metadata stays out of outputs unless requested
+	is.NoError(fs.WriteRows(filepath.Join(dir, "plain.json"), rows[:1], OutputOptions{}))
+	content, err := os.ReadFile(filepath.Join(dir, "plain.json"))
+	is.NoError(err)
+	is.NotContains(string(content), "line_number")
+
+	is.NoError(fs.WriteRows(filepath.Join(dir, "meta.json"), rows[:1], OutputOptions{IncludeMetadata: true}))
+	content, err = os.ReadFile(filepath.Join(dir, "meta.json"))
+	is.NoError(err)
+	is.Contains(string(content), "\"line_number\": 2")
+
+	is.NoError(fs.WriteRows(filepath.Join(dir, "meta.csv"), rows[:1], OutputOptions{IncludeMetadata: true}))
+	content, err = os.ReadFile(filepath.Join(dir, "meta.csv"))
+	is.NoError(err)
+	is.Equal("a,b,_line_number,_source_file\n1,2,2,"+input+"\n", string(content))
+}
+
+func TestFileService_StreamJSONL(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	fs := newTestFileService(t)
+	input := filepath.Join(t.TempDir(), "in.jsonl")
+	is.NoError(os.WriteFile(input, []byte(`{"id":"a","n":12345678901234567890,"ok":true,"note":null}`+"\n\n"+
+		`{"id":"b","tags":["x","y"]}`+"\n"), 0o600))
+
+	var rows []DataRow
+	is.NoError(fs.StreamJSONL(input, func(row DataRow) error {
+		rows = append(rows, row)
+		return nil
+	}))
+	is.Len(rows, 2)
+	is.Equal(map[string]string{"id": "a", "n": "12345678901234567890", "ok": "true", "note": ""}, rows[0].Fields)
+	is.Equal(map[string]string{"id": "b", "tags": `["x","y"]`}, rows[1].Fields)
+	is.Equal(3, rows[1].LineNumber)
+
+	is.NoError(os.WriteFile(input, []byte("{\"id\":\"a\"}\n[1]\n"), 0o600))
+	err := fs.StreamJSONL(input, func(DataRow) error { return nil })
+	is.ErrorContains(err, "line 2")
+}
+
+func TestFileService_ResolveInputFiles(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	for _, name := range []string{"day2.csv", "day1.csv", "notes.txt"} {
+		is.NoError(os.WriteFile(filepath.Join(dir, name), []byte("id\n"), 0o600))
+	}
+	fs := newTestFileService(t)
+
+	files, err := fs.ResolveInputFiles([]string{filepath.Join(dir, "*.csv"), filepath.Join(dir, "day1.csv"), "missing.csv"})
+	is.NoError(err)
+	is.Equal([]string{filepath.Join(dir, "day1.csv"), filepath.Join(dir, "day2.csv"), "missing.csv"}, files)
+
+	_, err = fs.ResolveInputFiles([]string{filepath.Join(dir, "*.json")})
+	is.ErrorContains(err, "matches no file")
+}
+
+func TestFileService_CheckHeaders(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		is.NoError(os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	first := write("a.csv", "id,name,amount\n1,ann,3\n")
+	reordered := write("b.csv", "amount,id,name\n")
+	different := write("c.csv", "id,label,amount\n")
+	fs := newTestFileService(t)
+
+	is.NoError(fs.CheckHeaders([]string{first, reordered}))
+	is.EqualError(fs.CheckHeaders([]string{first, reordered, different}),
+		"columns of "+different+" do not match those of "+first+": missing name; unexpected label")
+}
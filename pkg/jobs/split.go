@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policies of split rules with target fields for values with more parts than target fields.
+const (
+	SplitJoin = "join" // join the remainder into the last target field, the default
+	SplitDrop = "drop" // drop the parts without a target field
+)
+
+// Policies of split rules with target fields for values with fewer parts than target fields.
+const (
+	SplitEmpty = "empty" // empty the target fields without a part, the default
+	SplitSkip  = "skip"  // leave the target fields without a part as they are
+)
+
+// fieldSplit splits the field of a "split" rule into several fields when its parameters have
+// "target_fields", the ordered names of the parts, like ["first_name", "last_name"]; the target
+// field of the rule is then ignored. The other parameters are "separator", "," by default,
+// "max_parts", the most parts a value is split into, "extra" and "missing", the Split policies
+// for values with more or fewer parts than target fields, and "trim", trimming whitespace around
+// parts. Quoted separators are not told apart.
+type fieldSplit struct {
+	separator string
+	targets   []string
+	maxParts  int // -1 for no limit
+	extra     string
+	missing   string
+	trim      bool
+}
+
+// parseFieldSplit parses the parameters of a "split" rule, nil when it has no target fields.
+func parseFieldSplit(rule TransformRule) (*fieldSplit, error) {
+	raw, ok := rule.Parameters["target_fields"]
+	if !ok {
+		return nil, nil
+	}
+
+	split := &fieldSplit{separator: ",", extra: SplitJoin, missing: SplitEmpty}
+	var err error
+	if split.targets, err = parseSplitTargets(rule, raw); err != nil {
+		return nil, err
+	}
+
+	if separator, ok := rule.Parameters["separator"].(string); ok {
+		if separator == "" {
+			return nil, fmt.Errorf("empty separator of split rule on field '%s'", rule.Field)
+		}
+		split.separator = separator
+	}
+
+	split.maxParts = -1
+	if raw, ok := rule.Parameters["max_parts"]; ok {
+		if split.maxParts, ok = toInt(raw); !ok || split.maxParts < 1 {
+			return nil, fmt.Errorf("invalid max_parts %v of split rule on field '%s': expected a positive integer", raw, rule.Field)
+		}
+	}
+
+	if trim, ok := rule.Parameters["trim"]; ok {
+		if split.trim, ok = trim.(bool); !ok {
+			return nil, fmt.Errorf("invalid trim of split rule on field '%s': expected true or false", rule.Field)
+		}
+	}
+
+	if err := split.parsePolicies(rule); err != nil {
+		return nil, err
+	}
+	return split, nil
+}
+
+// parseSplitTargets parses the target fields of a "split" rule, distinct and at least one.
+func parseSplitTargets(rule TransformRule, raw interface{}) ([]string, error) {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, target := range parseColumnList(raw) {
+		target = strings.TrimSpace(target)
+		if target == "" || seen[target] {
+			return nil, fmt.Errorf("invalid target_fields of split rule on field '%s': expected distinct field names", rule.Field)
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("invalid target_fields of split rule on field '%s': expected a list of fields", rule.Field)
+	}
+	return targets, nil
+}
+
+// parsePolicies parses the policies of a "split" rule for values with more or fewer parts than target fields.
+func (f *fieldSplit) parsePolicies(rule TransformRule) error {
+	for name, policy := range map[string]*string{"extra": &f.extra, "missing": &f.missing} {
+		value, _ := rule.Parameters[name].(string)
+		switch {
+		case value == "":
+		case name == "extra" && (value == SplitJoin || value == SplitDrop),
+			name == "missing" && (value == SplitEmpty || value == SplitSkip):
+			*policy = value
+		default:
+			return fmt.Errorf("invalid %s %q of split rule on field '%s': expected %s", name, value, rule.Field, map[string]string{"extra": "join or drop", "missing": "empty or skip"}[name])
+		}
+	}
+	return nil
+}
+
+// parts splits a value into the parts of the target fields, in order, shorter when it has fewer.
+func (f *fieldSplit) parts(value string) []string {
+	// values are split into max_parts at most, the last part holding the remainder
+	parts := strings.SplitN(value, f.separator, f.maxParts)
+	if len(parts) > len(f.targets) {
+		last := len(f.targets) - 1
+		if f.extra == SplitJoin {
+			parts = append(parts[:last], strings.Join(parts[last:], f.separator))
+		} else {
+			parts = parts[:len(f.targets)]
+		}
+	}
+	if f.trim {
+		for i, part := range parts {
+			parts[i] = strings.TrimSpace(part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_splitFields(t *testing.T) {
+	t.Parallel()
+
+	input := testRows(t, []string{"name"}, []string{"Ada Lovelace"}, []string{"J R R Tolkien"}, []string{"Plato"}, []string{""})
+
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		want   [][]string
+	}{
+		{
+			name:   "join remainder",
+			params: map[string]interface{}{"separator": " "},
+			want:   [][]string{{"Ada", "Lovelace"}, {"J", "R R Tolkien"}, {"Plato", ""}, {"", ""}},
+		},
+		{
+			name:   "drop extra parts",
+			params: map[string]interface{}{"separator": " ", "extra": "drop"},
+			want:   [][]string{{"Ada", "Lovelace"}, {"J", "R"}, {"Plato", ""}, {"", ""}},
+		},
+		{
+			name:   "max parts",
+			params: map[string]interface{}{"separator": " ", "max_parts": 1},
+			want:   [][]string{{"Ada Lovelace", ""}, {"J R R Tolkien", ""}, {"Plato", ""}, {"", ""}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			tc.params["target_fields"] = []interface{}{"first_name", "last_name"}
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "name", Operation: Split, Parameters: tc.params, TargetField: "ignored"}},
+			})
+			is.NoError(err)
+			for i, row := range output {
+				is.Equal([]string{"name", "first_name", "last_name"}, row.Keys())
+				is.Equal(tc.want[i], []string{row.Fields["first_name"], row.Fields["last_name"]}, i)
+			}
+		})
+	}
+}
+
+func TestTransformService_splitAddress(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"address", "country"}, []string{"1 Main St , Springfield ,  IL ", "US"}, []string{"Rue de Rivoli, Paris", "FR"})
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"keep_fields": false,
+		"chained":     true,
+		"rules": []TransformRule{
+			{Field: "address", Operation: Split, Parameters: map[string]interface{}{"target_fields": "street,city,state", "trim": true, "missing": "skip"}},
+			{Field: "state", Operation: Default, Parameters: map[string]interface{}{"value": "n/a"}},
+		},
+	})
+	is.NoError(err)
+	is.Equal(map[string]string{"street": "1 Main St", "city": "Springfield", "state": "IL"}, output[0].Fields)
+	is.Equal(map[string]string{"street": "Rue de Rivoli", "city": "Paris", "state": "n/a"}, output[1].Fields)
+
+	for _, params := range []map[string]interface{}{
+		{"target_fields": []interface{}{}},
+		{"target_fields": "a,a"},
+		{"target_fields": "a,b", "separator": ""},
+		{"target_fields": "a,b", "max_parts": 0},
+		{"target_fields": "a,b", "trim": "yes"},
+		{"target_fields": "a,b", "extra": "keep"},
+		{"target_fields": "a,b", "missing": "null"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "address", Operation: Split, Parameters: params}},
+		})
+		is.ErrorContains(err, "split rule on field 'address'", params)
+	}
+}
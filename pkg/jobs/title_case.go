@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// nameParticles are the particles of names "title_case" rules in names mode keep lowercase
+// after the first word, like "van" in "Ludwig van Beethoven".
+var nameParticles = map[string]bool{
+	"da": true, "das": true, "de": true, "del": true, "della": true, "den": true, "der": true,
+	"des": true, "di": true, "do": true, "dos": true, "du": true, "la": true, "le": true,
+	"ten": true, "ter": true, "van": true, "von": true, "y": true, "zu": true,
+}
+
+// nameElisions are the elided particles of names "title_case" rules in names mode keep lowercase
+// after the first word, like "d'" in "Jeanne d'Arc".
+var nameElisions = map[string]bool{"d": true, "l": true}
+
+// titleCasing writes values in title case for "title_case" rules, following the Unicode word
+// boundaries and the casing of the "language" parameter, a BCP 47 tag, undetermined by default,
+// like "nl" writing "IJssel" or "tr" writing "İstanbul". With "names_mode", values are read as
+// person names: particles after the first word stay lowercase, like "Ludwig van Beethoven", and
+// letters after a one letter prefix and an apostrophe are capitalized, like "O'Brien".
+//
+// Values were written with strings.Title before, which capitalized letters after any apostrophe,
+// like "It'S", and kept the case of letters within words of values not lowercased by Go, so some
+// values are written differently since, like "It's", "Élodie" or "Ijssel".
+type titleCasing struct {
+	caser cases.Caser
+	names bool
+}
+
+// parseTitleCasing parses the parameters of a "title_case" rule.
+func parseTitleCasing(rule TransformRule) (*titleCasing, error) {
+	tag := language.Und
+	if raw, ok := rule.Parameters["language"]; ok {
+		name, _ := raw.(string)
+		var err error
+		if tag, err = language.Parse(name); err != nil {
+			return nil, fmt.Errorf("invalid language %v of title_case rule on field '%s': expected a BCP 47 tag like \"en\" or \"nl\"", raw, rule.Field)
+		}
+	}
+
+	casing := &titleCasing{caser: cases.Title(tag)}
+	if raw, ok := rule.Parameters["names_mode"]; ok {
+		if casing.names, ok = raw.(bool); !ok {
+			return nil, fmt.Errorf("invalid names_mode of title_case rule on field '%s': expected true or false", rule.Field)
+		}
+	}
+	return casing, nil
+}
+
+// convert writes a value in title case.
+func (c *titleCasing) convert(value string) string {
+	titled := c.caser.String(value)
+	if !c.names {
+		return titled
+	}
+
+	words := strings.Split(titled, " ")
+	first := true
+	for i, word := range words {
+		if word == "" {
+			continue
+		}
+		if !first && nameParticles[strings.ToLower(word)] {
+			words[i] = strings.ToLower(word)
+		} else {
+			words[i] = capitalizeElision(word, !first)
+		}
+		first = false
+	}
+	return strings.Join(words, " ")
+}
+
+// capitalizeElision capitalizes the letter after the apostrophe of a word starting with a one
+// letter prefix, like "O'brien", the prefix being lowercased when it is an elided particle not
+// starting the name, like "d'Arc".
+func capitalizeElision(word string, particle bool) string {
+	prefix, prefixSize := utf8.DecodeRuneInString(word)
+	apostrophe, apostropheSize := utf8.DecodeRuneInString(word[prefixSize:])
+	if !unicode.IsLetter(prefix) || (apostrophe != '\'' && apostrophe != '’') {
+		return word
+	}
+	rest := word[prefixSize+apostropheSize:]
+	letter, letterSize := utf8.DecodeRuneInString(rest)
+	if !unicode.IsLetter(letter) {
+		return word
+	}
+
+	if particle && nameElisions[string(unicode.ToLower(prefix))] {
+		prefix = unicode.ToLower(prefix)
+	}
+	return string(prefix) + string(apostrophe) + string(unicode.ToTitle(letter)) + rest[letterSize:]
+}
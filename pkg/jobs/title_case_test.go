@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_titleCase(t *testing.T) {
+	t.Parallel()
+
+	names := map[string]interface{}{"names_mode": true}
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "words", value: "hello WORLD", want: "Hello World"},
+		{name: "hyphenated", value: "jean-luc picard", want: "Jean-Luc Picard"},
+		{name: "apostrophe within word", value: "it's MINE", want: "It's Mine"},
+		{name: "apostrophe name", value: "o'brien", want: "O'brien"},
+		{name: "accented", value: "ÉLODIE MÜLLER", want: "Élodie Müller"},
+		{name: "accented lowercase", value: "élodie garcía", want: "Élodie García"},
+		{name: "dutch digraph", value: "ijssel", params: map[string]interface{}{"language": "nl"}, want: "IJssel"},
+		{name: "turkish dotted i", value: "istanbul", params: map[string]interface{}{"language": "tr"}, want: "İstanbul"},
+		{name: "names apostrophe", value: "o'brien", params: names, want: "O'Brien"},
+		{name: "names typographic apostrophe", value: "mary o’neill", params: names, want: "Mary O’Neill"},
+		{name: "names apostrophe hyphenated", value: "o'brien-smith", params: names, want: "O'Brien-Smith"},
+		{name: "names particles", value: "LUDWIG VAN BEETHOVEN", params: names, want: "Ludwig van Beethoven"},
+		{name: "names several particles", value: "johann von und zu liechtenstein", params: names, want: "Johann von Und zu Liechtenstein"},
+		{name: "names leading particle", value: "de la cruz", params: names, want: "De la Cruz"},
+		{name: "names elided particle", value: "jeanne d'arc", params: names, want: "Jeanne d'Arc"},
+		{name: "names leading elided particle", value: "d'angelo", params: names, want: "D'Angelo"},
+		{name: "names accented", value: "josé de san martín", params: names, want: "José de San Martín"},
+		{name: "names spacing kept", value: "anne  de bretagne", params: names, want: "Anne  de Bretagne"},
+		{name: "names contraction", value: "rock'n'roll", params: names, want: "Rock'n'roll"},
+		{name: "empty", value: "", want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(
+				[]DataRow{{Fields: map[string]string{"name": tc.value}}},
+				map[string]interface{}{"rules": []TransformRule{{Field: "name", Operation: TitleCase, Parameters: tc.params}}},
+			)
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["name"])
+		})
+	}
+}
+
+func TestTransformService_titleCaseErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for _, params := range []map[string]interface{}{
+		{"language": "not a tag"},
+		{"language": 12},
+		{"names_mode": "yes"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{{Field: "name", Operation: TitleCase, Parameters: params}}})
+		is.ErrorContains(err, "title_case rule on field 'name'", params)
+	}
+}
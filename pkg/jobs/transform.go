@@ -2,29 +2,82 @@ package jobs
 
 import (
 	"fmt"
+	"maps"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/samber/do/v2"
+	"golang.org/x/text/unicode/norm"
 )
 
 // TransformOperation defines transformation operation types.
 type TransformOperation string
 
 const (
-	UpperCase   TransformOperation = "upper_case"
-	LowerCase   TransformOperation = "lower_case"
-	TitleCase   TransformOperation = "title_case"
-	Trim        TransformOperation = "trim"
-	Replace     TransformOperation = "replace"
-	Extract     TransformOperation = "extract"
-	Split       TransformOperation = "split"
-	Join        TransformOperation = "join"
-	FormatDate  TransformOperation = "format_date"
-	Calculate   TransformOperation = "calculate"
-	Conditional TransformOperation = "conditional"
+	UpperCase           TransformOperation = "upper_case"
+	LowerCase           TransformOperation = "lower_case"
+	TitleCase           TransformOperation = "title_case"
+	Trim                TransformOperation = "trim"
+	Replace             TransformOperation = "replace"
+	Extract             TransformOperation = "extract"
+	Split               TransformOperation = "split"
+	Join                TransformOperation = "join"
+	FormatDate          TransformOperation = "format_date"
+	Calculate           TransformOperation = "calculate"
+	Conditional         TransformOperation = "conditional"
+	Concat              TransformOperation = "concat"
+	Rename              TransformOperation = "rename" // moves the field to the target field
+	Drop                TransformOperation = "drop"   // removes the field
+	Copy                TransformOperation = "copy"   // writes the field to the target field
+	Default             TransformOperation = "default"
+	Hash                TransformOperation = "hash" // pseudonymizes the field
+	Mask                TransformOperation = "mask" // hides the field but its ends
+	Pad                 TransformOperation = "pad"
+	Truncate            TransformOperation = "truncate"
+	Lookup              TransformOperation = "lookup"   // translates the field with a mapping
+	Template            TransformOperation = "template" // renders a text/template over the row
+	Round               TransformOperation = "round"
+	RowNumber           TransformOperation = "row_number" // numbers rows in input order
+	UUID                TransformOperation = "uuid"
+	Cast                TransformOperation = "cast" // converts the field to a canonical representation
+	SnakeCase           TransformOperation = "snake_case"
+	CamelCase           TransformOperation = "camel_case"
+	KebabCase           TransformOperation = "kebab_case"
+	Slugify             TransformOperation = "slugify"
+	RegexReplace        TransformOperation = "regex_replace"
+	Base64Encode        TransformOperation = "base64_encode"
+	Base64Decode        TransformOperation = "base64_decode"
+	HexEncode           TransformOperation = "hex_encode"
+	HexDecode           TransformOperation = "hex_decode"
+	URLEncode           TransformOperation = "url_encode"
+	URLDecode           TransformOperation = "url_decode"
+	URLQueryParam       TransformOperation = "url_query_param"
+	URLComponent        TransformOperation = "url_component"
+	JSONExtract         TransformOperation = "json_extract"
+	ConvertUnit         TransformOperation = "convert_unit"
+	DateAdd             TransformOperation = "date_add"
+	DateDiff            TransformOperation = "date_diff"
+	TrimChars           TransformOperation = "trim_chars"
+	NormalizeWhitespace TransformOperation = "normalize_whitespace"
+	UnicodeNormalize    TransformOperation = "unicode_normalize"
+	RemoveDiacritics    TransformOperation = "remove_diacritics" // writes the field in ASCII
+	FormatNumber        TransformOperation = "format_number"     // writes a number as a locale does
+	ParseLocaleNumber   TransformOperation = "parse_number"      // reads a number written as a locale does
+	ParseCurrency       TransformOperation = "parse_currency"    // reads an amount and its currency
+	Explode             TransformOperation = "explode"           // splits the row into one row per part of the field
+	Phonetic            TransformOperation = "phonetic"          // writes a code of how the field sounds
+)
+
+// RuleSourceParameter is the parameter of transform rules telling the row they read: the current
+// one, holding the results of the rules before them when chained, or the original one, as read
+// from the input, like {"source": "original"}.
+const (
+	RuleSourceParameter = "source"
+	RuleSourceCurrent   = "current"
+	RuleSourceOriginal  = "original"
 )
 
 // TransformRule defines a transformation rule.
@@ -33,6 +86,9 @@ type TransformRule struct {
 	Operation   TransformOperation     `json:"operation"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
 	TargetField string                 `json:"target_field,omitempty"` // if different from source
+
+	onError string      // on_error policy, see OnErrorKeep
+	parsed  interface{} // parsed parameters of the operation, set by its ruleParsers entry
 }
 
 // TransformOptions contains transformation configuration.
@@ -41,27 +97,73 @@ type TransformOptions struct {
 	OutputFile string          `json:"output_file"`
 	Rules      []TransformRule `json:"rules"`
 	KeepFields bool            `json:"keep_fields"` // keep non-transformed fields
+	Chained    bool            `json:"chained"`     // rules read the results of the rules before them, see parseTransformOptions
 	DropNulls  bool            `json:"drop_nulls"`  // remove rows with null values after transformation
+	Output     OutputOptions   `json:"output"`      // format details of the written rows
+
+	errors []TransformErrorStats // rows every rule could not transform, filled while transforming
 }
 
 // TransformService handles data transformation operations
 // This service demonstrates data field transformation with dependency injection.
 type TransformService struct {
-	fileService *FileService   `do:""`
-	logger      zerolog.Logger `do:""`
+	fileService *FileService     `do:""`
+	logger      zerolog.Logger   `do:""`
+	now         func() time.Time // clock of date_diff rules ending "now", time.Now when nil
 }
 
 // NewTransformService creates a new transform service with dependency injection.
 func NewTransformService(i do.Injector) (*TransformService, error) {
 	return &TransformService{
 		fileService: do.MustInvoke[*FileService](i),
-		logger:      do.MustInvoke[zerolog.Logger](i),
+		logger:      *do.MustInvoke[*zerolog.Logger](i),
+		now:         time.Now,
 	}, nil
 }
 
+// SetClock sets the clock of date_diff rules ending "now", freezing it in tests.
+func (s *TransformService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// currentTime returns the time of the clock of the service.
+func (s *TransformService) currentTime() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
 // ProcessData transforms data based on rules
 // This method demonstrates comprehensive data transformation logic.
 func (s *TransformService) ProcessData(input []DataRow, options map[string]interface{}) ([]DataRow, error) {
+	outcome, err := s.run(input, options)
+	if err != nil {
+		return nil, err
+	}
+	return outcome.rows, nil
+}
+
+// transformOutcome describes the result of a transform run.
+type transformOutcome struct {
+	rows     []DataRow             // transformed rows
+	skipped  int                   // rows left out by rules with the skip_row on_error policy
+	exploded int                   // rows added by explode rules
+	dropped  int                   // rows dropped for their null values
+	errors   []TransformErrorStats // rules that could not transform some rows
+}
+
+// warnings describes the rules that could not transform some rows.
+func (o *transformOutcome) warnings() []string {
+	warnings := make([]string, 0, len(o.errors))
+	for _, stats := range o.errors {
+		warnings = append(warnings, stats.String())
+	}
+	return warnings
+}
+
+// run transforms the input, or the input file when input is empty, and writes the output file.
+func (s *TransformService) run(input []DataRow, options map[string]interface{}) (*transformOutcome, error) {
 	s.logger.Info().Msg("Transforming data based on rules")
 
 	// Parse options
@@ -80,27 +182,44 @@ func (s *TransformService) ProcessData(input []DataRow, options map[string]inter
 	}
 
 	// Perform transformations
-	transformedData := s.transformData(input, opts)
+	outcome := &transformOutcome{}
+	outcome.rows, outcome.skipped, outcome.exploded, err = s.transformData(input, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// Filter out null rows if requested
 	if opts.DropNulls {
-		transformedData = s.filterNullRows(transformedData)
+		kept := s.filterNullRows(outcome.rows)
+		outcome.dropped = len(outcome.rows) - len(kept)
+		outcome.rows = kept
 	}
 
 	// Write results to file if output file specified
 	if opts.OutputFile != "" {
-		if err := s.fileService.WriteJSON(opts.OutputFile, transformedData); err != nil {
+		if err := s.fileService.WriteRows(opts.OutputFile, outcome.rows, opts.Output); err != nil {
 			return nil, fmt.Errorf("failed to write transformed data: %w", err)
 		}
 	}
 
+	for _, stats := range opts.errors {
+		if stats.Rows > 0 {
+			s.logger.Warn().Str("rule", stats.Rule).Str("field", stats.Field).Str("policy", stats.Policy).
+				Int("rows", stats.Rows).Str("first_error", stats.FirstError).Msg("Rule could not transform rows")
+			outcome.errors = append(outcome.errors, stats)
+		}
+	}
+
 	s.logger.Info().
 		Int("input_records", len(input)).
-		Int("output_records", len(transformedData)).
+		Int("output_records", len(outcome.rows)).
+		Int("skipped_records", outcome.skipped).
+		Int("exploded_records", outcome.exploded).
+		Int("dropped_records", outcome.dropped).
 		Int("rules", len(opts.Rules)).
 		Msg("Data transformation completed")
 
-	return transformedData, nil
+	return outcome, nil
 }
 
 // GetName returns the processor name.
@@ -114,6 +233,10 @@ func (s *TransformService) GetDescription() string {
 }
 
 // parseTransformOptions parses transformation options from map.
+//
+// Rules are not chained unless "chained" is true: each rule reads the original row, as in earlier
+// versions. Chaining becomes the default in the next minor version, set "chained" to false to keep
+// reading the original row then.
 func (s *TransformService) parseTransformOptions(options map[string]interface{}) (*TransformOptions, error) {
 	opts := &TransformOptions{
 		KeepFields: true, // default to keeping all fields
@@ -135,28 +258,238 @@ func (s *TransformService) parseTransformOptions(options map[string]interface{})
 		opts.DropNulls = dropNulls
 	}
 
-	// Parse transformation rules
-	if rulesRaw, ok := options["rules"].([]interface{}); ok {
-		for _, ruleRaw := range rulesRaw {
-			if ruleMap, ok := ruleRaw.(map[string]interface{}); ok {
-				rule := TransformRule{
-					Field:       s.getString(ruleMap, "field"),
-					Operation:   TransformOperation(s.getString(ruleMap, "operation")),
-					TargetField: s.getString(ruleMap, "target_field"),
-				}
+	if chained, ok := options["chained"].(bool); ok {
+		opts.Chained = chained
+	}
 
-				if params, ok := ruleMap["parameters"].(map[string]interface{}); ok {
-					rule.Parameters = params
-				}
+	outputOpts, err := parseOutputOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	opts.Output = outputOpts
 
-				opts.Rules = append(opts.Rules, rule)
-			}
+	rules, err := s.parseTransformRules(options["rules"])
+	if err != nil {
+		return nil, err
+	}
+	opts.Rules = rules
+	opts.errors = make([]TransformErrorStats, len(rules))
+	for i, rule := range rules {
+		opts.errors[i] = TransformErrorStats{
+			Rule:      fmt.Sprintf("rules[%d]", i),
+			Field:     ruleFieldName(rule),
+			Operation: string(rule.Operation),
+			Policy:    rule.onError,
 		}
 	}
 
 	return opts, nil
 }
 
+// ruleParser parses the parameters of a transformation rule into the value its operation applies.
+type ruleParser func(s *TransformService, rule TransformRule) (interface{}, error)
+
+// ruleParsers parse the parameters of the operations that have some, by operation. The apply
+// functions of the operations read the parsed value back with a type assertion.
+var ruleParsers = map[TransformOperation]ruleParser{
+	Hash: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseHashFormat(rule.Parameters, rule.Field)
+	},
+	Mask: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseMaskFormat(rule.Parameters, rule.Field)
+	},
+	Pad:      parseWidthRule,
+	Truncate: parseWidthRule,
+	Lookup: func(s *TransformService, rule TransformRule) (interface{}, error) {
+		return s.parseLookupMapping(rule)
+	},
+	Template: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseRowTemplate(rule)
+	},
+	Calculate: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseCalculation(rule)
+	},
+	Round: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseNumberOutput(rule, 0)
+	},
+	RowNumber: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseRowSequence(rule)
+	},
+	UUID: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseUUIDGenerator(rule)
+	},
+	Cast: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseTypeCast(rule)
+	},
+	TitleCase: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseTitleCasing(rule)
+	},
+	SnakeCase: parseCasingRule,
+	CamelCase: parseCasingRule,
+	KebabCase: parseCasingRule,
+	Slugify:   parseCasingRule,
+	// split rules without target fields have nothing to parse
+	Split: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		split, err := parseFieldSplit(rule)
+		if split == nil {
+			return nil, err
+		}
+		return split, err
+	},
+	Explode: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseFieldExplosion(rule)
+	},
+	Phonetic: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parsePhoneticEncoding(rule)
+	},
+	RegexReplace: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseRegexRewrite(rule)
+	},
+	Base64Encode:  parseCodecRule,
+	Base64Decode:  parseCodecRule,
+	HexEncode:     parseCodecRule,
+	HexDecode:     parseCodecRule,
+	URLEncode:     parseURLRuleParameters,
+	URLDecode:     parseURLRuleParameters,
+	URLQueryParam: parseURLRuleParameters,
+	URLComponent:  parseURLRuleParameters,
+	JSONExtract: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseJSONExtraction(rule)
+	},
+	ConvertUnit: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseUnitConversion(rule)
+	},
+	FormatNumber:      parseLocalizedNumberRule,
+	ParseLocaleNumber: parseLocalizedNumberRule,
+	ParseCurrency: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseCurrencyAmount(rule)
+	},
+	DateAdd: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseDateShift(rule)
+	},
+	DateDiff: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseDateDifference(rule)
+	},
+	TrimChars: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseCharTrim(rule)
+	},
+	UnicodeNormalize: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseUnicodeForm(rule)
+	},
+	RemoveDiacritics: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseDiacriticsRemoval(rule)
+	},
+	Concat: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseConcatFormat(rule)
+	},
+	FormatDate: func(_ *TransformService, rule TransformRule) (interface{}, error) {
+		return parseDateFormat(rule)
+	},
+	Extract: parseExtractPattern,
+}
+
+func parseWidthRule(_ *TransformService, rule TransformRule) (interface{}, error) {
+	return parseWidthFormat(rule)
+}
+
+func parseCasingRule(_ *TransformService, rule TransformRule) (interface{}, error) {
+	return parseCasing(rule, caseStyles[rule.Operation])
+}
+
+func parseCodecRule(_ *TransformService, rule TransformRule) (interface{}, error) {
+	return parseByteCodec(rule)
+}
+
+func parseURLRuleParameters(_ *TransformService, rule TransformRule) (interface{}, error) {
+	return parseURLRule(rule)
+}
+
+func parseLocalizedNumberRule(_ *TransformService, rule TransformRule) (interface{}, error) {
+	return parseLocalizedNumber(rule)
+}
+
+// parseExtractPattern compiles the pattern of an "extract" rule, rules without one extracting nothing.
+func parseExtractPattern(_ *TransformService, rule TransformRule) (interface{}, error) {
+	pattern, ok := rule.Parameters["pattern"].(string)
+	if !ok {
+		return nil, nil
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern %q on field '%s': %w", pattern, rule.Field, err)
+	}
+	return regex, nil
+}
+
+// parseTransformRules parses transformation rules, either typed or decoded from generic JSON,
+// then compiles their patterns and parses the parameters of their operations once, instead of once
+// per row, failing on invalid ones. Lookup rules load their mappings.
+func (s *TransformService) parseTransformRules(raw interface{}) ([]TransformRule, error) {
+	rules := s.decodeTransformRules(raw)
+	for i := range rules {
+		if err := checkTransformRule(&rules[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range rules {
+		parse, ok := ruleParsers[rules[i].Operation]
+		if !ok {
+			continue
+		}
+		parsed, err := parse(s, rules[i])
+		if err != nil {
+			return nil, err
+		}
+		rules[i].parsed = parsed
+	}
+
+	return rules, nil
+}
+
+// decodeTransformRules returns transformation rules, either typed or decoded from generic JSON.
+func (s *TransformService) decodeTransformRules(raw interface{}) []TransformRule {
+	if typed, ok := raw.([]TransformRule); ok {
+		return append([]TransformRule(nil), typed...)
+	}
+	rulesRaw, _ := raw.([]interface{})
+	var rules []TransformRule
+	for _, ruleRaw := range rulesRaw {
+		if ruleMap, ok := ruleRaw.(map[string]interface{}); ok {
+			rule := TransformRule{
+				Field:       s.getString(ruleMap, "field"),
+				Operation:   TransformOperation(s.getString(ruleMap, "operation")),
+				TargetField: s.getString(ruleMap, "target_field"),
+			}
+
+			if params, ok := ruleMap["parameters"].(map[string]interface{}); ok {
+				rule.Parameters = params
+			}
+
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// checkTransformRule checks the parameters every operation shares, and parses the on_error policy of a rule.
+func checkTransformRule(rule *TransformRule) error {
+	if (rule.Operation == Rename || rule.Operation == Copy) && rule.TargetField == "" {
+		return fmt.Errorf("%s rule on field '%s' needs a target_field", rule.Operation, rule.Field)
+	}
+	if _, ok := rule.Parameters["value"].(string); rule.Operation == Default && !ok {
+		return fmt.Errorf("default rule on field '%s' needs a string value", rule.Field)
+	}
+	switch source := rule.Parameters[RuleSourceParameter]; source {
+	case nil, RuleSourceCurrent, RuleSourceOriginal:
+	default:
+		return fmt.Errorf("invalid source %v of %s rule on field '%s': expected current or original", source, rule.Operation, rule.Field)
+	}
+	var err error
+	rule.onError, err = parseOnError(*rule)
+	return err
+}
+
 // getString helper to safely get string from map.
 func (s *TransformService) getString(m map[string]interface{}, key string) string {
 	if val, ok := m[key].(string); ok {
@@ -165,75 +498,631 @@ func (s *TransformService) getString(m map[string]interface{}, key string) strin
 	return ""
 }
 
-// transformData performs the actual transformations.
-func (s *TransformService) transformData(data []DataRow, opts *TransformOptions) []DataRow {
+// transformData performs the actual transformations, returning the transformed rows and the
+// number of rows left out by rules with the skip_row on_error policy and of rows added by explode
+// rules.
+func (s *TransformService) transformData(data []DataRow, opts *TransformOptions) ([]DataRow, int, int, error) {
 	transformedData := []DataRow{}
+	skipped, exploded := 0, 0
 
-	for _, row := range data {
-		transformedRow := s.transformRow(row, opts)
-		transformedData = append(transformedData, transformedRow)
+	for i, row := range data {
+		transformedRows, rowSkipped, err := s.transformRow(row, i, opts)
+		if err != nil {
+			return nil, skipped, exploded, err
+		}
+		// every row starts as one, explode rules add rows and skip_row policies remove them
+		skipped += rowSkipped
+		exploded += len(transformedRows) + rowSkipped - 1
+		transformedData = append(transformedData, transformedRows...)
 	}
 
-	return transformedData
+	return transformedData, skipped, exploded, nil
 }
 
-// transformRow transforms a single row based on rules.
-func (s *TransformService) transformRow(row DataRow, opts *TransformOptions) DataRow {
-	transformedRow := DataRow{Fields: make(map[string]string)}
+// rowState is a row being transformed: the current row, read and written by the rules, and the
+// transformed row written to the output.
+type rowState struct {
+	current     DataRow
+	transformed DataRow
+}
+
+// transformRow transforms a single row based on rules, returning the rows it becomes, several
+// once exploded, and the number of them left out. Values rules cannot transform are handled by
+// the on_error policy of the rules, failing the transformation or leaving the row out, and
+// counted in the errors of the options. Lookup rules with the error default policy and calculate
+// rules with the error divide-by-zero policy fail as well.
+func (s *TransformService) transformRow(row DataRow, index int, opts *TransformOptions) ([]DataRow, int, error) {
+	transformedRow := DataRow{
+		Fields:     make(map[string]string),
+		LineNumber: row.LineNumber,
+		SourceFile: row.SourceFile,
+	}
 
-	// Copy original fields if keeping fields
+	// Copy original fields if keeping fields, preserving their column order
 	if opts.KeepFields {
-		for field, value := range row.Fields {
-			transformedRow.Fields[field] = value
+		for _, field := range row.Keys() {
+			transformedRow.SetField(field, row.Fields[field])
 		}
 	}
 
-	// Apply transformation rules
-	for _, rule := range opts.Rules {
-		result := s.applyTransformRule(row, rule)
-		targetField := rule.TargetField
-		if targetField == "" {
-			targetField = rule.Field
+	// Apply transformation rules, new target fields are appended in rule order. Chained rules read
+	// the current row, holding the results of the rules before them whether kept or not, while
+	// unchained rules and rules with "source": "original" read the original row. Rename, drop,
+	// split and explode rules always act on the current row.
+	current := DataRow{Fields: maps.Clone(row.Fields), LineNumber: row.LineNumber, SourceFile: row.SourceFile}
+	states := []rowState{{current: current, transformed: transformedRow}}
+	// json_extract rules on the same field share its parsed JSON
+	parsedFields := make(map[string]*parsedJSON)
+	skipped := 0
+	for i, rule := range opts.Rules {
+		if rule.Operation == Explode {
+			states = s.explodeRows(states, rule)
+			continue
+		}
+
+		kept := states[:0]
+		for _, state := range states {
+			keep, err := s.applyRuleToRow(&state, row, index, i, rule, opts, parsedFields)
+			if err != nil {
+				return nil, skipped, err
+			}
+			if !keep {
+				skipped++
+				continue
+			}
+			kept = append(kept, state)
 		}
-		transformedRow.Fields[targetField] = result
+		states = kept
 	}
 
-	return transformedRow
+	rows := make([]DataRow, 0, len(states))
+	for _, state := range states {
+		rows = append(rows, state.transformed)
+	}
+	return rows, skipped, nil
 }
 
-// applyTransformRule applies a single transformation rule.
-func (s *TransformService) applyTransformRule(row DataRow, rule TransformRule) string {
-	fieldValue, exists := row.Fields[rule.Field]
-	if !exists {
-		return ""
+// applyRuleToRow applies the rule at a position of the options to a row being transformed from
+// the original row at an index of the input, reporting whether the row is kept.
+func (s *TransformService) applyRuleToRow(state *rowState, row DataRow, index int, position int, rule TransformRule, opts *TransformOptions, parsedFields map[string]*parsedJSON) (bool, error) {
+	if rule.Operation == Rename || rule.Operation == Drop {
+		s.moveField(&state.current, &state.transformed, rule)
+		return true, nil
+	}
+	if _, ok := rule.parsed.(*fieldSplit); ok {
+		s.splitField(&state.current, &state.transformed, rule)
+		return true, nil
+	}
+
+	targetField := rule.TargetField
+	if targetField == "" {
+		targetField = rule.Field
+	}
+
+	input := state.current
+	if !opts.Chained || rule.Parameters[RuleSourceParameter] == RuleSourceOriginal {
+		input = row
 	}
 
+	result, err := s.applyTransformRule(input, index, rule, parsedFields)
+	transformed := err == nil
+	if isUntransformable(err) {
+		var kept bool
+		if result, kept, err = s.applyOnError(err, input, row, position, rule, opts); !kept {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", row.Location(), err)
+	}
+	state.set(targetField, result)
+	if amount, ok := rule.parsed.(*currencyAmount); ok && amount.codeField != "" {
+		code := ""
+		if value := input.Fields[rule.Field]; transformed && value != "" {
+			// amounts that could not be read have no currency
+			_, code, _ = amount.read(value)
+		}
+		state.set(amount.codeField, code)
+	}
+	return true, nil
+}
+
+// set writes the value of a field to the current row and the transformed row.
+func (r *rowState) set(field, value string) {
+	r.current.Fields[field] = value
+	r.transformed.SetField(field, value)
+}
+
+// applyOnError applies the on_error policy of the rule at a position of the options to a value
+// it could not transform, recording the error. It returns the value written in its place, or the
+// error when the policy is to fail, and whether the row is kept.
+func (s *TransformService) applyOnError(err error, input, row DataRow, position int, rule TransformRule, opts *TransformOptions) (string, bool, error) {
+	policy := rule.onError
+	if policy == "" {
+		policy, _ = parseOnError(rule)
+	}
+	s.logger.Debug().Err(err).Str("policy", policy).Str("location", row.Location()).Msg("Rule could not transform value")
+	if position < len(opts.errors) {
+		opts.errors[position].record(row.Location(), err)
+	}
+
+	switch policy {
+	case OnErrorKeep:
+		return input.Fields[ruleFieldName(rule)], true, nil
+	case OnErrorEmpty:
+		return "", true, nil
+	case OnErrorSkipRow:
+		return "", false, nil
+	default:
+		return "", true, err
+	}
+}
+
+// moveField applies a rename or drop rule to the current row and the transformed row.
+// Renamed fields keep their column.
+func (s *TransformService) moveField(current, transformedRow *DataRow, rule TransformRule) {
+	value, exists := current.Fields[rule.Field]
+	delete(current.Fields, rule.Field)
 	//nolint:exhaustive
 	switch rule.Operation {
-	case UpperCase:
-		return strings.ToUpper(fieldValue)
-	case LowerCase:
-		return strings.ToLower(fieldValue)
-	case TitleCase:
-		return strings.Title(strings.ToLower(fieldValue)) //nolint:staticcheck
-	case Trim:
-		return strings.TrimSpace(fieldValue)
-	case Replace:
-		return s.applyReplace(fieldValue, rule.Parameters)
-	case Extract:
-		return s.applyExtract(fieldValue, rule.Parameters)
-	case Split:
-		return s.applySplit(fieldValue, rule.Parameters)
-	case Join:
-		return s.applyJoin(fieldValue, rule.Parameters)
-	case Calculate:
-		return s.applyCalculate(fieldValue, rule.Parameters)
-	case Conditional:
-		return s.applyConditional(row, rule.Parameters)
-	default:
-		s.logger.Warn().Str("operation", string(rule.Operation)).Msg("Unknown transform operation")
-		return fieldValue
+	case Rename:
+		if exists {
+			current.Fields[rule.TargetField] = value
+		}
+		switch {
+		case transformedRow.RenameField(rule.Field, rule.TargetField):
+		case exists:
+			// the field was left out with keep_fields false
+			transformedRow.SetField(rule.TargetField, value)
+		default:
+			s.logger.Debug().Str("field", rule.Field).Str("location", current.Location()).Msg("Renamed field does not exist")
+		}
+	case Drop:
+		if !transformedRow.RemoveField(rule.Field) && !exists {
+			s.logger.Debug().Str("field", rule.Field).Str("location", current.Location()).Msg("Dropped field does not exist")
+		}
+	}
+}
+
+// splitField writes the parts of the field of a split rule with target fields to the current row
+// and the transformed row. Missing fields split into no part.
+func (s *TransformService) splitField(current, transformedRow *DataRow, rule TransformRule) {
+	split, _ := rule.parsed.(*fieldSplit)
+	var parts []string
+	if value, exists := current.Fields[rule.Field]; exists {
+		parts = split.parts(value)
+	}
+	for i, target := range split.targets {
+		part := ""
+		if i < len(parts) {
+			part = parts[i]
+		} else if split.missing == SplitSkip {
+			continue
+		}
+		current.Fields[target] = part
+		transformedRow.SetField(target, part)
+	}
+}
+
+// explodeRows splits the rows being transformed into one row per part of the field of an explode
+// rule, copying their other fields.
+func (s *TransformService) explodeRows(states []rowState, rule TransformRule) []rowState {
+	targetField := rule.TargetField
+	if targetField == "" {
+		targetField = rule.Field
+	}
+
+	explosion, _ := rule.parsed.(*fieldExplosion)
+	exploded := make([]rowState, 0, len(states))
+	for _, state := range states {
+		parts := explosion.parts(state.current.Fields[rule.Field])
+		numbered := len(parts) > 0
+		if !numbered {
+			// values without parts keep their row
+			parts = []string{""}
+		}
+		for i, part := range parts {
+			// each row gets its own fields, columns being copied on write
+			child := state
+			if len(parts) > 1 {
+				child.current.Fields = maps.Clone(state.current.Fields)
+				child.transformed.Fields = maps.Clone(state.transformed.Fields)
+			}
+			child.current.Fields[targetField] = part
+			child.transformed.SetField(targetField, part)
+			if explosion.indexField != "" {
+				index := ""
+				if numbered {
+					index = strconv.Itoa(i + 1)
+				}
+				child.current.Fields[explosion.indexField] = index
+				child.transformed.SetField(explosion.indexField, index)
+			}
+			exploded = append(exploded, child)
+		}
+	}
+	return exploded
+}
+
+// deriveRow returns a copy of the row at an index of the input with the results of the rules
+// added, leaving the row itself untouched, and reports whether no rule with the skip_row
+// on_error policy left it out. Rules must come from parseTransformRules, without explode rules.
+func (s *TransformService) deriveRow(row DataRow, index int, rules []TransformRule) (DataRow, bool, error) {
+	rows, _, err := s.transformRow(row, index, &TransformOptions{Rules: rules, KeepFields: true, Chained: true})
+	if err != nil || len(rows) == 0 {
+		return row, false, err
+	}
+	return rows[0], true, nil
+}
+
+// fieldOperation applies a rule to the value of its field in a row. json_extract rules share the
+// JSON of the fields they parse in parsedFields.
+type fieldOperation func(s *TransformService, row DataRow, value string, rule TransformRule, parsedFields map[string]*parsedJSON) (string, error)
+
+// fieldOperations apply the operations transforming a field, by operation.
+var fieldOperations = map[TransformOperation]fieldOperation{
+	UpperCase:           textOperation(strings.ToUpper),
+	LowerCase:           textOperation(strings.ToLower),
+	Trim:                textOperation(strings.TrimSpace),
+	NormalizeWhitespace: textOperation(normalizeWhitespace),
+	Copy:                textOperation(func(value string) string { return value }),
+	TitleCase:           valueOperation((*TransformService).applyTitleCase),
+	Phonetic:            valueOperation((*TransformService).applyPhonetic),
+	Round:               valueOperation((*TransformService).applyRound),
+	ConvertUnit:         valueOperation((*TransformService).applyConvertUnit),
+	FormatNumber:        valueOperation((*TransformService).applyLocalizedNumber),
+	ParseLocaleNumber:   valueOperation((*TransformService).applyLocalizedNumber),
+	ParseCurrency:       valueOperation((*TransformService).applyParseCurrency),
+	Extract:             valueOperation((*TransformService).applyExtract),
+	FormatDate:          valueOperation((*TransformService).applyFormatDate),
+	DateAdd:             valueOperation((*TransformService).applyDateAdd),
+	TrimChars:           infallibleOperation((*TransformService).applyTrimChars),
+	UnicodeNormalize:    infallibleOperation((*TransformService).applyUnicodeNormalize),
+	RemoveDiacritics:    infallibleOperation((*TransformService).applyRemoveDiacritics),
+	Hash:                infallibleOperation((*TransformService).applyHash),
+	Mask:                infallibleOperation((*TransformService).applyMask),
+	Pad:                 infallibleOperation((*TransformService).applyWidth),
+	Truncate:            infallibleOperation((*TransformService).applyWidth),
+	SnakeCase:           infallibleOperation((*TransformService).applyCasing),
+	CamelCase:           infallibleOperation((*TransformService).applyCasing),
+	KebabCase:           infallibleOperation((*TransformService).applyCasing),
+	Slugify:             infallibleOperation((*TransformService).applyCasing),
+	RegexReplace:        infallibleOperation((*TransformService).applyRegexReplace),
+	Calculate:           rowOperation((*TransformService).applyCalculate),
+	Cast:                rowOperation((*TransformService).applyCast),
+	Lookup:              rowOperation((*TransformService).applyLookup),
+	Base64Encode:        rowOperation((*TransformService).applyCodec),
+	Base64Decode:        rowOperation((*TransformService).applyCodec),
+	HexEncode:           rowOperation((*TransformService).applyCodec),
+	HexDecode:           rowOperation((*TransformService).applyCodec),
+	URLEncode:           rowOperation((*TransformService).applyURL),
+	URLDecode:           rowOperation((*TransformService).applyURL),
+	URLQueryParam:       rowOperation((*TransformService).applyURL),
+	URLComponent:        rowOperation((*TransformService).applyURL),
+	DateDiff: func(s *TransformService, row DataRow, value string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return s.applyDateDiff(row, value, rule)
+	},
+	JSONExtract: func(s *TransformService, row DataRow, _ string, rule TransformRule, parsedFields map[string]*parsedJSON) (string, error) {
+		return s.applyJSONExtract(row, rule, parsedFields)
+	},
+	Replace: func(s *TransformService, _ DataRow, value string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return s.applyReplace(value, rule.Parameters), nil
+	},
+	Split: func(s *TransformService, _ DataRow, value string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return s.applySplit(value, rule.Parameters), nil
+	},
+	Join: func(s *TransformService, _ DataRow, value string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return s.applyJoin(value, rule.Parameters), nil
+	},
+	Conditional: func(s *TransformService, row DataRow, _ string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return s.applyConditional(row, rule.Parameters), nil
+	},
+}
+
+// textOperation applies a function of the value alone.
+func textOperation(apply func(value string) string) fieldOperation {
+	return func(_ *TransformService, _ DataRow, value string, _ TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return apply(value), nil
+	}
+}
+
+// valueOperation applies a rule to the value alone.
+func valueOperation(apply func(s *TransformService, value string, rule TransformRule) (string, error)) fieldOperation {
+	return func(s *TransformService, _ DataRow, value string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return apply(s, value, rule)
+	}
+}
+
+// infallibleOperation applies a rule to the value alone, never failing.
+func infallibleOperation(apply func(s *TransformService, value string, rule TransformRule) string) fieldOperation {
+	return func(s *TransformService, _ DataRow, value string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return apply(s, value, rule), nil
+	}
+}
+
+// rowOperation applies a rule reading the field from the row.
+func rowOperation(apply func(s *TransformService, row DataRow, rule TransformRule) (string, error)) fieldOperation {
+	return func(s *TransformService, row DataRow, _ string, rule TransformRule, _ map[string]*parsedJSON) (string, error) {
+		return apply(s, row, rule)
+	}
+}
+
+// applyTransformRule applies a single transformation rule to the row at an index of the input, from 0.
+// The rule must come from parseTransformRules, which parses its parameters. json_extract rules
+// share the JSON of the fields they parse in parsedFields. Rules that cannot transform a value
+// return an untransformableError.
+func (s *TransformService) applyTransformRule(row DataRow, index int, rule TransformRule, parsedFields map[string]*parsedJSON) (string, error) {
+	// Concat, row number, uuid and template rules do not transform a field
+	//nolint:exhaustive
+	switch rule.Operation {
+	case Concat:
+		return s.applyConcat(row, rule), nil
+	case RowNumber:
+		return s.applyRowNumber(index, rule), nil
+	case UUID:
+		return s.applyUUID(row, rule), nil
+	case Template:
+		return s.applyTemplate(row, rule)
+	}
+
+	fieldValue, exists := row.Fields[rule.Field]
+	if rule.Operation == Default {
+		return s.applyDefault(fieldValue, exists, rule.Parameters), nil
+	}
+	if !exists {
+		return "", nil
+	}
+
+	apply, ok := fieldOperations[rule.Operation]
+	if !ok {
+		s.logger.Warn().Str("operation", string(rule.Operation)).Str("location", row.Location()).Msg("Unknown transform operation")
+		return fieldValue, nil
+	}
+	return apply(s, row, fieldValue, rule, parsedFields)
+}
+
+// applyHash pseudonymizes a value.
+func (s *TransformService) applyHash(value string, rule TransformRule) string {
+	format, _ := rule.parsed.(*hashFormat)
+	return format.hash(value)
+}
+
+// applyMask hides a value.
+func (s *TransformService) applyMask(value string, rule TransformRule) string {
+	format, _ := rule.parsed.(*maskFormat)
+	return format.mask(value)
+}
+
+// applyWidth pads or truncates a value.
+func (s *TransformService) applyWidth(value string, rule TransformRule) string {
+	format, _ := rule.parsed.(*widthFormat)
+	if rule.Operation == Truncate {
+		return format.truncate(value)
+	}
+	return format.pad(value)
+}
+
+// applyLookup translates a value with the mapping of a rule.
+func (s *TransformService) applyLookup(row DataRow, rule TransformRule) (string, error) {
+	mapping, _ := rule.parsed.(*lookupMapping)
+	value, err := mapping.lookup(row.Fields[rule.Field])
+	if err != nil {
+		return "", fmt.Errorf("lookup rule on field '%s': %w", rule.Field, err)
 	}
+	return value, nil
+}
+
+// applyTemplate renders the template of a rule over a row.
+func (s *TransformService) applyTemplate(row DataRow, rule TransformRule) (string, error) {
+	tmpl, _ := rule.parsed.(*rowTemplate)
+	return tmpl.render(row, rule.TargetField)
+}
+
+// applyCasing converts a value to a case style.
+func (s *TransformService) applyCasing(value string, rule TransformRule) string {
+	c, _ := rule.parsed.(*casing)
+	return c.convert(value)
+}
+
+// applyRound rounds a number. Empty values are kept.
+func (s *TransformService) applyRound(value string, rule TransformRule) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	number, ok := parseDecimal(strings.TrimSpace(value), true)
+	if !ok {
+		return "", untransformable("round rule on field '%s': value %q is not a number", rule.Field, value)
+	}
+	output, _ := rule.parsed.(numberOutput)
+	return output.format(number), nil
+}
+
+// applyConvertUnit converts a number between the units of a rule. Empty values are kept.
+func (s *TransformService) applyConvertUnit(value string, rule TransformRule) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	number, ok := parseDecimal(strings.TrimSpace(value), true)
+	if !ok {
+		return "", untransformable("convert_unit rule on field '%s': value %q is not a number", rule.Field, value)
+	}
+	conversion, _ := rule.parsed.(*unitConversion)
+	return conversion.convert(number), nil
+}
+
+// applyLocalizedNumber writes or reads a number the way the locale of a rule does. Empty values are kept.
+func (s *TransformService) applyLocalizedNumber(value string, rule TransformRule) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	number, _ := rule.parsed.(*localizedNumber)
+	convert := number.read
+	if rule.Operation == FormatNumber {
+		convert = number.write
+	}
+	result, ok := convert(value)
+	if !ok {
+		return "", untransformable("%s rule on field '%s': value %q is not a number", rule.Operation, rule.Field, value)
+	}
+	return result, nil
+}
+
+// applyParseCurrency reads an amount. Empty values are kept.
+func (s *TransformService) applyParseCurrency(value string, rule TransformRule) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	amount, _ := rule.parsed.(*currencyAmount)
+	result, _, ok := amount.read(value)
+	if !ok {
+		return "", untransformable("parse_currency rule on field '%s': value %q is not an amount", rule.Field, value)
+	}
+	return result, nil
+}
+
+// applyCodec encodes or decodes a value.
+func (s *TransformService) applyCodec(row DataRow, rule TransformRule) (string, error) {
+	value, exists := row.Fields[rule.Field]
+	if !exists {
+		return "", nil
+	}
+	codec, _ := rule.parsed.(*byteCodec)
+	return codec.apply(value, rule.Field)
+}
+
+// applyURL encodes, decodes or parses a URL value.
+func (s *TransformService) applyURL(row DataRow, rule TransformRule) (string, error) {
+	value, exists := row.Fields[rule.Field]
+	if !exists {
+		return "", nil
+	}
+	parsed, _ := rule.parsed.(*urlRule)
+	return parsed.apply(value, rule.Field)
+}
+
+// applyJSONExtract extracts a value from a field holding JSON, parsing the JSON unless an earlier
+// rule of the row did.
+func (s *TransformService) applyJSONExtract(row DataRow, rule TransformRule, parsedFields map[string]*parsedJSON) (string, error) {
+	value, exists := row.Fields[rule.Field]
+	if !exists {
+		return "", nil
+	}
+	extraction, _ := rule.parsed.(*jsonExtraction)
+	return extraction.extract(parseFieldJSON(parsedFields, rule.Field, value), rule.Field)
+}
+
+// applyCast converts a value to the type of a rule.
+func (s *TransformService) applyCast(row DataRow, rule TransformRule) (string, error) {
+	value, exists := row.Fields[rule.Field]
+	if !exists {
+		return "", nil
+	}
+	cast, _ := rule.parsed.(*typeCast)
+	return cast.cast(value, rule.Field)
+}
+
+// applyRowNumber numbers the row at an index.
+func (s *TransformService) applyRowNumber(index int, rule TransformRule) string {
+	sequence, _ := rule.parsed.(*rowSequence)
+	return sequence.number(index)
+}
+
+// applyUUID generates the UUID of a row.
+func (s *TransformService) applyUUID(row DataRow, rule TransformRule) string {
+	generator, _ := rule.parsed.(*uuidGenerator)
+	return generator.generate(row, rule.Field)
+}
+
+// applyDefault returns the "value" parameter for a missing or empty field, or only for a missing
+// one with "only_missing".
+func (s *TransformService) applyDefault(value string, exists bool, params map[string]interface{}) string {
+	onlyMissing, _ := params["only_missing"].(bool)
+	if !exists || (value == "" && !onlyMissing) {
+		defaultValue, _ := params["value"].(string)
+		return defaultValue
+	}
+	return value
+}
+
+// applyConcat combines fields into the target field of a rule.
+func (s *TransformService) applyConcat(row DataRow, rule TransformRule) string {
+	format, _ := rule.parsed.(*concatFormat)
+	return format.concat(row)
+}
+
+// applyFormatDate converts a date to the output format of a rule. Empty values are kept.
+func (s *TransformService) applyFormatDate(value string, rule TransformRule) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	format, _ := rule.parsed.(*dateFormat)
+	formatted, ok := format.format(value)
+	if !ok {
+		return "", untransformable("format_date rule on field '%s': value %q is not a date", rule.Field, value)
+	}
+	return formatted, nil
+}
+
+// applyTrimChars trims the cutset of a rule from a value.
+func (s *TransformService) applyTrimChars(value string, rule TransformRule) string {
+	trim, _ := rule.parsed.(*charTrim)
+	return trim.trim(value)
+}
+
+// applyUnicodeNormalize normalizes a value to the Unicode form of a rule.
+func (s *TransformService) applyUnicodeNormalize(value string, rule TransformRule) string {
+	form, _ := rule.parsed.(*norm.Form)
+	return form.String(value)
+}
+
+// applyRemoveDiacritics writes a value in ASCII.
+func (s *TransformService) applyRemoveDiacritics(value string, rule TransformRule) string {
+	removal, _ := rule.parsed.(*diacriticsRemoval)
+	return removal.remove(value)
+}
+
+// applyTitleCase writes a value in title case.
+func (s *TransformService) applyTitleCase(value string, rule TransformRule) (string, error) {
+	casing, _ := rule.parsed.(*titleCasing)
+	return casing.convert(value), nil
+}
+
+// applyPhonetic writes the phonetic code of a value.
+func (s *TransformService) applyPhonetic(value string, rule TransformRule) (string, error) {
+	encoding, _ := rule.parsed.(*phoneticEncoding)
+	return encoding.encode(value), nil
+}
+
+// applyDateAdd shifts a date. Empty values are kept.
+func (s *TransformService) applyDateAdd(value string, rule TransformRule) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	shift, _ := rule.parsed.(*dateShift)
+	shifted, ok := shift.shift(value)
+	if !ok {
+		return "", untransformable("date_add rule on field '%s': value %q is not a date", rule.Field, value)
+	}
+	return shifted, nil
+}
+
+// applyDateDiff computes the time from a date to the end date of the rule. Empty values are kept.
+func (s *TransformService) applyDateDiff(row DataRow, value string, rule TransformRule) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	diff, _ := rule.parsed.(*dateDifference)
+	result, err := diff.diff(row, value, s.currentTime())
+	if err != nil {
+		return "", untransformable("date_diff rule on field '%s': %w", rule.Field, err)
+	}
+	return result, nil
+}
+
+// applyRegexReplace rewrites a value with the pattern and replacement of a rule.
+func (s *TransformService) applyRegexReplace(value string, rule TransformRule) string {
+	rewrite, _ := rule.parsed.(*regexRewrite)
+	return rewrite.rewrite(value)
 }
 
 // applyReplace applies string replacement.
@@ -249,29 +1138,26 @@ func (s *TransformService) applyReplace(value string, params map[string]interfac
 	return strings.ReplaceAll(value, oldStr, newStr)
 }
 
-// applyExtract extracts text using regex.
-func (s *TransformService) applyExtract(value string, params map[string]interface{}) string {
-	pattern, ok := params["pattern"].(string)
+// applyExtract extracts text using regex. Empty values are kept.
+func (s *TransformService) applyExtract(value string, rule TransformRule) (string, error) {
+	pattern, ok := rule.Parameters["pattern"].(string)
 	if !ok {
-		return value
+		return value, nil
 	}
-	group, ok := params["group"].(float64)
+	group, ok := rule.Parameters["group"].(float64)
 	if !ok {
 		group = 0
 	}
 
-	regex, err := regexp.Compile(pattern)
-	if err != nil {
-		s.logger.Error().Err(err).Str("pattern", pattern).Msg("Invalid regex pattern")
-		return value
+	if value == "" {
+		return "", nil
 	}
-
+	regex, _ := rule.parsed.(*regexp.Regexp)
 	matches := regex.FindStringSubmatch(value)
-	if len(matches) > int(group) {
-		return matches[int(group)]
+	if len(matches) <= int(group) {
+		return "", untransformable("extract rule on field '%s': value %q does not match %s", rule.Field, value, pattern)
 	}
-
-	return value
+	return matches[int(group)], nil
 }
 
 // applySplit splits string and optionally joins back.
@@ -313,41 +1199,6 @@ func (s *TransformService) applyJoin(value string, params map[string]interface{}
 	return strings.Join(parts, separator)
 }
 
-// applyCalculate performs mathematical calculations.
-func (s *TransformService) applyCalculate(value string, params map[string]interface{}) string {
-	operation, ok := params["operation"].(string)
-	if !ok {
-		return value
-	}
-
-	operand, ok := params["operand"].(float64)
-	if !ok {
-		return value
-	}
-
-	numValue, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		s.logger.Error().Err(err).Str("value", value).Msg("Cannot parse numeric value")
-		return value
-	}
-
-	switch operation {
-	case "add":
-		return fmt.Sprintf("%.2f", numValue+operand)
-	case "subtract":
-		return fmt.Sprintf("%.2f", numValue-operand)
-	case "multiply":
-		return fmt.Sprintf("%.2f", numValue*operand)
-	case "divide":
-		if operand != 0 {
-			return fmt.Sprintf("%.2f", numValue/operand)
-		}
-		return "0"
-	default:
-		return value
-	}
-}
-
 // applyConditional applies conditional logic.
 //
 //nolint:gocyclo
@@ -438,7 +1289,8 @@ func (s *TransformService) filterNullRows(data []DataRow) []DataRow {
 
 // TransformFile transforms data from a file
 // This convenience method demonstrates file-based transformation.
-func (s *TransformService) TransformFile(inputFile, outputFile string, rules []TransformRule, keepFields bool) (*ProcessingResult, error) {
+// extraOptions may carry any additional ProcessData option and can be nil.
+func (s *TransformService) TransformFile(inputFile, outputFile string, rules []TransformRule, keepFields bool, extraOptions map[string]interface{}) (*ProcessingResult, error) {
 	s.logger.Info().
 		Str("input", inputFile).
 		Str("output", outputFile).
@@ -446,14 +1298,14 @@ func (s *TransformService) TransformFile(inputFile, outputFile string, rules []T
 		Bool("keep_fields", keepFields).
 		Msg("Starting file transformation")
 
-	options := map[string]interface{}{
+	options := mergeOptions(map[string]interface{}{
 		"input_file":  inputFile,
 		"output_file": outputFile,
 		"rules":       rules,
 		"keep_fields": keepFields,
-	}
+	}, extraOptions)
 
-	transformedData, err := s.ProcessData(nil, options)
+	outcome, err := s.run(nil, options)
 	if err != nil {
 		return &ProcessingResult{
 			Success:   false,
@@ -464,9 +1316,13 @@ func (s *TransformService) TransformFile(inputFile, outputFile string, rules []T
 	}
 
 	return &ProcessingResult{
-		Success:    true,
-		Processed:  len(transformedData),
-		OutputPath: outputFile,
-		Processor:  s.GetName(),
+		Success:         true,
+		Processed:       len(outcome.rows),
+		Excluded:        outcome.skipped + outcome.dropped,
+		Expanded:        outcome.exploded,
+		TransformErrors: outcome.errors,
+		Warnings:        outcome.warnings(),
+		OutputPath:      outputFile,
+		Processor:       s.GetName(),
 	}, nil
 }
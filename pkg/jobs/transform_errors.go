@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Policies of transform rules for the values they cannot transform, like a calculate rule on a
+// value that is not a number, set by their "on_error" parameter.
+const (
+	OnErrorKeep    = "keep"     // write the value of the field as is, the default
+	OnErrorEmpty   = "empty"    // write an empty value
+	OnErrorSkipRow = "skip_row" // leave the row out of the output
+	OnErrorFail    = "fail"     // fail the transformation
+)
+
+// defaultOnError are the policies of the operations not keeping values by default.
+var defaultOnError = map[TransformOperation]string{
+	Template:    OnErrorFail,
+	JSONExtract: OnErrorEmpty,
+}
+
+// untransformableError is the error of a rule that cannot transform a value, handled by the
+// on_error policy of the rule, rather than failing the transformation.
+type untransformableError struct {
+	err error
+}
+
+// Error implements error.
+func (e *untransformableError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the reason the value could not be transformed.
+func (e *untransformableError) Unwrap() error {
+	return e.err
+}
+
+// untransformable returns the error of a rule that cannot transform a value, see untransformableError.
+func untransformable(format string, args ...interface{}) error {
+	return &untransformableError{err: fmt.Errorf(format, args...)}
+}
+
+// isUntransformable reports whether an error is the one of a rule that cannot transform a value.
+func isUntransformable(err error) bool {
+	var untransformableErr *untransformableError
+	return errors.As(err, &untransformableErr)
+}
+
+// parseOnError parses the on_error policy of a rule, defaulting to the one of its operation.
+func parseOnError(rule TransformRule) (string, error) {
+	switch policy, _ := rule.Parameters["on_error"].(string); policy {
+	case "":
+		if policy, ok := defaultOnError[rule.Operation]; ok {
+			return policy, nil
+		}
+		return OnErrorKeep, nil
+	case OnErrorKeep, OnErrorEmpty, OnErrorSkipRow, OnErrorFail:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid on_error %q of %s rule on field '%s': expected keep, empty, skip_row or fail", policy, rule.Operation, ruleFieldName(rule))
+	}
+}
+
+// ruleFieldName returns the field errors of a rule name, its target field for the rules without
+// a field, like template rules.
+func ruleFieldName(rule TransformRule) string {
+	if rule.Field == "" {
+		return rule.TargetField
+	}
+	return rule.Field
+}
+
+// TransformErrorStats counts the rows a transform rule could not transform, handled by its on_error policy.
+type TransformErrorStats struct {
+	Rule       string `json:"rule"` // position of the rule, like "rules[2]"
+	Field      string `json:"field"`
+	Operation  string `json:"operation"`
+	Policy     string `json:"policy"` // on_error policy applied
+	Rows       int    `json:"rows"`
+	FirstError string `json:"first_error"` // error of the first row, with its location
+}
+
+// record counts a row a rule could not transform.
+func (es *TransformErrorStats) record(location string, err error) {
+	if es.Rows == 0 {
+		es.FirstError = fmt.Sprintf("%s: %v", location, err)
+	}
+	es.Rows++
+}
+
+// String describes the errors of the rule.
+func (es TransformErrorStats) String() string {
+	return fmt.Sprintf("%s %s on field '%s': %d rows could not be transformed (on_error %s), first at %s",
+		es.Rule, es.Operation, es.Field, es.Rows, es.Policy, es.FirstError)
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_onError(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"name", "amount"}, []string{"a", "10"}, []string{"b", "n/a"}, []string{"c", ""}, []string{"d", "x"})
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{policy: "", want: []string{"10.00", "n/a", "", "x"}},
+		{policy: OnErrorKeep, want: []string{"10.00", "n/a", "", "x"}},
+		{policy: OnErrorEmpty, want: []string{"10.00", "", "", ""}},
+		{policy: OnErrorSkipRow, want: []string{"10.00", ""}},
+	}
+	for _, tt := range tests {
+		params := map[string]interface{}{"operation": "multiply", "operand": 1}
+		if tt.policy != "" {
+			params["on_error"] = tt.policy
+		}
+		output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+			"rules": []TransformRule{{Field: "amount", Operation: Calculate, Parameters: params}},
+		})
+		is.NoError(err, tt.policy)
+
+		amounts := make([]string, 0, len(output))
+		for _, row := range output {
+			amounts = append(amounts, row.Fields["amount"])
+		}
+		is.Equal(tt.want, amounts, tt.policy)
+	}
+
+	_, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "amount", Operation: Calculate, Parameters: map[string]interface{}{
+			"operation": "multiply", "operand": 1, "on_error": OnErrorFail,
+		}}},
+	})
+	is.ErrorContains(err, `line 3: calculate rule on field 'amount': value "n/a" is not a number`)
+}
+
+func TestTransformService_onErrorKeepsTargetField(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"code", "label"}, []string{"ab", "old"})
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{
+			{Operation: Template, TargetField: "label", Parameters: map[string]interface{}{
+				"template": "{{index .code 3 | printf \"%c\"}}", "on_error": OnErrorKeep,
+			}},
+			{Field: "code", Operation: Cast, TargetField: "number", Parameters: map[string]interface{}{"to": "int"}},
+		},
+	})
+	is.NoError(err)
+	is.Equal("old", output[0].Fields["label"])
+	is.Equal("ab", output[0].Fields["number"])
+}
+
+func TestTransformService_onErrorInvalid(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"rules": []TransformRule{{Field: "amount", Operation: Round, Parameters: map[string]interface{}{"on_error": "skip"}}},
+	})
+	is.ErrorContains(err, `invalid on_error "skip" of round rule on field 'amount': expected keep, empty, skip_row or fail`)
+}
+
+func TestTransformService_TransformFile_reportsErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("name,amount,date\na,1.5,2024-01-02\nb,n/a,\nc,x,soon\n,4,2024-02-03\n"), 0o600))
+
+	result, err := newTestTransformService(t).TransformFile(input, filepath.Join(dir, "out.csv"), []TransformRule{
+		{Field: "amount", Operation: Round, Parameters: map[string]interface{}{"on_error": OnErrorSkipRow}},
+		{Field: "date", Operation: FormatDate, Parameters: map[string]interface{}{"output_format": "DD/MM/YYYY", "on_error": OnErrorEmpty}},
+		{Field: "name", Operation: UpperCase},
+	}, true, map[string]interface{}{"drop_nulls": true})
+	is.NoError(err)
+
+	// rows b and c are skipped by the round rule, the last one dropped for its empty name
+	is.Equal(1, result.Processed)
+	is.Equal(3, result.Excluded)
+	is.Equal([]TransformErrorStats{{
+		Rule:       "rules[0]",
+		Field:      "amount",
+		Operation:  "round",
+		Policy:     OnErrorSkipRow,
+		Rows:       2,
+		FirstError: input + `:3: round rule on field 'amount': value "n/a" is not a number`,
+	}}, result.TransformErrors)
+	is.Len(result.Warnings, 1)
+	is.Contains(result.Warnings[0], "2 rows could not be transformed (on_error skip_row)")
+}
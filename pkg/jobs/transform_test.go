@@ -0,0 +1,290 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransformService(t *testing.T) *TransformService {
+	t.Helper()
+
+	return &TransformService{
+		fileService: newTestFileService(t),
+		logger:      zerolog.Nop(),
+	}
+}
+
+func TestTransformService_appendsTargetFieldsInRuleOrder(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	service := newTestTransformService(t)
+	input := []DataRow{
+		{Fields: map[string]string{"name": "john", "email": "JOHN@EXAMPLE.COM"}, Columns: []string{"name", "email"}},
+	}
+
+	output, err := service.ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{
+			{Field: "name", Operation: UpperCase, TargetField: "name_upper"},
+			{Field: "email", Operation: LowerCase},
+			{Field: "name", Operation: TitleCase, TargetField: "display_name"},
+		},
+	})
+	is.NoError(err)
+	is.Len(output, 1)
+	is.Equal([]string{"name", "email", "name_upper", "display_name"}, output[0].Keys())
+	is.Equal("john@example.com", output[0].Fields["email"])
+
+	output, err = service.ProcessData(input, map[string]interface{}{
+		"keep_fields": false,
+		"rules": []TransformRule{
+			{Field: "email", Operation: LowerCase, TargetField: "z"},
+			{Field: "name", Operation: UpperCase, TargetField: "a"},
+		},
+	})
+	is.NoError(err)
+	is.Equal([]string{"z", "a"}, output[0].Keys())
+}
+
+func TestTransformService_chainsRules(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"code"}, []string{"ab-123"})
+	rules := []TransformRule{
+		{Field: "code", Operation: UpperCase, TargetField: "upper"},
+		{Field: "upper", Operation: Extract, Parameters: map[string]interface{}{"pattern": "[A-Z]+"}, TargetField: "prefix"},
+		{Field: "code", Operation: Replace, Parameters: map[string]interface{}{"old": "-", "new": "_"}},
+		{Field: "code", Operation: UpperCase, Parameters: map[string]interface{}{"source": "original"}, TargetField: "original_upper"},
+		{Field: "code", Operation: Copy, TargetField: "copied"},
+	}
+
+	output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{"rules": rules, "chained": true})
+	is.NoError(err)
+	is.Equal("AB-123", output[0].Fields["upper"])
+	is.Equal("AB", output[0].Fields["prefix"])
+	is.Equal("ab_123", output[0].Fields["code"])
+	is.Equal("AB-123", output[0].Fields["original_upper"])
+	is.Equal("ab_123", output[0].Fields["copied"])
+
+	// rules are not chained by default, reading the original row, where the upper field does not exist
+	output, err = newTestTransformService(t).ProcessData(input, map[string]interface{}{"rules": rules})
+	is.NoError(err)
+	is.Equal("AB-123", output[0].Fields["upper"])
+	is.Equal("", output[0].Fields["prefix"])
+	is.Equal("ab_123", output[0].Fields["code"])
+	is.Equal("ab-123", output[0].Fields["copied"])
+
+	_, err = newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "code", Operation: Trim, Parameters: map[string]interface{}{"source": "input"}}},
+	})
+	is.ErrorContains(err, "invalid source input of trim rule on field 'code': expected current or original")
+}
+
+func TestTransformService_invalidExtractPatternFailsFast(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"rules": []TransformRule{
+			{Field: "email", Operation: Extract, Parameters: map[string]interface{}{"pattern": "[a-z"}},
+		},
+	})
+	is.ErrorContains(err, `"[a-z"`)
+}
+
+func TestTransformService_formatDate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "US to ISO", value: "01/15/2024", params: map[string]interface{}{"input_format": "MM/DD/YYYY", "output_format": "YYYY-MM-DD"}, want: "2024-01-15"},
+		{name: "auto-detected US date", value: "1/15/2024", params: map[string]interface{}{"output_format": "YYYY-MM-DD"}, want: "2024-01-15"},
+		{name: "input layouts", value: "15.01.2024", params: map[string]interface{}{"input_format": []interface{}{"YYYY-MM-DD", "DD.MM.YYYY"}, "output_format": "2006-01-02"}, want: "2024-01-15"},
+		{name: "epoch seconds to ISO", value: "1700000000", params: map[string]interface{}{"input_format": "epoch"}, want: "2023-11-14T22:13:20Z"},
+		{name: "ISO to epoch milliseconds", value: "2023-11-14T22:13:20Z", params: map[string]interface{}{"output_format": "epoch_ms"}, want: "1700000000000"},
+		{name: "location", value: "1700000000", params: map[string]interface{}{"input_format": "epoch", "location": "Europe/Paris"}, want: "2023-11-14T23:13:20+01:00"},
+		{name: "local date in location", value: "2024-07-01 12:00", params: map[string]interface{}{"location": "America/New_York", "output_format": "epoch"}, want: "1719849600"},
+		{name: "unparseable input is kept", value: "soon", params: map[string]interface{}{"output_format": "YYYY-MM-DD"}, want: "soon"},
+		{name: "unparseable input is emptied", value: "13/45/2024", params: map[string]interface{}{"input_format": "MM/DD/YYYY", "on_error": "empty"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := []DataRow{{Fields: map[string]string{"date": tc.value}, Columns: []string{"date"}}}
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "date", Operation: FormatDate, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["date"])
+		})
+	}
+}
+
+func TestTransformService_invalidFormatDateFailsFast(t *testing.T) {
+	t.Parallel()
+
+	for _, params := range []map[string]interface{}{
+		{"location": "Mars/Olympus"},
+		{"on_error": "drop"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "date", Operation: FormatDate, Parameters: params}},
+		})
+		assert.ErrorContains(t, err, "format_date rule on field 'date'", params)
+	}
+}
+
+func TestTransformService_concat(t *testing.T) {
+	t.Parallel()
+
+	row := DataRow{
+		Fields:  map[string]string{"first": "Ada", "middle": " ", "last": "Lovelace", "id": "42"},
+		Columns: []string{"first", "middle", "last", "id"},
+	}
+
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "separator", params: map[string]interface{}{"fields": []interface{}{"first", "middle", "last"}, "separator": "|"}, want: "Ada| |Lovelace"},
+		{name: "skip empty", params: map[string]interface{}{"fields": "first,middle,last", "separator": " ", "skip_empty": true}, want: "Ada Lovelace"},
+		{name: "missing field", params: map[string]interface{}{"fields": []string{"id", "region", "first"}, "separator": "-"}, want: "42--Ada"},
+		{name: "template", params: map[string]interface{}{"template": "{last}, {first} {{#{id}}}{region}"}, want: "Lovelace, Ada {#42}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData([]DataRow{row}, map[string]interface{}{
+				"rules": []TransformRule{{Operation: Concat, Parameters: tc.params, TargetField: "key"}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["key"])
+			is.Equal([]string{"first", "middle", "last", "id", "key"}, output[0].Keys())
+		})
+	}
+}
+
+func TestTransformService_invalidConcatFailsFast(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []TransformRule{
+		{Operation: Concat, Parameters: map[string]interface{}{"fields": "first,last"}},
+		{Operation: Concat, TargetField: "key"},
+		{Operation: Concat, TargetField: "key", Parameters: map[string]interface{}{"fields": "a", "template": "{a}"}},
+		{Operation: Concat, TargetField: "key", Parameters: map[string]interface{}{"fields": "a", "skip_empty": "yes"}},
+		{Operation: Concat, TargetField: "key", Parameters: map[string]interface{}{"template": "{a"}},
+		{Operation: Concat, TargetField: "key", Parameters: map[string]interface{}{"template": "a}"}},
+		{Operation: Concat, TargetField: "key", Parameters: map[string]interface{}{"template": "{}"}},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		assert.ErrorContains(t, err, "concat rule", rule.Parameters)
+	}
+}
+
+func TestTransformService_renameAndDrop(t *testing.T) {
+	t.Parallel()
+
+	input := []DataRow{{
+		Fields:  map[string]string{"id": "1", "e-mail": " Ann@Corp.com ", "internal_notes": "vip"},
+		Columns: []string{"id", "e-mail", "internal_notes"},
+	}}
+	rules := []TransformRule{
+		{Field: "e-mail", Operation: Trim},
+		{Field: "e-mail", Operation: Rename, TargetField: "email"},
+		{Field: "internal_notes", Operation: Drop},
+		{Field: "missing", Operation: Drop},
+	}
+
+	testCases := []struct {
+		name       string
+		keepFields bool
+		keys       []string
+	}{
+		{name: "keep fields", keepFields: true, keys: []string{"id", "email"}},
+		{name: "transformed fields only", keepFields: false, keys: []string{"email"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{"rules": rules, "keep_fields": tc.keepFields})
+			is.NoError(err)
+			is.Equal(tc.keys, output[0].Keys())
+			is.Equal("Ann@Corp.com", output[0].Fields["email"])
+			is.NotContains(output[0].Fields, "e-mail")
+		})
+	}
+
+	_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"rules": []TransformRule{{Field: "e-mail", Operation: Rename}},
+	})
+	assert.ErrorContains(t, err, "rename rule on field 'e-mail' needs a target_field")
+}
+
+func TestTransformService_copyAndDefault(t *testing.T) {
+	t.Parallel()
+
+	input := []DataRow{
+		{Fields: map[string]string{"name": "ann", "country": "FR"}, Columns: []string{"name", "country"}},
+		{Fields: map[string]string{"name": "bob", "country": ""}, Columns: []string{"name", "country"}},
+		{Fields: map[string]string{"name": "cid"}, Columns: []string{"name"}},
+	}
+
+	testCases := []struct {
+		name        string
+		onlyMissing bool
+		countries   []string
+	}{
+		{name: "missing or empty", countries: []string{"FR", "US", "US"}},
+		{name: "only missing", onlyMissing: true, countries: []string{"FR", "", "US"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"chained": true,
+				"rules": []TransformRule{
+					{Field: "name", Operation: Copy, TargetField: "original_name"},
+					{Field: "name", Operation: UpperCase},
+					{Field: "country", Operation: Default, Parameters: map[string]interface{}{"value": "US", "only_missing": tc.onlyMissing}},
+					{Operation: Concat, Parameters: map[string]interface{}{"template": "{name}/{country}"}, TargetField: "label"},
+				},
+			})
+			is.NoError(err)
+			is.Equal([]string{"name", "country", "original_name", "label"}, output[0].Keys())
+			is.Equal([]string{"name", "original_name", "country", "label"}, output[2].Keys())
+			for i, row := range output {
+				is.Equal(input[i].Fields["name"], row.Fields["original_name"])
+				is.Equal(tc.countries[i], row.Fields["country"])
+				is.Equal(row.Fields["name"]+"/"+tc.countries[i], row.Fields["label"])
+			}
+		})
+	}
+
+	for _, rule := range []TransformRule{
+		{Field: "name", Operation: Copy},
+		{Field: "country", Operation: Default},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		assert.ErrorContains(t, err, string(rule.Operation)+" rule on field '"+rule.Field+"' needs")
+	}
+}
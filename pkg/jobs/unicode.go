@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// unicodeForms are the forms of "unicode_normalize" rules.
+var unicodeForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// asciiPunctuation are the ASCII spellings of typographic punctuation "remove_diacritics" rules write.
+var asciiPunctuation = map[rune]string{
+	'‘': "'", '’': "'", '‚': "'", '‹': "<", '›': ">",
+	'“': `"`, '”': `"`, '„': `"`, '«': `"`, '»': `"`,
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '−': "-",
+}
+
+// parseUnicodeForm parses the "form" of a "unicode_normalize" rule, NFC by default.
+func parseUnicodeForm(rule TransformRule) (*norm.Form, error) {
+	name, _ := rule.Parameters["form"].(string)
+	if name == "" {
+		name = "NFC"
+	}
+	form, ok := unicodeForms[strings.ToUpper(name)]
+	if !ok {
+		return nil, fmt.Errorf("invalid form %q of unicode_normalize rule on field '%s': expected NFC, NFD, NFKC or NFKD", name, rule.Field)
+	}
+	return &form, nil
+}
+
+// diacriticsRemoval writes values in ASCII for "remove_diacritics" rules: letters lose their
+// diacritics, like "é" to "e", letters without decomposition are spelled out, like "ß" to "ss",
+// compatibility characters are decomposed, like "ﬁ" to "fi", and typographic quotes and dashes
+// become ASCII ones. "unmapped", keep by default or drop, tells what to do with other characters.
+type diacriticsRemoval struct {
+	dropUnmapped bool
+}
+
+// parseDiacriticsRemoval parses the parameters of a "remove_diacritics" rule.
+func parseDiacriticsRemoval(rule TransformRule) (*diacriticsRemoval, error) {
+	removal := &diacriticsRemoval{}
+	switch unmapped, _ := rule.Parameters["unmapped"].(string); unmapped {
+	case "", "keep":
+	case "drop":
+		removal.dropUnmapped = true
+	default:
+		return nil, fmt.Errorf("invalid unmapped %q of remove_diacritics rule on field '%s': expected keep or drop", unmapped, rule.Field)
+	}
+	return removal, nil
+}
+
+// remove writes a value in ASCII, but for the characters without ASCII spelling when kept.
+func (d *diacriticsRemoval) remove(value string) string {
+	var b strings.Builder
+	b.Grow(len(value))
+	for _, r := range norm.NFC.String(value) {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) {
+			// combining marks left over from letters that do not compose
+			continue
+		}
+
+		ascii, ok := asciiPunctuation[r]
+		if !ok {
+			ascii = transliterate(r)
+		}
+		if ascii == "" {
+			ascii = compatibilityASCII(r)
+		}
+		switch {
+		case ascii != "":
+			b.WriteString(ascii)
+		case !d.dropUnmapped:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// compatibilityASCII returns the ASCII compatibility decomposition of a character, like "fi" for
+// the "ﬁ" ligature or "2" for "²", empty when it has none.
+func compatibilityASCII(r rune) string {
+	var b strings.Builder
+	for _, decomposed := range norm.NFKD.String(string(r)) {
+		switch {
+		case decomposed < utf8.RuneSelf:
+			b.WriteRune(decomposed)
+		case !unicode.Is(unicode.Mn, decomposed):
+			return ""
+		}
+	}
+	return b.String()
+}
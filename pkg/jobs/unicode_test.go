@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_unicodeNormalize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		form  string
+		value string
+		want  string
+	}{
+		{form: "", value: "C\u0327a va", want: "Ça va"},
+		{form: "NFC", value: "Ça va", want: "Ça va"},
+		{form: "nfd", value: "Ça va", want: "C\u0327a va"},
+		{form: "NFKC", value: "ﬁn² Ａ", want: "fin2 A"},
+		{form: "NFKD", value: "éﬁ", want: "e\u0301fi"},
+		{form: "NFC", value: "", want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.form+" "+tc.value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: UnicodeNormalize, Parameters: map[string]interface{}{"form": tc.form}}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_removeDiacritics(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		unmapped string
+		want     string
+	}{
+		{value: "Ça va, Zoë? Ünïcödé", want: "Ca va, Zoe? Unicode"},
+		{value: "C\u0327a va", want: "Ca va"},
+		{value: "Straße Æsir Œuvre Łódź Øresund", want: "Strasse Aesir Oeuvre Lodz Oresund"},
+		{value: "ﬁn ² Ａ", want: "fin 2 A"},
+		{value: "l’été — “chaud”", want: `l'ete - "chaud"`},
+		{value: "Prix: 5 € 東京", want: "Prix: 5 € 東京"},
+		{value: "Prix: 5 € 東京", unmapped: "drop", want: "Prix: 5  "},
+		{value: "", want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: RemoveDiacritics, Parameters: map[string]interface{}{"unmapped": tc.unmapped}}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_unicodeErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"rules": []TransformRule{{Field: "value", Operation: UnicodeNormalize, Parameters: map[string]interface{}{"form": "NFX"}}},
+	})
+	is.ErrorContains(err, `invalid form "NFX" of unicode_normalize rule on field 'value'`)
+
+	_, err = newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"rules": []TransformRule{{Field: "value", Operation: RemoveDiacritics, Parameters: map[string]interface{}{"unmapped": "replace"}}},
+	})
+	is.ErrorContains(err, `invalid unmapped "replace" of remove_diacritics rule on field 'value'`)
+}
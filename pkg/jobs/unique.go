@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// uniqueConstraint extends a "unique" rule beyond the validated rows. Its constraints are then
+// {"fields": other key fields, "files": paths or glob patterns, "hash_values": bool}: the keys of
+// the rows of the files, CSV files or JSON Lines ones, count as found before the validated rows,
+// so that uniqueness holds across a set of files like monthly exports. The validated input file
+// is left out of the files. hash_values keeps a 16-byte hash of each key rather than its values,
+// bounding the memory of long keys.
+type uniqueConstraint struct {
+	files  []string
+	hash   bool
+	primed map[string][]uniqueOccurrence // rows of the files of each key, in order
+}
+
+// uniqueOccurrence is a row of a file a "unique" rule is primed from.
+type uniqueOccurrence struct {
+	file string
+	row  int
+}
+
+// parseUniqueConstraint parses the files and hashing of a "unique" rule whose key fields are
+// resolved, and reads the keys of the files, the input file excepted.
+func (s *ValidateService) parseUniqueConstraint(rule ValidationRule, constraints map[string]interface{}, inputFile string) (*uniqueConstraint, error) {
+	constraint := &uniqueConstraint{primed: make(map[string][]uniqueOccurrence)}
+	if hash, ok := constraints["hash_values"]; ok {
+		if constraint.hash, ok = hash.(bool); !ok {
+			return nil, fmt.Errorf("invalid hash_values of unique rule on field '%s': expected true or false", rule.Field)
+		}
+	}
+
+	files, err := s.fileService.ResolveInputFiles(parseColumnList(constraints["files"]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid files of unique rule on field '%s': %w", rule.Field, err)
+	}
+	for _, file := range files {
+		if inputFile == "" || filepath.Clean(file) != filepath.Clean(inputFile) {
+			constraint.files = append(constraint.files, file)
+		}
+	}
+
+	rule.unique = constraint
+	for _, file := range constraint.files {
+		if err := s.primeUniqueKeys(rule, file); err != nil {
+			return nil, fmt.Errorf("failed to read keys of unique rule on field '%s': %w", rule.Field, err)
+		}
+	}
+	return constraint, nil
+}
+
+// primeUniqueKeys records the non-empty keys of the rows of a file for a "unique" rule,
+// failing when the file, a CSV one, does not have the key fields.
+func (s *ValidateService) primeUniqueKeys(rule ValidationRule, file string) error {
+	rowNumber := 0
+	add := func(row DataRow) error {
+		rowNumber++
+		if key, empty := rule.uniqueKey(row); !empty {
+			rule.unique.primed[key] = append(rule.unique.primed[key], uniqueOccurrence{file: file, row: rowNumber})
+		}
+		return nil
+	}
+
+	if isJSONLFile(file) {
+		return s.fileService.StreamJSONL(file, add)
+	}
+
+	headers, err := s.fileService.readHeaders(file)
+	if err != nil {
+		return err
+	}
+	for _, field := range rule.keyFields {
+		if !slices.Contains(headers, field) {
+			return fmt.Errorf("column '%s' is missing from %s, available columns: %s", field, file, strings.Join(headers, ", "))
+		}
+	}
+	return s.fileService.StreamCSV(file, add)
+}
+
+// hashKey returns the key a "unique" rule keeps, the 16 first bytes of its SHA-256 hash when hashing.
+func (c *uniqueConstraint) hashKey(key string) string {
+	if c == nil || !c.hash {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return string(sum[:16])
+}
+
+// describeOccurrences lists the earlier rows of a key for the message of a "unique" rule:
+// rows of the files, with their file, then rows of the validated input, like
+// "row 4 of exports/2024-01.csv, row 2, 7".
+func describeOccurrences(primed []uniqueOccurrence, rowNumbers []int) string {
+	parts := make([]string, 0, len(primed)+1)
+	for _, occurrence := range primed {
+		parts = append(parts, fmt.Sprintf("row %d of %s", occurrence.row, occurrence.file))
+	}
+	if len(rowNumbers) > 0 {
+		rows := make([]string, len(rowNumbers))
+		for i, rowNumber := range rowNumbers {
+			rows[i] = strconv.Itoa(rowNumber)
+		}
+		parts = append(parts, "row "+strings.Join(rows, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,175 @@
+package jobs
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// unit is a unit of "convert_unit" rules. Values convert to the base unit of their dimension,
+// the first of the dimension in units, as (value + offset) * factor, the offset being only set
+// for temperatures.
+type unit struct {
+	dimension string
+	factor    string
+	offset    string
+}
+
+// units are the units of "convert_unit" rules, by name, with exact factors.
+var units = map[string]unit{
+	// length, in meters
+	"m":   {dimension: "length", factor: "1"},
+	"mm":  {dimension: "length", factor: "0.001"},
+	"cm":  {dimension: "length", factor: "0.01"},
+	"km":  {dimension: "length", factor: "1000"},
+	"in":  {dimension: "length", factor: "0.0254"},
+	"ft":  {dimension: "length", factor: "0.3048"},
+	"yd":  {dimension: "length", factor: "0.9144"},
+	"mi":  {dimension: "length", factor: "1609.344"},
+	"nmi": {dimension: "length", factor: "1852"},
+
+	// mass, in kilograms
+	"kg": {dimension: "mass", factor: "1"},
+	"mg": {dimension: "mass", factor: "0.000001"},
+	"g":  {dimension: "mass", factor: "0.001"},
+	"t":  {dimension: "mass", factor: "1000"},
+	"oz": {dimension: "mass", factor: "0.028349523125"},
+	"lb": {dimension: "mass", factor: "0.45359237"},
+	"st": {dimension: "mass", factor: "6.35029318"},
+
+	// temperature, in kelvins
+	"k": {dimension: "temperature", factor: "1"},
+	"c": {dimension: "temperature", factor: "1", offset: "273.15"},
+	"f": {dimension: "temperature", factor: "5/9", offset: "459.67"},
+
+	// volume, in liters, with US customary units but the imperial gallon
+	"l":       {dimension: "volume", factor: "1"},
+	"ml":      {dimension: "volume", factor: "0.001"},
+	"cl":      {dimension: "volume", factor: "0.01"},
+	"m3":      {dimension: "volume", factor: "1000"},
+	"tsp":     {dimension: "volume", factor: "0.00492892159375"},
+	"tbsp":    {dimension: "volume", factor: "0.01478676478125"},
+	"floz":    {dimension: "volume", factor: "0.0295735295625"},
+	"cup":     {dimension: "volume", factor: "0.2365882365"},
+	"pt":      {dimension: "volume", factor: "0.473176473"},
+	"qt":      {dimension: "volume", factor: "0.946352946"},
+	"gal":     {dimension: "volume", factor: "3.785411784"},
+	"imp_gal": {dimension: "volume", factor: "4.54609"},
+
+	// data size, in bytes
+	"byte": {dimension: "data size", factor: "1"},
+	"bit":  {dimension: "data size", factor: "1/8"},
+	"kb":   {dimension: "data size", factor: "1000"},
+	"mb":   {dimension: "data size", factor: "1000000"},
+	"gb":   {dimension: "data size", factor: "1000000000"},
+	"tb":   {dimension: "data size", factor: "1000000000000"},
+	"kib":  {dimension: "data size", factor: "1024"},
+	"mib":  {dimension: "data size", factor: "1048576"},
+	"gib":  {dimension: "data size", factor: "1073741824"},
+	"tib":  {dimension: "data size", factor: "1099511627776"},
+}
+
+// unitDimensions are the dimensions of units, in the order errors list them.
+var unitDimensions = []string{"length", "mass", "temperature", "volume", "data size"}
+
+// unitAliases are the other names of units.
+var unitAliases = map[string]string{
+	"meter": "m", "meters": "m", "metre": "m", "metres": "m",
+	"kilometer": "km", "kilometers": "km", "kilometre": "km", "kilometres": "km",
+	"centimeter": "cm", "centimeters": "cm", "millimeter": "mm", "millimeters": "mm",
+	"inch": "in", "inches": "in", "foot": "ft", "feet": "ft", "yard": "yd", "yards": "yd",
+	"mile": "mi", "miles": "mi",
+	"gram": "g", "grams": "g", "kilogram": "kg", "kilograms": "kg", "kgs": "kg",
+	"tonne": "t", "tonnes": "t", "ounce": "oz", "ounces": "oz",
+	"pound": "lb", "pounds": "lb", "lbs": "lb", "stone": "st",
+	"kelvin": "k", "celsius": "c", "fahrenheit": "f", "°c": "c", "°f": "f",
+	"liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"gallon": "gal", "gallons": "gal", "pint": "pt", "quart": "qt", "cups": "cup", "fl_oz": "floz",
+	"bytes": "byte", "bits": "bit",
+}
+
+// unitConversion converts numbers between two units of a dimension for "convert_unit" rules.
+// Its parameters are "from" and "to", units or their aliases, like "mi" and "km" or "fahrenheit"
+// and "celsius", and the precision and rounding of numberOutput, with 2 decimals by default.
+// Conversions are exact, so precision -1 writes the shortest decimal close to the result.
+type unitConversion struct {
+	scale  *big.Rat // factor of the from unit over the factor of the to unit
+	before *big.Rat // offset of the from unit
+	after  *big.Rat // offset of the to unit
+	output numberOutput
+}
+
+// parseUnitConversion parses the parameters of a "convert_unit" rule.
+func parseUnitConversion(rule TransformRule) (*unitConversion, error) {
+	fromName, _ := rule.Parameters["from"].(string)
+	from, err := lookupUnit(fromName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from unit of convert_unit rule on field '%s': %w", rule.Field, err)
+	}
+	toName, _ := rule.Parameters["to"].(string)
+	to, err := lookupUnit(toName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to unit of convert_unit rule on field '%s': %w", rule.Field, err)
+	}
+	if from.dimension != to.dimension {
+		return nil, fmt.Errorf("convert_unit rule on field '%s' cannot convert %s, a %s unit, to %s, a %s unit", rule.Field, fromName, from.dimension, toName, to.dimension)
+	}
+
+	conversion := &unitConversion{
+		scale:  new(big.Rat).Quo(unitRat(from.factor), unitRat(to.factor)),
+		before: unitRat(from.offset),
+		after:  unitRat(to.offset),
+	}
+	if conversion.output, err = parseNumberOutput(rule, 2); err != nil {
+		return nil, err
+	}
+	return conversion, nil
+}
+
+// lookupUnit returns the unit of a name or alias, case-insensitively, failing with the supported units.
+func lookupUnit(name string) (unit, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := unitAliases[key]; ok {
+		key = alias
+	}
+	if u, ok := units[key]; ok {
+		return u, nil
+	}
+
+	supported := make([]string, 0, len(unitDimensions))
+	for _, dimension := range unitDimensions {
+		names := []string{}
+		for name, u := range units {
+			if u.dimension == dimension {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+		supported = append(supported, fmt.Sprintf("%s (%s)", dimension, strings.Join(names, ", ")))
+	}
+	return unit{}, fmt.Errorf("unknown unit %q, expected one of %s", name, strings.Join(supported, "; "))
+}
+
+// unitRat parses a factor or offset of the units table, 0 when empty.
+func unitRat(value string) *big.Rat {
+	rat := new(big.Rat)
+	if value != "" {
+		rat.SetString(value)
+	}
+	return rat
+}
+
+// convert converts a number.
+func (c *unitConversion) convert(number decimalNumber) string {
+	result := new(big.Rat).Add(number.value, c.before)
+	result.Mul(result, c.scale)
+	result.Sub(result, c.after)
+
+	if c.output.precision < 0 {
+		value, _ := result.Float64()
+		return c.output.formatFloat(value)
+	}
+	return c.output.format(decimalNumber{value: result})
+}
@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_convertUnit(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		from, to string
+		value    string
+		params   map[string]interface{}
+		want     string
+	}{
+		// length
+		{from: "mi", to: "km", value: "26.2", want: "42.16"},
+		{from: "km", to: "mi", value: "10", params: map[string]interface{}{"precision": 4}, want: "6.2137"},
+		{from: "ft", to: "m", value: "1", params: map[string]interface{}{"precision": -1}, want: "0.3048"},
+		{from: "in", to: "cm", value: "12", want: "30.48"},
+		{from: "yd", to: "ft", value: "1", want: "3.00"},
+		{from: "nmi", to: "mm", value: "1", params: map[string]interface{}{"precision": 0}, want: "1852000"},
+		{from: "Miles", to: "meters", value: "1", params: map[string]interface{}{"precision": -1}, want: "1609.344"},
+
+		// mass
+		{from: "lb", to: "kg", value: "1", params: map[string]interface{}{"precision": -1}, want: "0.45359237"},
+		{from: "kg", to: "lbs", value: "100", want: "220.46"},
+		{from: "oz", to: "g", value: "16", params: map[string]interface{}{"precision": 3}, want: "453.592"},
+		{from: "st", to: "lb", value: "1", want: "14.00"},
+		{from: "t", to: "mg", value: "0.001", params: map[string]interface{}{"precision": 0}, want: "1000000"},
+
+		// temperature
+		{from: "f", to: "c", value: "32", want: "0.00"},
+		{from: "f", to: "c", value: "212", want: "100.00"},
+		{from: "fahrenheit", to: "celsius", value: "-40", want: "-40.00"},
+		{from: "c", to: "f", value: "37", params: map[string]interface{}{"precision": 1}, want: "98.6"},
+		{from: "°C", to: "K", value: "-273.15", want: "0.00"},
+		{from: "k", to: "f", value: "0", want: "-459.67"},
+		{from: "f", to: "f", value: "98.6", params: map[string]interface{}{"precision": -1}, want: "98.6"},
+
+		// volume
+		{from: "gal", to: "l", value: "1", params: map[string]interface{}{"precision": -1}, want: "3.785411784"},
+		{from: "imp_gal", to: "gal", value: "1", params: map[string]interface{}{"precision": 4}, want: "1.2009"},
+		{from: "cup", to: "tbsp", value: "1", want: "16.00"},
+		{from: "tbsp", to: "tsp", value: "1", want: "3.00"},
+		{from: "qt", to: "pt", value: "1", want: "2.00"},
+		{from: "floz", to: "ml", value: "8", want: "236.59"},
+		{from: "m3", to: "cl", value: "0.5", params: map[string]interface{}{"precision": 0}, want: "50000"},
+
+		// data size
+		{from: "gib", to: "mb", value: "1", params: map[string]interface{}{"precision": -1}, want: "1073.741824"},
+		{from: "tb", to: "tib", value: "1", params: map[string]interface{}{"precision": 3}, want: "0.909"},
+		{from: "byte", to: "bit", value: "1.5", params: map[string]interface{}{"precision": 0}, want: "12"},
+		{from: "mib", to: "kib", value: "1", params: map[string]interface{}{"precision": 0}, want: "1024"},
+		{from: "kb", to: "gb", value: "2.5e6", params: map[string]interface{}{"precision": -1}, want: "2.5"},
+
+		// rounding and values that are not numbers
+		{from: "in", to: "cm", value: "1", params: map[string]interface{}{"precision": 1, "rounding": "floor"}, want: "2.5"},
+		{from: "in", to: "cm", value: "n/a", want: "n/a"},
+		{from: "in", to: "cm", value: "", want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.from+" to "+tc.to+" "+tc.value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			params := map[string]interface{}{"from": tc.from, "to": tc.to}
+			for name, value := range tc.params {
+				params[name] = value
+			}
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: ConvertUnit, Parameters: params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestUnits(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for name, u := range units {
+		is.Contains(unitDimensions, u.dimension, name)
+		is.Positive(unitRat(u.factor).Sign(), name)
+		if u.offset != "" {
+			is.Equal("temperature", u.dimension, name)
+		}
+
+		// conversions between two units of a dimension are exact inverses of each other
+		for other, v := range units {
+			if v.dimension != u.dimension {
+				continue
+			}
+			there, err := parseUnitConversion(TransformRule{Field: "value", Parameters: map[string]interface{}{"from": name, "to": other}})
+			is.NoError(err)
+			back, err := parseUnitConversion(TransformRule{Field: "value", Parameters: map[string]interface{}{"from": other, "to": name}})
+			is.NoError(err)
+			is.Equal("1", new(big.Rat).Mul(there.scale, back.scale).RatString(), "%s to %s", name, other)
+			is.Equal(there.after.RatString(), back.before.RatString(), "%s to %s", name, other)
+		}
+	}
+
+	for alias, name := range unitAliases {
+		_, ok := units[name]
+		is.True(ok, alias)
+	}
+}
+
+func TestTransformService_convertUnitErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for params, message := range map[[2]string]string{
+		{"mi", "furlong"}: `invalid to unit of convert_unit rule on field 'value': unknown unit "furlong", expected one of length (cm, ft, in, km, m, mi, mm, nmi, yd); mass`,
+		{"", "km"}:        `invalid from unit of convert_unit rule on field 'value': unknown unit ""`,
+		{"kg", "l"}:       `convert_unit rule on field 'value' cannot convert kg, a mass unit, to l, a volume unit`,
+		{"f", "mb"}:       `cannot convert f, a temperature unit, to mb, a data size unit`,
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "value", Operation: ConvertUnit, Parameters: map[string]interface{}{"from": params[0], "to": params[1]}}},
+		})
+		is.ErrorContains(err, message, params)
+	}
+
+	_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+		"rules": []TransformRule{{Field: "value", Operation: ConvertUnit, Parameters: map[string]interface{}{"from": "m", "to": "ft", "precision": -2}}},
+	})
+	is.ErrorContains(err, "invalid precision -2 of convert_unit rule on field 'value'")
+}
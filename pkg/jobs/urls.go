@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// urlComponents are the components of "url_component" rules.
+var urlComponents = map[string]func(u *url.URL) string{
+	"scheme":   func(u *url.URL) string { return u.Scheme },
+	"host":     func(u *url.URL) string { return u.Host },
+	"hostname": func(u *url.URL) string { return u.Hostname() },
+	"port":     func(u *url.URL) string { return u.Port() },
+	"path":     func(u *url.URL) string { return u.Path },
+	"query":    func(u *url.URL) string { return u.RawQuery },
+	"fragment": func(u *url.URL) string { return u.Fragment },
+	"user":     func(u *url.URL) string { return u.User.Username() },
+}
+
+// urlRule encodes, decodes and parses values for "url_encode", "url_decode", "url_query_param"
+// and "url_component" rules. Encoding rules take "mode", "query" by default for form encoding,
+// spaces being "+", or "path" for percent-encoding, spaces being "%20" and "+" being kept.
+// "url_query_param" rules take the "param" name and "occurrence", "first" by default, "last"
+// or "all", joined with "separator", "," by default; a missing parameter is empty.
+// "url_component" rules take the "component", like "host" or "path". Values that do not decode
+// or parse follow the on_error policy of the rules.
+type urlRule struct {
+	operation  TransformOperation
+	pathMode   bool
+	param      string
+	occurrence string
+	separator  string
+	component  func(u *url.URL) string
+}
+
+// parseURLRule parses the parameters of a URL rule.
+func parseURLRule(rule TransformRule) (*urlRule, error) {
+	parsed := &urlRule{operation: rule.Operation, occurrence: "first", separator: ","}
+
+	//nolint:exhaustive
+	switch rule.Operation {
+	case URLEncode, URLDecode:
+		switch mode, _ := rule.Parameters["mode"].(string); mode {
+		case "", "query":
+		case "path":
+			parsed.pathMode = true
+		default:
+			return nil, fmt.Errorf("invalid mode %q of %s rule on field '%s': expected query or path", mode, rule.Operation, rule.Field)
+		}
+
+	case URLQueryParam:
+		if parsed.param, _ = rule.Parameters["param"].(string); parsed.param == "" {
+			return nil, fmt.Errorf("param of url_query_param rule on field '%s' not specified", rule.Field)
+		}
+		switch occurrence, _ := rule.Parameters["occurrence"].(string); occurrence {
+		case "":
+		case "first", "last", "all":
+			parsed.occurrence = occurrence
+		default:
+			return nil, fmt.Errorf("invalid occurrence %q of url_query_param rule on field '%s': expected first, last or all", occurrence, rule.Field)
+		}
+		if separator, ok := rule.Parameters["separator"].(string); ok {
+			parsed.separator = separator
+		}
+
+	case URLComponent:
+		name, _ := rule.Parameters["component"].(string)
+		if parsed.component = urlComponents[name]; parsed.component == nil {
+			return nil, fmt.Errorf("invalid component %q of url_component rule on field '%s': expected scheme, host, hostname, port, path, query, fragment or user", name, rule.Field)
+		}
+	}
+	return parsed, nil
+}
+
+// apply encodes, decodes or parses a value, failing with an untransformableError when it does not decode or parse.
+func (r *urlRule) apply(value string, field string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	result, err := r.transform(value)
+	if err != nil {
+		return "", untransformable("%s rule on field '%s': %w", r.operation, field, err)
+	}
+	return result, nil
+}
+
+// transform encodes, decodes or parses a value.
+func (r *urlRule) transform(value string) (string, error) {
+	//nolint:exhaustive
+	switch r.operation {
+	case URLEncode:
+		if r.pathMode {
+			return url.PathEscape(value), nil
+		}
+		return url.QueryEscape(value), nil
+	case URLDecode:
+		if r.pathMode {
+			return url.PathUnescape(value)
+		}
+		return url.QueryUnescape(value)
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return "", err
+	}
+	if r.operation == URLComponent {
+		return r.component(parsed), nil
+	}
+
+	query, err := url.ParseQuery(parsed.RawQuery)
+	if err != nil {
+		return "", err
+	}
+	values := query[r.param]
+	switch {
+	case len(values) == 0:
+		return "", nil
+	case r.occurrence == "last":
+		return values[len(values)-1], nil
+	case r.occurrence == "all":
+		return strings.Join(values, r.separator), nil
+	default:
+		return values[0], nil
+	}
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_urls(t *testing.T) {
+	t.Parallel()
+
+	landing := "https://user@shop.example.com:8443/sale/summer?utm_source=news+letter&utm_medium=email&tag=a&tag=b%20c#top"
+	testCases := []struct {
+		name      string
+		value     string
+		operation TransformOperation
+		params    map[string]interface{}
+		want      string
+	}{
+		{name: "query encode", value: "a b+c&d", operation: URLEncode, want: "a+b%2Bc%26d"},
+		{name: "path encode", value: "a b+c/d", operation: URLEncode, params: map[string]interface{}{"mode": "path"}, want: "a%20b+c%2Fd"},
+		{name: "query decode", value: "a+b%20c%2B", operation: URLDecode, want: "a b c+"},
+		{name: "path decode keeps plus", value: "a+b%20c", operation: URLDecode, params: map[string]interface{}{"mode": "path"}, want: "a+b c"},
+		{name: "malformed escape kept", value: "100%", operation: URLDecode, want: "100%"},
+		{name: "malformed escape emptied", value: "%zz", operation: URLDecode, params: map[string]interface{}{"on_error": "empty"}, want: ""},
+		{name: "query param", value: landing, operation: URLQueryParam, params: map[string]interface{}{"param": "utm_source"}, want: "news letter"},
+		{name: "missing query param", value: landing, operation: URLQueryParam, params: map[string]interface{}{"param": "utm_campaign"}, want: ""},
+		{name: "first repeated param", value: landing, operation: URLQueryParam, params: map[string]interface{}{"param": "tag"}, want: "a"},
+		{name: "last repeated param", value: landing, operation: URLQueryParam, params: map[string]interface{}{"param": "tag", "occurrence": "last"}, want: "b c"},
+		{name: "all repeated params", value: landing, operation: URLQueryParam, params: map[string]interface{}{"param": "tag", "occurrence": "all", "separator": "|"}, want: "a|b c"},
+		{name: "malformed url", value: "http://[::1", operation: URLQueryParam, params: map[string]interface{}{"param": "a", "on_error": "empty"}, want: ""},
+		{name: "hostname", value: landing, operation: URLComponent, params: map[string]interface{}{"component": "hostname"}, want: "shop.example.com"},
+		{name: "host", value: landing, operation: URLComponent, params: map[string]interface{}{"component": "host"}, want: "shop.example.com:8443"},
+		{name: "path", value: landing, operation: URLComponent, params: map[string]interface{}{"component": "path"}, want: "/sale/summer"},
+		{name: "fragment", value: landing, operation: URLComponent, params: map[string]interface{}{"component": "fragment"}, want: "top"},
+		{name: "user", value: landing, operation: URLComponent, params: map[string]interface{}{"component": "user"}, want: "user"},
+		{name: "no user", value: "https://example.com", operation: URLComponent, params: map[string]interface{}{"component": "user"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: tc.operation, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_urlErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	input := testRows(t, []string{"url"}, []string{"https://example.com?a=1"}, []string{"https://example.com?a=%zz"})
+	_, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+		"rules": []TransformRule{{Field: "url", Operation: URLQueryParam, Parameters: map[string]interface{}{"param": "a", "on_error": "fail"}}},
+	})
+	is.ErrorContains(err, "line 3: url_query_param rule on field 'url'")
+
+	for _, rule := range []TransformRule{
+		{Operation: URLEncode, Parameters: map[string]interface{}{"mode": "form"}},
+		{Operation: URLDecode, Parameters: map[string]interface{}{"on_error": "skip"}},
+		{Operation: URLQueryParam},
+		{Operation: URLQueryParam, Parameters: map[string]interface{}{"param": "a", "occurrence": "second"}},
+		{Operation: URLComponent, Parameters: map[string]interface{}{"component": "domain"}},
+	} {
+		rule.Field = "url"
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		is.ErrorContains(err, string(rule.Operation)+" rule on field 'url'", rule.Parameters)
+	}
+}
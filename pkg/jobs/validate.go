@@ -1,26 +1,418 @@
 package jobs
 
 import (
+	"crypto/sha256"
 	"fmt"
-	"regexp"
+	"math/big"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/samber/do/v2"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 // ValidationRule defines a validation rule for a field.
 type ValidationRule struct {
 	Field       string      `json:"field"`
-	Type        string      `json:"type"`        // required, email, numeric, regex, min_length, max_length, custom
-	Constraints interface{} `json:"constraints"` // value for min/max, pattern for regex, etc.
-	Message     string      `json:"message"`     // custom error message
+	Type        string      `json:"type"`               // required, email, numeric, regex, min_length, max_length, range, unique, compare_fields, date, uuid, ipv4, ipv6, hostname, integer, decimal, duplicate_row, checksum, foreign_key, outlier, expression, enum
+	Constraints interface{} `json:"constraints"`        // value for min/max, pattern for regex, allowed values for enum, other key fields for unique, key columns for duplicate_row, layouts for date, version for uuid, digits and bounds for decimal, algorithm for checksum, reference file for foreign_key, method for outlier, condition for expression, etc.
+	Message     string      `json:"message"`            // custom error message
+	Severity    string      `json:"severity,omitempty"` // error or warning, see defaultSeverity when empty
+
+	constraint valueConstraint   // parsed constraints of the rule types of constraintParsers
+	keyFields  []string          // fields whose values make the key of a "unique" rule
+	seen       map[string][]int  // row numbers of each key of a "unique" rule, in order
+	unique     *uniqueConstraint // files and hashing of a "unique" rule, when its constraints are an object
+	compare    *fieldComparison  // parsed constraints of a "compare_fields" rule
+	rows       *duplicateRows    // rows seen by a "duplicate_row" rule
+	condition  *Expression       // parsed condition of an "expression" rule
+}
+
+// duplicateRows tracks the rows of a "duplicate_row" rule by the hash of their key columns,
+// rather than by the values themselves, so that its memory stays bounded on wide rows.
+type duplicateRows struct {
+	first  map[[16]byte]firstRow // first row of each key
+	groups []int                 // first row numbers of the keys found more than once
+}
+
+// firstRow is the first row of a key of a "duplicate_row" rule.
+type firstRow struct {
+	number     int
+	duplicated bool
+}
+
+// dateConstraint checks the values of a "date" rule. Its constraints are a layout, a list of
+// layouts, or {"layouts": ..., "min": date, "max": date}, layouts being Go layouts or friendly
+// tokens like YYYY-MM-DD, and bounds being dates or "now", like "max": "now" for past dates.
+type dateConstraint struct {
+	layouts []string // Go layouts, DefaultDateLayouts when empty
+	formats []string // layouts as given, for messages
+	min     *time.Time
+	max     *time.Time
+	minText string // bounds as given, for messages
+	maxText string
+}
+
+// parseDateConstraint parses the constraints of a "date" rule.
+func parseDateConstraint(rule ValidationRule) (*dateConstraint, error) {
+	constraint := &dateConstraint{}
+
+	layouts := rule.Constraints
+	bounds, isMap := rule.Constraints.(map[string]interface{})
+	if isMap {
+		layouts = bounds["layouts"]
+		if layouts == nil {
+			layouts = bounds["layout"]
+		}
+	} else if layouts != nil && rawDateLayouts(layouts) == nil {
+		return nil, fmt.Errorf("invalid constraints of date rule on field '%s': expected layouts or an object", rule.Field)
+	}
+	constraint.formats = rawDateLayouts(layouts)
+	constraint.layouts = parseDateLayouts(layouts)
+
+	var err error
+	if constraint.minText, _ = bounds["min"].(string); constraint.minText != "" {
+		if constraint.min, err = parseDateBound(constraint.minText, constraint.layouts); err != nil {
+			return nil, fmt.Errorf("invalid min of date rule on field '%s': %w", rule.Field, err)
+		}
+	}
+	if constraint.maxText, _ = bounds["max"].(string); constraint.maxText != "" {
+		if constraint.max, err = parseDateBound(constraint.maxText, constraint.layouts); err != nil {
+			return nil, fmt.Errorf("invalid max of date rule on field '%s': %w", rule.Field, err)
+		}
+	}
+	if constraint.min != nil && constraint.max != nil && constraint.min.After(*constraint.max) {
+		return nil, fmt.Errorf("min of date rule on field '%s' is after its max", rule.Field)
+	}
+
+	return constraint, nil
+}
+
+// parseDateBound parses a bound of a "date" rule, "now" or a date in the rule layouts or ISO-8601.
+func parseDateBound(value string, layouts []string) (*time.Time, error) {
+	if strings.EqualFold(value, "now") {
+		now := time.Now()
+		return &now, nil
+	}
+	if date, ok := parseDate(value, append(layouts[:len(layouts):len(layouts)], DefaultDateLayouts...)); ok {
+		return &date, nil
+	}
+	return nil, fmt.Errorf("%q is not a date", value)
+}
+
+// check validates a value, returning the failure message, if any.
+func (c *dateConstraint) check(value string) string {
+	date, ok := parseDate(value, c.layouts)
+	if !ok {
+		if len(c.formats) == 0 {
+			return "Invalid date, expected an ISO-8601 date"
+		}
+		return "Invalid date, expected format " + strings.Join(c.formats, " or ")
+	}
+	if c.min != nil && date.Before(*c.min) {
+		return "Date must not be before " + c.minText
+	}
+	if c.max != nil && date.After(*c.max) {
+		return "Date must not be after " + c.maxText
+	}
+	return ""
+}
+
+// numberConstraint checks the values of an "integer" or "decimal" rule, parsed exactly so that long
+// integers keep every digit. Its optional constraints are {"min": number, "max": number,
+// "max_integer_digits": n, "max_fraction_digits": n, "scientific": bool}, bounds being numbers
+// or strings for ones a float64 cannot hold, and scientific allowing values like 1.5e3.
+// Integer values may have a decimal point, like 3.0, as long as they have no fraction.
+type numberConstraint struct {
+	integer           bool
+	scientific        bool
+	maxIntegerDigits  int // no limit when negative
+	maxFractionDigits int // no limit when negative
+	min               *big.Rat
+	max               *big.Rat
+	minText           string // bounds as given, for messages
+	maxText           string
+}
+
+// parseNumberConstraint parses the constraints of an "integer" or "decimal" rule.
+func parseNumberConstraint(rule ValidationRule) (*numberConstraint, error) {
+	constraint := &numberConstraint{integer: rule.Type == "integer", maxIntegerDigits: -1, maxFractionDigits: -1}
+	if rule.Constraints == nil {
+		return constraint, nil
+	}
+	constraints, ok := rule.Constraints.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid constraints of %s rule on field '%s': expected an object", rule.Type, rule.Field)
+	}
+
+	if scientific, ok := constraints["scientific"]; ok {
+		if constraint.scientific, ok = scientific.(bool); !ok {
+			return nil, fmt.Errorf("invalid scientific of %s rule on field '%s': expected true or false", rule.Type, rule.Field)
+		}
+	}
+	for key, digits := range map[string]*int{"max_integer_digits": &constraint.maxIntegerDigits, "max_fraction_digits": &constraint.maxFractionDigits} {
+		value, ok := constraints[key]
+		if !ok {
+			continue
+		}
+		if *digits, ok = toInt(value); !ok || *digits < 0 {
+			return nil, fmt.Errorf("invalid %s %v of %s rule on field '%s': expected 0 or more", key, value, rule.Type, rule.Field)
+		}
+	}
+
+	var err error
+	if constraint.min, constraint.minText, err = parseNumberBound(constraints["min"]); err != nil {
+		return nil, fmt.Errorf("invalid min of %s rule on field '%s': %w", rule.Type, rule.Field, err)
+	}
+	if constraint.max, constraint.maxText, err = parseNumberBound(constraints["max"]); err != nil {
+		return nil, fmt.Errorf("invalid max of %s rule on field '%s': %w", rule.Type, rule.Field, err)
+	}
+	if constraint.min != nil && constraint.max != nil && constraint.min.Cmp(constraint.max) > 0 {
+		return nil, fmt.Errorf("min of %s rule on field '%s' is greater than its max", rule.Type, rule.Field)
+	}
+
+	return constraint, nil
+}
+
+// parseNumberBound parses a bound of an "integer" or "decimal" rule, a number or a string, nil when absent.
+func parseNumberBound(value interface{}) (*big.Rat, string, error) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil, "", nil
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		text = strconv.Itoa(v)
+	case string:
+		text = v
+	default:
+		return nil, "", fmt.Errorf("%v is not a number", value)
+	}
+	number, ok := parseDecimal(text, true)
+	if !ok {
+		return nil, "", fmt.Errorf("%q is not a number", text)
+	}
+	return number.value, text, nil
+}
+
+// check validates a value, returning the failure message, if any.
+func (c *numberConstraint) check(value string) string {
+	number, ok := parseDecimal(value, c.scientific)
+	switch {
+	case !ok && c.integer:
+		return "Value must be an integer"
+	case !ok:
+		return "Value must be a decimal number"
+	case c.integer && !number.value.IsInt():
+		return "Value must be an integer, without fraction"
+	case c.maxIntegerDigits >= 0 && number.integerDigits > c.maxIntegerDigits:
+		return fmt.Sprintf("Value must have at most %d integer digits", c.maxIntegerDigits)
+	case c.maxFractionDigits >= 0 && number.fractionDigits > c.maxFractionDigits:
+		return fmt.Sprintf("Value must have at most %d fraction digits", c.maxFractionDigits)
+	case c.min != nil && number.value.Cmp(c.min) < 0:
+		return "Value must be at least " + c.minText
+	case c.max != nil && number.value.Cmp(c.max) > 0:
+		return "Value must be at most " + c.maxText
+	}
+	return ""
+}
+
+// fieldComparison compares the field of a "compare_fields" rule to another field of the row.
+// Its constraints are {"field": other, "operator": gt|gte|lt|lte|eq|ne, "type": auto|number|date|string,
+// "date_layouts": [...]}: with the auto type, values compare as numbers when both are numbers,
+// as dates when both are dates, and as strings otherwise.
+type fieldComparison struct {
+	field     string
+	operator  string
+	valueType ValueType
+	layouts   []string
+}
+
+// comparisonOperators maps the operators of "compare_fields" rules to their description.
+var comparisonOperators = map[string]string{
+	"gt":  "greater than",
+	"gte": "greater than or equal to",
+	"lt":  "less than",
+	"lte": "less than or equal to",
+	"eq":  "equal to",
+	"ne":  "different from",
+}
+
+// parseFieldComparison parses the constraints of a "compare_fields" rule.
+func parseFieldComparison(rule ValidationRule) (*fieldComparison, error) {
+	constraints, ok := rule.Constraints.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("compare_fields rule on field '%s' requires constraints with a field and an operator", rule.Field)
+	}
+
+	comparison := &fieldComparison{layouts: parseDateLayouts(constraints["date_layouts"])}
+	comparison.field, _ = constraints["field"].(string)
+	if comparison.field == "" {
+		return nil, fmt.Errorf("compare_fields rule on field '%s' requires the field to compare to", rule.Field)
+	}
+
+	operator, _ := constraints["operator"].(string)
+	comparison.operator = strings.ToLower(operator)
+	if _, ok := comparisonOperators[comparison.operator]; !ok {
+		return nil, fmt.Errorf("unknown comparison operator %q on field '%s': expected gt, gte, lt, lte, eq or ne", operator, rule.Field)
+	}
+
+	valueType, _ := constraints["type"].(string)
+	var err error
+	if comparison.valueType, err = ParseValueType(valueType); err != nil {
+		return nil, fmt.Errorf("compare_fields rule on field '%s': %w", rule.Field, err)
+	}
+
+	return comparison, nil
+}
+
+// check compares a value to the value of the other field, returning the failure message, if any.
+// Empty values are not compared, as required rules deal with them.
+func (c *fieldComparison) check(value, other string) string {
+	if value == "" || other == "" {
+		return ""
+	}
+
+	valueType := c.valueType
+	if valueType == ValueTypeAuto {
+		valueType = detectValueType(value, c.layouts)
+		if detectValueType(other, c.layouts) != valueType {
+			valueType = ValueTypeString
+		}
+	}
+
+	comparator := valueComparator{valueType: valueType, layouts: c.layouts}
+	a, ok := comparator.parse(value)
+	if !ok {
+		return fmt.Sprintf("Value is not a %s", valueType)
+	}
+	b, ok := comparator.parse(other)
+	if !ok {
+		return fmt.Sprintf("Value of field '%s' is not a %s: %q", c.field, valueType, other)
+	}
+
+	cmp := comparator.compare(a, b)
+	var isValid bool
+	switch c.operator {
+	case "gt":
+		isValid = cmp > 0
+	case "gte":
+		isValid = cmp >= 0
+	case "lt":
+		isValid = cmp < 0
+	case "lte":
+		isValid = cmp <= 0
+	case "eq":
+		isValid = cmp == 0
+	case "ne":
+		isValid = cmp != 0
+	}
+	if isValid {
+		return ""
+	}
+	return fmt.Sprintf("Value must be %s field '%s' (%s)", comparisonOperators[c.operator], c.field, other)
+}
+
+// defaultSeverity returns the severity of failures of a rule type when its rule sets none:
+// regex, length and outlier rules are often advisory, so they only warn.
+func defaultSeverity(ruleType string) string {
+	switch ruleType {
+	case "regex", "min_length", "max_length", "outlier":
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// severity returns the severity of the failures of a rule.
+func (r ValidationRule) severity() string {
+	if r.Severity != "" {
+		return r.Severity
+	}
+	return defaultSeverity(r.Type)
+}
+
+// fields returns the fields a rule reads.
+func (r ValidationRule) fields() []string {
+	fields := []string{r.Field}
+	if r.compare != nil {
+		fields = append(fields, r.compare.field)
+	}
+	for _, field := range r.keyFields {
+		if field != r.Field {
+			fields = append(fields, field)
+		}
+	}
+	if r.condition != nil {
+		for _, field := range r.condition.Fields() {
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// references reports whether a rule reads any of the given fields.
+func (r ValidationRule) references(fields map[string]bool) bool {
+	for _, field := range r.fields() {
+		if fields[field] {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueKey returns the key of a row for a "unique" rule, and whether all of its values are empty.
+func (r ValidationRule) uniqueKey(row DataRow) (string, bool) {
+	var key strings.Builder
+	empty := true
+	for _, field := range r.keyFields {
+		value := row.Fields[field]
+		if value != "" {
+			empty = false
+		}
+		writeKeyPart(&key, value)
+	}
+	return r.unique.hashKey(key.String()), empty
+}
+
+// rowHash returns the hash of the key columns of a row for a "duplicate_row" rule,
+// or of all of its fields, names included, when the rule has no key columns.
+func (r ValidationRule) rowHash(row DataRow) [16]byte {
+	fields := r.keyFields
+	if len(fields) == 0 {
+		fields = make([]string, 0, len(row.Fields))
+		for field := range row.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+
+	var key strings.Builder
+	for _, field := range fields {
+		if len(r.keyFields) == 0 {
+			writeKeyPart(&key, field)
+		}
+		writeKeyPart(&key, row.Fields[field])
+	}
+
+	var hash [16]byte
+	sum := sha256.Sum256([]byte(key.String()))
+	copy(hash[:], sum[:])
+	return hash
 }
 
 // ValidationError represents a validation error.
 type ValidationError struct {
-	RowNumber  int     `json:"row_number"`
+	RowNumber  int     `json:"row_number"`            // 1-based index within the parsed rows
+	LineNumber int     `json:"line_number,omitempty"` // line of the row in its source file
+	SourceFile string  `json:"source_file,omitempty"`
 	FieldName  string  `json:"field_name"`
 	FieldValue string  `json:"field_value"`
 	RuleType   string  `json:"rule_type"`
@@ -36,8 +428,138 @@ type ValidationResult struct {
 	TotalRows    int               `json:"total_rows"`
 	Errors       []ValidationError `json:"errors"`
 	Warnings     []ValidationError `json:"warnings"`
-	FieldStats   map[string]int    `json:"field_stats,omitempty"`
-	QualityScore float64           `json:"quality_score"`
+	Structural   []ValidationError `json:"structural,omitempty"`  // column errors of the whole dataset, see validateStructure
+	FieldStats   map[string]int    `json:"field_stats,omitempty"` // rows having each field
+	Profile      []FieldProfile    `json:"profile,omitempty"`     // profile of each field, columns first, with include_profile
+	QualityScore float64           `json:"quality_score"`         // over the processed rows, see ScoringProfile
+
+	// ScoreBreakdown explains QualityScore
+	ScoreBreakdown *ScoreBreakdown `json:"score_breakdown,omitempty"`
+
+	// Rows validated, and rows left out once fail_fast stopped the validation
+	RowsProcessed int `json:"rows_processed"`
+	RowsSkipped   int `json:"rows_skipped"`
+
+	// DuplicateGroups counts the distinct rows found more than once by duplicate_row rules
+	DuplicateGroups int `json:"duplicate_groups,omitempty"`
+
+	// Exact counts of errors and warnings, which Errors and Warnings only sample when truncated
+	ErrorCount        int           `json:"error_count"`
+	WarningCount      int           `json:"warning_count"`
+	ErrorsTruncated   bool          `json:"errors_truncated,omitempty"`
+	WarningsTruncated bool          `json:"warnings_truncated,omitempty"`
+	RuleSummary       []RuleSummary `json:"rule_summary,omitempty"` // most errors first
+
+	// Fixes lists the values auto_fix corrected, counted per rule in FixSummary
+	Fixes      []Fix        `json:"fixes,omitempty"`
+	FixSummary []FixSummary `json:"fix_summary,omitempty"`
+
+	// References describes the reference values loaded by foreign_key rules
+	References []ReferenceSet `json:"references,omitempty"`
+
+	// ThresholdFailures lists the quality thresholds of the options the result does not meet
+	ThresholdFailures []ThresholdFailure `json:"threshold_failures,omitempty"`
+
+	invalidRecords []invalidRecord // invalid rows with their errors, when exported with them
+}
+
+// Quality thresholds of ValidateOptions, as named by ThresholdFailure.
+const (
+	ThresholdMinQualityScore = "min_quality_score"
+	ThresholdMaxInvalidRows  = "max_invalid_rows"
+)
+
+// ThresholdFailure is a quality threshold a validation result does not meet.
+type ThresholdFailure struct {
+	Threshold string `json:"threshold"` // ThresholdMinQualityScore or ThresholdMaxInvalidRows
+	Message   string `json:"message"`
+}
+
+// ruleSummaryExamples is the number of distinct values a rule summary keeps as examples.
+const ruleSummaryExamples = 5
+
+// RuleSummary sums up the issues of a rule type on a field, all of them even when only some are detailed.
+type RuleSummary struct {
+	Rule     string   `json:"rule"`
+	Field    string   `json:"field"`
+	Errors   int      `json:"errors"`
+	Warnings int      `json:"warnings"`
+	Rows     int      `json:"rows"`               // rows with issues, none for structural issues
+	Examples []string `json:"examples,omitempty"` // first distinct values with issues
+}
+
+// issueCollector adds issues to a result, counting all of them but only keeping
+// the details of the first MaxErrors ones, and of the first ErrorsPerRule ones of each rule.
+type issueCollector struct {
+	result    *ValidationResult
+	maxErrors int
+	perRule   int
+	rules     map[[2]string]int // index of each rule type and field in RuleSummary
+	lastRows  []int             // last row number with issues of each rule summary
+	kept      map[[2]string]int // detailed issues of each rule type and field
+}
+
+// newIssueCollector returns a collector of the issues of a result, within the limits of the options.
+func newIssueCollector(result *ValidationResult, opts *ValidateOptions) *issueCollector {
+	return &issueCollector{
+		result:    result,
+		maxErrors: opts.MaxErrors,
+		perRule:   opts.ErrorsPerRule,
+		rules:     make(map[[2]string]int),
+		kept:      make(map[[2]string]int),
+	}
+}
+
+// add counts an issue in the summary of its rule, and keeps its details within the limits.
+// Structural issues are always kept. Issues are added in row order.
+func (c *issueCollector) add(issue ValidationError, structural bool) {
+	key := [2]string{issue.RuleType, issue.FieldName}
+	i, ok := c.rules[key]
+	if !ok {
+		i = len(c.result.RuleSummary)
+		c.rules[key] = i
+		c.result.RuleSummary = append(c.result.RuleSummary, RuleSummary{Rule: issue.RuleType, Field: issue.FieldName})
+		c.lastRows = append(c.lastRows, 0)
+	}
+
+	summary := &c.result.RuleSummary[i]
+	details, count, truncated := &c.result.Warnings, &c.result.WarningCount, &c.result.WarningsTruncated
+	if issue.Severity == "error" {
+		summary.Errors++
+		details, count, truncated = &c.result.Errors, &c.result.ErrorCount, &c.result.ErrorsTruncated
+	} else {
+		summary.Warnings++
+	}
+	if issue.RowNumber > 0 && issue.RowNumber != c.lastRows[i] {
+		summary.Rows++
+		c.lastRows[i] = issue.RowNumber
+	}
+	if !structural && len(summary.Examples) < ruleSummaryExamples && !slices.Contains(summary.Examples, issue.FieldValue) {
+		summary.Examples = append(summary.Examples, issue.FieldValue)
+	}
+
+	if structural {
+		c.result.Structural = append(c.result.Structural, issue)
+		return
+	}
+	*count++
+	if (c.maxErrors > 0 && len(*details) >= c.maxErrors) || (c.perRule > 0 && c.kept[key] >= c.perRule) {
+		*truncated = true
+		return
+	}
+	c.kept[key]++
+	*details = append(*details, issue)
+}
+
+// sortRuleSummary sorts the rule summary of the result, most errors then most warnings first.
+func (c *issueCollector) sortRuleSummary() {
+	summary := c.result.RuleSummary
+	sort.SliceStable(summary, func(a, b int) bool {
+		if summary[a].Errors != summary[b].Errors {
+			return summary[a].Errors > summary[b].Errors
+		}
+		return summary[a].Warnings > summary[b].Warnings
+	})
 }
 
 // ValidateService handles data validation operations
@@ -51,7 +573,7 @@ type ValidateService struct {
 func NewValidateService(i do.Injector) (*ValidateService, error) {
 	return &ValidateService{
 		fileService: do.MustInvoke[*FileService](i),
-		logger:      do.MustInvoke[zerolog.Logger](i),
+		logger:      *do.MustInvoke[*zerolog.Logger](i),
 	}, nil
 }
 
@@ -60,9 +582,44 @@ type ValidateOptions struct {
 	InputFile     string           `json:"input_file"`
 	OutputFile    string           `json:"output_file"`
 	Rules         []ValidationRule `json:"rules"`
-	FailFast      bool             `json:"fail_fast"`      // stop on first error
-	ExportValid   bool             `json:"export_valid"`   // export valid records
-	ExportInvalid bool             `json:"export_invalid"` // export invalid records
+	FailFast      bool             `json:"fail_fast"`                 // stop on first error, or after FailFastAfter errors
+	FailFastAfter int              `json:"fail_fast_after,omitempty"` // errors fail_fast stops after, 1 when 0
+	MaxErrors     int              `json:"max_errors,omitempty"`      // errors, and warnings, detailed at most, all of them when 0
+	ErrorsPerRule int              `json:"errors_per_rule,omitempty"` // errors, and warnings, detailed at most per rule and field, all of them when 0
+	ExportValid   bool             `json:"export_valid"`              // export valid records
+	ExportInvalid bool             `json:"export_invalid"`            // export invalid records
+	InvalidExport string           `json:"invalid_export,omitempty"`  // format of the invalid records export, InvalidExportField when empty
+
+	IncludeMetadata bool `json:"include_metadata"` // write source line numbers in exported records
+
+	// AutoFix lists the rule types whose failing values are fixed before their row is validated, see autoFixes
+	AutoFix   []string `json:"auto_fix,omitempty"`
+	FixedFile string   `json:"fixed_file,omitempty"` // file the input is written to, fixes applied
+
+	// IncludeProfile profiles every field, rules or not, which holds up to maxProfileDistinct values per field.
+	IncludeProfile bool `json:"include_profile,omitempty"`
+
+	// ReportFormat is the format of the output file, picked from its extension when empty
+	ReportFormat ReportFormat    `json:"report_format,omitempty"`
+	CSV          CSVWriteOptions `json:"csv"` // dialect of CSV reports
+
+	// SchemaFile is a JSON Schema document every row is validated against, as an object, besides the rules.
+	SchemaFile    string `json:"schema_file,omitempty"`
+	CoerceNumbers bool   `json:"coerce_numbers,omitempty"` // validate numeric-looking values as numbers against the schema
+
+	// Columns checked once against the headers, before the rows
+	RequiredColumns     []string `json:"required_columns,omitempty"`      // besides the fields of the rules
+	ForbiddenColumns    []string `json:"forbidden_columns,omitempty"`     // columns that must not be there
+	ExpectedColumnOrder []string `json:"expected_column_order,omitempty"` // relative order of these columns, when present
+
+	// Quality thresholds, reported as ThresholdFailures when not met
+	MinQualityScore float64 `json:"min_quality_score,omitempty"` // minimum quality score, from 0 to 100
+	MaxInvalidRows  *int    `json:"max_invalid_rows,omitempty"`  // maximum number of invalid rows, none when nil
+
+	// Scoring weighs issues into the quality score, DefaultScoringProfile when nil
+	Scoring *ScoringProfile `json:"scoring,omitempty"`
+
+	schema *jsonschema.Schema
 }
 
 // ProcessData validates data based on rules
@@ -70,10 +627,33 @@ type ValidateOptions struct {
 func (s *ValidateService) ProcessData(input []DataRow, options map[string]interface{}) ([]DataRow, error) {
 	s.logger.Info().Msg("Validating data based on rules")
 
+	_, validData, _, err := s.ValidateRows(input, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return valid data for further processing
+	return validData, nil
+}
+
+// GetName returns the processor name.
+func (s *ValidateService) GetName() string {
+	return "validate-data"
+}
+
+// GetDescription returns the processor description.
+func (s *ValidateService) GetDescription() string {
+	return "Validate data integrity and quality"
+}
+
+// ValidateRows validates data based on options, writing and exporting results as requested.
+// It returns the validation result along with the valid and invalid rows, the input file
+// being read when input is empty.
+func (s *ValidateService) ValidateRows(input []DataRow, options map[string]interface{}) (*ValidationResult, []DataRow, []DataRow, error) {
 	// Parse options
 	opts, err := s.parseValidateOptions(options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse validation options: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to parse validation options: %w", err)
 	}
 
 	// If input data is empty, try to read from file
@@ -81,31 +661,42 @@ func (s *ValidateService) ProcessData(input []DataRow, options map[string]interf
 		var err error
 		input, err = s.fileService.ReadCSV(opts.InputFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read input file: %w", err)
+			return nil, nil, nil, fmt.Errorf("failed to read input file: %w", err)
 		}
 	}
 
 	// Perform validation
 	result, validData, invalidData := s.validateData(input, opts)
+	result.ThresholdFailures = checkThresholds(result, opts)
 
 	// Write results to file if output file specified
 	if opts.OutputFile != "" {
-		if err := s.fileService.WriteJSON(opts.OutputFile, result); err != nil {
-			return nil, fmt.Errorf("failed to write validation results: %w", err)
+		if err := s.writeReport(opts.OutputFile, result, opts); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to write validation results: %w", err)
+		}
+	}
+
+	// Write the input with its fixes applied, even without fixes so that pipelines always get the file
+	if opts.FixedFile != "" {
+		outputOptions := OutputOptions{CSV: opts.CSV, IncludeMetadata: opts.IncludeMetadata}
+		if err := s.fileService.WriteRows(opts.FixedFile, applyFixes(input, result.Fixes), outputOptions); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to write fixed data: %w", err)
 		}
 	}
 
-	// Export valid and invalid data if requested
+	// Export valid and invalid data if requested, next to the report whatever its format,
+	// or next to the input file without report
+	base := exportBase(opts)
 	if opts.ExportValid && len(validData) > 0 {
-		validFile := strings.TrimSuffix(opts.OutputFile, ".json") + "_valid.json"
-		if err := s.fileService.WriteJSON(validFile, validData); err != nil {
+		validFile := base + "_valid.json"
+		if err := s.fileService.WriteJSONRows(validFile, validData, opts.IncludeMetadata); err != nil {
 			s.logger.Error().Err(err).Msg("Failed to export valid data")
 		}
 	}
 
 	if opts.ExportInvalid && len(invalidData) > 0 {
-		invalidFile := strings.TrimSuffix(opts.OutputFile, ".json") + "_invalid.json"
-		if err := s.fileService.WriteJSON(invalidFile, invalidData); err != nil {
+		invalidFile := base + "_invalid.json"
+		if err := s.writeInvalidExport(invalidFile, result.invalidRecords, invalidData, opts); err != nil {
 			s.logger.Error().Err(err).Msg("Failed to export invalid data")
 		}
 	}
@@ -115,23 +706,14 @@ func (s *ValidateService) ProcessData(input []DataRow, options map[string]interf
 		Int("total_rows", result.TotalRows).
 		Int("valid_rows", result.ValidRows).
 		Int("invalid_rows", result.InvalidRows).
-		Int("errors", len(result.Errors)).
-		Int("warnings", len(result.Warnings)).
+		Int("skipped_rows", result.RowsSkipped).
+		Int("errors", result.ErrorCount).
+		Int("warnings", result.WarningCount).
+		Int("structural", len(result.Structural)).
 		Float64("quality_score", result.QualityScore).
 		Msg("Data validation completed")
 
-	// Return valid data for further processing
-	return validData, nil
-}
-
-// GetName returns the processor name.
-func (s *ValidateService) GetName() string {
-	return "validate-data"
-}
-
-// GetDescription returns the processor description.
-func (s *ValidateService) GetDescription() string {
-	return "Validate data integrity and quality"
+	return result, validData, invalidData, nil
 }
 
 // parseValidateOptions parses validation options from map.
@@ -146,34 +728,308 @@ func (s *ValidateService) parseValidateOptions(options map[string]interface{}) (
 		opts.OutputFile = outputFile
 	}
 
+	if err := opts.parseLimits(options); err != nil {
+		return nil, err
+	}
+
+	if err := opts.parseThresholds(options); err != nil {
+		return nil, err
+	}
+
+	if err := opts.parseOutputs(options); err != nil {
+		return nil, err
+	}
+
+	if schemaFile, ok := options["schema_file"].(string); ok {
+		opts.SchemaFile = schemaFile
+	}
+
+	if coerceNumbers, ok := options["coerce_numbers"].(bool); ok {
+		opts.CoerceNumbers = coerceNumbers
+	}
+
+	opts.RequiredColumns = parseColumnList(options["required_columns"])
+	opts.ForbiddenColumns = parseColumnList(options["forbidden_columns"])
+	opts.ExpectedColumnOrder = parseColumnList(options["expected_column_order"])
+
+	opts.Rules = s.decodeRules(options["rules"])
+
+	if opts.SchemaFile != "" {
+		schema, err := compileSchema(opts.SchemaFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.schema = schema
+	}
+
+	// Parse the constraints of the rules once, so that they fail before any row is read
+	for i := range opts.Rules {
+		if err := s.parseRule(&opts.Rules[i], opts.InputFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return opts, nil
+}
+
+// decodeRules returns validation rules, either typed or decoded from generic JSON.
+func (s *ValidateService) decodeRules(raw interface{}) []ValidationRule {
+	if rules, ok := raw.([]ValidationRule); ok {
+		return append([]ValidationRule(nil), rules...)
+	}
+	rulesRaw, _ := raw.([]interface{})
+	var rules []ValidationRule
+	for _, ruleRaw := range rulesRaw {
+		if ruleMap, ok := ruleRaw.(map[string]interface{}); ok {
+			rules = append(rules, ValidationRule{
+				Field:       s.getString(ruleMap, "field"),
+				Type:        s.getString(ruleMap, "type"),
+				Constraints: ruleMap["constraints"],
+				Message:     s.getString(ruleMap, "message"),
+				Severity:    s.getString(ruleMap, "severity"),
+			})
+		}
+	}
+	return rules
+}
+
+// parseLimits parses the options bounding the errors found and detailed.
+func (o *ValidateOptions) parseLimits(options map[string]interface{}) error {
 	if failFast, ok := options["fail_fast"].(bool); ok {
-		opts.FailFast = failFast
+		o.FailFast = failFast
+	}
+
+	// fail_fast_after implies fail_fast
+	if failFastAfter, ok := toInt(options["fail_fast_after"]); ok {
+		if failFastAfter < 1 {
+			return fmt.Errorf("fail_fast_after must be at least 1, got %d", failFastAfter)
+		}
+		o.FailFast = true
+		o.FailFastAfter = failFastAfter
+	}
+
+	if maxErrors, ok := toInt(options["max_errors"]); ok {
+		if maxErrors < 0 {
+			return fmt.Errorf("max_errors must not be negative, got %d", maxErrors)
+		}
+		o.MaxErrors = maxErrors
+	}
+
+	if errorsPerRule, ok := toInt(options["errors_per_rule"]); ok {
+		if errorsPerRule < 0 {
+			return fmt.Errorf("errors_per_rule must not be negative, got %d", errorsPerRule)
+		}
+		o.ErrorsPerRule = errorsPerRule
+	}
+
+	return nil
+}
+
+// parseThresholds parses the quality thresholds and the scoring profile.
+func (o *ValidateOptions) parseThresholds(options map[string]interface{}) error {
+	if minScore, ok := toFloat(options["min_quality_score"]); ok {
+		if minScore < 0 || minScore > 100 {
+			return fmt.Errorf("min_quality_score must be between 0 and 100, got %v", minScore)
+		}
+		o.MinQualityScore = minScore
+	}
+
+	if scoring, ok := options["scoring"]; ok && scoring != nil {
+		profile, err := parseScoringProfile(scoring)
+		if err != nil {
+			return err
+		}
+		o.Scoring = &profile
+	}
+
+	if maxInvalid, ok := toInt(options["max_invalid_rows"]); ok {
+		if maxInvalid < 0 {
+			return fmt.Errorf("max_invalid_rows must not be negative, got %d", maxInvalid)
+		}
+		o.MaxInvalidRows = &maxInvalid
 	}
 
+	return nil
+}
+
+// parseOutputs parses the options of the report, the exports and the fixed file.
+func (o *ValidateOptions) parseOutputs(options map[string]interface{}) error {
 	if exportValid, ok := options["export_valid"].(bool); ok {
-		opts.ExportValid = exportValid
+		o.ExportValid = exportValid
 	}
 
 	if exportInvalid, ok := options["export_invalid"].(bool); ok {
-		opts.ExportInvalid = exportInvalid
-	}
-
-	// Parse validation rules
-	if rulesRaw, ok := options["rules"].([]interface{}); ok {
-		for _, ruleRaw := range rulesRaw {
-			if ruleMap, ok := ruleRaw.(map[string]interface{}); ok {
-				rule := ValidationRule{
-					Field:       s.getString(ruleMap, "field"),
-					Type:        s.getString(ruleMap, "type"),
-					Constraints: ruleMap["constraints"],
-					Message:     s.getString(ruleMap, "message"),
-				}
-				opts.Rules = append(opts.Rules, rule)
+		o.ExportInvalid = exportInvalid
+	}
+
+	invalidExport, _ := options["invalid_export"].(string)
+	format, err := parseInvalidExport(invalidExport)
+	if err != nil {
+		return err
+	}
+	o.InvalidExport = format
+
+	if includeMetadata, ok := options["include_metadata"].(bool); ok {
+		o.IncludeMetadata = includeMetadata
+	}
+
+	if autoFix, ok := options["auto_fix"]; ok && autoFix != nil {
+		ruleTypes, err := parseAutoFix(autoFix)
+		if err != nil {
+			return err
+		}
+		o.AutoFix = ruleTypes
+	}
+
+	if fixedFile, ok := options["fixed_file"].(string); ok {
+		o.FixedFile = fixedFile
+	}
+
+	if includeProfile, ok := options["include_profile"].(bool); ok {
+		o.IncludeProfile = includeProfile
+	}
+
+	if reportFormat, ok := options["report_format"].(string); ok {
+		format, err := ParseReportFormat(reportFormat)
+		if err != nil {
+			return err
+		}
+		o.ReportFormat = format
+	}
+
+	csvOpts, err := parseCSVWriteOptions(options)
+	if err != nil {
+		return err
+	}
+	o.CSV = csvOpts
+
+	return nil
+}
+
+// parseRule checks the severity of a rule and parses its constraints, by type.
+// Unknown types are left as is, and reported as warnings on every row.
+func (s *ValidateService) parseRule(rule *ValidationRule, inputFile string) error {
+	switch severity := strings.ToLower(rule.Severity); severity {
+	case "", "error", "warning":
+		rule.Severity = severity
+	default:
+		return fmt.Errorf("unknown severity %q on field '%s': expected error or warning", rule.Severity, rule.Field)
+	}
+
+	if parse, ok := constraintParsers[rule.Type]; ok {
+		constraint, err := parse(s, *rule)
+		if err != nil {
+			return err
+		}
+		rule.constraint = constraint
+		return nil
+	}
+
+	var err error
+	switch rule.Type {
+	case "compare_fields":
+		rule.compare, err = parseFieldComparison(*rule)
+	case "expression":
+		rule.condition, err = parseRuleCondition(*rule)
+	case "unique":
+		err = s.parseUniqueKey(rule, inputFile)
+	case "duplicate_row":
+		rule.keyFields, err = parseDuplicateRowColumns(*rule)
+	}
+	return err
+}
+
+// parseRuleCondition parses the condition of an "expression" rule.
+func parseRuleCondition(rule ValidationRule) (*Expression, error) {
+	source, ok := rule.Constraints.(string)
+	if !ok || strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("invalid constraints %v of expression rule: expected a condition, like \"quantity * unit_price == total\"", rule.Constraints)
+	}
+	condition, err := ParseExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition of expression rule: %w", err)
+	}
+	return condition, nil
+}
+
+// parseUniqueKey resolves the key fields of a "unique" rule: the rule field, then the constraint fields,
+// and the files and hashing of the rule when its constraints are an object.
+func (s *ValidateService) parseUniqueKey(rule *ValidationRule, inputFile string) error {
+	keyFields := []string{rule.Field}
+	var others []string
+	constraints, extended := rule.Constraints.(map[string]interface{})
+	if extended {
+		others = parseColumnList(constraints["fields"])
+	}
+	switch constraints := rule.Constraints.(type) {
+	case map[string]interface{}:
+	case string:
+		if constraints != "" {
+			others = strings.Split(constraints, ",")
+		}
+	case []string:
+		others = constraints
+	case []interface{}:
+		for _, field := range constraints {
+			if name, ok := field.(string); ok {
+				others = append(others, name)
 			}
 		}
+	case nil:
+	default:
+		return fmt.Errorf("invalid constraints of unique rule on field '%s': expected a list of fields or an object", rule.Field)
 	}
+	for _, field := range others {
+		if field = strings.TrimSpace(field); field != "" && field != rule.Field {
+			keyFields = append(keyFields, field)
+		}
+	}
+	rule.keyFields = keyFields
 
-	return opts, nil
+	if extended {
+		unique, err := s.parseUniqueConstraint(*rule, constraints, inputFile)
+		if err != nil {
+			return err
+		}
+		rule.unique = unique
+	}
+	return nil
+}
+
+// parseDuplicateRowColumns resolves the key columns of a "duplicate_row" rule, every column when none is given.
+func parseDuplicateRowColumns(rule ValidationRule) ([]string, error) {
+	switch rule.Constraints.(type) {
+	case string, []string, []interface{}, nil:
+	default:
+		return nil, fmt.Errorf("invalid constraints %v of duplicate_row rule: expected a list of columns", rule.Constraints)
+	}
+	var columns []string
+	for _, column := range parseColumnList(rule.Constraints) {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+// parseColumnList parses a list of columns, as a comma-separated string or a list.
+func parseColumnList(raw interface{}) []string {
+	var columns []string
+	switch list := raw.(type) {
+	case string:
+		if list != "" {
+			columns = strings.Split(list, ",")
+		}
+	case []string:
+		columns = list
+	case []interface{}:
+		for _, column := range list {
+			if name, ok := column.(string); ok {
+				columns = append(columns, name)
+			}
+		}
+	}
+	return columns
 }
 
 // getString helper to safely get string from map.
@@ -185,43 +1041,397 @@ func (s *ValidateService) getString(m map[string]interface{}, key string) string
 }
 
 // validateData performs the actual validation.
+//
+// Unique rules keep the row numbers of every non-empty key they see, so their memory grows
+// with the number of rows: on very high-cardinality fields of large files, it is of the order
+// of the size of the key columns themselves. Duplicate row rules only keep a 16-byte hash and
+// a row number per distinct row. Outlier rules read every row before the validation starts,
+// to compute the distribution of their field, and IQR ones hold its numeric values meanwhile.
+// Only the second and later rows of a duplicate set are invalid, but the earlier ones are also
+// returned with the invalid rows, in row order, so that whole duplicate sets can be exported
+// and reviewed.
 func (s *ValidateService) validateData(data []DataRow, opts *ValidateOptions) (*ValidationResult, []DataRow, []DataRow) {
 	result := &ValidationResult{
 		TotalRows:  len(data),
 		FieldStats: make(map[string]int),
 	}
+	prepareRules(data, opts, result)
+
+	issues := newIssueCollector(result, opts)
+	defer issues.sortRuleSummary()
+	scorer := newQualityScorer(opts)
+
+	// Check the columns once, and leave rules on missing ones out of the row checks
+	rules := s.checkStructure(data, opts, issues)
+	if opts.FailFast && len(result.Structural) > 0 {
+		result.RowsSkipped = len(data)
+		result.QualityScore, result.ScoreBreakdown = scorer.score(result)
+		return result, nil, nil
+	}
 
-	var validData, invalidData []DataRow
+	run := newValidationRun(data, opts, result, issues)
+	validated := 0
 
 	for i, row := range data {
-		rowErrors, rowWarnings := s.validateRow(row, opts.Rules, i+1)
-
-		if len(rowErrors) > 0 {
-			invalidData = append(invalidData, row)
-			result.Errors = append(result.Errors, rowErrors...)
-			result.InvalidRows++
-		} else {
-			validData = append(validData, row)
-			result.ValidRows++
+		if len(opts.AutoFix) > 0 {
+			var fixes []Fix
+			if row, fixes = s.fixRow(row, rules, i+1, opts.AutoFix); len(fixes) > 0 {
+				run.fixed[i] = row
+				result.Fixes = append(result.Fixes, fixes...)
+			}
 		}
 
-		result.Warnings = append(result.Warnings, rowWarnings...)
-
-		// Update field statistics
-		for field := range row.Fields {
-			result.FieldStats[field]++
+		rowErrors, rowWarnings := s.validateRow(row, rules, i+1)
+		if opts.schema != nil {
+			rowErrors = append(rowErrors, s.validateSchema(row, opts, i+1)...)
 		}
+		validated++
+		scorer.addRow(rowErrors, rowWarnings)
+		run.record(i+1, row, rowErrors, rowWarnings)
 
-		// Stop validation if fail_fast is enabled and we have errors
-		if opts.FailFast && len(rowErrors) > 0 {
+		// Stop validation if fail_fast is enabled and enough errors were found
+		if opts.FailFast && len(rowErrors) > 0 && result.ErrorCount >= max(opts.FailFastAfter, 1) {
 			break
 		}
 	}
+	result.RowsProcessed = validated
+	result.RowsSkipped = len(data) - validated
+
+	run.markDuplicateSets(opts.Rules)
+	invalidData := run.invalidRows(data[:validated])
+	result.FixSummary = summarizeFixes(result.Fixes)
+
+	if run.profiler != nil {
+		result.Profile = run.profiler.result()
+	}
 
 	// Calculate quality score
-	result.QualityScore = s.calculateQualityScore(result)
+	result.QualityScore, result.ScoreBreakdown = scorer.score(result)
+
+	return result, run.validData, invalidData
+}
+
+// prepareRules sets up the state of the rules before the rows are validated: the keys seen by
+// unique and duplicate_row rules, the distributions of outlier rules and the reference sets
+// of foreign_key rules.
+func prepareRules(data []DataRow, opts *ValidateOptions, result *ValidationResult) {
+	for i, rule := range opts.Rules {
+		switch rule.Type {
+		case "unique":
+			opts.Rules[i].seen = make(map[string][]int)
+		case "duplicate_row":
+			opts.Rules[i].rows = &duplicateRows{first: make(map[[16]byte]firstRow)}
+		case "outlier":
+			// a first pass over the rows sets the distribution rows are then checked against
+			if outlier, ok := rule.constraint.(*outlierConstraint); ok {
+				outlier.fit(data, rule.Field)
+			}
+		case "foreign_key":
+			if reference, ok := rule.constraint.(*foreignKey); ok {
+				result.References = append(result.References, ReferenceSet{
+					Field:  rule.Field,
+					File:   reference.file,
+					Column: reference.column,
+					Size:   len(reference.values),
+				})
+			}
+		}
+	}
+}
+
+// checkStructure checks the columns of a dataset, and returns the rules whose fields are all there.
+func (s *ValidateService) checkStructure(data []DataRow, opts *ValidateOptions, issues *issueCollector) []ValidationRule {
+	if len(data) == 0 {
+		return opts.Rules
+	}
+	structural, missing := s.validateStructure(datasetHeaders(data), opts)
+	for _, issue := range structural {
+		issues.add(issue, true)
+	}
+	rules := make([]ValidationRule, 0, len(opts.Rules))
+	for _, rule := range opts.Rules {
+		if !rule.references(missing) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// validationRun gathers the rows of a validation as they are validated.
+type validationRun struct {
+	result    *ValidationResult
+	issues    *issueCollector
+	profiler  *fieldProfiler
+	validData []DataRow
+	exported  map[int]bool              // row numbers of the rows returned as invalid
+	fixed     map[int]DataRow           // rows auto_fix changed, by index
+	rowErrors map[int][]ValidationError // errors of the invalid rows, kept for their export
+}
+
+func newValidationRun(data []DataRow, opts *ValidateOptions, result *ValidationResult, issues *issueCollector) *validationRun {
+	run := &validationRun{
+		result:   result,
+		issues:   issues,
+		exported: make(map[int]bool),
+		fixed:    make(map[int]DataRow),
+	}
+	if opts.IncludeProfile && len(data) > 0 {
+		run.profiler = newFieldProfiler(datasetHeaders(data))
+	}
+	if opts.ExportInvalid && opts.InvalidExport != InvalidExportPlain {
+		run.rowErrors = make(map[int][]ValidationError)
+	}
+	return run
+}
+
+// record adds the issues of a validated row to the result, and the row to the valid or invalid ones.
+func (r *validationRun) record(rowNumber int, row DataRow, rowErrors, rowWarnings []ValidationError) {
+	if len(rowErrors) > 0 {
+		r.exported[rowNumber] = true
+		if r.rowErrors != nil {
+			r.rowErrors[rowNumber] = rowErrors
+		}
+		for _, issue := range rowErrors {
+			r.issues.add(issue, false)
+		}
+		r.result.InvalidRows++
+	} else {
+		r.validData = append(r.validData, row)
+		r.result.ValidRows++
+	}
+
+	for _, issue := range rowWarnings {
+		r.issues.add(issue, false)
+	}
+
+	// Update field statistics
+	for field := range row.Fields {
+		r.result.FieldStats[field]++
+	}
+	if r.profiler != nil {
+		r.profiler.add(row)
+	}
+}
+
+// markDuplicateSets counts the duplicate sets of the rules, and exports their first rows
+// along with their duplicates.
+func (r *validationRun) markDuplicateSets(rules []ValidationRule) {
+	for _, rule := range rules {
+		if rule.rows != nil {
+			r.result.DuplicateGroups += len(rule.rows.groups)
+		}
+		if rule.severity() != "error" {
+			continue
+		}
+		for _, rowNumbers := range rule.seen {
+			if len(rowNumbers) > 1 {
+				r.exported[rowNumbers[0]] = true
+			}
+		}
+		if rule.rows != nil {
+			for _, rowNumber := range rule.rows.groups {
+				r.exported[rowNumber] = true
+			}
+		}
+	}
+}
+
+// invalidRows returns the exported rows of the validated ones, in row order, fixes applied.
+func (r *validationRun) invalidRows(validated []DataRow) []DataRow {
+	var invalidData []DataRow
+	for i := range validated {
+		if !r.exported[i+1] {
+			continue
+		}
+		row, ok := r.fixed[i]
+		if !ok {
+			row = validated[i]
+		}
+		invalidData = append(invalidData, row)
+		if r.rowErrors != nil {
+			r.result.invalidRecords = append(r.result.invalidRecords, invalidRecord{row: row, errors: r.rowErrors[i+1]})
+		}
+	}
+	return invalidData
+}
+
+// datasetHeaders returns the columns of a dataset: those of its first row,
+// or every field of its rows in order of appearance when rows have no columns.
+func datasetHeaders(data []DataRow) []string {
+	if len(data[0].Columns) > 0 {
+		return data[0].Columns
+	}
+	return collectHeaders(data)
+}
+
+// validateStructure checks the headers of a dataset, returning one error per missing required
+// column, rule fields included, per forbidden column, and for columns out of the expected order.
+// It also returns the missing columns, which rows are not checked for.
+func (s *ValidateService) validateStructure(headers []string, opts *ValidateOptions) ([]ValidationError, map[string]bool) {
+	present := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		present[header] = true
+	}
+
+	var structural []ValidationError
+	missing := make(map[string]bool)
+	addMissing := func(column, reason string) {
+		if column == "" || present[column] || missing[column] {
+			return
+		}
+		missing[column] = true
+		structural = append(structural, ValidationError{
+			FieldName: column,
+			RuleType:  "required_column",
+			Message:   fmt.Sprintf("Column '%s' is missing%s, available columns: %s", column, reason, strings.Join(headers, ", ")),
+			Severity:  "error",
+		})
+	}
+	for _, column := range opts.RequiredColumns {
+		addMissing(column, "")
+	}
+	for _, rule := range opts.Rules {
+		reason := fmt.Sprintf(" (read by the %s rule on field '%s')", rule.Type, rule.Field)
+		if rule.Field == "" {
+			reason = fmt.Sprintf(" (read by the %s rule)", rule.Type)
+		}
+		for _, column := range rule.fields() {
+			addMissing(column, reason)
+		}
+	}
+
+	for _, column := range opts.ForbiddenColumns {
+		if present[column] {
+			structural = append(structural, ValidationError{
+				FieldName: column,
+				RuleType:  "forbidden_column",
+				Message:   fmt.Sprintf("Column '%s' is forbidden", column),
+				Severity:  "error",
+			})
+		}
+	}
+
+	if issue := checkColumnOrder(headers, present, opts.ExpectedColumnOrder); issue != nil {
+		structural = append(structural, *issue)
+	}
+
+	return structural, missing
+}
+
+// checkColumnOrder checks that the expected columns that are present are in their expected order.
+func checkColumnOrder(headers []string, present map[string]bool, order []string) *ValidationError {
+	if len(order) == 0 {
+		return nil
+	}
+	// the expected columns that are present, in their expected then actual order
+	expected := make(map[string]bool, len(order))
+	var wanted, actual []string
+	for _, column := range order {
+		if present[column] && !expected[column] {
+			expected[column] = true
+			wanted = append(wanted, column)
+		}
+	}
+	for _, header := range headers {
+		if expected[header] {
+			actual = append(actual, header)
+		}
+	}
+	if strings.Join(wanted, ",") == strings.Join(actual, ",") {
+		return nil
+	}
+	return &ValidationError{
+		RuleType: "column_order",
+		Message:  fmt.Sprintf("Columns are not in the expected order: expected %s, got %s", strings.Join(wanted, ", "), strings.Join(actual, ", ")),
+		Severity: "error",
+	}
+}
+
+// validateDuplicateRow checks whether a row repeats an earlier one on the key columns of a
+// "duplicate_row" rule, reporting the first occurrence of duplicates.
+func (s *ValidateService) validateDuplicateRow(row DataRow, rule ValidationRule, rowNumber int) *ValidationError {
+	if rule.rows == nil {
+		return nil
+	}
+	hash := rule.rowHash(row)
+	first, found := rule.rows.first[hash]
+	if !found {
+		rule.rows.first[hash] = firstRow{number: rowNumber}
+		return nil
+	}
+	if !first.duplicated {
+		rule.rows.groups = append(rule.rows.groups, first.number)
+		rule.rows.first[hash] = firstRow{number: first.number, duplicated: true}
+	}
+
+	message := rule.Message
+	if message == "" {
+		message = fmt.Sprintf("Duplicate row, already found at row %d", first.number)
+		if len(rule.keyFields) > 0 {
+			message = fmt.Sprintf("Duplicate row on %s, already found at row %d", strings.Join(rule.keyFields, ", "), first.number)
+		}
+	}
+	return &ValidationError{
+		RowNumber:  rowNumber,
+		LineNumber: row.LineNumber,
+		SourceFile: row.SourceFile,
+		FieldName:  rule.Field,
+		RuleType:   rule.Type,
+		Message:    message,
+		Severity:   rule.severity(),
+		RowData:    row,
+	}
+}
+
+// validateExpression checks whether a row satisfies the condition of an "expression" rule.
+// Rows whose condition cannot be evaluated, like when arithmetic reads a non-numeric value,
+// fail the rule with their own message. Messages show the values of the fields the condition reads.
+func (s *ValidateService) validateExpression(row DataRow, rule ValidationRule, rowNumber int) *ValidationError {
+	if rule.condition == nil {
+		return nil
+	}
+	ok, err := rule.condition.Check(row)
+	if ok {
+		return nil
+	}
+
+	message := rule.Message
+	if message == "" {
+		values := make([]string, 0, len(rule.condition.Fields()))
+		for _, field := range rule.condition.Fields() {
+			values = append(values, fmt.Sprintf("%s=%q", field, row.Fields[field]))
+		}
+		message = fmt.Sprintf("Expression %q is false (%s)", rule.condition.String(), strings.Join(values, ", "))
+		if err != nil {
+			message = fmt.Sprintf("Expression %q cannot be evaluated: %s (%s)", rule.condition.String(), err, strings.Join(values, ", "))
+		}
+	}
+	return &ValidationError{
+		RowNumber:  rowNumber,
+		LineNumber: row.LineNumber,
+		SourceFile: row.SourceFile,
+		FieldName:  rule.Field,
+		FieldValue: row.Fields[rule.Field],
+		RuleType:   rule.Type,
+		Message:    message,
+		Severity:   rule.severity(),
+		RowData:    row,
+	}
+}
+
+// maxDescribedValues is the number of allowed values enum rule messages list.
+const maxDescribedValues = 10
 
-	return result, validData, invalidData
+// describeValues lists the allowed values of an enum rule for messages, the first ones only when many.
+func describeValues(values []string) string {
+	quoted := make([]string, 0, min(len(values), maxDescribedValues))
+	for _, value := range values[:min(len(values), maxDescribedValues)] {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+	if len(values) > maxDescribedValues {
+		return fmt.Sprintf("%s and %d more", strings.Join(quoted, ", "), len(values)-maxDescribedValues)
+	}
+	return strings.Join(quoted, ", ")
 }
 
 // validateRow validates a single row against all rules.
@@ -243,100 +1453,43 @@ func (s *ValidateService) validateRow(row DataRow, rules []ValidationRule, rowNu
 }
 
 // validateField validates a single field against a rule.
-//
-//nolint:gocyclo
 func (s *ValidateService) validateField(row DataRow, rule ValidationRule, rowNumber int) *ValidationError {
+	if rule.Type == "duplicate_row" {
+		return s.validateDuplicateRow(row, rule, rowNumber)
+	}
+	if rule.Type == "expression" {
+		return s.validateExpression(row, rule, rowNumber)
+	}
+
 	fieldValue, exists := row.Fields[rule.Field]
 	if !exists {
 		return &ValidationError{
 			RowNumber:  rowNumber,
+			LineNumber: row.LineNumber,
+			SourceFile: row.SourceFile,
 			FieldName:  rule.Field,
 			FieldValue: "",
 			RuleType:   rule.Type,
-			Message:    fmt.Sprintf("Field '%s' is missing", rule.Field),
+			Message:    fmt.Sprintf("Field '%s' is missing (%s)", rule.Field, row.Location()),
 			Severity:   "error",
 			RowData:    row,
 		}
 	}
 
-	var isValid bool
 	var message string
-
-	switch rule.Type {
-	case "required":
-		isValid = fieldValue != ""
-		if !isValid {
-			message = "Field is required"
-		}
-
-	case "email":
-		isValid = s.validateEmail(fieldValue)
-		if !isValid {
-			message = "Invalid email format"
-		}
-
-	case "numeric":
-		isValid = s.validateNumeric(fieldValue)
-		if !isValid {
-			message = "Value must be numeric"
-		}
-
-	case "regex": //nolint:goconst
-		if pattern, ok := rule.Constraints.(string); ok {
-			isValid = s.validateRegex(fieldValue, pattern)
-			if !isValid {
-				message = "Value does not match pattern: " + pattern
-			}
-		} else {
-			message = "Regex pattern not specified"
-		}
-
-	case "min_length":
-		if minLength, ok := rule.Constraints.(float64); ok {
-			isValid = len(fieldValue) >= int(minLength)
-			if !isValid {
-				message = fmt.Sprintf("Value must be at least %d characters", int(minLength))
-			}
-		} else {
-			message = "Min length not specified"
-		}
-
-	case "max_length":
-		if maxLength, ok := rule.Constraints.(float64); ok {
-			isValid = len(fieldValue) <= int(maxLength)
-			if !isValid {
-				message = fmt.Sprintf("Value must be at most %d characters", int(maxLength))
-			}
-		} else {
-			message = "Max length not specified"
-		}
-
-	case "range":
-		if constraints, ok := rule.Constraints.(map[string]interface{}); ok { //nolint:nestif
-			if mIn, ok := constraints["min"].(float64); ok {
-				if mAx, ok := constraints["max"].(float64); ok {
-					if num, err := strconv.ParseFloat(fieldValue, 64); err == nil {
-						isValid = num >= mIn && num <= mAx
-						if !isValid {
-							message = fmt.Sprintf("Value must be between %.2f and %.2f", mIn, mAx)
-						}
-					} else {
-						message = "Value must be numeric for range validation"
-					}
-				} else {
-					message = "Max value not specified for range"
-				}
-			} else {
-				message = "Min value not specified for range"
-			}
-		} else {
-			message = "Range constraints not specified"
-		}
-
+	switch {
+	case rule.constraint != nil:
+		message = rule.constraint.check(fieldValue)
+	case rule.Type == "unique":
+		message = checkUnique(row, rule, rowNumber)
+	case rule.Type == "compare_fields":
+		message = checkComparison(row, fieldValue, rule)
 	default:
 		// Unknown rule type - treat as warning
 		return &ValidationError{
 			RowNumber:  rowNumber,
+			LineNumber: row.LineNumber,
+			SourceFile: row.SourceFile,
 			FieldName:  rule.Field,
 			FieldValue: fieldValue,
 			RuleType:   rule.Type,
@@ -345,81 +1498,99 @@ func (s *ValidateService) validateField(row DataRow, rule ValidationRule, rowNum
 			RowData:    row,
 		}
 	}
+	if message == "" {
+		return nil
+	}
 
-	if !isValid {
-		errorMessage := message
-		if rule.Message != "" {
-			errorMessage = rule.Message
-		}
+	if rule.Message != "" {
+		message = rule.Message
+	}
 
-		severity := "error"
-		if rule.Type == "regex" || rule.Type == "min_length" || rule.Type == "max_length" {
-			severity = "warning" // These are often warnings rather than errors
-		}
-
-		return &ValidationError{
-			RowNumber:  rowNumber,
-			FieldName:  rule.Field,
-			FieldValue: fieldValue,
-			RuleType:   rule.Type,
-			Message:    errorMessage,
-			Severity:   severity,
-			RowData:    row,
+	if checksum, ok := rule.constraint.(*checksumConstraint); ok && checksum.mask {
+		fieldValue = maskValue(fieldValue)
+		fields := make(map[string]string, len(row.Fields))
+		for field, value := range row.Fields {
+			fields[field] = value
 		}
+		fields[rule.Field] = fieldValue
+		row.Fields = fields
 	}
 
-	return nil
-}
-
-// validateEmail validates email format.
-func (s *ValidateService) validateEmail(email string) bool {
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	//bearer:disable go_lang_permissive_regex_validation
-	return emailRegex.MatchString(email)
+	return &ValidationError{
+		RowNumber:  rowNumber,
+		LineNumber: row.LineNumber,
+		SourceFile: row.SourceFile,
+		FieldName:  rule.Field,
+		FieldValue: fieldValue,
+		RuleType:   rule.Type,
+		Message:    message,
+		Severity:   rule.severity(),
+		RowData:    row,
+	}
 }
 
-// validateNumeric validates numeric format.
-func (s *ValidateService) validateNumeric(value string) bool {
-	_, err := strconv.ParseFloat(value, 64)
-	return err == nil
+// checkUnique checks the key of a row against the earlier rows of a "unique" rule, and records it.
+func checkUnique(row DataRow, rule ValidationRule, rowNumber int) string {
+	key, empty := rule.uniqueKey(row)
+	if empty {
+		return ""
+	}
+	earlier := rule.seen[key]
+	if rule.seen != nil {
+		rule.seen[key] = append(earlier, rowNumber)
+	}
+	var primed []uniqueOccurrence
+	if rule.unique != nil {
+		primed = rule.unique.primed[key]
+	}
+	if len(earlier)+len(primed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Duplicate value of %s, already found at %s", strings.Join(rule.keyFields, ", "), describeOccurrences(primed, earlier))
 }
 
-// validateRegex validates against regex pattern.
-func (s *ValidateService) validateRegex(value, pattern string) bool {
-	regex, err := regexp.Compile(pattern)
-	if err != nil {
-		return false
+// checkComparison compares the value of a "compare_fields" rule to the other field of its row.
+func checkComparison(row DataRow, value string, rule ValidationRule) string {
+	if rule.compare == nil {
+		return "Comparison constraints not specified"
+	}
+	other, ok := row.Fields[rule.compare.field]
+	if !ok {
+		return fmt.Sprintf("Field '%s' cannot be compared to missing field '%s' (%s)", rule.Field, rule.compare.field, row.Location())
 	}
-	return regex.MatchString(value)
+	return rule.compare.check(value, other)
 }
 
-// calculateQualityScore calculates data quality score.
-func (s *ValidateService) calculateQualityScore(result *ValidationResult) float64 {
-	if result.TotalRows == 0 {
-		return 0
+// scoringNote names the scoring profile of a result in messages, when it is not the default one.
+func scoringNote(result *ValidationResult) string {
+	if result.ScoreBreakdown == nil || result.ScoreBreakdown.Profile == ScoringDefault {
+		return ""
 	}
+	return fmt.Sprintf(" (%s scoring)", result.ScoreBreakdown.Profile)
+}
 
-	// Base score on valid rows percentage
-	score := float64(result.ValidRows) / float64(result.TotalRows) * 100
-
-	// Deduct points for warnings
-	warningPenalty := float64(len(result.Warnings)) / float64(result.TotalRows) * 5
-	score -= warningPenalty
-
-	// Ensure score is between 0 and 100
-	if score < 0 {
-		score = 0
+// checkThresholds returns the quality thresholds of the options a result does not meet.
+func checkThresholds(result *ValidationResult, opts *ValidateOptions) []ThresholdFailure {
+	var failures []ThresholdFailure
+	if opts.MinQualityScore > 0 && result.QualityScore < opts.MinQualityScore {
+		failures = append(failures, ThresholdFailure{
+			Threshold: ThresholdMinQualityScore,
+			Message:   fmt.Sprintf("quality score %.2f%% is below the minimum of %.2f%%%s", result.QualityScore, opts.MinQualityScore, scoringNote(result)),
+		})
 	}
-	if score > 100 {
-		score = 100
+	if opts.MaxInvalidRows != nil && result.InvalidRows > *opts.MaxInvalidRows {
+		failures = append(failures, ThresholdFailure{
+			Threshold: ThresholdMaxInvalidRows,
+			Message:   fmt.Sprintf("%d invalid records exceed the maximum of %d", result.InvalidRows, *opts.MaxInvalidRows),
+		})
 	}
-
-	return score
+	return failures
 }
 
 // ValidateFile validates data from a file
 // This convenience method demonstrates file-based validation.
-func (s *ValidateService) ValidateFile(inputFile, outputFile string, rules []ValidationRule, failFast bool) (*ValidationResult, error) {
+// extraOptions may carry any additional ProcessData option, like schema_file, and can be nil.
+func (s *ValidateService) ValidateFile(inputFile, outputFile string, rules []ValidationRule, failFast bool, extraOptions map[string]interface{}) (*ValidationResult, error) {
 	s.logger.Info().
 		Str("input", inputFile).
 		Str("output", outputFile).
@@ -427,23 +1598,16 @@ func (s *ValidateService) ValidateFile(inputFile, outputFile string, rules []Val
 		Bool("fail_fast", failFast).
 		Msg("Starting file validation")
 
-	options := map[string]interface{}{
+	options := mergeOptions(map[string]interface{}{
 		"input_file":  inputFile,
 		"output_file": outputFile,
 		"rules":       rules,
 		"fail_fast":   failFast,
-	}
+	}, extraOptions)
 
-	validData, err := s.ProcessData(nil, options)
+	result, _, _, err := s.ValidateRows(nil, options)
 	if err != nil {
 		return nil, err
 	}
-
-	// Create validation result
-	result := ValidationResult{
-		ValidRows:    len(validData),
-		TotalRows:    len(validData), // This would be calculated properly in the actual validation
-		QualityScore: 100.0,
-	}
-	return &result, nil
+	return result, nil
 }
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Formats of the export of invalid rows.
+const (
+	InvalidExportField  = "field"  // rows with an ErrorsField listing their errors, the default
+	InvalidExportObject = "object" // {"row": fields, "errors": [{"field", "rule", "message"}]} objects
+	InvalidExportPlain  = "plain"  // bare rows
+)
+
+// ErrorsField is the field listing the errors of exported invalid rows, as "field:rule:message"
+// separated by "; ".
+const ErrorsField = "_errors"
+
+// parseInvalidExport validates the format of the export of invalid rows, the empty one being InvalidExportField.
+func parseInvalidExport(format string) (string, error) {
+	switch format = strings.ToLower(format); format {
+	case "":
+		return InvalidExportField, nil
+	case InvalidExportField, InvalidExportObject, InvalidExportPlain:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown invalid_export %q: expected field, object or plain", format)
+	}
+}
+
+// invalidRecord is an exported invalid row with its errors. The first rows of duplicate sets
+// have none, their duplicates holding the errors.
+type invalidRecord struct {
+	row    DataRow
+	errors []ValidationError
+}
+
+// exportBase returns the path exports are named after, without extension: the output file,
+// else the input file, else "validation" in the working directory.
+func exportBase(opts *ValidateOptions) string {
+	for _, file := range []string{opts.OutputFile, opts.InputFile} {
+		if file != "" {
+			return strings.TrimSuffix(file, filepath.Ext(file))
+		}
+	}
+	return "validation"
+}
+
+// writeInvalidExport writes invalid rows to a JSON file in the invalid_export format.
+func (s *ValidateService) writeInvalidExport(path string, records []invalidRecord, invalidData []DataRow, opts *ValidateOptions) error {
+	switch opts.InvalidExport {
+	case InvalidExportPlain:
+		return s.fileService.WriteJSONRows(path, invalidData, opts.IncludeMetadata)
+	case InvalidExportObject:
+		objects := make([]invalidObject, 0, len(records))
+		for _, record := range records {
+			objects = append(objects, invalidObject{record: record, includeMetadata: opts.IncludeMetadata})
+		}
+		return s.fileService.WriteJSON(path, objects)
+	default:
+		rows := make([]DataRow, 0, len(records))
+		for _, record := range records {
+			row := record.row
+			row.Fields = maps.Clone(row.Fields)
+			row.SetField(ErrorsField, formatRowErrors(record.errors))
+			rows = append(rows, row)
+		}
+		return s.fileService.WriteJSONRows(path, rows, opts.IncludeMetadata)
+	}
+}
+
+// formatRowErrors lists errors as "field:rule:message" separated by "; ".
+func formatRowErrors(errors []ValidationError) string {
+	parts := make([]string, 0, len(errors))
+	for _, issue := range errors {
+		parts = append(parts, issue.FieldName+":"+issue.RuleType+":"+issue.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// invalidObject encodes an invalid record in the InvalidExportObject format.
+type invalidObject struct {
+	record          invalidRecord
+	includeMetadata bool
+}
+
+// exportedError is an error of an invalid record in the InvalidExportObject format.
+type exportedError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// MarshalJSON encodes the row fields in column order, then the errors, then the source
+// metadata when included.
+func (o invalidObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"row":`)
+	if err := writeOrderedFields(&buf, o.record.row.Keys(), o.record.row.Fields); err != nil {
+		return nil, err
+	}
+
+	errors := make([]exportedError, 0, len(o.record.errors))
+	for _, issue := range o.record.errors {
+		errors = append(errors, exportedError{Field: issue.FieldName, Rule: issue.RuleType, Message: issue.Message})
+	}
+	encoded, err := json.Marshal(errors)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString(`,"errors":`)
+	buf.Write(encoded)
+
+	if o.includeMetadata {
+		buf.WriteString(`,"line_number":` + strconv.Itoa(o.record.row.LineNumber))
+		if o.record.row.SourceFile != "" {
+			sourceFile, err := json.Marshal(o.record.row.SourceFile)
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString(`,"source_file":`)
+			buf.Write(sourceFile)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
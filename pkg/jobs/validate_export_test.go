@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateService_invalidExport(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		format   string
+		metadata bool
+		expected string
+	}{
+		{"", false, `[{"fields":{"id":"1","email":"bob","_errors":"email:email:Invalid email format"}},` +
+			`{"fields":{"id":"1","email":"cid@corp.com","_errors":"id:unique:Duplicate value of id, already found at row 1; email:max_length:Value must be at most 10 characters"}}]`},
+		{InvalidExportObject, true, `[{"row":{"id":"1","email":"bob"},"errors":[{"field":"email","rule":"email","message":"Invalid email format"}],"line_number":2,"source_file":"%s"},` +
+			`{"row":{"id":"1","email":"cid@corp.com"},"errors":[{"field":"id","rule":"unique","message":"Duplicate value of id, already found at row 1"},{"field":"email","rule":"max_length","message":"Value must be at most 10 characters"}],"line_number":4,"source_file":"%s"}]`},
+		{InvalidExportPlain, false, `[{"fields":{"id":"1","email":"bob"}},{"fields":{"id":"1","email":"cid@corp.com"}}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run("format "+tc.format, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			dir := t.TempDir()
+			input := filepath.Join(dir, "orders.csv")
+			is.NoError(os.WriteFile(input, []byte("id,email\n1,bob\n2,ann@co.io\n1,cid@corp.com\n"), 0o600))
+
+			s := newTestValidateService(t)
+			_, _, _, err := s.ValidateRows(nil, map[string]interface{}{
+				"input_file":       input,
+				"export_valid":     true,
+				"export_invalid":   true,
+				"invalid_export":   tc.format,
+				"include_metadata": tc.metadata,
+				"rules": []ValidationRule{
+					{Field: "id", Type: "unique"},
+					{Field: "email", Type: "email"},
+					{Field: "email", Type: "max_length", Constraints: float64(10), Severity: "error"},
+				},
+			})
+			is.NoError(err)
+
+			// without output file, exports are named after the input file
+			invalid, err := os.ReadFile(filepath.Join(dir, "orders_invalid.json"))
+			is.NoError(err)
+			expected := tc.expected
+			if tc.metadata {
+				expected = fmt.Sprintf(expected, input, input)
+			}
+			is.JSONEq(expected, string(invalid))
+
+			// the valid export stays clean
+			valid, err := os.ReadFile(filepath.Join(dir, "orders_valid.json"))
+			is.NoError(err)
+			is.NotContains(string(valid), ErrorsField)
+			if !tc.metadata {
+				is.JSONEq(`[{"fields":{"id":"2","email":"ann@co.io"}}]`, string(valid))
+			}
+		})
+	}
+
+	_, err := newTestValidateService(t).parseValidateOptions(map[string]interface{}{"invalid_export": "csv"})
+	assert.Error(t, err)
+}
+
+func TestExportBase(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	is.Equal("out/report", exportBase(&ValidateOptions{OutputFile: "out/report.html", InputFile: "data/orders.csv"}))
+	is.Equal("data/orders", exportBase(&ValidateOptions{InputFile: "data/orders.csv"}))
+	is.Equal("validation", exportBase(&ValidateOptions{}))
+}
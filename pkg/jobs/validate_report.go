@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReportFormat defines the format of validation reports.
+type ReportFormat string
+
+const (
+	// ReportJSON writes the ValidationResult as JSON.
+	ReportJSON ReportFormat = "json"
+	// ReportCSV writes one row per detailed validation error, warnings and structural errors included.
+	ReportCSV ReportFormat = "csv"
+	// ReportHTML writes a self-contained page with the summary and the first issues.
+	ReportHTML ReportFormat = "html"
+)
+
+// ParseReportFormat validates a report format name, the empty name being left to reportFormat.
+func ParseReportFormat(name string) (ReportFormat, error) {
+	switch format := ReportFormat(strings.ToLower(name)); format {
+	case "", ReportJSON, ReportCSV, ReportHTML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q: expected json, csv or html", name)
+	}
+}
+
+// reportFormat returns the format of a report file: the given one, or the one of its extension, JSON by default.
+func reportFormat(path string, format ReportFormat) ReportFormat {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReportCSV
+	case ".html", ".htm":
+		return ReportHTML
+	default:
+		return ReportJSON
+	}
+}
+
+// maxHTMLReportIssues is the number of issues listed by HTML reports, which stay readable in a browser.
+const maxHTMLReportIssues = 500
+
+// reportHeaders are the columns of CSV reports.
+var reportHeaders = []string{"row", "line", "source_file", "field", "value", "rule", "severity", "message"}
+
+// reportIssues returns the structural errors, errors and warnings of a result, in this order.
+func reportIssues(result *ValidationResult) []ValidationError {
+	issues := make([]ValidationError, 0, len(result.Structural)+len(result.Errors)+len(result.Warnings))
+	issues = append(issues, result.Structural...)
+	issues = append(issues, result.Errors...)
+	return append(issues, result.Warnings...)
+}
+
+// writeReport writes the report of a result in the given format.
+func (s *ValidateService) writeReport(path string, result *ValidationResult, opts *ValidateOptions) error {
+	//nolint:exhaustive
+	switch reportFormat(path, opts.ReportFormat) {
+	case ReportCSV:
+		return s.writeCSVReport(path, result, opts.CSV)
+	case ReportHTML:
+		return s.writeHTMLReport(path, result)
+	default:
+		return s.fileService.WriteJSON(path, result)
+	}
+}
+
+// writeCSVReport writes every detailed issue of a result, one per row. Structural errors have no row nor line.
+func (s *ValidateService) writeCSVReport(path string, result *ValidationResult, csvOpts CSVWriteOptions) error {
+	issues := reportIssues(result)
+	records := make([][]string, 0, len(issues))
+	for _, issue := range issues {
+		var row, line string
+		if issue.RowNumber > 0 {
+			row = strconv.Itoa(issue.RowNumber)
+		}
+		if issue.LineNumber > 0 {
+			line = strconv.Itoa(issue.LineNumber)
+		}
+		records = append(records, []string{row, line, issue.SourceFile, issue.FieldName, issue.FieldValue, issue.RuleType, issue.Severity, issue.Message})
+	}
+	return s.fileService.WriteCSV(path, reportHeaders, records, csvOpts)
+}
+
+// htmlReport is the data of the HTML report template.
+type htmlReport struct {
+	Result    *ValidationResult
+	Issues    []ValidationError
+	Total     int  // issues, sampled ones included
+	Truncated bool // whether Issues lists less than Total issues
+	Sampled   bool // whether the result only details some issues
+}
+
+// writeHTMLReport writes a self-contained page with the summary of a result, its issue counts
+// per rule, and its first maxHTMLReportIssues detailed issues.
+func (s *ValidateService) writeHTMLReport(path string, result *ValidationResult) error {
+	issues := reportIssues(result)
+	report := htmlReport{
+		Result:  result,
+		Issues:  issues,
+		Total:   len(result.Structural) + result.ErrorCount + result.WarningCount,
+		Sampled: result.ErrorsTruncated || result.WarningsTruncated,
+	}
+	if len(issues) > maxHTMLReportIssues {
+		report.Issues = issues[:maxHTMLReportIssues]
+	}
+	report.Truncated = len(report.Issues) < report.Total
+
+	return s.fileService.writeOutput(path, func(w io.Writer) error {
+		return htmlReportTemplate.Execute(w, report)
+	})
+}
+
+// htmlReportTemplate renders htmlReport values, with no external resource.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Validation report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+.error { color: #b00020; }
+.warning { color: #a15c00; }
+.note { font-style: italic; }
+</style>
+</head>
+<body>
+<h1>Validation report</h1>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Total rows</th><td>{{.Result.TotalRows}}</td></tr>
+{{- if .Result.RowsSkipped}}
+<tr><th>Skipped rows</th><td>{{.Result.RowsSkipped}} (stopped early at row {{.Result.RowsProcessed}})</td></tr>
+{{- end}}
+<tr><th>Valid rows</th><td>{{.Result.ValidRows}}</td></tr>
+<tr><th>Invalid rows</th><td>{{.Result.InvalidRows}}</td></tr>
+<tr><th>Errors</th><td>{{.Result.ErrorCount}}</td></tr>
+<tr><th>Warnings</th><td>{{.Result.WarningCount}}</td></tr>
+<tr><th>Structural errors</th><td>{{len .Result.Structural}}</td></tr>
+{{- if .Result.DuplicateGroups}}
+<tr><th>Duplicate groups</th><td>{{.Result.DuplicateGroups}}</td></tr>
+{{- end}}
+<tr><th>Quality score</th><td>{{printf "%.2f" .Result.QualityScore}}%</td></tr>
+</table>
+{{- range .Result.ThresholdFailures}}
+<p class="error">Threshold failed ({{.Threshold}}): {{.Message}}</p>
+{{- end}}
+{{if .Result.RuleSummary}}
+<h2>Issues per rule</h2>
+<table>
+<tr><th>Rule</th><th>Field</th><th>Errors</th><th>Warnings</th><th>Rows</th><th>Examples</th></tr>
+{{- range .Result.RuleSummary}}
+<tr><td>{{.Rule}}</td><td>{{.Field}}</td><td>{{.Errors}}</td><td>{{.Warnings}}</td><td>{{.Rows}}</td><td>{{range $i, $example := .Examples}}{{if $i}}, {{end}}{{printf "%q" $example}}{{end}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Issues</h2>
+{{- if .Truncated}}
+<p class="note">Showing the first {{len .Issues}} of {{.Total}} issues.
+{{- if .Sampled}} Only some issues were detailed, as limited by max_errors or errors_per_rule; counts are exact.
+{{- else}} The CSV report lists all of them.{{end}}</p>
+{{- end}}
+<table>
+<tr><th>Row</th><th>Line</th><th>Field</th><th>Value</th><th>Rule</th><th>Severity</th><th>Message</th></tr>
+{{- range .Issues}}
+<tr class="{{.Severity}}"><td>{{if .RowNumber}}{{.RowNumber}}{{end}}</td><td>{{if .LineNumber}}{{.LineNumber}}{{end}}</td><td>{{.FieldName}}</td><td>{{.FieldValue}}</td><td>{{.RuleType}}</td><td>{{.Severity}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No issue found.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateService_reports(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"name", "email"},
+		[]string{"a", "a@example.com"},
+		[]string{"", "b, at example.com"},
+		[]string{"<c>", "c@example.com"},
+	)
+	rules := []ValidationRule{{Field: "name", Type: "required"}, {Field: "email", Type: "email"}, {Field: "name", Type: "max_length", Constraints: float64(2)}}
+
+	// CSV picked from the extension
+	output := filepath.Join(dir, "report.csv")
+	_, err := s.ProcessData(rows, map[string]interface{}{
+		"output_file":      output,
+		"rules":            rules,
+		"required_columns": "id",
+	})
+	is.NoError(err)
+	content, err := os.ReadFile(output)
+	is.NoError(err)
+	is.Equal(`row,line,source_file,field,value,rule,severity,message
+,,,id,,required_column,error,"Column 'id' is missing, available columns: name, email"
+2,3,,name,,required,error,Field is required
+2,3,,email,"b, at example.com",email,error,Invalid email format
+3,4,,name,<c>,max_length,warning,Value must be at most 2 characters
+`, string(content))
+
+	// HTML forced by the option
+	output = filepath.Join(dir, "report.txt")
+	_, err = s.ProcessData(rows, map[string]interface{}{"output_file": output, "rules": rules, "report_format": "HTML"})
+	is.NoError(err)
+	content, err = os.ReadFile(output)
+	is.NoError(err)
+	html := string(content)
+	is.Contains(html, "<tr><th>Quality score</th><td>65.00%</td></tr>")
+	is.Contains(html, "<tr><td>required</td><td>name</td><td>1</td><td>0</td><td>1</td><td>&#34;&#34;</td></tr>")
+	is.Contains(html, "&lt;c&gt;")
+	is.NotContains(html, "Showing the first")
+
+	_, err = s.ProcessData(rows, map[string]interface{}{"output_file": output, "rules": rules, "report_format": "pdf"})
+	is.EqualError(err, `failed to parse validation options: unknown report format "pdf": expected json, csv or html`)
+}
+
+func TestValidateService_truncatedHTMLReport(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	values := make([][]string, 0, maxHTMLReportIssues+10)
+	for i := 0; i < maxHTMLReportIssues+10; i++ {
+		values = append(values, []string{""})
+	}
+	rows := testRows(t, []string{"name"}, values...)
+
+	dir := t.TempDir()
+	s := newTestValidateService(t)
+	options := map[string]interface{}{"rules": []ValidationRule{{Field: "name", Type: "required"}}}
+
+	_, err := s.ProcessData(rows, mergeOptions(options, map[string]interface{}{"output_file": filepath.Join(dir, "report.html")}))
+	is.NoError(err)
+	content, err := os.ReadFile(filepath.Join(dir, "report.html"))
+	is.NoError(err)
+	is.Contains(string(content), "Showing the first 500 of 510 issues. The CSV report lists all of them.")
+	is.Equal(maxHTMLReportIssues, strings.Count(string(content), `<tr class="error">`))
+
+	// CSV reports are complete
+	_, err = s.ProcessData(rows, mergeOptions(options, map[string]interface{}{"output_file": filepath.Join(dir, "report.csv")}))
+	is.NoError(err)
+	content, err = os.ReadFile(filepath.Join(dir, "report.csv"))
+	is.NoError(err)
+	is.Equal(maxHTMLReportIssues+11, strings.Count(string(content), "\n"))
+}
@@ -0,0 +1,853 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestValidateService(t *testing.T) *ValidateService {
+	t.Helper()
+
+	return &ValidateService{
+		fileService: newTestFileService(t),
+		logger:      zerolog.Nop(),
+	}
+}
+
+func TestValidateService_regexRules(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"code"}, []string{"AB-12"}, []string{"nope"})
+
+	valid, err := s.ProcessData(rows, map[string]interface{}{
+		"rules": []ValidationRule{{Field: "code", Type: "regex", Constraints: `^[A-Z]{2}-\d+$`}},
+	})
+	is.NoError(err)
+	// regex failures are warnings, so every row stays valid
+	is.Len(valid, 2)
+
+	_, err = s.ProcessData(rows, map[string]interface{}{
+		"rules": []ValidationRule{{Field: "code", Type: "regex", Constraints: `^[A-Z`}},
+	})
+	is.ErrorContains(err, "^[A-Z")
+}
+
+func TestValidateService_uniqueRule(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"id", "shop"},
+		[]string{"1", "a"},
+		[]string{"2", "a"},
+		[]string{"1", "b"},
+		[]string{"", "a"},
+		[]string{"1", "a"},
+		[]string{"", "a"},
+		[]string{"2", "a"},
+	)
+
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "id", Type: "unique"}},
+	})
+	is.NoError(err)
+	result, valid, invalid := s.validateData(rows, opts)
+	is.Equal(4, result.ValidRows)
+	is.Equal(3, result.InvalidRows)
+	is.Len(valid, 4)
+	// empty values are never duplicates
+	is.Equal([]int{3, 5, 7}, validationRows(result.Errors))
+	is.Equal("Duplicate value of id, already found at row 1, 3", result.Errors[1].Message)
+	// invalid rows include the first rows of the duplicate sets
+	is.Equal([]int{1, 2, 3, 5, 7}, dataRowLines(invalid))
+
+	// composite keys, decoded from generic JSON
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"field": "id", "type": "unique", "constraints": []interface{}{"shop"}}},
+	})
+	is.NoError(err)
+	result, _, invalid = s.validateData(rows, opts)
+	is.Equal([]int{5, 6, 7}, validationRows(result.Errors))
+	is.Equal([]int{1, 2, 4, 5, 6, 7}, dataRowLines(invalid))
+
+	// fail_fast stops at the first duplicate, still exporting its earlier row
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules":     []ValidationRule{{Field: "id", Type: "unique"}},
+		"fail_fast": true,
+	})
+	is.NoError(err)
+	result, valid, invalid = s.validateData(rows, opts)
+	is.Equal([]int{3}, validationRows(result.Errors))
+	is.Len(valid, 2)
+	is.Equal([]int{1, 3}, dataRowLines(invalid))
+
+	_, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "id", Type: "unique", Constraints: float64(2)}},
+	})
+	is.Error(err)
+}
+
+func TestValidateService_failFast(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"email"},
+		[]string{"ann@corp.com"},
+		[]string{"bob"},
+		[]string{"cid@corp.com"},
+		[]string{"dan"},
+		[]string{"eve"},
+		[]string{"fay@corp.com"},
+	)
+
+	testCases := []struct {
+		name      string
+		options   map[string]interface{}
+		processed int
+		invalid   int
+		score     float64
+	}{
+		{"disabled", map[string]interface{}{}, 6, 3, 50},
+		{"first error", map[string]interface{}{"fail_fast": true}, 2, 1, 50},
+		{"after 2 errors", map[string]interface{}{"fail_fast_after": 2}, 4, 2, 50},
+		{"after more errors than found", map[string]interface{}{"fail_fast": true, "fail_fast_after": float64(5)}, 6, 3, 50},
+		{"structural errors", map[string]interface{}{"fail_fast": true, "required_columns": []string{"name"}}, 0, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestValidateService(t)
+			tc.options["rules"] = []ValidationRule{{Field: "email", Type: "email"}}
+			opts, err := s.parseValidateOptions(tc.options)
+			is.NoError(err)
+			result, _, _ := s.validateData(rows, opts)
+			is.Equal(6, result.TotalRows)
+			is.Equal(tc.processed, result.RowsProcessed)
+			is.Equal(6-tc.processed, result.RowsSkipped)
+			is.Equal(tc.invalid, result.InvalidRows)
+			is.Equal(tc.processed, result.ValidRows+result.InvalidRows)
+			is.InDelta(tc.score, result.QualityScore, 0.001)
+		})
+	}
+
+	_, err := newTestValidateService(t).parseValidateOptions(map[string]interface{}{"fail_fast_after": 0})
+	assert.Error(t, err)
+}
+
+func TestValidateService_uniqueRuleAcrossFiles(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	january := filepath.Join(dir, "2024-01.csv")
+	is.NoError(os.WriteFile(january, []byte("id,shop\n1,a\n2,a\n,a\n"), 0o600))
+	february := filepath.Join(dir, "2024-02.csv")
+	is.NoError(os.WriteFile(february, []byte("id,shop\n3,b\n1,b\n"), 0o600))
+	march := filepath.Join(dir, "2024-03.jsonl")
+	is.NoError(os.WriteFile(march, []byte(`{"id":4,"shop":"c"}`+"\n"), 0o600))
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"id", "shop"},
+		[]string{"1", "c"},
+		[]string{"5", "c"},
+		[]string{"", "c"},
+		[]string{"5", "c"},
+		[]string{"4", "c"},
+	)
+
+	for _, hash := range []bool{false, true} {
+		// the glob matches the input file, which is left out
+		opts, err := s.parseValidateOptions(map[string]interface{}{
+			"input_file": february,
+			"rules": []interface{}{map[string]interface{}{"field": "id", "type": "unique", "constraints": map[string]interface{}{
+				"files":       []interface{}{filepath.Join(dir, "*.csv"), march},
+				"hash_values": hash,
+			}}},
+		})
+		is.NoError(err)
+		result, _, invalid := s.validateData(rows, opts)
+		is.Equal([]int{1, 4, 5}, validationRows(result.Errors))
+		is.Equal("Duplicate value of id, already found at row 1 of "+january, result.Errors[0].Message)
+		is.Equal("Duplicate value of id, already found at row 2", result.Errors[1].Message)
+		is.Equal("Duplicate value of id, already found at row 1 of "+march, result.Errors[2].Message)
+		is.Equal([]int{1, 2, 4, 5}, dataRowLines(invalid))
+	}
+
+	// composite keys, against the files only
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "id", Type: "unique", Constraints: map[string]interface{}{
+			"fields": []interface{}{"shop"},
+			"files":  filepath.Join(dir, "*.csv"),
+		}}},
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(testRows(t, []string{"id", "shop"}, []string{"1", "b"}, []string{"1", "b"}), opts)
+	is.Equal("Duplicate value of id, shop, already found at row 2 of "+february, result.Errors[0].Message)
+	is.Equal("Duplicate value of id, shop, already found at row 2 of "+february+", row 1", result.Errors[1].Message)
+
+	_, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "id", Type: "unique", Constraints: map[string]interface{}{"fields": "region", "files": january}}},
+	})
+	is.ErrorContains(err, "column 'region' is missing from "+january)
+
+	_, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "id", Type: "unique", Constraints: map[string]interface{}{"files": filepath.Join(dir, "*.tsv")}}},
+	})
+	is.ErrorContains(err, "matches no file")
+}
+
+func TestValidateService_duplicateRowRule(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"id", "shop", "qty"},
+		[]string{"1", "a", "5"},
+		[]string{"2", "a", "5"},
+		[]string{"1", "a", "5"},
+		[]string{"1", "a", "6"},
+		[]string{"1", "a", "5"},
+		[]string{"2", "a", "7"},
+	)
+
+	// every column by default
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Type: "duplicate_row"}},
+	})
+	is.NoError(err)
+	result, _, invalid := s.validateData(rows, opts)
+	is.Equal([]int{3, 5}, validationRows(result.Errors))
+	is.Equal("Duplicate row, already found at row 1", result.Errors[1].Message)
+	is.Equal(1, result.DuplicateGroups)
+	is.Equal([]int{1, 3, 5}, dataRowLines(invalid))
+
+	// key columns, decoded from generic JSON, as warnings
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"type": "duplicate_row", "constraints": []interface{}{"id", "shop"}, "severity": "warning"}},
+	})
+	is.NoError(err)
+	result, valid, _ := s.validateData(rows, opts)
+	is.Empty(result.Errors)
+	is.Len(valid, 6)
+	is.Equal([]int{3, 4, 5, 6}, validationRows(result.Warnings))
+	is.Equal("Duplicate row on id, shop, already found at row 2", result.Warnings[3].Message)
+	is.Equal(2, result.DuplicateGroups)
+
+	// missing key columns are structural errors
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Type: "duplicate_row", Constraints: "id,region"}},
+	})
+	is.NoError(err)
+	result, _, _ = s.validateData(rows, opts)
+	is.Len(result.Structural, 1)
+	is.Equal("Column 'region' is missing (read by the duplicate_row rule), available columns: id, shop, qty", result.Structural[0].Message)
+
+	_, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Type: "duplicate_row", Constraints: float64(2)}},
+	})
+	is.Error(err)
+}
+
+func TestValidateService_expressionRule(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"quantity", "unit_price", "total"},
+		[]string{"2", "10.5", "21"},
+		[]string{"3", "10", "31"},
+		[]string{"x", "10", "30"},
+	)
+
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"type": "expression", "constraints": "quantity * unit_price == total"}},
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.Equal([]int{2, 3}, validationRows(result.Errors))
+	is.Equal(`Expression "quantity * unit_price == total" is false (quantity="3", unit_price="10", total="31")`, result.Errors[0].Message)
+	is.Equal(`Expression "quantity * unit_price == total" cannot be evaluated: "x" is not a number (quantity="x", unit_price="10", total="30")`, result.Errors[1].Message)
+
+	// fields of the expression are required columns
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "total", Type: "expression", Constraints: "total <= budget", Severity: "warning"}},
+	})
+	is.NoError(err)
+	result, _, _ = s.validateData(rows, opts)
+	is.Len(result.Structural, 1)
+	is.Equal("Column 'budget' is missing (read by the expression rule on field 'total'), available columns: quantity, unit_price, total", result.Structural[0].Message)
+
+	// syntax errors fail before any row is read
+	_, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Type: "expression", Constraints: "quantity * == total"}},
+	})
+	is.ErrorContains(err, "invalid condition of expression rule: expected a value, got '=='")
+
+	_, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Type: "expression"}},
+	})
+	is.Error(err)
+}
+
+func BenchmarkValidateService_expression(b *testing.B) {
+	values := make([][]string, 0, 1000)
+	for i := range 1000 {
+		values = append(values, []string{strconv.Itoa(i % 10), "2.5", strconv.FormatFloat(float64(i%10)*2.5, 'f', -1, 64), "75001", "FR"})
+	}
+	rows := testRows(b, []string{"quantity", "unit_price", "total", "zip", "country"}, values...)
+	s := &ValidateService{logger: zerolog.Nop()}
+
+	for _, condition := range []string{"quantity * unit_price == total", "len(zip) == 5 || country != 'US'"} {
+		b.Run(condition, func(b *testing.B) {
+			opts, err := s.parseValidateOptions(map[string]interface{}{
+				"rules": []ValidationRule{{Type: "expression", Constraints: condition}},
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for range b.N {
+				result, _, _ := s.validateData(rows, opts)
+				if len(result.Errors) > 0 {
+					b.Fatal(result.Errors[0].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateService_checksumRule(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"card", "siret"},
+		[]string{"4111 1111 1111 1111", "73282932000074"},
+		[]string{"4111 1111 1111 1112", "73282932000047"},
+	)
+
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"field": "card", "type": "checksum", "constraints": map[string]interface{}{"algorithm": "luhn", "mask_value": true}},
+			map[string]interface{}{"field": "siret", "type": "checksum", "constraints": "FR_SIRET"},
+		},
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.Equal([]int{2, 2}, validationRows(result.Errors))
+	is.Equal("Invalid luhn checksum", result.Errors[0].Message)
+	is.Equal("***************1112", result.Errors[0].FieldValue)
+	is.Equal("***************1112", result.Errors[0].RowData.Fields["card"])
+	is.Equal("4111 1111 1111 1112", rows[1].Fields["card"])
+	is.Equal("Invalid fr_siret checksum", result.Errors[1].Message)
+	is.Equal("73282932000047", result.Errors[1].FieldValue)
+
+	for _, constraints := range []interface{}{
+		nil,
+		"crc32",
+		map[string]interface{}{"algorithm": "iban", "mask_value": "yes"},
+	} {
+		_, err := s.parseValidateOptions(map[string]interface{}{
+			"rules": []ValidationRule{{Field: "card", Type: "checksum", Constraints: constraints}},
+		})
+		is.Error(err)
+	}
+}
+
+func TestValidateService_foreignKeyRule(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	products := filepath.Join(dir, "products.csv")
+	is.NoError(os.WriteFile(products, []byte("id,name\nP1,pen\nP2,ink\np3,pad\n,none\n"), 0o600))
+	productsJSONL := filepath.Join(dir, "products.jsonl")
+	is.NoError(os.WriteFile(productsJSONL, []byte(`{"sku":"P1"}`+"\n"+`{"sku":"P2"}`+"\n"), 0o600))
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"product_id"},
+		[]string{"P1"},
+		[]string{"P3"},
+		[]string{""},
+		[]string{"P2"},
+	)
+
+	testCases := []struct {
+		name        string
+		constraints map[string]interface{}
+		invalid     []int
+		size        int
+	}{
+		{"csv", map[string]interface{}{"file": products, "column": "id"}, []int{2, 3}, 3},
+		{"allow empty", map[string]interface{}{"file": products, "column": "id", "allow_empty": true}, []int{2}, 3},
+		{"case insensitive", map[string]interface{}{"file": products, "column": "id", "case_insensitive": true, "allow_empty": true}, nil, 3},
+		{"jsonl", map[string]interface{}{"file": productsJSONL, "column": "sku"}, []int{2, 3}, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			opts, err := s.parseValidateOptions(map[string]interface{}{
+				"rules": []ValidationRule{{Field: "product_id", Type: "foreign_key", Constraints: tc.constraints}},
+			})
+			is.NoError(err)
+			result, _, _ := s.validateData(rows, opts)
+			if tc.invalid == nil {
+				is.Empty(result.Errors)
+			} else {
+				is.Equal(tc.invalid, validationRows(result.Errors))
+				is.Equal("Value not found in column '"+tc.constraints["column"].(string)+"' of "+tc.constraints["file"].(string), result.Errors[0].Message)
+			}
+			is.Equal([]ReferenceSet{{Field: "product_id", File: tc.constraints["file"].(string), Column: tc.constraints["column"].(string), Size: tc.size}}, result.References)
+		})
+	}
+
+	for _, constraints := range []interface{}{
+		"products.csv",
+		map[string]interface{}{"column": "id"},
+		map[string]interface{}{"file": filepath.Join(dir, "missing.csv")},
+		map[string]interface{}{"file": products, "column": "sku"},
+		map[string]interface{}{"file": productsJSONL, "column": "id"},
+		map[string]interface{}{"file": products, "column": "id", "allow_empty": "yes"},
+	} {
+		_, err := s.parseValidateOptions(map[string]interface{}{
+			"rules": []ValidationRule{{Field: "product_id", Type: "foreign_key", Constraints: constraints}},
+		})
+		is.Error(err)
+	}
+}
+
+// validationRows returns the row numbers of validation errors.
+func validationRows(errors []ValidationError) []int {
+	rows := make([]int, 0, len(errors))
+	for _, err := range errors {
+		rows = append(rows, err.RowNumber)
+	}
+	return rows
+}
+
+// dataRowLines returns the row numbers of rows built by testRows, from their line numbers.
+func dataRowLines(rows []DataRow) []int {
+	numbers := make([]int, 0, len(rows))
+	for _, row := range rows {
+		numbers = append(numbers, row.LineNumber-1)
+	}
+	return numbers
+}
+
+func TestValidateService_compareFields(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"start", "end", "price", "discount"},
+		[]string{"2024-01-10", "2024-02-01", "10", "9.5"},
+		[]string{"2024-03-01", "2024-02-01", "9", "10"},
+		[]string{"2024-01-10", "2024-01-10", "100", "20"},
+		[]string{"2024-01-10", "", "", "5"},
+		[]string{"15/01/2024", "2024-01-20", "abc", "5"},
+	)
+
+	testCases := []struct {
+		name        string
+		constraints map[string]interface{}
+		field       string
+		invalid     []int
+	}{
+		{"dates after", map[string]interface{}{"field": "start", "operator": "gt"}, "end", []int{2, 3}},
+		{"dates after or equal", map[string]interface{}{"field": "start", "operator": "gte"}, "end", []int{2}},
+		{"dates with layouts", map[string]interface{}{"field": "start", "operator": "gt", "date_layouts": []interface{}{"2006-01-02", "02/01/2006"}}, "end", []int{2, 3}},
+		// 9.5 < 10 as numbers, but not as strings
+		{"numbers less than", map[string]interface{}{"field": "price", "operator": "lt"}, "discount", []int{2}},
+		{"forced strings", map[string]interface{}{"field": "price", "operator": "lt", "type": "string"}, "discount", []int{1, 3}},
+		{"forced numbers", map[string]interface{}{"field": "price", "operator": "lt", "type": "number"}, "discount", []int{2, 5}},
+		{"not equal", map[string]interface{}{"field": "start", "operator": "ne"}, "end", []int{3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestValidateService(t)
+			opts, err := s.parseValidateOptions(map[string]interface{}{
+				"rules": []ValidationRule{{Field: tc.field, Type: "compare_fields", Constraints: tc.constraints}},
+			})
+			is.NoError(err)
+			result, _, _ := s.validateData(rows, opts)
+			is.Equal(tc.invalid, validationRows(result.Errors))
+		})
+	}
+
+	is := assert.New(t)
+	s := newTestValidateService(t)
+
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "end", Type: "compare_fields", Constraints: map[string]interface{}{"field": "start", "operator": "gt"}}},
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows[1:2], opts)
+	is.Equal("Value must be greater than field 'start' (2024-03-01)", result.Errors[0].Message)
+
+	// the other field must exist
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "end", Type: "compare_fields", Constraints: map[string]interface{}{"field": "begin", "operator": "gt"}}},
+	})
+	is.NoError(err)
+	result, _, _ = s.validateData(rows[:1], opts)
+	is.Empty(result.Errors)
+	is.Len(result.Structural, 1)
+	is.Contains(result.Structural[0].Message, "'end'")
+	is.Contains(result.Structural[0].Message, "'begin'")
+
+	for _, constraints := range []interface{}{
+		nil,
+		map[string]interface{}{"operator": "gt"},
+		map[string]interface{}{"field": "start", "operator": "after"},
+		map[string]interface{}{"field": "start", "operator": "gt", "type": "bool"},
+	} {
+		_, err := s.parseValidateOptions(map[string]interface{}{
+			"rules": []ValidationRule{{Field: "end", Type: "compare_fields", Constraints: constraints}},
+		})
+		is.Error(err)
+	}
+}
+
+func TestValidateService_dateRule(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"date"},
+		[]string{"2024-01-15"},
+		[]string{"15/01/2024"},
+		[]string{"2019-12-31"},
+		[]string{"2999-01-01"},
+		[]string{"soon"},
+	)
+
+	testCases := []struct {
+		name        string
+		constraints interface{}
+		invalid     []int
+		message     string
+	}{
+		{"iso dates by default", nil, []int{2, 5}, "Invalid date, expected an ISO-8601 date"},
+		{"friendly layout", "DD/MM/YYYY", []int{1, 3, 4, 5}, "Invalid date, expected format DD/MM/YYYY"},
+		{"list of layouts", []interface{}{"2006-01-02", "DD/MM/YYYY"}, []int{5}, "Invalid date, expected format 2006-01-02 or DD/MM/YYYY"},
+		{"not in the future", map[string]interface{}{"max": "now"}, []int{2, 4, 5}, "Date must not be after now"},
+		{"bounds", map[string]interface{}{"layouts": []interface{}{"YYYY-MM-DD", "DD/MM/YYYY"}, "min": "2020-01-01", "max": "31/12/2024"}, []int{3, 4, 5}, "Date must not be before 2020-01-01"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestValidateService(t)
+			opts, err := s.parseValidateOptions(map[string]interface{}{
+				"rules": []ValidationRule{{Field: "date", Type: "date", Constraints: tc.constraints}},
+			})
+			is.NoError(err)
+			result, _, _ := s.validateData(rows, opts)
+			is.Equal(tc.invalid, validationRows(result.Errors))
+			messages := make([]string, 0, len(result.Errors))
+			for _, validationError := range result.Errors {
+				messages = append(messages, validationError.Message)
+			}
+			is.Contains(messages, tc.message)
+		})
+	}
+
+	is := assert.New(t)
+	s := newTestValidateService(t)
+	for _, constraints := range []interface{}{
+		float64(1),
+		map[string]interface{}{"min": "yesterday"},
+		map[string]interface{}{"min": "2024-02-01", "max": "2024-01-01"},
+	} {
+		_, err := s.parseValidateOptions(map[string]interface{}{
+			"rules": []ValidationRule{{Field: "date", Type: "date", Constraints: constraints}},
+		})
+		is.Error(err)
+	}
+}
+
+func TestValidateService_numberRules(t *testing.T) {
+	t.Parallel()
+
+	rows := testRows(t, []string{"value"},
+		[]string{"3"},
+		[]string{"3.14"},
+		[]string{"-120.5"},
+		[]string{"1.5e3"},
+		[]string{"9223372036854775807"},
+		[]string{"abc"},
+	)
+
+	testCases := []struct {
+		name        string
+		ruleType    string
+		constraints interface{}
+		invalid     []int
+		message     string
+	}{
+		{"integer", "integer", nil, []int{2, 3, 4, 6}, "Value must be an integer, without fraction"},
+		{"scientific integer", "integer", map[string]interface{}{"scientific": true}, []int{2, 3, 6}, "Value must be an integer"},
+		{"long integer bound", "integer", map[string]interface{}{"max": "9223372036854775806"}, []int{2, 3, 4, 5, 6}, "Value must be at most 9223372036854775806"},
+		{"decimal", "decimal", nil, []int{4, 6}, "Value must be a decimal number"},
+		{"fraction digits", "decimal", map[string]interface{}{"max_fraction_digits": float64(1)}, []int{2, 4, 6}, "Value must have at most 1 fraction digits"},
+		{"integer digits", "decimal", map[string]interface{}{"max_integer_digits": float64(3), "scientific": true}, []int{4, 5, 6}, "Value must have at most 3 integer digits"},
+		{"bounds", "decimal", map[string]interface{}{"min": float64(0), "max": 3.14}, []int{3, 4, 5, 6}, "Value must be at least 0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestValidateService(t)
+			opts, err := s.parseValidateOptions(map[string]interface{}{
+				"rules": []ValidationRule{{Field: "value", Type: tc.ruleType, Constraints: tc.constraints}},
+			})
+			is.NoError(err)
+			result, _, _ := s.validateData(rows, opts)
+			is.Equal(tc.invalid, validationRows(result.Errors))
+			messages := make([]string, 0, len(result.Errors))
+			for _, validationError := range result.Errors {
+				messages = append(messages, validationError.Message)
+			}
+			is.Contains(messages, tc.message)
+		})
+	}
+
+	is := assert.New(t)
+	s := newTestValidateService(t)
+	for _, constraints := range []interface{}{
+		float64(2),
+		map[string]interface{}{"max_fraction_digits": float64(-1)},
+		map[string]interface{}{"max_integer_digits": 1.5},
+		map[string]interface{}{"scientific": "yes"},
+		map[string]interface{}{"min": "ten"},
+		map[string]interface{}{"min": float64(10), "max": float64(1)},
+	} {
+		_, err := s.parseValidateOptions(map[string]interface{}{
+			"rules": []ValidationRule{{Field: "value", Type: "decimal", Constraints: constraints}},
+		})
+		is.Error(err)
+	}
+}
+
+func TestValidateService_identifierRules(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"id", "ip", "host"},
+		[]string{"f47ac10b-58cc-4372-a567-0e02b2c3d479", "10.0.0.1", "db.internal"},
+		[]string{"123e4567-e89b-12d3-a456-426614174000", "10.0.0.256", "db_1"},
+	)
+
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"field": "id", "type": "uuid", "constraints": float64(4)},
+			map[string]interface{}{"field": "ip", "type": "ipv4"},
+			map[string]interface{}{"field": "host", "type": "hostname"},
+		},
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.Equal(1, result.ValidRows)
+	is.Len(result.Errors, 3)
+	is.Equal("Value must be a version 4 UUID, like xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx", result.Errors[0].Message)
+	is.Equal("Value must be an IPv4 address, like 192.168.0.1", result.Errors[1].Message)
+	is.Equal("Value must be a hostname, like api.example.com", result.Errors[2].Message)
+
+	for _, version := range []interface{}{float64(9), "4", float64(4.5)} {
+		_, err := s.parseValidateOptions(map[string]interface{}{
+			"rules": []ValidationRule{{Field: "id", Type: "uuid", Constraints: version}},
+		})
+		is.Error(err)
+	}
+}
+
+func TestValidateService_severity(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"code", "note"},
+		[]string{"AB-1", "ok"},
+		[]string{"nope", "ok"},
+		[]string{"AB-2", "x"},
+		[]string{"AB-2", "ok"},
+	)
+
+	// default severities: regex and length failures are warnings
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{
+			{Field: "code", Type: "regex", Constraints: `^[A-Z]{2}-\d+$`},
+			{Field: "note", Type: "min_length", Constraints: float64(2)},
+		},
+	})
+	is.NoError(err)
+	result, _, _ := s.validateData(rows, opts)
+	is.Equal(4, result.ValidRows)
+	is.Empty(result.Errors)
+	is.Len(result.Warnings, 2)
+	is.InDelta(97.5, result.QualityScore, 0.001)
+
+	// overridden severities, from generic JSON
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"field": "code", "type": "regex", "constraints": `^[A-Z]{2}-\d+$`, "severity": "Error"},
+			map[string]interface{}{"field": "note", "type": "min_length", "constraints": float64(2), "severity": "warning"},
+			map[string]interface{}{"field": "code", "type": "unique", "severity": "warning"},
+		},
+	})
+	is.NoError(err)
+	result, _, invalid := s.validateData(rows, opts)
+	is.Equal(3, result.ValidRows)
+	is.Equal(1, result.InvalidRows)
+	is.Equal([]int{2}, validationRows(result.Errors))
+	is.Equal([]int{3, 4}, validationRows(result.Warnings))
+	// duplicates that only warn leave the first rows of their sets out of the invalid rows
+	is.Equal([]int{2}, dataRowLines(invalid))
+	is.InDelta(72.5, result.QualityScore, 0.001)
+
+	_, err = s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{{Field: "code", Type: "required", Severity: "fatal"}},
+	})
+	is.EqualError(err, `unknown severity "fatal" on field 'code': expected error or warning`)
+}
+
+func TestValidateService_structure(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	s := newTestValidateService(t)
+	rows := testRows(t, []string{"name", "mail", "ssn", "id"},
+		[]string{"a", "a@example.com", "1", "1"},
+		[]string{"", "b@example.com", "2", "2"},
+	)
+
+	opts, err := s.parseValidateOptions(map[string]interface{}{
+		"rules": []ValidationRule{
+			{Field: "email", Type: "email"},
+			{Field: "name", Type: "required"},
+			{Field: "email", Type: "required"},
+		},
+		"required_columns":      "id,created_at",
+		"forbidden_columns":     []interface{}{"ssn", "password"},
+		"expected_column_order": []string{"id", "name", "missing"},
+	})
+	is.NoError(err)
+	result, valid, _ := s.validateData(rows, opts)
+
+	// one error per missing column, none per row
+	is.Len(result.Structural, 4)
+	is.Equal("created_at", result.Structural[0].FieldName)
+	is.Equal("Column 'created_at' is missing, available columns: name, mail, ssn, id", result.Structural[0].Message)
+	is.Equal("Column 'email' is missing (read by the email rule on field 'email'), available columns: name, mail, ssn, id", result.Structural[1].Message)
+	is.Equal("Column 'ssn' is forbidden", result.Structural[2].Message)
+	is.Equal("Columns are not in the expected order: expected id, name, got name, id", result.Structural[3].Message)
+	is.Equal([]int{2}, validationRows(result.Errors))
+	is.Len(valid, 1)
+
+	// fail_fast stops before the rows
+	opts.FailFast = true
+	result, valid, _ = s.validateData(rows, opts)
+	is.Len(result.Structural, 4)
+	is.Empty(result.Errors)
+	is.Empty(valid)
+	is.Equal(2, result.TotalRows)
+
+	// matching headers
+	opts, err = s.parseValidateOptions(map[string]interface{}{
+		"required_columns":      []string{"id"},
+		"expected_column_order": "name,id",
+	})
+	is.NoError(err)
+	result, _, _ = s.validateData(rows, opts)
+	is.Empty(result.Structural)
+}
+
+func TestValidateService_ValidateFile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("name,age\na,1\n,2\nc,x\n"), 0o600))
+
+	result, err := newTestValidateService(t).ValidateFile(input, filepath.Join(dir, "out.json"),
+		[]ValidationRule{{Field: "name", Type: "required"}, {Field: "age", Type: "numeric"}}, false,
+		map[string]interface{}{"required_columns": []string{"id"}})
+	is.NoError(err)
+	is.Equal(3, result.TotalRows)
+	is.Equal(1, result.ValidRows)
+	is.Equal(2, result.InvalidRows)
+	is.Len(result.Structural, 1)
+}
+
+func TestValidateService_errorLimits(t *testing.T) {
+	t.Parallel()
+
+	values := make([][]string, 0, 20)
+	for i := 0; i < 10; i++ {
+		values = append(values, []string{"", "x"}, []string{"ok", "x"})
+	}
+	rows := testRows(t, []string{"name", "age"}, values...)
+	rules := []ValidationRule{{Field: "name", Type: "required"}, {Field: "age", Type: "numeric"}, {Field: "age", Type: "max_length", Constraints: float64(0)}}
+
+	testCases := []struct {
+		name      string
+		options   map[string]interface{}
+		errors    int
+		warnings  int
+		truncated bool
+	}{
+		{"no limit", nil, 30, 20, false},
+		{"max errors", map[string]interface{}{"max_errors": float64(5)}, 5, 5, true},
+		{"errors per rule", map[string]interface{}{"errors_per_rule": 3}, 6, 3, true},
+		{"both", map[string]interface{}{"errors_per_rule": 3, "max_errors": 4}, 4, 3, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			s := newTestValidateService(t)
+			opts, err := s.parseValidateOptions(mergeOptions(map[string]interface{}{"rules": rules}, tc.options))
+			is.NoError(err)
+			result, _, _ := s.validateData(rows, opts)
+
+			is.Len(result.Errors, tc.errors)
+			is.Len(result.Warnings, tc.warnings)
+			is.Equal(tc.truncated, result.ErrorsTruncated)
+			is.Equal(tc.truncated, result.WarningsTruncated)
+
+			// counts stay exact
+			is.Equal(30, result.ErrorCount)
+			is.Equal(20, result.WarningCount)
+			is.Equal(20, result.InvalidRows)
+			is.Equal([]RuleSummary{
+				{Rule: "numeric", Field: "age", Errors: 20, Rows: 20, Examples: []string{"x"}},
+				{Rule: "required", Field: "name", Errors: 10, Rows: 10, Examples: []string{""}},
+				{Rule: "max_length", Field: "age", Warnings: 20, Rows: 20, Examples: []string{"x"}},
+			}, result.RuleSummary)
+			is.InDelta(0, result.QualityScore, 0.001)
+		})
+	}
+
+	_, err := newTestValidateService(t).parseValidateOptions(map[string]interface{}{"max_errors": -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// zeroWidthRunes are the invisible characters "normalize_whitespace" rules remove. Zero width
+// joiners and non-joiners are kept, as they change how emojis and some scripts render.
+var zeroWidthRunes = map[rune]bool{
+	'\u200b': true, // zero width space
+	'\u2060': true, // word joiner
+	'\ufeff': true, // zero width no-break space, or byte order mark
+}
+
+// charTrim trims runes from the ends of values for "trim_chars" rules. Its parameters are the
+// "cutset", the runes to trim, like "\"'", and the "side", both by default, left or right.
+type charTrim struct {
+	cutset string
+	side   string
+}
+
+// parseCharTrim parses the parameters of a "trim_chars" rule.
+func parseCharTrim(rule TransformRule) (*charTrim, error) {
+	trim := &charTrim{side: "both"}
+	if trim.cutset, _ = rule.Parameters["cutset"].(string); trim.cutset == "" {
+		return nil, fmt.Errorf("cutset of trim_chars rule on field '%s' not specified", rule.Field)
+	}
+
+	switch side, _ := rule.Parameters["side"].(string); side {
+	case "":
+	case "both", "left", "right":
+		trim.side = side
+	default:
+		return nil, fmt.Errorf("invalid side %q of trim_chars rule on field '%s': expected both, left or right", side, rule.Field)
+	}
+	return trim, nil
+}
+
+// trim trims the runes of the cutset from the side of a value.
+func (t *charTrim) trim(value string) string {
+	switch t.side {
+	case "left":
+		return strings.TrimLeft(value, t.cutset)
+	case "right":
+		return strings.TrimRight(value, t.cutset)
+	default:
+		return strings.Trim(value, t.cutset)
+	}
+}
+
+// normalizeWhitespace removes zero width characters from a value, replaces runs of Unicode
+// whitespace, like tabs, line breaks and non-breaking spaces, with a single space, and trims it.
+func normalizeWhitespace(value string) string {
+	var builder strings.Builder
+	builder.Grow(len(value))
+	space := false
+	for _, r := range value {
+		switch {
+		case zeroWidthRunes[r]:
+		case unicode.IsSpace(r):
+			space = builder.Len() > 0
+		default:
+			if space {
+				builder.WriteByte(' ')
+				space = false
+			}
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
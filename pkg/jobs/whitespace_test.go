@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_normalizeWhitespace(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"":                                  "",
+		" \t\u00a0\n ":                      "",
+		"\u200b":                            "",
+		"plain":                             "plain",
+		"  Jean\u00a0Dupont  ":              "Jean Dupont",
+		"a\t\tb\tc":                         "a b c",
+		"line\r\nbreak":                     "line break",
+		"zero\u200bwidth\ufeff":             "zerowidth",
+		"\ufeffBOM at start":                "BOM at start",
+		"word\u2060joiner":                  "wordjoiner",
+		"a \u200b b":                        "a b",
+		"ideographic\u3000space":            "ideographic space",
+		"narrow\u202fno-break":              "narrow no-break",
+		"family \U0001F468\u200d\U0001F469": "family \U0001F468\u200d\U0001F469",
+		"\u00a0\t12\u00a0345\t\u00a0":       "12 345",
+	}
+
+	for value, want := range testCases {
+		t.Run(value, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: NormalizeWhitespace}},
+			})
+			is.NoError(err)
+			is.Equal(want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_trimChars(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		value  string
+		params map[string]interface{}
+		want   string
+	}{
+		{name: "quotes", value: `"'quoted'"`, params: map[string]interface{}{"cutset": `"'`}, want: "quoted"},
+		{name: "inner runes kept", value: "--a-b--", params: map[string]interface{}{"cutset": "-"}, want: "a-b"},
+		{name: "left", value: "000120", params: map[string]interface{}{"cutset": "0", "side": "left"}, want: "120"},
+		{name: "right", value: "1.500", params: map[string]interface{}{"cutset": "0", "side": "right"}, want: "1.5"},
+		{name: "non-breaking spaces", value: "\u00a0 value\t\u00a0", params: map[string]interface{}{"cutset": "\u00a0\t "}, want: "value"},
+		{name: "multibyte runes", value: "«guillemets»", params: map[string]interface{}{"cutset": "«»"}, want: "guillemets"},
+		{name: "only cutset", value: "***", params: map[string]interface{}{"cutset": "*"}, want: ""},
+		{name: "empty", value: "", params: map[string]interface{}{"cutset": "*"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			input := testRows(t, []string{"value"}, []string{tc.value})
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{
+				"rules": []TransformRule{{Field: "value", Operation: TrimChars, Parameters: tc.params}},
+			})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+}
+
+func TestTransformService_trimCharsErrors(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	for _, params := range []map[string]interface{}{
+		{},
+		{"cutset": ""},
+		{"cutset": "-", "side": "middle"},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{
+			"rules": []TransformRule{{Field: "value", Operation: TrimChars, Parameters: params}},
+		})
+		is.ErrorContains(err, "trim_chars rule on field 'value'", params)
+	}
+}
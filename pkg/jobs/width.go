@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// zeroWidthJoiner joins emoji into one, like the family emoji.
+const zeroWidthJoiner = '\u200d'
+
+// widthFormat fits values to a width, counted in runes, for "pad" and "truncate" rules.
+// Pad rules take "length", "pad_char", a space by default, and "side", left by default,
+// like "42" padded to "0000042". Truncate rules take "length" and an "ellipsis" suffix counted
+// in the length, and never cut a character from the combining marks or joined emoji following it.
+type widthFormat struct {
+	length   int
+	padChar  string
+	padRight bool
+	ellipsis string
+}
+
+// parseWidthFormat parses the parameters of a "pad" or "truncate" rule.
+func parseWidthFormat(rule TransformRule) (*widthFormat, error) {
+	format := &widthFormat{padChar: " "}
+	length, ok := toInt(rule.Parameters["length"])
+	if !ok || length < 1 {
+		return nil, fmt.Errorf("invalid length %v of %s rule on field '%s': expected a positive integer", rule.Parameters["length"], rule.Operation, rule.Field)
+	}
+	format.length = length
+
+	if rule.Operation == Truncate {
+		format.ellipsis, _ = rule.Parameters["ellipsis"].(string)
+		if utf8.RuneCountInString(format.ellipsis) >= length {
+			return nil, fmt.Errorf("ellipsis %q of truncate rule on field '%s' is not shorter than the length", format.ellipsis, rule.Field)
+		}
+		return format, nil
+	}
+
+	if char, ok := rule.Parameters["pad_char"].(string); ok {
+		if utf8.RuneCountInString(char) != 1 {
+			return nil, fmt.Errorf("invalid pad_char %q of pad rule on field '%s': expected one character", char, rule.Field)
+		}
+		format.padChar = char
+	}
+	switch side, _ := rule.Parameters["side"].(string); side {
+	case "", "left":
+	case "right":
+		format.padRight = true
+	default:
+		return nil, fmt.Errorf("invalid side %q of pad rule on field '%s': expected left or right", side, rule.Field)
+	}
+	return format, nil
+}
+
+// pad pads a value to the length, leaving longer values as is.
+func (f *widthFormat) pad(value string) string {
+	missing := f.length - utf8.RuneCountInString(value)
+	if missing <= 0 {
+		return value
+	}
+	if f.padRight {
+		return value + strings.Repeat(f.padChar, missing)
+	}
+	return strings.Repeat(f.padChar, missing) + value
+}
+
+// truncate cuts a value to the length, ellipsis included, leaving shorter values as is.
+func (f *widthFormat) truncate(value string) string {
+	runes := []rune(value)
+	if len(runes) <= f.length {
+		return value
+	}
+
+	cut := f.length - utf8.RuneCountInString(f.ellipsis)
+	for cut > 0 && (isJoinedRune(runes[cut]) || runes[cut-1] == zeroWidthJoiner) {
+		cut--
+	}
+	return string(runes[:cut]) + f.ellipsis
+}
+
+// isJoinedRune reports whether a rune belongs with the character before it: combining marks,
+// variation selectors, emoji modifiers and joiners.
+func isJoinedRune(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me) || r == zeroWidthJoiner || (r >= 0x1f3fb && r <= 0x1f3ff)
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformService_padAndTruncate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		value string
+		rule  TransformRule
+		want  string
+	}{
+		{name: "zero padding", value: "42", rule: TransformRule{Operation: Pad, Parameters: map[string]interface{}{"length": float64(7), "pad_char": "0"}}, want: "0000042"},
+		{name: "right padding", value: "né", rule: TransformRule{Operation: Pad, Parameters: map[string]interface{}{"length": 4, "side": "right"}}, want: "né  "},
+		{name: "padding a long value", value: "12345678", rule: TransformRule{Operation: Pad, Parameters: map[string]interface{}{"length": 7, "pad_char": "0"}}, want: "12345678"},
+		{name: "truncating runes", value: "Déjà vu", rule: TransformRule{Operation: Truncate, Parameters: map[string]interface{}{"length": 4}}, want: "Déjà"},
+		{name: "truncating a short value", value: "abc", rule: TransformRule{Operation: Truncate, Parameters: map[string]interface{}{"length": 4, "ellipsis": "…"}}, want: "abc"},
+		{name: "ellipsis", value: "description", rule: TransformRule{Operation: Truncate, Parameters: map[string]interface{}{"length": 6, "ellipsis": "..."}}, want: "des..."},
+		{name: "combining characters", value: "cafe\u0301s", rule: TransformRule{Operation: Truncate, Parameters: map[string]interface{}{"length": 4}}, want: "caf"},
+		{name: "emoji", value: "ok👍🏽👍", rule: TransformRule{Operation: Truncate, Parameters: map[string]interface{}{"length": 3}}, want: "ok"},
+		{name: "joined emoji", value: "a👩‍💻b", rule: TransformRule{Operation: Truncate, Parameters: map[string]interface{}{"length": 3}}, want: "a"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			tc.rule.Field = "value"
+			input := []DataRow{{Fields: map[string]string{"value": tc.value}, Columns: []string{"value"}}}
+			output, err := newTestTransformService(t).ProcessData(input, map[string]interface{}{"rules": []TransformRule{tc.rule}})
+			is.NoError(err)
+			is.Equal(tc.want, output[0].Fields["value"])
+		})
+	}
+
+	for _, rule := range []TransformRule{
+		{Field: "value", Operation: Pad},
+		{Field: "value", Operation: Pad, Parameters: map[string]interface{}{"length": -1}},
+		{Field: "value", Operation: Pad, Parameters: map[string]interface{}{"length": 3, "pad_char": "00"}},
+		{Field: "value", Operation: Pad, Parameters: map[string]interface{}{"length": 3, "side": "center"}},
+		{Field: "value", Operation: Truncate, Parameters: map[string]interface{}{"length": 3, "ellipsis": "..."}},
+	} {
+		_, err := newTestTransformService(t).ProcessData(nil, map[string]interface{}{"rules": []TransformRule{rule}})
+		assert.ErrorContains(t, err, string(rule.Operation)+" rule on field 'value'", rule.Parameters)
+	}
+}
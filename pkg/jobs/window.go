@@ -0,0 +1,368 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/do/v2"
+)
+
+// WindowOperation defines window operation types.
+type WindowOperation string
+
+const (
+	CumulativeSum   WindowOperation = "cumulative_sum"
+	CumulativeCount WindowOperation = "cumulative_count"
+	MovingAverage   WindowOperation = "moving_average"
+)
+
+// WindowRule defines a column computed over the rows preceding each row of its partition.
+type WindowRule struct {
+	Field     string          `json:"field"`
+	Operation WindowOperation `json:"operation"`
+	Alias     string          `json:"alias,omitempty"`
+	Size      int             `json:"size,omitempty"` // rows of a moving average, the current one included
+}
+
+// alias returns the name of the rule column, "<field>_<operation>" unless Alias is set.
+func (r WindowRule) alias() string {
+	if r.Alias != "" {
+		return r.Alias
+	}
+	if r.Field == "" {
+		return string(r.Operation)
+	}
+	return fmt.Sprintf("%s_%s", r.Field, r.Operation)
+}
+
+// WindowOptions contains window configuration.
+type WindowOptions struct {
+	InputFile   string        `json:"input_file"`
+	OutputFile  string        `json:"output_file"`
+	Rules       []WindowRule  `json:"rules"`
+	OrderBy     []SortKey     `json:"order_by,omitempty"`     // input order when empty
+	PartitionBy []string      `json:"partition_by,omitempty"` // fields whose values restart the windows
+	DateLayouts []string      `json:"date_layouts,omitempty"` // layouts of ordering dates, DefaultDateLayouts when empty
+	Output      OutputOptions `json:"output"`                 // format details of the written rows
+}
+
+// WindowService computes running aggregates over ordered rows
+// This service demonstrates row-preserving analytics with dependency injection.
+type WindowService struct {
+	fileService *FileService   `do:""`
+	logger      zerolog.Logger `do:""`
+}
+
+// NewWindowService creates a new window service with dependency injection.
+func NewWindowService(i do.Injector) (*WindowService, error) {
+	return &WindowService{
+		fileService: do.MustInvoke[*FileService](i),
+		logger:      *do.MustInvoke[*zerolog.Logger](i),
+	}, nil
+}
+
+// ProcessData appends window columns to every row
+// This method demonstrates ordered, partitioned running computations.
+func (s *WindowService) ProcessData(input []DataRow, options map[string]interface{}) ([]DataRow, error) {
+	s.logger.Info().Msg("Computing window aggregates")
+
+	// Parse options
+	opts, err := s.parseWindowOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse window options: %w", err)
+	}
+
+	// If input data is empty, try to read from file
+	if len(input) == 0 && opts.InputFile != "" {
+		input, err = s.fileService.ReadCSV(opts.InputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+	}
+
+	output := s.window(input, opts)
+
+	// Write results to file if output file specified
+	if opts.OutputFile != "" {
+		if err := s.fileService.WriteRows(opts.OutputFile, output, opts.Output); err != nil {
+			return nil, fmt.Errorf("failed to write window data: %w", err)
+		}
+	}
+
+	s.logger.Info().
+		Int("records", len(output)).
+		Int("rules", len(opts.Rules)).
+		Strs("partition_by", opts.PartitionBy).
+		Msg("Window aggregates completed")
+
+	return output, nil
+}
+
+// GetName returns the processor name.
+func (s *WindowService) GetName() string {
+	return "window"
+}
+
+// GetDescription returns the processor description.
+func (s *WindowService) GetDescription() string {
+	return "Append running totals and moving averages to ordered rows"
+}
+
+// parseWindowOptions parses window options from map.
+func (s *WindowService) parseWindowOptions(options map[string]interface{}) (*WindowOptions, error) {
+	opts := &WindowOptions{}
+
+	if inputFile, ok := options["input_file"].(string); ok {
+		opts.InputFile = inputFile
+	}
+
+	if outputFile, ok := options["output_file"].(string); ok {
+		opts.OutputFile = outputFile
+	}
+
+	opts.Rules = decodeWindowRules(options["rules"])
+	if len(opts.Rules) == 0 {
+		return nil, errors.New("at least one window rule is required")
+	}
+
+	for _, rule := range opts.Rules {
+		if err := rule.check(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse order and partition fields, as comma-separated strings or lists
+	switch orderBy := options["order_by"].(type) {
+	case string:
+		opts.OrderBy = ParseSortKeys(orderBy, false)
+	case []string:
+		opts.OrderBy = ParseSortKeys(strings.Join(orderBy, ","), false)
+	case []interface{}:
+		for _, field := range orderBy {
+			if name, ok := field.(string); ok {
+				opts.OrderBy = append(opts.OrderBy, ParseSortKeys(name, false)...)
+			}
+		}
+	}
+
+	opts.PartitionBy = parseColumnList(options["partition_by"])
+	opts.DateLayouts = parseDateLayouts(options["date_layouts"])
+
+	outputOpts, err := parseOutputOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	opts.Output = outputOpts
+
+	return opts, nil
+}
+
+// decodeWindowRules returns window rules, either typed or decoded from generic JSON.
+func decodeWindowRules(raw interface{}) []WindowRule {
+	if rules, ok := raw.([]WindowRule); ok {
+		return rules
+	}
+	var rules []WindowRule
+	rulesRaw, _ := raw.([]interface{})
+	for _, ruleRaw := range rulesRaw {
+		if ruleMap, ok := ruleRaw.(map[string]interface{}); ok {
+			field, _ := ruleMap["field"].(string)
+			operation, _ := ruleMap["operation"].(string)
+			alias, _ := ruleMap["alias"].(string)
+			size, _ := toInt(ruleMap["size"])
+			rules = append(rules, WindowRule{Field: field, Operation: WindowOperation(operation), Alias: alias, Size: size})
+		}
+	}
+	return rules
+}
+
+// check fails when a window rule lacks the field or the size of its operation.
+func (r WindowRule) check() error {
+	switch r.Operation {
+	case CumulativeCount:
+	case CumulativeSum:
+		if r.Field == "" {
+			return errors.New("cumulative_sum requires a field")
+		}
+	case MovingAverage:
+		if r.Field == "" {
+			return errors.New("moving_average requires a field")
+		}
+		if r.Size <= 0 {
+			return fmt.Errorf("moving_average on field '%s' requires a positive size, got %d", r.Field, r.Size)
+		}
+	default:
+		return fmt.Errorf("unknown window operation %q: expected cumulative_sum, cumulative_count or moving_average", r.Operation)
+	}
+	return nil
+}
+
+// window returns copies of the rows in window order with the rule columns appended.
+// Rows are sorted stably by the order keys, each compared as numbers, dates or strings
+// depending on its values, with empty values last.
+func (s *WindowService) window(rows []DataRow, opts *WindowOptions) []DataRow {
+	order := s.order(rows, opts)
+
+	states := make(map[string][]*windowState)
+	output := make([]DataRow, 0, len(rows))
+	for _, i := range order {
+		row := rows[i]
+		out := DataRow{
+			Fields:     make(map[string]string, len(row.Fields)+len(opts.Rules)),
+			Columns:    row.Columns,
+			LineNumber: row.LineNumber,
+			SourceFile: row.SourceFile,
+		}
+		for field, value := range row.Fields {
+			out.Fields[field] = value
+		}
+
+		var partition strings.Builder
+		for _, field := range opts.PartitionBy {
+			writeKeyPart(&partition, row.Fields[field])
+		}
+		state, ok := states[partition.String()]
+		if !ok {
+			state = make([]*windowState, len(opts.Rules))
+			for r, rule := range opts.Rules {
+				state[r] = &windowState{window: make([]float64, 0, rule.Size), valid: make([]bool, 0, rule.Size)}
+			}
+			states[partition.String()] = state
+		}
+
+		for r, rule := range opts.Rules {
+			out.SetField(rule.alias(), state[r].next(rule, row))
+		}
+		output = append(output, out)
+	}
+
+	return output
+}
+
+// order returns the indexes of rows sorted by the order keys, in input order when there are none.
+func (s *WindowService) order(rows []DataRow, opts *WindowOptions) []int {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	if len(opts.OrderBy) == 0 {
+		return order
+	}
+
+	// values of each key, parsed as the type all of them share
+	keys := make([][]*comparableValue, len(opts.OrderBy))
+	comparators := make([]valueComparator, len(opts.OrderBy))
+	for k, key := range opts.OrderBy {
+		values := make([]interface{}, len(rows))
+		for i, row := range rows {
+			if value := row.Fields[key.Field]; value != "" {
+				values[i] = value
+			}
+		}
+		comparators[k] = valueComparator{valueType: sortValueType(values, opts.DateLayouts), layouts: opts.DateLayouts}
+		keys[k] = make([]*comparableValue, len(rows))
+		for i, value := range values {
+			keys[k][i] = comparators[k].sortKey(value)
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		for k, key := range opts.OrderBy {
+			if c := compareSortValues(keys[k][order[a]], keys[k][order[b]], comparators[k], key.Desc); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return order
+}
+
+// windowState holds the running state of a rule in a partition.
+type windowState struct {
+	sum    float64
+	count  int
+	window []float64 // last values of a moving average, oldest first
+	valid  []bool    // whether each value of the window is a number
+}
+
+// next folds a row into the state and returns the column value of the row.
+func (w *windowState) next(rule WindowRule, row DataRow) string {
+	value := row.Fields[rule.Field]
+
+	switch rule.Operation {
+	case CumulativeCount:
+		// rows, or rows with a value when the rule has a field
+		if rule.Field == "" || value != "" {
+			w.count++
+		}
+		return strconv.Itoa(w.count)
+	case CumulativeSum:
+		if num, err := strconv.ParseFloat(value, 64); err == nil {
+			w.sum += num
+		}
+		return strconv.FormatFloat(w.sum, 'f', -1, 64)
+	case MovingAverage:
+		num, err := strconv.ParseFloat(value, 64)
+		if len(w.window) == rule.Size {
+			w.window, w.valid = w.window[1:], w.valid[1:]
+		}
+		w.window, w.valid = append(w.window, num), append(w.valid, err == nil)
+
+		var sum float64
+		numbers := 0
+		for i, val := range w.window {
+			if w.valid[i] {
+				sum += val
+				numbers++
+			}
+		}
+		if numbers == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(sum/float64(numbers), 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// WindowFile appends window columns to the rows of a file
+// This convenience method demonstrates file-based window computations.
+// extraOptions may carry any additional ProcessData option and can be nil.
+func (s *WindowService) WindowFile(inputFile, outputFile string, rules []WindowRule, orderBy string, partitionBy []string, extraOptions map[string]interface{}) (*ProcessingResult, error) {
+	s.logger.Info().
+		Str("input", inputFile).
+		Str("output", outputFile).
+		Int("rules", len(rules)).
+		Str("order_by", orderBy).
+		Strs("partition_by", partitionBy).
+		Msg("Starting file window computation")
+
+	options := mergeOptions(map[string]interface{}{
+		"input_file":   inputFile,
+		"output_file":  outputFile,
+		"rules":        rules,
+		"order_by":     orderBy,
+		"partition_by": partitionBy,
+	}, extraOptions)
+
+	output, err := s.ProcessData(nil, options)
+	if err != nil {
+		return &ProcessingResult{
+			Success:   false,
+			Processed: 0,
+			Processor: s.GetName(),
+			Errors:    []string{err.Error()},
+		}, err
+	}
+
+	return &ProcessingResult{
+		Success:    true,
+		Processed:  len(output),
+		OutputPath: outputFile,
+		Processor:  s.GetName(),
+	}, nil
+}
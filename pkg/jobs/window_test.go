@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWindowService(t *testing.T) *WindowService {
+	t.Helper()
+
+	return &WindowService{
+		fileService: newTestFileService(t),
+		logger:      zerolog.Nop(),
+	}
+}
+
+// windowColumn returns a field of every row, in order.
+func windowColumn(rows []DataRow, field string) []string {
+	values := make([]string, 0, len(rows))
+	for _, row := range rows {
+		values = append(values, row.Fields[field])
+	}
+	return values
+}
+
+func TestWindowService_ProcessData(t *testing.T) {
+	t.Parallel()
+
+	columns := []string{"date", "shop", "amount"}
+	rows := testRows(t, columns,
+		[]string{"2024-01-03", "b", "5"},
+		[]string{"2024-01-01", "a", "10"},
+		[]string{"2024-01-10", "a", "x"},
+		[]string{"2024-01-02", "b", "1.5"},
+		[]string{"2024-01-02", "a", "20"},
+		[]string{"", "a", "7"},
+	)
+
+	testCases := []struct {
+		name     string
+		options  map[string]interface{}
+		field    string
+		dates    []string
+		expected []string
+	}{
+		{
+			"cumulative sum by date",
+			map[string]interface{}{"order_by": "date", "rules": []WindowRule{{Field: "amount", Operation: CumulativeSum}}},
+			"amount_cumulative_sum",
+			[]string{"2024-01-01", "2024-01-02", "2024-01-02", "2024-01-03", "2024-01-10", ""},
+			[]string{"10", "11.5", "31.5", "36.5", "36.5", "43.5"},
+		},
+		{
+			"partitioned sum",
+			map[string]interface{}{"order_by": "date", "partition_by": "shop", "rules": []WindowRule{{Field: "amount", Operation: CumulativeSum, Alias: "running"}}},
+			"running",
+			[]string{"2024-01-01", "2024-01-02", "2024-01-02", "2024-01-03", "2024-01-10", ""},
+			[]string{"10", "1.5", "30", "6.5", "30", "37"},
+		},
+		{
+			"descending count",
+			map[string]interface{}{"order_by": []string{"-date"}, "rules": []interface{}{map[string]interface{}{"operation": "cumulative_count"}}},
+			"cumulative_count",
+			[]string{"2024-01-10", "2024-01-03", "2024-01-02", "2024-01-02", "2024-01-01", ""},
+			[]string{"1", "2", "3", "4", "5", "6"},
+		},
+		{
+			"moving average",
+			map[string]interface{}{"order_by": "date", "rules": []interface{}{map[string]interface{}{"field": "amount", "operation": "moving_average", "size": float64(2)}}},
+			"amount_moving_average",
+			[]string{"2024-01-01", "2024-01-02", "2024-01-02", "2024-01-03", "2024-01-10", ""},
+			[]string{"10", "5.75", "10.75", "12.5", "5", "7"},
+		},
+		{
+			"input order",
+			map[string]interface{}{"rules": []WindowRule{{Field: "amount", Operation: CumulativeCount}}},
+			"amount_cumulative_count",
+			[]string{"2024-01-03", "2024-01-01", "2024-01-10", "2024-01-02", "2024-01-02", ""},
+			[]string{"1", "2", "3", "4", "5", "6"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			output, err := newTestWindowService(t).ProcessData(rows, tc.options)
+			is.NoError(err)
+			is.Equal(tc.dates, windowColumn(output, "date"))
+			is.Equal(tc.expected, windowColumn(output, tc.field))
+			is.Equal(append(append([]string{}, columns...), tc.field), output[0].Columns)
+		})
+	}
+
+	// the input rows are left untouched
+	is := assert.New(t)
+	is.Equal(columns, rows[0].Columns)
+	is.Len(rows[0].Fields, 3)
+}
+
+func TestWindowService_dateOrder(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	// dates sort chronologically, not lexicographically, and ties keep their input order
+	rows := testRows(t, []string{"date", "id"},
+		[]string{"10/01/2024", "1"},
+		[]string{"02/02/2024", "2"},
+		[]string{"09/01/2024", "3"},
+		[]string{"10/01/2024", "4"},
+	)
+	output, err := newTestWindowService(t).ProcessData(rows, map[string]interface{}{
+		"order_by":     "date",
+		"date_layouts": []string{"02/01/2006"},
+		"rules":        []WindowRule{{Field: "id", Operation: CumulativeCount}},
+	})
+	is.NoError(err)
+	is.Equal([]string{"3", "1", "4", "2"}, windowColumn(output, "id"))
+}
+
+func TestWindowService_invalidOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		rules []WindowRule
+	}{
+		{"no rules", nil},
+		{"unknown operation", []WindowRule{{Field: "amount", Operation: "median"}}},
+		{"sum without field", []WindowRule{{Operation: CumulativeSum}}},
+		{"average without size", []WindowRule{{Field: "amount", Operation: MovingAverage}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := assert.New(t)
+
+			_, err := newTestWindowService(t).ProcessData(nil, map[string]interface{}{"rules": tc.rules})
+			is.Error(err)
+		})
+	}
+}
+
+func TestWindowService_WindowFile(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	is.NoError(os.WriteFile(input, []byte("date,amount\n2024-01-02,5\n2024-01-01,3\n"), 0o600))
+
+	output := filepath.Join(dir, "out.csv")
+	result, err := newTestWindowService(t).WindowFile(input, output,
+		[]WindowRule{{Field: "amount", Operation: CumulativeSum, Alias: "total"}}, "date", nil, nil)
+	is.NoError(err)
+	is.Equal(2, result.Processed)
+
+	content, err := os.ReadFile(output)
+	is.NoError(err)
+	is.Equal("date,amount,total\n2024-01-01,3,3\n2024-01-02,5,8\n", string(content))
+}